@@ -10,6 +10,20 @@ import (
 	"github.com/portworx/kvdb"
 )
 
+// Alert types raised by the drivers. They are unique across the drivers so
+// that subscriptions to an alert type are not shared by unrelated alerts.
+const (
+	// AlertTypeLayerHighWaterMark is raised when a graph layer crosses the
+	// high water mark of its size limit.
+	AlertTypeLayerHighWaterMark int64 = 1001
+	// AlertTypeVolumeQuotaWarning is raised when a volume crosses the
+	// warning threshold of its quota.
+	AlertTypeVolumeQuotaWarning int64 = 2001
+	// AlertTypeVolumeQuotaFull is raised when a volume uses all of its
+	// quota.
+	AlertTypeVolumeQuotaFull int64 = 2002
+)
+
 var (
 	// ErrNotSupported implemenation of a specific function is not supported.
 	ErrNotSupported = errors.New("Implementation not supported")
@@ -28,6 +28,12 @@ const (
 	SpecDedupe               = "dedupe"
 	SpecPassphrase           = "secret_key"
 	SpecAutoAggregationValue = "auto"
+	SpecAccessMode           = "access_mode"
+	SpecMountOptions         = "mount_options"
+	SpecCompressed           = "compressed"
+	SpecIoProfile            = "io_profile"
+	SpecMaxIops              = "max_iops"
+	SpecMaxBandwidth         = "max_bandwidth"
 )
 
 // OptionKey specifies a set of recognized query params.
@@ -42,6 +48,8 @@ const (
 	OptConfigLabel = "ConfigLabel"
 	// OptCumulative query parameter used to request cumulative stats.
 	OptCumulative = "Cumulative"
+	// OptParent query parameter used to export the changes since a snapshot.
+	OptParent = "Parent"
 )
 
 // Api client-server Constants
@@ -199,6 +207,37 @@ func (x IoProfile) SimpleString() string {
 	return simpleString("io_profile", IoProfile_name, int32(x))
 }
 
+// AccessModeSimpleValueOf returns the AccessMode for its string format or
+// one of the rwo, rox and rwx abbreviations.
+func AccessModeSimpleValueOf(s string) (AccessMode, error) {
+	switch strings.ToLower(s) {
+	case "rwo":
+		return AccessMode_ACCESS_MODE_READ_WRITE_ONCE, nil
+	case "rox":
+		return AccessMode_ACCESS_MODE_READ_ONLY_MANY, nil
+	case "rwx":
+		return AccessMode_ACCESS_MODE_READ_WRITE_MANY, nil
+	}
+	obj, err := simpleValueOf("access_mode", AccessMode_value, s)
+	return AccessMode(obj), err
+}
+
+// SimpleString returns the string format of AccessMode
+func (x AccessMode) SimpleString() string {
+	return simpleString("access_mode", AccessMode_name, int32(x))
+}
+
+// StorageMediumSimpleValueOf returns the StorageMedium for its string format
+func StorageMediumSimpleValueOf(s string) (StorageMedium, error) {
+	obj, err := simpleValueOf("storage_medium", StorageMedium_value, s)
+	return StorageMedium(obj), err
+}
+
+// SimpleString returns the string format of StorageMedium
+func (x StorageMedium) SimpleString() string {
+	return simpleString("storage_medium", StorageMedium_name, int32(x))
+}
+
 func simpleValueOf(typeString string, valueMap map[string]int32, s string) (int32, error) {
 	obj, ok := valueMap[strings.ToUpper(fmt.Sprintf("%s_%s", typeString, s))]
 	if !ok {
@@ -468,6 +468,38 @@ func (x ClusterNotify) String() string {
 }
 func (ClusterNotify) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{13} }
 
+// AccessMode specifies how many mounts of a volume are allowed.
+type AccessMode int32
+
+const (
+	// No access restrictions are enforced.
+	AccessMode_ACCESS_MODE_NONE AccessMode = 0
+	// Read write mounts at a single path.
+	AccessMode_ACCESS_MODE_READ_WRITE_ONCE AccessMode = 1
+	// Read only mounts at many paths.
+	AccessMode_ACCESS_MODE_READ_ONLY_MANY AccessMode = 2
+	// Read write mounts at many paths.
+	AccessMode_ACCESS_MODE_READ_WRITE_MANY AccessMode = 3
+)
+
+var AccessMode_name = map[int32]string{
+	0: "ACCESS_MODE_NONE",
+	1: "ACCESS_MODE_READ_WRITE_ONCE",
+	2: "ACCESS_MODE_READ_ONLY_MANY",
+	3: "ACCESS_MODE_READ_WRITE_MANY",
+}
+var AccessMode_value = map[string]int32{
+	"ACCESS_MODE_NONE":            0,
+	"ACCESS_MODE_READ_WRITE_ONCE": 1,
+	"ACCESS_MODE_READ_ONLY_MANY":  2,
+	"ACCESS_MODE_READ_WRITE_MANY": 3,
+}
+
+func (x AccessMode) String() string {
+	return proto.EnumName(AccessMode_name, int32(x))
+}
+func (AccessMode) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{14} }
+
 // StorageResource groups properties of a storage device.
 type StorageResource struct {
 	// Id is the LUN identifier.
@@ -762,6 +794,17 @@ type VolumeSpec struct {
 	MaxBackups uint32 `protobuf:"varint,19,opt,name=max_backups,json=maxBackups" json:"max_backups,omitempty"`
 	// BackupSchedule is the schedule for cloud backups
 	BackupSchedule string `protobuf:"bytes,20,opt,name=backup_schedule,json=backupSchedule" json:"backup_schedule,omitempty"`
+	// AccessMode restricts the concurrent mounts of the volume.
+	AccessMode AccessMode `protobuf:"varint,21,opt,name=access_mode,json=accessMode,enum=openstorage.api.AccessMode" json:"access_mode,omitempty"`
+	// MountOptions are flags such as ro, noexec, nosuid or nodev and
+	// filesystem specific options used to mount the volume.
+	MountOptions []string `protobuf:"bytes,22,rep,name=mount_options,json=mountOptions" json:"mount_options,omitempty"`
+	// Compressed enables compression of the data on the volume.
+	Compressed bool `protobuf:"varint,23,opt,name=compressed" json:"compressed,omitempty"`
+	// MaxIops limits the read and write operations per second of the volume.
+	MaxIops uint64 `protobuf:"varint,24,opt,name=max_iops,json=maxIops" json:"max_iops,omitempty"`
+	// MaxBandwidth limits the read and write bytes per second of the volume.
+	MaxBandwidth uint64 `protobuf:"varint,25,opt,name=max_bandwidth,json=maxBandwidth" json:"max_bandwidth,omitempty"`
 }
 
 func (m *VolumeSpec) Reset()                    { *m = VolumeSpec{} }
@@ -909,6 +952,41 @@ func (m *VolumeSpec) GetBackupSchedule() string {
 	return ""
 }
 
+func (m *VolumeSpec) GetAccessMode() AccessMode {
+	if m != nil {
+		return m.AccessMode
+	}
+	return AccessMode_ACCESS_MODE_NONE
+}
+
+func (m *VolumeSpec) GetMountOptions() []string {
+	if m != nil {
+		return m.MountOptions
+	}
+	return nil
+}
+
+func (m *VolumeSpec) GetCompressed() bool {
+	if m != nil {
+		return m.Compressed
+	}
+	return false
+}
+
+func (m *VolumeSpec) GetMaxIops() uint64 {
+	if m != nil {
+		return m.MaxIops
+	}
+	return 0
+}
+
+func (m *VolumeSpec) GetMaxBandwidth() uint64 {
+	if m != nil {
+		return m.MaxBandwidth
+	}
+	return 0
+}
+
 // ReplicaSet set of machine IDs (nodes) to which part of this volume is erasure coded - for clustered storage arrays
 type ReplicaSet struct {
 	Nodes []string `protobuf:"bytes,1,rep,name=nodes" json:"nodes,omitempty"`
@@ -1727,184 +1805,194 @@ func init() {
 	proto.RegisterEnum("openstorage.api.VolumeStatus", VolumeStatus_name, VolumeStatus_value)
 	proto.RegisterEnum("openstorage.api.StorageMedium", StorageMedium_name, StorageMedium_value)
 	proto.RegisterEnum("openstorage.api.ClusterNotify", ClusterNotify_name, ClusterNotify_value)
+	proto.RegisterEnum("openstorage.api.AccessMode", AccessMode_name, AccessMode_value)
 }
 
 func init() { proto.RegisterFile("api/api.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 2782 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0xac, 0x59, 0x4b, 0x73, 0xe3, 0xc6,
-	0xb5, 0x1e, 0xf0, 0xcd, 0x43, 0x91, 0x82, 0x7a, 0x34, 0x12, 0x47, 0xf3, 0x92, 0x59, 0xd7, 0xb6,
-	0x8a, 0xd7, 0x57, 0x72, 0xc9, 0x8f, 0x3b, 0x9e, 0x7b, 0x2b, 0x29, 0x8a, 0x04, 0x25, 0xc4, 0x7c,
-	0xb9, 0x01, 0x69, 0x6c, 0xa7, 0x52, 0x28, 0x0c, 0xd9, 0x23, 0x21, 0x43, 0x12, 0x18, 0x00, 0x54,
-	0x2c, 0xff, 0x81, 0x6c, 0x52, 0xc9, 0x2a, 0xa9, 0xca, 0x2a, 0x3f, 0xc0, 0xab, 0xac, 0xb3, 0xc8,
-	0xda, 0x8b, 0x54, 0x25, 0xbf, 0x20, 0xbf, 0x21, 0x7f, 0x20, 0x95, 0x3a, 0xdd, 0x0d, 0x12, 0x20,
-	0xa5, 0x79, 0x24, 0xde, 0x75, 0x7f, 0xe7, 0xd1, 0x7d, 0x4e, 0x9f, 0x17, 0x48, 0x28, 0xdb, 0x9e,
-	0x73, 0x60, 0x7b, 0xce, 0xbe, 0xe7, 0xbb, 0xa1, 0x4b, 0xd6, 0x5d, 0x8f, 0x4d, 0x83, 0xd0, 0xf5,
-	0xed, 0x73, 0xb6, 0x6f, 0x7b, 0xce, 0xce, 0xa3, 0x73, 0xd7, 0x3d, 0x1f, 0xb3, 0x03, 0x4e, 0x7e,
-	0x36, 0x7b, 0x7e, 0x10, 0x3a, 0x13, 0x16, 0x84, 0xf6, 0xc4, 0x13, 0x12, 0xb5, 0x7f, 0xa4, 0x60,
-	0xdd, 0x10, 0x02, 0x94, 0x05, 0xee, 0xcc, 0x1f, 0x32, 0x52, 0x81, 0x94, 0x33, 0xaa, 0x2a, 0xbb,
-	0xca, 0x5e, 0x91, 0xa6, 0x9c, 0x11, 0x21, 0x90, 0xf1, 0xec, 0xf0, 0xa2, 0x9a, 0xe2, 0x08, 0x5f,
-	0x93, 0x4f, 0x21, 0x37, 0x61, 0x23, 0x67, 0x36, 0xa9, 0xa6, 0x77, 0x95, 0xbd, 0xca, 0xe1, 0xc3,
-	0xfd, 0xa5, 0xa3, 0xf7, 0xa5, 0xd6, 0x2e, 0xe7, 0xa2, 0x92, 0x9b, 0x6c, 0x41, 0xce, 0x9d, 0x8e,
-	0x9d, 0x29, 0xab, 0x66, 0x76, 0x95, 0xbd, 0x02, 0x95, 0x3b, 0x3c, 0xc3, 0x71, 0xbd, 0xa0, 0x9a,
-	0xdd, 0x55, 0xf6, 0x32, 0x94, 0xaf, 0xc9, 0x3d, 0x28, 0x06, 0xec, 0xa5, 0xf5, 0x0b, 0xdf, 0x09,
-	0x59, 0x35, 0xb7, 0xab, 0xec, 0x29, 0xb4, 0x10, 0xb0, 0x97, 0x4f, 0x71, 0x4f, 0xee, 0x02, 0xae,
-	0x2d, 0x9f, 0xd9, 0xa3, 0x6a, 0x9e, 0xd3, 0xf2, 0x01, 0x7b, 0x49, 0x99, 0x3d, 0xc2, 0x33, 0x7c,
-	0x7b, 0x3a, 0xa2, 0x4f, 0xab, 0x05, 0x4e, 0x90, 0x3b, 0x3c, 0x23, 0x70, 0xbe, 0x65, 0xd5, 0xa2,
-	0x38, 0x03, 0xd7, 0x88, 0xcd, 0x02, 0x36, 0xaa, 0x82, 0xc0, 0x70, 0x4d, 0xde, 0x85, 0x8a, 0xef,
-	0x86, 0x76, 0xe8, 0xb8, 0x53, 0x2b, 0xf0, 0x18, 0x1b, 0x55, 0x4b, 0xdc, 0xf2, 0x72, 0x84, 0x1a,
-	0x08, 0x92, 0xff, 0x85, 0xe2, 0xd8, 0x0e, 0x42, 0x2b, 0x18, 0xda, 0xd3, 0xea, 0xda, 0xae, 0xb2,
-	0x57, 0x3a, 0xdc, 0xd9, 0x17, 0xfe, 0xde, 0x8f, 0xfc, 0xbd, 0x6f, 0x46, 0xfe, 0xa6, 0x05, 0x64,
-	0x36, 0x86, 0xf6, 0xb4, 0xf6, 0x37, 0x05, 0x4a, 0xd2, 0x3b, 0x03, 0xd7, 0x1d, 0xa3, 0xbf, 0xf5,
-	0x16, 0xf7, 0x77, 0x96, 0xa6, 0x9c, 0x16, 0xa9, 0x43, 0xba, 0xe9, 0x06, 0xdc, 0xdd, 0x95, 0xc3,
-	0xea, 0x8a, 0x63, 0x9b, 0x6e, 0x60, 0x5e, 0x79, 0x8c, 0xa6, 0x87, 0x6e, 0x80, 0xef, 0xd0, 0xfd,
-	0x77, 0xde, 0xe1, 0x3e, 0x14, 0xa9, 0xed, 0x8c, 0x3a, 0xec, 0x92, 0x8d, 0xf9, 0x53, 0x14, 0x69,
-	0xd1, 0x8f, 0x00, 0xa4, 0x9a, 0x6e, 0x68, 0x8f, 0x0d, 0x74, 0x57, 0x9e, 0xbb, 0xa6, 0x18, 0x46,
-	0x00, 0xfa, 0xec, 0x14, 0x7d, 0x56, 0x58, 0xf8, 0xac, 0xf6, 0x27, 0x05, 0xca, 0x67, 0xee, 0x78,
-	0x36, 0x61, 0x1d, 0x77, 0x68, 0x87, 0xae, 0x8f, 0x5c, 0x53, 0x7b, 0xc2, 0x64, 0x1c, 0xf1, 0x35,
-	0x39, 0x85, 0xf2, 0x25, 0x67, 0xb2, 0xc6, 0xf6, 0x33, 0x36, 0x46, 0x1b, 0xd3, 0x7b, 0xa5, 0xc3,
-	0x0f, 0x57, 0x2e, 0x9d, 0x50, 0x15, 0xed, 0xb8, 0x88, 0x36, 0x0d, 0xfd, 0x2b, 0xba, 0x76, 0x19,
-	0x83, 0x76, 0x7e, 0x0c, 0x1b, 0x2b, 0x2c, 0x44, 0x85, 0xf4, 0x0b, 0x76, 0x25, 0x8f, 0xc7, 0x25,
-	0xd9, 0x84, 0xec, 0xa5, 0x3d, 0x9e, 0x31, 0x19, 0xc8, 0x62, 0xf3, 0x24, 0xf5, 0x58, 0xa9, 0x7d,
-	0x0c, 0x39, 0x43, 0xc4, 0xfe, 0x16, 0xe4, 0x3c, 0xdb, 0x67, 0xd3, 0x50, 0x0a, 0xca, 0x1d, 0x8f,
-	0x1d, 0x8c, 0x04, 0x99, 0x03, 0xb8, 0xae, 0x6d, 0x43, 0xf6, 0xd8, 0x77, 0x67, 0xde, 0x72, 0xc2,
-	0xd4, 0xfe, 0x9a, 0x03, 0x10, 0x17, 0x32, 0x3c, 0x36, 0x44, 0x6f, 0x32, 0xef, 0x82, 0x4d, 0x98,
-	0x6f, 0x8f, 0x39, 0x57, 0x81, 0x2e, 0x80, 0x79, 0x54, 0xa6, 0x62, 0x51, 0x79, 0x00, 0xb9, 0xe7,
-	0xae, 0x3f, 0xb1, 0x43, 0xf9, 0xaa, 0xdb, 0x2b, 0x0e, 0x6a, 0x1b, 0x3c, 0x06, 0x24, 0x1b, 0x79,
-	0x00, 0xf0, 0x6c, 0xec, 0x0e, 0x5f, 0x58, 0x5c, 0x15, 0xbe, 0x67, 0x9a, 0x16, 0x39, 0xc2, 0x5f,
-	0xec, 0x2e, 0x14, 0x2e, 0x6c, 0x6b, 0xcc, 0x1f, 0x3b, 0xcb, 0x89, 0xf9, 0x0b, 0x5b, 0x3c, 0x75,
-	0x1d, 0x30, 0x8e, 0x78, 0x7a, 0xbd, 0x36, 0xd8, 0x3e, 0x03, 0x70, 0x5c, 0xcb, 0xf3, 0xdd, 0xe7,
-	0xce, 0x58, 0xc4, 0x45, 0xe5, 0x70, 0x67, 0x45, 0x44, 0x77, 0x07, 0x82, 0x83, 0x16, 0x9d, 0x68,
-	0x89, 0x7e, 0x1d, 0xb1, 0xd1, 0xcc, 0x63, 0x3c, 0x6a, 0x0a, 0x54, 0xee, 0xc8, 0x7f, 0xc3, 0x46,
-	0x30, 0xb5, 0xbd, 0xe0, 0xc2, 0x0d, 0x2d, 0x67, 0x1a, 0x32, 0xff, 0xd2, 0x1e, 0xf3, 0x04, 0x2d,
-	0x53, 0x35, 0x22, 0xe8, 0x12, 0x27, 0x74, 0x39, 0x7c, 0x80, 0x87, 0xcf, 0xff, 0xdc, 0x10, 0x3e,
-	0xe8, 0xfc, 0xd7, 0xc5, 0x0e, 0x5e, 0x2c, 0xb8, 0xb0, 0x7d, 0x99, 0xe4, 0x05, 0x2a, 0x77, 0xe4,
-	0xff, 0xa1, 0xe4, 0x33, 0x6f, 0xec, 0x0c, 0x6d, 0x2b, 0x60, 0xa1, 0xcc, 0xef, 0x7b, 0x2b, 0x27,
-	0x51, 0xc1, 0x63, 0xb0, 0x90, 0x82, 0x3f, 0x5f, 0xa3, 0x59, 0xf6, 0xf9, 0xb9, 0xcf, 0xce, 0x45,
-	0x15, 0x11, 0x9e, 0x2f, 0x0b, 0xb3, 0x62, 0x84, 0x79, 0xb6, 0xb1, 0xe9, 0xd0, 0xbf, 0xf2, 0x42,
-	0x36, 0xaa, 0x56, 0x64, 0x7c, 0x44, 0x00, 0x79, 0x08, 0xe0, 0xd9, 0x41, 0xe0, 0x5d, 0xf8, 0x76,
-	0xc0, 0xaa, 0xeb, 0x3c, 0xc8, 0x62, 0x48, 0xc2, 0x83, 0xc1, 0xf0, 0x82, 0x8d, 0x66, 0x63, 0x56,
-	0x55, 0x39, 0xdb, 0xdc, 0x83, 0x86, 0xc4, 0x31, 0x05, 0x82, 0xa1, 0x3d, 0x66, 0xd5, 0x0d, 0x7e,
-	0x17, 0xb1, 0xe1, 0x3e, 0x08, 0x9d, 0xe1, 0x8b, 0xab, 0x2a, 0x91, 0x3e, 0xe0, 0x3b, 0xf2, 0x08,
-	0x4a, 0x13, 0xfb, 0x1b, 0xeb, 0x99, 0x3d, 0x7c, 0x31, 0xf3, 0x82, 0xea, 0x6d, 0x2e, 0x03, 0x13,
-	0xfb, 0x9b, 0x23, 0x81, 0x90, 0xf7, 0x61, 0x5d, 0x10, 0x17, 0x27, 0x6f, 0xf2, 0x93, 0x2b, 0x02,
-	0x8e, 0xce, 0xfd, 0xcf, 0x33, 0xb4, 0x06, 0xb0, 0x70, 0x35, 0xf2, 0x4d, 0xdd, 0x11, 0x0b, 0xaa,
-	0xca, 0x6e, 0x1a, 0xf9, 0xf8, 0xa6, 0xf6, 0x9d, 0x02, 0xeb, 0x74, 0x36, 0xc5, 0x16, 0x67, 0x84,
-	0x76, 0xc8, 0xba, 0xb6, 0x47, 0x9e, 0x42, 0xd9, 0x17, 0x90, 0x15, 0x20, 0xc6, 0x25, 0x4a, 0x87,
-	0x87, 0xab, 0x0f, 0x99, 0x14, 0x4c, 0xec, 0x65, 0xdc, 0xf8, 0x31, 0x08, 0x2d, 0x5a, 0x61, 0x79,
-	0x2b, 0x8b, 0xfe, 0x99, 0x83, 0x9c, 0xf0, 0xc9, 0x4a, 0xc3, 0x3d, 0x80, 0x9c, 0x68, 0xc5, 0x5c,
-	0xaa, 0x74, 0x4d, 0xfa, 0x8b, 0x6a, 0x45, 0x25, 0x1b, 0xf9, 0x00, 0xb2, 0xe7, 0x58, 0x89, 0x78,
-	0xb9, 0x28, 0x1d, 0x6e, 0xad, 0xf0, 0xf3, 0x3a, 0x45, 0x05, 0x13, 0xd9, 0x81, 0x02, 0xb6, 0x4d,
-	0x77, 0x3a, 0xbe, 0x92, 0x5d, 0x78, 0xbe, 0x27, 0x8f, 0x21, 0x3f, 0x16, 0x55, 0x97, 0x17, 0x8a,
-	0xd2, 0x35, 0x0d, 0x25, 0x51, 0x9b, 0x69, 0xc4, 0x4e, 0x3e, 0x84, 0xec, 0x10, 0xdd, 0xc1, 0x4b,
-	0xc9, 0xab, 0x5b, 0xa1, 0x60, 0x24, 0x07, 0x90, 0x09, 0x3c, 0x36, 0xe4, 0x85, 0xe4, 0xba, 0xdc,
-	0x5a, 0x64, 0x31, 0xe5, 0x8c, 0xe8, 0xcc, 0x59, 0x60, 0x9f, 0x33, 0xd9, 0x79, 0xc4, 0x26, 0xd9,
-	0x87, 0x8b, 0x6f, 0xde, 0x87, 0x63, 0x55, 0x16, 0xde, 0xac, 0xca, 0x7e, 0x82, 0x79, 0x62, 0x87,
-	0xb3, 0x80, 0xd7, 0x8a, 0xca, 0xe1, 0x83, 0x9b, 0xae, 0xcc, 0x99, 0xa8, 0x64, 0x26, 0x87, 0x90,
-	0x15, 0xb1, 0xb7, 0xc6, 0xa5, 0xee, 0xbf, 0x42, 0x8a, 0x51, 0xc1, 0x8a, 0xa9, 0x67, 0x87, 0xa1,
-	0x8d, 0xf9, 0x63, 0xb9, 0x53, 0x5e, 0x3a, 0x8a, 0x14, 0x22, 0xa8, 0x3f, 0x45, 0x86, 0x11, 0xbb,
-	0x74, 0x86, 0xcc, 0xe2, 0xb3, 0x59, 0x45, 0x30, 0x08, 0x68, 0x80, 0x13, 0xda, 0x5c, 0x83, 0x60,
-	0x58, 0xe7, 0x99, 0x22, 0x35, 0x70, 0x86, 0x1f, 0xc1, 0x5a, 0xac, 0xc2, 0x05, 0x55, 0x95, 0x67,
-	0xc6, 0x2b, 0x4b, 0x5c, 0x69, 0x51, 0xe2, 0x02, 0x7c, 0x0d, 0xe6, 0xfb, 0xae, 0xcf, 0x6b, 0x49,
-	0x91, 0x8a, 0x0d, 0xd1, 0x96, 0x13, 0x8e, 0x70, 0xb5, 0xbb, 0xaf, 0x4b, 0xb8, 0x64, 0x7a, 0x91,
-	0x0f, 0x80, 0x04, 0x6c, 0x38, 0xf3, 0x99, 0x15, 0xb7, 0xf2, 0xb6, 0x2c, 0x6b, 0x9c, 0xd2, 0x5a,
-	0xd8, 0xfa, 0x11, 0xdc, 0xc1, 0x82, 0x83, 0xe1, 0x3d, 0x1d, 0x61, 0x83, 0x1a, 0xb2, 0x20, 0x70,
-	0xa6, 0xe7, 0xbc, 0x1a, 0x15, 0xe8, 0xe6, 0x82, 0x38, 0x98, 0xd3, 0x6a, 0x7f, 0x48, 0x41, 0x16,
-	0x0f, 0xe3, 0x96, 0x60, 0x02, 0x04, 0x3c, 0x05, 0x33, 0x54, 0x6c, 0xc8, 0x36, 0xe4, 0x71, 0x61,
-	0x4d, 0x02, 0xd9, 0x9b, 0x73, 0xb8, 0xed, 0x06, 0xd8, 0x6c, 0x39, 0xe1, 0xd9, 0x55, 0xc8, 0x02,
-	0x9e, 0x72, 0x19, 0x5a, 0x44, 0xe4, 0x08, 0x01, 0xac, 0xa6, 0x7c, 0x64, 0x0d, 0x78, 0x72, 0x65,
-	0xa8, 0xdc, 0x61, 0x13, 0xe6, 0x2b, 0x54, 0x28, 0xc6, 0xdc, 0x3c, 0xdf, 0x77, 0x03, 0x7c, 0x2b,
-	0x41, 0x12, 0x2a, 0x73, 0x9c, 0x0a, 0x1c, 0x12, 0x3a, 0x1f, 0x41, 0x49, 0x74, 0xde, 0x73, 0x9f,
-	0x05, 0x81, 0x1c, 0xc9, 0x80, 0xb7, 0x57, 0x8e, 0x90, 0xdb, 0x90, 0x75, 0x5c, 0xd4, 0x5c, 0x88,
-	0x06, 0x68, 0x71, 0x51, 0xae, 0xd0, 0xe2, 0x23, 0xae, 0x18, 0x7b, 0x8b, 0x1c, 0xc1, 0xf9, 0x8d,
-	0x2b, 0x95, 0xad, 0x15, 0x25, 0x41, 0x2a, 0x95, 0x50, 0x37, 0xa8, 0xfd, 0x25, 0x05, 0xd9, 0xc6,
-	0x98, 0xf9, 0x61, 0xac, 0x42, 0xa5, 0x79, 0x85, 0xfa, 0x0c, 0xa7, 0xef, 0x4b, 0xe6, 0x3b, 0xe1,
-	0x95, 0x9c, 0x53, 0x57, 0x73, 0xc1, 0x90, 0x0c, 0x3c, 0x85, 0xe6, 0xec, 0x78, 0x29, 0x1b, 0x75,
-	0x5a, 0xe1, 0x95, 0xc7, 0xb8, 0xf7, 0xd2, 0xb4, 0xc8, 0x11, 0x64, 0x24, 0x55, 0xc8, 0x4f, 0x58,
-	0xc0, 0xb3, 0x5c, 0x8c, 0xa5, 0xd1, 0x96, 0x3c, 0x86, 0xe2, 0xfc, 0xeb, 0x45, 0x16, 0xa7, 0x57,
-	0xe5, 0xf9, 0x82, 0x19, 0x0d, 0xf5, 0xe5, 0xc7, 0x8d, 0xe5, 0x8c, 0xb8, 0x7b, 0x8b, 0xd8, 0xae,
-	0x05, 0xa4, 0x73, 0x73, 0xa2, 0x9d, 0x1c, 0x6b, 0x1e, 0x5c, 0x93, 0x06, 0x82, 0x41, 0x98, 0x13,
-	0xb1, 0xe3, 0x7d, 0x87, 0x63, 0xc6, 0x07, 0x08, 0x31, 0xd9, 0x44, 0x5b, 0x6c, 0x06, 0x61, 0x38,
-	0x96, 0x6e, 0xc7, 0x65, 0xed, 0x53, 0xc8, 0x71, 0x77, 0x06, 0x58, 0xb0, 0xb9, 0xc9, 0xb2, 0x1d,
-	0xad, 0x16, 0x6c, 0xce, 0x47, 0x05, 0x53, 0xed, 0x8f, 0x0a, 0xdc, 0x16, 0x35, 0xa2, 0xe9, 0x33,
-	0x2c, 0x12, 0xec, 0xe5, 0x8c, 0x05, 0x61, 0xbc, 0x58, 0x2b, 0x6f, 0x57, 0xac, 0xdf, 0xba, 0xc3,
-	0x44, 0xb5, 0x3a, 0xfd, 0x86, 0xb5, 0xba, 0xf6, 0x1e, 0x54, 0x04, 0x46, 0x59, 0xe0, 0xb9, 0xd3,
-	0x80, 0x2d, 0xea, 0x85, 0x12, 0xab, 0x17, 0x35, 0x0f, 0x36, 0x93, 0xa6, 0x49, 0xee, 0xe5, 0x9e,
-	0x78, 0x02, 0xeb, 0x72, 0xf6, 0xf3, 0x25, 0x8b, 0xbc, 0xfa, 0xa3, 0x1b, 0xee, 0x12, 0x69, 0xa2,
-	0x95, 0xcb, 0xc4, 0xbe, 0xf6, 0xbd, 0x12, 0x0d, 0x23, 0xbc, 0xd4, 0x34, 0x86, 0x38, 0x89, 0x91,
-	0x27, 0x90, 0x13, 0xb5, 0x91, 0x9f, 0x59, 0x39, 0xac, 0xdd, 0xa0, 0x56, 0xb0, 0x0f, 0x6c, 0xdf,
-	0x9e, 0x50, 0x29, 0x41, 0x1e, 0x43, 0x76, 0xe2, 0xce, 0xa6, 0xa1, 0x4c, 0x85, 0x37, 0x11, 0x15,
-	0x02, 0x98, 0x0c, 0x7c, 0x21, 0xca, 0x5b, 0x5a, 0x7c, 0x87, 0x71, 0x24, 0xaa, 0xe1, 0xf1, 0xf2,
-	0x97, 0x59, 0x2e, 0xf2, 0xb5, 0x3f, 0xa7, 0x40, 0x95, 0xb6, 0xb0, 0xf0, 0x87, 0x08, 0x0b, 0xf1,
-	0xca, 0xa9, 0x37, 0xed, 0xc8, 0xe8, 0x35, 0x6e, 0x95, 0x0c, 0x8c, 0xda, 0xab, 0x7a, 0x9b, 0xb0,
-	0x9f, 0x4a, 0x09, 0x72, 0x02, 0x79, 0xd7, 0xc3, 0x15, 0x16, 0x4a, 0xcc, 0x82, 0xfd, 0x9b, 0x84,
-	0xe7, 0xa6, 0xed, 0xf7, 0x85, 0x80, 0x18, 0xc8, 0x22, 0xf1, 0x9d, 0x27, 0xb0, 0x16, 0x27, 0xbc,
-	0xd5, 0x18, 0xf6, 0xeb, 0x45, 0x34, 0xe0, 0x31, 0x32, 0xfa, 0x0e, 0x20, 0x27, 0xa2, 0x46, 0x7a,
-	0x70, 0xfb, 0xa6, 0x20, 0x93, 0x6c, 0x3f, 0x60, 0x78, 0x5e, 0xc1, 0x86, 0x31, 0xb5, 0xbd, 0x64,
-	0xa6, 0x2f, 0x67, 0x43, 0xec, 0x89, 0x53, 0x6f, 0xf7, 0xc4, 0xf1, 0xe1, 0x2f, 0x9d, 0x1c, 0xfe,
-	0x6a, 0x2f, 0x81, 0xc4, 0x8f, 0x96, 0xbe, 0xf8, 0x29, 0x6c, 0x49, 0xd3, 0x86, 0x9c, 0xb0, 0xb0,
-	0x50, 0xf8, 0xe6, 0xdd, 0x1b, 0x8e, 0x4e, 0xaa, 0xa1, 0x9b, 0x97, 0xd7, 0xa0, 0xb5, 0x30, 0xfa,
-	0x52, 0xd6, 0xa7, 0xcf, 0x5d, 0x72, 0x0f, 0x8a, 0xf2, 0xa8, 0xb9, 0xb5, 0x05, 0x01, 0xe8, 0xd7,
-	0xff, 0x0c, 0xf5, 0x09, 0xe4, 0xe5, 0xc1, 0x6f, 0x52, 0x99, 0x22, 0xde, 0xda, 0x08, 0xc8, 0xb1,
-	0x6f, 0x7b, 0x17, 0x2d, 0xdf, 0xb9, 0x64, 0x7e, 0xf3, 0xc2, 0x9e, 0x9e, 0xb3, 0x60, 0x7e, 0x80,
-	0x12, 0x3b, 0xe0, 0x09, 0x64, 0x5e, 0x38, 0xd3, 0x91, 0xcc, 0xec, 0xf7, 0xae, 0x19, 0xac, 0x97,
-	0xd4, 0xf0, 0xf6, 0xc0, 0x65, 0x6a, 0xef, 0xc3, 0x7a, 0x73, 0x3c, 0x0b, 0x42, 0xe6, 0xbf, 0xa6,
-	0x06, 0xfe, 0x4e, 0x81, 0x32, 0x26, 0xc7, 0xe5, 0xfc, 0xbd, 0x4f, 0xa0, 0x40, 0xd9, 0x4b, 0x16,
-	0x84, 0x9f, 0x9f, 0xc9, 0x16, 0xf1, 0xc1, 0x6a, 0x8b, 0x88, 0x4b, 0xec, 0x47, 0xec, 0x22, 0x35,
-	0x0a, 0xbe, 0xdc, 0xee, 0xfc, 0x1f, 0x94, 0x13, 0xa4, 0x78, 0x72, 0xa4, 0x5f, 0x97, 0x1c, 0xdf,
-	0x42, 0x25, 0x71, 0x4a, 0x40, 0x6a, 0xb0, 0x26, 0xd7, 0x4d, 0x5e, 0xf1, 0x84, 0x9a, 0x35, 0x3f,
-	0x86, 0x91, 0xd6, 0x92, 0x35, 0xf2, 0x57, 0x9e, 0x87, 0xaf, 0xb6, 0x80, 0x96, 0xed, 0xf8, 0xb6,
-	0xfe, 0x7d, 0x0a, 0x72, 0x62, 0x90, 0x26, 0xeb, 0x50, 0x32, 0xcc, 0x86, 0x79, 0x6a, 0x58, 0xbd,
-	0x7e, 0x4f, 0x53, 0x6f, 0xc5, 0x00, 0xbd, 0xa7, 0x9b, 0xaa, 0x42, 0xca, 0x50, 0x94, 0x40, 0xff,
-	0x73, 0x35, 0x45, 0x08, 0x54, 0xa2, 0x6d, 0xbb, 0xdd, 0xd1, 0x7b, 0x9a, 0x9a, 0x26, 0x2a, 0xac,
-	0x49, 0x4c, 0xa3, 0xb4, 0x4f, 0xd5, 0x0c, 0xa9, 0xc2, 0xe6, 0x5c, 0xad, 0x69, 0xe9, 0x3d, 0xeb,
-	0x8b, 0xd3, 0x3e, 0x3d, 0xed, 0xaa, 0x59, 0xb2, 0x0d, 0xb7, 0x25, 0xa5, 0xa5, 0x35, 0xfb, 0xdd,
-	0xae, 0x6e, 0x18, 0x7a, 0xbf, 0xa7, 0xe6, 0xc8, 0x16, 0x10, 0x49, 0xe8, 0x36, 0xf4, 0x9e, 0xa9,
-	0xf5, 0x1a, 0xbd, 0xa6, 0xa6, 0xe6, 0x63, 0x02, 0x86, 0xd9, 0xa7, 0x8d, 0x63, 0xcd, 0x6a, 0xf5,
-	0x9f, 0xf6, 0xd4, 0x02, 0xb9, 0x07, 0xdb, 0xcb, 0x04, 0xed, 0x98, 0x36, 0x5a, 0x5a, 0x4b, 0x2d,
-	0xc6, 0xa4, 0x7a, 0x9a, 0xd6, 0x32, 0x2c, 0xaa, 0x1d, 0xf5, 0xfb, 0xa6, 0x0a, 0xe4, 0x3e, 0x54,
-	0x97, 0xa4, 0xa8, 0x76, 0xd4, 0xe8, 0xf0, 0xc3, 0x4a, 0x64, 0x17, 0xee, 0x2f, 0xeb, 0xa4, 0xfa,
-	0x19, 0xf2, 0x0c, 0x3a, 0x8d, 0xa6, 0xa6, 0xae, 0x91, 0x0a, 0xc0, 0xfc, 0x9a, 0x5f, 0xaa, 0xe5,
-	0xfa, 0xef, 0x15, 0x00, 0x11, 0xa4, 0x7c, 0xc6, 0xda, 0x04, 0x95, 0x4b, 0x50, 0xcb, 0xfc, 0x6a,
-	0xa0, 0x45, 0x4e, 0x5d, 0x42, 0xdb, 0x7a, 0x47, 0x53, 0x15, 0x72, 0x07, 0x36, 0xe2, 0xe8, 0x51,
-	0xa7, 0xdf, 0x44, 0x0f, 0x6f, 0x01, 0x89, 0xc3, 0xfd, 0xa3, 0x9f, 0x68, 0x4d, 0x53, 0x4d, 0x93,
-	0xbb, 0x70, 0x27, 0x8e, 0x37, 0x3b, 0xa7, 0x86, 0xa9, 0x51, 0xad, 0xa5, 0x66, 0x96, 0x35, 0x1d,
-	0xd3, 0xc6, 0xe0, 0x44, 0xcd, 0xd6, 0x7f, 0xab, 0x40, 0x4e, 0x7c, 0x67, 0xe1, 0x13, 0xb5, 0x8d,
-	0xc4, 0x9d, 0x36, 0xa0, 0x1c, 0x21, 0x47, 0x26, 0x6d, 0x1b, 0xaa, 0x12, 0x67, 0xd2, 0xbe, 0x34,
-	0x3f, 0x56, 0x53, 0x71, 0xa4, 0x7d, 0x6a, 0xe0, 0x5b, 0xaf, 0x43, 0x69, 0xae, 0xa8, 0x6d, 0xa8,
-	0x99, 0x38, 0x70, 0xd6, 0x36, 0xd4, 0x6c, 0x1c, 0xf8, 0xb2, 0x6d, 0xa8, 0xb9, 0x38, 0xf0, 0x75,
-	0xdb, 0x50, 0xf3, 0xf5, 0xef, 0x14, 0xb8, 0x73, 0x6d, 0x76, 0x93, 0x77, 0xe0, 0x01, 0xbf, 0xbc,
-	0x25, 0xcd, 0x69, 0x9e, 0x34, 0x7a, 0xc7, 0x5a, 0xe2, 0xde, 0xef, 0xc2, 0x3b, 0x37, 0xb2, 0x74,
-	0xfb, 0x2d, 0xbd, 0xad, 0x6b, 0x2d, 0x55, 0x21, 0x35, 0x78, 0x78, 0x23, 0x5b, 0xa3, 0x85, 0x41,
-	0x92, 0x22, 0xff, 0x05, 0xbb, 0x37, 0xf2, 0xb4, 0xb4, 0x8e, 0x66, 0x6a, 0x2d, 0x35, 0x5d, 0x0f,
-	0x61, 0x2d, 0x3e, 0x6f, 0xf3, 0x40, 0xd5, 0xce, 0x34, 0xaa, 0x9b, 0x5f, 0x25, 0x2e, 0x86, 0x21,
-	0x97, 0xc0, 0x1b, 0x9d, 0x06, 0xed, 0xaa, 0x0a, 0x3e, 0x5c, 0x92, 0xf0, 0xb4, 0x41, 0x7b, 0x7a,
-	0xef, 0x58, 0x4d, 0xf1, 0x3c, 0x59, 0xd2, 0x65, 0xea, 0xed, 0xaf, 0xd4, 0x74, 0xfd, 0x57, 0x0a,
-	0x96, 0x83, 0xc5, 0x5c, 0x8c, 0xc7, 0x52, 0xcd, 0xe8, 0x9f, 0xd2, 0x66, 0xd2, 0x1f, 0x55, 0xd8,
-	0x4c, 0xe2, 0x67, 0xfd, 0xce, 0x69, 0x17, 0xe3, 0xeb, 0x1a, 0x89, 0x96, 0xa6, 0xa6, 0xf0, 0x3e,
-	0x49, 0x5c, 0x86, 0x92, 0x9a, 0x46, 0x1b, 0x92, 0x24, 0xee, 0x19, 0x35, 0x53, 0xff, 0xa5, 0x02,
-	0xeb, 0x7c, 0x70, 0x16, 0x93, 0x06, 0xbf, 0xd1, 0x0e, 0x6c, 0x35, 0x3a, 0x1a, 0x35, 0xad, 0x46,
-	0xd3, 0xd4, 0xfb, 0xbd, 0xc4, 0xad, 0xee, 0x43, 0x75, 0x95, 0x26, 0x7c, 0xaa, 0x2a, 0xd7, 0x53,
-	0x9b, 0x54, 0x6b, 0x98, 0x78, 0xbf, 0x6b, 0xa9, 0xa7, 0x83, 0x16, 0x52, 0xd3, 0xf5, 0x9f, 0x47,
-	0x43, 0x45, 0x6c, 0xe6, 0x43, 0x11, 0x61, 0x76, 0x24, 0x33, 0x68, 0xd0, 0x46, 0x37, 0xba, 0xcc,
-	0x3d, 0xd8, 0xbe, 0x8e, 0xda, 0x6f, 0xb7, 0x55, 0x05, 0xad, 0xb8, 0x96, 0xd8, 0x53, 0x53, 0xf5,
-	0x43, 0xc8, 0xcb, 0x5f, 0x69, 0x49, 0x01, 0x32, 0x52, 0x5b, 0x1e, 0xd2, 0x9d, 0xfe, 0x53, 0x55,
-	0x21, 0x00, 0xb9, 0xae, 0xd6, 0xd2, 0x4f, 0xbb, 0x6a, 0x0a, 0xc9, 0x27, 0xfa, 0xf1, 0x89, 0x9a,
-	0xae, 0x0f, 0xa0, 0x38, 0xff, 0x99, 0x16, 0x5d, 0xad, 0xf7, 0xad, 0x01, 0xed, 0x63, 0xca, 0x5b,
-	0x86, 0xf6, 0xc5, 0xa9, 0xd6, 0x33, 0xf5, 0x46, 0x47, 0xbd, 0x85, 0x39, 0x1b, 0x23, 0xd1, 0x46,
-	0xaf, 0xd5, 0xc7, 0x60, 0xd9, 0x80, 0x72, 0x0c, 0x6e, 0x1d, 0xa9, 0xa9, 0xfa, 0xdf, 0x15, 0x28,
-	0xc5, 0x66, 0x3d, 0x94, 0x94, 0x37, 0xc6, 0x4a, 0x14, 0x0f, 0x84, 0x04, 0x3c, 0xd0, 0x7a, 0x2d,
-	0x8c, 0xb2, 0xb8, 0x89, 0x82, 0xd2, 0x38, 0x6b, 0xe8, 0x9d, 0xc6, 0x51, 0x47, 0x06, 0x43, 0x92,
-	0x66, 0x9a, 0x8d, 0xe6, 0x09, 0x06, 0xfe, 0x0a, 0xa9, 0xa5, 0x49, 0x52, 0x26, 0xe6, 0xd1, 0x05,
-	0xc9, 0x6c, 0x9e, 0xe0, 0x71, 0x59, 0x8c, 0xbb, 0x04, 0x51, 0x34, 0x85, 0xdc, 0xca, 0x05, 0xa3,
-	0x14, 0xcb, 0xd7, 0x7f, 0xa3, 0xc0, 0x5a, 0xfc, 0xf7, 0x9d, 0x25, 0x15, 0x8b, 0xee, 0xf4, 0x00,
-	0xee, 0x2e, 0xe3, 0xa6, 0x35, 0xa0, 0x9a, 0xa1, 0xf5, 0xb0, 0x57, 0x6d, 0x82, 0x9a, 0x24, 0x9f,
-	0x0e, 0x44, 0x41, 0x4d, 0xa2, 0xbc, 0x81, 0xa4, 0x97, 0xdc, 0xc2, 0x3b, 0x92, 0xec, 0x1f, 0x99,
-	0xfa, 0xcf, 0xa0, 0x9c, 0xf8, 0x77, 0x47, 0x74, 0x1b, 0xd1, 0x12, 0xc4, 0xa3, 0x5b, 0xdd, 0xc6,
-	0x71, 0x4f, 0x33, 0xf5, 0xa6, 0x7a, 0x4b, 0xf4, 0xae, 0x04, 0xd1, 0x30, 0xb0, 0x08, 0xf1, 0x2e,
-	0x94, 0xc0, 0x7b, 0x67, 0x5d, 0x4d, 0x4d, 0xd5, 0xf7, 0xa0, 0x2c, 0xe7, 0x97, 0x9e, 0x1b, 0x3a,
-	0xcf, 0xaf, 0x90, 0x53, 0x66, 0xa1, 0x2c, 0x01, 0xe2, 0x92, 0xb7, 0x8e, 0xee, 0xc3, 0xed, 0xa1,
-	0x3b, 0x59, 0xee, 0xef, 0x03, 0xe5, 0xeb, 0xb4, 0xed, 0x39, 0xcf, 0x72, 0xfc, 0xeb, 0xfc, 0xa3,
-	0x7f, 0x05, 0x00, 0x00, 0xff, 0xff, 0x66, 0x20, 0x0c, 0x41, 0xad, 0x1c, 0x00, 0x00,
+	// 2925 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x59, 0x4b, 0x73, 0xdb, 0xd6,
+	0xf5, 0x37, 0xf8, 0xe6, 0xa1, 0x48, 0xc1, 0xd7, 0xb2, 0x0c, 0xcb, 0x2f, 0x85, 0xff, 0x7f, 0x12,
+	0x0d, 0x9b, 0xca, 0x19, 0xe5, 0x51, 0xc7, 0xcd, 0xb4, 0x03, 0x91, 0xa0, 0xc4, 0x86, 0xaf, 0x5c,
+	0x50, 0x72, 0x9c, 0x4e, 0x07, 0x03, 0x93, 0xd7, 0x12, 0x6a, 0x92, 0x80, 0x01, 0x50, 0x89, 0xb2,
+	0xee, 0x4c, 0x37, 0x9d, 0x76, 0xd1, 0x69, 0x67, 0xba, 0xea, 0x07, 0xc8, 0xaa, 0xeb, 0x2e, 0xba,
+	0xce, 0xa2, 0x8b, 0x7e, 0x82, 0x7e, 0x86, 0x7e, 0x81, 0x4e, 0xe7, 0xdc, 0x7b, 0x41, 0x02, 0xa4,
+	0x64, 0xcb, 0x6d, 0x76, 0xf7, 0xfe, 0xce, 0xe3, 0xde, 0x73, 0xee, 0x79, 0x81, 0x84, 0xb2, 0xed,
+	0x39, 0x0f, 0x6d, 0xcf, 0xd9, 0xf5, 0x7c, 0x37, 0x74, 0xc9, 0xba, 0xeb, 0xb1, 0x69, 0x10, 0xba,
+	0xbe, 0x7d, 0xc2, 0x76, 0x6d, 0xcf, 0xd9, 0x7a, 0x70, 0xe2, 0xba, 0x27, 0x63, 0xf6, 0x90, 0x93,
+	0x9f, 0xcd, 0x9e, 0x3f, 0x0c, 0x9d, 0x09, 0x0b, 0x42, 0x7b, 0xe2, 0x09, 0x89, 0xea, 0xbf, 0x52,
+	0xb0, 0x6e, 0x0a, 0x01, 0xca, 0x02, 0x77, 0xe6, 0x0f, 0x19, 0xa9, 0x40, 0xca, 0x19, 0x69, 0xca,
+	0xb6, 0xb2, 0x53, 0xa4, 0x29, 0x67, 0x44, 0x08, 0x64, 0x3c, 0x3b, 0x3c, 0xd5, 0x52, 0x1c, 0xe1,
+	0x6b, 0xf2, 0x31, 0xe4, 0x26, 0x6c, 0xe4, 0xcc, 0x26, 0x5a, 0x7a, 0x5b, 0xd9, 0xa9, 0xec, 0xdd,
+	0xdf, 0x5d, 0x3a, 0x7a, 0x57, 0x6a, 0xed, 0x70, 0x2e, 0x2a, 0xb9, 0xc9, 0x26, 0xe4, 0xdc, 0xe9,
+	0xd8, 0x99, 0x32, 0x2d, 0xb3, 0xad, 0xec, 0x14, 0xa8, 0xdc, 0xe1, 0x19, 0x8e, 0xeb, 0x05, 0x5a,
+	0x76, 0x5b, 0xd9, 0xc9, 0x50, 0xbe, 0x26, 0x77, 0xa0, 0x18, 0xb0, 0x97, 0xd6, 0x57, 0xbe, 0x13,
+	0x32, 0x2d, 0xb7, 0xad, 0xec, 0x28, 0xb4, 0x10, 0xb0, 0x97, 0x4f, 0x70, 0x4f, 0x6e, 0x03, 0xae,
+	0x2d, 0x9f, 0xd9, 0x23, 0x2d, 0xcf, 0x69, 0xf9, 0x80, 0xbd, 0xa4, 0xcc, 0x1e, 0xe1, 0x19, 0xbe,
+	0x3d, 0x1d, 0xd1, 0x27, 0x5a, 0x81, 0x13, 0xe4, 0x0e, 0xcf, 0x08, 0x9c, 0x6f, 0x98, 0x56, 0x14,
+	0x67, 0xe0, 0x1a, 0xb1, 0x59, 0xc0, 0x46, 0x1a, 0x08, 0x0c, 0xd7, 0xe4, 0x6d, 0xa8, 0xf8, 0x6e,
+	0x68, 0x87, 0x8e, 0x3b, 0xb5, 0x02, 0x8f, 0xb1, 0x91, 0x56, 0xe2, 0x96, 0x97, 0x23, 0xd4, 0x44,
+	0x90, 0xfc, 0x08, 0x8a, 0x63, 0x3b, 0x08, 0xad, 0x60, 0x68, 0x4f, 0xb5, 0xb5, 0x6d, 0x65, 0xa7,
+	0xb4, 0xb7, 0xb5, 0x2b, 0xfc, 0xbd, 0x1b, 0xf9, 0x7b, 0x77, 0x10, 0xf9, 0x9b, 0x16, 0x90, 0xd9,
+	0x1c, 0xda, 0xd3, 0xea, 0x3f, 0x14, 0x28, 0x49, 0xef, 0xf4, 0x5d, 0x77, 0x8c, 0xfe, 0x6e, 0x35,
+	0xb8, 0xbf, 0xb3, 0x34, 0xe5, 0x34, 0x48, 0x0d, 0xd2, 0x75, 0x37, 0xe0, 0xee, 0xae, 0xec, 0x69,
+	0x2b, 0x8e, 0xad, 0xbb, 0xc1, 0xe0, 0xdc, 0x63, 0x34, 0x3d, 0x74, 0x03, 0x7c, 0x87, 0xce, 0x7f,
+	0xf3, 0x0e, 0x77, 0xa1, 0x48, 0x6d, 0x67, 0xd4, 0x66, 0x67, 0x6c, 0xcc, 0x9f, 0xa2, 0x48, 0x8b,
+	0x7e, 0x04, 0x20, 0x75, 0xe0, 0x86, 0xf6, 0xd8, 0x44, 0x77, 0xe5, 0xb9, 0x6b, 0x8a, 0x61, 0x04,
+	0xa0, 0xcf, 0x8e, 0xd0, 0x67, 0x85, 0x85, 0xcf, 0xaa, 0x7f, 0x55, 0xa0, 0x7c, 0xec, 0x8e, 0x67,
+	0x13, 0xd6, 0x76, 0x87, 0x76, 0xe8, 0xfa, 0xc8, 0x35, 0xb5, 0x27, 0x4c, 0xc6, 0x11, 0x5f, 0x93,
+	0x23, 0x28, 0x9f, 0x71, 0x26, 0x6b, 0x6c, 0x3f, 0x63, 0x63, 0xb4, 0x31, 0xbd, 0x53, 0xda, 0x7b,
+	0x7f, 0xe5, 0xd2, 0x09, 0x55, 0xd1, 0x8e, 0x8b, 0x18, 0xd3, 0xd0, 0x3f, 0xa7, 0x6b, 0x67, 0x31,
+	0x68, 0xeb, 0xa7, 0x70, 0x7d, 0x85, 0x85, 0xa8, 0x90, 0x7e, 0xc1, 0xce, 0xe5, 0xf1, 0xb8, 0x24,
+	0x1b, 0x90, 0x3d, 0xb3, 0xc7, 0x33, 0x26, 0x03, 0x59, 0x6c, 0x1e, 0xa7, 0x1e, 0x29, 0xd5, 0x0f,
+	0x21, 0x67, 0x8a, 0xd8, 0xdf, 0x84, 0x9c, 0x67, 0xfb, 0x6c, 0x1a, 0x4a, 0x41, 0xb9, 0xe3, 0xb1,
+	0x83, 0x91, 0x20, 0x73, 0x00, 0xd7, 0xd5, 0x5b, 0x90, 0x3d, 0xf0, 0xdd, 0x99, 0xb7, 0x9c, 0x30,
+	0xd5, 0xdf, 0x17, 0x00, 0xc4, 0x85, 0x4c, 0x8f, 0x0d, 0xd1, 0x9b, 0xcc, 0x3b, 0x65, 0x13, 0xe6,
+	0xdb, 0x63, 0xce, 0x55, 0xa0, 0x0b, 0x60, 0x1e, 0x95, 0xa9, 0x58, 0x54, 0x3e, 0x84, 0xdc, 0x73,
+	0xd7, 0x9f, 0xd8, 0xa1, 0x7c, 0xd5, 0x5b, 0x2b, 0x0e, 0x6a, 0x9a, 0x3c, 0x06, 0x24, 0x1b, 0xb9,
+	0x07, 0xf0, 0x6c, 0xec, 0x0e, 0x5f, 0x58, 0x5c, 0x15, 0xbe, 0x67, 0x9a, 0x16, 0x39, 0xc2, 0x5f,
+	0xec, 0x36, 0x14, 0x4e, 0x6d, 0x6b, 0xcc, 0x1f, 0x3b, 0xcb, 0x89, 0xf9, 0x53, 0x5b, 0x3c, 0x75,
+	0x0d, 0x30, 0x8e, 0xb4, 0xdc, 0x55, 0x82, 0xed, 0x13, 0x00, 0xc7, 0xb5, 0x3c, 0xdf, 0x7d, 0xee,
+	0x8c, 0x45, 0x5c, 0x54, 0xf6, 0xb6, 0x56, 0x44, 0x5a, 0x6e, 0x5f, 0x70, 0xd0, 0xa2, 0x13, 0x2d,
+	0xd1, 0xaf, 0x23, 0x36, 0x9a, 0x79, 0x8c, 0x47, 0x4d, 0x81, 0xca, 0x1d, 0xf9, 0x01, 0x5c, 0x0f,
+	0xa6, 0xb6, 0x17, 0x9c, 0xba, 0xa1, 0xe5, 0x4c, 0x43, 0xe6, 0x9f, 0xd9, 0x63, 0x9e, 0xa0, 0x65,
+	0xaa, 0x46, 0x84, 0x96, 0xc4, 0x09, 0x5d, 0x0e, 0x1f, 0xe0, 0xe1, 0xf3, 0xc3, 0x4b, 0xc2, 0x07,
+	0x9d, 0xff, 0xba, 0xd8, 0xc1, 0x8b, 0x05, 0xa7, 0xb6, 0x2f, 0x93, 0xbc, 0x40, 0xe5, 0x8e, 0x7c,
+	0x0a, 0x25, 0x9f, 0x79, 0x63, 0x67, 0x68, 0x5b, 0x01, 0x0b, 0x65, 0x7e, 0xdf, 0x59, 0x39, 0x89,
+	0x0a, 0x1e, 0x93, 0x85, 0x14, 0xfc, 0xf9, 0x1a, 0xcd, 0xb2, 0x4f, 0x4e, 0x7c, 0x76, 0x22, 0xaa,
+	0x88, 0xf0, 0x7c, 0x59, 0x98, 0x15, 0x23, 0xcc, 0xb3, 0x8d, 0x4d, 0x87, 0xfe, 0xb9, 0x17, 0xb2,
+	0x91, 0x56, 0x91, 0xf1, 0x11, 0x01, 0xe4, 0x3e, 0x80, 0x67, 0x07, 0x81, 0x77, 0xea, 0xdb, 0x01,
+	0xd3, 0xd6, 0x79, 0x90, 0xc5, 0x90, 0x84, 0x07, 0x83, 0xe1, 0x29, 0x1b, 0xcd, 0xc6, 0x4c, 0x53,
+	0x39, 0xdb, 0xdc, 0x83, 0xa6, 0xc4, 0x31, 0x05, 0x82, 0xa1, 0x3d, 0x66, 0xda, 0x75, 0x7e, 0x17,
+	0xb1, 0xe1, 0x3e, 0x08, 0x9d, 0xe1, 0x8b, 0x73, 0x8d, 0x48, 0x1f, 0xf0, 0x1d, 0x79, 0x00, 0xa5,
+	0x89, 0xfd, 0xb5, 0xf5, 0xcc, 0x1e, 0xbe, 0x98, 0x79, 0x81, 0x76, 0x83, 0xcb, 0xc0, 0xc4, 0xfe,
+	0x7a, 0x5f, 0x20, 0xe4, 0x5d, 0x58, 0x17, 0xc4, 0xc5, 0xc9, 0x1b, 0xfc, 0xe4, 0x8a, 0x80, 0xe7,
+	0xe7, 0x7e, 0x0a, 0x25, 0x7b, 0x38, 0x64, 0x41, 0x60, 0x4d, 0xdc, 0x11, 0xd3, 0x6e, 0xf2, 0xd0,
+	0x59, 0xf5, 0xa6, 0xce, 0x79, 0x3a, 0xee, 0x88, 0x51, 0xb0, 0xe7, 0x6b, 0xf2, 0x7f, 0x50, 0x9e,
+	0xb8, 0xb3, 0x69, 0x68, 0xb9, 0x1e, 0x7a, 0x2d, 0xd0, 0x36, 0xb7, 0xd3, 0x3b, 0x45, 0xba, 0xc6,
+	0xc1, 0x9e, 0xc0, 0xd0, 0x4f, 0x43, 0x77, 0xe2, 0xf9, 0x2c, 0xc0, 0xda, 0x74, 0x8b, 0x1b, 0x12,
+	0x43, 0x30, 0x07, 0xd0, 0x18, 0xde, 0x65, 0x34, 0x9e, 0x6b, 0xf9, 0x89, 0xfd, 0x75, 0x0b, 0x1b,
+	0x0d, 0xea, 0xe7, 0x76, 0x4e, 0x47, 0x5f, 0x39, 0xa3, 0xf0, 0x54, 0xbb, 0xcd, 0xe9, 0x6b, 0xdc,
+	0x52, 0x89, 0xfd, 0xef, 0x45, 0xa6, 0x0a, 0xb0, 0x88, 0x16, 0xe4, 0x9b, 0xba, 0x23, 0x16, 0x68,
+	0x0a, 0xb7, 0x45, 0x6c, 0xaa, 0xdf, 0x2a, 0xb0, 0x4e, 0x67, 0x53, 0xec, 0xd2, 0x66, 0x68, 0x87,
+	0xac, 0x63, 0x7b, 0xe4, 0x09, 0x94, 0x7d, 0x01, 0x59, 0x01, 0x62, 0x5c, 0xa2, 0xb4, 0xb7, 0xb7,
+	0x1a, 0x8b, 0x49, 0xc1, 0xc4, 0x5e, 0x86, 0xbe, 0x1f, 0x83, 0xd0, 0xa2, 0x15, 0x96, 0x37, 0xb2,
+	0xe8, 0xdf, 0x39, 0xc8, 0x09, 0x9f, 0xac, 0xcc, 0x0c, 0x0f, 0x21, 0x27, 0xa6, 0x09, 0x2e, 0x55,
+	0xba, 0xa0, 0x82, 0x89, 0x82, 0x4b, 0x25, 0x1b, 0x79, 0x0f, 0xb2, 0x27, 0x58, 0x4c, 0x79, 0xc5,
+	0x2b, 0xed, 0x6d, 0xae, 0xf0, 0xf3, 0x52, 0x4b, 0x05, 0x13, 0xd9, 0x82, 0x02, 0x76, 0x7e, 0x77,
+	0x3a, 0x3e, 0x97, 0x83, 0xc4, 0x7c, 0x4f, 0x1e, 0x41, 0x7e, 0x2c, 0x1a, 0x07, 0xaf, 0x75, 0xa5,
+	0x0b, 0x7a, 0x62, 0xa2, 0xbd, 0xd0, 0x88, 0x9d, 0xbc, 0x0f, 0xd9, 0x21, 0xba, 0x43, 0xcb, 0xbd,
+	0xb6, 0x9b, 0x0b, 0x46, 0xf2, 0x10, 0x32, 0x81, 0xc7, 0x86, 0x5a, 0xfe, 0x92, 0xf2, 0xb0, 0x28,
+	0x44, 0x94, 0x33, 0xa2, 0x33, 0x67, 0x81, 0x7d, 0xc2, 0x64, 0xf3, 0x14, 0x9b, 0xe4, 0x28, 0x51,
+	0xbc, 0xfa, 0x28, 0x11, 0x6b, 0x14, 0x70, 0xb5, 0x46, 0xf1, 0x11, 0xa6, 0xba, 0x1d, 0xce, 0x02,
+	0x5e, 0xee, 0x2a, 0x7b, 0xf7, 0x2e, 0xbb, 0x32, 0x67, 0xa2, 0x92, 0x99, 0xec, 0x41, 0x56, 0xc4,
+	0xde, 0x1a, 0x97, 0xba, 0xfb, 0x0a, 0x29, 0x46, 0x05, 0x2b, 0x56, 0x0f, 0x3b, 0x0c, 0x6d, 0x2c,
+	0x01, 0x96, 0x3b, 0xe5, 0xd5, 0xaf, 0x48, 0x21, 0x82, 0x7a, 0x53, 0x64, 0x18, 0xb1, 0x33, 0x67,
+	0xc8, 0x2c, 0x3e, 0x5e, 0x56, 0x04, 0x83, 0x80, 0xfa, 0x38, 0x64, 0xce, 0x35, 0x08, 0x86, 0xf5,
+	0xed, 0xf4, 0x42, 0x03, 0x67, 0xf8, 0x09, 0xac, 0xc5, 0x8a, 0x74, 0xa0, 0xa9, 0xdb, 0xe9, 0x0b,
+	0x9f, 0x21, 0x56, 0xa5, 0x4b, 0x8b, 0x2a, 0x1d, 0xe0, 0x6b, 0x30, 0xdf, 0x77, 0x7d, 0x5e, 0x0e,
+	0x8b, 0x54, 0x6c, 0x88, 0xb1, 0x9c, 0x70, 0x84, 0xab, 0xdd, 0x7e, 0x5d, 0xc2, 0x25, 0xd3, 0x8b,
+	0xbc, 0x07, 0x24, 0x60, 0xc3, 0x99, 0xcf, 0xac, 0xb8, 0x95, 0x37, 0x64, 0x65, 0xe6, 0x94, 0xc6,
+	0xc2, 0xd6, 0x0f, 0xe0, 0x26, 0xd6, 0x4c, 0x0c, 0xef, 0xe9, 0x08, 0x7b, 0x2c, 0x56, 0x3f, 0x67,
+	0x7a, 0xc2, 0x0b, 0x6a, 0x81, 0x6e, 0x2c, 0x88, 0xfd, 0x39, 0xad, 0xfa, 0xe7, 0x14, 0x64, 0xf1,
+	0x30, 0x6e, 0x09, 0x26, 0x40, 0xc0, 0x53, 0x30, 0x43, 0xc5, 0x86, 0xdc, 0x82, 0x3c, 0x2e, 0xac,
+	0x49, 0x20, 0xc7, 0x8b, 0x1c, 0x6e, 0x3b, 0x01, 0xce, 0x0b, 0x9c, 0xf0, 0xec, 0x3c, 0x64, 0x01,
+	0x4f, 0xb9, 0x0c, 0x2d, 0x22, 0xb2, 0x8f, 0x00, 0x36, 0x04, 0x3e, 0x75, 0x07, 0x3c, 0xb9, 0x32,
+	0x54, 0xee, 0xb0, 0x86, 0xf2, 0x15, 0x2a, 0x14, 0x93, 0x7a, 0x9e, 0xef, 0x3b, 0x01, 0xbe, 0x95,
+	0x20, 0x09, 0x95, 0x39, 0x4e, 0x05, 0x0e, 0x09, 0x9d, 0x0f, 0xa0, 0x24, 0x86, 0x87, 0x13, 0x2c,
+	0xc8, 0x72, 0xaa, 0x04, 0x3e, 0x21, 0x70, 0x84, 0xdc, 0x80, 0xac, 0xe3, 0xa2, 0xe6, 0x42, 0xf4,
+	0x0d, 0x20, 0x2e, 0xca, 0x15, 0x5a, 0x7c, 0x4a, 0x17, 0x93, 0x7b, 0x91, 0x23, 0x38, 0x82, 0x72,
+	0xa5, 0x72, 0x3a, 0x40, 0x49, 0x90, 0x4a, 0x25, 0xd4, 0x09, 0xaa, 0x7f, 0x4f, 0x41, 0x56, 0x1f,
+	0x33, 0x3f, 0x8c, 0x55, 0xa8, 0x34, 0xaf, 0x50, 0x9f, 0xe0, 0x07, 0xc4, 0x19, 0xf3, 0x9d, 0xf0,
+	0x5c, 0x4b, 0x5d, 0x92, 0x0b, 0xa6, 0x64, 0xe0, 0x29, 0x34, 0x67, 0xc7, 0x4b, 0xd9, 0xa8, 0xd3,
+	0x0a, 0xcf, 0x3d, 0xc6, 0xbd, 0x97, 0xa6, 0x45, 0x8e, 0x20, 0x23, 0xd1, 0x20, 0x3f, 0x61, 0x01,
+	0xcf, 0x72, 0x31, 0x59, 0x47, 0x5b, 0xf2, 0x08, 0x8a, 0xf3, 0x0f, 0x30, 0x2d, 0xfb, 0xda, 0x3c,
+	0x5f, 0x30, 0xa3, 0xa1, 0xbe, 0xfc, 0x3e, 0xb3, 0x9c, 0x11, 0x77, 0x6f, 0x91, 0x42, 0x04, 0xb5,
+	0xb8, 0x39, 0xd1, 0x4e, 0xcb, 0x5f, 0x62, 0x4e, 0xf4, 0x85, 0x27, 0xcc, 0x89, 0xd8, 0xf1, 0xbe,
+	0xc3, 0x31, 0xe3, 0x33, 0x90, 0x18, 0xce, 0xa2, 0x2d, 0x36, 0x83, 0x30, 0x1c, 0x4b, 0xb7, 0xe3,
+	0xb2, 0xfa, 0x31, 0xe4, 0xb8, 0x3b, 0x03, 0x2c, 0xd8, 0xdc, 0x64, 0xd9, 0x8e, 0x56, 0x0b, 0x36,
+	0xe7, 0xa3, 0x82, 0xa9, 0xfa, 0x17, 0x05, 0x6e, 0x88, 0x1a, 0x51, 0xf7, 0x19, 0x16, 0x09, 0xf6,
+	0x72, 0xc6, 0x82, 0x30, 0x5e, 0xac, 0x95, 0x37, 0x2b, 0xd6, 0x6f, 0xdc, 0x61, 0xa2, 0x5a, 0x9d,
+	0xbe, 0x62, 0xad, 0xae, 0xbe, 0x03, 0x15, 0x81, 0x51, 0x16, 0x78, 0xee, 0x34, 0x60, 0x8b, 0x7a,
+	0xa1, 0xc4, 0xea, 0x45, 0xd5, 0x83, 0x8d, 0xa4, 0x69, 0x92, 0x7b, 0xb9, 0x27, 0x1e, 0xc2, 0xba,
+	0x1c, 0x5f, 0x7d, 0xc9, 0x22, 0xaf, 0xfe, 0xe0, 0x92, 0xbb, 0x44, 0x9a, 0x68, 0xe5, 0x2c, 0xb1,
+	0xaf, 0x7e, 0xa7, 0x44, 0xc3, 0x08, 0x2f, 0x35, 0xfa, 0x10, 0x47, 0x20, 0xf2, 0x18, 0x72, 0xa2,
+	0x36, 0xf2, 0x33, 0x2b, 0x7b, 0xd5, 0x4b, 0xd4, 0x0a, 0xf6, 0xbe, 0xed, 0xdb, 0x13, 0x2a, 0x25,
+	0xc8, 0x23, 0xc8, 0xf2, 0x69, 0x4a, 0x4b, 0x5d, 0x59, 0x54, 0x08, 0x60, 0x32, 0xf0, 0x85, 0x28,
+	0x6f, 0x69, 0xf1, 0x29, 0xc9, 0x91, 0xa8, 0x86, 0xc7, 0xcb, 0x5f, 0x66, 0xb9, 0xc8, 0x57, 0xff,
+	0x96, 0x02, 0x55, 0xda, 0xc2, 0xc2, 0xef, 0x23, 0x2c, 0xc4, 0x2b, 0xa7, 0xae, 0xda, 0x91, 0xd1,
+	0x6b, 0xdc, 0x2a, 0x19, 0x18, 0xd5, 0x57, 0xf5, 0x36, 0x61, 0x3f, 0x95, 0x12, 0xe4, 0x10, 0xf2,
+	0xd1, 0x48, 0x9a, 0xe1, 0x59, 0xb0, 0x7b, 0x99, 0xf0, 0xdc, 0xb4, 0x5d, 0x39, 0xaf, 0x8a, 0x81,
+	0x2c, 0x12, 0xdf, 0x7a, 0x0c, 0x6b, 0x71, 0xc2, 0x1b, 0x8d, 0x61, 0xbf, 0x5d, 0x44, 0x03, 0x0b,
+	0xa3, 0x18, 0xc1, 0xfc, 0x10, 0x51, 0xa3, 0x29, 0x97, 0xe4, 0x87, 0x0c, 0x32, 0xc9, 0xf6, 0x3d,
+	0x86, 0xe7, 0x39, 0x5c, 0x37, 0xa7, 0xb6, 0x97, 0xcc, 0xf4, 0xe5, 0x6c, 0x88, 0x3d, 0x71, 0xea,
+	0xcd, 0x9e, 0x38, 0x3e, 0xfc, 0xa5, 0x93, 0xc3, 0x5f, 0xf5, 0x25, 0x90, 0xf8, 0xd1, 0xd2, 0x17,
+	0x3f, 0x87, 0x4d, 0x69, 0xda, 0x90, 0x13, 0x16, 0x16, 0x0a, 0xdf, 0xbc, 0x7d, 0xc9, 0xd1, 0x49,
+	0x35, 0x74, 0xe3, 0xec, 0x02, 0xb4, 0x1a, 0x46, 0x1f, 0xfb, 0xad, 0xe9, 0x73, 0x17, 0x7f, 0xb4,
+	0x92, 0x47, 0xcd, 0xad, 0x2d, 0x08, 0xa0, 0x75, 0xf1, 0x2f, 0x69, 0x1f, 0x41, 0x5e, 0x1e, 0x7c,
+	0x95, 0xca, 0x14, 0xf1, 0x56, 0x47, 0x40, 0x0e, 0x7c, 0xdb, 0x3b, 0x6d, 0xf8, 0xce, 0x19, 0xf3,
+	0xeb, 0xa7, 0xf6, 0xf4, 0x84, 0x05, 0xf3, 0x03, 0x94, 0xd8, 0x01, 0x8f, 0x21, 0xf3, 0xc2, 0x99,
+	0x8e, 0x64, 0x66, 0xbf, 0x73, 0xc1, 0x60, 0xbd, 0xa4, 0x86, 0xb7, 0x07, 0x2e, 0x53, 0x7d, 0x17,
+	0xd6, 0xeb, 0xe3, 0x59, 0x10, 0x32, 0xff, 0x35, 0x35, 0xf0, 0x8f, 0x0a, 0x94, 0x31, 0x39, 0xce,
+	0xe6, 0xef, 0x7d, 0x08, 0x05, 0xca, 0x5e, 0xb2, 0x20, 0xfc, 0xec, 0x58, 0xb6, 0x88, 0xf7, 0x2e,
+	0xf8, 0xde, 0x8b, 0x49, 0xec, 0x46, 0xec, 0x22, 0x35, 0x0a, 0xbe, 0xdc, 0x6e, 0xfd, 0x18, 0xca,
+	0x09, 0x52, 0x3c, 0x39, 0xd2, 0xaf, 0x4b, 0x8e, 0x6f, 0xa0, 0x92, 0x38, 0x25, 0x20, 0x55, 0x58,
+	0x93, 0xeb, 0x3a, 0xaf, 0x78, 0x42, 0xcd, 0x9a, 0x1f, 0xc3, 0x48, 0x63, 0xc9, 0x1a, 0xf9, 0x43,
+	0xd5, 0xfd, 0x57, 0x5b, 0x40, 0xcb, 0x76, 0x7c, 0x5b, 0xfb, 0x2e, 0x05, 0x39, 0x31, 0x48, 0x93,
+	0x75, 0x28, 0x99, 0x03, 0x7d, 0x70, 0x64, 0x5a, 0xdd, 0x5e, 0xd7, 0x50, 0xaf, 0xc5, 0x80, 0x56,
+	0xb7, 0x35, 0x50, 0x15, 0x52, 0x86, 0xa2, 0x04, 0x7a, 0x9f, 0xa9, 0x29, 0x42, 0xa0, 0x12, 0x6d,
+	0x9b, 0xcd, 0x76, 0xab, 0x6b, 0xa8, 0x69, 0xa2, 0xc2, 0x9a, 0xc4, 0x0c, 0x4a, 0x7b, 0x54, 0xcd,
+	0x10, 0x0d, 0x36, 0xe6, 0x6a, 0x07, 0x56, 0xab, 0x6b, 0x7d, 0x7e, 0xd4, 0xa3, 0x47, 0x1d, 0x35,
+	0x4b, 0x6e, 0xc1, 0x0d, 0x49, 0x69, 0x18, 0xf5, 0x5e, 0xa7, 0xd3, 0x32, 0xcd, 0x56, 0xaf, 0xab,
+	0xe6, 0xc8, 0x26, 0x10, 0x49, 0xe8, 0xe8, 0xad, 0xee, 0xc0, 0xe8, 0xea, 0xdd, 0xba, 0xa1, 0xe6,
+	0x63, 0x02, 0xe6, 0xa0, 0x47, 0xf5, 0x03, 0xc3, 0x6a, 0xf4, 0x9e, 0x74, 0xd5, 0x02, 0xb9, 0x03,
+	0xb7, 0x96, 0x09, 0xc6, 0x01, 0xd5, 0x1b, 0x46, 0x43, 0x2d, 0xc6, 0xa4, 0xba, 0x86, 0xd1, 0x30,
+	0x2d, 0x6a, 0xec, 0xf7, 0x7a, 0x03, 0x15, 0xc8, 0x5d, 0xd0, 0x96, 0xa4, 0xa8, 0xb1, 0xaf, 0xb7,
+	0xf9, 0x61, 0x25, 0xb2, 0x0d, 0x77, 0x97, 0x75, 0xd2, 0xd6, 0x31, 0xf2, 0xf4, 0xdb, 0x7a, 0xdd,
+	0x50, 0xd7, 0x48, 0x05, 0x60, 0x7e, 0xcd, 0x2f, 0xd4, 0x72, 0xed, 0x4f, 0x0a, 0x80, 0x08, 0x52,
+	0x3e, 0x63, 0x6d, 0x80, 0xca, 0x25, 0xa8, 0x35, 0x78, 0xda, 0x37, 0x22, 0xa7, 0x2e, 0xa1, 0xcd,
+	0x56, 0xdb, 0x50, 0x15, 0x72, 0x13, 0xae, 0xc7, 0xd1, 0xfd, 0x76, 0xaf, 0x8e, 0x1e, 0xde, 0x04,
+	0x12, 0x87, 0x7b, 0xfb, 0x3f, 0x33, 0xea, 0x03, 0x35, 0x4d, 0x6e, 0xc3, 0xcd, 0x38, 0x5e, 0x6f,
+	0x1f, 0x99, 0x03, 0x83, 0x1a, 0x0d, 0x35, 0xb3, 0xac, 0xe9, 0x80, 0xea, 0xfd, 0x43, 0x35, 0x5b,
+	0xfb, 0x83, 0x02, 0x39, 0xf1, 0x9d, 0x85, 0x4f, 0xd4, 0x34, 0x13, 0x77, 0xba, 0x0e, 0xe5, 0x08,
+	0xd9, 0x1f, 0xd0, 0xa6, 0xa9, 0x2a, 0x71, 0x26, 0xe3, 0x8b, 0xc1, 0x87, 0x6a, 0x2a, 0x8e, 0x34,
+	0x8f, 0x4c, 0x7c, 0xeb, 0x75, 0x28, 0xcd, 0x15, 0x35, 0x4d, 0x35, 0x13, 0x07, 0x8e, 0x9b, 0xa6,
+	0x9a, 0x8d, 0x03, 0x5f, 0x34, 0x4d, 0x35, 0x17, 0x07, 0xbe, 0x6c, 0x9a, 0x6a, 0xbe, 0xf6, 0xad,
+	0x02, 0x37, 0x2f, 0xcc, 0x6e, 0xf2, 0x16, 0xdc, 0xe3, 0x97, 0xb7, 0xa4, 0x39, 0xf5, 0x43, 0xbd,
+	0x7b, 0x60, 0x24, 0xee, 0xfd, 0x36, 0xbc, 0x75, 0x29, 0x4b, 0xa7, 0xd7, 0x68, 0x35, 0x5b, 0x46,
+	0x43, 0x55, 0x48, 0x15, 0xee, 0x5f, 0xca, 0xa6, 0x37, 0x30, 0x48, 0x52, 0xe4, 0xff, 0x61, 0xfb,
+	0x52, 0x9e, 0x86, 0xd1, 0x36, 0x06, 0x46, 0x43, 0x4d, 0xd7, 0x42, 0x58, 0x8b, 0xcf, 0xdb, 0x3c,
+	0x50, 0x8d, 0x63, 0x83, 0xb6, 0x06, 0x4f, 0x13, 0x17, 0xc3, 0x90, 0x4b, 0xe0, 0x7a, 0x5b, 0xa7,
+	0x1d, 0x55, 0xc1, 0x87, 0x4b, 0x12, 0x9e, 0xe8, 0xb4, 0xdb, 0xea, 0x1e, 0xa8, 0x29, 0x9e, 0x27,
+	0x4b, 0xba, 0x06, 0xad, 0xe6, 0x53, 0x35, 0x5d, 0xfb, 0x8d, 0x82, 0xe5, 0x60, 0x31, 0x17, 0xe3,
+	0xb1, 0xd4, 0x30, 0x7b, 0x47, 0xb4, 0x9e, 0xf4, 0x87, 0x06, 0x1b, 0x49, 0xfc, 0xb8, 0xd7, 0x3e,
+	0xea, 0x60, 0x7c, 0x5d, 0x20, 0xd1, 0x30, 0xd4, 0x14, 0xde, 0x27, 0x89, 0xcb, 0x50, 0x52, 0xd3,
+	0x68, 0x43, 0x92, 0xc4, 0x3d, 0xa3, 0x66, 0x6a, 0xbf, 0x56, 0x60, 0x9d, 0x0f, 0xce, 0x62, 0xd2,
+	0xe0, 0x37, 0xda, 0x82, 0x4d, 0xbd, 0x6d, 0xd0, 0x81, 0xa5, 0xd7, 0x07, 0xad, 0x5e, 0x37, 0x71,
+	0xab, 0xbb, 0xa0, 0xad, 0xd2, 0x84, 0x4f, 0x55, 0xe5, 0x62, 0x6a, 0x9d, 0x1a, 0xfa, 0x00, 0xef,
+	0x77, 0x21, 0xf5, 0xa8, 0xdf, 0x40, 0x6a, 0xba, 0xf6, 0xcb, 0x68, 0xa8, 0x88, 0xcd, 0x7c, 0x28,
+	0x22, 0xcc, 0x8e, 0x64, 0xfa, 0x3a, 0xd5, 0x3b, 0xd1, 0x65, 0xee, 0xc0, 0xad, 0x8b, 0xa8, 0xbd,
+	0x66, 0x53, 0x55, 0xd0, 0x8a, 0x0b, 0x89, 0x5d, 0x35, 0x55, 0xdb, 0x83, 0xbc, 0xfc, 0xa1, 0x99,
+	0x14, 0x20, 0x23, 0xb5, 0xe5, 0x21, 0xdd, 0xee, 0x3d, 0x51, 0x15, 0x02, 0x90, 0xeb, 0x18, 0x8d,
+	0xd6, 0x51, 0x47, 0x4d, 0x21, 0xf9, 0xb0, 0x75, 0x70, 0xa8, 0xa6, 0x6b, 0x7d, 0x28, 0xce, 0x7f,
+	0x69, 0x46, 0x57, 0xb7, 0x7a, 0x56, 0x9f, 0xf6, 0x30, 0xe5, 0x2d, 0xd3, 0xf8, 0xfc, 0xc8, 0xe8,
+	0x0e, 0x5a, 0x7a, 0x5b, 0xbd, 0x86, 0x39, 0x1b, 0x23, 0x51, 0xbd, 0xdb, 0xe8, 0x61, 0xb0, 0x5c,
+	0x87, 0x72, 0x0c, 0x6e, 0xec, 0xab, 0xa9, 0xda, 0x3f, 0x15, 0x28, 0xc5, 0x66, 0x3d, 0x94, 0x94,
+	0x37, 0xc6, 0x4a, 0x14, 0x0f, 0x84, 0x04, 0xdc, 0x37, 0xba, 0x0d, 0x8c, 0xb2, 0xb8, 0x89, 0x82,
+	0xa2, 0x1f, 0xeb, 0xad, 0xb6, 0xbe, 0xdf, 0x96, 0xc1, 0x90, 0xa4, 0x0d, 0x06, 0x7a, 0xfd, 0x10,
+	0x03, 0x7f, 0x85, 0xd4, 0x30, 0x24, 0x29, 0x13, 0xf3, 0xe8, 0x82, 0x34, 0xa8, 0x1f, 0xe2, 0x71,
+	0x59, 0x8c, 0xbb, 0x04, 0x51, 0x34, 0x85, 0xdc, 0xca, 0x05, 0xa3, 0x14, 0xcb, 0xd7, 0x7e, 0xa7,
+	0xc0, 0x5a, 0xfc, 0xf7, 0x9d, 0x25, 0x15, 0x8b, 0xee, 0x74, 0x0f, 0x6e, 0x2f, 0xe3, 0x03, 0xab,
+	0x4f, 0x0d, 0xd3, 0xe8, 0x62, 0xaf, 0xda, 0x00, 0x35, 0x49, 0x3e, 0xea, 0x8b, 0x82, 0x9a, 0x44,
+	0x79, 0x03, 0x49, 0x2f, 0xb9, 0xe5, 0xc8, 0x5c, 0xf4, 0x8f, 0x4c, 0xed, 0x17, 0x50, 0x4e, 0xfc,
+	0x41, 0x25, 0xba, 0x8d, 0x68, 0x09, 0xe2, 0xd1, 0xad, 0x8e, 0x7e, 0xd0, 0x35, 0x06, 0xad, 0xba,
+	0x7a, 0x4d, 0xf4, 0xae, 0x04, 0xd1, 0x34, 0xb1, 0x08, 0xf1, 0x2e, 0x94, 0xc0, 0xbb, 0xc7, 0x1d,
+	0x43, 0x4d, 0xd5, 0x76, 0xa0, 0x2c, 0xe7, 0x97, 0xae, 0x1b, 0x3a, 0xcf, 0xcf, 0x91, 0x53, 0x66,
+	0xa1, 0x2c, 0x01, 0xe2, 0x92, 0xd7, 0x6a, 0xbf, 0x52, 0x00, 0x16, 0x3f, 0x3f, 0xa3, 0x85, 0x7a,
+	0xbd, 0x6e, 0x98, 0x26, 0xd6, 0xba, 0xf9, 0xd3, 0x3f, 0x80, 0x3b, 0x71, 0x94, 0x1a, 0x7a, 0xc3,
+	0x7a, 0x42, 0x5b, 0x03, 0xc3, 0xea, 0x61, 0x5f, 0x53, 0xc8, 0x7d, 0xd8, 0x5a, 0x61, 0xe8, 0x75,
+	0xdb, 0x4f, 0xad, 0x8e, 0xde, 0x7d, 0xaa, 0xa6, 0x5e, 0xa1, 0x80, 0x33, 0xa4, 0xf7, 0xef, 0xc2,
+	0x8d, 0xa1, 0x3b, 0x59, 0x1e, 0x33, 0xfa, 0xca, 0x97, 0x69, 0xdb, 0x73, 0x9e, 0xe5, 0xf8, 0x8f,
+	0x04, 0x1f, 0xfc, 0x67, 0x00, 0x3a, 0x08, 0xe3, 0xf5, 0xf7, 0x1d, 0x00, 0x00,
 }
@@ -0,0 +1,83 @@
+package chaos
+
+import (
+	"github.com/libopenstorage/openstorage/api/client"
+	"github.com/libopenstorage/openstorage/pkg/chaos"
+)
+
+const (
+	// OsdSocket is the unix socket for chaos apis
+	OsdSocket = "osd"
+	chaosPath = "/chaos"
+)
+
+// Manager lists, enables and disables the chaos points of a daemon.
+type Manager interface {
+	// Enumerate the chaos points of pkg, or all of them if pkg is "".
+	Enumerate(pkg string) ([]chaos.Chaos, error)
+	// Enable the chaos point id.
+	Enable(id chaos.ID, when chaos.When, what chaos.Action, opts chaos.Options) error
+	// Disable the chaos point id.
+	Disable(id chaos.ID) error
+}
+
+type chaosClient struct {
+	c *client.Client
+}
+
+// ChaosManager returns a REST wrapper for the chaos points of a daemon.
+func ChaosManager(c *client.Client) Manager {
+	return &chaosClient{c: c}
+}
+
+// NewChaosClient returns a new REST client.
+// host: REST endpoint [http://<ip>:<port> OR unix://<path-to-unix-socket>]. default: [unix://var/lib/osd/chaos/osd.sock]
+// version: Chaos API version
+func NewChaosClient(host, version string) (*client.Client, error) {
+	if host == "" {
+		host = client.GetUnixServerPath(OsdSocket, chaos.APIBase)
+	}
+	if version == "" {
+		// Set the default version
+		version = chaos.APIVersion
+	}
+	return client.NewClient(host, version)
+}
+
+func (c *chaosClient) Enumerate(pkg string) ([]chaos.Chaos, error) {
+	var ko []chaos.Chaos
+	request := c.c.Get().Resource(chaosPath)
+	if pkg != "" {
+		request.QueryOption("pkg", pkg)
+	}
+	resp := request.Do()
+	if resp.Error() != nil {
+		return nil, resp.FormatError()
+	}
+	if err := resp.Unmarshal(&ko); err != nil {
+		return nil, err
+	}
+	return ko, nil
+}
+
+func (c *chaosClient) Enable(
+	id chaos.ID,
+	when chaos.When,
+	what chaos.Action,
+	opts chaos.Options,
+) error {
+	resp := c.c.Put().Resource(chaosPath + "/enable").Instance(id.String()).
+		Body(&chaos.Chaos{When: when, What: what, Options: opts}).Do()
+	if resp.Error() != nil {
+		return resp.FormatError()
+	}
+	return nil
+}
+
+func (c *chaosClient) Disable(id chaos.ID) error {
+	resp := c.c.Put().Resource(chaosPath + "/disable").Instance(id.String()).Do()
+	if resp.Error() != nil {
+		return resp.FormatError()
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package chaos
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/libopenstorage/openstorage/api/server"
+	"github.com/libopenstorage/openstorage/pkg/chaos"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChaosAPI(t *testing.T) {
+	router := mux.NewRouter()
+	for _, r := range server.GetChaosAPIRoutes() {
+		router.Methods(r.GetVerb()).Path(r.GetPath()).HandlerFunc(r.GetFn())
+	}
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+	clnt, err := NewChaosClient(ts.URL, "")
+	require.NoError(t, err)
+	manager := ChaosManager(clnt)
+
+	id := chaos.Add("apitest", "fn", "api test point")
+	ko, err := manager.Enumerate("apitest")
+	require.NoError(t, err)
+	require.Len(t, ko, 1)
+	require.Equal(t, id, ko[0].ID)
+	require.False(t, ko[0].Enabled)
+
+	require.NoError(t, manager.Enable(id, chaos.Random, chaos.Delay,
+		chaos.Options{Delay: time.Millisecond, Rate: 0.5}))
+	ko, err = manager.Enumerate("apitest")
+	require.NoError(t, err)
+	require.True(t, ko[0].Enabled)
+	require.Equal(t, chaos.Random, ko[0].When)
+	require.Equal(t, chaos.Delay, ko[0].What)
+	require.Equal(t, time.Millisecond, ko[0].Delay)
+	require.Equal(t, 0.5, ko[0].Rate)
+
+	require.Error(t, manager.Enable(id, chaos.Once, chaos.Error,
+		chaos.Options{Rate: 2}), "Rates are probabilities")
+	require.Error(t, manager.Enable(chaos.ID(1<<31), chaos.Once, chaos.Error,
+		chaos.Options{}), "Unknown chaos points cannot be enabled")
+
+	require.NoError(t, manager.Disable(id))
+	ko, err = manager.Enumerate("apitest")
+	require.NoError(t, err)
+	require.False(t, ko[0].Enabled)
+	require.Error(t, manager.Disable(chaos.ID(1<<31)))
+}
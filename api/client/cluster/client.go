@@ -7,6 +7,7 @@ import (
 	"github.com/libopenstorage/openstorage/api"
 	"github.com/libopenstorage/openstorage/api/client"
 	"github.com/libopenstorage/openstorage/cluster"
+	"github.com/libopenstorage/openstorage/pkg/placement"
 )
 
 const (
@@ -138,6 +139,36 @@ func (c *clusterClient) EnableUpdates() error {
 	return nil
 }
 
+func (c *clusterClient) Benchmark() (map[string]api.StorageResource, error) {
+	var disks map[string]api.StorageResource
+	if err := c.c.Put().Resource(clusterPath + "/benchmark").Do().Unmarshal(&disks); err != nil {
+		return nil, err
+	}
+	return disks, nil
+}
+
+func (c *clusterClient) Place(
+	spec *api.VolumeSpec,
+	placed []string,
+	failureDomain string,
+) (*placement.Placement, error) {
+	p := &placement.Placement{}
+	req := c.c.Post().Resource(clusterPath + "/placement").Body(spec)
+	for _, node := range placed {
+		req.QueryOption("placed", node)
+	}
+	if len(failureDomain) != 0 {
+		req.QueryOption("failure_domain", failureDomain)
+	}
+	if err := req.Do().Unmarshal(p); err != nil {
+		return nil, err
+	}
+	if len(p.Error) != 0 {
+		return p, errors.New(p.Error)
+	}
+	return p, nil
+}
+
 func (c *clusterClient) GetGossipState() *cluster.ClusterState {
 	var status *cluster.ClusterState
 
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
@@ -28,6 +29,7 @@ type Request struct {
 	instance string
 	err      error
 	body     []byte
+	stream   io.Reader
 	req      *http.Request
 	resp     *http.Response
 	timeout  time.Duration
@@ -162,6 +164,16 @@ func (r *Request) Body(v interface{}) *Request {
 	return r
 }
 
+// BodyStream sets the request Body to the data read from stream, which is
+// sent as is instead of being marshalled.
+func (r *Request) BodyStream(stream io.Reader) *Request {
+	if r.err != nil {
+		return r
+	}
+	r.stream = stream
+	return r
+}
+
 // URL returns the current working URL.
 func (r *Request) URL() *url.URL {
 	u := *r.base
@@ -235,25 +247,13 @@ func parseHTTPStatus(resp *http.Response, body []byte) error {
 func (r *Request) Do() *Response {
 	var (
 		err  error
-		req  *http.Request
 		resp *http.Response
-		url  string
 		body []byte
 	)
 	if r.err != nil {
 		return &Response{err: r.err}
 	}
-	url = r.URL().String()
-	req, err = http.NewRequest(r.verb, url, bytes.NewBuffer(r.body))
-	if err != nil {
-		return &Response{err: err}
-	}
-	if r.headers == nil {
-		r.headers = http.Header{}
-	}
-	req.Header = r.headers
-	req.Header.Set("Content-Type", "application/json")
-	resp, err = r.client.Do(req)
+	resp, err = r.send()
 	if err != nil {
 		return &Response{err: err}
 	}
@@ -272,6 +272,44 @@ func (r *Request) Do() *Response {
 	}
 }
 
+// Stream executes the request and returns the body of the response as it is
+// received. The caller must close it.
+func (r *Request) Stream() (io.ReadCloser, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	resp, err := r.send()
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < http.StatusOK || resp.StatusCode > http.StatusPartialContent {
+		defer resp.Body.Close()
+		body, _ := ioutil.ReadAll(resp.Body)
+		return nil, fmt.Errorf("HTTP-%d: %s", resp.StatusCode,
+			strings.TrimSpace(string(body)))
+	}
+	return resp.Body, nil
+}
+
+func (r *Request) send() (*http.Response, error) {
+	var body io.Reader = bytes.NewBuffer(r.body)
+	contentType := "application/json"
+	if r.stream != nil {
+		body = r.stream
+		contentType = "application/octet-stream"
+	}
+	req, err := http.NewRequest(r.verb, r.URL().String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if r.headers == nil {
+		r.headers = http.Header{}
+	}
+	req.Header = r.headers
+	req.Header.Set("Content-Type", contentType)
+	return r.client.Do(req)
+}
+
 // Body return http body, valid only if there is no error
 func (r Response) Body() ([]byte, error) {
 	return r.body, r.err
@@ -2,7 +2,12 @@ package client
 
 import (
 	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"strings"
 	"testing"
 )
 
@@ -41,5 +46,34 @@ func TestBasic(t *testing.T) {
 	}
 }
 
+func TestStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Content-Type") != "application/octet-stream" {
+				http.Error(w, "bad content type", http.StatusBadRequest)
+				return
+			}
+			io.Copy(w, r.Body)
+		}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+
+	r := NewRequest(http.DefaultClient, u, "POST", "v1")
+	stream, err := r.Resource("resource").BodyStream(strings.NewReader("data")).Stream()
+	if err != nil {
+		t.Fatalf("Stream failed: %v", err)
+	}
+	defer stream.Close()
+	if b, _ := ioutil.ReadAll(stream); string(b) != "data" {
+		t.Fatalf("\nExpected %#v\nbut got  %#v", "data", string(b))
+	}
+
+	r = NewRequest(http.DefaultClient, u, "POST", "v1")
+	_, err = r.Resource("resource").Body("data").Stream()
+	if err == nil || !strings.Contains(err.Error(), "bad content type") {
+		t.Fatalf("Expected bad content type error but got %v", err)
+	}
+}
+
 func init() {
 }
@@ -221,6 +221,49 @@ func (v *volumeClient) UsedSize(
 	return usedSize, err
 }
 
+// Fstrim discards the unused blocks of a mounted volume.
+// Errors ErrEnoEnt, ErrVolDetached may be returned
+func (v *volumeClient) Fstrim(volumeID string) (uint64, error) {
+	var trimmed uint64
+	req := v.c.Post().Resource(volumePath + "/fstrim").Instance(volumeID)
+	err := req.Do().Unmarshal(&trimmed)
+	return trimmed, err
+}
+
+// Export writes the data of a read-only snapshot to w, or only the changes
+// since the snapshot parentID if it is set.
+// Errors ErrEnoEnt may be returned
+func (v *volumeClient) Export(snapID string, parentID string, w io.Writer) error {
+	req := v.c.Get().Resource(volumePath + "/export").Instance(snapID)
+	if len(parentID) != 0 {
+		req.QueryOption(api.OptParent, parentID)
+	}
+	stream, err := req.Stream()
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+	_, err = io.Copy(w, stream)
+	return err
+}
+
+// Import creates a read-only volume from the data written by Export.
+func (v *volumeClient) Import(locator *api.VolumeLocator, r io.Reader) (string, error) {
+	response := &api.VolumeCreateResponse{}
+	req := v.c.Post().Resource(volumePath + "/import").BodyStream(r)
+	if locator != nil {
+		req.QueryOption(api.OptName, locator.Name)
+		req.QueryOptionLabel(api.OptLabel, locator.VolumeLabels)
+	}
+	if err := req.Do().Unmarshal(response); err != nil {
+		return "", err
+	}
+	if response.VolumeResponse != nil && response.VolumeResponse.Error != "" {
+		return "", errors.New(response.VolumeResponse.Error)
+	}
+	return response.Id, nil
+}
+
 // Alerts on this volume.
 // Errors ErrEnoEnt may be returned
 func (v *volumeClient) Alerts(volumeID string) (*api.Alerts, error) {
@@ -0,0 +1,120 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/libopenstorage/openstorage/pkg/chaos"
+)
+
+type chaosApi struct {
+	restBase
+}
+
+func (c *chaosApi) Routes() []*Route {
+	return []*Route{
+		{verb: "GET", path: "/chaos/versions", fn: c.versions},
+		{verb: "GET", path: chaosPath("", chaos.APIVersion), fn: c.enumerate},
+		{verb: "PUT", path: chaosPath("/enable/{id}", chaos.APIVersion), fn: c.enable},
+		{verb: "PUT", path: chaosPath("/disable/{id}", chaos.APIVersion), fn: c.disable},
+	}
+}
+
+func newChaosAPI() restServer {
+	return &chaosApi{restBase{version: chaos.APIVersion, name: "Chaos API"}}
+}
+
+// StartChaosAPI starts a REST server to list, enable and disable the chaos
+// points of the daemon.
+func StartChaosAPI(chaosApiBase string, chaosPort uint16) error {
+	chaosApi := newChaosAPI()
+	if err := startServer("osd", chaosApiBase, chaosPort, chaosApi.Routes()); err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetChaosAPIRoutes returns the routes of the chaos API.
+func GetChaosAPIRoutes() []*Route {
+	chaosApi := newChaosAPI()
+	return chaosApi.Routes()
+}
+
+func (c *chaosApi) String() string {
+	return c.name
+}
+
+func (c *chaosApi) enumerate(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(chaos.Enumerate(r.URL.Query().Get("pkg")))
+}
+
+// id parses the chaos point id of the request.
+func (c *chaosApi) id(method string, w http.ResponseWriter, r *http.Request) (chaos.ID, bool) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		c.sendError(c.name, method, w, "Invalid id param", http.StatusBadRequest)
+		return 0, false
+	}
+	return chaos.ID(id), true
+}
+
+// sendChaosError sends err with the status of its cause.
+func (c *chaosApi) sendChaosError(method string, w http.ResponseWriter, err error) {
+	code := http.StatusBadRequest
+	if err == chaos.ErrNoEnt {
+		code = http.StatusNotFound
+	}
+	c.sendError(c.name, method, w, err.Error(), code)
+}
+
+func (c *chaosApi) enable(w http.ResponseWriter, r *http.Request) {
+	method := "enable"
+	id, ok := c.id(method, w, r)
+	if !ok {
+		return
+	}
+	// The When, What and Options of the request enable the chaos point.
+	req := chaos.Chaos{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		c.sendError(c.name, method, w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Delay == 0 {
+		req.Delay = chaos.DefaultDelay
+	}
+	if req.Rate == 0 {
+		req.Rate = chaos.DefaultRate
+	}
+	if err := chaos.EnableWith(id, req.When, req.What, req.Options); err != nil {
+		c.sendChaosError(method, w, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (c *chaosApi) disable(w http.ResponseWriter, r *http.Request) {
+	method := "disable"
+	id, ok := c.id(method, w, r)
+	if !ok {
+		return
+	}
+	if err := chaos.Disable(id); err != nil {
+		c.sendChaosError(method, w, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (c *chaosApi) versions(w http.ResponseWriter, r *http.Request) {
+	versions := []string{
+		chaos.APIVersion,
+		// Update supported versions by adding them here
+	}
+	json.NewEncoder(w).Encode(versions)
+}
+
+func chaosPath(route, version string) string {
+	return "/" + version + "/chaos" + route
+}
@@ -29,6 +29,8 @@ func (c *clusterApi) Routes() []*Route {
 		{verb: "PUT", path: clusterPath("/disablegossip", cluster.APIVersion), fn: c.disableGossip},
 		{verb: "PUT", path: clusterPath("/shutdown", cluster.APIVersion), fn: c.shutdown},
 		{verb: "PUT", path: clusterPath("/shutdown/{id}", cluster.APIVersion), fn: c.shutdown},
+		{verb: "PUT", path: clusterPath("/benchmark", cluster.APIVersion), fn: c.benchmark},
+		{verb: "POST", path: clusterPath("/placement", cluster.APIVersion), fn: c.placement},
 	}
 }
 func newClusterAPI() restServer {
@@ -131,6 +133,51 @@ func (c *clusterApi) disableGossip(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(clusterResponse)
 }
 
+func (c *clusterApi) benchmark(w http.ResponseWriter, r *http.Request) {
+	method := "benchmark"
+
+	inst, err := cluster.Inst()
+	if err != nil {
+		c.sendError(c.name, method, w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	disks, err := inst.Benchmark()
+	if err != nil {
+		c.sendError(c.name, method, w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(disks)
+}
+
+// placement explains where a volume of the spec in the body would be
+// placed, without creating it. The placed query options are the nodes that
+// already store its replicas. A placement that fails is returned with its
+// Error.
+func (c *clusterApi) placement(w http.ResponseWriter, r *http.Request) {
+	method := "placement"
+
+	var spec api.VolumeSpec
+	if err := json.NewDecoder(r.Body).Decode(&spec); err != nil {
+		c.sendError(c.name, method, w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	inst, err := cluster.Inst()
+	if err != nil {
+		c.sendError(c.name, method, w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	query := r.URL.Query()
+	p, err := inst.Place(&spec, query["placed"], query.Get("failure_domain"))
+	if p == nil {
+		c.sendError(c.name, method, w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	json.NewEncoder(w).Encode(p)
+}
+
 func (c *clusterApi) gossipState(w http.ResponseWriter, r *http.Request) {
 	method := "gossipState"
 
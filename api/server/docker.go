@@ -75,7 +75,7 @@ func volDriverPath(method string) string {
 }
 
 func (d *driver) volNotFound(request string, id string, e error, w http.ResponseWriter) error {
-	err := fmt.Errorf("Failed to locate volume: " + e.Error())
+	err := fmt.Errorf("Failed to locate volume: %v", e)
 	d.logRequest(request, id).Warnln(http.StatusNotFound, " ", err.Error())
 	return err
 }
@@ -438,6 +438,12 @@ func (d *driver) mount(w http.ResponseWriter, r *http.Request) {
 	// Note that name is unchanged even if a new volume was created as a
 	// result of scale up.
 	response.Mountpoint = mountpoint
+	if err = checkMount(v, vol.Id, mountpoint); err != nil {
+		d.logRequest(method, request.Name).Warnf(
+			"Cannot mount volume %v, %v", mountpoint, err)
+		d.errorResponse(w, err)
+		return
+	}
 	os.MkdirAll(mountpoint, 0755)
 	err = v.Mount(vol.Id, response.Mountpoint)
 	if err != nil {
@@ -469,7 +475,7 @@ func (d *driver) path(w http.ResponseWriter, r *http.Request) {
 	}
 
 	d.logRequest(method, name).Debugf("")
-	if len(vol.AttachPath) == 0 || len(vol.AttachPath) == 0 {
+	if len(vol.AttachPath) == 0 {
 		e := d.volNotMounted(method, name)
 		d.errorResponse(w, e)
 		return
@@ -499,7 +505,7 @@ func (d *driver) list(w http.ResponseWriter, r *http.Request) {
 	volInfo := make([]volumeInfo, len(vols))
 	for i, v := range vols {
 		volInfo[i].Name = v.Locator.Name
-		if len(v.AttachPath) > 0 || len(v.AttachPath) > 0 {
+		if len(v.AttachPath) > 0 {
 			volInfo[i].Mountpoint = path.Join(v.AttachPath[0], config.DataDir)
 		}
 	}
@@ -528,7 +534,7 @@ func (d *driver) get(w http.ResponseWriter, r *http.Request) {
 	}
 
 	volInfo := volumeInfo{Name: returnName}
-	if len(vol.AttachPath) > 0 || len(vol.AttachPath) > 0 {
+	if len(vol.AttachPath) > 0 {
 		volInfo.Mountpoint = path.Join(vol.AttachPath[0], config.DataDir)
 	}
 
@@ -25,9 +25,10 @@ type graphDriver struct {
 }
 
 type graphRequest struct {
-	ID         string `json:",omitempty"`
-	Parent     string `json:",omitempty"`
-	MountLabel string `json:",omitempty"`
+	ID         string            `json:",omitempty"`
+	Parent     string            `json:",omitempty"`
+	MountLabel string            `json:",omitempty"`
+	StorageOpt map[string]string `json:",omitempty"`
 }
 
 type graphResponse struct {
@@ -147,7 +148,7 @@ func (d *graphDriver) create(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		return
 	}
-	if err := d.gd.Create(request.ID, request.Parent, "", nil); err != nil {
+	if err := d.gd.Create(request.ID, request.Parent, request.MountLabel, request.StorageOpt); err != nil {
 		d.errResponse(method, w, err)
 		return
 	}
@@ -3,6 +3,7 @@ package server
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"strconv"
 	"strings"
@@ -12,12 +13,26 @@ import (
 	"github.com/libopenstorage/openstorage/api"
 	"github.com/libopenstorage/openstorage/volume"
 	"github.com/libopenstorage/openstorage/volume/drivers"
+	"github.com/libopenstorage/openstorage/volume/drivers/common"
 )
 
 type volApi struct {
 	restBase
 }
 
+// checkMount returns an error if the access mode of a volume does not allow
+// it to be mounted at mountpath while it is mounted elsewhere.
+func checkMount(d volume.VolumeDriver, volumeID string, mountpath string) error {
+	vols, err := d.Inspect([]string{volumeID})
+	if err != nil {
+		return err
+	}
+	if len(vols) != 1 {
+		return volume.ErrEnoEnt
+	}
+	return common.CheckAccess(vols[0], mountpath, api.AccessMode_ACCESS_MODE_NONE)
+}
+
 func responseStatus(err error) string {
 	if err == nil {
 		return ""
@@ -114,6 +129,9 @@ func (vd *volApi) volumeSet(w http.ResponseWriter, r *http.Request) {
 					err = fmt.Errorf("Invalid mount path")
 					break
 				}
+				if err = checkMount(d, volumeID, req.Action.MountPath); err != nil {
+					break
+				}
 				err = d.Mount(volumeID, req.Action.MountPath)
 			} else {
 				err = d.Unmount(volumeID, req.Action.MountPath)
@@ -401,6 +419,106 @@ func (vd *volApi) usedsize(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(used)
 }
 
+func (vd *volApi) fstrim(w http.ResponseWriter, r *http.Request) {
+	var volumeID string
+	var err error
+
+	method := "fstrim"
+	if volumeID, err = vd.parseVolumeID(r); err != nil {
+		e := fmt.Errorf("Failed to parse volumeID: %s", err.Error())
+		vd.sendError(vd.name, method, w, e.Error(), http.StatusBadRequest)
+		return
+	}
+
+	d, err := volumedrivers.Get(vd.name)
+	if err != nil {
+		notFound(w, r)
+		return
+	}
+
+	trimmed, err := d.Fstrim(volumeID)
+	if err != nil {
+		e := fmt.Errorf("Failed to trim volume: %s", err.Error())
+		vd.sendError(vd.name, method, w, e.Error(), http.StatusBadRequest)
+		return
+	}
+	json.NewEncoder(w).Encode(trimmed)
+}
+
+// countingWriter counts the bytes written through it.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func (vd *volApi) export(w http.ResponseWriter, r *http.Request) {
+	var snapID string
+	var err error
+
+	method := "export"
+	if snapID, err = vd.parseVolumeID(r); err != nil {
+		e := fmt.Errorf("Failed to parse volumeID: %s", err.Error())
+		vd.sendError(vd.name, method, w, e.Error(), http.StatusBadRequest)
+		return
+	}
+
+	d, err := volumedrivers.Get(vd.name)
+	if err != nil {
+		notFound(w, r)
+		return
+	}
+
+	vd.logRequest(method, snapID).Infoln("")
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	cw := &countingWriter{w: w}
+	if err := d.Export(snapID, r.URL.Query().Get(api.OptParent), cw); err != nil {
+		e := fmt.Errorf("Failed to export volume: %s", err.Error())
+		if cw.n == 0 {
+			vd.sendError(vd.name, method, w, e.Error(), http.StatusBadRequest)
+			return
+		}
+		// The status was sent with the data, so the receiver only sees
+		// a truncated stream.
+		vd.logRequest(method, snapID).Warnln(e.Error())
+	}
+}
+
+func (vd *volApi) volumeImport(w http.ResponseWriter, r *http.Request) {
+	var locator api.VolumeLocator
+	var dcRes api.VolumeCreateResponse
+	method := "import"
+
+	d, err := volumedrivers.Get(vd.name)
+	if err != nil {
+		notFound(w, r)
+		return
+	}
+	params := r.URL.Query()
+	locator.Name = params.Get(api.OptName)
+	if v := params.Get(api.OptLabel); len(v) != 0 {
+		if err := json.Unmarshal([]byte(v), &locator.VolumeLabels); err != nil {
+			e := fmt.Errorf("Failed to parse VolumeLabels: %s", err.Error())
+			vd.sendError(vd.name, method, w, e.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	id, err := d.Import(&locator, r.Body)
+	dcRes.VolumeResponse = &api.VolumeResponse{Error: responseStatus(err)}
+	dcRes.Id = id
+
+	vd.logRequest(method, id).Infoln("")
+
+	json.NewEncoder(w).Encode(&dcRes)
+}
+
 func (vd *volApi) alerts(w http.ResponseWriter, r *http.Request) {
 	var volumeID string
 	var err error
@@ -483,6 +601,9 @@ func (vd *volApi) Routes() []*Route {
 		{verb: "GET", path: volPath("/stats/{id}", volume.APIVersion), fn: vd.stats},
 		{verb: "GET", path: volPath("/usedsize", volume.APIVersion), fn: vd.usedsize},
 		{verb: "GET", path: volPath("/usedsize/{id}", volume.APIVersion), fn: vd.usedsize},
+		{verb: "POST", path: volPath("/fstrim/{id}", volume.APIVersion), fn: vd.fstrim},
+		{verb: "GET", path: volPath("/export/{id}", volume.APIVersion), fn: vd.export},
+		{verb: "POST", path: volPath("/import", volume.APIVersion), fn: vd.volumeImport},
 		{verb: "GET", path: volPath("/alerts", volume.APIVersion), fn: vd.alerts},
 		{verb: "GET", path: volPath("/alerts/{id}", volume.APIVersion), fn: vd.alerts},
 		{verb: "GET", path: volPath("/requests", volume.APIVersion), fn: vd.requests},
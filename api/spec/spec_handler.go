@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"regexp"
 	"strconv"
+	"strings"
 
 	"github.com/libopenstorage/openstorage/api"
 	"github.com/libopenstorage/openstorage/pkg/units"
@@ -48,6 +49,10 @@ var (
 	sharedRegex     = regexp.MustCompile(api.SpecShared + "=([A-Za-z]+),?")
 	passphraseRegex = regexp.MustCompile(api.SpecPassphrase + "=([0-9A-Za-z_@./#&+-]+),?")
 	stickyRegex     = regexp.MustCompile(api.SpecSticky + "=([A-Za-z]+),?")
+	accessModeRegex = regexp.MustCompile(api.SpecAccessMode + "=([A-Za-z_]+),?")
+	ioProfileRegex  = regexp.MustCompile(api.SpecIoProfile + "=([A-Za-z_]+),?")
+	maxIopsRegex    = regexp.MustCompile(api.SpecMaxIops + "=([0-9]+),?")
+	maxBwRegex      = regexp.MustCompile(api.SpecMaxBandwidth + "=([0-9A-Za-z]+),?")
 )
 
 type specHandler struct {
@@ -136,6 +141,24 @@ func (d *specHandler) SpecFromOpts(
 		case api.SpecPriority:
 			cos, _ := api.CosTypeSimpleValueOf(v)
 			spec.Cos = cos
+		case api.SpecIoProfile:
+			if ioProfile, err := api.IoProfileSimpleValueOf(v); err != nil {
+				return nil, nil, err
+			} else {
+				spec.IoProfile = ioProfile
+			}
+		case api.SpecMaxIops:
+			if maxIops, err := strconv.ParseUint(v, 10, 64); err != nil {
+				return nil, nil, err
+			} else {
+				spec.MaxIops = maxIops
+			}
+		case api.SpecMaxBandwidth:
+			if maxBandwidth, err := units.Parse(v); err != nil {
+				return nil, nil, err
+			} else {
+				spec.MaxBandwidth = uint64(maxBandwidth)
+			}
 		case api.SpecDedupe:
 			spec.Dedupe, _ = strconv.ParseBool(v)
 		case api.SpecSnapshotInterval:
@@ -163,6 +186,24 @@ func (d *specHandler) SpecFromOpts(
 		case api.SpecPassphrase:
 			spec.Encrypted = true
 			spec.Passphrase = v
+		case api.SpecAccessMode:
+			if accessMode, err := api.AccessModeSimpleValueOf(v); err != nil {
+				return nil, nil, err
+			} else {
+				spec.AccessMode = accessMode
+			}
+		case api.SpecCompressed:
+			if compressed, err := strconv.ParseBool(v); err != nil {
+				return nil, nil, err
+			} else {
+				spec.Compressed = compressed
+			}
+		case api.SpecMountOptions:
+			for _, o := range strings.Split(v, ",") {
+				if o = strings.TrimSpace(o); len(o) != 0 {
+					spec.MountOptions = append(spec.MountOptions, o)
+				}
+			}
 		default:
 			spec.VolumeLabels[k] = v
 		}
@@ -208,6 +249,18 @@ func (d *specHandler) SpecFromString(
 	if ok, passphrase := d.getVal(passphraseRegex, str); ok {
 		opts[api.SpecPassphrase] = passphrase
 	}
+	if ok, accessMode := d.getVal(accessModeRegex, str); ok {
+		opts[api.SpecAccessMode] = accessMode
+	}
+	if ok, ioProfile := d.getVal(ioProfileRegex, str); ok {
+		opts[api.SpecIoProfile] = ioProfile
+	}
+	if ok, maxIops := d.getVal(maxIopsRegex, str); ok {
+		opts[api.SpecMaxIops] = maxIops
+	}
+	if ok, maxBandwidth := d.getVal(maxBwRegex, str); ok {
+		opts[api.SpecMaxBandwidth] = maxBandwidth
+	}
 
 	spec, source, err := d.SpecFromOpts(opts)
 	if err != nil {
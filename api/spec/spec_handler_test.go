@@ -0,0 +1,61 @@
+package spec
+
+import (
+	"testing"
+
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpecFromOptsAccess(t *testing.T) {
+	s := NewSpecHandler()
+	spec, _, err := s.SpecFromOpts(map[string]string{
+		api.SpecAccessMode:   "rox",
+		api.SpecMountOptions: "noexec, nosuid,discard",
+		api.SpecCompressed:   "true",
+	})
+	require.NoError(t, err)
+	require.Equal(t, api.AccessMode_ACCESS_MODE_READ_ONLY_MANY, spec.AccessMode)
+	require.Equal(t, []string{"noexec", "nosuid", "discard"}, spec.MountOptions)
+	require.True(t, spec.Compressed)
+
+	spec, _, err = s.SpecFromOpts(map[string]string{
+		api.SpecAccessMode: "read_write_many",
+	})
+	require.NoError(t, err)
+	require.Equal(t, api.AccessMode_ACCESS_MODE_READ_WRITE_MANY, spec.AccessMode)
+
+	_, _, err = s.SpecFromOpts(map[string]string{api.SpecAccessMode: "bogus"})
+	require.Error(t, err)
+}
+
+func TestSpecFromStringAccess(t *testing.T) {
+	s := NewSpecHandler()
+	parsed, spec, _, name := s.SpecFromString("name=vol1,access_mode=rwo")
+	require.True(t, parsed)
+	require.Equal(t, "vol1", name)
+	require.Equal(t, api.AccessMode_ACCESS_MODE_READ_WRITE_ONCE, spec.AccessMode)
+}
+
+func TestSpecFromOptsThrottle(t *testing.T) {
+	s := NewSpecHandler()
+	spec, _, err := s.SpecFromOpts(map[string]string{
+		api.SpecPriority:     "low",
+		api.SpecIoProfile:    "random",
+		api.SpecMaxIops:      "300",
+		api.SpecMaxBandwidth: "10M",
+	})
+	require.NoError(t, err)
+	require.Equal(t, api.CosType_LOW, spec.Cos)
+	require.Equal(t, api.IoProfile_IO_PROFILE_RANDOM, spec.IoProfile)
+	require.Equal(t, uint64(300), spec.MaxIops)
+	require.Equal(t, uint64(10<<20), spec.MaxBandwidth)
+
+	_, _, err = s.SpecFromOpts(map[string]string{api.SpecMaxIops: "many"})
+	require.Error(t, err)
+
+	parsed, spec, _, _ := s.SpecFromString("name=vol1,max_iops=100,io_profile=db")
+	require.True(t, parsed)
+	require.Equal(t, uint64(100), spec.MaxIops)
+	require.Equal(t, api.IoProfile_IO_PROFILE_DB, spec.IoProfile)
+}
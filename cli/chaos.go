@@ -0,0 +1,162 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/codegangsta/cli"
+
+	chaosclient "github.com/libopenstorage/openstorage/api/client/chaos"
+	"github.com/libopenstorage/openstorage/pkg/chaos"
+)
+
+type chaosClient struct {
+	manager chaosclient.Manager
+}
+
+func (c *chaosClient) chaosOptions(context *cli.Context) {
+	// Currently we choose the default version
+	clnt, err := chaosclient.NewChaosClient("", chaos.APIVersion)
+	if err != nil {
+		fmt.Printf("Failed to initialize client library: %v\n", err)
+		os.Exit(1)
+	}
+	c.manager = chaosclient.ChaosManager(clnt)
+}
+
+// chaosID parses the chaos point id argument of cmd.
+func chaosID(context *cli.Context, cmd string) chaos.ID {
+	if len(context.Args()) != 1 {
+		missingParameter(context, cmd, "id", "Chaos point id is required")
+	}
+	id, err := strconv.ParseUint(context.Args()[0], 10, 32)
+	if err != nil {
+		badParameter(context, cmd, "id", "Chaos point id is not a number")
+	}
+	return chaos.ID(id)
+}
+
+func (c *chaosClient) list(context *cli.Context) {
+	c.chaosOptions(context)
+	fn := "list"
+
+	ko, err := c.manager.Enumerate(context.String("pkg"))
+	if err != nil {
+		cmdError(context, fn, err)
+		return
+	}
+	if context.GlobalBool("json") {
+		fmtOutput(context, &Format{Result: ko})
+		return
+	}
+	w := new(tabwriter.Writer)
+	w.Init(os.Stdout, 12, 12, 1, ' ', 0)
+	fmt.Fprintln(w, "ID\t PKG\t FN\t ENABLED\t WHEN\t ACTION\t COUNT\t DESC")
+	for _, v := range ko {
+		when, what := "-", "-"
+		if v.Enabled {
+			when, what = v.When.String(), v.What.String()
+			if v.When == chaos.Random {
+				when = fmt.Sprintf("%v(%v)", when, v.Rate)
+			}
+			if v.What == chaos.Delay {
+				what = fmt.Sprintf("%v(%v)", what, v.Delay)
+			}
+		}
+		fmt.Fprintln(w, v.ID, "\t", v.Pkg, "\t", v.Fn, "\t", v.Enabled, "\t",
+			when, "\t", what, "\t", v.Count, "\t", v.Desc)
+	}
+	fmt.Fprintln(w)
+	w.Flush()
+}
+
+func (c *chaosClient) enable(context *cli.Context) {
+	fn := "enable"
+	id := chaosID(context, fn)
+	when, err := chaos.ParseWhen(context.String("when"))
+	if err != nil {
+		badParameter(context, fn, "when", err.Error())
+	}
+	what, err := chaos.ParseAction(context.String("action"))
+	if err != nil {
+		badParameter(context, fn, "action", err.Error())
+	}
+	c.chaosOptions(context)
+	if err := c.manager.Enable(id, when, what, chaos.Options{
+		Delay: context.Duration("delay"),
+		Rate:  context.Float64("rate"),
+	}); err != nil {
+		cmdError(context, fn, err)
+		return
+	}
+	fmtOutput(context, &Format{UUID: []string{id.String()}})
+}
+
+func (c *chaosClient) disable(context *cli.Context) {
+	fn := "disable"
+	id := chaosID(context, fn)
+	c.chaosOptions(context)
+	if err := c.manager.Disable(id); err != nil {
+		cmdError(context, fn, err)
+		return
+	}
+	fmtOutput(context, &Format{UUID: []string{id.String()}})
+}
+
+// ChaosCommands exports CLI commands for the chaos points of the daemon.
+func ChaosCommands() []cli.Command {
+	c := &chaosClient{}
+
+	commands := []cli.Command{
+		{
+			Name:    "list",
+			Aliases: []string{"l"},
+			Usage:   "List chaos points",
+			Action:  c.list,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "pkg,p",
+					Usage: "Only list the chaos points of a package, e.g btrfs",
+					Value: "",
+				},
+			},
+		},
+		{
+			Name:    "enable",
+			Aliases: []string{"e"},
+			Usage:   "Enable a chaos point: enable [options] id",
+			Action:  c.enable,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "when,w",
+					Usage: "When the chaos point triggers: once, random or always",
+					Value: "once",
+				},
+				cli.StringFlag{
+					Name:  "action,a",
+					Usage: "What the chaos point does: crash, error, delay or hang",
+					Value: "error",
+				},
+				cli.DurationFlag{
+					Name:  "delay,d",
+					Usage: "How long the delay action sleeps",
+					Value: chaos.DefaultDelay,
+				},
+				cli.Float64Flag{
+					Name:  "rate,r",
+					Usage: "Probability in [0, 1] that a random chaos point triggers",
+					Value: chaos.DefaultRate,
+				},
+			},
+		},
+		{
+			Name:    "disable",
+			Aliases: []string{"d"},
+			Usage:   "Disable a chaos point and release the calls it hangs: disable id",
+			Action:  c.disable,
+		},
+	}
+	return commands
+}
@@ -33,7 +33,11 @@ func TestCmdMarshalProto(t *testing.T) {
  "scale": 0,
  "sticky": false,
  "max_backups": 0,
- "backup_schedule": ""
+ "backup_schedule": "",
+ "access_mode": "none",
+ "compressed": false,
+ "max_iops": "0",
+ "max_bandwidth": "0"
 }`,
 		data,
 	)
@@ -3,9 +3,13 @@ package cli
 import (
 	"fmt"
 	"os"
+	"sort"
+	"strings"
 	"text/tabwriter"
+	"time"
 
 	humanize "github.com/dustin/go-humanize"
+	"go.pedge.io/proto/time"
 
 	"github.com/codegangsta/cli"
 
@@ -13,6 +17,8 @@ import (
 	"github.com/libopenstorage/openstorage/api"
 	clusterclient "github.com/libopenstorage/openstorage/api/client/cluster"
 	"github.com/libopenstorage/openstorage/cluster"
+	"github.com/libopenstorage/openstorage/pkg/units"
+	"github.com/libopenstorage/openstorage/volume"
 )
 
 type clusterClient struct {
@@ -87,7 +93,135 @@ func (c *clusterClient) inspect(context *cli.Context) {
 
 	if jsonOut {
 		fmtOutput(context, &Format{Cluster: &cluster})
-	} else {
+		return
+	}
+	outFd := os.Stdout
+	for _, n := range cluster.Nodes {
+		fmt.Fprintf(outFd, "Node %s (%s): Status: %v\n\n", n.Id, n.Hostname, n.Status)
+
+		fmt.Fprintf(outFd, "Disks:\n")
+		printDisks(n.Disks)
+
+		fmt.Fprintf(outFd, "Storage Pools:\n")
+		w := new(tabwriter.Writer)
+		w.Init(outFd, 12, 12, 1, ' ', 0)
+		fmt.Fprintln(w, "ID\t COS\t MEDIUM\t RAID LEVEL\t TOTAL SIZE\t USED")
+		for _, p := range n.Pools {
+			fmt.Fprintln(w, p.ID, "\t", p.Cos.SimpleString(), "\t",
+				p.Medium.SimpleString(), "\t", p.RaidLevel, "\t",
+				humanize.Bytes(p.TotalSize), "\t", humanize.Bytes(p.Used))
+		}
+		fmt.Fprintln(w)
+		w.Flush()
+	}
+}
+
+// printDisks prints a table of disks and their benchmark results.
+func printDisks(disks map[string]api.StorageResource) {
+	w := new(tabwriter.Writer)
+	w.Init(os.Stdout, 12, 12, 1, ' ', 0)
+	fmt.Fprintln(w, "PATH\t MEDIUM\t ROTATION\t SIZE\t USED\t ONLINE\t"+
+		" IOPS\t SEQ READ\t SEQ WRITE\t RAND RW\t LAST SCAN")
+	paths := make([]string, 0, len(disks))
+	for path := range disks {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		d := disks[path]
+		lastScan := "-"
+		if d.LastScan != nil {
+			lastScan = prototime.TimestampToTime(d.LastScan).Format(time.RFC3339)
+		}
+		fmt.Fprintln(w, d.Path, "\t", d.Medium.SimpleString(), "\t",
+			d.RotationSpeed, "\t", humanize.Bytes(d.Size), "\t",
+			humanize.Bytes(d.Used), "\t", d.Online, "\t", d.Iops, "\t",
+			throughput(d.SeqRead), "\t", throughput(d.SeqWrite), "\t",
+			throughput(d.RandRW), "\t", lastScan)
+	}
+	fmt.Fprintln(w)
+	w.Flush()
+}
+
+// throughput formats a throughput in bytes per second.
+func throughput(bps float64) string {
+	if bps == 0 {
+		return "-"
+	}
+	return humanize.Bytes(uint64(bps)) + "/s"
+}
+
+func (c *clusterClient) benchmark(context *cli.Context) {
+	c.clusterOptions(context)
+	fn := "benchmark"
+
+	disks, err := c.manager.Benchmark()
+	if err != nil {
+		cmdError(context, fn, err)
+		return
+	}
+	if context.GlobalBool("json") {
+		fmtOutput(context, &Format{Result: disks})
+		return
+	}
+	printDisks(disks)
+}
+
+func (c *clusterClient) placement(context *cli.Context) {
+	c.clusterOptions(context)
+	fn := "placement"
+
+	size, err := units.Parse(context.String("size"))
+	if err != nil {
+		cmdError(context, fn, err)
+		return
+	}
+	cos, err := api.CosTypeSimpleValueOf(context.String("cos"))
+	if err != nil {
+		cmdError(context, fn, err)
+		return
+	}
+	spec := &api.VolumeSpec{
+		Size:         uint64(size),
+		HaLevel:      int64(context.Int("repl")),
+		Cos:          cos,
+		VolumeLabels: make(map[string]string),
+	}
+	if nodes := context.String("nodes"); nodes != "" {
+		spec.ReplicaSet = &api.ReplicaSet{Nodes: strings.Split(nodes, ",")}
+	}
+	if constraint := context.String("constraint"); constraint != "" {
+		spec.VolumeLabels[volume.LocationConstraint] = constraint
+	}
+
+	var placed []string
+	if nodes := context.String("placed"); nodes != "" {
+		placed = strings.Split(nodes, ",")
+	}
+	p, err := c.manager.Place(spec, placed, context.String("failure_domain"))
+	if p == nil {
+		cmdError(context, fn, err)
+		return
+	}
+	if context.GlobalBool("json") {
+		fmtOutput(context, &Format{Result: p})
+		return
+	}
+	w := new(tabwriter.Writer)
+	w.Init(os.Stdout, 12, 12, 1, ' ', 0)
+	fmt.Fprintln(w, "NODE	 DOMAIN	 POOL	 FREE	 SELECTED	 REASON")
+	for _, cand := range p.Candidates {
+		pool := "-"
+		if cand.Pool >= 0 {
+			pool = fmt.Sprint(cand.Pool)
+		}
+		fmt.Fprintln(w, cand.Node, "\t", cand.Domain, "\t", pool, "\t",
+			humanize.Bytes(cand.Free), "\t", cand.Selected, "\t", cand.Reason)
+	}
+	fmt.Fprintln(w)
+	w.Flush()
+	if err != nil {
+		cmdError(context, fn, err)
 	}
 }
 
@@ -174,6 +308,51 @@ func ClusterCommands() []cli.Command {
 				},
 			},
 		},
+		{
+			Name:    "benchmark",
+			Aliases: []string{"b"},
+			Usage:   "Benchmark the disks of the node and set the Cos of its pools",
+			Action:  c.benchmark,
+		},
+		{
+			Name:    "placement",
+			Aliases: []string{"p"},
+			Usage:   "Explain which nodes would store the replicas of a new volume",
+			Action:  c.placement,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "size,s",
+					Usage: "volume size, e.g 10G",
+					Value: "1G",
+				},
+				cli.IntFlag{
+					Name:  "repl,r",
+					Usage: "replication factor",
+					Value: 1,
+				},
+				cli.StringFlag{
+					Name:  "cos",
+					Usage: "Class of Service: [high|medium|low|none]",
+					Value: "none",
+				},
+				cli.StringFlag{
+					Name:  "nodes,n",
+					Usage: "Comma separated node ids the replicas are pinned to",
+				},
+				cli.StringFlag{
+					Name:  "placed",
+					Usage: "Comma separated node ids that already store replicas",
+				},
+				cli.StringFlag{
+					Name:  "constraint",
+					Usage: "LocalNode or comma separated node labels, e.g zone=a",
+				},
+				cli.StringFlag{
+					Name:  "failure_domain",
+					Usage: "node label replicas are spread across, e.g rack",
+				},
+			},
+		},
 		{
 			Name:    "disable-gossip",
 			Aliases: []string{"dg"},
@@ -0,0 +1,72 @@
+package cli
+
+import (
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/codegangsta/cli"
+	"github.com/libopenstorage/openstorage/pkg/mount"
+	"github.com/libopenstorage/openstorage/volume"
+)
+
+// mountTable is the output of the mount list command for one mount table.
+type mountTable struct {
+	Journal string         `json:"journal"`
+	Table   *mount.Table   `json:"table,omitempty"`
+	Drift   []*mount.Drift `json:"drift,omitempty"`
+	Err     string         `json:"error,omitempty"`
+}
+
+func mountList(c *cli.Context) {
+	cmd := "mount list"
+	dir := c.String("dir")
+	journals := []string(c.Args())
+	if len(journals) == 0 {
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			cmdError(c, cmd, err)
+			return
+		}
+		for _, f := range files {
+			if !f.IsDir() {
+				journals = append(journals, f.Name())
+			}
+		}
+	}
+	tables := make([]*mountTable, 0, len(journals))
+	for _, j := range journals {
+		if !filepath.IsAbs(j) {
+			j = filepath.Join(dir, j)
+		}
+		t := &mountTable{Journal: j}
+		table, drift, err := mount.InspectJournal(mount.NewFileJournal(j))
+		if err != nil {
+			t.Err = err.Error()
+		} else {
+			t.Table = table
+			t.Drift = drift
+		}
+		tables = append(tables, t)
+	}
+	fmtOutput(c, &Format{Result: tables})
+}
+
+// MountCommands exports the list of CLI mount subcommands.
+func MountCommands() []cli.Command {
+	commands := []cli.Command{
+		{
+			Name:    "list",
+			Aliases: []string{"l"},
+			Usage:   "List saved mount tables and their drift from the kernel mount table",
+			Action:  mountList,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "dir,d",
+					Usage: "directory of mount tables",
+					Value: volume.MountJournalBase,
+				},
+			},
+		},
+	}
+	return commands
+}
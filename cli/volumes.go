@@ -8,6 +8,7 @@ import (
 	"github.com/codegangsta/cli"
 	"github.com/libopenstorage/openstorage/api"
 	volumeclient "github.com/libopenstorage/openstorage/api/client/volume"
+	"github.com/libopenstorage/openstorage/pkg/units"
 	"github.com/libopenstorage/openstorage/volume"
 )
 
@@ -49,6 +50,24 @@ func processLabels(s string) (map[string]string, error) {
 	return m, nil
 }
 
+// ioLimits sets the I/O profile and limits of spec from the command flags.
+func ioLimits(context *cli.Context, spec *api.VolumeSpec) error {
+	ioProfile, err := api.IoProfileSimpleValueOf(context.String("io_profile"))
+	if err != nil {
+		return err
+	}
+	spec.IoProfile = ioProfile
+	spec.MaxIops = uint64(context.Int("max_iops"))
+	if bw := context.String("max_bandwidth"); bw != "" {
+		maxBandwidth, err := units.Parse(bw)
+		if err != nil {
+			return err
+		}
+		spec.MaxBandwidth = uint64(maxBandwidth)
+	}
+	return nil
+}
+
 func (v *volDriver) volumeOptions(context *cli.Context) {
 	// Currently we choose the default version
 	clnt, err := volumeclient.NewDriverClient("", v.name, volume.APIVersion)
@@ -92,6 +111,15 @@ func (v *volDriver) volumeCreate(context *cli.Context) {
 		cmdError(context, fn, err)
 		return
 	}
+	accessMode, err := api.AccessModeSimpleValueOf(context.String("access_mode"))
+	if err != nil {
+		cmdError(context, fn, err)
+		return
+	}
+	var mountOptions []string
+	if o := context.String("mount_options"); o != "" {
+		mountOptions = strings.Split(o, ",")
+	}
 	spec := &api.VolumeSpec{
 		Size:             uint64(VolumeSzUnits(context.Int("s")) * MiB),
 		Format:           fsType,
@@ -99,6 +127,13 @@ func (v *volDriver) volumeCreate(context *cli.Context) {
 		HaLevel:          int64(context.Int("r")),
 		Cos:              cosType,
 		SnapshotInterval: uint32(context.Int("si")),
+		AccessMode:       accessMode,
+		MountOptions:     mountOptions,
+		Compressed:       context.Bool("compressed"),
+	}
+	if err := ioLimits(context, spec); err != nil {
+		cmdError(context, fn, err)
+		return
 	}
 	source := &api.Source{
 		Seed: context.String("seed"),
@@ -157,6 +192,32 @@ func (v *volDriver) volumeUnmount(context *cli.Context) {
 	fmtOutput(context, &Format{UUID: []string{volumeID}})
 }
 
+func (v *volDriver) volumeSet(context *cli.Context) {
+	v.volumeOptions(context)
+	fn := "set"
+	if len(context.Args()) != 1 {
+		missingParameter(context, fn, "volumeID", "Invalid number of arguments")
+		return
+	}
+	volumeID := context.Args()[0]
+	cosType, err := api.CosTypeSimpleValueOf(context.String("cos"))
+	if err != nil {
+		cmdError(context, fn, err)
+		return
+	}
+	spec := &api.VolumeSpec{Cos: cosType}
+	if err := ioLimits(context, spec); err != nil {
+		cmdError(context, fn, err)
+		return
+	}
+	if err := v.volDriver.Set(volumeID, nil, spec); err != nil {
+		cmdError(context, fn, err)
+		return
+	}
+
+	fmtOutput(context, &Format{UUID: []string{volumeID}})
+}
+
 func (v *volDriver) volumeAttach(context *cli.Context) {
 	fn := "attach"
 	if len(context.Args()) < 1 {
@@ -248,6 +309,87 @@ func (v *volDriver) volumeAlerts(context *cli.Context) {
 	cmdOutputProto(alerts, context.GlobalBool("raw"))
 }
 
+func (v *volDriver) volumeFstrim(context *cli.Context) {
+	v.volumeOptions(context)
+	fn := "fstrim"
+	if len(context.Args()) != 1 {
+		missingParameter(context, fn, "volumeID", "Invalid number of arguments")
+		return
+	}
+
+	trimmed, err := v.volDriver.Fstrim(string(context.Args()[0]))
+	if err != nil {
+		cmdError(context, fn, err)
+		return
+	}
+
+	fmtOutput(context, &Format{
+		UUID:   []string{context.Args()[0]},
+		Result: trimmed,
+	})
+}
+
+func (v *volDriver) volumeExport(context *cli.Context) {
+	v.volumeOptions(context)
+	fn := "export"
+	if len(context.Args()) != 1 {
+		missingParameter(context, fn, "snapID", "Invalid number of arguments")
+		return
+	}
+	out := os.Stdout
+	if file := context.String("file"); file != "" {
+		f, err := os.Create(file)
+		if err != nil {
+			cmdError(context, fn, err)
+			return
+		}
+		defer f.Close()
+		out = f
+	}
+
+	err := v.volDriver.Export(context.Args()[0], context.String("parent"), out)
+	if err != nil {
+		cmdError(context, fn, err)
+		return
+	}
+	if out != os.Stdout {
+		fmtOutput(context, &Format{UUID: []string{context.Args()[0]}})
+	}
+}
+
+func (v *volDriver) volumeImport(context *cli.Context) {
+	var err error
+	var labels map[string]string
+	fn := "import"
+
+	v.volumeOptions(context)
+	if l := context.String("label"); l != "" {
+		if labels, err = processLabels(l); err != nil {
+			cmdError(context, fn, err)
+			return
+		}
+	}
+	in := os.Stdin
+	if file := context.String("file"); file != "" {
+		if in, err = os.Open(file); err != nil {
+			cmdError(context, fn, err)
+			return
+		}
+		defer in.Close()
+	}
+	locator := &api.VolumeLocator{
+		Name:         context.String("name"),
+		VolumeLabels: labels,
+	}
+	id, err := v.volDriver.Import(locator, in)
+	if err != nil {
+		cmdError(context, fn, err)
+		return
+	}
+
+	fmtOutput(context, &Format{UUID: []string{id}})
+}
+
 func (v *volDriver) volumeEnumerate(context *cli.Context) {
 	locator := &api.VolumeLocator{}
 	var err error
@@ -388,14 +530,40 @@ func baseVolumeCommand(v *volDriver) []cli.Command {
 				},
 				cli.StringFlag{
 					Name:  "cos",
-					Usage: "Class of Service: [high|medium|low]",
-					Value: "low",
+					Usage: "Class of Service: [high|medium|low|none]",
+					Value: "none",
+				},
+				cli.StringFlag{
+					Name:  "io_profile",
+					Usage: "I/O profile: [sequential|random|db]",
+					Value: "sequential",
+				},
+				cli.IntFlag{
+					Name:  "max_iops",
+					Usage: "maximum read and write IOPS, 0 is unlimited",
+				},
+				cli.StringFlag{
+					Name:  "max_bandwidth",
+					Usage: "maximum read and write bytes per second, e.g 100M",
 				},
 				cli.IntFlag{
 					Name:  "snap_interval,si",
 					Usage: "snapshot interval in minutes, 0 disables snaps",
 					Value: 0,
 				},
+				cli.StringFlag{
+					Name:  "access_mode",
+					Usage: "concurrent mounts allowed: [rwo|rox|rwx]",
+					Value: "none",
+				},
+				cli.StringFlag{
+					Name:  "mount_options",
+					Usage: "Comma separated mount options, e.g noexec,nosuid",
+				},
+				cli.BoolFlag{
+					Name:  "compressed",
+					Usage: "compress the data on the volume",
+				},
 			},
 		},
 		{
@@ -460,6 +628,70 @@ func baseVolumeCommand(v *volDriver) []cli.Command {
 			Usage:  "volume stats",
 			Action: v.volumeStats,
 		},
+		{
+			Name:   "set",
+			Usage:  "Set the class of service and I/O limits of a volume",
+			Action: v.volumeSet,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "cos",
+					Usage: "Class of Service: [high|medium|low]",
+					Value: "high",
+				},
+				cli.StringFlag{
+					Name:  "io_profile",
+					Usage: "I/O profile: [sequential|random|db]",
+					Value: "sequential",
+				},
+				cli.IntFlag{
+					Name:  "max_iops",
+					Usage: "maximum read and write IOPS, 0 is unlimited",
+				},
+				cli.StringFlag{
+					Name:  "max_bandwidth",
+					Usage: "maximum read and write bytes per second, e.g 100M",
+				},
+			},
+		},
+		{
+			Name:   "fstrim",
+			Usage:  "Discard unused blocks of a mounted volume",
+			Action: v.volumeFstrim,
+		},
+		{
+			Name:   "export",
+			Usage:  "Export the data of a readonly snap",
+			Action: v.volumeExport,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "parent,p",
+					Usage: "only export the changes since this snap, which was imported before",
+				},
+				cli.StringFlag{
+					Name:  "file,f",
+					Usage: "file to write the data to instead of stdout",
+				},
+			},
+		},
+		{
+			Name:   "import",
+			Usage:  "Create a readonly volume from exported data",
+			Action: v.volumeImport,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "name",
+					Usage: "user friendly name",
+				},
+				cli.StringFlag{
+					Name:  "label,l",
+					Usage: "Comma separated name=value pairs, e.g name=sqlvolume,type=production",
+				},
+				cli.StringFlag{
+					Name:  "file,f",
+					Usage: "file to read the data from instead of stdin",
+				},
+			},
+		},
 		{
 			Name:    "snap",
 			Aliases: []string{"sc"},
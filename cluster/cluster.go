@@ -8,6 +8,8 @@ import (
 	"github.com/libopenstorage/gossip/types"
 	"github.com/libopenstorage/openstorage/api"
 	"github.com/libopenstorage/openstorage/config"
+	"github.com/libopenstorage/openstorage/pkg/disk"
+	"github.com/libopenstorage/openstorage/pkg/placement"
 	"github.com/portworx/kvdb"
 )
 
@@ -118,6 +120,14 @@ type ClusterListener interface {
 	QuorumMember(node *api.Node) bool
 }
 
+// StoragePoolListener is implemented by cluster listeners that provision
+// storage from pools on this node.
+type StoragePoolListener interface {
+	// ListenerPools returns the storage pools of the listener, which are
+	// reported in the Pools field of this node.
+	ListenerPools() []api.StoragePool
+}
+
 // ClusterState is the gossip state of all nodes in the cluster
 type ClusterState struct {
 	NodeStatus []types.NodeValue
@@ -167,6 +177,28 @@ type ClusterRemove interface {
 	NodeRemoveDone(nodeID string, result error)
 }
 
+// ClusterDisks interface provides apis for the disks of THIS node
+type ClusterDisks interface {
+	// Benchmark measures the performance of the disks of THIS node and
+	// returns them by path. The results are reported in the Disks of this
+	// node and set the Cos of its Pools.
+	Benchmark() (map[string]api.StorageResource, error)
+}
+
+// ClusterPlacement interface provides apis to place the data of volumes
+type ClusterPlacement interface {
+	// Place picks the nodes of the cluster that store the replicas of a
+	// volume and explains the choice. The placed nodes already store
+	// replicas of the volume. Replicas are spread across the values of the
+	// failureDomain node label, or of the configured one if it is empty.
+	// Nothing is allocated.
+	Place(
+		spec *api.VolumeSpec,
+		placed []string,
+		failureDomain string,
+	) (*placement.Placement, error)
+}
+
 // Cluster is the API that a cluster provider will implement.
 type Cluster interface {
 	// Inspect the node given a UUID.
@@ -193,6 +225,8 @@ type Cluster interface {
 	ClusterData
 	ClusterRemove
 	ClusterStatus
+	ClusterDisks
+	ClusterPlacement
 }
 
 // ClusterNotify is the callback function listeners can use to notify cluster manager
@@ -216,6 +250,7 @@ func Init(cfg config.ClusterConfig) error {
 		kv:           kv,
 		nodeCache:    make(map[string]api.Node),
 		nodeStatuses: make(map[string]api.Status),
+		benchmarks:   make(map[string]disk.Result),
 	}
 
 	return nil
@@ -228,3 +263,105 @@ func Inst() (Cluster, error) {
 	}
 	return inst, nil
 }
+
+// NullClusterListener is a NULL implementation of ClusterListener functions
+// ClusterListeners should use this as the base override functions they
+// are interested in.
+type NullClusterListener struct{}
+
+// String returns a string representation of this listener.
+func (nc *NullClusterListener) String() string {
+	return "NullClusterListener"
+}
+
+// ClusterInit is called when a brand new cluster is initialized.
+func (nc *NullClusterListener) ClusterInit(self *api.Node) error {
+	return nil
+}
+
+// Init is called when this node is joining an existing cluster for the
+// first time.
+func (nc *NullClusterListener) Init(
+	self *api.Node,
+	state *ClusterInfo,
+) (FinalizeInitCb, error) {
+	return nil, nil
+}
+
+// CleanupInit is called when Init failed.
+func (nc *NullClusterListener) CleanupInit(
+	self *api.Node,
+	clusterInfo *ClusterInfo,
+) error {
+	return nil
+}
+
+// Halt is called when a node is gracefully shutting down.
+func (nc *NullClusterListener) Halt(
+	self *api.Node,
+	clusterInfo *ClusterInfo,
+) error {
+	return nil
+}
+
+// Join is called when this node is joining an existing cluster.
+func (nc *NullClusterListener) Join(
+	self *api.Node,
+	state *ClusterInitState,
+	clusterNotify ClusterNotify,
+) error {
+	return nil
+}
+
+// Add is called when a new node joins the cluster.
+func (nc *NullClusterListener) Add(node *api.Node) error {
+	return nil
+}
+
+// Remove is called when a node leaves the cluster
+func (nc *NullClusterListener) Remove(node *api.Node, forceRemove bool) error {
+	return nil
+}
+
+// CanNodeRemove test to see if we can remove this node
+func (nc *NullClusterListener) CanNodeRemove(node *api.Node) error {
+	return nil
+}
+
+// MarkNodeDown marks the given node's status as down
+func (nc *NullClusterListener) MarkNodeDown(node *api.Node) error {
+	return nil
+}
+
+// Update is called when a node status changes significantly
+// in the cluster changes.
+func (nc *NullClusterListener) Update(node *api.Node) error {
+	return nil
+}
+
+// Leave is called when this node leaves the cluster.
+func (nc *NullClusterListener) Leave(node *api.Node) error {
+	return nil
+}
+
+// ListenerStatus returns the listener's Status
+func (nc *NullClusterListener) ListenerStatus() api.Status {
+	return api.Status_STATUS_NONE
+}
+
+// ListenerPeerStatus returns the peer Statuses for a listener
+func (nc *NullClusterListener) ListenerPeerStatus() map[string]api.Status {
+	return nil
+}
+
+// ListenerData returns the data that the listener wants to share
+// with ClusterManager and would be stored in NodeData field.
+func (nc *NullClusterListener) ListenerData() map[string]interface{} {
+	return nil
+}
+
+// QuorumMember returns true if the listener wants this node to
+// participate in quorum decisions.
+func (nc *NullClusterListener) QuorumMember(node *api.Node) bool {
+	return true
+}
@@ -21,6 +21,8 @@ import (
 	"github.com/libopenstorage/gossip/types"
 	"github.com/libopenstorage/openstorage/api"
 	"github.com/libopenstorage/openstorage/config"
+	"github.com/libopenstorage/openstorage/pkg/disk"
+	"github.com/libopenstorage/openstorage/pkg/placement"
 	"github.com/libopenstorage/systemutils"
 	"github.com/portworx/kvdb"
 )
@@ -29,6 +31,7 @@ const (
 	heartbeatKey       = "heartbeat"
 	clusterLockKey     = "/cluster/lock"
 	gossipVersionKey   = "Gossip Version"
+	diskScanInterval   = time.Minute
 	decommissionErrMsg = "Node %s must be offline or in maintenance " +
 		"mode to be decommissioned."
 )
@@ -60,6 +63,9 @@ type ClusterManager struct {
 	selfNode      api.Node
 	selfNodeLock  sync.Mutex // Lock that guards data and label of selfNode
 	system        systemutils.System
+	disks         map[string]api.StorageResource // Last scan of the disks of selfNode.
+	benchmarks    map[string]disk.Result         // Benchmarks of the disks by path.
+	benchmarkLock sync.Mutex                     // Lock that serializes benchmarks
 }
 
 type checkFunc func(ClusterInfo) error
@@ -287,6 +293,26 @@ func (c *ClusterManager) getCurrentState() *api.Node {
 		}
 	}
 
+	c.selfNode.Disks = make(map[string]api.StorageResource, len(c.disks))
+	for path, d := range c.disks {
+		if result, ok := c.benchmarks[path]; ok {
+			result.Apply(&d)
+		}
+		c.selfNode.Disks[path] = d
+	}
+
+	pools := disk.Pools(c.selfNode.Disks)
+	for e := c.listeners.Front(); e != nil; e = e.Next() {
+		if l, ok := e.Value.(StoragePoolListener); ok {
+			pools = append(pools, l.ListenerPools()...)
+		}
+	}
+	// Pool IDs are unique on this node across all listeners.
+	for i := range pools {
+		pools[i].ID = int32(i)
+	}
+	c.selfNode.Pools = pools
+
 	nodeCopy := (&c.selfNode).Copy()
 	return nodeCopy
 }
@@ -300,8 +326,8 @@ func (c *ClusterManager) getNonDecommisionedPeers(
 			continue
 		}
 		peers[types.NodeId(nodeEntry.Id)] = types.NodeUpdate{
-			nodeEntry.DataIp + ":9002",
-			!nodeEntry.NonQuorumMember,
+			Addr:         nodeEntry.DataIp + ":9002",
+			QuorumMember: !nodeEntry.NonQuorumMember,
 		}
 	}
 	return peers
@@ -774,6 +800,90 @@ func (c *ClusterManager) EnableUpdates() error {
 	return nil
 }
 
+// Benchmark measures the performance of the disks of this node one at a
+// time.
+func (c *ClusterManager) Benchmark() (map[string]api.StorageResource, error) {
+	c.benchmarkLock.Lock()
+	defer c.benchmarkLock.Unlock()
+
+	c.selfNodeLock.Lock()
+	disks := make(map[string]api.StorageResource)
+	for path, d := range c.disks {
+		disks[path] = d
+	}
+	c.selfNodeLock.Unlock()
+
+	for path, d := range disks {
+		result, err := disk.Benchmark(path, disk.DefaultBenchOptions)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to benchmark disk %v: %v", path, err)
+		}
+		dlog.Infof("Disk %v: %v IOPS, %v B/s sequential read, %v B/s sequential write",
+			path, result.Iops, uint64(result.SeqRead), uint64(result.SeqWrite))
+		result.Apply(&d)
+		disks[path] = d
+
+		c.selfNodeLock.Lock()
+		c.benchmarks[path] = *result
+		c.selfNodeLock.Unlock()
+	}
+	return disks, nil
+}
+
+// benchmarkDisks benchmarks the disks of this node at every interval.
+func (c *ClusterManager) benchmarkDisks(interval time.Duration) {
+	for {
+		time.Sleep(interval)
+		if _, err := c.Benchmark(); err != nil {
+			dlog.Warnf("Scheduled disk benchmark failed: %v", err)
+		}
+	}
+}
+
+// scanDisks updates the disks of this node. Scans read sysfs and run
+// outside of selfNodeLock.
+func (c *ClusterManager) scanDisks() {
+	disks, err := disk.Scan(c.config.Devices)
+	if err != nil {
+		dlog.Warnf("Failed to scan disks: %v", err)
+		return
+	}
+	c.selfNodeLock.Lock()
+	c.disks = disks
+	c.selfNodeLock.Unlock()
+}
+
+// watchDisks scans the disks of this node at every diskScanInterval.
+func (c *ClusterManager) watchDisks() {
+	for {
+		time.Sleep(diskScanInterval)
+		c.scanDisks()
+	}
+}
+
+// Place picks the nodes that store the replicas of a volume from the nodes
+// of the cluster.
+func (c *ClusterManager) Place(
+	spec *api.VolumeSpec,
+	placed []string,
+	failureDomain string,
+) (*placement.Placement, error) {
+	cluster, err := c.Enumerate()
+	if err != nil {
+		return nil, err
+	}
+	if len(failureDomain) == 0 {
+		failureDomain = c.config.FailureDomain
+	}
+	return placement.Place(&placement.Request{
+		Spec:          spec,
+		Nodes:         cluster.Nodes,
+		Self:          cluster.NodeId,
+		Placed:        placed,
+		FailureDomain: failureDomain,
+	})
+}
+
 // GetGossipState returns current gossip state
 func (c *ClusterManager) GetGossipState() *ClusterState {
 	gossipStoreKey := types.StoreKey(heartbeatKey + c.config.ClusterId)
@@ -1021,6 +1131,8 @@ func (c *ClusterManager) Start(
 
 	c.selfNode.NodeData = make(map[string]interface{})
 	c.system = systemutils.New()
+	c.scanDisks()
+	go c.watchDisks()
 
 	// Start the gossip protocol.
 	// XXX Make the port configurable.
@@ -1063,6 +1175,9 @@ func (c *ClusterManager) Start(
 	}
 
 	go c.updateClusterStatus()
+	if interval := c.config.BenchmarkInterval; interval > 0 {
+		go c.benchmarkDisks(interval)
+	}
 	go c.replayNodeDecommission()
 
 	return nil
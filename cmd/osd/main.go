@@ -17,6 +17,8 @@ import (
 	"github.com/libopenstorage/openstorage/cluster"
 	"github.com/libopenstorage/openstorage/config"
 	"github.com/libopenstorage/openstorage/graph/drivers"
+	"github.com/libopenstorage/openstorage/pkg/chaos"
+	"github.com/libopenstorage/openstorage/pkg/seed"
 	"github.com/libopenstorage/openstorage/volume"
 	"github.com/libopenstorage/openstorage/volume/drivers"
 	"github.com/portworx/kvdb"
@@ -61,6 +63,10 @@ func main() {
 			Usage: "file to read the OSD configuration from.",
 			Value: "",
 		},
+		cli.BoolFlag{
+			Name:  "chaos",
+			Usage: "Activate chaos points and serve the chaos API in daemon mode",
+		},
 	}
 	app.Action = wrapAction(start)
 	app.Commands = []cli.Command{
@@ -76,6 +82,17 @@ func main() {
 			Usage:       "Manage cluster",
 			Subcommands: osdcli.ClusterCommands(),
 		},
+		{
+			Name:        "mount",
+			Aliases:     []string{"m"},
+			Usage:       "Inspect mount tables",
+			Subcommands: osdcli.MountCommands(),
+		},
+		{
+			Name:        "chaos",
+			Usage:       "Manage chaos points of the daemon",
+			Subcommands: osdcli.ChaosCommands(),
+		},
 		{
 			Name:    "version",
 			Aliases: []string{"v"},
@@ -90,7 +107,7 @@ func main() {
 		switch v.DriverType {
 		case api.DriverType_DRIVER_TYPE_BLOCK:
 			bCmds := osdcli.BlockVolumeCommands(v.Name)
-			cmds := append(bCmds)
+			cmds := bCmds
 			c := cli.Command{
 				Name:        v.Name,
 				Usage:       fmt.Sprintf("Manage %s storage", v.Name),
@@ -99,7 +116,7 @@ func main() {
 			app.Commands = append(app.Commands, c)
 		case api.DriverType_DRIVER_TYPE_FILE:
 			fCmds := osdcli.FileVolumeCommands(v.Name)
-			cmds := append(fCmds)
+			cmds := fCmds
 			c := cli.Command{
 				Name:        v.Name,
 				Usage:       fmt.Sprintf("Manage %s volumes", v.Name),
@@ -143,6 +160,7 @@ func start(c *cli.Context) error {
 	if err != nil {
 		return err
 	}
+	seed.SetFileRoots(cfg.Osd.SeedRoots)
 	kvdbURL := c.String("kvdb")
 	u, err := url.Parse(kvdbURL)
 	scheme := u.Scheme
@@ -156,6 +174,14 @@ func start(c *cli.Context) error {
 		return fmt.Errorf("Failed to initialize KVDB: %v", err)
 	}
 
+	if c.Bool("chaos") {
+		dlog.Warnf("OSD activating chaos points.")
+		chaos.Activate(true)
+		if err := server.StartChaosAPI(chaos.APIBase, 0); err != nil {
+			return fmt.Errorf("Unable to start chaos API server: %v", err)
+		}
+	}
+
 	// Start the cluster state machine, if enabled.
 	clusterInit := false
 	if cfg.Osd.ClusterConfig.NodeId != "" && cfg.Osd.ClusterConfig.ClusterId != "" {
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"time"
 
 	"gopkg.in/yaml.v2"
 
@@ -37,6 +38,16 @@ type ClusterConfig struct {
 	DefaultDriver string
 	MgmtIp        string
 	DataIp        string
+	// Devices are the local disks osd uses for storage. Other disks are
+	// not reported in the disks and pools of the node.
+	Devices []string
+	// BenchmarkInterval is the time between benchmarks of the disks.
+	// Scheduled benchmarks are disabled if it is not positive.
+	BenchmarkInterval time.Duration
+	// FailureDomain is the node label, such as rack or zone, whose values
+	// fail independently. The replicas of volumes are placed in distinct
+	// failure domains.
+	FailureDomain string
 }
 
 type Config struct {
@@ -46,6 +57,9 @@ type Config struct {
 		Drivers map[string]map[string]string
 		// map[string]string is volume.VolumeParams equivalent
 		GraphDrivers map[string]map[string]string
+		// SeedRoots are the host directories that file:// seeds may be
+		// loaded from. File seeds are disabled if there are none.
+		SeedRoots []string
 	}
 }
 
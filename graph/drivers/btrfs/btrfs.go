@@ -0,0 +1,179 @@
+// +build linux,have_btrfs
+
+package btrfs
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path"
+	"strings"
+
+	"go.pedge.io/dlog"
+
+	"github.com/docker/docker/daemon/graphdriver"
+	"github.com/docker/docker/daemon/graphdriver/btrfs"
+	"github.com/docker/docker/pkg/idtools"
+	"github.com/docker/docker/pkg/parsers"
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/graph"
+	"github.com/libopenstorage/openstorage/pkg/qgroup"
+	"github.com/libopenstorage/openstorage/pkg/units"
+)
+
+// Btrfs graphdriver stores every image layer as a btrfs subvolume snapshot
+// of its parent. The writable layer of a container can be limited in size
+// with a btrfs qgroup. To use this as the graphdriver in Docker:
+//
+// DOCKER_STORAGE_OPTIONS= -s btrfsgraph --storage-opt btrfsgraph.size=10G
+
+const (
+	// Name of the driver. It differs from the btrfs volume driver, which
+	// has its own CLI command and plugin socket.
+	Name = "btrfsgraph"
+	// Type of the driver
+	Type = api.DriverType_DRIVER_TYPE_GRAPH
+	// DefaultSizeOpt is the driver option for the default size limit of
+	// a container's writable layer.
+	DefaultSizeOpt = "btrfsgraph.size"
+	// SizeOpt is the storage option for the size limit of a layer.
+	SizeOpt = "size"
+)
+
+// Driver implements the graphdriver interface
+type Driver struct {
+	// Driver is the docker btrfs graphdriver. Only select methods are overridden
+	graphdriver.Driver
+	// home base string
+	home string
+	// defaultSize is the size limit applied to container writable layers.
+	defaultSize uint64
+}
+
+func init() {
+	graph.Register(Name, Init)
+}
+
+// Init initializes the driver
+func Init(home string, options []string, uidMaps, gidMaps []idtools.IDMap) (graphdriver.Driver, error) {
+	var defaultSize uint64
+	for _, option := range options {
+		key, val, err := parsers.ParseKeyValueOpt(option)
+		if err != nil {
+			return nil, err
+		}
+		switch key {
+		case DefaultSizeOpt:
+			if defaultSize, err = parseSize(val); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("Unknown option %s\n", key)
+		}
+	}
+	d, err := btrfs.Init(home, nil, uidMaps, gidMaps)
+	if err != nil {
+		return nil, err
+	}
+	if err := qgroup.Enable(home); err != nil {
+		return nil, err
+	}
+	dlog.Infof("Btrfs graphdriver at %v, default layer size %v",
+		home, units.String(defaultSize))
+	return &Driver{
+		Driver:      d,
+		home:        home,
+		defaultSize: defaultSize,
+	}, nil
+}
+
+func parseSize(val string) (uint64, error) {
+	size, err := units.Parse(val)
+	if err != nil {
+		return 0, err
+	}
+	if size < 0 {
+		return 0, fmt.Errorf("Invalid size %s", val)
+	}
+	return uint64(size), nil
+}
+
+func (d *Driver) subvolumesDir() string {
+	return path.Join(d.home, "subvolumes")
+}
+
+func (d *Driver) subvolumePath(id string) string {
+	return path.Join(d.subvolumesDir(), id)
+}
+
+// Status returns the driver status along with the usage of every layer.
+func (d *Driver) Status() [][2]string {
+	status := d.Driver.Status()
+	layers, err := ioutil.ReadDir(d.subvolumesDir())
+	if err != nil {
+		return status
+	}
+	for _, layer := range layers {
+		q, err := qgroup.Inspect(d.subvolumePath(layer.Name()))
+		if err != nil {
+			continue
+		}
+		usage := units.String(q.Exclusive)
+		if q.MaxExclusive != 0 {
+			usage = fmt.Sprintf("%s (limit %s)",
+				usage, units.String(q.MaxExclusive))
+		}
+		status = append(status, [2]string{layer.Name(), usage})
+	}
+	return status
+}
+
+// Create creates a new layer as a snapshot of its parent. If a size is
+// specified, or this is a container's writable layer, the layer is limited
+// to that size.
+func (d *Driver) Create(id string, parent string, mountLabel string, storageOpts map[string]string) error {
+	var size uint64
+	// This relies on an <instance_id>-init layer being created as the
+	// parent of every container's writable layer.
+	if strings.HasSuffix(parent, "-init") {
+		size = d.defaultSize
+	}
+	for key, val := range storageOpts {
+		switch strings.ToLower(key) {
+		case SizeOpt:
+			var err error
+			if size, err = parseSize(val); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("Unknown option %s\n", key)
+		}
+	}
+	if err := d.Driver.Create(id, parent, mountLabel, nil); err != nil {
+		return err
+	}
+	if size == 0 {
+		return nil
+	}
+	if err := qgroup.Limit(d.subvolumePath(id), size); err != nil {
+		d.Driver.Remove(id)
+		return err
+	}
+	return nil
+}
+
+// Remove removes a layer and its qgroup.
+func (d *Driver) Remove(id string) error {
+	q, err := qgroup.Inspect(d.subvolumePath(id))
+	if err != nil {
+		dlog.Warnf("Failed to find qgroup for layer %v: %v", id, err)
+	}
+	if err := d.Driver.Remove(id); err != nil {
+		return err
+	}
+	if q != nil {
+		if err := qgroup.Destroy(q.ID, d.home); err != nil {
+			dlog.Warnf("Failed to destroy qgroup %v: %v", q.ID, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+// +build linux,have_btrfs
+
+package btrfs
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/docker/daemon/graphdriver"
+	"github.com/libopenstorage/openstorage/pkg/qgroup"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	btrfsFile = "/var/btrfsgraph"
+	testPath  = "/var/test_btrfsgraph"
+
+	KiB = 1024
+	MiB = KiB * 1024
+	GiB = MiB * 1024
+)
+
+func setup(t *testing.T, options []string) graphdriver.Driver {
+	output, err := exec.Command("umount", testPath).Output()
+	if err != nil {
+		t.Logf("error on umount %s (not fatal): %s %v", testPath, string(output), err)
+	}
+	if err := os.Remove(btrfsFile); err != nil {
+		t.Logf("error on rm %s (not fatal): %v", btrfsFile, err)
+	}
+	if err := os.MkdirAll(testPath, 0755); err != nil {
+		t.Fatalf("failed on mkdir -p %s: %v", testPath, err)
+	}
+	file, err := os.Create(btrfsFile)
+	if err != nil {
+		t.Fatalf("failed to setup btrfs file at %s: %v", btrfsFile, err)
+	}
+	if err := file.Truncate(GiB); err != nil {
+		t.Fatalf("failed to truncate %s 1G  %v", btrfsFile, err)
+	}
+	output, err = exec.Command("mkfs", "-t", "btrfs", "-f", btrfsFile).Output()
+	if err != nil {
+		t.Fatalf("failed to format to btrfs: %s %v", string(output), err)
+	}
+	output, err = exec.Command("mount", btrfsFile, testPath).Output()
+	if err != nil {
+		t.Fatalf("failed to mount to btrfs: %s %v", string(output), err)
+	}
+	d, err := Init(testPath, options, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to initialize Driver: %v", err)
+	}
+	return d
+}
+
+func TestCreateEmpty(t *testing.T) {
+	d := setup(t, nil)
+	defer d.Cleanup()
+	require.NoError(t, d.Create("empty", "", "", nil))
+	require.True(t, d.Exists("empty"))
+	dir, err := d.Get("empty", "")
+	require.NoError(t, err)
+	entries, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+	require.NoError(t, d.Put("empty"))
+	require.NoError(t, d.Remove("empty"))
+	require.False(t, d.Exists("empty"))
+}
+
+func TestCreateSnap(t *testing.T) {
+	d := setup(t, nil)
+	defer d.Cleanup()
+	require.NoError(t, d.Create("base", "", "", nil))
+	dir, err := d.Get("base", "")
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "a"), []byte("a"), 0644))
+	require.NoError(t, d.Put("base"))
+
+	require.NoError(t, d.Create("snap", "base", "", nil))
+	dir, err = d.Get("snap", "")
+	require.NoError(t, err)
+	b, err := ioutil.ReadFile(filepath.Join(dir, "a"))
+	require.NoError(t, err)
+	require.Equal(t, "a", string(b))
+	require.NoError(t, d.Put("snap"))
+
+	require.NoError(t, d.Remove("snap"))
+	require.NoError(t, d.Remove("base"))
+}
+
+func TestSize(t *testing.T) {
+	d := setup(t, []string{DefaultSizeOpt + "=10M"})
+	defer d.Cleanup()
+	require.Error(t, d.Create("bad", "", "", map[string]string{"unknown": "1"}))
+	require.False(t, d.Exists("bad"))
+
+	// The writable layer of a container has the default size.
+	require.NoError(t, d.Create("c-init", "", "", nil))
+	require.NoError(t, d.Create("c", "c-init", "", nil))
+	q, err := qgroup.Inspect(filepath.Join(testPath, "subvolumes", "c"))
+	require.NoError(t, err)
+	require.Equal(t, uint64(10*MiB), q.MaxExclusive)
+
+	require.NoError(t, d.Create("sized", "", "", map[string]string{SizeOpt: "4M"}))
+	dir, err := d.Get("sized", "")
+	require.NoError(t, err)
+	defer d.Put("sized")
+	data := bytes.Repeat([]byte{1}, 8*MiB)
+	file := filepath.Join(dir, "data")
+	ioutil.WriteFile(file, data, 0644)
+	exec.Command("sync").Run()
+	require.Error(t, ioutil.WriteFile(file+"2", data, 0644),
+		"Writes beyond the size of the layer must fail")
+}
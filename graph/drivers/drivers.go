@@ -2,6 +2,7 @@ package graphdrivers
 
 import (
 	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/graph/drivers/btrfs"
 	"github.com/libopenstorage/openstorage/graph/drivers/chainfs"
 	"github.com/libopenstorage/openstorage/graph/drivers/layer0"
 	"github.com/libopenstorage/openstorage/graph/drivers/proxy"
@@ -17,6 +18,8 @@ type Driver struct {
 var (
 	// AllDrivers is a slice of all existing known Drivers.
 	AllDrivers = []Driver{
+		// Btrfs driver uses btrfs subvolume snapshots with qgroup limits.
+		{DriverType: btrfs.Type, Name: btrfs.Name},
 		// ChainFS driver implements a chained filesystem using FUSE.
 		{DriverType: chainfs.Type, Name: chainfs.Name},
 		// Layer0 driver provides persistent storage for the writable layer.
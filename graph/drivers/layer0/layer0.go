@@ -7,16 +7,17 @@ import (
 	"strings"
 	"sync"
 	"sync/atomic"
+	"syscall"
 
 	"go.pedge.io/dlog"
 
 	"github.com/docker/docker/daemon/graphdriver"
-	"github.com/docker/docker/daemon/graphdriver/overlay"
 	"github.com/docker/docker/pkg/archive"
 	"github.com/docker/docker/pkg/idtools"
 	"github.com/docker/docker/pkg/parsers"
 	"github.com/libopenstorage/openstorage/api"
 	"github.com/libopenstorage/openstorage/graph"
+	"github.com/libopenstorage/openstorage/graph/drivers/proxy"
 	"github.com/libopenstorage/openstorage/volume"
 	"github.com/libopenstorage/openstorage/volume/drivers"
 )
@@ -27,6 +28,11 @@ import (
 // To use this as the graphdriver in Docker with aws as the backend volume provider:
 //
 // DOCKER_STORAGE_OPTIONS= -s layer0 --storage-opt layer0.volume_driver=aws
+//
+// The size of the writeable layer can be limited with the size storage option.
+// A persistent layer is limited by the size of its volume, so only volumes no
+// larger than the requested size are used. Other layers are limited by the
+// proxy driver.
 
 // Layer0Vol represents the volume
 type Layer0Vol struct {
@@ -53,6 +59,8 @@ type Layer0 struct {
 	volumes map[string]*Layer0Vol
 	// volDriver is the volume driver used for the writeable layer.
 	volDriver volume.VolumeDriver
+	// monitor raises alerts for volumes that are running out of space.
+	monitor graph.Monitor
 }
 
 // Layer0Graphdriver options. This should be passed in as a st
@@ -69,19 +77,23 @@ func init() {
 	graph.Register(Name, Init)
 }
 
-// Init initializes the driver
+// Init initializes the driver. The options that are not layer0 options are
+// passed to the proxy driver.
 func Init(home string, options []string, uidMaps, gidMaps []idtools.IDMap) (graphdriver.Driver, error) {
 	var volumeDriver string
+	var proxyOptions []string
 	for _, option := range options {
 		key, val, err := parsers.ParseKeyValueOpt(option)
 		if err != nil {
 			return nil, err
 		}
-		switch key {
-		case Layer0VolumeDriver:
+		switch {
+		case key == Layer0VolumeDriver:
 			volumeDriver = val
-		default:
+		case strings.HasPrefix(key, Name+"."):
 			return nil, fmt.Errorf("Unknown option %s\n", key)
+		default:
+			proxyOptions = append(proxyOptions, option)
 		}
 	}
 	dlog.Infof("Layer0 volume driver: %v", volumeDriver)
@@ -89,7 +101,7 @@ func Init(home string, options []string, uidMaps, gidMaps []idtools.IDMap) (grap
 	if err != nil {
 		return nil, err
 	}
-	ov, err := overlay.Init(home, options, uidMaps, gidMaps)
+	ov, err := proxy.Init(home, proxyOptions, uidMaps, gidMaps)
 	if err != nil {
 		volDriver.Shutdown()
 		return nil, err
@@ -99,6 +111,8 @@ func Init(home string, options []string, uidMaps, gidMaps []idtools.IDMap) (grap
 		home:      home,
 		volumes:   make(map[string]*Layer0Vol),
 		volDriver: volDriver,
+		monitor: graph.NewMonitor(Name, graph.DefaultHighWaterMark,
+			graph.DefaultMonitorInterval),
 	}
 
 	return d, nil
@@ -136,7 +150,7 @@ func (l *Layer0) realID(id string) string {
 	return id
 }
 
-func (l *Layer0) create(id, parent string) (string, *Layer0Vol, error) {
+func (l *Layer0) create(id, parent string, size uint64) (string, *Layer0Vol, error) {
 	l.Lock()
 	defer l.Unlock()
 
@@ -169,16 +183,20 @@ func (l *Layer0) create(id, parent string) (string, *Layer0Vol, error) {
 		return id, nil, nil
 	}
 
-	// Find a volume that is available.
+	// Find a volume that is available and within the requested size.
 	index := -1
 	for i, v := range vols {
+		if size != 0 && (v.Spec == nil || v.Spec.Size > size) {
+			continue
+		}
 		if len(v.AttachPath) == 0 {
 			index = i
 			break
 		}
 	}
 	if index == -1 {
-		dlog.Infof("Failed to find free volume for id %v", vol.parent)
+		dlog.Infof("Failed to find free volume for id %v of size %v",
+			vol.parent, size)
 		delete(l.volumes, id)
 		return id, nil, nil
 	}
@@ -211,14 +229,27 @@ func (l *Layer0) create(id, parent string) (string, *Layer0Vol, error) {
 
 // Create creates a new and empty filesystem layer
 func (l *Layer0) Create(id string, parent string, mountLabel string, storageOpts map[string]string) error {
-	id, vol, err := l.create(id, parent)
+	size, opts, err := proxy.ParseSize(storageOpts)
 	if err != nil {
 		return err
 	}
-	err = l.Driver.Create(id, parent, mountLabel, storageOpts)
-	if err != nil || vol == nil {
+	id, vol, err := l.create(id, parent, size)
+	if err != nil {
 		return err
 	}
+	if vol == nil {
+		// Not a layer0, let the proxy driver enforce the size.
+		if size != 0 {
+			dlog.Infof("Layer %v has no layer0 volume, its size is "+
+				"limited with project quotas", id)
+		}
+		return l.Driver.Create(id, parent, mountLabel, storageOpts)
+	}
+	// The volume size limits a layer0, so drop the size option.
+	if err = l.Driver.Create(id, parent, mountLabel, opts); err != nil {
+		return err
+	}
+	l.monitor.Add(vol.id, volumeUsage(vol.path))
 	// This is layer0. Restore saved upper dir, if one exists.
 	savedUpper := path.Join(vol.path, "upper")
 	if _, err := os.Stat(savedUpper); err != nil {
@@ -257,6 +288,7 @@ func (l *Layer0) Remove(id string) error {
 			}
 			err = os.RemoveAll(v.path)
 			delete(l.volumes, v.id)
+			l.monitor.Remove(v.id)
 		}
 	} else {
 		dlog.Warnf("Failed to find layer0 vol for id %v", id)
@@ -293,3 +325,21 @@ func (l *Layer0) GetMetadata(id string) (map[string]string, error) {
 	id = l.realID(id)
 	return l.Driver.GetMetadata(id)
 }
+
+// Cleanup stops the usage monitor and releases the driver.
+func (l *Layer0) Cleanup() error {
+	l.monitor.Stop()
+	return l.Driver.Cleanup()
+}
+
+func volumeUsage(path string) graph.UsageFunc {
+	return func() (uint64, uint64, error) {
+		var statfs syscall.Statfs_t
+		if err := syscall.Statfs(path, &statfs); err != nil {
+			return 0, 0, err
+		}
+		limit := statfs.Blocks * uint64(statfs.Bsize)
+		used := (statfs.Blocks - statfs.Bfree) * uint64(statfs.Bsize)
+		return used, limit, nil
+	}
+}
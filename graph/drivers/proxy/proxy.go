@@ -1,9 +1,26 @@
 package proxy
 
 import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"go.pedge.io/dlog"
+
+	"github.com/docker/docker/daemon/graphdriver"
 	"github.com/docker/docker/daemon/graphdriver/overlay"
+	"github.com/docker/docker/pkg/archive"
+	"github.com/docker/docker/pkg/idtools"
 	"github.com/libopenstorage/openstorage/api"
 	"github.com/libopenstorage/openstorage/graph"
+	"github.com/libopenstorage/openstorage/pkg/mount"
+	"github.com/libopenstorage/openstorage/pkg/projectquota"
+	"github.com/libopenstorage/openstorage/pkg/units"
+	"github.com/libopenstorage/openstorage/volume"
+	"github.com/opencontainers/runc/libcontainer/label"
 )
 
 const (
@@ -11,8 +28,253 @@ const (
 	Name = "proxy"
 	// Type of the driver
 	Type = api.DriverType_DRIVER_TYPE_GRAPH
+	// SizeOpt is the storage option for the size limit of a layer.
+	SizeOpt = "size"
 )
 
+// Driver uses the Docker overlay driver and limits the size of the upper
+// dir of a layer with xfs project quotas. Layers are mounted through an
+// overlay mount manager, which refcounts them.
+type Driver struct {
+	// Driver diffs the layers of the overlay graphdriver. Only select
+	// methods are overridden
+	graphdriver.Driver
+	// overlay is the overlay graphdriver.
+	overlay graphdriver.Driver
+	// home base string
+	home string
+	// quota controls the project quotas of the upper dirs, nil if the
+	// backing filesystem does not support them.
+	quota projectquota.Control
+	// monitor raises alerts for layers that are running out of space.
+	monitor graph.Monitor
+	// mounter tracks the overlay mounts of the layers.
+	mounter mount.Manager
+	uidMaps []idtools.IDMap
+	gidMaps []idtools.IDMap
+}
+
 func init() {
-	graph.Register(Name, overlay.Init)
+	graph.Register(Name, Init)
+}
+
+// Init initializes the driver
+func Init(home string, options []string, uidMaps, gidMaps []idtools.IDMap) (graphdriver.Driver, error) {
+	ov, err := overlay.Init(home, options, uidMaps, gidMaps)
+	if err != nil {
+		return nil, err
+	}
+	mounter, err := mount.New(
+		mount.OverlayMount,
+		nil,
+		[]string{home},
+		mount.NewFileJournal(filepath.Join(volume.MountJournalBase,
+			"graph-"+path.Base(home))),
+	)
+	if err != nil {
+		return nil, err
+	}
+	quota, err := projectquota.NewControl(home)
+	if err != nil {
+		dlog.Warnf("Layer size limits are disabled for %v: %v", home, err)
+	}
+	d := &Driver{
+		overlay: ov,
+		home:    home,
+		quota:   quota,
+		monitor: graph.NewMonitor(Name, graph.DefaultHighWaterMark,
+			graph.DefaultMonitorInterval),
+		mounter: mounter,
+		uidMaps: uidMaps,
+		gidMaps: gidMaps,
+	}
+	// The diffs mount the layers with Get and Put of this driver, so that
+	// the mounts are only refcounted by the mounter.
+	d.Driver = graphdriver.NewNaiveDiffDriver(
+		&protoDriver{Driver: ov, proxy: d},
+		uidMaps,
+		gidMaps,
+	)
+	d.watchLayers()
+	return d, nil
+}
+
+// protoDriver is the overlay graphdriver with the Get and Put of the proxy
+// driver.
+type protoDriver struct {
+	graphdriver.Driver
+	proxy *Driver
+}
+
+func (p *protoDriver) Get(id string, mountLabel string) (string, error) {
+	return p.proxy.Get(id, mountLabel)
+}
+
+func (p *protoDriver) Put(id string) error {
+	return p.proxy.Put(id)
+}
+
+// watchLayers monitors the size limited layers created before a restart.
+func (d *Driver) watchLayers() {
+	if d.quota == nil {
+		return
+	}
+	layers, err := ioutil.ReadDir(d.home)
+	if err != nil {
+		dlog.Warnf("Failed to find the layers of %v: %v", d.home, err)
+		return
+	}
+	for _, layer := range layers {
+		if !layer.IsDir() {
+			continue
+		}
+		usage, err := d.quota.GetQuota(d.upperDir(layer.Name()))
+		if err != nil || usage.Limit == 0 {
+			continue
+		}
+		d.watch(layer.Name())
+	}
+}
+
+// watch monitors the usage of a size limited layer.
+func (d *Driver) watch(id string) {
+	upperDir := d.upperDir(id)
+	d.monitor.Add(id, func() (uint64, uint64, error) {
+		usage, err := d.quota.GetQuota(upperDir)
+		if err != nil {
+			return 0, 0, err
+		}
+		return usage.Used, usage.Limit, nil
+	})
+}
+
+// ParseSize removes the size option from storageOpts and returns its value
+// in bytes, or 0 if no size was specified.
+func ParseSize(storageOpts map[string]string) (uint64, map[string]string, error) {
+	var size uint64
+	opts := make(map[string]string)
+	for key, val := range storageOpts {
+		if strings.ToLower(key) != SizeOpt {
+			opts[key] = val
+			continue
+		}
+		bytes, err := units.Parse(val)
+		if err != nil {
+			return 0, nil, err
+		}
+		if bytes < 0 {
+			return 0, nil, fmt.Errorf("Invalid size %s", val)
+		}
+		size = uint64(bytes)
+	}
+	return size, opts, nil
+}
+
+func (d *Driver) upperDir(id string) string {
+	return path.Join(d.home, id, "upper")
+}
+
+// Create creates a new layer. If a size is specified, the layer's upper dir
+// is limited to that size.
+func (d *Driver) Create(id string, parent string, mountLabel string, storageOpts map[string]string) error {
+	size, opts, err := ParseSize(storageOpts)
+	if err != nil {
+		return err
+	}
+	if size != 0 && d.quota == nil {
+		return fmt.Errorf("Option %s is not supported: %v",
+			SizeOpt, projectquota.ErrNotSupported)
+	}
+	if len(opts) == 0 {
+		opts = nil
+	}
+	if err := d.Driver.Create(id, parent, mountLabel, opts); err != nil {
+		return err
+	}
+	if size == 0 {
+		return nil
+	}
+	if err := d.quota.SetQuota(d.upperDir(id), size); err != nil {
+		d.Driver.Remove(id)
+		return err
+	}
+	d.watch(id)
+	return nil
+}
+
+// Remove removes a layer and its size limit.
+func (d *Driver) Remove(id string) error {
+	d.monitor.Remove(id)
+	if d.quota != nil {
+		if err := d.quota.ClearQuota(d.upperDir(id)); err != nil {
+			dlog.Debugf("Failed to clear quota of layer %v: %v", id, err)
+		}
+	}
+	return d.Driver.Remove(id)
+}
+
+// Get mounts the layer and returns its mountpoint. Each Get must be
+// matched by a Put.
+func (d *Driver) Get(id string, mountLabel string) (string, error) {
+	md, err := d.Driver.GetMetadata(id)
+	if err != nil {
+		return "", err
+	}
+	// If id has a root, just return it
+	if rootDir, ok := md["RootDir"]; ok {
+		return rootDir, nil
+	}
+	opts := fmt.Sprintf("lowerdir=%s,upperdir=%s,workdir=%s",
+		md["LowerDir"], md["UpperDir"], md["WorkDir"])
+	if err := d.mounter.Mount(
+		0,
+		md["UpperDir"],
+		md["MergedDir"],
+		mount.OverlayFs,
+		0,
+		label.FormatMountLabel(opts, mountLabel),
+		0,
+	); err != nil {
+		return "", fmt.Errorf("error creating overlay mount to %s: %v",
+			md["MergedDir"], err)
+	}
+	// chown "workdir/work" to the remapped root UID/GID. Overlay fs inside a
+	// user namespace requires this to move a directory from lower to upper.
+	rootUID, rootGID, err := idtools.GetRootUIDGID(d.uidMaps, d.gidMaps)
+	if err == nil {
+		err = os.Chown(path.Join(md["WorkDir"], "work"), rootUID, rootGID)
+	}
+	if err != nil {
+		d.mounter.Unmount(md["UpperDir"], md["MergedDir"], 0)
+		return "", err
+	}
+	return md["MergedDir"], nil
+}
+
+// Put releases a mount of the layer and unmounts it once it is no longer
+// in use.
+func (d *Driver) Put(id string) error {
+	upperDir := d.upperDir(id)
+	err := d.mounter.Unmount(upperDir, path.Join(d.home, id, "merged"), 0)
+	if err == mount.ErrEnoent {
+		dlog.Debugf("Put on a non-mounted layer %v", id)
+		return nil
+	}
+	return err
+}
+
+// ApplyDiff extracts a diff into a layer. The overlay graphdriver applies
+// the diffs of image layers to a hard linked copy of their parent, the other
+// layers are mounted.
+func (d *Driver) ApplyDiff(id string, parent string, diff archive.Reader) (int64, error) {
+	if md, err := d.overlay.GetMetadata(parent); err == nil && len(md["RootDir"]) != 0 {
+		return d.overlay.ApplyDiff(id, parent, diff)
+	}
+	return d.Driver.ApplyDiff(id, parent, diff)
+}
+
+// Cleanup stops the usage monitor and releases the driver.
+func (d *Driver) Cleanup() error {
+	d.monitor.Stop()
+	return d.Driver.Cleanup()
 }
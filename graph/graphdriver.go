@@ -3,9 +3,22 @@ package graph
 import (
 	"errors"
 	"sync"
+	"time"
 
 	"github.com/docker/docker/daemon/graphdriver"
 	"github.com/docker/docker/pkg/idtools"
+	"github.com/libopenstorage/openstorage/alert"
+)
+
+const (
+	// DefaultHighWaterMark is the percentage of a layer's size limit
+	// above which an alert is raised.
+	DefaultHighWaterMark = 90
+	// DefaultMonitorInterval is how often layer usage is checked.
+	DefaultMonitorInterval = time.Minute
+	// AlertTypeHighWaterMark is the alert type raised when a layer crosses
+	// the high water mark.
+	AlertTypeHighWaterMark = alert.AlertTypeLayerHighWaterMark
 )
 
 var (
@@ -23,6 +36,26 @@ var (
 // InitFunc is the initialization function every graphdriver should implement
 type InitFunc func(root string, options []string, uidMaps, gidMaps []idtools.IDMap) (graphdriver.Driver, error)
 
+// UsageFunc returns the bytes used by a layer and its size limit.
+type UsageFunc func() (used uint64, limit uint64, err error)
+
+// Monitor watches the usage of size limited layers and raises an alert
+// when a layer crosses the high water mark.
+type Monitor interface {
+	// Add starts watching the layer id.
+	Add(id string, usage UsageFunc)
+	// Remove stops watching the layer id.
+	Remove(id string)
+	// Stop stops the monitor.
+	Stop()
+}
+
+// NewMonitor returns a Monitor for the layers of the named driver.
+// highWaterMark is a percentage of the layer size limit.
+func NewMonitor(name string, highWaterMark uint64, interval time.Duration) Monitor {
+	return newMonitor(name, highWaterMark, interval)
+}
+
 // Get returns an already registered driver
 func Get(name string) (graphdriver.Driver, error) {
 	if v, ok := instances[name]; ok {
@@ -0,0 +1,143 @@
+package graph
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"go.pedge.io/dlog"
+
+	"github.com/libopenstorage/openstorage/alert"
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/cluster"
+	"github.com/libopenstorage/openstorage/pkg/units"
+	"github.com/portworx/kvdb"
+)
+
+type layerUsage struct {
+	usage UsageFunc
+	// raised is set once an alert is raised, until usage drops
+	// below the high water mark again.
+	raised bool
+}
+
+type monitor struct {
+	sync.Mutex
+	name          string
+	highWaterMark uint64
+	layers        map[string]*layerUsage
+	alert         alert.Alert
+	stop          chan struct{}
+	stopOnce      sync.Once
+}
+
+func newMonitor(name string, highWaterMark uint64, interval time.Duration) *monitor {
+	m := &monitor{
+		name:          name,
+		highWaterMark: highWaterMark,
+		layers:        make(map[string]*layerUsage),
+		stop:          make(chan struct{}),
+	}
+	go m.run(interval)
+	return m
+}
+
+func (m *monitor) Add(id string, usage UsageFunc) {
+	m.Lock()
+	defer m.Unlock()
+	m.layers[id] = &layerUsage{usage: usage}
+}
+
+func (m *monitor) Remove(id string) {
+	m.Lock()
+	defer m.Unlock()
+	delete(m.layers, id)
+}
+
+func (m *monitor) Stop() {
+	m.stopOnce.Do(func() {
+		close(m.stop)
+	})
+}
+
+func (m *monitor) run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.stop:
+			return
+		case <-ticker.C:
+			m.check()
+		}
+	}
+}
+
+func (m *monitor) check() {
+	m.Lock()
+	layers := make(map[string]*layerUsage, len(m.layers))
+	for id, layer := range m.layers {
+		layers[id] = layer
+	}
+	m.Unlock()
+	// Reading the usage may exec a command, so it is done without the
+	// lock that Add and Remove wait for.
+	for id, layer := range layers {
+		used, limit, err := layer.usage()
+		if err != nil || limit == 0 {
+			continue
+		}
+		if used*100 < limit*m.highWaterMark {
+			layer.raised = false
+			continue
+		}
+		if layer.raised {
+			continue
+		}
+		layer.raised = m.raise(id, used, limit)
+	}
+}
+
+func (m *monitor) raise(id string, used uint64, limit uint64) bool {
+	message := fmt.Sprintf("%s layer %s is using %s of %s", m.name, id,
+		units.String(used), units.String(limit))
+	dlog.Warnf("%s", message)
+	alerter, err := m.alerter()
+	if err != nil {
+		dlog.Warnf("Failed to raise alert for layer %v: %v", id, err)
+		return false
+	}
+	if err := alerter.Raise(&api.Alert{
+		Severity:   api.SeverityType_SEVERITY_TYPE_WARNING,
+		AlertType:  AlertTypeHighWaterMark,
+		Message:    message,
+		ResourceId: id,
+		Resource:   api.ResourceType_RESOURCE_TYPE_NODE,
+	}); err != nil {
+		dlog.Warnf("Failed to raise alert for layer %v: %v", id, err)
+		return false
+	}
+	return true
+}
+
+func (m *monitor) alerter() (alert.Alert, error) {
+	if m.alert != nil {
+		return m.alert, nil
+	}
+	kv := kvdb.Instance()
+	if kv == nil {
+		return nil, alert.ErrNotInitialized
+	}
+	var clusterID string
+	if c, err := cluster.Inst(); err == nil {
+		if info, err := c.Enumerate(); err == nil {
+			clusterID = info.Id
+		}
+	}
+	alerter, err := alert.New(alert.Name, clusterID, kv)
+	if err != nil {
+		return nil, err
+	}
+	m.alert = alerter
+	return m.alert, nil
+}
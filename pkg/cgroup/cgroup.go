@@ -0,0 +1,302 @@
+// Package cgroup limits the block I/O of the processes that mount a
+// filesystem with the blkio controller of cgroup v1 or the io controller of
+// cgroup v2.
+package cgroup
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+var (
+	// ErrNotBlock is returned for filesystems, such as NFS, whose I/O does
+	// not go through a local block device and cannot be throttled.
+	ErrNotBlock = errors.New("Filesystem is not stored on a block device")
+	// ErrNotMounted is returned if nothing is mounted at a mount path.
+	ErrNotMounted = errors.New("Path is not a mount point")
+)
+
+var (
+	// cgroupRoot is the mount point of the cgroup hierarchies.
+	cgroupRoot = "/sys/fs/cgroup"
+	// procRoot is the proc filesystem of this host.
+	procRoot = "/proc"
+	// sysDevBlock links device numbers to the block devices in sysfs.
+	sysDevBlock = "/sys/dev/block"
+)
+
+// Limits of the block I/O of a cgroup to a device. Zero is unlimited.
+type Limits struct {
+	// ReadIops is the read operations per second.
+	ReadIops uint64
+	// WriteIops is the write operations per second.
+	WriteIops uint64
+	// ReadBps is the read bytes per second.
+	ReadBps uint64
+	// WriteBps is the write bytes per second.
+	WriteBps uint64
+}
+
+// Unlimited returns true if none of the limits is set.
+func (l Limits) Unlimited() bool {
+	return l == Limits{}
+}
+
+// Device is the number of a block device.
+type Device struct {
+	Major int
+	Minor int
+}
+
+// String returns the device number as major:minor.
+func (d Device) String() string {
+	return fmt.Sprintf("%d:%d", d.Major, d.Minor)
+}
+
+// Unified returns true if the host uses the cgroup v2 hierarchy.
+func Unified() bool {
+	_, err := os.Stat(path.Join(cgroupRoot, "cgroup.controllers"))
+	return err == nil
+}
+
+// ThrottleMount applies limits to the disk that stores the filesystem
+// mounted at mountpath for the cgroups of the processes that mount it in
+// other mount namespaces, such as containers. It returns the cgroups that
+// were throttled.
+func ThrottleMount(mountpath string, limits Limits) ([]string, error) {
+	m, err := mountOf("self", mountpath)
+	if err != nil {
+		return nil, err
+	}
+	dev, err := diskOf(m)
+	if err != nil {
+		return nil, err
+	}
+	cgroups, err := mounters(m)
+	if err != nil {
+		return nil, err
+	}
+	for _, cgroup := range cgroups {
+		if err := Throttle(cgroup, dev, limits); err != nil {
+			return nil, err
+		}
+	}
+	return cgroups, nil
+}
+
+// Throttle applies limits to the I/O of the cgroup to dev. The cgroup is a
+// path relative to the root of the hierarchy, as listed in /proc/pid/cgroup.
+func Throttle(cgroup string, dev Device, limits Limits) error {
+	if Unified() {
+		max := func(v uint64) string {
+			if v == 0 {
+				return "max"
+			}
+			return strconv.FormatUint(v, 10)
+		}
+		return write(path.Join(cgroupRoot, cgroup, "io.max"), fmt.Sprintf(
+			"%v rbps=%v wbps=%v riops=%v wiops=%v", dev,
+			max(limits.ReadBps), max(limits.WriteBps),
+			max(limits.ReadIops), max(limits.WriteIops)))
+	}
+	// A limit of 0 removes the rule of the device.
+	dir := path.Join(cgroupRoot, "blkio", cgroup)
+	for file, v := range map[string]uint64{
+		"blkio.throttle.read_iops_device":  limits.ReadIops,
+		"blkio.throttle.write_iops_device": limits.WriteIops,
+		"blkio.throttle.read_bps_device":   limits.ReadBps,
+		"blkio.throttle.write_bps_device":  limits.WriteBps,
+	} {
+		if err := write(path.Join(dir, file), fmt.Sprintf("%v %v", dev, v)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// write a rule to a cgroup file. The cgroups of processes that exit in the
+// meantime are ignored.
+func write(file, rule string) error {
+	if _, err := os.Stat(path.Dir(file)); os.IsNotExist(err) {
+		return nil
+	}
+	if err := ioutil.WriteFile(file, []byte(rule), 0644); err != nil {
+		return fmt.Errorf("Failed to write %q to %v: %v", rule, file, err)
+	}
+	return nil
+}
+
+// mount is an entry of /proc/pid/mountinfo.
+type mount struct {
+	dev        Device
+	root       string
+	mountpoint string
+	source     string
+}
+
+// mountInfo returns the mounts of a process.
+func mountInfo(pid string) ([]mount, error) {
+	f, err := os.Open(path.Join(procRoot, pid, "mountinfo"))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var mounts []mount
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// id parent major:minor root mountpoint options [optional...] - fstype source super
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 {
+			continue
+		}
+		var m mount
+		if _, err := fmt.Sscanf(fields[2], "%d:%d", &m.dev.Major, &m.dev.Minor); err != nil {
+			continue
+		}
+		m.root = fields[3]
+		m.mountpoint = fields[4]
+		for i := 6; i < len(fields)-2; i++ {
+			if fields[i] == "-" {
+				m.source = fields[i+2]
+				break
+			}
+		}
+		mounts = append(mounts, m)
+	}
+	return mounts, scanner.Err()
+}
+
+// mountOf returns the last mount of a process at mountpoint.
+func mountOf(pid string, mountpoint string) (*mount, error) {
+	mounts, err := mountInfo(pid)
+	if err != nil {
+		return nil, err
+	}
+	mountpoint = path.Clean(mountpoint)
+	for i := len(mounts) - 1; i >= 0; i-- {
+		if mounts[i].mountpoint == mountpoint {
+			return &mounts[i], nil
+		}
+	}
+	return nil, ErrNotMounted
+}
+
+// diskOf returns the whole disk that stores the filesystem of m. Filesystems
+// such as btrfs report an anonymous device, so the device is looked up from
+// the mount source.
+func diskOf(m *mount) (Device, error) {
+	dev := m.dev
+	if dev.Major == 0 {
+		var st syscall.Stat_t
+		if err := syscall.Stat(m.source, &st); err != nil ||
+			st.Mode&syscall.S_IFMT != syscall.S_IFBLK {
+			return dev, ErrNotBlock
+		}
+		dev = Device{
+			Major: int((st.Rdev >> 8 & 0xfff) | (st.Rdev >> 32 &^ 0xfff)),
+			Minor: int((st.Rdev & 0xff) | (st.Rdev >> 12 &^ 0xff)),
+		}
+	}
+	// Partitions cannot be throttled, the disk they are on is.
+	link := path.Join(sysDevBlock, dev.String())
+	if _, err := os.Stat(path.Join(link, "partition")); err != nil {
+		return dev, nil
+	}
+	dir, err := filepath.EvalSymlinks(link)
+	if err != nil {
+		return dev, err
+	}
+	data, err := ioutil.ReadFile(path.Join(path.Dir(dir), "dev"))
+	if err != nil {
+		return dev, err
+	}
+	var disk Device
+	if _, err := fmt.Sscanf(strings.TrimSpace(string(data)), "%d:%d",
+		&disk.Major, &disk.Minor); err != nil {
+		return dev, err
+	}
+	return disk, nil
+}
+
+// mounters returns the cgroups of the processes in other mount namespaces
+// that mount the filesystem of m, or a directory in it.
+func mounters(m *mount) ([]string, error) {
+	self, err := os.Readlink(path.Join(procRoot, "self", "ns", "mnt"))
+	if err != nil {
+		return nil, err
+	}
+	entries, err := ioutil.ReadDir(procRoot)
+	if err != nil {
+		return nil, err
+	}
+	found := make(map[string]bool)
+	var cgroups []string
+	for _, e := range entries {
+		pid := e.Name()
+		if _, err := strconv.Atoi(pid); err != nil {
+			continue
+		}
+		// Processes that exit while they are inspected are skipped.
+		ns, err := os.Readlink(path.Join(procRoot, pid, "ns", "mnt"))
+		if err != nil || ns == self {
+			continue
+		}
+		mounts, err := mountInfo(pid)
+		if err != nil || !mounted(mounts, m) {
+			continue
+		}
+		cgroup, err := cgroupOf(pid)
+		if err != nil || found[cgroup] {
+			continue
+		}
+		found[cgroup] = true
+		cgroups = append(cgroups, cgroup)
+	}
+	return cgroups, nil
+}
+
+// mounted returns true if mounts has the filesystem of m or a directory in
+// it.
+func mounted(mounts []mount, m *mount) bool {
+	dir := strings.TrimSuffix(m.root, "/") + "/"
+	for _, o := range mounts {
+		if o.dev == m.dev && (o.root == m.root || strings.HasPrefix(o.root, dir)) {
+			return true
+		}
+	}
+	return false
+}
+
+// cgroupOf returns the cgroup of a process in the hierarchy that controls
+// block I/O.
+func cgroupOf(pid string) (string, error) {
+	data, err := ioutil.ReadFile(path.Join(procRoot, pid, "cgroup"))
+	if err != nil {
+		return "", err
+	}
+	unified := Unified()
+	for _, line := range strings.Split(string(data), "\n") {
+		// hierarchy-id:controllers:path
+		fields := strings.SplitN(line, ":", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		if unified && fields[0] == "0" && len(fields[1]) == 0 {
+			return fields[2], nil
+		}
+		for _, controller := range strings.Split(fields[1], ",") {
+			if !unified && controller == "blkio" {
+				return fields[2], nil
+			}
+		}
+	}
+	return "", fmt.Errorf("Process %v has no block I/O cgroup", pid)
+}
@@ -0,0 +1,111 @@
+package cgroup
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeHost creates a proc filesystem, sysfs and cgroup hierarchy where a
+// volume on /dev/sda1 is mounted at /mnt/vol1 and by two processes of
+// container abc. Container def mounts another directory of the disk.
+func fakeHost(t *testing.T) string {
+	root, err := ioutil.TempDir("", "cgroup_test")
+	require.NoError(t, err)
+	procRoot = path.Join(root, "proc")
+	cgroupRoot = path.Join(root, "cgroup")
+	sysDevBlock = path.Join(root, "dev", "block")
+
+	write := func(file, data string) {
+		require.NoError(t, os.MkdirAll(path.Dir(file), 0755))
+		require.NoError(t, ioutil.WriteFile(file, []byte(data), 0644))
+	}
+	process := func(pid, ns, mountinfo, cgroup string) {
+		dir := path.Join(procRoot, pid)
+		require.NoError(t, os.MkdirAll(path.Join(dir, "ns"), 0755))
+		require.NoError(t, os.Symlink(ns, path.Join(dir, "ns", "mnt")))
+		write(path.Join(dir, "mountinfo"), mountinfo)
+		write(path.Join(dir, "cgroup"), cgroup)
+	}
+	host := "22 1 8:1 / / rw,relatime shared:1 - ext4 /dev/sda1 rw\n" +
+		"36 22 8:1 /var/lib/osd/volumes/vol1 /mnt/vol1 rw,relatime shared:1 - ext4 /dev/sda1 rw\n"
+	process("self", "mnt:[1]", host, "")
+	process("1", "mnt:[1]", host, "10:blkio:/\n0::/init.scope\n")
+	abc := "400 300 0:50 / / rw - overlay overlay rw\n" +
+		"500 400 8:1 /var/lib/osd/volumes/vol1 /data rw,relatime - ext4 /dev/sda1 rw\n"
+	abcCgroup := "11:cpu,cpuacct:/docker/abc\n10:blkio:/docker/abc\n0::/system.slice/docker-abc.scope\n"
+	process("100", "mnt:[2]", abc, abcCgroup)
+	process("101", "mnt:[2]", abc, abcCgroup)
+	process("200", "mnt:[3]",
+		"500 400 8:1 /var/lib/osd/volumes/vol10 /data rw - ext4 /dev/sda1 rw\n",
+		"10:blkio:/docker/def\n0::/system.slice/docker-def.scope\n")
+
+	require.NoError(t, os.MkdirAll(path.Join(root, "devices", "sda", "sda1"), 0755))
+	write(path.Join(root, "devices", "sda", "dev"), "8:0\n")
+	write(path.Join(root, "devices", "sda", "sda1", "partition"), "1\n")
+	require.NoError(t, os.MkdirAll(sysDevBlock, 0755))
+	require.NoError(t, os.Symlink(path.Join(root, "devices", "sda", "sda1"),
+		path.Join(sysDevBlock, "8:1")))
+	return root
+}
+
+func readRule(t *testing.T, file string) string {
+	data, err := ioutil.ReadFile(path.Join(cgroupRoot, file))
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestThrottleMountV1(t *testing.T) {
+	root := fakeHost(t)
+	defer os.RemoveAll(root)
+	for _, cgroup := range []string{"docker/abc", "docker/def"} {
+		require.NoError(t, os.MkdirAll(path.Join(cgroupRoot, "blkio", cgroup), 0755))
+	}
+
+	_, err := ThrottleMount("/mnt/vol2", Limits{})
+	require.Equal(t, ErrNotMounted, err)
+
+	cgroups, err := ThrottleMount("/mnt/vol1/", Limits{ReadIops: 100, WriteBps: 1 << 20})
+	require.NoError(t, err)
+	require.Equal(t, []string{"/docker/abc"}, cgroups,
+		"Only the container that mounts the volume should be throttled")
+	require.Equal(t, "8:0 100", readRule(t, "blkio/docker/abc/blkio.throttle.read_iops_device"),
+		"The disk of the partition should be throttled")
+	require.Equal(t, "8:0 0", readRule(t, "blkio/docker/abc/blkio.throttle.write_iops_device"))
+	require.Equal(t, "8:0 1048576", readRule(t, "blkio/docker/abc/blkio.throttle.write_bps_device"))
+	_, err = os.Stat(path.Join(cgroupRoot, "blkio/docker/def/blkio.throttle.read_iops_device"))
+	require.True(t, os.IsNotExist(err))
+
+	require.NoError(t, Throttle("/docker/gone", Device{8, 0}, Limits{}),
+		"Cgroups that no longer exist should be ignored")
+}
+
+func TestThrottleMountV2(t *testing.T) {
+	root := fakeHost(t)
+	defer os.RemoveAll(root)
+	require.NoError(t, os.MkdirAll(path.Join(cgroupRoot, "system.slice", "docker-abc.scope"), 0755))
+	require.NoError(t, ioutil.WriteFile(path.Join(cgroupRoot, "cgroup.controllers"), []byte("io"), 0644))
+	require.True(t, Unified())
+
+	cgroups, err := ThrottleMount("/mnt/vol1", Limits{ReadIops: 100, WriteBps: 1 << 20})
+	require.NoError(t, err)
+	sort.Strings(cgroups)
+	require.Equal(t, []string{"/system.slice/docker-abc.scope"}, cgroups)
+	require.Equal(t, "8:0 rbps=max wbps=1048576 riops=100 wiops=max",
+		readRule(t, "system.slice/docker-abc.scope/io.max"))
+}
+
+func TestDiskOf(t *testing.T) {
+	root := fakeHost(t)
+	defer os.RemoveAll(root)
+
+	dev, err := diskOf(&mount{dev: Device{259, 0}, source: "/dev/nvme0n1"})
+	require.NoError(t, err)
+	require.Equal(t, Device{259, 0}, dev)
+	_, err = diskOf(&mount{dev: Device{0, 45}, source: "server:/export"})
+	require.Equal(t, ErrNotBlock, err)
+}
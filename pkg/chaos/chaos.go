@@ -4,10 +4,23 @@ import (
 	"errors"
 	"fmt"
 	"math/rand"
-	"sync/atomic"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
+const (
+	// APIVersion for the chaos APIs
+	APIVersion = "v1"
+	// APIBase url for the chaos APIs
+	APIBase = "/var/lib/osd/chaos/"
+	// DefaultRate is the rate at which Random chaos points trigger.
+	DefaultRate = 0.1
+	// DefaultDelay is how long Delay actions sleep.
+	DefaultDelay = 5 * time.Second
+)
+
 // When defines when a chaos point is triggered
 type When int
 
@@ -16,6 +29,8 @@ const (
 	Once When = 1 << iota
 	// Random chaos at a random number of calls to ChaosNow.
 	Random
+	// Always chaos at every call to ChaosNow.
+	Always
 )
 
 // Action to take when a chaos point is triggered.
@@ -26,11 +41,105 @@ const (
 	Crash Action = 1 << iota
 	// Error out at calll to Now();
 	Error
+	// Delay the call to Now().
+	Delay
+	// Hang at a call to Now() until the chaos point is disabled.
+	Hang
+)
+
+var (
+	whens   = map[When]string{Once: "once", Random: "random", Always: "always"}
+	actions = map[Action]string{Crash: "crash", Error: "error", Delay: "delay", Hang: "hang"}
 )
 
+func (w When) String() string {
+	if s, ok := whens[w]; ok {
+		return s
+	}
+	return fmt.Sprintf("When(%d)", int(w))
+}
+
+func (a Action) String() string {
+	if s, ok := actions[a]; ok {
+		return s
+	}
+	return fmt.Sprintf("Action(%d)", int(a))
+}
+
+// MarshalText encodes w by name, and the When of disabled chaos points as "".
+func (w When) MarshalText() ([]byte, error) {
+	if w == 0 {
+		return nil, nil
+	}
+	return []byte(w.String()), nil
+}
+
+// UnmarshalText decodes w from its name.
+func (w *When) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*w = 0
+		return nil
+	}
+	parsed, err := ParseWhen(string(text))
+	*w = parsed
+	return err
+}
+
+// MarshalText encodes a by name, and the Action of disabled chaos points as
+// "".
+func (a Action) MarshalText() ([]byte, error) {
+	if a == 0 {
+		return nil, nil
+	}
+	return []byte(a.String()), nil
+}
+
+// UnmarshalText decodes a from its name.
+func (a *Action) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*a = 0
+		return nil
+	}
+	parsed, err := ParseAction(string(text))
+	*a = parsed
+	return err
+}
+
+// ParseWhen returns the When named s.
+func ParseWhen(s string) (When, error) {
+	for w, name := range whens {
+		if strings.EqualFold(s, name) {
+			return w, nil
+		}
+	}
+	return 0, fmt.Errorf("Unknown chaos when %q", s)
+}
+
+// ParseAction returns the Action named s.
+func ParseAction(s string) (Action, error) {
+	for a, name := range actions {
+		if strings.EqualFold(s, name) {
+			return a, nil
+		}
+	}
+	return 0, fmt.Errorf("Unknown chaos action %q", s)
+}
+
 // ID identifies a chaos point.
 type ID uint32
 
+func (id ID) String() string {
+	return strconv.FormatUint(uint64(id), 10)
+}
+
+// Options tune the actions of a chaos point.
+type Options struct {
+	// Delay is how long Delay actions sleep.
+	Delay time.Duration
+	// Rate is the probability in [0, 1] that a Random chaos point triggers.
+	Rate float64
+}
+
 // Chaos represents an instance of a chaos point
 type Chaos struct {
 	ID      ID
@@ -41,12 +150,16 @@ type Chaos struct {
 	What    Action
 	When    When
 	Count   int
+	Options
+	// release is closed to release the calls that hang at the chaos point.
+	release chan struct{}
 }
 
 var (
+	lock      sync.Mutex
 	activated bool
 	chaos     map[ID]*Chaos
-	count     int32
+	count     ID
 	r         *rand.Rand
 	// ErrNoEnt is generated on an unknown chaos ID.
 	ErrNoEnt = errors.New("ID does not exist")
@@ -56,23 +169,36 @@ var (
 
 // Activate activates chaos points in the system.
 func Activate(activate bool) {
+	lock.Lock()
+	defer lock.Unlock()
 	activated = activate
 }
 
+// Activated returns true if chaos points are activated.
+func Activated() bool {
+	lock.Lock()
+	defer lock.Unlock()
+	return activated
+}
+
 // Add new chaos point. The ID returned can be used to perform operations on this Chaos Point.
 func Add(pkg string, fn string, desc string) ID {
-	id := ID(atomic.AddInt32(&count, 1))
-	chaos[id] = &Chaos{ID: id, Pkg: pkg, Fn: fn, Desc: desc, Enabled: false}
-	return id
+	lock.Lock()
+	defer lock.Unlock()
+	count++
+	chaos[count] = &Chaos{ID: count, Pkg: pkg, Fn: fn, Desc: desc, Enabled: false}
+	return count
 }
 
 // Enumerate all chaos points in the system for specified package.
 // If the pkg is "" enumerate all chaos points.
 func Enumerate(pkg string) []Chaos {
+	lock.Lock()
+	defer lock.Unlock()
 
 	ko := make([]Chaos, 0, 10)
-	for _, v := range chaos {
-		if pkg == "" || pkg == v.Pkg {
+	for id := ID(1); id <= count; id++ {
+		if v := chaos[id]; pkg == "" || pkg == v.Pkg {
 			ko = append(ko, *v)
 		}
 	}
@@ -81,41 +207,95 @@ func Enumerate(pkg string) []Chaos {
 
 // Enable chaos point identified by ID.
 func Enable(id ID, when When, what Action) error {
-	if v, ok := chaos[id]; !ok {
+	return EnableWith(id, when, what, Options{Delay: DefaultDelay, Rate: DefaultRate})
+}
+
+// EnableWith enables the chaos point identified by ID with opts.
+func EnableWith(id ID, when When, what Action, opts Options) error {
+	if _, ok := whens[when]; !ok {
+		return fmt.Errorf("Unknown chaos when %v", when)
+	}
+	if _, ok := actions[what]; !ok {
+		return fmt.Errorf("Unknown chaos action %v", what)
+	}
+	if opts.Rate < 0 || opts.Rate > 1 {
+		return fmt.Errorf("Chaos rate %v is not in [0, 1]", opts.Rate)
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	v, ok := chaos[id]
+	if !ok {
 		return ErrNoEnt
-	} else {
-		v.Enabled = true
-		v.When = when
-		v.What = what
 	}
+	if v.release == nil {
+		v.release = make(chan struct{})
+	}
+	v.Enabled = true
+	v.When = when
+	v.What = what
+	v.Count = 0
+	v.Options = opts
 	return nil
 }
 
-// Disable chaos point identified by ID.
+// Disable chaos point identified by ID. Calls that hang at the chaos point
+// return.
 func Disable(id ID) error {
-	if v, ok := chaos[id]; !ok {
+	lock.Lock()
+	defer lock.Unlock()
+	v, ok := chaos[id]
+	if !ok {
 		return ErrNoEnt
-	} else {
-		v.Enabled = false
+	}
+	v.Enabled = false
+	if v.release != nil {
+		close(v.release)
+		v.release = nil
 	}
 	return nil
 }
 
+// trigger returns the chaos point id if it triggers at this call.
+func trigger(id ID) (*Chaos, bool) {
+	lock.Lock()
+	defer lock.Unlock()
+	if !activated {
+		return nil, false
+	}
+	v, ok := chaos[id]
+	if !ok || !v.Enabled {
+		return nil, false
+	}
+	v.Count++
+	switch v.When {
+	case Once:
+		if v.Count != 1 {
+			return nil, false
+		}
+	case Random:
+		if r.Float64() >= v.Rate {
+			return nil, false
+		}
+	}
+	c := *v
+	return &c, true
+}
+
 // Now will trigger chaos point if it is enabled.
 func Now(id ID) error {
-	if !activated {
+	v, ok := trigger(id)
+	if !ok {
 		return nil
 	}
-	if v, ok := chaos[id]; ok && v.Enabled {
-		v.Count++
-		if v.When == Once ||
-			(v.Count%(r.Int()%10)) == 0 {
-			if v.What == Error {
-				return ErrChaos
-			}
-		} else {
-			panic(fmt.Sprintf("Chaos triggered panic"))
-		}
+	switch v.What {
+	case Error:
+		return ErrChaos
+	case Delay:
+		time.Sleep(v.Delay)
+	case Hang:
+		<-v.release
+	case Crash:
+		panic(fmt.Sprintf("Chaos triggered panic at %v %v: %v", v.Pkg, v.Fn, v.Desc))
 	}
 	return nil
 }
@@ -124,5 +304,4 @@ func init() {
 	r = rand.New(rand.NewSource(time.Now().UnixNano()))
 	activated = false
 	chaos = make(map[ID]*Chaos)
-	count = -1
 }
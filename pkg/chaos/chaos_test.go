@@ -0,0 +1,91 @@
+package chaos
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNow(t *testing.T) {
+	id := Add("test", "now", "error once")
+	require.NoError(t, Enable(id, Once, Error))
+	require.NoError(t, Now(id), "Chaos points should not trigger before activation")
+
+	Activate(true)
+	defer Activate(false)
+	require.NoError(t, Enable(id, Once, Error))
+	require.Equal(t, ErrChaos, Now(id))
+	require.NoError(t, Now(id), "Once chaos points should trigger once")
+	require.NoError(t, Enable(id, Always, Error))
+	require.Equal(t, ErrChaos, Now(id))
+	require.Equal(t, ErrChaos, Now(id))
+	require.NoError(t, Disable(id))
+	require.NoError(t, Now(id))
+
+	require.NoError(t, EnableWith(id, Random, Error, Options{Rate: 0}))
+	require.NoError(t, Now(id), "Random chaos points with no rate should not trigger")
+	require.NoError(t, EnableWith(id, Random, Error, Options{Rate: 1}))
+	require.Equal(t, ErrChaos, Now(id))
+	require.Error(t, EnableWith(id, Random, Error, Options{Rate: 2}))
+	require.NoError(t, Disable(id))
+
+	require.NoError(t, Enable(id, Always, Crash))
+	require.Panics(t, func() { Now(id) })
+	require.NoError(t, Disable(id))
+
+	require.Equal(t, ErrNoEnt, Enable(ID(1<<31), Once, Error))
+	require.Equal(t, ErrNoEnt, Disable(ID(1<<31)))
+}
+
+func TestDelayHang(t *testing.T) {
+	id := Add("test", "delay", "delay and hang")
+	Activate(true)
+	defer Activate(false)
+
+	require.NoError(t, EnableWith(id, Always, Delay, Options{Delay: 20 * time.Millisecond}))
+	start := time.Now()
+	require.NoError(t, Now(id))
+	require.True(t, time.Since(start) >= 20*time.Millisecond)
+
+	require.NoError(t, Enable(id, Always, Hang))
+	done := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() { done <- Now(id) }()
+	}
+	select {
+	case <-done:
+		t.Fatal("Hang chaos points should block until disabled")
+	case <-time.After(20 * time.Millisecond):
+	}
+	require.NoError(t, Disable(id))
+	for i := 0; i < 2; i++ {
+		require.NoError(t, <-done)
+	}
+}
+
+func TestEnumerate(t *testing.T) {
+	id := Add("enumerate", "fn", "enumerated")
+	ko := Enumerate("enumerate")
+	require.Len(t, ko, 1)
+	require.Equal(t, id, ko[0].ID)
+	require.False(t, ko[0].Enabled)
+	require.True(t, len(Enumerate("")) >= 1)
+
+	require.NoError(t, Enable(id, Random, Delay))
+	data, err := json.Marshal(Enumerate("enumerate")[0])
+	require.NoError(t, err)
+	c := Chaos{}
+	require.NoError(t, json.Unmarshal(data, &c))
+	require.Equal(t, Random, c.When)
+	require.Equal(t, Delay, c.What)
+	require.Equal(t, DefaultRate, c.Rate)
+	require.NoError(t, Disable(id))
+
+	when, err := ParseWhen("Always")
+	require.NoError(t, err)
+	require.Equal(t, Always, when)
+	_, err = ParseAction("explode")
+	require.Error(t, err)
+}
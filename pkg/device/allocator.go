@@ -0,0 +1,241 @@
+package device
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"sync"
+
+	"github.com/portworx/kvdb"
+)
+
+const (
+	// allocatorKey is the kvdb prefix of the devices assigned on each node.
+	allocatorKey = "devices/"
+)
+
+// sysBlock is the directory of the block devices of this host.
+var sysBlock = "/sys/block"
+
+// Scheme is the list of device names an Allocator assigns, in the order
+// they are assigned.
+type Scheme []string
+
+// Letters names devices with a prefix and a suffix of letters from first to
+// last. Suffixes count a to z and then aa to zz, as in /dev/xvdf or
+// /dev/xvdba.
+func Letters(prefix, first, last string) Scheme {
+	from, ok := letterIndex(first)
+	if !ok {
+		return nil
+	}
+	to, ok := letterIndex(last)
+	if !ok {
+		return nil
+	}
+	var s Scheme
+	for i := from; i <= to; i++ {
+		s = append(s, prefix+letters(i))
+	}
+	return s
+}
+
+// Numbers names count devices with a number between a prefix and a suffix,
+// starting at first, as in /dev/nbd0.
+func Numbers(prefix, suffix string, first, count int) Scheme {
+	var s Scheme
+	for i := first; i < first+count; i++ {
+		s = append(s, fmt.Sprintf("%s%d%s", prefix, i, suffix))
+	}
+	return s
+}
+
+// NVMe names the first namespace of count NVMe controllers starting at
+// first, as in /dev/nvme1n1.
+func NVMe(first, count int) Scheme {
+	return Numbers("/dev/nvme", "n1", first, count)
+}
+
+// Chain assigns the devices of each scheme in turn.
+func Chain(schemes ...Scheme) Scheme {
+	var s Scheme
+	for _, scheme := range schemes {
+		s = append(s, scheme...)
+	}
+	return s
+}
+
+// letters returns the i-th suffix of letters, counting a to z and then aa.
+func letters(i int) string {
+	s := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		s = string('a'+byte((i-1)%26)) + s
+	}
+	return s
+}
+
+// letterIndex is the inverse of letters.
+func letterIndex(s string) (int, bool) {
+	if len(s) == 0 {
+		return 0, false
+	}
+	i := 0
+	for _, c := range s {
+		if c < 'a' || c > 'z' {
+			return 0, false
+		}
+		i = i*26 + int(c-'a') + 1
+	}
+	return i - 1, true
+}
+
+// InUse returns true if the device is in use on this host, by an Allocator
+// or otherwise.
+type InUse func(dev string) bool
+
+// Present returns true if the block device exists in sysfs, which is the
+// case for attached cloud disks.
+func Present(dev string) bool {
+	_, err := os.Stat(path.Join(sysBlock, path.Base(dev)))
+	return err == nil
+}
+
+// Allocator assigns the devices of a Scheme and persists its assignments
+// in kvdb per node, so that devices still in use are not assigned again
+// after a restart.
+type Allocator struct {
+	sync.Mutex
+	name     string
+	key      string
+	kv       kvdb.Kvdb
+	scheme   Scheme
+	names    map[string]bool
+	inUse    InUse
+	assigned map[string]bool
+}
+
+// NewAllocator instance of Allocator for the devices of scheme on node. The
+// assignments persisted by a previous instance are reconciled with the
+// host: devices that inUse reports unused are released. Assign skips the
+// devices that inUse reports used.
+func NewAllocator(
+	kv kvdb.Kvdb,
+	node string,
+	name string,
+	scheme Scheme,
+	inUse InUse,
+) (*Allocator, error) {
+	if len(scheme) == 0 {
+		return nil, ErrEinval
+	}
+	a := &Allocator{
+		name:     name,
+		key:      allocatorKey + node + "/" + name,
+		kv:       kv,
+		scheme:   scheme,
+		names:    make(map[string]bool),
+		inUse:    inUse,
+		assigned: make(map[string]bool),
+	}
+	for _, dev := range scheme {
+		a.names[dev] = true
+	}
+	var devices []string
+	if _, err := kv.GetVal(a.key, &devices); err != nil && err != kvdb.ErrNotFound {
+		return nil, err
+	}
+	for _, dev := range devices {
+		if a.names[dev] && a.inUse(dev) {
+			a.assigned[dev] = true
+		}
+	}
+	if len(a.assigned) != len(devices) {
+		if err := a.persist(); err != nil {
+			return nil, err
+		}
+	}
+	return a, nil
+}
+
+// String is a description of this device.
+func (a *Allocator) String() string {
+	return a.name
+}
+
+// Assign the first device of the scheme that is neither assigned nor in
+// use.
+func (a *Allocator) Assign() (string, error) {
+	a.Lock()
+	defer a.Unlock()
+	for _, dev := range a.scheme {
+		if a.assigned[dev] || a.inUse(dev) {
+			continue
+		}
+		a.assigned[dev] = true
+		if err := a.persist(); err != nil {
+			delete(a.assigned, dev)
+			return "", err
+		}
+		return dev, nil
+	}
+	return "", ErrEnospc
+}
+
+// Reserve assigns a device that is known to be in use, so that it is never
+// assigned until it is released.
+func (a *Allocator) Reserve(dev string) error {
+	a.Lock()
+	defer a.Unlock()
+	if !a.names[dev] {
+		return ErrEinval
+	}
+	if a.assigned[dev] {
+		return nil
+	}
+	a.assigned[dev] = true
+	if err := a.persist(); err != nil {
+		delete(a.assigned, dev)
+		return err
+	}
+	return nil
+}
+
+// Release device to devices pool.
+func (a *Allocator) Release(dev string) error {
+	a.Lock()
+	defer a.Unlock()
+	if !a.names[dev] {
+		return ErrEinval
+	}
+	if !a.assigned[dev] {
+		return nil
+	}
+	delete(a.assigned, dev)
+	if err := a.persist(); err != nil {
+		a.assigned[dev] = true
+		return err
+	}
+	return nil
+}
+
+// Assigned returns the assigned devices sorted by name.
+func (a *Allocator) Assigned() []string {
+	a.Lock()
+	defer a.Unlock()
+	return a.list()
+}
+
+func (a *Allocator) list() []string {
+	devices := make([]string, 0, len(a.assigned))
+	for dev := range a.assigned {
+		devices = append(devices, dev)
+	}
+	sort.Strings(devices)
+	return devices
+}
+
+func (a *Allocator) persist() error {
+	_, err := a.kv.Put(a.key, a.list(), 0)
+	return err
+}
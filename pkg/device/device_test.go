@@ -0,0 +1,53 @@
+package device
+
+import (
+	"testing"
+
+	"github.com/portworx/kvdb"
+	"github.com/portworx/kvdb/mem"
+	"github.com/stretchr/testify/require"
+	"go.pedge.io/dlog"
+)
+
+func TestSchemes(t *testing.T) {
+	require.Equal(t, Scheme{"/dev/xvdy", "/dev/xvdz", "/dev/xvdaa", "/dev/xvdab"},
+		Letters("/dev/xvd", "y", "ab"))
+	require.Len(t, Letters("/dev/xvd", "ba", "cz"), 52)
+	require.Len(t, Letters("/dev/xvd", "f", "F"), 0)
+	require.Equal(t, Scheme{"/dev/nvme1n1", "/dev/nvme2n1"}, NVMe(1, 2))
+	require.Equal(t, Scheme{"/dev/sdf", "/dev/nbd0"},
+		Chain(Letters("/dev/sd", "f", "f"), Numbers("/dev/nbd", "", 0, 1)))
+}
+
+func TestAllocator(t *testing.T) {
+	kv, err := kvdb.New(mem.Name, "device_test", []string{}, nil, dlog.Panicf)
+	require.NoError(t, err)
+	used := map[string]bool{"/dev/xvdf": true}
+	inUse := func(dev string) bool { return used[dev] }
+	scheme := Letters("/dev/xvd", "f", "h")
+
+	a, err := NewAllocator(kv, "node", "test", scheme, inUse)
+	require.NoError(t, err)
+	dev, err := a.Assign()
+	require.NoError(t, err)
+	require.Equal(t, "/dev/xvdg", dev, "Devices in use should be skipped")
+	dev, err = a.Assign()
+	require.NoError(t, err)
+	require.Equal(t, "/dev/xvdh", dev)
+	_, err = a.Assign()
+	require.Equal(t, ErrEnospc, err)
+	require.Equal(t, ErrEinval, a.Release("/dev/sdf"))
+
+	// Assignments survive a restart while their devices are in use.
+	used["/dev/xvdg"] = true
+	a, err = NewAllocator(kv, "node", "test", scheme, inUse)
+	require.NoError(t, err)
+	require.Equal(t, []string{"/dev/xvdg"}, a.Assigned())
+	require.NoError(t, a.Release("/dev/xvdg"))
+	require.NoError(t, a.Reserve("/dev/xvdh"))
+	require.Equal(t, []string{"/dev/xvdh"}, a.Assigned())
+
+	other, err := NewAllocator(kv, "other", "test", scheme, inUse)
+	require.NoError(t, err)
+	require.Len(t, other.Assigned(), 0, "Assignments should be per node")
+}
@@ -0,0 +1,214 @@
+package disk
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"github.com/libopenstorage/openstorage/api"
+)
+
+const (
+	// alignment of the buffers and offsets of direct I/O.
+	alignment = 4096
+	// highIops is the random IOPS from which disks are of HIGH Cos.
+	highIops = 10000
+	// mediumIops is the random IOPS from which disks are of MEDIUM Cos.
+	mediumIops = 1000
+)
+
+// BenchOptions tune a benchmark.
+type BenchOptions struct {
+	// Region is the size of the region at the end of the disk that is read,
+	// or of the scratch file of a directory.
+	Region int64
+	// SeqBlockSize is the block size of the sequential tests.
+	SeqBlockSize int
+	// RandBlockSize is the block size of the random test.
+	RandBlockSize int
+	// RandOps is the number of reads, and writes of scratch files, of the
+	// random test.
+	RandOps int
+}
+
+// DefaultBenchOptions run a benchmark in a few seconds on magnetic disks.
+var DefaultBenchOptions = BenchOptions{
+	Region:        64 << 20,
+	SeqBlockSize:  1 << 20,
+	RandBlockSize: 4 << 10,
+	RandOps:       500,
+}
+
+// Result of a benchmark. Throughputs are in bytes per second.
+type Result struct {
+	// Iops of the random test.
+	Iops uint64
+	// SeqRead throughput.
+	SeqRead float64
+	// SeqWrite throughput.
+	SeqWrite float64
+	// RandRW throughput of the random test, of reads only on disks.
+	RandRW float64
+	// Time the benchmark ran.
+	Time time.Time
+}
+
+// Apply records the result in the properties of disk.
+func (r *Result) Apply(disk *api.StorageResource) {
+	disk.Iops = r.Iops
+	disk.SeqRead = r.SeqRead
+	disk.SeqWrite = r.SeqWrite
+	disk.RandRW = r.RandRW
+}
+
+// Benchmark measures the sequential and random performance of the disk or
+// file at path with direct I/O. Disks and files are only read, in a region
+// at their end, so that the data they hold is never at risk; their write
+// throughputs are not measured. If path is a directory, such as the mount
+// point of a pool, a scratch file of the size of the region is created in it
+// to measure the writes too, and removed at the end.
+func Benchmark(path string, opts BenchOptions) (*Result, error) {
+	if opts.SeqBlockSize%alignment != 0 || opts.RandBlockSize%alignment != 0 ||
+		opts.SeqBlockSize == 0 || opts.RandBlockSize == 0 {
+		return nil, fmt.Errorf("Block sizes must be multiples of %v", alignment)
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if fi.IsDir() {
+		return benchmarkDir(path, opts)
+	}
+	f, err := openDirect(path, os.O_RDONLY)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	size, err := f.Seek(0, os.SEEK_END)
+	if err != nil {
+		return nil, err
+	}
+	return benchmark(f, size, opts, false)
+}
+
+// benchmarkDir measures the performance of the filesystem of dir on a
+// scratch file.
+func benchmarkDir(dir string, opts BenchOptions) (*Result, error) {
+	f, err := ioutil.TempFile(dir, ".benchmark")
+	if err != nil {
+		return nil, err
+	}
+	name := f.Name()
+	defer os.Remove(name)
+	f.Close()
+	if f, err = openDirect(name, os.O_RDWR); err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return benchmark(f, opts.Region, opts, true)
+}
+
+// openDirect opens path for direct I/O, or for synchronous I/O on
+// filesystems such as tmpfs that do not support direct I/O.
+func openDirect(path string, flag int) (*os.File, error) {
+	f, err := os.OpenFile(path, flag|syscall.O_DIRECT, 0)
+	if pe, ok := err.(*os.PathError); ok && pe.Err == syscall.EINVAL {
+		f, err = os.OpenFile(path, flag|os.O_SYNC, 0)
+	}
+	return f, err
+}
+
+// benchmark runs the tests in the region at the end of the size bytes of f.
+// The region is written first if write is true, otherwise f is only read.
+func benchmark(f *os.File, size int64, opts BenchOptions, write bool) (*Result, error) {
+	region := opts.Region
+	if region > size {
+		region = size
+	}
+	region -= region % int64(opts.SeqBlockSize)
+	if region == 0 {
+		return nil, fmt.Errorf("%v is smaller than a block of %v",
+			f.Name(), opts.SeqBlockSize)
+	}
+	start := (size - region) &^ (alignment - 1)
+	result := &Result{Time: time.Now()}
+
+	buf := alignedBuffer(opts.SeqBlockSize)
+	if write {
+		t := time.Now()
+		for off := start; off < start+region; off += int64(len(buf)) {
+			if _, err := f.WriteAt(buf, off); err != nil {
+				return nil, err
+			}
+		}
+		result.SeqWrite = throughput(region, time.Since(t))
+	}
+	t := time.Now()
+	for off := start; off < start+region; off += int64(len(buf)) {
+		if _, err := f.ReadAt(buf, off); err != nil {
+			return nil, err
+		}
+	}
+	result.SeqRead = throughput(region, time.Since(t))
+
+	buf = buf[:opts.RandBlockSize]
+	blocks := region / int64(opts.RandBlockSize)
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	ops := int64(opts.RandOps)
+	t = time.Now()
+	for i := 0; i < opts.RandOps; i++ {
+		off := start + r.Int63n(blocks)*int64(opts.RandBlockSize)
+		if _, err := f.ReadAt(buf, off); err != nil {
+			return nil, err
+		}
+		if write {
+			if _, err := f.WriteAt(buf, off); err != nil {
+				return nil, err
+			}
+		}
+	}
+	elapsed := time.Since(t)
+	if write {
+		ops *= 2
+	}
+	result.Iops = uint64(throughput(ops, elapsed))
+	result.RandRW = throughput(ops*int64(opts.RandBlockSize), elapsed)
+	return result, nil
+}
+
+// throughput returns n per second of d.
+func throughput(n int64, d time.Duration) float64 {
+	if d <= 0 {
+		d = time.Nanosecond
+	}
+	return float64(n) / d.Seconds()
+}
+
+// alignedBuffer returns a buffer of size bytes aligned for direct I/O.
+func alignedBuffer(size int) []byte {
+	buf := make([]byte, size+alignment)
+	off := int(uintptr(unsafe.Pointer(&buf[0])) & (alignment - 1))
+	if off != 0 {
+		off = alignment - off
+	}
+	return buf[off : off+size]
+}
+
+// CosOf returns the class of service of a disk from its random IOPS, or
+// from its medium until it is benchmarked.
+func CosOf(disk api.StorageResource) api.CosType {
+	switch {
+	case disk.Iops == 0:
+		return Cos(disk.Medium)
+	case disk.Iops >= highIops:
+		return api.CosType_HIGH
+	case disk.Iops >= mediumIops:
+		return api.CosType_MEDIUM
+	default:
+		return api.CosType_LOW
+	}
+}
@@ -0,0 +1,240 @@
+// Package disk discovers the local disks used by osd and groups them into
+// storage pools.
+package disk
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"go.pedge.io/proto/time"
+
+	"github.com/libopenstorage/openstorage/api"
+)
+
+const (
+	// Rotational is the rotation speed of magnetic disks, whose speed in
+	// RPM is not reported by the kernel.
+	Rotational = "rotational"
+	// NonRotational is the rotation speed of solid state disks.
+	NonRotational = "non-rotational"
+)
+
+var (
+	// sysBlock is the directory of the block devices of this host.
+	sysBlock = "/sys/block"
+	// sysDevBlock links the numbers of the block devices to their directory.
+	sysDevBlock = "/sys/dev/block"
+	// procPartitions lists the sizes of the disks and partitions.
+	procPartitions = "/proc/partitions"
+)
+
+// Scan returns the disks of this host that are in devices, by device path.
+// Other disks were not configured for osd and are not reported.
+func Scan(devices []string) (map[string]api.StorageResource, error) {
+	configured := make(map[string]bool)
+	for _, dev := range devices {
+		if resolved, err := filepath.EvalSymlinks(dev); err == nil {
+			dev = resolved
+		}
+		configured[path.Base(dev)] = true
+	}
+	sizes, err := partitions()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := ioutil.ReadDir(sysBlock)
+	if err != nil {
+		return nil, err
+	}
+	disks := make(map[string]api.StorageResource)
+	for _, e := range entries {
+		name := e.Name()
+		if !configured[name] {
+			continue
+		}
+		disk := scan(name, sizes)
+		disks[disk.Path] = disk
+	}
+	return disks, nil
+}
+
+// scan returns the properties of the disk name.
+func scan(name string, sizes map[string]uint64) api.StorageResource {
+	dir := path.Join(sysBlock, name)
+	disk := api.StorageResource{
+		Id:       name,
+		Path:     "/dev/" + name,
+		Online:   true,
+		LastScan: prototime.Now(),
+	}
+	if wwid := readString(path.Join(dir, "wwid")); len(wwid) != 0 {
+		disk.Id = wwid
+	} else if wwid := readString(path.Join(dir, "device", "wwid")); len(wwid) != 0 {
+		disk.Id = wwid
+	}
+	if state := readString(path.Join(dir, "device", "state")); len(state) != 0 {
+		disk.Online = state == "running" || state == "live"
+	}
+	rotational := readString(path.Join(dir, "queue", "rotational")) == "1"
+	disk.Medium = medium(name, rotational)
+	disk.RotationSpeed = NonRotational
+	if rotational {
+		disk.RotationSpeed = Rotational
+	}
+	if size, ok := sizes[name]; ok {
+		disk.Size = size
+	} else if sectors, err := strconv.ParseUint(readString(path.Join(dir, "size")), 10, 64); err == nil {
+		disk.Size = sectors * 512
+	}
+	// The space of the partitions of the disk is used.
+	for part, size := range sizes {
+		if part == name {
+			continue
+		}
+		if _, err := os.Stat(path.Join(dir, part)); err == nil {
+			disk.Used += size
+		}
+	}
+	return disk
+}
+
+// medium classifies a disk by its name and rotation.
+func medium(name string, rotational bool) api.StorageMedium {
+	switch {
+	case strings.HasPrefix(name, "nvme"):
+		return api.StorageMedium_STORAGE_MEDIUM_NVME
+	case rotational:
+		return api.StorageMedium_STORAGE_MEDIUM_MAGNETIC
+	default:
+		return api.StorageMedium_STORAGE_MEDIUM_SSD
+	}
+}
+
+// MediumOf returns the medium of the disk that a file is on. It is magnetic
+// unless the kernel reports the disk as non rotational.
+func MediumOf(file string) api.StorageMedium {
+	var st syscall.Stat_t
+	if err := syscall.Stat(file, &st); err != nil {
+		return api.StorageMedium_STORAGE_MEDIUM_MAGNETIC
+	}
+	dev := uint64(st.Dev)
+	major := (dev>>8)&0xfff | (dev>>32)&^0xfff
+	minor := dev&0xff | (dev>>12)&^0xff
+	dir, err := filepath.EvalSymlinks(path.Join(sysDevBlock, fmt.Sprintf("%d:%d", major, minor)))
+	if err != nil {
+		return api.StorageMedium_STORAGE_MEDIUM_MAGNETIC
+	}
+	return diskMedium(path.Base(dir))
+}
+
+// diskMedium returns the medium of the disk name, or of the disk of the
+// partition name.
+func diskMedium(name string) api.StorageMedium {
+	dir := path.Join(sysBlock, name)
+	if _, err := os.Stat(dir); err != nil {
+		// The partitions of a disk are in its directory.
+		matches, _ := filepath.Glob(path.Join(sysBlock, "*", name))
+		if len(matches) == 0 {
+			return api.StorageMedium_STORAGE_MEDIUM_MAGNETIC
+		}
+		dir = path.Dir(matches[0])
+	}
+	rotational := readString(path.Join(dir, "queue", "rotational")) != "0"
+	return medium(path.Base(dir), rotational)
+}
+
+// partitions returns the sizes in bytes of the disks and partitions listed
+// in /proc/partitions by name.
+func partitions() (map[string]uint64, error) {
+	f, err := os.Open(procPartitions)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	sizes := make(map[string]uint64)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// major minor #blocks name
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 4 {
+			continue
+		}
+		blocks, err := strconv.ParseUint(fields[2], 10, 64)
+		if err != nil {
+			continue
+		}
+		sizes[fields[3]] = blocks * 1024
+	}
+	return sizes, scanner.Err()
+}
+
+func readString(file string) string {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// Cos returns the class of service of the disks of a medium.
+func Cos(medium api.StorageMedium) api.CosType {
+	switch medium {
+	case api.StorageMedium_STORAGE_MEDIUM_NVME:
+		return api.CosType_HIGH
+	case api.StorageMedium_STORAGE_MEDIUM_SSD:
+		return api.CosType_MEDIUM
+	default:
+		return api.CosType_LOW
+	}
+}
+
+// Pools groups disks into a storage pool per medium and Cos. The pools are
+// ordered by medium and Cos, and their IDs are their index.
+func Pools(disks map[string]api.StorageResource) []api.StoragePool {
+	type poolKey struct {
+		medium api.StorageMedium
+		cos    api.CosType
+	}
+	byKey := make(map[poolKey]*api.StoragePool)
+	for _, disk := range disks {
+		key := poolKey{disk.Medium, CosOf(disk)}
+		pool, ok := byKey[key]
+		if !ok {
+			pool = &api.StoragePool{
+				Cos:    key.cos,
+				Medium: key.medium,
+			}
+			byKey[key] = pool
+		}
+		pool.TotalSize += disk.Size
+		pool.Used += disk.Used
+	}
+	pools := make([]api.StoragePool, 0, len(byKey))
+	for _, pool := range byKey {
+		pools = append(pools, *pool)
+	}
+	sort.Sort(byMediumCos(pools))
+	for i := range pools {
+		pools[i].ID = int32(i)
+	}
+	return pools
+}
+
+type byMediumCos []api.StoragePool
+
+func (p byMediumCos) Len() int      { return len(p) }
+func (p byMediumCos) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
+func (p byMediumCos) Less(i, j int) bool {
+	if p[i].Medium != p[j].Medium {
+		return p[i].Medium < p[j].Medium
+	}
+	return p[i].Cos < p[j].Cos
+}
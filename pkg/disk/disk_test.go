@@ -0,0 +1,154 @@
+package disk
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSys creates a sysfs and /proc/partitions with an SSD with a
+// partition, an NVMe disk and a magnetic disk.
+func fakeSys(t *testing.T) string {
+	root, err := ioutil.TempDir("", "disk_test")
+	require.NoError(t, err)
+	sysBlock = path.Join(root, "block")
+	procPartitions = path.Join(root, "partitions")
+
+	write := func(file, data string) {
+		file = path.Join(sysBlock, file)
+		require.NoError(t, os.MkdirAll(path.Dir(file), 0755))
+		require.NoError(t, ioutil.WriteFile(file, []byte(data+"\n"), 0644))
+	}
+	write("sda/queue/rotational", "0")
+	write("sda/device/wwid", "naa.5000")
+	write("sda/device/state", "running")
+	require.NoError(t, os.MkdirAll(path.Join(sysBlock, "sda", "sda1"), 0755))
+	write("nvme0n1/queue/rotational", "0")
+	write("nvme0n1/wwid", "eui.0025")
+	write("sdb/queue/rotational", "1")
+	write("sdb/device/state", "offline")
+	write("sdb/size", "4096")
+	write("loop0/queue/rotational", "1")
+	require.NoError(t, ioutil.WriteFile(procPartitions, []byte(
+		"major minor  #blocks  name\n\n"+
+			"   8        0    1048576 sda\n"+
+			"   8        1     262144 sda1\n"+
+			" 259        0    2097152 nvme0n1\n"+
+			"   7        0      10240 loop0\n",
+	), 0644))
+	return root
+}
+
+func TestScan(t *testing.T) {
+	root := fakeSys(t)
+	defer os.RemoveAll(root)
+
+	disks, err := Scan([]string{"/dev/sda", "/dev/nvme0n1", "/dev/sdb"})
+	require.NoError(t, err)
+	require.Len(t, disks, 3, "Disks that were not configured should be excluded")
+
+	sda := disks["/dev/sda"]
+	require.Equal(t, "naa.5000", sda.Id)
+	require.Equal(t, api.StorageMedium_STORAGE_MEDIUM_SSD, sda.Medium)
+	require.Equal(t, NonRotational, sda.RotationSpeed)
+	require.True(t, sda.Online)
+	require.Equal(t, uint64(1<<30), sda.Size)
+	require.Equal(t, uint64(256<<20), sda.Used)
+	require.NotNil(t, sda.LastScan)
+
+	nvme := disks["/dev/nvme0n1"]
+	require.Equal(t, "eui.0025", nvme.Id)
+	require.Equal(t, api.StorageMedium_STORAGE_MEDIUM_NVME, nvme.Medium)
+
+	sdb := disks["/dev/sdb"]
+	require.Equal(t, "sdb", sdb.Id)
+	require.Equal(t, api.StorageMedium_STORAGE_MEDIUM_MAGNETIC, sdb.Medium)
+	require.Equal(t, Rotational, sdb.RotationSpeed)
+	require.False(t, sdb.Online)
+	require.Equal(t, uint64(4096*512), sdb.Size, "Size should fall back to sysfs")
+
+	pools := Pools(disks)
+	require.Len(t, pools, 3)
+	require.Equal(t, int32(0), pools[0].ID)
+	require.Equal(t, api.StorageMedium_STORAGE_MEDIUM_MAGNETIC, pools[0].Medium)
+	require.Equal(t, api.CosType_LOW, pools[0].Cos)
+	require.Equal(t, api.CosType_MEDIUM, pools[1].Cos)
+	require.Equal(t, uint64(1<<30), pools[1].TotalSize)
+	require.Equal(t, uint64(256<<20), pools[1].Used)
+	require.Equal(t, api.CosType_HIGH, pools[2].Cos)
+}
+
+func TestDiskMedium(t *testing.T) {
+	root := fakeSys(t)
+	defer os.RemoveAll(root)
+
+	require.Equal(t, api.StorageMedium_STORAGE_MEDIUM_SSD, diskMedium("sda1"))
+	require.Equal(t, api.StorageMedium_STORAGE_MEDIUM_NVME, diskMedium("nvme0n1"))
+	require.Equal(t, api.StorageMedium_STORAGE_MEDIUM_MAGNETIC, diskMedium("sdb"))
+	require.Equal(t, api.StorageMedium_STORAGE_MEDIUM_MAGNETIC, diskMedium("unknown"))
+}
+
+func TestBenchmark(t *testing.T) {
+	f, err := ioutil.TempFile("", "bench_test")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	data := make([]byte, 1<<20)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+	_, err = f.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	_, err = Benchmark(f.Name(), BenchOptions{SeqBlockSize: 1000, RandBlockSize: 4096})
+	require.Error(t, err, "Block sizes should be aligned")
+	r, err := Benchmark(f.Name(), BenchOptions{
+		Region:        512 << 10,
+		SeqBlockSize:  64 << 10,
+		RandBlockSize: 4 << 10,
+		RandOps:       50,
+	})
+	require.NoError(t, err)
+	require.True(t, r.SeqRead > 0)
+	require.Equal(t, float64(0), r.SeqWrite, "Disks should only be read")
+	require.True(t, r.Iops > 0)
+	require.True(t, r.RandRW > 0)
+	after, err := ioutil.ReadFile(f.Name())
+	require.NoError(t, err)
+	require.Equal(t, data, after, "Benchmarks should not modify disks")
+
+	dir, err := ioutil.TempDir("", "bench_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	r, err = Benchmark(dir, BenchOptions{
+		Region:        512 << 10,
+		SeqBlockSize:  64 << 10,
+		RandBlockSize: 4 << 10,
+		RandOps:       50,
+	})
+	require.NoError(t, err)
+	require.True(t, r.SeqRead > 0)
+	require.True(t, r.SeqWrite > 0)
+	require.True(t, r.Iops > 0)
+	entries, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	require.Empty(t, entries, "Scratch files should be removed")
+
+	disk := api.StorageResource{Medium: api.StorageMedium_STORAGE_MEDIUM_MAGNETIC}
+	require.Equal(t, api.CosType_LOW, CosOf(disk))
+	r.Iops = 50000
+	r.Apply(&disk)
+	require.Equal(t, api.CosType_HIGH, CosOf(disk))
+	disks := map[string]api.StorageResource{
+		"/dev/sda": disk,
+		"/dev/sdb": {Medium: api.StorageMedium_STORAGE_MEDIUM_MAGNETIC},
+	}
+	pools := Pools(disks)
+	require.Len(t, pools, 2, "Disks of a medium should be pooled by Cos")
+	require.Equal(t, api.CosType_LOW, pools[0].Cos)
+	require.Equal(t, api.CosType_HIGH, pools[1].Cos)
+}
@@ -0,0 +1,140 @@
+// +build linux
+
+package mount
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/docker/docker/pkg/mount"
+)
+
+// BindMounter implements Manager and keeps track of active bind mounts for
+// volume drivers. Sources are directories and the fs of a bind mount is "".
+type BindMounter struct {
+	Mounter
+}
+
+// NewBindMounter returns a new BindMounter that tracks bind mounts of sources
+// with one of srcPrefixes.
+func NewBindMounter(
+	srcPrefixes []string,
+	mountImpl MountImpl,
+	journal Journal,
+) (*BindMounter, error) {
+	m := &BindMounter{
+		Mounter: Mounter{
+			mountImpl:   &flagsMounter{mountImpl},
+			mounts:      make(DeviceMap),
+			paths:       make(PathMap),
+			mounterType: BindMount,
+			identifiers: srcPrefixes,
+			journal:     journal,
+		},
+	}
+	if err := m.Load(srcPrefixes); err != nil {
+		return nil, err
+	}
+	if err := m.reconcile(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Reload reloads the mount table for the specified source
+func (m *BindMounter) Reload(source string) error {
+	return ErrUnsupported
+}
+
+// Load mount table. The source of a bind mount is the mountpoint of the
+// filesystem it is in, joined with the root of the bind mount.
+func (m *BindMounter) Load(srcPrefixes []string) error {
+	info, err := mount.GetMounts()
+	if err != nil {
+		return err
+	}
+	fsRoots := make(map[string]string)
+	for _, v := range info {
+		dev := fmt.Sprintf("%d:%d", v.Major, v.Minor)
+		if _, ok := fsRoots[dev]; !ok && v.Root == "/" {
+			fsRoots[dev] = v.Mountpoint
+		}
+	}
+BindLoop:
+	for _, v := range info {
+		if v.Root == "/" {
+			continue
+		}
+		fsRoot, ok := fsRoots[fmt.Sprintf("%d:%d", v.Major, v.Minor)]
+		if !ok {
+			continue
+		}
+		source := normalizeMountPath(filepath.Join(fsRoot, v.Root))
+		if !hasPrefix(source, srcPrefixes) {
+			continue
+		}
+		mount, ok := m.mounts[source]
+		if !ok {
+			mount = &Info{
+				Device:     source,
+				Minor:      v.Minor,
+				Mountpoint: make([]*PathInfo, 0),
+			}
+			m.mounts[source] = mount
+		}
+		path := normalizeMountPath(v.Mountpoint)
+		// Allow Load to be called multiple times.
+		for _, p := range mount.Mountpoint {
+			if p.Path == path {
+				continue BindLoop
+			}
+		}
+		mount.Mountpoint = append(
+			mount.Mountpoint,
+			&PathInfo{
+				Path: path,
+				ref:  1,
+			},
+		)
+		m.paths[path] = source
+	}
+	return nil
+}
+
+// ParsePropagation returns the mount flags for a propagation type, one of
+// shared, slave, private or unbindable, optionally prefixed with r to apply
+// it recursively. An empty propagation returns no flags.
+func ParsePropagation(propagation string) (uintptr, error) {
+	var flags uintptr
+	p := propagation
+	if strings.HasPrefix(p, "r") && p != "r" {
+		flags |= syscall.MS_REC
+		p = p[1:]
+	}
+	switch p {
+	case "":
+		return 0, nil
+	case "shared":
+		flags |= syscall.MS_SHARED
+	case "slave":
+		flags |= syscall.MS_SLAVE
+	case "private":
+		flags |= syscall.MS_PRIVATE
+	case "unbindable":
+		flags |= syscall.MS_UNBINDABLE
+	default:
+		return 0, fmt.Errorf("Invalid mount propagation %q", propagation)
+	}
+	return flags, nil
+}
+
+func hasPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,115 @@
+// +build linux
+
+package mount
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/docker/docker/pkg/mount"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBindMount(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bind")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	src := filepath.Join(dir, "src")
+	dst := filepath.Join(dir, "dst")
+	require.NoError(t, os.MkdirAll(src, 0755))
+	require.NoError(t, os.MkdirAll(dst, 0755))
+
+	m, err := New(BindMount, nil, []string{dir}, nil)
+	require.NoError(t, err)
+	propagation, err := ParsePropagation("rshared")
+	require.NoError(t, err)
+	flags := syscall.MS_BIND | propagation
+	require.NoError(t, m.Mount(0, src, dst, "", flags, "", 0))
+	defer syscall.Unmount(dst, syscall.MNT_DETACH)
+	require.NoError(t, m.Mount(0, src, dst, "", flags, "", 0))
+	require.Equal(t, 2, m.Inspect(src)[0].Refcnt())
+
+	info, err := mountInfo(dst)
+	require.NoError(t, err)
+	require.True(t, strings.Contains(info.Optional, "shared:"),
+		"Expected %v to be shared, got %q", dst, info.Optional)
+
+	// A new mounter finds the bind mount in the kernel mount table.
+	loaded, err := New(BindMount, nil, []string{dir}, nil)
+	require.NoError(t, err)
+	exists, err := loaded.Exists(src, dst)
+	require.NoError(t, err)
+	require.True(t, exists, "Expected %v to be mounted at %v", src, dst)
+	source, ok := loaded.HasTarget(dst)
+	require.True(t, ok)
+	require.Equal(t, src, source)
+
+	require.NoError(t, m.Unmount(src, dst, 0))
+	_, err = mountInfo(dst)
+	require.NoError(t, err, "Expected %v to still be mounted", dst)
+	require.NoError(t, m.Unmount(src, dst, 0))
+	_, err = mountInfo(dst)
+	require.Error(t, err, "Expected %v to be unmounted", dst)
+	require.Equal(t, ErrEnoent, m.Unmount(src, dst, 0))
+}
+
+func TestOverlayMount(t *testing.T) {
+	dir, err := ioutil.TempDir("", "overlay")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	dirs := make(map[string]string)
+	for _, d := range []string{"lower", "upper", "work", "merged"} {
+		dirs[d] = filepath.Join(dir, d)
+		require.NoError(t, os.MkdirAll(dirs[d], 0755))
+	}
+	opts := fmt.Sprintf("lowerdir=%s,upperdir=%s,workdir=%s",
+		dirs["lower"], dirs["upper"], dirs["work"])
+
+	m, err := New(OverlayMount, nil, []string{dir}, nil)
+	require.NoError(t, err)
+	if err := m.Mount(0, dirs["upper"], dirs["merged"], OverlayFs, 0, opts, 0); err != nil {
+		t.Skipf("Overlay is not supported: %v", err)
+	}
+	defer syscall.Unmount(dirs["merged"], syscall.MNT_DETACH)
+	require.NoError(t, m.Mount(0, dirs["upper"], dirs["merged"], OverlayFs, 0, opts, 0))
+	require.Equal(t, 2, m.Inspect(dirs["upper"])[0].Refcnt())
+
+	loaded, err := New(OverlayMount, nil, []string{dir}, nil)
+	require.NoError(t, err)
+	source, ok := loaded.HasTarget(dirs["merged"])
+	require.True(t, ok)
+	require.Equal(t, dirs["upper"], source)
+
+	require.NoError(t, m.Unmount(dirs["upper"], dirs["merged"], 0))
+	require.NoError(t, m.Unmount(dirs["upper"], dirs["merged"], 0))
+	require.Equal(t, 0, m.HasMounts(dirs["upper"]))
+}
+
+func TestParsePropagation(t *testing.T) {
+	flags, err := ParsePropagation("rslave")
+	require.NoError(t, err)
+	require.Equal(t, uintptr(syscall.MS_REC|syscall.MS_SLAVE), flags)
+	flags, err = ParsePropagation("")
+	require.NoError(t, err)
+	require.Equal(t, uintptr(0), flags)
+	_, err = ParsePropagation("bogus")
+	require.Error(t, err)
+}
+
+func mountInfo(path string) (*mount.Info, error) {
+	info, err := mount.GetMounts()
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range info {
+		if v.Mountpoint == path {
+			return v, nil
+		}
+	}
+	return nil, ErrEnoent
+}
@@ -17,28 +17,36 @@ type DeviceMounter struct {
 func NewDeviceMounter(
 	devPrefixes []string,
 	mountImpl MountImpl,
+	journal Journal,
 ) (*DeviceMounter, error) {
 
 	m := &DeviceMounter{
 		Mounter: Mounter{
-			mountImpl: mountImpl,
-			mounts:    make(DeviceMap),
-			paths:     make(PathMap),
+			mountImpl:   &flagsMounter{mountImpl},
+			mounts:      make(DeviceMap),
+			paths:       make(PathMap),
+			mounterType: DeviceMount,
+			identifiers: devPrefixes,
+			journal:     journal,
 		},
 	}
 	err := m.Load(devPrefixes)
 	if err != nil {
 		return nil, err
 	}
+	if err := m.reconcile(); err != nil {
+		return nil, err
+	}
 	return m, nil
 }
 
 // Reload reloads the mount table
 func (m *DeviceMounter) Reload(device string) error {
-	newDm, err := NewDeviceMounter([]string{device}, m.mountImpl)
+	newDm, err := NewDeviceMounter([]string{device}, m.mountImpl, nil)
 	if err != nil {
 		return err
 	}
+	defer m.save()
 	m.Lock()
 	defer m.Unlock()
 
@@ -0,0 +1,211 @@
+// +build linux
+
+package mount
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"go.pedge.io/dlog"
+)
+
+// fileJournal saves the mount table as JSON in a file.
+type fileJournal struct {
+	path string
+}
+
+func newFileJournal(path string) *fileJournal {
+	return &fileJournal{path: path}
+}
+
+// Load returns the mount table saved in the journal file.
+func (j *fileJournal) Load() (*Table, error) {
+	b, err := ioutil.ReadFile(j.path)
+	if err != nil {
+		return nil, err
+	}
+	table := &Table{}
+	if err := json.Unmarshal(b, table); err != nil {
+		return nil, err
+	}
+	if table.Refs == nil {
+		table.Refs = make(map[string]map[string]int)
+	}
+	return table, nil
+}
+
+// Save writes the mount table to a temporary file and renames it over the
+// journal file, so that a crash never leaves a partially written table.
+func (j *fileJournal) Save(table *Table) error {
+	b, err := json.Marshal(table)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(j.path), 0755); err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(filepath.Dir(j.path), filepath.Base(j.path))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), j.path)
+}
+
+// table returns a snapshot of the mount table.
+func (m *Mounter) table() *Table {
+	m.Lock()
+	infos := make([]*Info, 0, len(m.mounts))
+	for _, info := range m.mounts {
+		infos = append(infos, info)
+	}
+	m.Unlock()
+
+	table := &Table{
+		Type:        m.mounterType,
+		Identifiers: m.identifiers,
+		Refs:        make(map[string]map[string]int),
+	}
+	for _, info := range infos {
+		info.Lock()
+		if len(info.Mountpoint) != 0 {
+			refs := make(map[string]int)
+			for _, p := range info.Mountpoint {
+				refs[p.Path] = p.ref
+			}
+			table.Refs[info.Device] = refs
+		}
+		info.Unlock()
+	}
+	return table
+}
+
+// save writes the mount table to the journal. It must not be called with
+// the mounter or a device locked.
+func (m *Mounter) save() {
+	if m.journal == nil {
+		return
+	}
+	m.journalLock.Lock()
+	defer m.journalLock.Unlock()
+	if err := m.journal.Save(m.table()); err != nil {
+		dlog.Warnf("Failed to save mount table: %v", err)
+	}
+}
+
+// reconcile restores the reference counts saved in the journal for mounts
+// the kernel still has and drops journal entries for mounts that are gone.
+// The mount table must have been loaded from the kernel mount table.
+func (m *Mounter) reconcile() error {
+	if m.journal == nil {
+		return nil
+	}
+	table, err := m.journal.Load()
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if table != nil {
+		m.Lock()
+		for device, refs := range table.Refs {
+			for path, ref := range refs {
+				if !m.restore(device, path, ref) {
+					dlog.Warnf("Dropping %q mounted at %q with refcnt %d, "+
+						"it is no longer mounted", device, path, ref)
+				}
+			}
+		}
+		m.Unlock()
+	}
+	m.save()
+	return nil
+}
+
+// restore sets the reference count of device at path if the device is
+// mounted there. Must be called with the mounter locked.
+func (m *Mounter) restore(device, path string, ref int) bool {
+	info, ok := m.mounts[device]
+	if !ok || ref <= 0 {
+		return false
+	}
+	for _, p := range info.Mountpoint {
+		if p.Path == path {
+			p.ref = ref
+			m.paths[path] = device
+			return true
+		}
+	}
+	return false
+}
+
+// scan returns the kernel mount table for a mount manager of mounterType
+// created with identifiers.
+func scan(mounterType MountType, identifiers []string) (map[string]map[string]int, error) {
+	mgr, err := New(mounterType, nil, identifiers, nil)
+	if err != nil {
+		return nil, err
+	}
+	kernel := make(map[string]map[string]int)
+	for _, device := range mgr.GetSourcePaths() {
+		paths := make(map[string]int)
+		for _, path := range mgr.Mounts(device) {
+			paths[path] = 1
+		}
+		kernel[device] = paths
+	}
+	return kernel, nil
+}
+
+// drift returns the mounts that are only in refs or only in kernel.
+func drift(refs, kernel map[string]map[string]int) []*Drift {
+	drifts := make([]*Drift, 0)
+	for device, paths := range refs {
+		for path, ref := range paths {
+			if _, ok := kernel[device][path]; !ok {
+				drifts = append(drifts, &Drift{
+					Source: device,
+					Path:   path,
+					Refcnt: ref,
+				})
+			}
+		}
+	}
+	for device, paths := range kernel {
+		for path := range paths {
+			if _, ok := refs[device][path]; !ok {
+				drifts = append(drifts, &Drift{
+					Source:  device,
+					Path:    path,
+					Mounted: true,
+				})
+			}
+		}
+	}
+	sort.Sort(driftList(drifts))
+	return drifts
+}
+
+// driftList sorts drifts by source and path.
+type driftList []*Drift
+
+func (d driftList) Len() int      { return len(d) }
+func (d driftList) Swap(i, j int) { d[i], d[j] = d[j], d[i] }
+func (d driftList) Less(i, j int) bool {
+	if d[i].Source != d[j].Source {
+		return d[i].Source < d[j].Source
+	}
+	return d[i].Path < d[j].Path
+}
@@ -0,0 +1,103 @@
+// +build linux
+
+package mount
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileJournal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "journal")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	j := NewFileJournal(filepath.Join(dir, "table"))
+	_, err = j.Load()
+	require.True(t, os.IsNotExist(err), "Expected not exist, got %v", err)
+
+	table := &Table{
+		Type:        NFSMount,
+		Identifiers: []string{"server"},
+		Refs:        map[string]map[string]int{"dev": {"/mnt": 2}},
+	}
+	require.NoError(t, j.Save(table))
+	loaded, err := j.Load()
+	require.NoError(t, err)
+	require.Equal(t, table, loaded)
+}
+
+func TestReconcile(t *testing.T) {
+	kernel, err := New(NFSMount, nil, []string{""}, nil)
+	require.NoError(t, err)
+	var device, path string
+	for _, d := range kernel.GetSourcePaths() {
+		if paths := kernel.Mounts(d); len(paths) != 0 {
+			device, path = d, paths[0]
+			break
+		}
+	}
+	if device == "" {
+		t.Skip("No mounts found")
+	}
+
+	dir, err := ioutil.TempDir("", "journal")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	j := NewFileJournal(filepath.Join(dir, "table"))
+	require.NoError(t, j.Save(&Table{
+		Type:        NFSMount,
+		Identifiers: []string{""},
+		Refs: map[string]map[string]int{
+			device:      {path: 3},
+			"stale-dev": {"/stale/path": 1},
+		},
+	}))
+
+	m, err := New(NFSMount, nil, []string{""}, j)
+	require.NoError(t, err)
+	p := m.Inspect(device)
+	found := false
+	for _, info := range p {
+		if info.Path == path {
+			require.Equal(t, 3, info.Refcnt())
+			found = true
+		}
+	}
+	require.True(t, found, "Expected %v to be mounted at %v", device, path)
+
+	table, err := j.Load()
+	require.NoError(t, err)
+	require.Equal(t, 3, table.Refs[device][path])
+	_, ok := table.Refs["stale-dev"]
+	require.False(t, ok, "Expected stale entry to be dropped")
+
+	drift, err := m.Drift()
+	require.NoError(t, err)
+	for _, d := range drift {
+		require.False(t, d.Source == device && d.Path == path,
+			"Unexpected drift %+v", d)
+	}
+}
+
+func TestDrift(t *testing.T) {
+	drift := drift(
+		map[string]map[string]int{
+			"a": {"/a": 2, "/b": 1},
+			"b": {"/c": 1},
+		},
+		map[string]map[string]int{
+			"a": {"/a": 1},
+			"c": {"/d": 1},
+		},
+	)
+	require.Equal(t, []*Drift{
+		{Source: "a", Path: "/b", Refcnt: 1},
+		{Source: "b", Path: "/c", Refcnt: 1},
+		{Source: "c", Path: "/d", Mounted: true},
+	}, drift)
+}
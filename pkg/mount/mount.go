@@ -44,6 +44,40 @@ type Manager interface {
 	// ErrEnoent is returned if the device or mountpoint for the device
 	// is not found.
 	Unmount(source, path string, timeout int) error
+	// Drift compares the mount table with the kernel mount table and
+	// returns the entries that differ.
+	Drift() ([]*Drift, error)
+}
+
+// Journal persists the mount table so that reference counts survive restarts.
+type Journal interface {
+	// Load returns the saved mount table. An error satisfying
+	// os.IsNotExist is returned if no table was saved.
+	Load() (*Table, error)
+	// Save atomically replaces the saved mount table.
+	Save(table *Table) error
+}
+
+// Table is the persisted form of a mount table.
+type Table struct {
+	// Type of the mount manager that saved the table.
+	Type MountType
+	// Identifiers the mount manager was created with.
+	Identifiers []string
+	// Refs maps a source to its mountpoints and their reference counts.
+	Refs map[string]map[string]int
+}
+
+// Drift is a mount that differs between the mount table and the kernel.
+type Drift struct {
+	// Source of the mount.
+	Source string
+	// Path the source is mounted at.
+	Path string
+	// Refcnt in the mount table, 0 if the mount is not in the table.
+	Refcnt int
+	// Mounted is true if the kernel has the source mounted at path.
+	Mounted bool
 }
 
 // MountImpl backend implementation for Mount/Unmount calls
@@ -60,6 +94,10 @@ const (
 	DeviceMount MountType = 1 << iota
 	// NFSMount indicates a NFS mount point
 	NFSMount
+	// BindMount indicates a bind mount of a directory
+	BindMount
+	// OverlayMount indicates an overlay filesystem mount
+	OverlayMount
 )
 
 var (
@@ -101,6 +139,12 @@ type Mounter struct {
 	mountImpl MountImpl
 	mounts    DeviceMap
 	paths     PathMap
+	// mounterType and identifiers the mounter was created with.
+	mounterType MountType
+	identifiers []string
+	// journal persists the mount table, nil if it is kept in memory only.
+	journal     Journal
+	journalLock sync.Mutex
 }
 
 // DefaultMounter defaults to syscall implementation.
@@ -131,7 +175,7 @@ func (p *PathInfo) Refcnt() int {
 
 // String representation of Mounter
 func (m *Mounter) String() string {
-	return fmt.Sprintf("%#v", *m)
+	return fmt.Sprintf("%#v", m)
 }
 
 // Inspect mount table for device
@@ -289,6 +333,7 @@ func (m *Mounter) Mount(
 	timeout int,
 ) error {
 
+	defer m.save()
 	path = normalizeMountPath(path)
 	dev, ok := m.hasPath(path)
 	if ok && dev != device {
@@ -338,6 +383,7 @@ func (m *Mounter) Mount(
 // mountpoints left after this operation, it is removed from the matrix.
 // ErrEnoent is returned if the device or mountpoint for the device is not found.
 func (m *Mounter) Unmount(device, path string, timeout int) error {
+	defer m.save()
 	m.Lock()
 
 	path = normalizeMountPath(path)
@@ -376,10 +422,23 @@ func (m *Mounter) Unmount(device, path string, timeout int) error {
 	return ErrEnoent
 }
 
-// New returns a new Mount Manager
+// Drift compares the mount table with the kernel mount table.
+func (m *Mounter) Drift() ([]*Drift, error) {
+	kernel, err := scan(m.mounterType, m.identifiers)
+	if err != nil {
+		return nil, err
+	}
+	return drift(m.table().Refs, kernel), nil
+}
+
+// New returns a new Mount Manager. identifiers are device prefixes for
+// DeviceMount, the server for NFSMount, source prefixes for BindMount and
+// upper dir prefixes for OverlayMount. If journal is not nil, the reference
+// counts of the mount table are saved to it and restored from it.
 func New(mounterType MountType,
 	mountImpl MountImpl,
 	identifiers []string,
+	journal Journal,
 ) (Manager, error) {
 
 	if mountImpl == nil {
@@ -388,12 +447,35 @@ func New(mounterType MountType,
 
 	switch mounterType {
 	case DeviceMount:
-		return NewDeviceMounter(identifiers, mountImpl)
+		return NewDeviceMounter(identifiers, mountImpl, journal)
 	case NFSMount:
 		if len(identifiers) > 1 {
 			return nil, fmt.Errorf("Multiple server addresses provided.")
 		}
-		return NewNFSMounter(identifiers[0], mountImpl)
+		return NewNFSMounter(identifiers[0], mountImpl, journal)
+	case BindMount:
+		return NewBindMounter(identifiers, mountImpl, journal)
+	case OverlayMount:
+		return NewOverlayMounter(identifiers, mountImpl, journal)
 	}
 	return nil, ErrUnsupported
 }
+
+// NewFileJournal returns a Journal that saves the mount table to a file.
+func NewFileJournal(path string) Journal {
+	return newFileJournal(path)
+}
+
+// InspectJournal returns the mount table saved in journal and its drift from
+// the kernel mount table. It does not modify the journal.
+func InspectJournal(journal Journal) (*Table, []*Drift, error) {
+	table, err := journal.Load()
+	if err != nil {
+		return nil, nil, err
+	}
+	kernel, err := scan(table.Type, table.Identifiers)
+	if err != nil {
+		return nil, nil, err
+	}
+	return table, drift(table.Refs, kernel), nil
+}
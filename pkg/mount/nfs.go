@@ -15,19 +15,29 @@ type NFSMounter struct {
 }
 
 // NewNFSMounter instance
-func NewNFSMounter(server string, mountImpl MountImpl) (Manager, error) {
+func NewNFSMounter(
+	server string,
+	mountImpl MountImpl,
+	journal Journal,
+) (Manager, error) {
 	m := &NFSMounter{
 		server: server,
 		Mounter: Mounter{
-			mountImpl: mountImpl,
-			mounts:    make(DeviceMap),
-			paths:     make(PathMap),
+			mountImpl:   &flagsMounter{mountImpl},
+			mounts:      make(DeviceMap),
+			paths:       make(PathMap),
+			mounterType: NFSMount,
+			identifiers: []string{server},
+			journal:     journal,
 		},
 	}
 	err := m.Load([]string{""})
 	if err != nil {
 		return nil, err
 	}
+	if err := m.reconcile(); err != nil {
+		return nil, err
+	}
 	return m, nil
 }
 
@@ -0,0 +1,128 @@
+// +build linux
+
+package mount
+
+import (
+	"strings"
+	"syscall"
+)
+
+// mountFlags are the mount options that map to mount flags. The other
+// mount options are filesystem specific.
+var mountFlags = map[string]struct {
+	clear bool
+	flag  uintptr
+}{
+	"defaults":      {false, 0},
+	"ro":            {false, syscall.MS_RDONLY},
+	"rw":            {true, syscall.MS_RDONLY},
+	"suid":          {true, syscall.MS_NOSUID},
+	"nosuid":        {false, syscall.MS_NOSUID},
+	"dev":           {true, syscall.MS_NODEV},
+	"nodev":         {false, syscall.MS_NODEV},
+	"exec":          {true, syscall.MS_NOEXEC},
+	"noexec":        {false, syscall.MS_NOEXEC},
+	"sync":          {false, syscall.MS_SYNCHRONOUS},
+	"async":         {true, syscall.MS_SYNCHRONOUS},
+	"dirsync":       {false, syscall.MS_DIRSYNC},
+	"mand":          {false, syscall.MS_MANDLOCK},
+	"nomand":        {true, syscall.MS_MANDLOCK},
+	"atime":         {true, syscall.MS_NOATIME},
+	"noatime":       {false, syscall.MS_NOATIME},
+	"diratime":      {true, syscall.MS_NODIRATIME},
+	"nodiratime":    {false, syscall.MS_NODIRATIME},
+	"relatime":      {false, syscall.MS_RELATIME},
+	"norelatime":    {true, syscall.MS_RELATIME},
+	"strictatime":   {false, syscall.MS_STRICTATIME},
+	"nostrictatime": {true, syscall.MS_STRICTATIME},
+}
+
+// propagationFlags are the mount flags that change the propagation type of
+// a mount instead of mounting it.
+const propagationFlags = syscall.MS_SHARED | syscall.MS_SLAVE |
+	syscall.MS_PRIVATE | syscall.MS_UNBINDABLE
+
+// bindFlags are the flags that the kernel ignores when a bind mount is
+// created and only applies when it is remounted.
+const bindFlags = syscall.MS_RDONLY | syscall.MS_NOSUID | syscall.MS_NODEV |
+	syscall.MS_NOEXEC | syscall.MS_NOATIME | syscall.MS_NODIRATIME |
+	syscall.MS_RELATIME | syscall.MS_STRICTATIME
+
+// ParseOptions converts fstab style mount options into mount flags and the
+// filesystem specific mount data.
+func ParseOptions(options []string) (uintptr, string) {
+	var flags uintptr
+	data := make([]string, 0)
+	for _, o := range options {
+		o = strings.TrimSpace(o)
+		if len(o) == 0 {
+			continue
+		}
+		f, ok := mountFlags[o]
+		if !ok {
+			data = append(data, o)
+			continue
+		}
+		if f.clear {
+			flags &^= f.flag
+		} else {
+			flags |= f.flag
+		}
+	}
+	return flags, strings.Join(data, ",")
+}
+
+// flagsMounter applies the mount flags that the kernel ignores in the
+// first mount call. Propagation flags are applied with a second mount call
+// and the flags of a bind mount are applied by remounting it.
+type flagsMounter struct {
+	MountImpl
+}
+
+// Mount source at target and apply the propagation and bind mount flags.
+func (f *flagsMounter) Mount(
+	source string,
+	target string,
+	fstype string,
+	flags uintptr,
+	data string,
+	timeout int,
+) error {
+	propagation := flags & propagationFlags
+	if err := f.MountImpl.Mount(
+		source,
+		target,
+		fstype,
+		flags&^propagationFlags,
+		data,
+		timeout,
+	); err != nil {
+		return err
+	}
+	var err error
+	if flags&syscall.MS_BIND != 0 && flags&bindFlags != 0 {
+		err = f.MountImpl.Mount(
+			"",
+			target,
+			"",
+			syscall.MS_BIND|syscall.MS_REMOUNT|flags&bindFlags,
+			"",
+			timeout,
+		)
+	}
+	if err == nil && propagation != 0 {
+		err = f.MountImpl.Mount(
+			"",
+			target,
+			"",
+			propagation|flags&syscall.MS_REC,
+			"",
+			timeout,
+		)
+	}
+	if err != nil {
+		f.MountImpl.Unmount(target, syscall.MNT_DETACH, timeout)
+		return err
+	}
+	return nil
+}
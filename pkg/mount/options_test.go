@@ -0,0 +1,43 @@
+// +build linux
+
+package mount
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseOptions(t *testing.T) {
+	flags, data := ParseOptions([]string{"ro", "noexec", "exec", "nosuid", "discard", "data=ordered"})
+	require.Equal(t, uintptr(syscall.MS_RDONLY|syscall.MS_NOSUID), flags)
+	require.Equal(t, "discard,data=ordered", data)
+}
+
+func TestBindMountReadonly(t *testing.T) {
+	dir, err := ioutil.TempDir("", "options")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	src := filepath.Join(dir, "src")
+	dst := filepath.Join(dir, "dst")
+	require.NoError(t, os.MkdirAll(src, 0755))
+	require.NoError(t, os.MkdirAll(dst, 0755))
+
+	m, err := New(BindMount, nil, []string{dir}, nil)
+	require.NoError(t, err)
+	flags, _ := ParseOptions([]string{"ro", "noexec"})
+	require.NoError(t, m.Mount(0, src, dst, "", syscall.MS_BIND|flags, "", 0))
+	defer syscall.Unmount(dst, syscall.MNT_DETACH)
+
+	info, err := mountInfo(dst)
+	require.NoError(t, err)
+	opts := strings.Split(info.Opts, ",")
+	require.Contains(t, opts, "ro")
+	require.Contains(t, opts, "noexec")
+	require.NoError(t, m.Unmount(src, dst, 0))
+}
@@ -0,0 +1,116 @@
+// +build linux
+
+package mount
+
+import (
+	"regexp"
+
+	"github.com/docker/docker/pkg/mount"
+)
+
+// OverlayFs is the fs of an overlay mount.
+const OverlayFs = "overlay"
+
+// OverlayMounter implements Manager and keeps track of active overlay mounts
+// for graph drivers. The source of an overlay mount is its upper dir.
+type OverlayMounter struct {
+	Mounter
+}
+
+// NewOverlayMounter returns a new OverlayMounter that tracks overlay mounts
+// with an upper dir that has one of upperPrefixes.
+func NewOverlayMounter(
+	upperPrefixes []string,
+	mountImpl MountImpl,
+	journal Journal,
+) (*OverlayMounter, error) {
+	m := &OverlayMounter{
+		Mounter: Mounter{
+			mountImpl:   &overlayMounter{&flagsMounter{mountImpl}},
+			mounts:      make(DeviceMap),
+			paths:       make(PathMap),
+			mounterType: OverlayMount,
+			identifiers: upperPrefixes,
+			journal:     journal,
+		},
+	}
+	if err := m.Load(upperPrefixes); err != nil {
+		return nil, err
+	}
+	if err := m.reconcile(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Reload reloads the mount table for the specified upper dir
+func (m *OverlayMounter) Reload(upper string) error {
+	return ErrUnsupported
+}
+
+// Load mount table
+func (m *OverlayMounter) Load(upperPrefixes []string) error {
+	info, err := mount.GetMounts()
+	if err != nil {
+		return err
+	}
+	re := regexp.MustCompile(`(?:^|,)upperdir=([^,]*)`)
+OverlayLoop:
+	for _, v := range info {
+		if v.Fstype != OverlayFs {
+			continue
+		}
+		matches := re.FindStringSubmatch(v.VfsOpts)
+		if len(matches) != 2 {
+			continue
+		}
+		upper := normalizeMountPath(matches[1])
+		if !hasPrefix(upper, upperPrefixes) {
+			continue
+		}
+		mount, ok := m.mounts[upper]
+		if !ok {
+			mount = &Info{
+				Device:     upper,
+				Fs:         OverlayFs,
+				Minor:      v.Minor,
+				Mountpoint: make([]*PathInfo, 0),
+			}
+			m.mounts[upper] = mount
+		}
+		path := normalizeMountPath(v.Mountpoint)
+		// Allow Load to be called multiple times.
+		for _, p := range mount.Mountpoint {
+			if p.Path == path {
+				continue OverlayLoop
+			}
+		}
+		mount.Mountpoint = append(
+			mount.Mountpoint,
+			&PathInfo{
+				Path: path,
+				ref:  1,
+			},
+		)
+		m.paths[path] = upper
+	}
+	return nil
+}
+
+// overlayMounter mounts an overlay filesystem for an upper dir. The lower,
+// upper and work dirs are passed in the mount data.
+type overlayMounter struct {
+	MountImpl
+}
+
+// Mount an overlay filesystem at target.
+func (o *overlayMounter) Mount(
+	upper string,
+	target string,
+	fstype string,
+	flags uintptr,
+	data string,
+	timeout int,
+) error {
+	return o.MountImpl.Mount(OverlayFs, target, OverlayFs, flags, data, timeout)
+}
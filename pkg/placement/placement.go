@@ -0,0 +1,283 @@
+// Package placement picks the nodes that store the data of new volumes from
+// their spec and the labels, status and storage pools of the nodes.
+package placement
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/pkg/units"
+	"github.com/libopenstorage/openstorage/volume"
+)
+
+// Request to place the data of a volume.
+type Request struct {
+	// Spec of the volume. Its HaLevel is the number of replicas and its
+	// ReplicaSet pins replicas to nodes. The LocationConstraint label is
+	// either LocalNode or comma separated node labels, as in
+	// zone=us-east-1a,disk=ssd.
+	Spec *api.VolumeSpec
+	// Nodes of the cluster.
+	Nodes []api.Node
+	// Self is the ID of this node, which LocalNode refers to.
+	Self string
+	// Placed are the nodes that already store replicas of the volume, when
+	// replicas are added to it. They count as replicas and hold their
+	// failure domain, but are not checked, so that a replica on a node that
+	// is down or full can be replaced.
+	Placed []string
+	// FailureDomain is the node label, such as rack or zone, whose values
+	// fail independently. Replicas are placed in distinct failure domains.
+	// Nodes without the label are failure domains of their own.
+	FailureDomain string
+}
+
+// Candidate is a node considered for the data of a volume.
+type Candidate struct {
+	// Node ID.
+	Node string
+	// Domain is the failure domain of the node.
+	Domain string
+	// Pool is the ID of the pool picked on the node, -1 if none fits.
+	Pool int32
+	// Free space of the pool.
+	Free uint64
+	// Selected is true if the node stores a replica.
+	Selected bool
+	// Reason the node was selected or not.
+	Reason string
+}
+
+// Placement of the replicas of a volume.
+type Placement struct {
+	// Nodes that store the replicas of the volume.
+	Nodes []string
+	// Candidates are the nodes of the cluster, selected ones first.
+	Candidates []Candidate
+	// Error is why the volume cannot be placed, if it cannot.
+	Error string
+}
+
+// Place picks a node for each replica of a volume. Placed nodes are kept,
+// nodes pinned by the ReplicaSet are picked next, then the eligible nodes
+// with the most free space in distinct failure domains. The placement explains the choice even
+// if it fails, in which case an error is also returned.
+func Place(r *Request) (*Placement, error) {
+	replicas := int(r.Spec.GetHaLevel())
+	if replicas < 1 {
+		replicas = 1
+	}
+	constraint, err := parseConstraint(
+		r.Spec.GetVolumeLabels()[volume.LocationConstraint], r.Self)
+	if err != nil {
+		return nil, err
+	}
+	placed := make(map[string]bool)
+	for _, id := range r.Placed {
+		placed[id] = true
+	}
+	if len(placed) > replicas {
+		return nil, fmt.Errorf("Volume already has %v of %v replicas",
+			len(placed), replicas)
+	}
+	var pinned []string
+	if rs := r.Spec.GetReplicaSet(); rs != nil {
+		for _, id := range rs.Nodes {
+			if id == volume.LocalNode {
+				id = r.Self
+			}
+			if !placed[id] {
+				pinned = append(pinned, id)
+			}
+		}
+	}
+	if len(placed)+len(pinned) > replicas {
+		return nil, fmt.Errorf("ReplicaSet has %v nodes for %v replicas",
+			len(placed)+len(pinned), replicas)
+	}
+
+	byID := make(map[string]*Candidate)
+	candidates := make([]*Candidate, 0, len(r.Nodes))
+	for i := range r.Nodes {
+		c := evaluate(&r.Nodes[i], r, constraint)
+		byID[c.Node] = c
+		candidates = append(candidates, c)
+	}
+
+	p := &Placement{}
+	domains := make(map[string]string)
+	pick := func(c *Candidate, reason string) {
+		c.Selected = true
+		c.Reason = reason
+		p.Nodes = append(p.Nodes, c.Node)
+		domains[c.Domain] = c.Node
+	}
+	for _, id := range r.Placed {
+		c, ok := byID[id]
+		switch {
+		case ok && c.Selected:
+		case ok:
+			pick(c, "Already stores a replica")
+		case len(domains[id]) == 0:
+			// A node removed from the cluster is its own failure domain.
+			p.Nodes = append(p.Nodes, id)
+			domains[id] = id
+		}
+	}
+	var failed []string
+	for _, id := range pinned {
+		c, ok := byID[id]
+		if !ok {
+			failed = append(failed, fmt.Sprintf("node %v is not in the cluster", id))
+			continue
+		}
+		if c.Selected {
+			continue
+		}
+		if len(c.Reason) != 0 {
+			failed = append(failed, fmt.Sprintf("node %v %v", id, c.Reason))
+			continue
+		}
+		pick(c, "Pinned by the replica set")
+	}
+
+	// Spread the data on the nodes with the most free space.
+	sort.Sort(byFree(candidates))
+	for _, c := range candidates {
+		switch {
+		case c.Selected || len(c.Reason) != 0:
+		case len(p.Nodes) >= replicas:
+			c.Reason = "Replicas are already placed"
+		case len(domains[c.Domain]) != 0:
+			c.Reason = fmt.Sprintf("Failure domain %v already has a replica on %v",
+				c.Domain, domains[c.Domain])
+		default:
+			pick(c, "Eligible with the most free space")
+		}
+	}
+	sort.Stable(bySelected(candidates))
+	for _, c := range candidates {
+		p.Candidates = append(p.Candidates, *c)
+	}
+
+	if len(failed) != 0 {
+		err = fmt.Errorf("Cannot place volume on its replica set: %v",
+			strings.Join(failed, ", "))
+	} else if n := len(p.Nodes); n < replicas {
+		err = fmt.Errorf("Only %v of %v replicas can be placed in distinct failure domains",
+			n, replicas)
+	}
+	if err != nil {
+		p.Nodes = nil
+		p.Error = err.Error()
+		return p, err
+	}
+	return p, nil
+}
+
+// evaluate returns a node as a candidate with the reason it is not eligible.
+func evaluate(n *api.Node, r *Request, constraint *constraint) *Candidate {
+	c := &Candidate{
+		Node:   n.Id,
+		Domain: n.Id,
+		Pool:   -1,
+	}
+	if len(r.FailureDomain) != 0 {
+		if domain, ok := n.NodeLabels[r.FailureDomain]; ok {
+			c.Domain = r.FailureDomain + "=" + domain
+		}
+	}
+	if n.Status != api.Status_STATUS_OK {
+		c.Reason = fmt.Sprintf("is %v", n.Status)
+		return c
+	}
+	if len(constraint.node) != 0 && n.Id != constraint.node {
+		c.Reason = "is not the local node"
+		return c
+	}
+	for k, v := range constraint.labels {
+		if n.NodeLabels[k] != v {
+			c.Reason = fmt.Sprintf("does not match location constraint %v=%v", k, v)
+			return c
+		}
+	}
+	cos := r.Spec.GetCos()
+	size := r.Spec.GetSize()
+	for _, pool := range n.Pools {
+		if pool.Cos < cos || free(pool) == 0 || free(pool) < size {
+			continue
+		}
+		// Lower classes are preferred to keep faster pools for the
+		// volumes that require them.
+		if c.Pool < 0 || pool.Cos < poolOf(n, c.Pool).Cos ||
+			(pool.Cos == poolOf(n, c.Pool).Cos && free(pool) > c.Free) {
+			c.Pool = pool.ID
+			c.Free = free(pool)
+		}
+	}
+	if c.Pool < 0 {
+		c.Reason = fmt.Sprintf("has no pool of Cos %v or higher with %v free",
+			cos.SimpleString(), units.String(size))
+	}
+	return c
+}
+
+// constraint is the node or the node labels a location constraint requires.
+type constraint struct {
+	node   string
+	labels map[string]string
+}
+
+func parseConstraint(s string, self string) (*constraint, error) {
+	c := &constraint{labels: make(map[string]string)}
+	if len(s) == 0 {
+		return c, nil
+	}
+	if s == volume.LocalNode {
+		c.node = self
+		return c, nil
+	}
+	for _, kv := range strings.Split(s, ",") {
+		pair := strings.SplitN(strings.TrimSpace(kv), "=", 2)
+		if len(pair) != 2 || len(pair[0]) == 0 {
+			return nil, fmt.Errorf("Malformed location constraint: %v", kv)
+		}
+		c.labels[pair[0]] = pair[1]
+	}
+	return c, nil
+}
+
+func free(pool api.StoragePool) uint64 {
+	if pool.Used >= pool.TotalSize {
+		return 0
+	}
+	return pool.TotalSize - pool.Used
+}
+
+func poolOf(n *api.Node, id int32) api.StoragePool {
+	for _, pool := range n.Pools {
+		if pool.ID == id {
+			return pool
+		}
+	}
+	return api.StoragePool{}
+}
+
+type byFree []*Candidate
+
+func (c byFree) Len() int      { return len(c) }
+func (c byFree) Swap(i, j int) { c[i], c[j] = c[j], c[i] }
+func (c byFree) Less(i, j int) bool {
+	if c[i].Free != c[j].Free {
+		return c[i].Free > c[j].Free
+	}
+	return c[i].Node < c[j].Node
+}
+
+type bySelected []*Candidate
+
+func (c bySelected) Len() int           { return len(c) }
+func (c bySelected) Swap(i, j int)      { c[i], c[j] = c[j], c[i] }
+func (c bySelected) Less(i, j int) bool { return c[i].Selected && !c[j].Selected }
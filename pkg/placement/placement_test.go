@@ -0,0 +1,160 @@
+package placement
+
+import (
+	"testing"
+
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/volume"
+	"github.com/stretchr/testify/require"
+)
+
+// node returns an online node in rack with a pool of free GiB of cos.
+func node(id, rack string, cos api.CosType, free uint64) api.Node {
+	return api.Node{
+		Id:         id,
+		Status:     api.Status_STATUS_OK,
+		NodeLabels: map[string]string{"rack": rack, "zone": "a"},
+		Pools: []api.StoragePool{
+			{ID: 0, Cos: api.CosType_LOW, TotalSize: 100 << 30, Used: 100 << 30},
+			{ID: 1, Cos: cos, TotalSize: free << 30},
+		},
+	}
+}
+
+func nodes() []api.Node {
+	offline := node("n5", "r3", api.CosType_HIGH, 900)
+	offline.Status = api.Status_STATUS_OFFLINE
+	return []api.Node{
+		node("n1", "r1", api.CosType_MEDIUM, 100),
+		node("n2", "r1", api.CosType_MEDIUM, 200),
+		node("n3", "r2", api.CosType_HIGH, 50),
+		node("n4", "r2", api.CosType_LOW, 500),
+		offline,
+	}
+}
+
+func TestPlace(t *testing.T) {
+	p, err := Place(&Request{
+		Spec:  &api.VolumeSpec{Size: 10 << 30, HaLevel: 2},
+		Nodes: nodes(),
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"n4", "n2"}, p.Nodes,
+		"Nodes with the most free space should be selected")
+	require.Len(t, p.Candidates, 5)
+	require.True(t, p.Candidates[0].Selected)
+	require.Equal(t, int32(1), p.Candidates[0].Pool)
+	require.False(t, p.Candidates[4].Selected)
+	require.Equal(t, "is STATUS_OFFLINE", p.Candidates[4].Reason)
+
+	p, err = Place(&Request{
+		Spec:          &api.VolumeSpec{Size: 10 << 30, HaLevel: 2, Cos: api.CosType_MEDIUM},
+		Nodes:         nodes(),
+		FailureDomain: "rack",
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"n2", "n3"}, p.Nodes,
+		"Replicas should be in distinct racks of pools of the Cos")
+	for _, c := range p.Candidates {
+		if c.Node == "n1" {
+			require.Equal(t, "Failure domain rack=r1 already has a replica on n2", c.Reason)
+		}
+	}
+
+	p, err = Place(&Request{
+		Spec:          &api.VolumeSpec{HaLevel: 3},
+		Nodes:         nodes(),
+		FailureDomain: "zone",
+	})
+	require.Error(t, err, "All the nodes are in one zone")
+	require.Nil(t, p.Nodes)
+	require.Equal(t, err.Error(), p.Error)
+	require.Len(t, p.Candidates, 5)
+}
+
+func TestPlaceConstraints(t *testing.T) {
+	p, err := Place(&Request{
+		Spec: &api.VolumeSpec{
+			HaLevel:    2,
+			ReplicaSet: &api.ReplicaSet{Nodes: []string{volume.LocalNode}},
+		},
+		Nodes: nodes(),
+		Self:  "n1",
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"n1", "n4"}, p.Nodes, "LocalNode should be pinned first")
+
+	_, err = Place(&Request{
+		Spec:  &api.VolumeSpec{ReplicaSet: &api.ReplicaSet{Nodes: []string{"n5"}}},
+		Nodes: nodes(),
+	})
+	require.Error(t, err, "Offline nodes cannot be pinned")
+	_, err = Place(&Request{
+		Spec:  &api.VolumeSpec{ReplicaSet: &api.ReplicaSet{Nodes: []string{"n1", "n2"}}},
+		Nodes: nodes(),
+	})
+	require.Error(t, err, "More nodes than replicas cannot be pinned")
+
+	p, err = Place(&Request{
+		Spec: &api.VolumeSpec{
+			VolumeLabels: map[string]string{volume.LocationConstraint: "rack=r1"},
+		},
+		Nodes: nodes(),
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"n2"}, p.Nodes)
+
+	p, err = Place(&Request{
+		Spec: &api.VolumeSpec{
+			VolumeLabels: map[string]string{volume.LocationConstraint: volume.LocalNode},
+		},
+		Nodes: nodes(),
+		Self:  "n3",
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"n3"}, p.Nodes)
+
+	_, err = Place(&Request{
+		Spec: &api.VolumeSpec{
+			VolumeLabels: map[string]string{volume.LocationConstraint: "rack"},
+		},
+		Nodes: nodes(),
+	})
+	require.Error(t, err)
+}
+
+func TestPlacePlaced(t *testing.T) {
+	p, err := Place(&Request{
+		Spec: &api.VolumeSpec{
+			Size:       10 << 30,
+			HaLevel:    3,
+			ReplicaSet: &api.ReplicaSet{Nodes: []string{"n1"}},
+		},
+		Nodes:         nodes(),
+		Placed:        []string{"n5", "n1"},
+		FailureDomain: "rack",
+	})
+	require.NoError(t, err, "Placed nodes are not checked")
+	require.Equal(t, []string{"n5", "n1", "n4"}, p.Nodes)
+	for _, c := range p.Candidates {
+		if c.Node == "n5" {
+			require.True(t, c.Selected)
+			require.Equal(t, "Already stores a replica", c.Reason)
+		}
+	}
+
+	p, err = Place(&Request{
+		Spec:   &api.VolumeSpec{HaLevel: 2},
+		Nodes:  nodes(),
+		Placed: []string{"removed"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"removed", "n4"}, p.Nodes)
+
+	_, err = Place(&Request{
+		Spec:   &api.VolumeSpec{HaLevel: 1},
+		Nodes:  nodes(),
+		Placed: []string{"n1", "n2"},
+	})
+	require.Error(t, err, "More nodes than replicas are placed")
+}
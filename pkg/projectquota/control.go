@@ -0,0 +1,227 @@
+// +build linux
+
+package projectquota
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	xfsMagic = 0x58465342
+	// fsIocFsGetXattr is FS_IOC_FSGETXATTR from linux/fs.h
+	fsIocFsGetXattr = 0x801c581f
+	// fsIocFsSetXattr is FS_IOC_FSSETXATTR from linux/fs.h
+	fsIocFsSetXattr = 0x401c5820
+	// fsXflagProjInherit is FS_XFLAG_PROJINHERIT from linux/fs.h
+	fsXflagProjInherit = 0x00000200
+)
+
+// fsxattr mirrors struct fsxattr from linux/fs.h
+type fsxattr struct {
+	xflags     uint32
+	extsize    uint32
+	nextents   uint32
+	projid     uint32
+	cowextsize uint32
+	pad        [8]byte
+}
+
+type control struct {
+	sync.Mutex
+	// mountPoint of the filesystem backing basePath.
+	mountPoint string
+	// nextProjectID is the next free project id.
+	nextProjectID uint32
+}
+
+func newControl(basePath string) (*control, error) {
+	var statfs syscall.Statfs_t
+	if err := syscall.Statfs(basePath, &statfs); err != nil {
+		return nil, err
+	}
+	if statfs.Type != xfsMagic {
+		return nil, ErrNotSupported
+	}
+	mountPoint, err := findMountPoint(basePath)
+	if err != nil {
+		return nil, err
+	}
+	c := &control{mountPoint: mountPoint}
+	// Query the quota state to make sure project quotas are enabled.
+	if _, err := c.xfsQuota("state -p"); err != nil {
+		return nil, ErrNotSupported
+	}
+	// Project ids are never reused, so start after the highest id in use.
+	maxID, err := maxProjectID(basePath)
+	if err != nil {
+		return nil, err
+	}
+	c.nextProjectID = maxID + 1
+	return c, nil
+}
+
+func (c *control) SetQuota(path string, size uint64) error {
+	c.Lock()
+	defer c.Unlock()
+	id, err := getProjectID(path)
+	if err != nil {
+		return err
+	}
+	if id == 0 {
+		id = c.nextProjectID
+		if err := setProjectID(path, id); err != nil {
+			return err
+		}
+		c.nextProjectID++
+	}
+	_, err = c.xfsQuota(fmt.Sprintf("limit -p bhard=%dk %d",
+		(size+1023)/1024, id))
+	return err
+}
+
+func (c *control) GetQuota(path string) (*Usage, error) {
+	id, err := getProjectID(path)
+	if err != nil {
+		return nil, err
+	}
+	if id == 0 {
+		return nil, ErrNotFound
+	}
+	out, err := c.xfsQuota(fmt.Sprintf("quota -p -N -b -v %d", id))
+	if err != nil {
+		return nil, err
+	}
+	return parseQuota(out)
+}
+
+func (c *control) ClearQuota(path string) error {
+	id, err := getProjectID(path)
+	if err != nil {
+		return err
+	}
+	if id == 0 {
+		return nil
+	}
+	_, err = c.xfsQuota(fmt.Sprintf("limit -p bhard=0 %d", id))
+	return err
+}
+
+func (c *control) xfsQuota(command string) ([]byte, error) {
+	out, err := exec.Command("xfs_quota", "-x", "-c", command,
+		c.mountPoint).CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("xfs_quota %s: %v: %s",
+			command, err, strings.TrimSpace(string(out)))
+	}
+	return out, nil
+}
+
+// parseQuota parses the output of "xfs_quota -c 'quota -p -N -b -v'",
+// which reports blocks of 1KiB:
+// <device> <used> <soft> <hard> <warn/grace> ...
+func parseQuota(out []byte) (*Usage, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 {
+			continue
+		}
+		used, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid quota line %q", scanner.Text())
+		}
+		limit, err := strconv.ParseUint(fields[3], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid quota line %q", scanner.Text())
+		}
+		return &Usage{Used: used * 1024, Limit: limit * 1024}, nil
+	}
+	return nil, ErrNotFound
+}
+
+func findMountPoint(path string) (string, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	var st syscall.Stat_t
+	if err := syscall.Stat(path, &st); err != nil {
+		return "", err
+	}
+	for path != "/" {
+		var parent syscall.Stat_t
+		if err := syscall.Stat(filepath.Dir(path), &parent); err != nil {
+			return "", err
+		}
+		if parent.Dev != st.Dev {
+			break
+		}
+		path = filepath.Dir(path)
+	}
+	return path, nil
+}
+
+func maxProjectID(basePath string) (uint32, error) {
+	var maxID uint32
+	err := filepath.Walk(basePath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		if rel, _ := filepath.Rel(basePath, path); strings.Count(rel, "/") > 1 {
+			// Quotas are only set on <basePath>/<id>/<dir>.
+			return filepath.SkipDir
+		}
+		if id, err := getProjectID(path); err == nil && id > maxID {
+			maxID = id
+		}
+		return nil
+	})
+	return maxID, err
+}
+
+func getProjectID(path string) (uint32, error) {
+	dir, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer dir.Close()
+	var attr fsxattr
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, dir.Fd(),
+		fsIocFsGetXattr, uintptr(unsafe.Pointer(&attr))); errno != 0 {
+		return 0, fmt.Errorf("Failed to get project id of %s: %v", path, errno)
+	}
+	return attr.projid, nil
+}
+
+func setProjectID(path string, id uint32) error {
+	dir, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	var attr fsxattr
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, dir.Fd(),
+		fsIocFsGetXattr, uintptr(unsafe.Pointer(&attr))); errno != 0 {
+		return fmt.Errorf("Failed to get project id of %s: %v", path, errno)
+	}
+	attr.projid = id
+	attr.xflags |= fsXflagProjInherit
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, dir.Fd(),
+		fsIocFsSetXattr, uintptr(unsafe.Pointer(&attr))); errno != 0 {
+		return fmt.Errorf("Failed to set project id of %s: %v", path, errno)
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+// +build linux
+
+// Package projectquota limits the size of directories on xfs using project quotas.
+package projectquota
+
+import (
+	"errors"
+)
+
+var (
+	// ErrNotSupported is returned if the backing filesystem does not
+	// support project quotas.
+	ErrNotSupported = errors.New("Project quotas are not supported on this filesystem")
+	// ErrNotFound is returned if a path does not have a quota.
+	ErrNotFound = errors.New("Project quota not found")
+)
+
+// Usage is the usage and limit of a project quota in bytes.
+type Usage struct {
+	// Used bytes.
+	Used uint64
+	// Limit in bytes, 0 if there is no limit.
+	Limit uint64
+}
+
+// Control manages project quotas for the directories under a base path.
+type Control interface {
+	// SetQuota assigns a project to the directory at path, if it does not
+	// have one yet, and limits the project to size bytes.
+	SetQuota(path string, size uint64) error
+	// GetQuota returns the usage of the project assigned to path.
+	GetQuota(path string) (*Usage, error)
+	// ClearQuota removes the limit of the project assigned to path.
+	ClearQuota(path string) error
+}
+
+// NewControl returns a Control for the directories under basePath.
+// ErrNotSupported is returned if basePath is not on xfs.
+func NewControl(basePath string) (Control, error) {
+	control, err := newControl(basePath)
+	if err != nil {
+		return nil, err
+	}
+	return control, nil
+}
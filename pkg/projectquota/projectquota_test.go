@@ -0,0 +1,22 @@
+// +build linux
+
+package projectquota
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseQuota(t *testing.T) {
+	usage, err := parseQuota([]byte("/dev/loop0  2048  0  10240  00 [--------] /mnt\n"))
+	require.NoError(t, err, "parseQuota")
+	require.Equal(t, uint64(2048*1024), usage.Used)
+	require.Equal(t, uint64(10240*1024), usage.Limit)
+
+	_, err = parseQuota([]byte(""))
+	require.Equal(t, ErrNotFound, err)
+
+	_, err = parseQuota([]byte("/dev/loop0  x  0  10240  00"))
+	require.Error(t, err, "parseQuota")
+}
@@ -0,0 +1,132 @@
+// Package qgroup manages btrfs quota groups through the btrfs command line tool.
+package qgroup
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrNotFound is returned when no qgroup exists for a subvolume.
+	ErrNotFound = errors.New("qgroup not found")
+)
+
+// Qgroup describes the usage and limits of a btrfs quota group.
+// Sizes are in bytes and a limit of 0 means no limit is set.
+type Qgroup struct {
+	// ID is the qgroup id in level/id form, i.e. 0/257.
+	ID string
+	// Referenced is the amount of data referenced by the qgroup.
+	Referenced uint64
+	// Exclusive is the amount of data only referenced by this qgroup.
+	Exclusive uint64
+	// MaxReferenced is the referenced limit.
+	MaxReferenced uint64
+	// MaxExclusive is the exclusive limit.
+	MaxExclusive uint64
+}
+
+// Enable turns on quota accounting for the btrfs filesystem at path.
+func Enable(path string) error {
+	return run("quota", "enable", path)
+}
+
+// Limit sets the exclusive size limit of the subvolume at path.
+// A size of 0 clears the limit.
+func Limit(path string, size uint64) error {
+	limit := "none"
+	if size != 0 {
+		limit = strconv.FormatUint(size, 10)
+	}
+	return run("qgroup", "limit", "-e", limit, path)
+}
+
+// LimitReferenced sets the referenced size limit of the subvolume at path,
+// which includes the data it shares with snapshots. A size of 0 clears the
+// limit.
+func LimitReferenced(path string, size uint64) error {
+	limit := "none"
+	if size != 0 {
+		limit = strconv.FormatUint(size, 10)
+	}
+	return run("qgroup", "limit", limit, path)
+}
+
+// Inspect returns the level 0 qgroup of the subvolume at path.
+func Inspect(path string) (*Qgroup, error) {
+	out, err := output("qgroup", "show", "-f", "--raw", "-re", path)
+	if err != nil {
+		return nil, err
+	}
+	qgroups, err := Parse(out)
+	if err != nil {
+		return nil, err
+	}
+	for _, q := range qgroups {
+		if strings.HasPrefix(q.ID, "0/") {
+			return q, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// Destroy removes the qgroup with the given id from the filesystem at path.
+func Destroy(id string, path string) error {
+	return run("qgroup", "destroy", id, path)
+}
+
+// Parse parses the output of "btrfs qgroup show --raw -re".
+func Parse(out []byte) ([]*Qgroup, error) {
+	qgroups := make([]*Qgroup, 0)
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 5 || !strings.Contains(fields[0], "/") {
+			// Header or separator line.
+			continue
+		}
+		q := &Qgroup{ID: fields[0]}
+		sizes := []*uint64{
+			&q.Referenced,
+			&q.Exclusive,
+			&q.MaxReferenced,
+			&q.MaxExclusive,
+		}
+		for i, size := range sizes {
+			v, err := parseSize(fields[i+1])
+			if err != nil {
+				return nil, fmt.Errorf("Invalid qgroup line %q: %v",
+					scanner.Text(), err)
+			}
+			*size = v
+		}
+		qgroups = append(qgroups, q)
+	}
+	return qgroups, scanner.Err()
+}
+
+func parseSize(s string) (uint64, error) {
+	if s == "none" || s == "-" {
+		return 0, nil
+	}
+	return strconv.ParseUint(s, 10, 64)
+}
+
+func run(args ...string) error {
+	_, err := output(args...)
+	return err
+}
+
+func output(args ...string) ([]byte, error) {
+	out, err := exec.Command("btrfs", args...).CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("btrfs %s: %v: %s",
+			strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return out, nil
+}
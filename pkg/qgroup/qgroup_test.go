@@ -0,0 +1,32 @@
+package qgroup
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const showOutput = `qgroupid         rfer         excl     max_rfer     max_excl
+--------         ----         ----     --------     --------
+0/5             16384        16384         none         none
+0/257         1114112       868352         none     10485760
+`
+
+func TestParse(t *testing.T) {
+	qgroups, err := Parse([]byte(showOutput))
+	require.NoError(t, err, "Parse")
+	require.Len(t, qgroups, 2, "Parse")
+
+	require.Equal(t, "0/5", qgroups[0].ID)
+	require.Equal(t, uint64(16384), qgroups[0].Referenced)
+	require.Equal(t, uint64(0), qgroups[0].MaxExclusive)
+
+	require.Equal(t, "0/257", qgroups[1].ID)
+	require.Equal(t, uint64(1114112), qgroups[1].Referenced)
+	require.Equal(t, uint64(868352), qgroups[1].Exclusive)
+	require.Equal(t, uint64(0), qgroups[1].MaxReferenced)
+	require.Equal(t, uint64(10485760), qgroups[1].MaxExclusive)
+
+	_, err = Parse([]byte("0/258 12 bad none none"))
+	require.Error(t, err, "Parse")
+}
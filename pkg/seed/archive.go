@@ -0,0 +1,356 @@
+package seed
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	formatTar = "tar"
+	formatTgz = "tgz"
+	formatZip = "zip"
+	formatRaw = "raw"
+)
+
+// progress counts the bytes written to it. Writes fail once it is canceled.
+type progress struct {
+	loaded   uint64
+	total    uint64
+	canceled int32
+	lock     sync.Mutex
+	// cancel is closed when the progress is canceled.
+	cancel chan struct{}
+}
+
+// archive loads a tar, tgz, zip or raw file into a volume.
+type archive struct {
+	metadata
+	progress
+	uri      string
+	format   string
+	checksum string
+}
+
+func newArchive(uri string, name string, options map[string]string) (*archive, error) {
+	format := options[Format]
+	if len(format) == 0 {
+		format = archiveFormat(name)
+	}
+	switch format {
+	case formatTar, formatTgz, formatZip, formatRaw:
+	default:
+		return nil, fmt.Errorf("Unknown archive format for %s, "+
+			"specify one of tar, tgz, zip or raw with option %q",
+			uri, Format)
+	}
+	checksum := options[Checksum]
+	if len(checksum) != 0 {
+		if _, _, err := parseChecksum(checksum); err != nil {
+			return nil, err
+		}
+	}
+	return &archive{uri: uri, format: format, checksum: checksum}, nil
+}
+
+// String representation of this source
+func (a *archive) String() string {
+	return a.uri
+}
+
+// load copies the archive of size bytes from r to a temporary file,
+// verifies its checksum and extracts it into dest.
+func (a *archive) load(r io.Reader, size int64, name string, dest string) error {
+	f, err := ioutil.TempFile("", "seed")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	a.start(size)
+	digest := sha256.New()
+	writers := []io.Writer{f, digest, &a.progress}
+	var algorithm, expected string
+	var verify hash.Hash
+	if len(a.checksum) != 0 {
+		algorithm, expected, _ = parseChecksum(a.checksum)
+		verify = newHash(algorithm)
+		writers = append(writers, verify)
+	}
+	if _, err := io.Copy(io.MultiWriter(writers...), r); err != nil {
+		return err
+	}
+	if verify != nil {
+		if actual := hex.EncodeToString(verify.Sum(nil)); actual != expected {
+			return fmt.Errorf("%v: %s is %s:%s, expected %s",
+				ErrChecksum, a.uri, algorithm, actual, a.checksum)
+		}
+	}
+	if _, err := f.Seek(0, 0); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dest, 0755); err != nil {
+		return err
+	}
+	switch a.format {
+	case formatTar:
+		err = extractTar(f, dest)
+	case formatTgz:
+		var zr *gzip.Reader
+		if zr, err = gzip.NewReader(f); err == nil {
+			err = extractTar(zr, dest)
+			zr.Close()
+		}
+	case formatZip:
+		err = extractZip(f.Name(), dest)
+	case formatRaw:
+		err = copyFile(f, filepath.Join(dest, path.Base(name)), 0644)
+	}
+	if err != nil {
+		return err
+	}
+	a.md = Metadata{
+		Source: a.uri,
+		Digest: "sha256:" + hex.EncodeToString(digest.Sum(nil)),
+		Time:   time.Now(),
+	}
+	return nil
+}
+
+// Progress returns the bytes loaded and the total bytes to load.
+func (p *progress) Progress() (uint64, uint64) {
+	return atomic.LoadUint64(&p.loaded), atomic.LoadUint64(&p.total)
+}
+
+// Write counts the bytes in b as loaded.
+func (p *progress) Write(b []byte) (int, error) {
+	if atomic.LoadInt32(&p.canceled) != 0 {
+		return 0, ErrCanceled
+	}
+	atomic.AddUint64(&p.loaded, uint64(len(b)))
+	return len(b), nil
+}
+
+// Cancel fails the writes of the loads in progress.
+func (p *progress) Cancel() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if atomic.LoadInt32(&p.canceled) != 0 {
+		return
+	}
+	atomic.StoreInt32(&p.canceled, 1)
+	if p.cancel != nil {
+		close(p.cancel)
+	}
+}
+
+// isCanceled returns true once the progress is canceled.
+func (p *progress) isCanceled() bool {
+	return atomic.LoadInt32(&p.canceled) != 0
+}
+
+// canceledChan returns a channel that is closed when the progress is
+// canceled, which stops the requests of a load.
+func (p *progress) canceledChan() <-chan struct{} {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.cancel == nil {
+		p.cancel = make(chan struct{})
+		if p.isCanceled() {
+			close(p.cancel)
+		}
+	}
+	return p.cancel
+}
+
+func (p *progress) start(total int64) {
+	atomic.StoreUint64(&p.loaded, 0)
+	if total < 0 {
+		total = 0
+	}
+	atomic.StoreUint64(&p.total, uint64(total))
+}
+
+func archiveFormat(name string) string {
+	switch {
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return formatTgz
+	case strings.HasSuffix(name, ".tar"):
+		return formatTar
+	case strings.HasSuffix(name, ".zip"):
+		return formatZip
+	}
+	return ""
+}
+
+func parseChecksum(checksum string) (string, string, error) {
+	parts := strings.SplitN(checksum, ":", 2)
+	if len(parts) != 2 || newHash(parts[0]) == nil {
+		return "", "", fmt.Errorf("Invalid checksum %q, "+
+			"expected md5:<hex>, sha1:<hex> or sha256:<hex>", checksum)
+	}
+	return parts[0], strings.ToLower(parts[1]), nil
+}
+
+func newHash(algorithm string) hash.Hash {
+	switch algorithm {
+	case "md5":
+		return md5.New()
+	case "sha1":
+		return sha1.New()
+	case "sha256":
+		return sha256.New()
+	}
+	return nil
+}
+
+// within returns true if p is dir or is under dir.
+func within(dir string, p string) bool {
+	dir = filepath.Clean(dir)
+	p = filepath.Clean(p)
+	return p == dir || strings.HasPrefix(p, dir+string(os.PathSeparator))
+}
+
+// target returns the path of name under dest, failing for names that
+// would escape dest, either by name or through the symlinks extracted
+// before.
+func target(dest string, name string) (string, error) {
+	p := filepath.Join(dest, name)
+	if !within(dest, p) {
+		return "", fmt.Errorf("Illegal path %q in archive", name)
+	}
+	if fi, err := os.Lstat(p); err == nil && fi.Mode()&os.ModeSymlink != 0 {
+		return "", fmt.Errorf("Illegal path %q in archive overwrites a link", name)
+	}
+	root, err := filepath.EvalSymlinks(dest)
+	if err != nil {
+		return "", err
+	}
+	// Resolve the longest existing prefix of p, the rest is created.
+	for existing := p; ; existing = filepath.Dir(existing) {
+		resolved, err := filepath.EvalSymlinks(existing)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		if !within(root, resolved) {
+			return "", fmt.Errorf("Illegal path %q in archive", name)
+		}
+		return p, nil
+	}
+}
+
+// checkSymlink fails for a link at p to linkname outside of dest, through
+// which files could be read or written out of dest.
+func checkSymlink(dest string, p string, linkname string) error {
+	if filepath.IsAbs(linkname) ||
+		!within(dest, filepath.Join(filepath.Dir(p), linkname)) {
+		return fmt.Errorf("Illegal link %q in archive", linkname)
+	}
+	return nil
+}
+
+func extractTar(r io.Reader, dest string) error {
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		p, err := target(dest, header.Name)
+		if err != nil {
+			return err
+		}
+		mode := os.FileMode(header.Mode).Perm()
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(p, mode)
+		case tar.TypeReg, tar.TypeRegA:
+			err = copyFile(tr, p, mode)
+		case tar.TypeSymlink:
+			if err = checkSymlink(dest, p, header.Linkname); err != nil {
+				break
+			}
+			if err = os.MkdirAll(filepath.Dir(p), 0755); err == nil {
+				err = os.Symlink(header.Linkname, p)
+			}
+		case tar.TypeLink:
+			var link string
+			if link, err = target(dest, header.Linkname); err == nil {
+				err = os.Link(link, p)
+			}
+		default:
+			// Devices and fifos are not seeded.
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func extractZip(name string, dest string) error {
+	zr, err := zip.OpenReader(name)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+	for _, f := range zr.File {
+		p, err := target(dest, f.Name)
+		if err != nil {
+			return err
+		}
+		if f.FileInfo().IsDir() {
+			if err := os.MkdirAll(p, f.Mode().Perm()); err != nil {
+				return err
+			}
+			continue
+		}
+		r, err := f.Open()
+		if err != nil {
+			return err
+		}
+		err = copyFile(r, p, f.Mode().Perm())
+		r.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func copyFile(r io.Reader, dest string, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(dest, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,68 @@
+package seed
+
+import (
+	"archive/tar"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testTar(t *testing.T, headers ...*tar.Header) *bytes.Buffer {
+	var b bytes.Buffer
+	tw := tar.NewWriter(&b)
+	for _, h := range headers {
+		require.NoError(t, tw.WriteHeader(h))
+		if h.Size != 0 {
+			_, err := tw.Write(bytes.Repeat([]byte("a"), int(h.Size)))
+			require.NoError(t, err)
+		}
+	}
+	require.NoError(t, tw.Close())
+	return &b
+}
+
+func TestExtractTarLinks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "seed_archive")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	dest := filepath.Join(dir, "dest")
+	require.NoError(t, os.Mkdir(dest, 0755))
+
+	require.NoError(t, extractTar(testTar(t,
+		&tar.Header{Name: "dir/", Mode: 0755, Typeflag: tar.TypeDir},
+		&tar.Header{Name: "dir/a", Mode: 0644, Size: 1, Typeflag: tar.TypeReg},
+		&tar.Header{Name: "link", Linkname: "dir/a", Typeflag: tar.TypeSymlink},
+		&tar.Header{Name: "dir/up", Linkname: "../link", Typeflag: tar.TypeSymlink},
+	), dest))
+	b, err := ioutil.ReadFile(filepath.Join(dest, "dir", "up"))
+	require.NoError(t, err)
+	assert.Equal(t, "a", string(b))
+
+	for _, headers := range [][]*tar.Header{
+		{{Name: "abs", Linkname: "/etc", Typeflag: tar.TypeSymlink}},
+		{{Name: "up", Linkname: "../", Typeflag: tar.TypeSymlink}},
+		// Links that point out of dest through other links.
+		{
+			{Name: "self", Linkname: ".", Typeflag: tar.TypeSymlink},
+			{Name: "up", Linkname: "self/..", Typeflag: tar.TypeSymlink},
+			{Name: "up/escaped", Mode: 0644, Size: 1, Typeflag: tar.TypeReg},
+		},
+		{
+			{Name: "x", Linkname: "dir/a", Typeflag: tar.TypeSymlink},
+			{Name: "x", Mode: 0644, Size: 1, Typeflag: tar.TypeReg},
+		},
+	} {
+		d, err := ioutil.TempDir(dest, "bad")
+		require.NoError(t, err)
+		assert.Error(t, extractTar(testTar(t, headers...), d), headers[0].Name)
+	}
+	_, err = os.Stat(filepath.Join(dest, "escaped"))
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(filepath.Join(dir, "escaped"))
+	assert.True(t, os.IsNotExist(err))
+}
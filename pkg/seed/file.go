@@ -0,0 +1,162 @@
+package seed
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrFileRoot is returned for a file source out of the roots that file
+	// sources may be loaded from.
+	ErrFileRoot = errors.New("Seed path is not in a seed root of this host")
+
+	fileRootsLock sync.Mutex
+	// fileRoots are the host directories file sources may be loaded from.
+	fileRoots []string
+)
+
+// SetFileRoots sets the host directories that file sources may be loaded
+// from. File sources are disabled until roots are set, so that seeds cannot
+// copy any host file into a volume.
+func SetFileRoots(roots []string) {
+	fileRootsLock.Lock()
+	defer fileRootsLock.Unlock()
+	fileRoots = append([]string(nil), roots...)
+}
+
+// checkFileRoot returns p with its symlinks resolved, or ErrFileRoot if it is
+// not in a file root.
+func checkFileRoot(p string) (string, error) {
+	resolved, err := filepath.EvalSymlinks(p)
+	if err != nil {
+		return "", err
+	}
+	fileRootsLock.Lock()
+	defer fileRootsLock.Unlock()
+	for _, root := range fileRoots {
+		root, err := filepath.EvalSymlinks(root)
+		if err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(root, resolved)
+		if err == nil && rel != ".." &&
+			!strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return resolved, nil
+		}
+	}
+	return "", ErrFileRoot
+}
+
+// fileSource loads a local directory or archive in a file root.
+type fileSource struct {
+	*archive
+	path string
+}
+
+func newFileSource(u *url.URL, options map[string]string) (Source, error) {
+	if _, err := checkFileRoot(u.Path); err != nil {
+		return nil, err
+	}
+	fi, err := os.Stat(u.Path)
+	if err != nil {
+		return nil, err
+	}
+	if fi.IsDir() {
+		if len(options[Checksum]) != 0 {
+			return nil, fmt.Errorf("Option %q is not supported for directory %s",
+				Checksum, u.Path)
+		}
+		return &fileSource{archive: &archive{uri: u.String()}, path: u.Path}, nil
+	}
+	a, err := newArchive(u.String(), u.Path, options)
+	if err != nil {
+		return nil, err
+	}
+	return &fileSource{archive: a, path: u.Path}, nil
+}
+
+// Load from URI into dest. The path is checked again, in case a link in it
+// changed since the source was created.
+func (f *fileSource) Load(dest string) error {
+	resolved, err := checkFileRoot(f.path)
+	if err != nil {
+		return err
+	}
+	if len(f.format) != 0 {
+		file, err := os.Open(resolved)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		fi, err := file.Stat()
+		if err != nil {
+			return err
+		}
+		return f.load(file, fi.Size(), f.path, dest)
+	}
+	size, err := dirSize(resolved)
+	if err != nil {
+		return err
+	}
+	f.start(size)
+	if err := copyDir(resolved, dest, &f.progress); err != nil {
+		return err
+	}
+	f.md = Metadata{Source: f.uri, Time: time.Now()}
+	return nil
+}
+
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.Walk(dir, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.Mode().IsRegular() {
+			size += fi.Size()
+		}
+		return nil
+	})
+	return size, err
+}
+
+// copyDir copies the directory src to dest, writing the copied file
+// contents to w as well.
+func copyDir(src string, dest string, w io.Writer) error {
+	return filepath.Walk(src, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		destPath := filepath.Join(dest, rel)
+		switch {
+		case fi.IsDir():
+			return os.MkdirAll(destPath, fi.Mode().Perm())
+		case fi.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(p)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, destPath)
+		case fi.Mode().IsRegular():
+			file, err := os.Open(p)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			return copyFile(io.TeeReader(file, w), destPath, fi.Mode().Perm())
+		}
+		// Devices, sockets and fifos are not seeded.
+		return nil
+	})
+}
@@ -0,0 +1,83 @@
+package seed
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileLoad(t *testing.T) {
+	src, err := ioutil.TempDir("", "seed_src")
+	require.NoError(t, err)
+	defer os.RemoveAll(src)
+	dest, err := ioutil.TempDir("", "seed_dest")
+	require.NoError(t, err)
+	defer os.RemoveAll(dest)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(src, "a", "b"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(src, "a", "b", "c"), []byte("c"), 0644))
+	SetFileRoots([]string{src})
+	defer SetFileRoots(nil)
+
+	_, err = New("file://"+src, map[string]string{Checksum: "sha256:00"})
+	assert.Error(t, err, "checksum of a directory should fail")
+
+	s, err := New("file://"+src, nil)
+	require.NoError(t, err, "New")
+	require.NoError(t, s.Load(dest), "Load")
+	b, err := ioutil.ReadFile(filepath.Join(dest, "a", "b", "c"))
+	require.NoError(t, err)
+	assert.Equal(t, "c", string(b))
+
+	require.NoError(t, s.MetadataWrite(dest), "MetadataWrite")
+	md, err := s.MetadataRead(dest)
+	require.NoError(t, err, "MetadataRead")
+	assert.Contains(t, md, "file://"+src)
+}
+
+func TestFileLoadCancel(t *testing.T) {
+	src, err := ioutil.TempDir("", "seed_src")
+	require.NoError(t, err)
+	defer os.RemoveAll(src)
+	dest, err := ioutil.TempDir("", "seed_dest")
+	require.NoError(t, err)
+	defer os.RemoveAll(dest)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(src, "a"), []byte("a"), 0644))
+	SetFileRoots([]string{src})
+	defer SetFileRoots(nil)
+
+	s, err := New("file://"+src, nil)
+	require.NoError(t, err, "New")
+	s.(Canceler).Cancel()
+	assert.Equal(t, ErrCanceled, s.Load(dest))
+}
+
+func TestFileRoots(t *testing.T) {
+	dir, err := ioutil.TempDir("", "seed_roots")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	root := filepath.Join(dir, "root")
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "data"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "secret"), 0755))
+	require.NoError(t, os.Symlink("../secret", filepath.Join(root, "link")))
+
+	_, err = New("file://"+filepath.Join(root, "data"), nil)
+	assert.Equal(t, ErrFileRoot, err, "File sources should be disabled by default")
+
+	SetFileRoots([]string{root})
+	defer SetFileRoots(nil)
+	_, err = New("file://"+filepath.Join(root, "data"), nil)
+	assert.NoError(t, err)
+	for _, p := range []string{
+		filepath.Join(dir, "secret"),
+		filepath.Join(root, "..", "secret"),
+		filepath.Join(root, "link"),
+	} {
+		_, err = New("file://"+p, nil)
+		assert.Equal(t, ErrFileRoot, err, p)
+	}
+}
@@ -5,11 +5,14 @@ import (
 	"net/url"
 	"os/exec"
 	"path"
+	"strings"
+	"time"
 )
 
 const GitRevision = "revision"
 
 type Git struct {
+	metadata
 	host     string
 	revision string
 	ready    bool
@@ -29,35 +32,34 @@ func (g *Git) Load(dest string) error {
 	if output, err := cmd.CombinedOutput(); err != nil {
 		return fmt.Errorf("'wd: %s git clone %s': %s: %s", dest, g.host, output, err)
 	}
-	if len(g.revision) == 0 {
-		g.ready = true
-		return nil
+	if len(g.revision) != 0 {
+		cmd = exec.Command("git", "checkout", g.revision)
+		cmd.Dir = dest
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("wd %v 'git checkout %s': %s: %s", cmd.Dir, g.revision, output, err)
+		}
+		cmd = exec.Command("git", "reset", "--hard")
+		cmd.Dir = dest
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("wd %v 'git reset --hard  %s': %s: %s", cmd.Dir, g.revision, output, err)
+		}
 	}
-	cmd = exec.Command("git", "checkout", g.revision)
+	cmd = exec.Command("git", "rev-parse", "HEAD")
 	cmd.Dir = dest
-	if output, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("wd %v 'git checkout %s': %s: %s", cmd.Dir, g.revision, output, err)
+	output, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("wd %v 'git rev-parse HEAD': %s", cmd.Dir, err)
 	}
-	cmd = exec.Command("git", "reset", "--hard")
-	cmd.Dir = dest
-	if output, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("wd %v 'git reset --hard  %s': %s: %s", cmd.Dir, g.revision, output, err)
+	g.md = Metadata{
+		Source:   g.String(),
+		Revision: strings.TrimSpace(string(output)),
+		Time:     time.Now(),
 	}
 
 	g.ready = true
 	return nil
 }
 
-// Metadata for this source.
-func (g *Git) MetadataRead(mdDir string) (string, error) {
-	return "", nil
-}
-
-// MetadataWrite for this source.
-func (g *Git) MetadataWrite(mdDir string) error {
-	return nil
-}
-
 func NewGitSource(uri string, options map[string]string) (Source, error) {
 	u, err := url.Parse(uri)
 	if err != nil {
@@ -0,0 +1,77 @@
+package seed
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// seedClient downloads the archives. Downloads may take long, so only
+// connecting and waiting for the response time out, and a stalled download
+// is stopped by canceling its seed.
+var seedClient = &http.Client{
+	Transport: &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		Dial: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).Dial,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: time.Minute,
+	},
+}
+
+// httpSource loads an archive from an http(s) URL.
+type httpSource struct {
+	*archive
+	url string
+}
+
+func newHTTPSource(u *url.URL, options map[string]string) (Source, error) {
+	a, err := newArchive(u.String(), u.Path, options)
+	if err != nil {
+		return nil, err
+	}
+	return &httpSource{archive: a, url: u.String()}, nil
+}
+
+// Load from URI into dest.
+func (h *httpSource) Load(dest string) error {
+	return loadURL(h.archive, h.url, dest)
+}
+
+// loadURL loads the archive at rawurl into dest. Canceling the archive
+// stops the download.
+func loadURL(a *archive, rawurl string, dest string) error {
+	req, err := http.NewRequest("GET", rawurl, nil)
+	if err != nil {
+		return err
+	}
+	req.Cancel = a.canceledChan()
+	resp, err := seedClient.Do(req)
+	if a.isCanceled() {
+		if err == nil {
+			resp.Body.Close()
+		}
+		return ErrCanceled
+	} else if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Failed to get %s: %s", a.uri, resp.Status)
+	}
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return err
+	}
+	if err := a.load(resp.Body, resp.ContentLength, u.Path, dest); err != nil {
+		if a.isCanceled() {
+			return ErrCanceled
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package seed
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testTarball(t *testing.T) []byte {
+	var b bytes.Buffer
+	zw := gzip.NewWriter(&b)
+	tw := tar.NewWriter(zw)
+	content := []byte("hello")
+	require.NoError(t, tw.WriteHeader(&tar.Header{
+		Name:     "dir/",
+		Mode:     0755,
+		Typeflag: tar.TypeDir,
+	}))
+	require.NoError(t, tw.WriteHeader(&tar.Header{
+		Name:     "dir/hello.txt",
+		Mode:     0644,
+		Size:     int64(len(content)),
+		Typeflag: tar.TypeReg,
+	}))
+	_, err := tw.Write(content)
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+	require.NoError(t, zw.Close())
+	return b.Bytes()
+}
+
+func TestHTTPLoad(t *testing.T) {
+	tarball := testTarball(t)
+	sum := sha256.Sum256(tarball)
+	digest := "sha256:" + hex.EncodeToString(sum[:])
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			w.Write(tarball)
+		}))
+	defer server.Close()
+
+	dest, err := ioutil.TempDir("", "seed_http")
+	require.NoError(t, err)
+	defer os.RemoveAll(dest)
+
+	s, err := New(server.URL+"/seed.tar.gz", map[string]string{Checksum: digest})
+	require.NoError(t, err, "New")
+	require.NoError(t, s.Load(dest), "Load")
+	b, err := ioutil.ReadFile(filepath.Join(dest, "dir", "hello.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(b))
+
+	require.NoError(t, s.MetadataWrite(dest), "MetadataWrite")
+	md, err := ReadMetadata(dest)
+	require.NoError(t, err, "ReadMetadata")
+	assert.Equal(t, server.URL+"/seed.tar.gz", md.Source)
+	assert.Equal(t, digest, md.Digest)
+
+	s, err = New(server.URL+"/seed.tar.gz", map[string]string{Checksum: "sha256:00"})
+	require.NoError(t, err, "New")
+	assert.Error(t, s.Load(dest), "Load should fail checksum verification")
+
+	_, err = New(server.URL+"/seed.bin", nil)
+	assert.Error(t, err, "unknown format should fail")
+}
+
+func TestHTTPLoadCancel(t *testing.T) {
+	stalled := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Length", "1048576")
+			w.Write(make([]byte, 512))
+			w.(http.Flusher).Flush()
+			<-stalled
+		}))
+	defer server.Close()
+	defer close(stalled)
+
+	dest, err := ioutil.TempDir("", "seed_http")
+	require.NoError(t, err)
+	defer os.RemoveAll(dest)
+
+	s, err := New(server.URL+"/seed.tar", nil)
+	require.NoError(t, err, "New")
+	done := make(chan error)
+	go func() {
+		done <- s.Load(dest)
+	}()
+	time.Sleep(100 * time.Millisecond)
+	s.(Canceler).Cancel()
+	select {
+	case err := <-done:
+		assert.Equal(t, ErrCanceled, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Canceling should stop a stalled download")
+	}
+}
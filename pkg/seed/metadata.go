@@ -0,0 +1,47 @@
+package seed
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// metadata implements the metadata methods of Source. Sources fill in
+// md when they are loaded.
+type metadata struct {
+	md Metadata
+}
+
+// MetadataRead returns the metadata in mdDir as JSON.
+func (m *metadata) MetadataRead(mdDir string) (string, error) {
+	b, err := ioutil.ReadFile(filepath.Join(mdDir, MetadataFile))
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// MetadataWrite writes the metadata of the last load to mdDir.
+func (m *metadata) MetadataWrite(mdDir string) error {
+	b, err := json.Marshal(&m.md)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(mdDir, 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(mdDir, MetadataFile), b, 0644)
+}
+
+func readMetadata(mdDir string) (*Metadata, error) {
+	b, err := ioutil.ReadFile(filepath.Join(mdDir, MetadataFile))
+	if err != nil {
+		return nil, err
+	}
+	var md Metadata
+	if err := json.Unmarshal(b, &md); err != nil {
+		return nil, err
+	}
+	return &md, nil
+}
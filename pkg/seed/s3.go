@@ -0,0 +1,95 @@
+package seed
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	clientmetadata "github.com/aws/aws-sdk-go/aws/client/metadata"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/private/signer/v4"
+)
+
+const (
+	s3DefaultRegion = "us-east-1"
+	s3PresignExpiry = 15 * time.Minute
+)
+
+// s3Source loads an archive from an S3 object at s3://bucket/key.
+type s3Source struct {
+	*archive
+	bucket string
+	key    string
+	config *aws.Config
+}
+
+func newS3Source(u *url.URL, options map[string]string) (Source, error) {
+	key := strings.TrimPrefix(u.Path, "/")
+	if len(u.Host) == 0 || len(key) == 0 {
+		return nil, fmt.Errorf("Invalid S3 URI %s, expected s3://bucket/key", u)
+	}
+	a, err := newArchive(u.String(), key, options)
+	if err != nil {
+		return nil, err
+	}
+	region := options[S3Region]
+	if len(region) == 0 {
+		region = s3DefaultRegion
+	}
+	// Options are stored with the volume, so credentials are not taken
+	// from them but from the environment, shared credentials file or
+	// instance role of the daemon.
+	return &s3Source{
+		archive: a,
+		bucket:  u.Host,
+		key:     key,
+		config:  &aws.Config{Region: aws.String(region)},
+	}, nil
+}
+
+// Load from URI into dest.
+func (s *s3Source) Load(dest string) error {
+	rawurl, err := s.presign()
+	if err != nil {
+		return err
+	}
+	return loadURL(s.archive, rawurl, dest)
+}
+
+// presign returns a URL that reads the object without further credentials.
+// Public objects are read with a plain URL if no credentials are found.
+func (s *s3Source) presign() (string, error) {
+	sess := session.New(s.config)
+	region := aws.StringValue(sess.Config.Region)
+	endpoint := "https://s3.amazonaws.com"
+	if region != s3DefaultRegion {
+		endpoint = fmt.Sprintf("https://s3-%s.amazonaws.com", region)
+	}
+	if _, err := sess.Config.Credentials.Get(); err != nil {
+		return endpoint + "/" + s.bucket + "/" + s.key, nil
+	}
+	handlers := request.Handlers{}
+	handlers.Sign.PushBack(v4.Sign)
+	r := request.New(
+		*sess.Config,
+		clientmetadata.ClientInfo{
+			ServiceName:   "s3",
+			SigningRegion: region,
+			Endpoint:      endpoint,
+			APIVersion:    "2006-03-01",
+		},
+		handlers,
+		nil,
+		&request.Operation{
+			Name:       "GetObject",
+			HTTPMethod: "GET",
+			HTTPPath:   "/" + s.bucket + "/" + s.key,
+		},
+		nil,
+		nil,
+	)
+	return r.Presign(s3PresignExpiry)
+}
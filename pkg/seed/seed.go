@@ -3,6 +3,7 @@ package seed
 import (
 	"errors"
 	"net/url"
+	"time"
 )
 
 // Source defines the interface for keep track of volume driver mounts.
@@ -17,9 +18,52 @@ type Source interface {
 	MetadataWrite(mdDir string) error
 }
 
+// Progress is implemented by sources that report the progress of a Load.
+type Progress interface {
+	// Progress returns the number of bytes loaded so far and the total
+	// number of bytes to load, or 0 if the total is not known.
+	Progress() (uint64, uint64)
+}
+
+// Canceler is implemented by sources whose Load can be canceled.
+type Canceler interface {
+	// Cancel makes a Load in progress, or the next one, fail with
+	// ErrCanceled.
+	Cancel()
+}
+
+// Metadata describes what a volume was seeded from.
+type Metadata struct {
+	// Source is the seed URI.
+	Source string `json:"source"`
+	// Revision of the source, if the source is versioned.
+	Revision string `json:"revision,omitempty"`
+	// Digest of the loaded archive in algorithm:hex form.
+	Digest string `json:"digest,omitempty"`
+	// Time the source was loaded.
+	Time time.Time `json:"time"`
+}
+
+const (
+	// MetadataFile is the name of the file in mdDir that holds the Metadata.
+	MetadataFile = ".seed"
+	// Checksum option verifies the loaded archive. The value is in
+	// algorithm:hex form, where algorithm is one of md5, sha1 or sha256.
+	Checksum = "checksum"
+	// Format option overrides the archive format derived from the URI.
+	// One of tar, tgz, zip or raw.
+	Format = "format"
+	// S3Region option is the region of an S3 bucket.
+	S3Region = "region"
+)
+
 var (
 	// ErrUnsupported is returned for an unsupported seed source.
 	ErrUnsupported = errors.New("Not supported")
+	// ErrChecksum is returned if a loaded archive does not match its checksum.
+	ErrChecksum = errors.New("Checksum mismatch")
+	// ErrCanceled is returned by a Load that was canceled.
+	ErrCanceled = errors.New("Seed canceled")
 )
 
 // New returns a new instance of Source
@@ -31,6 +75,17 @@ func New(uri string, options map[string]string) (Source, error) {
 	switch u.Scheme {
 	case "github":
 		return NewGitSource(uri, options)
+	case "http", "https":
+		return newHTTPSource(u, options)
+	case "file":
+		return newFileSource(u, options)
+	case "s3":
+		return newS3Source(u, options)
 	}
 	return nil, ErrUnsupported
 }
+
+// ReadMetadata returns the Metadata written by Source.MetadataWrite to mdDir.
+func ReadMetadata(mdDir string) (*Metadata, error) {
+	return readMetadata(mdDir)
+}
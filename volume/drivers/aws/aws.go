@@ -5,12 +5,9 @@ import (
 	"io/ioutil"
 	"net/http"
 	"os"
-	"os/exec"
-	"syscall"
 	"time"
 
 	"go.pedge.io/dlog"
-	"go.pedge.io/proto/time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
@@ -18,9 +15,9 @@ import (
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/aws/aws-sdk-go/service/opsworks"
 	"github.com/libopenstorage/openstorage/api"
-	"github.com/libopenstorage/openstorage/pkg/chaos"
+	"github.com/libopenstorage/openstorage/pkg/device"
 	"github.com/libopenstorage/openstorage/volume"
-	"github.com/libopenstorage/openstorage/volume/drivers/common"
+	"github.com/libopenstorage/openstorage/volume/drivers/cloud"
 	"github.com/portworx/kvdb"
 )
 
@@ -28,7 +25,7 @@ const (
 	// Name of the driver
 	Name = "aws"
 	// Type of the driver
-	Type = api.DriverType_DRIVER_TYPE_BLOCK
+	Type = cloud.Type
 	// AwsDBKey for openstorage
 	AwsDBKey = "OpenStorageAWSKey"
 	// awsAccessKeyID identifier for authentication.
@@ -37,22 +34,14 @@ const (
 	awsSecretAccessKey = "AWS_SECRET_ACCESS_KEY"
 )
 
-var (
-	koStrayCreate = chaos.Add("aws", "create", "create in driver before DB")
-	koStrayDelete = chaos.Add("aws", "delete", "create in driver before DB")
-)
-
 // Metadata for the driver
 type Metadata struct {
 	zone     string
 	instance string
 }
 
-// Driver implements VolumeDriver interface
-type Driver struct {
-	volume.StatsDriver
-	volume.StoreEnumerator
-	volume.IODriver
+// provider adapts the EBS volumes of StorageOps to the cloud driver.
+type provider struct {
 	ops StorageOps
 	md  *Metadata
 }
@@ -83,15 +72,24 @@ func Init(params map[string]string) (volume.VolumeDriver, error) {
 			},
 		),
 	)
-	d := &Driver{
-		StatsDriver: volume.StatsNotSupported,
-		ops:         NewEc2Storage(instance, ec2),
-		md: &Metadata{
-			zone:     zone,
-			instance: instance,
-		},
-		IODriver:        volume.IONotSupported,
-		StoreEnumerator: common.NewDefaultStoreEnumerator(Name, kvdb.Instance()),
+	devices, err := device.NewAllocator(
+		kvdb.Instance(),
+		instance,
+		Name,
+		Devices,
+		device.Present,
+	)
+	if err != nil {
+		return nil, err
+	}
+	d, err := cloud.NewDriver(
+		Name,
+		NewProvider(NewEc2Storage(instance, ec2, devices), zone, instance),
+		&cloud.ExecRunner{},
+		nil,
+	)
+	if err != nil {
+		return nil, err
 	}
 	return d, nil
 }
@@ -159,296 +157,205 @@ func metadata(key string) (string, error) {
 	return string(body), nil
 }
 
-// Name returns the name of the driver
-func (d *Driver) Name() string {
-	return Name
+// NewProvider returns the cloud provider for the EBS volumes of ops in the
+// zone of instance.
+func NewProvider(ops StorageOps, zone string, instance string) cloud.Provider {
+	return &provider{
+		ops: ops,
+		md: &Metadata{
+			zone:     zone,
+			instance: instance,
+		},
+	}
 }
 
-// Type returns the type of the driver
-func (d *Driver) Type() api.DriverType {
-	return Type
+func (p *provider) Instance() string {
+	return p.md.instance
 }
 
-// Status returns the current status
-func (d *Driver) Status() [][2]string {
-	return [][2]string{}
+func (p *provider) DiskType(cos api.CosType) (string, int64) {
+	iops, volType := mapCos(uint32(cos))
+	// Gp2 Volumes don't support the iops parameter
+	if *volType == opsworks.VolumeTypeGp2 {
+		return *volType, 0
+	}
+	return *volType, *iops
 }
 
-// Create creates a new volume
-func (d *Driver) Create(
-	locator *api.VolumeLocator,
-	source *api.Source,
-	spec *api.VolumeSpec,
-) (string, error) {
-	var snapID *string
-	// Spec size is in bytes, translate to GiB.
-	sz := int64(spec.Size / (1024 * 1024 * 1024))
-	iops, volType := mapCos(uint32(spec.Cos))
-	if source != nil && string(source.Parent) != "" {
-		id := string(source.Parent)
-		snapID = &id
+// disk translates an EBS volume to a disk.
+func (p *provider) disk(vol *ec2.Volume) *cloud.Disk {
+	d := &cloud.Disk{
+		Id:     *vol.VolumeId,
+		Labels: p.ops.Tags(vol),
 	}
-	ec2Vol := &ec2.Volume{
-		AvailabilityZone: &d.md.zone,
-		VolumeType:       volType,
-		SnapshotId:       snapID,
-		Size:             &sz,
+	if vol.AvailabilityZone != nil {
+		d.Zone = *vol.AvailabilityZone
 	}
-
-	// Gp2 Volumes don't support the iops parameter
-	if *volType != opsworks.VolumeTypeGp2 {
-		ec2Vol.Iops = iops
+	if vol.Size != nil {
+		d.Size = *vol.Size
 	}
-	vol, err := d.ops.Create(ec2Vol, locator.VolumeLabels)
-	if err != nil {
-		dlog.Warnf("Failed in CreateVolumeRequest :%v", err)
-		return "", err
+	if vol.VolumeType != nil {
+		d.Type = *vol.VolumeType
 	}
-	volume := common.NewVolume(
-		*vol.VolumeId,
-		api.FSType_FS_TYPE_EXT4,
-		locator,
-		source,
-		spec,
-	)
-	err = d.UpdateVol(volume)
-	if err != nil {
-		return "", err
+	if vol.Iops != nil {
+		d.Iops = *vol.Iops
 	}
-	if _, err := d.Attach(volume.Id, nil); err != nil {
-		return "", err
+	if vol.SnapshotId != nil {
+		d.Snapshot = *vol.SnapshotId
 	}
-
-	dlog.Infof("aws preparing volume %s...", *vol.VolumeId)
-	if err := d.Format(volume.Id); err != nil {
-		return "", err
+	state := ""
+	if vol.State != nil {
+		state = *vol.State
 	}
-	if err := d.Detach(volume.Id); err != nil {
-		return "", err
-	}
-
-	return volume.Id, err
-}
-
-// merge volume properties from aws into volume.
-func (d *Driver) merge(v *api.Volume, aws *ec2.Volume) {
-	v.AttachedOn = ""
-	v.State = api.VolumeState_VOLUME_STATE_DETACHED
-	v.DevicePath = ""
-
-	switch *aws.State {
+	switch state {
 	case ec2.VolumeStateAvailable:
-		v.Status = api.VolumeStatus_VOLUME_STATUS_UP
+		d.State = cloud.DiskAvailable
 	case ec2.VolumeStateCreating, ec2.VolumeStateDeleting:
-		v.State = api.VolumeState_VOLUME_STATE_PENDING
-		v.Status = api.VolumeStatus_VOLUME_STATUS_DOWN
+		d.State = cloud.DiskPending
 	case ec2.VolumeStateDeleted:
-		v.State = api.VolumeState_VOLUME_STATE_DELETED
-		v.Status = api.VolumeStatus_VOLUME_STATUS_DOWN
-	case ec2.VolumeStateError:
-		v.State = api.VolumeState_VOLUME_STATE_ERROR
-		v.Status = api.VolumeStatus_VOLUME_STATUS_DOWN
+		d.State = cloud.DiskDeleted
 	case ec2.VolumeStateInUse:
-		v.Status = api.VolumeStatus_VOLUME_STATUS_UP
-		if aws.Attachments != nil && len(aws.Attachments) != 0 {
-			if aws.Attachments[0].InstanceId != nil {
-				v.AttachedOn = *aws.Attachments[0].InstanceId
-			}
-			if aws.Attachments[0].State != nil {
-				v.State = d.volumeState(aws.Attachments[0].State)
-			}
-			if aws.Attachments[0].Device != nil {
-				v.DevicePath = *aws.Attachments[0].Device
+		d.State = cloud.DiskAvailable
+		if len(vol.Attachments) != 0 {
+			if vol.Attachments[0].InstanceId != nil {
+				d.Instance = *vol.Attachments[0].InstanceId
 			}
+			d.State = p.attachmentState(vol.Attachments[0].State)
 		}
+	default:
+		d.State = cloud.DiskError
 	}
+	return d
 }
 
-// Inspect insepcts a volume
-func (d *Driver) Inspect(volumeIDs []string) ([]*api.Volume, error) {
-	vols, err := d.StoreEnumerator.Inspect(volumeIDs)
-	if err != nil {
-		return nil, err
-	}
-	ids := make([]*string, len(vols))
-	for i, v := range vols {
-		id := v.Id
-		ids[i] = &id
-	}
-	volumeMap, err := d.ops.Enumerate(ids, nil, "")
-	if err != nil {
-		return nil, err
-	}
-	if len(volumeMap) != 1 {
-		return nil, fmt.Errorf("Inspect volumeMap mismatch")
+func (p *provider) attachmentState(ec2VolState *string) cloud.DiskState {
+	if ec2VolState == nil {
+		return cloud.DiskAvailable
 	}
-	for _, awsVols := range volumeMap {
-		if len(awsVols) != len(vols) {
-			return nil, fmt.Errorf("Inspect volume count mismatch")
-		}
-		for i, v := range awsVols {
-			if string(vols[i].Id) != *v.VolumeId {
-				d.merge(vols[i], v)
-			}
-		}
+	switch *ec2VolState {
+	case ec2.VolumeAttachmentStateAttached:
+		return cloud.DiskAttached
+	case ec2.VolumeAttachmentStateDetached:
+		return cloud.DiskAvailable
+	case ec2.VolumeAttachmentStateAttaching:
+		return cloud.DiskAttaching
+	case ec2.VolumeAttachmentStateDetaching:
+		return cloud.DiskDetaching
+	default:
+		dlog.Warnf("Failed to translate EC2 volume status %v", *ec2VolState)
 	}
-	return vols, nil
+	return cloud.DiskError
 }
 
-func (d *Driver) Delete(volumeID string) error {
-	if err := d.ops.Delete(volumeID); err != nil {
-		return err
+func (p *provider) Create(template *cloud.Disk, labels map[string]string) (*cloud.Disk, error) {
+	volType := template.Type
+	ec2Vol := &ec2.Volume{
+		AvailabilityZone: &p.md.zone,
+		VolumeType:       &volType,
 	}
-	return d.DeleteVol(volumeID)
-}
-
-func (d *Driver) Snapshot(
-	volumeID string,
-	readonly bool,
-	locator *api.VolumeLocator,
-) (string, error) {
-	vols, err := d.StoreEnumerator.Inspect([]string{volumeID})
-	if err != nil {
-		return "", err
+	if template.Size != 0 {
+		size := template.Size
+		ec2Vol.Size = &size
 	}
-	if len(vols) != 1 {
-		return "", fmt.Errorf("Failed to inspect %v len %v", volumeID, len(vols))
+	if template.Iops != 0 {
+		iops := template.Iops
+		ec2Vol.Iops = &iops
 	}
-	snap, err := d.ops.Snapshot(volumeID, readonly)
-	if err != nil {
-		return "", err
+	if len(template.Snapshot) != 0 {
+		snapID := template.Snapshot
+		ec2Vol.SnapshotId = &snapID
 	}
-	chaos.Now(koStrayCreate)
-	vols[0].Id = *snap.SnapshotId
-	vols[0].Source = &api.Source{Parent: volumeID}
-	vols[0].Locator = locator
-	vols[0].Ctime = prototime.Now()
-
-	chaos.Now(koStrayCreate)
-	if err = d.CreateVol(vols[0]); err != nil {
-		return "", err
+	vol, err := p.ops.Create(ec2Vol, labels)
+	if err != nil {
+		return nil, err
 	}
-	return vols[0].Id, nil
+	return p.disk(vol), nil
 }
 
-func (d *Driver) Attach(
-	volumeID string,
-	attachOptions map[string]string,
-) (string, error) {
-	volume, err := d.GetVol(volumeID)
-	if err != nil {
-		return "", fmt.Errorf("Volume %s could not be located", volumeID)
-	}
-	path, err := d.ops.Attach(volumeID)
-	if err != nil {
-		return "", err
-	}
-	volume.DevicePath = path
-	if err := d.UpdateVol(volume); err != nil {
-		d.ops.Detach(volumeID)
-		return "", err
-	}
-	return path, nil
+func (p *provider) Delete(id string) error {
+	return p.ops.Delete(id)
 }
 
-func (d *Driver) volumeState(ec2VolState *string) api.VolumeState {
-	if ec2VolState == nil {
-		return api.VolumeState_VOLUME_STATE_DETACHED
-	}
-	switch *ec2VolState {
-	case ec2.VolumeAttachmentStateAttached:
-		return api.VolumeState_VOLUME_STATE_ATTACHED
-	case ec2.VolumeAttachmentStateDetached:
-		return api.VolumeState_VOLUME_STATE_DETACHED
-	case ec2.VolumeAttachmentStateAttaching, ec2.VolumeAttachmentStateDetaching:
-		return api.VolumeState_VOLUME_STATE_PENDING
-	default:
-		dlog.Warnf("Failed to translate EC2 volume status %v", ec2VolState)
-	}
-	return api.VolumeState_VOLUME_STATE_ERROR
+func (p *provider) Attach(id string) (string, error) {
+	return p.ops.Attach(id)
 }
 
-func (d *Driver) Format(volumeID string) error {
-	volume, err := d.GetVol(volumeID)
-	if err != nil {
-		return fmt.Errorf("Failed to locate volume %q", volumeID)
-	}
+func (p *provider) Detach(id string) error {
+	return p.ops.Detach(id)
+}
 
-	// XXX: determine mount state
-	awsVol, err := d.ops.Inspect([]*string{&volumeID})
-	if err != nil {
-		return err
+func (p *provider) Inspect(ids []string) ([]*cloud.Disk, error) {
+	volumeIds := make([]*string, len(ids))
+	for i := range ids {
+		volumeIds[i] = &ids[i]
 	}
-	if len(awsVol) != 1 {
-		return fmt.Errorf("Failed to inspect volume %v", volumeID)
-	}
-	devicePath, err := d.ops.DevicePath(awsVol[0])
+	vols, err := p.ops.Inspect(volumeIds)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	cmd := "/sbin/mkfs." + volume.Spec.Format.SimpleString()
-	o, err := exec.Command(cmd, devicePath).Output()
-	if err != nil {
-		dlog.Warnf("Failed to run command %v %v: %v", cmd, devicePath, o)
-		return err
+	disks := make([]*cloud.Disk, len(vols))
+	for i, vol := range vols {
+		disks[i] = p.disk(vol)
 	}
-	volume.Format = volume.Spec.Format
-	return d.UpdateVol(volume)
+	return disks, nil
 }
 
-func (d *Driver) Detach(volumeID string) error {
-	if err := d.ops.Detach(volumeID); err != nil {
-		return err
-	}
-	volume, err := d.GetVol(volumeID)
+func (p *provider) Enumerate(labels map[string]string) ([]*cloud.Disk, error) {
+	sets, err := p.ops.Enumerate(nil, labels, "")
 	if err != nil {
-		dlog.Warnf("Volume %s could not be located, attempting to detach anyway", volumeID)
-	} else {
-		volume.DevicePath = ""
-		if err := d.UpdateVol(volume); err != nil {
-			dlog.Warnf("Failed to update volume", volumeID)
+		return nil, err
+	}
+	var disks []*cloud.Disk
+	for _, vols := range sets {
+		for _, vol := range vols {
+			disks = append(disks, p.disk(vol))
 		}
 	}
-	return nil
+	return disks, nil
 }
 
-func (d *Driver) MountedAt(mountpath string) string {
-	return ""
+func (p *provider) DeviceMappings() (map[string]string, error) {
+	return p.ops.DeviceMappings()
 }
 
-func (d *Driver) Mount(volumeID string, mountpath string) error {
-	volume, err := d.GetVol(volumeID)
-	if err != nil {
-		return fmt.Errorf("Failed to locate volume %q", volumeID)
-	}
-	awsVol, err := d.ops.Inspect([]*string{&volumeID})
-	if err != nil {
-		return err
-	}
-	if len(awsVol) != 1 {
-		return fmt.Errorf("Failed to inspect volume %v", volumeID)
-	}
-	devicePath, err := d.ops.DevicePath(awsVol[0])
-	if err != nil {
-		return err
-	}
-	err = syscall.Mount(devicePath, mountpath, volume.Spec.Format.SimpleString(), 0, "")
+func (p *provider) Snapshot(id string) (string, error) {
+	snap, err := p.ops.Snapshot(id, false)
 	if err != nil {
-		return err
+		return "", err
 	}
-	return nil
+	return *snap.SnapshotId, nil
+}
+
+func (p *provider) IsSnapshot(id string) bool {
+	return isSnapshot(id)
 }
 
-func (d *Driver) Unmount(volumeID string, mountpath string) error {
-	// XXX:  determine if valid mount path
-	err := syscall.Unmount(mountpath, 0)
-	return err
+func (p *provider) ApplyTags(id string, labels map[string]string) error {
+	return p.ops.ApplyTags(&ec2.Volume{VolumeId: &id}, labels)
 }
 
-func (d *Driver) Shutdown() {
-	dlog.Printf("%s Shutting down", Name)
+func (p *provider) RemoveTags(id string, labels map[string]string) error {
+	return p.ops.RemoveTags(&ec2.Volume{VolumeId: &id}, labels)
 }
 
-func (d *Driver) Set(volumeID string, locator *api.VolumeLocator, spec *api.VolumeSpec) error {
-	return volume.ErrNotSupported
+func (p *provider) Modify(template *cloud.Disk) (*cloud.Disk, error) {
+	id := template.Id
+	ec2Vol := &ec2.Volume{VolumeId: &id}
+	if template.Size != 0 {
+		size := template.Size
+		ec2Vol.Size = &size
+	}
+	if len(template.Type) != 0 {
+		volType := template.Type
+		ec2Vol.VolumeType = &volType
+	}
+	if template.Iops != 0 {
+		iops := template.Iops
+		ec2Vol.Iops = &iops
+	}
+	vol, err := p.ops.Modify(ec2Vol)
+	if err != nil {
+		return nil, err
+	}
+	return p.disk(vol), nil
 }
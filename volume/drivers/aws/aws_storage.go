@@ -7,21 +7,51 @@ import (
 	"time"
 
 	"github.com/Sirupsen/logrus"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/aws/aws-sdk-go/service/opsworks"
 	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/pkg/device"
 )
 
 type ec2Ops struct {
 	instance string
 	ec2      *ec2.EC2
+	devices  *device.Allocator
 	mutex    sync.Mutex
 }
 
+// Devices are the device names recommended for EBS volumes on Linux
+// instances, /dev/xvdf to /dev/xvdp and then /dev/xvdba to /dev/xvdcz.
+var Devices = device.Chain(
+	device.Letters("/dev/xvd", "f", "p"),
+	device.Letters("/dev/xvd", "ba", "cz"),
+)
+
 const (
 	SetIdentifierNone = "None"
 )
 
+// modifyVolumeInput is the request of the ModifyVolume call, which the
+// vendored EC2 client predates.
+type modifyVolumeInput struct {
+	_          struct{} `type:"structure"`
+	VolumeId   *string  `type:"string" required:"true"`
+	Size       *int64   `type:"integer"`
+	VolumeType *string  `type:"string"`
+	Iops       *int64   `type:"integer"`
+}
+
+// modifyVolumeOutput is the reply of the ModifyVolume call.
+type modifyVolumeOutput struct {
+	_                  struct{} `type:"structure"`
+	VolumeModification *struct {
+		_                 struct{} `type:"structure"`
+		ModificationState *string  `locationName:"modificationState" type:"string"`
+		StatusMessage     *string  `locationName:"statusMessage" type:"string"`
+	} `locationName:"volumeModification" type:"structure"`
+}
+
 // Custom AWS volume error codes.
 const (
 	_ = iota + 5000
@@ -69,6 +99,11 @@ type StorageOps interface {
 	Snapshot(volumeID string, readonly bool) (*ec2.Snapshot, error)
 	// ApplyTags
 	ApplyTags(v *ec2.Volume, labels map[string]string) error
+	// RemoveTags removes the tags with the keys of labels from v.
+	RemoveTags(v *ec2.Volume, labels map[string]string) error
+	// Modify changes the size, type and iops of the volume identified by
+	// template to the values set in template.
+	Modify(template *ec2.Volume) (*ec2.Volume, error)
 	// Tags
 	Tags(v *ec2.Volume) map[string]string
 }
@@ -81,10 +116,13 @@ func (e *StorageError) Error() string {
 	return e.Msg
 }
 
-func NewEc2Storage(instance string, ec2 *ec2.EC2) StorageOps {
+// NewEc2Storage returns the StorageOps of the EBS volumes of instance. EBS
+// volumes are attached at the devices assigned by devices.
+func NewEc2Storage(instance string, ec2 *ec2.EC2, devices *device.Allocator) StorageOps {
 	return &ec2Ops{
 		instance: instance,
 		ec2:      ec2,
+		devices:  devices,
 	}
 }
 
@@ -227,6 +265,23 @@ func (s *ec2Ops) ApplyTags(
 	return err
 }
 
+func (s *ec2Ops) RemoveTags(
+	v *ec2.Volume,
+	labels map[string]string,
+) error {
+	t := make([]*ec2.Tag, 0, len(labels))
+	for k := range labels {
+		key := k
+		t = append(t, &ec2.Tag{Key: &key})
+	}
+	req := &ec2.DeleteTagsInput{
+		Resources: []*string{v.VolumeId},
+		Tags:      t,
+	}
+	_, err := s.ec2.DeleteTags(req)
+	return err
+}
+
 func (s *ec2Ops) matchTag(tag *ec2.Tag, match string) bool {
 	return tag.Key != nil &&
 		tag.Value != nil &&
@@ -293,53 +348,32 @@ func (s *ec2Ops) describe() (*ec2.Instance, error) {
 	return out.Reservations[0].Instances[0], nil
 }
 
-// freeDevices returns list of available device IDs.
-func (s *ec2Ops) freeDevices() ([]string, error) {
-	initial := []byte("fghijklmnop")
-	self, err := s.describe()
+// assignDevice assigns a device that EC2 does not map to a volume of this
+// instance. Devices mapped to volumes attached by others stay assigned.
+func (s *ec2Ops) assignDevice() (string, error) {
+	mappings, err := s.DeviceMappings()
 	if err != nil {
-		return nil, err
+		return "", err
 	}
-	devPrefix := "/dev/sd"
-	for _, dev := range self.BlockDeviceMappings {
-		if dev.DeviceName == nil {
-			return nil, fmt.Errorf("Nil device name")
-		}
-		devName := *dev.DeviceName
-
-		// per AWS docs EC instances have the root mounted at /dev/sda1,
-		// this label should be skipped
-		if devName == "/dev/sda1" {
-			continue
-		}
-		if !strings.HasPrefix(devName, devPrefix) {
-			devPrefix = "/dev/xvd"
-			if !strings.HasPrefix(devName, devPrefix) {
-				return nil, fmt.Errorf("bad device name %q", devName)
-			}
-		}
-		letter := devName[len(devPrefix):]
-		if len(letter) != 1 {
-			return nil, fmt.Errorf("too many letters %q", devName)
-		}
-		index := letter[0] - 'f'
-		if index > ('p' - 'f') {
-			continue
+	for {
+		dev, err := s.devices.Assign()
+		if err != nil {
+			return "", err
 		}
-		initial[index] = '0'
-	}
-	free := make([]string, len(initial))
-	count := 0
-	for _, b := range initial {
-		if b != '0' {
-			free[count] = devPrefix + string(b)
-			count++
+		if _, ok := mappings[dev]; !ok {
+			return dev, nil
 		}
 	}
-	if count == 0 {
-		return nil, fmt.Errorf("No more free devices")
+}
+
+// ec2Device returns the name of dev in an attach request, /dev/sdf for
+// /dev/xvdf. Names with two letters are requested as is.
+func ec2Device(dev string) string {
+	suffix := strings.TrimPrefix(dev, "/dev/xvd")
+	if len(suffix) == 1 {
+		return "/dev/sd" + suffix
 	}
-	return free[:count], nil
+	return dev
 }
 
 func (s *ec2Ops) rollbackCreate(id string, createErr error) error {
@@ -455,25 +489,80 @@ func (s *ec2Ops) Create(
 }
 
 func (s *ec2Ops) Delete(id string) error {
+	if isSnapshot(id) {
+		req := &ec2.DeleteSnapshotInput{SnapshotId: &id}
+		_, err := s.ec2.DeleteSnapshot(req)
+		return err
+	}
 	req := &ec2.DeleteVolumeInput{VolumeId: &id}
 	_, err := s.ec2.DeleteVolume(req)
 	return err
 }
 
+func (s *ec2Ops) Modify(template *ec2.Volume) (*ec2.Volume, error) {
+	req := s.ec2.NewRequest(
+		&request.Operation{
+			Name:       "ModifyVolume",
+			HTTPMethod: "POST",
+			HTTPPath:   "/",
+		},
+		&modifyVolumeInput{
+			VolumeId:   template.VolumeId,
+			Size:       template.Size,
+			VolumeType: template.VolumeType,
+			Iops:       template.Iops,
+		},
+		&modifyVolumeOutput{},
+	)
+	// ModifyVolume was added in this version of the EC2 API.
+	req.ClientInfo.APIVersion = "2016-11-15"
+	if err := req.Send(); err != nil {
+		return nil, err
+	}
+	return s.waitModification(template)
+}
+
+// waitModification waits until the volume reports the size, type and iops
+// of template. Volumes can be used at their new size while EBS optimizes
+// them.
+func (s *ec2Ops) waitModification(template *ec2.Volume) (*ec2.Volume, error) {
+	request := &ec2.DescribeVolumesInput{VolumeIds: []*string{template.VolumeId}}
+	for retries, maxRetries := 0, 60; ; retries++ {
+		awsVols, err := s.ec2.DescribeVolumes(request)
+		if err != nil {
+			return nil, err
+		}
+		if len(awsVols.Volumes) != 1 {
+			return nil, fmt.Errorf("expected one volume %v got %v",
+				*template.VolumeId, len(awsVols.Volumes))
+		}
+		vol := awsVols.Volumes[0]
+		if modified(vol, template) {
+			return vol, nil
+		}
+		if retries == maxRetries {
+			return nil, fmt.Errorf("Volume %v was not modified", *template.VolumeId)
+		}
+		time.Sleep(5 * time.Second)
+	}
+}
+
 func (s *ec2Ops) Attach(volumeID string) (string, error) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	devices, err := s.freeDevices()
+	dev, err := s.assignDevice()
 	if err != nil {
 		return "", err
 	}
+	name := ec2Device(dev)
 	req := &ec2.AttachVolumeInput{
-		Device:     &devices[0],
+		Device:     &name,
 		InstanceId: &s.instance,
 		VolumeId:   &volumeID,
 	}
 	if _, err = s.ec2.AttachVolume(req); err != nil {
+		s.devices.Release(dev)
 		return "", err
 	}
 	vol, err := s.waitAttachmentStatus(
@@ -488,6 +577,14 @@ func (s *ec2Ops) Attach(volumeID string) (string, error) {
 }
 
 func (s *ec2Ops) Detach(volumeID string) error {
+	vols, err := s.Inspect([]*string{&volumeID})
+	if err != nil {
+		return err
+	}
+	if len(vols) != 1 {
+		return fmt.Errorf("Failed to inspect %v len %v", volumeID, len(vols))
+	}
+	dev, _ := s.DevicePath(vols[0])
 	force := false
 	req := &ec2.DetachVolumeInput{
 		InstanceId: &s.instance,
@@ -497,11 +594,16 @@ func (s *ec2Ops) Detach(volumeID string) error {
 	if _, err := s.ec2.DetachVolume(req); err != nil {
 		return err
 	}
-	_, err := s.waitAttachmentStatus(volumeID,
+	if _, err := s.waitAttachmentStatus(volumeID,
 		ec2.VolumeAttachmentStateDetached,
 		time.Minute,
-	)
-	return err
+	); err != nil {
+		return err
+	}
+	if len(dev) != 0 {
+		s.devices.Release(dev)
+	}
+	return nil
 }
 
 func (s *ec2Ops) Snapshot(
@@ -515,6 +617,11 @@ func (s *ec2Ops) Snapshot(
 }
 
 func (s *ec2Ops) DevicePath(vol *ec2.Volume) (string, error) {
+	return devicePath(s.instance, vol)
+}
+
+// devicePath returns the local path of vol if it is attached to instance.
+func devicePath(instance string, vol *ec2.Volume) (string, error) {
 	if vol.Attachments == nil || len(vol.Attachments) == 0 {
 		return "", NewStorageError(ErrVolDetached,
 			"Volume is detached", *vol.VolumeId)
@@ -523,10 +630,10 @@ func (s *ec2Ops) DevicePath(vol *ec2.Volume) (string, error) {
 		return "", NewStorageError(ErrVolInval,
 			"Unable to determine volume instance attachment", "")
 	}
-	if s.instance != *vol.Attachments[0].InstanceId {
+	if instance != *vol.Attachments[0].InstanceId {
 		return "", NewStorageError(ErrVolAttachedOnRemoteNode,
 			fmt.Sprintf("Volume attached on %q current instance %q",
-				*vol.Attachments[0].InstanceId, s.instance),
+				*vol.Attachments[0].InstanceId, instance),
 			*vol.Attachments[0].InstanceId)
 
 	}
@@ -549,3 +656,18 @@ func (s *ec2Ops) DevicePath(vol *ec2.Volume) (string, error) {
 	}
 	return dev, nil
 }
+
+// isSnapshot returns true if id is the id of an EBS snapshot.
+func isSnapshot(id string) bool {
+	return strings.HasPrefix(id, "snap-")
+}
+
+// modified returns true if vol has the size, type and iops set in template.
+func modified(vol *ec2.Volume, template *ec2.Volume) bool {
+	return (template.Size == nil ||
+		(vol.Size != nil && *vol.Size == *template.Size)) &&
+		(template.VolumeType == nil ||
+			(vol.VolumeType != nil && *vol.VolumeType == *template.VolumeType)) &&
+		(template.Iops == nil ||
+			(vol.Iops != nil && *vol.Iops == *template.Iops))
+}
@@ -4,8 +4,12 @@ import (
 	"testing"
 
 	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/opsworks"
 	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/volume/drivers/cloud"
 	"github.com/libopenstorage/openstorage/volume/drivers/test"
+	"github.com/stretchr/testify/require"
 )
 
 func TestAll(t *testing.T) {
@@ -20,3 +24,122 @@ func TestAll(t *testing.T) {
 	ctx.Filesystem = api.FSType_FS_TYPE_EXT4
 	test.RunShort(t, ctx)
 }
+
+func newTestProvider() (cloud.Provider, *fakeOps) {
+	ops := NewFakeStorage("i-local", "us-east-1a").(*fakeOps)
+	return NewProvider(ops, "us-east-1a", "i-local"), ops
+}
+
+func TestAttachState(t *testing.T) {
+	p, ops := newTestProvider()
+
+	diskType, iops := p.DiskType(api.CosType_LOW)
+	disk, err := p.Create(&cloud.Disk{Size: 1, Type: diskType, Iops: iops}, nil)
+	require.NoError(t, err)
+	require.Equal(t, cloud.DiskAvailable, disk.State)
+	require.Equal(t, "us-east-1a", disk.Zone)
+	require.Equal(t, opsworks.VolumeTypeGp2, disk.Type)
+
+	path, err := p.Attach(disk.Id)
+	require.NoError(t, err)
+	require.Equal(t, "/dev/xvdf", path)
+	disks, err := p.Inspect([]string{disk.Id})
+	require.NoError(t, err)
+	require.Equal(t, cloud.DiskAttached, disks[0].State)
+	require.Equal(t, "i-local", disks[0].Instance)
+	require.NoError(t, p.Detach(disk.Id))
+
+	_, err = ops.attach("i-remote", disk.Id)
+	require.NoError(t, err)
+	disks, err = p.Inspect([]string{disk.Id})
+	require.NoError(t, err)
+	require.Equal(t, cloud.DiskAttached, disks[0].State)
+	require.Equal(t, "i-remote", disks[0].Instance)
+	vols, err := ops.Inspect([]*string{&disk.Id})
+	require.NoError(t, err)
+	_, err = ops.DevicePath(vols[0])
+	require.Equal(t, ErrVolAttachedOnRemoteNode, err.(*StorageError).Code)
+	mappings, err := p.DeviceMappings()
+	require.NoError(t, err)
+	require.Len(t, mappings, 0)
+	require.Error(t, p.Delete(disk.Id), "Delete of an attached disk should fail")
+
+	require.NoError(t, ops.detach("i-remote", disk.Id))
+	require.NoError(t, p.Delete(disk.Id))
+}
+
+func TestRestore(t *testing.T) {
+	p, _ := newTestProvider()
+
+	disk, err := p.Create(&cloud.Disk{Size: 2, Type: opsworks.VolumeTypeGp2}, nil)
+	require.NoError(t, err)
+	snapID, err := p.Snapshot(disk.Id)
+	require.NoError(t, err)
+	require.True(t, p.IsSnapshot(snapID))
+	require.False(t, p.IsSnapshot(disk.Id))
+
+	restored, err := p.Create(
+		&cloud.Disk{Type: opsworks.VolumeTypeGp2, Snapshot: snapID},
+		nil,
+	)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), restored.Size)
+	require.Equal(t, snapID, restored.Snapshot)
+	_, err = p.Create(
+		&cloud.Disk{Size: 1, Type: opsworks.VolumeTypeGp2, Snapshot: snapID},
+		nil,
+	)
+	require.Error(t, err, "Disks should not be smaller than their snapshot")
+
+	require.NoError(t, p.Delete(snapID))
+	require.NoError(t, p.Delete(restored.Id))
+	require.NoError(t, p.Delete(disk.Id))
+}
+
+func TestModify(t *testing.T) {
+	p, ops := newTestProvider()
+
+	disk, err := p.Create(&cloud.Disk{Size: 1, Type: opsworks.VolumeTypeGp2}, nil)
+	require.NoError(t, err)
+	diskType, iops := p.DiskType(api.CosType_HIGH)
+	disk, err = p.Modify(&cloud.Disk{Id: disk.Id, Size: 3, Type: diskType, Iops: iops})
+	require.NoError(t, err)
+	require.Equal(t, int64(3), disk.Size)
+	require.Equal(t, opsworks.VolumeTypeIo1, disk.Type)
+	require.Equal(t, int64(10000), disk.Iops)
+	vols, err := ops.Inspect([]*string{&disk.Id})
+	require.NoError(t, err)
+	require.Equal(t, ec2.VolumeStateAvailable, *vols[0].State)
+	_, err = p.Modify(&cloud.Disk{Id: disk.Id, Size: 2})
+	require.Error(t, err, "Disks should not shrink")
+
+	require.NoError(t, p.Delete(disk.Id))
+}
+
+func TestTags(t *testing.T) {
+	p, ops := newTestProvider()
+
+	disk, err := p.Create(
+		&cloud.Disk{Size: 1, Type: opsworks.VolumeTypeGp2},
+		map[string]string{"app": "db", "old": "x"},
+	)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"app": "db", "old": "x"}, disk.Labels)
+	require.NoError(t, p.RemoveTags(disk.Id, map[string]string{"old": "x"}))
+	require.NoError(t, p.ApplyTags(disk.Id, map[string]string{"app": "web", "tier": "1"}))
+	disks, err := p.Inspect([]string{disk.Id})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"app": "web", "tier": "1"}, disks[0].Labels)
+
+	disks, err = p.Enumerate(map[string]string{"app": "web"})
+	require.NoError(t, err)
+	require.Len(t, disks, 1)
+	disks, err = p.Enumerate(map[string]string{"app": "db"})
+	require.NoError(t, err)
+	require.Len(t, disks, 0)
+	sets, err := ops.Enumerate(nil, map[string]string{"app": "web"}, "tier")
+	require.NoError(t, err)
+	require.Len(t, sets["1"], 1)
+
+	require.NoError(t, p.Delete(disk.Id))
+}
@@ -0,0 +1,417 @@
+package aws
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/opsworks"
+)
+
+// fakeOps is an in-memory StorageOps. It keeps the EBS volumes, snapshots
+// and block device mappings of all instances so that volumes attached to
+// other instances can be simulated.
+type fakeOps struct {
+	sync.Mutex
+	instance  string
+	zone      string
+	next      int
+	volumes   map[string]*ec2.Volume
+	snapshots map[string]*ec2.Snapshot
+	// devices maps instances to their device names to the volumes
+	// attached at them.
+	devices map[string]map[string]string
+}
+
+// NewFakeStorage returns a StorageOps for instance in zone that keeps its
+// volumes in memory.
+func NewFakeStorage(instance string, zone string) StorageOps {
+	return &fakeOps{
+		instance:  instance,
+		zone:      zone,
+		volumes:   make(map[string]*ec2.Volume),
+		snapshots: make(map[string]*ec2.Snapshot),
+		devices:   make(map[string]map[string]string),
+	}
+}
+
+func (f *fakeOps) id(prefix string) string {
+	f.next++
+	return fmt.Sprintf("%s-%08x", prefix, f.next)
+}
+
+func (f *fakeOps) volume(volumeID string) (*ec2.Volume, error) {
+	v, ok := f.volumes[volumeID]
+	if !ok {
+		return nil, fmt.Errorf("InvalidVolume.NotFound: volume %v does not exist",
+			volumeID)
+	}
+	return v, nil
+}
+
+// copyVolume returns a copy of v that the caller can change.
+func copyVolume(v *ec2.Volume) *ec2.Volume {
+	c := *v
+	c.Tags = make([]*ec2.Tag, len(v.Tags))
+	for i, t := range v.Tags {
+		tag := *t
+		c.Tags[i] = &tag
+	}
+	c.Attachments = make([]*ec2.VolumeAttachment, len(v.Attachments))
+	for i, a := range v.Attachments {
+		attachment := *a
+		c.Attachments[i] = &attachment
+	}
+	return &c
+}
+
+func (f *fakeOps) Create(
+	template *ec2.Volume,
+	labels map[string]string,
+) (*ec2.Volume, error) {
+	f.Lock()
+	defer f.Unlock()
+
+	if template.VolumeType == nil {
+		return nil, fmt.Errorf("InvalidParameterValue: no volume type")
+	}
+	if *template.VolumeType == opsworks.VolumeTypeIo1 &&
+		(template.Iops == nil || *template.Iops == 0) {
+		return nil, fmt.Errorf("InvalidParameterCombination: io1 volumes need iops")
+	}
+	var size int64
+	if template.Size != nil {
+		size = *template.Size
+	}
+	if template.SnapshotId != nil {
+		snap, ok := f.snapshots[*template.SnapshotId]
+		if !ok {
+			return nil, fmt.Errorf("InvalidSnapshot.NotFound: snapshot %v does not exist",
+				*template.SnapshotId)
+		}
+		if size == 0 {
+			size = *snap.VolumeSize
+		} else if size < *snap.VolumeSize {
+			return nil, fmt.Errorf("InvalidParameterValue: size %v is smaller than snapshot %v",
+				size, *snap.VolumeSize)
+		}
+	}
+	if size == 0 {
+		return nil, fmt.Errorf("InvalidParameterValue: no volume size")
+	}
+
+	id := f.id("vol")
+	zone := f.zone
+	if template.AvailabilityZone != nil {
+		zone = *template.AvailabilityZone
+	}
+	state := ec2.VolumeStateAvailable
+	now := time.Now()
+	v := &ec2.Volume{
+		AvailabilityZone: &zone,
+		CreateTime:       &now,
+		Encrypted:        template.Encrypted,
+		KmsKeyId:         template.KmsKeyId,
+		Size:             &size,
+		SnapshotId:       template.SnapshotId,
+		State:            &state,
+		VolumeId:         &id,
+		VolumeType:       template.VolumeType,
+	}
+	if *template.VolumeType == opsworks.VolumeTypeIo1 {
+		v.Iops = template.Iops
+	}
+	f.volumes[id] = v
+	f.applyTags(v, labels)
+	return copyVolume(v), nil
+}
+
+func (f *fakeOps) Attach(volumeID string) (string, error) {
+	f.Lock()
+	defer f.Unlock()
+
+	return f.attach(f.instance, volumeID)
+}
+
+// attach attaches volumeID to instance at its first free device.
+func (f *fakeOps) attach(instance string, volumeID string) (string, error) {
+	v, err := f.volume(volumeID)
+	if err != nil {
+		return "", err
+	}
+	if *v.State != ec2.VolumeStateAvailable {
+		return "", fmt.Errorf("VolumeInUse: volume %v is %v", volumeID, *v.State)
+	}
+	devices, ok := f.devices[instance]
+	if !ok {
+		devices = make(map[string]string)
+		f.devices[instance] = devices
+	}
+	device := ""
+	for _, b := range []byte("fghijklmnop") {
+		name := "/dev/sd" + string(b)
+		if _, ok := devices[name]; !ok {
+			device = name
+			break
+		}
+	}
+	if device == "" {
+		return "", fmt.Errorf("No more free devices")
+	}
+	devices[device] = volumeID
+
+	state := ec2.VolumeStateInUse
+	attached := ec2.VolumeAttachmentStateAttached
+	now := time.Now()
+	v.State = &state
+	v.Attachments = []*ec2.VolumeAttachment{
+		{
+			AttachTime: &now,
+			Device:     &device,
+			InstanceId: &instance,
+			State:      &attached,
+			VolumeId:   v.VolumeId,
+		},
+	}
+	return devicePath(instance, v)
+}
+
+func (f *fakeOps) Detach(volumeID string) error {
+	f.Lock()
+	defer f.Unlock()
+
+	return f.detach(f.instance, volumeID)
+}
+
+// detach detaches volumeID from instance.
+func (f *fakeOps) detach(instance string, volumeID string) error {
+	v, err := f.volume(volumeID)
+	if err != nil {
+		return err
+	}
+	if _, err := devicePath(instance, v); err != nil {
+		return err
+	}
+	delete(f.devices[instance], *v.Attachments[0].Device)
+	state := ec2.VolumeStateAvailable
+	v.State = &state
+	v.Attachments = nil
+	return nil
+}
+
+func (f *fakeOps) Delete(id string) error {
+	f.Lock()
+	defer f.Unlock()
+
+	if isSnapshot(id) {
+		if _, ok := f.snapshots[id]; !ok {
+			return fmt.Errorf("InvalidSnapshot.NotFound: snapshot %v does not exist", id)
+		}
+		delete(f.snapshots, id)
+		return nil
+	}
+	v, err := f.volume(id)
+	if err != nil {
+		return err
+	}
+	if *v.State != ec2.VolumeStateAvailable {
+		return fmt.Errorf("VolumeInUse: volume %v is %v", id, *v.State)
+	}
+	delete(f.volumes, id)
+	return nil
+}
+
+func (f *fakeOps) Inspect(volumeIds []*string) ([]*ec2.Volume, error) {
+	f.Lock()
+	defer f.Unlock()
+
+	vols := make([]*ec2.Volume, 0, len(volumeIds))
+	for _, id := range volumeIds {
+		v, err := f.volume(*id)
+		if err != nil {
+			return nil, err
+		}
+		vols = append(vols, copyVolume(v))
+	}
+	return vols, nil
+}
+
+func (f *fakeOps) DeviceMappings() (map[string]string, error) {
+	f.Lock()
+	defer f.Unlock()
+
+	m := make(map[string]string)
+	for device, id := range f.devices[f.instance] {
+		m["/dev/xvd"+strings.TrimPrefix(device, "/dev/sd")] = id
+	}
+	return m, nil
+}
+
+func (f *fakeOps) Enumerate(
+	volumeIds []*string,
+	labels map[string]string,
+	setIdentifier string,
+) (map[string][]*ec2.Volume, error) {
+	f.Lock()
+	defer f.Unlock()
+
+	var vols []*ec2.Volume
+	if len(volumeIds) != 0 {
+		for _, id := range volumeIds {
+			v, err := f.volume(*id)
+			if err != nil {
+				return nil, err
+			}
+			vols = append(vols, v)
+		}
+	} else {
+		for _, v := range f.volumes {
+			vols = append(vols, v)
+		}
+	}
+
+	sets := make(map[string][]*ec2.Volume)
+	for _, v := range vols {
+		tags := f.tags(v)
+		match := true
+		for k, value := range labels {
+			if tags[k] != value {
+				match = false
+				break
+			}
+		}
+		if !match {
+			continue
+		}
+		set := SetIdentifierNone
+		if value, ok := tags[setIdentifier]; ok && len(value) != 0 {
+			set = value
+		}
+		sets[set] = append(sets[set], copyVolume(v))
+	}
+	return sets, nil
+}
+
+func (f *fakeOps) DevicePath(vol *ec2.Volume) (string, error) {
+	return devicePath(f.instance, vol)
+}
+
+func (f *fakeOps) Snapshot(volumeID string, readonly bool) (*ec2.Snapshot, error) {
+	f.Lock()
+	defer f.Unlock()
+
+	v, err := f.volume(volumeID)
+	if err != nil {
+		return nil, err
+	}
+	id := f.id("snap")
+	state := ec2.SnapshotStateCompleted
+	progress := "100%"
+	now := time.Now()
+	snap := &ec2.Snapshot{
+		Encrypted:  v.Encrypted,
+		Progress:   &progress,
+		SnapshotId: &id,
+		StartTime:  &now,
+		State:      &state,
+		VolumeId:   v.VolumeId,
+		VolumeSize: v.Size,
+	}
+	f.snapshots[id] = snap
+	c := *snap
+	return &c, nil
+}
+
+func (f *fakeOps) ApplyTags(v *ec2.Volume, labels map[string]string) error {
+	f.Lock()
+	defer f.Unlock()
+
+	vol, err := f.volume(*v.VolumeId)
+	if err != nil {
+		return err
+	}
+	f.applyTags(vol, labels)
+	return nil
+}
+
+func (f *fakeOps) applyTags(v *ec2.Volume, labels map[string]string) {
+	tags := f.tags(v)
+	for k, value := range labels {
+		tags[k] = value
+	}
+	v.Tags = make([]*ec2.Tag, 0, len(tags))
+	for k, value := range tags {
+		key := k
+		val := value
+		v.Tags = append(v.Tags, &ec2.Tag{Key: &key, Value: &val})
+	}
+}
+
+func (f *fakeOps) RemoveTags(v *ec2.Volume, labels map[string]string) error {
+	f.Lock()
+	defer f.Unlock()
+
+	vol, err := f.volume(*v.VolumeId)
+	if err != nil {
+		return err
+	}
+	tags := vol.Tags[:0]
+	for _, t := range vol.Tags {
+		if _, ok := labels[*t.Key]; !ok {
+			tags = append(tags, t)
+		}
+	}
+	vol.Tags = tags
+	return nil
+}
+
+func (f *fakeOps) Tags(v *ec2.Volume) map[string]string {
+	return f.tags(v)
+}
+
+func (f *fakeOps) tags(v *ec2.Volume) map[string]string {
+	labels := make(map[string]string)
+	for _, tag := range v.Tags {
+		labels[*tag.Key] = *tag.Value
+	}
+	return labels
+}
+
+func (f *fakeOps) Modify(template *ec2.Volume) (*ec2.Volume, error) {
+	f.Lock()
+	defer f.Unlock()
+
+	v, err := f.volume(*template.VolumeId)
+	if err != nil {
+		return nil, err
+	}
+	if template.Size != nil && *template.Size < *v.Size {
+		return nil, fmt.Errorf("InvalidParameterValue: volume %v cannot shrink from %v to %v",
+			*v.VolumeId, *v.Size, *template.Size)
+	}
+	volType := *v.VolumeType
+	if template.VolumeType != nil {
+		volType = *template.VolumeType
+	}
+	iops := template.Iops
+	if iops == nil {
+		iops = v.Iops
+	}
+	if volType == opsworks.VolumeTypeIo1 && (iops == nil || *iops == 0) {
+		return nil, fmt.Errorf("InvalidParameterCombination: io1 volumes need iops")
+	}
+	if template.Size != nil {
+		size := *template.Size
+		v.Size = &size
+	}
+	v.VolumeType = &volType
+	if volType == opsworks.VolumeTypeIo1 {
+		i := *iops
+		v.Iops = &i
+	} else {
+		v.Iops = nil
+	}
+	return copyVolume(v), nil
+}
@@ -0,0 +1,296 @@
+package azure
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// armURL is the endpoint of the resource manager API.
+	armURL = "https://management.azure.com"
+	// imdsURL is the endpoint of the instance metadata service.
+	imdsURL = "http://169.254.169.254/metadata/"
+	// diskAPIVersion is the version of the disk and snapshot APIs.
+	diskAPIVersion = "2022-03-02"
+	// vmAPIVersion is the version of the virtual machine API.
+	vmAPIVersion = "2022-03-01"
+	// operationTimeout is how long operations take to complete.
+	operationTimeout = 5 * time.Minute
+	// operationPollInterval is how often operations are polled.
+	operationPollInterval = 2 * time.Second
+)
+
+// Provisioning states
+const (
+	provisioningCreating  = "Creating"
+	provisioningUpdating  = "Updating"
+	provisioningDeleting  = "Deleting"
+	provisioningSucceeded = "Succeeded"
+	provisioningFailed    = "Failed"
+)
+
+// Operation statuses
+const (
+	operationInProgress = "InProgress"
+	operationSucceeded  = "Succeeded"
+	operationFailed     = "Failed"
+)
+
+// errNotFound is returned for resources that do not exist.
+var errNotFound = fmt.Errorf("Resource not found")
+
+// sku is the storage class of a disk.
+type sku struct {
+	Name string `json:"name"`
+}
+
+// creationData describes where a disk or snapshot is created from.
+type creationData struct {
+	CreateOption     string `json:"createOption"`
+	SourceResourceID string `json:"sourceResourceId,omitempty"`
+}
+
+// diskProperties are the properties of a managed disk.
+type diskProperties struct {
+	CreationData      *creationData `json:"creationData,omitempty"`
+	DiskSizeGB        int64         `json:"diskSizeGB,omitempty"`
+	DiskIOPSReadWrite int64         `json:"diskIOPSReadWrite,omitempty"`
+	DiskState         string        `json:"diskState,omitempty"`
+	ProvisioningState string        `json:"provisioningState,omitempty"`
+}
+
+// managedDisk is a disk or snapshot.
+type managedDisk struct {
+	ID         string            `json:"id,omitempty"`
+	Name       string            `json:"name,omitempty"`
+	Location   string            `json:"location,omitempty"`
+	Sku        *sku              `json:"sku,omitempty"`
+	ManagedBy  string            `json:"managedBy,omitempty"`
+	Tags       map[string]string `json:"tags,omitempty"`
+	Properties *diskProperties   `json:"properties,omitempty"`
+}
+
+// diskList is a page of disks.
+type diskList struct {
+	Value    []*managedDisk `json:"value"`
+	NextLink string         `json:"nextLink,omitempty"`
+}
+
+// dataDisk is a disk attached to a virtual machine.
+type dataDisk struct {
+	Lun          int    `json:"lun"`
+	Name         string `json:"name,omitempty"`
+	CreateOption string `json:"createOption"`
+	ManagedDisk  struct {
+		ID string `json:"id"`
+	} `json:"managedDisk"`
+}
+
+// virtualMachine is the storage profile of a virtual machine.
+type virtualMachine struct {
+	ID         string `json:"id,omitempty"`
+	Name       string `json:"name,omitempty"`
+	Properties struct {
+		StorageProfile struct {
+			DataDisks []*dataDisk `json:"dataDisks"`
+		} `json:"storageProfile"`
+		ProvisioningState string `json:"provisioningState,omitempty"`
+	} `json:"properties"`
+}
+
+// armError describes why a call failed.
+type armError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// errorReply is the body of a failed call.
+type errorReply struct {
+	Error armError `json:"error"`
+}
+
+// operationStatus is the status of an asynchronous operation.
+type operationStatus struct {
+	Status string    `json:"status"`
+	Error  *armError `json:"error,omitempty"`
+}
+
+// tokenReply is the access token of the managed identity of an instance.
+type tokenReply struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int    `json:"expires_in,string"`
+}
+
+// instanceMetadata is the compute metadata of an instance.
+type instanceMetadata struct {
+	Name              string `json:"name"`
+	Location          string `json:"location"`
+	SubscriptionID    string `json:"subscriptionId"`
+	ResourceGroupName string `json:"resourceGroupName"`
+}
+
+// armClient calls the resource manager API for the compute resources of a
+// resource group.
+type armClient struct {
+	sync.Mutex
+	client       *http.Client
+	base         string
+	token        string
+	expiry       time.Time
+	pollInterval time.Duration
+}
+
+func newARMClient(client *http.Client, subscription string, group string) *armClient {
+	return &armClient{
+		client: client,
+		base: "/subscriptions/" + subscription + "/resourceGroups/" + group +
+			"/providers/Microsoft.Compute/",
+		pollInterval: operationPollInterval,
+	}
+}
+
+// imds decodes the reply of the instance metadata service at path into out.
+func imds(client *http.Client, path string, out interface{}) error {
+	req, err := http.NewRequest("GET", imdsURL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Metadata", "true")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("Error querying Azure metadata %v: %v %s",
+			path, resp.Status, body)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// accessToken returns a token of the managed identity of the instance,
+// refreshing it before it expires.
+func (c *armClient) accessToken() (string, error) {
+	c.Lock()
+	defer c.Unlock()
+	if len(c.token) != 0 && time.Now().Before(c.expiry) {
+		return c.token, nil
+	}
+	reply := tokenReply{}
+	if err := imds(
+		c.client,
+		"identity/oauth2/token?api-version=2018-02-01&resource="+armURL+"/",
+		&reply,
+	); err != nil {
+		return "", err
+	}
+	c.token = reply.AccessToken
+	c.expiry = time.Now().Add(time.Duration(reply.ExpiresIn)*time.Second - time.Minute)
+	return c.token, nil
+}
+
+// resourceID returns the id of the resource at path.
+func (c *armClient) resourceID(path string) string {
+	return c.base + path
+}
+
+// call sends in to the resource at path, which is relative to the resource
+// group unless it is a URL, decodes the reply into out, and returns the URL
+// of the operation it started, if any.
+func (c *armClient) call(
+	method string,
+	path string,
+	version string,
+	in interface{},
+	out interface{},
+) (string, error) {
+	url := path
+	if !strings.HasPrefix(path, "https://") {
+		sep := "?"
+		if strings.Contains(path, "?") {
+			sep = "&"
+		}
+		url = armURL + c.base + path + sep + "api-version=" + version
+	}
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return "", err
+		}
+	}
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	token, err := c.accessToken()
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return "", errNotFound
+	}
+	if resp.StatusCode >= 300 {
+		reply := errorReply{}
+		json.NewDecoder(resp.Body).Decode(&reply)
+		return "", fmt.Errorf("%v %v failed: %v %v: %v", method, path,
+			resp.Status, reply.Error.Code, reply.Error.Message)
+	}
+	if out != nil && resp.StatusCode != http.StatusAccepted &&
+		resp.StatusCode != http.StatusNoContent {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return "", err
+		}
+	}
+	return resp.Header.Get("Azure-AsyncOperation"), nil
+}
+
+// do sends in to path and waits for the operation it starts.
+func (c *armClient) do(method string, path string, version string, in interface{}) error {
+	op, err := c.call(method, path, version, in, nil)
+	if err != nil || len(op) == 0 {
+		return err
+	}
+	deadline := time.Now().Add(operationTimeout)
+	for {
+		status := operationStatus{}
+		if _, err := c.call("GET", op, version, nil, &status); err != nil {
+			return err
+		}
+		switch status.Status {
+		case operationSucceeded:
+			return nil
+		case operationInProgress:
+		default:
+			if status.Error != nil {
+				return fmt.Errorf("%v %v failed: %v: %v", method, path,
+					status.Error.Code, status.Error.Message)
+			}
+			return fmt.Errorf("%v %v failed: %v", method, path, status.Status)
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%v %v did not complete in %v", method, path,
+				operationTimeout)
+		}
+		time.Sleep(c.pollInterval)
+	}
+}
+
+// lastSegment returns the name of the resource with id.
+func lastSegment(id string) string {
+	return id[strings.LastIndex(id, "/")+1:]
+}
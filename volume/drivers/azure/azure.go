@@ -0,0 +1,385 @@
+package azure
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pborman/uuid"
+	"go.pedge.io/dlog"
+
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/volume"
+	"github.com/libopenstorage/openstorage/volume/drivers/cloud"
+)
+
+const (
+	// Name of the driver
+	Name = "azure"
+	// Type of the driver
+	Type = cloud.Type
+	// diskPrefix names the disks of the driver.
+	diskPrefix = "osd-"
+	// snapshotPrefix names the snapshots of the driver.
+	snapshotPrefix = "osd-snap-"
+	// devicePrefix is the path of the devices of attached disks, which are
+	// named after their lun.
+	devicePrefix = "/dev/disk/azure/scsi1/lun"
+	// maxLuns is the number of data disks a virtual machine can attach.
+	maxLuns = 64
+)
+
+// provider manages the managed disks of an Azure virtual machine.
+type provider struct {
+	// lock serializes the changes to the data disks of the instance.
+	lock     sync.Mutex
+	arm      *armClient
+	location string
+	instance string
+}
+
+// Init initializes the driver for the virtual machine it runs on. The
+// subscription, resource_group, location and instance params override those
+// of the instance metadata service.
+func Init(params map[string]string) (volume.VolumeDriver, error) {
+	client := &http.Client{Timeout: time.Minute}
+	md := instanceMetadata{
+		Name:              params["instance"],
+		Location:          params["location"],
+		SubscriptionID:    params["subscription"],
+		ResourceGroupName: params["resource_group"],
+	}
+	if len(md.Name) == 0 || len(md.Location) == 0 ||
+		len(md.SubscriptionID) == 0 || len(md.ResourceGroupName) == 0 {
+		host := instanceMetadata{}
+		if err := imds(client, "instance/compute?api-version=2021-02-01", &host); err != nil {
+			return nil, err
+		}
+		for value, hostValue := range map[*string]string{
+			&md.Name:              host.Name,
+			&md.Location:          host.Location,
+			&md.SubscriptionID:    host.SubscriptionID,
+			&md.ResourceGroupName: host.ResourceGroupName,
+		} {
+			if len(*value) == 0 {
+				*value = hostValue
+			}
+		}
+	}
+	dlog.Infof("Azure instance %v location %v resource group %v",
+		md.Name, md.Location, md.ResourceGroupName)
+	d, err := cloud.NewDriver(
+		Name,
+		newProvider(client, md.SubscriptionID, md.ResourceGroupName, md.Location, md.Name),
+		&cloud.ExecRunner{},
+		nil,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func newProvider(
+	client *http.Client,
+	subscription string,
+	group string,
+	location string,
+	instance string,
+) *provider {
+	return &provider{
+		arm:      newARMClient(client, subscription, group),
+		location: location,
+		instance: instance,
+	}
+}
+
+func (p *provider) Instance() string {
+	return p.instance
+}
+
+func (p *provider) DiskType(cos api.CosType) (string, int64) {
+	switch cos {
+	case api.CosType_LOW:
+		return "Standard_LRS", 0
+	case api.CosType_HIGH:
+		return "Premium_LRS", 0
+	}
+	return "StandardSSD_LRS", 0
+}
+
+// resource returns the path of a disk or snapshot.
+func (p *provider) resource(id string) string {
+	if p.IsSnapshot(id) {
+		return "snapshots/" + id
+	}
+	return "disks/" + id
+}
+
+// disk translates a managed disk to a disk.
+func (p *provider) disk(d *managedDisk) *cloud.Disk {
+	disk := &cloud.Disk{
+		Id:     d.Name,
+		Zone:   d.Location,
+		Labels: d.Tags,
+		State:  cloud.DiskError,
+	}
+	if d.Sku != nil {
+		disk.Type = d.Sku.Name
+	}
+	if d.Properties == nil {
+		return disk
+	}
+	disk.Size = d.Properties.DiskSizeGB
+	disk.Iops = d.Properties.DiskIOPSReadWrite
+	if c := d.Properties.CreationData; c != nil && len(c.SourceResourceID) != 0 {
+		disk.Snapshot = lastSegment(c.SourceResourceID)
+	}
+	switch d.Properties.ProvisioningState {
+	case provisioningCreating, provisioningUpdating, provisioningDeleting:
+		disk.State = cloud.DiskPending
+	case provisioningSucceeded:
+		disk.State = cloud.DiskAvailable
+		if len(d.ManagedBy) != 0 {
+			disk.State = cloud.DiskAttached
+			disk.Instance = lastSegment(d.ManagedBy)
+		}
+	}
+	return disk
+}
+
+func (p *provider) get(id string) (*managedDisk, error) {
+	d := &managedDisk{}
+	if _, err := p.arm.call("GET", p.resource(id), diskAPIVersion, nil, d); err != nil {
+		if err == errNotFound {
+			return nil, fmt.Errorf("Disk %v does not exist", id)
+		}
+		return nil, err
+	}
+	return d, nil
+}
+
+func (p *provider) Create(template *cloud.Disk, labels map[string]string) (*cloud.Disk, error) {
+	name := diskPrefix + uuid.New()
+	d := &managedDisk{
+		Location: p.location,
+		Sku:      &sku{Name: template.Type},
+		Tags:     labels,
+		Properties: &diskProperties{
+			CreationData:      &creationData{CreateOption: "Empty"},
+			DiskSizeGB:        template.Size,
+			DiskIOPSReadWrite: template.Iops,
+		},
+	}
+	if len(template.Snapshot) != 0 {
+		d.Properties.CreationData = &creationData{
+			CreateOption:     "Copy",
+			SourceResourceID: p.arm.resourceID(p.resource(template.Snapshot)),
+		}
+	}
+	if err := p.arm.do("PUT", "disks/"+name, diskAPIVersion, d); err != nil {
+		return nil, err
+	}
+	created, err := p.get(name)
+	if err != nil {
+		return nil, err
+	}
+	return p.disk(created), nil
+}
+
+func (p *provider) Delete(id string) error {
+	return p.arm.do("DELETE", p.resource(id), diskAPIVersion, nil)
+}
+
+// vm returns the virtual machine of this instance.
+func (p *provider) vm() (*virtualMachine, error) {
+	vm := &virtualMachine{}
+	if _, err := p.arm.call(
+		"GET",
+		"virtualMachines/"+p.instance,
+		vmAPIVersion,
+		nil,
+		vm,
+	); err != nil {
+		return nil, err
+	}
+	return vm, nil
+}
+
+// updateDataDisks sets the data disks of the virtual machine of this
+// instance.
+func (p *provider) updateDataDisks(disks []*dataDisk) error {
+	update := &virtualMachine{}
+	update.Properties.StorageProfile.DataDisks = disks
+	return p.arm.do("PATCH", "virtualMachines/"+p.instance, vmAPIVersion, update)
+}
+
+// Attach attaches the disk id at the lowest free lun of this instance.
+func (p *provider) Attach(id string) (string, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	vm, err := p.vm()
+	if err != nil {
+		return "", err
+	}
+	disks := vm.Properties.StorageProfile.DataDisks
+	used := make(map[int]bool)
+	for _, d := range disks {
+		used[d.Lun] = true
+	}
+	lun := 0
+	for ; lun < maxLuns && used[lun]; lun++ {
+	}
+	if lun == maxLuns {
+		return "", fmt.Errorf("No more free luns on %v", p.instance)
+	}
+	d := &dataDisk{Lun: lun, Name: id, CreateOption: "Attach"}
+	d.ManagedDisk.ID = p.arm.resourceID("disks/" + id)
+	if err := p.updateDataDisks(append(disks, d)); err != nil {
+		return "", err
+	}
+	return devicePrefix + strconv.Itoa(lun), nil
+}
+
+func (p *provider) Detach(id string) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	vm, err := p.vm()
+	if err != nil {
+		return err
+	}
+	disks := vm.Properties.StorageProfile.DataDisks
+	for i, d := range disks {
+		if lastSegment(d.ManagedDisk.ID) == id {
+			return p.updateDataDisks(append(disks[:i:i], disks[i+1:]...))
+		}
+	}
+	return fmt.Errorf("Disk %v is not attached to %v", id, p.instance)
+}
+
+func (p *provider) Inspect(ids []string) ([]*cloud.Disk, error) {
+	disks := make([]*cloud.Disk, 0, len(ids))
+	for _, id := range ids {
+		d, err := p.get(id)
+		if err != nil {
+			return nil, err
+		}
+		disks = append(disks, p.disk(d))
+	}
+	return disks, nil
+}
+
+func (p *provider) Enumerate(labels map[string]string) ([]*cloud.Disk, error) {
+	var disks []*cloud.Disk
+	for next := "disks"; len(next) != 0; {
+		list := &diskList{}
+		if _, err := p.arm.call("GET", next, diskAPIVersion, nil, list); err != nil {
+			return nil, err
+		}
+		for _, d := range list.Value {
+			if hasTags(d.Tags, labels) {
+				disks = append(disks, p.disk(d))
+			}
+		}
+		next = list.NextLink
+	}
+	return disks, nil
+}
+
+// hasTags returns true if set has all the tags of subset.
+func hasTags(set map[string]string, subset map[string]string) bool {
+	for k, v := range subset {
+		if set[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *provider) DeviceMappings() (map[string]string, error) {
+	vm, err := p.vm()
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[string]string)
+	for _, d := range vm.Properties.StorageProfile.DataDisks {
+		m[devicePrefix+strconv.Itoa(d.Lun)] = lastSegment(d.ManagedDisk.ID)
+	}
+	return m, nil
+}
+
+func (p *provider) Snapshot(id string) (string, error) {
+	name := snapshotPrefix + uuid.New()
+	snap := &managedDisk{
+		Location: p.location,
+		Properties: &diskProperties{
+			CreationData: &creationData{
+				CreateOption:     "Copy",
+				SourceResourceID: p.arm.resourceID("disks/" + id),
+			},
+		},
+	}
+	if err := p.arm.do("PUT", "snapshots/"+name, diskAPIVersion, snap); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+func (p *provider) IsSnapshot(id string) bool {
+	return strings.HasPrefix(id, snapshotPrefix)
+}
+
+// setTags changes the tags of the disk id with update.
+func (p *provider) setTags(id string, update func(map[string]string)) error {
+	d, err := p.get(id)
+	if err != nil {
+		return err
+	}
+	tags := make(map[string]string)
+	for k, v := range d.Tags {
+		tags[k] = v
+	}
+	update(tags)
+	return p.arm.do("PATCH", "disks/"+id, diskAPIVersion, &managedDisk{Tags: tags})
+}
+
+func (p *provider) ApplyTags(id string, labels map[string]string) error {
+	return p.setTags(id, func(tags map[string]string) {
+		for k, v := range labels {
+			tags[k] = v
+		}
+	})
+}
+
+func (p *provider) RemoveTags(id string, labels map[string]string) error {
+	return p.setTags(id, func(tags map[string]string) {
+		for k := range labels {
+			delete(tags, k)
+		}
+	})
+}
+
+func (p *provider) Modify(template *cloud.Disk) (*cloud.Disk, error) {
+	update := &managedDisk{
+		Properties: &diskProperties{
+			DiskSizeGB:        template.Size,
+			DiskIOPSReadWrite: template.Iops,
+		},
+	}
+	if len(template.Type) != 0 {
+		update.Sku = &sku{Name: template.Type}
+	}
+	if err := p.arm.do("PATCH", "disks/"+template.Id, diskAPIVersion, update); err != nil {
+		return nil, err
+	}
+	d, err := p.get(template.Id)
+	if err != nil {
+		return nil, err
+	}
+	return p.disk(d), nil
+}
@@ -0,0 +1,318 @@
+package azure
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/libopenstorage/openstorage/volume/drivers/cloud"
+)
+
+const (
+	// FakeSubscription is the subscription of the fake resource manager.
+	FakeSubscription = "fake-subscription"
+	// FakeResourceGroup is the resource group of the fake resource manager.
+	FakeResourceGroup = "fake-group"
+	// FakeLocation is the location of the fake resource manager.
+	FakeLocation = "fakelocation"
+)
+
+// fakeOperation is an operation that is in progress until it is polled.
+type fakeOperation struct {
+	polled bool
+	status *operationStatus
+}
+
+// fakeARM serves the disks, snapshots and virtual machines of a resource
+// group in memory. Operations are in progress until they are polled once.
+type fakeARM struct {
+	sync.Mutex
+	next       int
+	base       string
+	disks      map[string]*managedDisk
+	snapshots  map[string]*managedDisk
+	vms        map[string]*virtualMachine
+	operations map[string]*fakeOperation
+}
+
+// NewFakeARM returns an in-memory resource manager for FakeSubscription
+// and FakeResourceGroup.
+func NewFakeARM() http.Handler {
+	return &fakeARM{
+		base: "/subscriptions/" + FakeSubscription + "/resourceGroups/" +
+			FakeResourceGroup + "/providers/Microsoft.Compute/",
+		disks:      make(map[string]*managedDisk),
+		snapshots:  make(map[string]*managedDisk),
+		vms:        make(map[string]*virtualMachine),
+		operations: make(map[string]*fakeOperation),
+	}
+}
+
+// NewFakeProvider returns a provider for instance that manages the disks of
+// arm, which is a fake returned by NewFakeARM. Providers for other instances
+// of the same fake share its disks.
+func NewFakeProvider(arm http.Handler, instance string) cloud.Provider {
+	p := newProvider(
+		cloud.NewHandlerClient(arm),
+		FakeSubscription,
+		FakeResourceGroup,
+		FakeLocation,
+		instance,
+	)
+	p.arm.pollInterval = 0
+	return p
+}
+
+// fail replies with an API error.
+func fail(w http.ResponseWriter, status int, code string, format string, args ...interface{}) {
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(&errorReply{
+		Error: armError{Code: code, Message: fmt.Sprintf(format, args...)},
+	})
+}
+
+// accept replies that the operation of r started, which completes with err.
+func (f *fakeARM) accept(w http.ResponseWriter, r *http.Request, err *armError) {
+	f.next++
+	id := strconv.Itoa(f.next)
+	status := &operationStatus{Status: operationSucceeded}
+	if err != nil {
+		status = &operationStatus{Status: operationFailed, Error: err}
+	}
+	f.operations[id] = &fakeOperation{status: status}
+	w.Header().Set("Azure-AsyncOperation", armURL+f.base+"operations/"+id+
+		"?api-version="+r.URL.Query().Get("api-version"))
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (f *fakeARM) vm(name string) *virtualMachine {
+	vm, ok := f.vms[name]
+	if !ok {
+		vm = &virtualMachine{ID: f.base + "virtualMachines/" + name, Name: name}
+		vm.Properties.ProvisioningState = provisioningSucceeded
+		f.vms[name] = vm
+	}
+	return vm
+}
+
+func (f *fakeARM) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+
+	if r.URL.Path == "/metadata/identity/oauth2/token" {
+		json.NewEncoder(w).Encode(&tokenReply{AccessToken: "token", ExpiresIn: 3600})
+		return
+	}
+	if r.Header.Get("Authorization") != "Bearer token" {
+		fail(w, http.StatusUnauthorized, "InvalidAuthenticationToken", "Invalid token")
+		return
+	}
+	if !strings.HasPrefix(r.URL.Path, f.base) {
+		fail(w, http.StatusNotFound, "ResourceGroupNotFound", "Unknown resource group")
+		return
+	}
+	if len(r.URL.Query().Get("api-version")) == 0 {
+		fail(w, http.StatusBadRequest, "MissingApiVersionParameter", "No api-version")
+		return
+	}
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, f.base), "/")
+	var res interface{}
+	switch {
+	case len(parts) == 2 && parts[0] == "operations":
+		op, ok := f.operations[parts[1]]
+		if !ok {
+			fail(w, http.StatusNotFound, "NotFound", "Operation %v not found", parts[1])
+			return
+		}
+		res = &operationStatus{Status: operationInProgress}
+		if op.polled {
+			res = op.status
+		}
+		op.polled = true
+	case len(parts) == 1 && parts[0] == "disks":
+		list := &diskList{}
+		names := make([]string, 0, len(f.disks))
+		for name := range f.disks {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			list.Value = append(list.Value, f.disks[name])
+		}
+		res = list
+	case len(parts) == 2 && (parts[0] == "disks" || parts[0] == "snapshots"):
+		f.serveDisk(w, r, parts[0], parts[1])
+		return
+	case len(parts) == 2 && parts[0] == "virtualMachines":
+		vm := f.vm(parts[1])
+		if r.Method == "PATCH" {
+			update := &virtualMachine{}
+			if err := json.NewDecoder(r.Body).Decode(update); err != nil {
+				fail(w, http.StatusBadRequest, "InvalidRequestContent", "%v", err)
+				return
+			}
+			f.accept(w, r, f.updateDataDisks(vm, update.Properties.StorageProfile.DataDisks))
+			return
+		}
+		res = vm
+	default:
+		fail(w, http.StatusNotFound, "NotFound", "Unknown resource %v", r.URL.Path)
+		return
+	}
+	json.NewEncoder(w).Encode(res)
+}
+
+// serveDisk serves the calls on the disk or snapshot name.
+func (f *fakeARM) serveDisk(w http.ResponseWriter, r *http.Request, kind string, name string) {
+	resources := f.disks
+	if kind == "snapshots" {
+		resources = f.snapshots
+	}
+	d, ok := resources[name]
+	if r.Method == "PUT" {
+		if ok {
+			fail(w, http.StatusConflict, "Conflict", "%v already exists", name)
+			return
+		}
+		d = &managedDisk{}
+		if err := json.NewDecoder(r.Body).Decode(d); err != nil {
+			fail(w, http.StatusBadRequest, "InvalidRequestContent", "%v", err)
+			return
+		}
+		if status, err := f.create(d, kind, name); err != nil {
+			fail(w, status, "BadRequest", "%v", err)
+			return
+		}
+		resources[name] = d
+		f.accept(w, r, nil)
+		return
+	}
+	if !ok {
+		fail(w, http.StatusNotFound, "ResourceNotFound", "%v not found", name)
+		return
+	}
+	switch r.Method {
+	case "GET":
+		json.NewEncoder(w).Encode(d)
+	case "DELETE":
+		if len(d.ManagedBy) != 0 {
+			fail(w, http.StatusConflict, "OperationNotAllowed",
+				"Disk %v is attached to %v", name, d.ManagedBy)
+			return
+		}
+		delete(resources, name)
+		f.accept(w, r, nil)
+	case "PATCH":
+		update := &managedDisk{}
+		if err := json.NewDecoder(r.Body).Decode(update); err != nil {
+			fail(w, http.StatusBadRequest, "InvalidRequestContent", "%v", err)
+			return
+		}
+		if p := update.Properties; p != nil {
+			if p.DiskSizeGB != 0 && p.DiskSizeGB < d.Properties.DiskSizeGB {
+				fail(w, http.StatusBadRequest, "BadRequest",
+					"Disk %v cannot shrink", name)
+				return
+			}
+			if p.DiskSizeGB != 0 {
+				d.Properties.DiskSizeGB = p.DiskSizeGB
+			}
+			if p.DiskIOPSReadWrite != 0 {
+				d.Properties.DiskIOPSReadWrite = p.DiskIOPSReadWrite
+			}
+		}
+		if update.Sku != nil {
+			d.Sku = update.Sku
+		}
+		if update.Tags != nil {
+			d.Tags = update.Tags
+		}
+		f.accept(w, r, nil)
+	default:
+		fail(w, http.StatusMethodNotAllowed, "BadRequest", "Unknown call %v", r.Method)
+	}
+}
+
+// create validates and completes the new disk or snapshot d.
+func (f *fakeARM) create(d *managedDisk, kind string, name string) (int, error) {
+	if d.Properties == nil || d.Properties.CreationData == nil {
+		return http.StatusBadRequest, fmt.Errorf("No creation data for %v", name)
+	}
+	c := d.Properties.CreationData
+	switch c.CreateOption {
+	case "Empty":
+	case "Copy":
+		source, ok := f.disks[lastSegment(c.SourceResourceID)]
+		if !ok {
+			source, ok = f.snapshots[lastSegment(c.SourceResourceID)]
+		}
+		if !ok {
+			return http.StatusNotFound, fmt.Errorf("Source %v not found",
+				c.SourceResourceID)
+		}
+		if d.Properties.DiskSizeGB == 0 {
+			d.Properties.DiskSizeGB = source.Properties.DiskSizeGB
+		} else if d.Properties.DiskSizeGB < source.Properties.DiskSizeGB {
+			return http.StatusBadRequest, fmt.Errorf("%v is smaller than %v",
+				name, c.SourceResourceID)
+		}
+	default:
+		return http.StatusBadRequest, fmt.Errorf("Unknown create option %v",
+			c.CreateOption)
+	}
+	if d.Properties.DiskSizeGB == 0 {
+		return http.StatusBadRequest, fmt.Errorf("%v has no size", name)
+	}
+	d.ID = f.base + kind + "/" + name
+	d.Name = name
+	d.Properties.ProvisioningState = provisioningSucceeded
+	if kind == "disks" {
+		d.Properties.DiskState = "Unattached"
+	}
+	return 0, nil
+}
+
+// updateDataDisks attaches and detaches disks to make disks the data disks
+// of vm.
+func (f *fakeARM) updateDataDisks(vm *virtualMachine, disks []*dataDisk) *armError {
+	luns := make(map[int]bool)
+	attached := make(map[string]bool)
+	for _, dd := range disks {
+		name := lastSegment(dd.ManagedDisk.ID)
+		d, ok := f.disks[name]
+		if !ok {
+			return &armError{Code: "NotFound", Message: "Disk " + name + " not found"}
+		}
+		if len(d.ManagedBy) != 0 && d.ManagedBy != vm.ID {
+			return &armError{
+				Code:    "AttachDiskWhileBeingDetached",
+				Message: "Disk " + name + " is attached to " + d.ManagedBy,
+			}
+		}
+		if luns[dd.Lun] {
+			return &armError{
+				Code:    "InvalidParameter",
+				Message: "Lun " + strconv.Itoa(dd.Lun) + " is already in use",
+			}
+		}
+		luns[dd.Lun] = true
+		attached[name] = true
+	}
+	for _, dd := range vm.Properties.StorageProfile.DataDisks {
+		name := lastSegment(dd.ManagedDisk.ID)
+		if d, ok := f.disks[name]; ok && !attached[name] {
+			d.ManagedBy = ""
+			d.Properties.DiskState = "Unattached"
+		}
+	}
+	for name := range attached {
+		f.disks[name].ManagedBy = vm.ID
+		f.disks[name].Properties.DiskState = "Attached"
+	}
+	vm.Properties.StorageProfile.DataDisks = disks
+	return nil
+}
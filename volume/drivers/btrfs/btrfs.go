@@ -3,16 +3,29 @@
 package btrfs
 
 import (
+	"bytes"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
 	"path/filepath"
+	"strings"
+	"sync"
 	"syscall"
+	"time"
 
+	"go.pedge.io/dlog"
 	"go.pedge.io/proto/time"
 
 	"github.com/docker/docker/daemon/graphdriver"
 	"github.com/docker/docker/daemon/graphdriver/btrfs"
+	"github.com/libopenstorage/openstorage/alert"
 	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/cluster"
 	"github.com/libopenstorage/openstorage/pkg/chaos"
+	"github.com/libopenstorage/openstorage/pkg/qgroup"
+	"github.com/libopenstorage/openstorage/pkg/units"
 	"github.com/libopenstorage/openstorage/volume"
 	"github.com/libopenstorage/openstorage/volume/drivers/common"
 	"github.com/pborman/uuid"
@@ -24,19 +37,39 @@ const (
 	Type      = api.DriverType_DRIVER_TYPE_FILE
 	RootParam = "home"
 	Volumes   = "volumes"
+	// QuotaWarningPercent is the percentage of its size above which a
+	// volume raises a warning.
+	QuotaWarningPercent = 90
+	// QuotaMonitorInterval is how often the usage of volumes is checked.
+	QuotaMonitorInterval = time.Minute
+	// AlertTypeQuotaWarning is the alert type raised when a volume crosses
+	// QuotaWarningPercent of its size.
+	AlertTypeQuotaWarning = alert.AlertTypeVolumeQuotaWarning
+	// AlertTypeQuotaFull is the alert type raised when a volume uses all of
+	// its size.
+	AlertTypeQuotaFull = alert.AlertTypeVolumeQuotaFull
 )
 
 var (
-	koStrayCreate chaos.ID
-	koStrayDelete chaos.ID
+	koStrayCreate = chaos.Add("btrfs", "create", "create in DB before driver")
+	koStrayDelete = chaos.Add("btrfs", "delete", "delete in DB before driver")
+	koMount       = chaos.Add("btrfs", "mount", "bind mount subvolume")
 )
 
 type driver struct {
 	volume.StoreEnumerator
 	volume.IODriver
 	volume.BlockDriver
+	volume.StatsDriver
+	volume.TrimDriver
 	btrfs graphdriver.Driver
 	root  string
+	home  string
+	lock  sync.Mutex
+	alert alert.Alert
+	// raised is the quota alert currently raised for each volume.
+	raised map[string]*api.Alert
+	stop   chan struct{}
 }
 
 func Init(params map[string]string) (volume.VolumeDriver, error) {
@@ -44,18 +77,34 @@ func Init(params map[string]string) (volume.VolumeDriver, error) {
 	if !ok {
 		return nil, fmt.Errorf("Root directory should be specified with key %q", RootParam)
 	}
-	home := filepath.Join(root, "volumes")
+	home := filepath.Join(root, Volumes)
 	d, err := btrfs.Init(home, nil, nil, nil)
 	if err != nil {
 		return nil, err
 	}
-	return &driver{
-		common.NewDefaultStoreEnumerator(Name, kvdb.Instance()),
-		common.IONotSupported,
-		common.BlockNotSupported,
-		d,
-		root,
-	}, nil
+	if err := qgroup.Enable(home); err != nil {
+		return nil, err
+	}
+	inst := &driver{
+		StoreEnumerator: common.NewDefaultStoreEnumerator(Name, kvdb.Instance()),
+		IODriver:        volume.IONotSupported,
+		BlockDriver:     volume.BlockNotSupported,
+		StatsDriver:     volume.StatsNotSupported,
+		TrimDriver:      volume.TrimNotSupported,
+		btrfs:           d,
+		root:            root,
+		home:            home,
+		stop:            make(chan struct{}),
+	}
+	if err := common.RecoverSeeds(inst); err != nil {
+		dlog.Warnf("Failed to recover the seeds of btrfs volumes: %v", err)
+	}
+	go inst.monitor()
+	return inst, nil
+}
+
+func (d *driver) subvolumePath(volumeID string) string {
+	return filepath.Join(d.home, "subvolumes", volumeID)
 }
 
 func (d *driver) Name() string {
@@ -70,7 +119,8 @@ func (d *driver) Type() api.DriverType {
 	return Type
 }
 
-// Create a new subvolume. The volume spec is not taken into account.
+// Create a new subvolume. The size of the subvolume is limited to spec.Size
+// by its qgroup.
 func (d *driver) Create(
 	locator *api.VolumeLocator,
 	source *api.Source,
@@ -79,44 +129,121 @@ func (d *driver) Create(
 	if spec.Format != api.FSType_FS_TYPE_BTRFS && spec.Format != api.FSType_FS_TYPE_NONE {
 		return "", fmt.Errorf("Filesystem format (%v) must be %v", spec.Format.SimpleString(), api.FSType_FS_TYPE_BTRFS.SimpleString())
 	}
-	volume := common.NewVolume(
+	if err := common.RejectIoLimits(spec); err != nil {
+		return "", err
+	}
+	v := common.NewVolume(
 		uuid.New(),
 		api.FSType_FS_TYPE_BTRFS,
 		locator,
 		source,
 		spec,
 	)
-	if err := d.CreateVol(volume); err != nil {
+	if err := d.CreateVol(v); err != nil {
 		return "", err
 	}
-	if err := d.btrfs.Create(volume.Id, "", "", nil); err != nil {
+	if err := chaos.Now(koStrayCreate); err != nil {
 		return "", err
 	}
-	devicePath, err := d.btrfs.Get(volume.Id, "")
+	if err := d.btrfs.Create(v.Id, "", "", nil); err != nil {
+		return "", err
+	}
+	if err := d.setup(v); err != nil {
+		if err := d.Delete(v.Id); err != nil {
+			dlog.Warnf("Failed to remove volume %v: %v", v.Id, err)
+		}
+		return "", err
+	}
+	return v.Id, nil
+}
+
+// setup limits the size of the subvolume of a new volume and seeds it.
+func (d *driver) setup(v *api.Volume) error {
+	devicePath, err := d.btrfs.Get(v.Id, "")
 	if err != nil {
-		return volume.Id, err
+		return err
+	}
+	v.DevicePath = devicePath
+	if err := d.UpdateVol(v); err != nil {
+		return err
 	}
-	volume.DevicePath = devicePath
-	return volume.Id, d.UpdateVol(volume)
+	if v.Spec.Size != 0 {
+		if err := qgroup.LimitReferenced(devicePath, v.Spec.Size); err != nil {
+			return err
+		}
+	}
+	return common.Seed(d, v, devicePath, devicePath)
 }
 
+// Delete removes the subvolume and its qgroup.
 func (d *driver) Delete(volumeID string) error {
+	common.CancelSeed(volumeID)
 	if err := d.DeleteVol(volumeID); err != nil {
 		return err
 	}
-	chaos.Now(koStrayDelete)
-	return d.btrfs.Remove(volumeID)
+	if err := chaos.Now(koStrayDelete); err != nil {
+		return err
+	}
+	q, err := qgroup.Inspect(d.subvolumePath(volumeID))
+	if err != nil {
+		dlog.Warnf("Failed to find qgroup for volume %v: %v", volumeID, err)
+	}
+	if err := d.btrfs.Remove(volumeID); err != nil {
+		return err
+	}
+	if q != nil {
+		if err := qgroup.Destroy(q.ID, d.home); err != nil {
+			dlog.Warnf("Failed to destroy qgroup %v: %v", q.ID, err)
+		}
+	}
+	d.checkQuota(volumeID, 0, 0)
+	return nil
+}
+
+func (d *driver) MountedAt(mountpath string) string {
+	return ""
 }
 
 func (d *driver) Mount(volumeID string, mountpath string) error {
+	if err := common.WaitSeed(d, volumeID, common.SeedWaitTimeout); err != nil {
+		return err
+	}
 	v, err := d.GetVol(volumeID)
 	if err != nil {
 		return err
 	}
+	if err := common.CheckAccess(
+		v,
+		mountpath,
+		api.AccessMode_ACCESS_MODE_READ_WRITE_ONCE,
+	); err != nil {
+		return err
+	}
+	flags, err := common.BindMountFlags(v)
+	if err != nil {
+		return err
+	}
+	if err := chaos.Now(koMount); err != nil {
+		return err
+	}
 	if err := syscall.Mount(v.DevicePath, mountpath, v.Format.SimpleString(), syscall.MS_BIND, ""); err != nil {
 		return fmt.Errorf("Failed to mount %v at %v: %v", v.DevicePath, mountpath, err)
 	}
-	v.AttachPath = mountpath
+	// The flags of a bind mount only apply when it is remounted.
+	if flags != 0 {
+		if err := syscall.Mount(
+			"",
+			mountpath,
+			"",
+			syscall.MS_BIND|syscall.MS_REMOUNT|flags,
+			"",
+		); err != nil {
+			syscall.Unmount(mountpath, 0)
+			return fmt.Errorf("Failed to remount %v at %v: %v", v.DevicePath, mountpath, err)
+		}
+	}
+	common.AddAttachPath(v, mountpath)
+	common.SkipThrottle(v)
 	return d.UpdateVol(v)
 }
 
@@ -125,27 +252,38 @@ func (d *driver) Unmount(volumeID string, mountpath string) error {
 	if err != nil {
 		return err
 	}
-	if v.AttachPath == "" {
+	if len(v.AttachPath) == 0 || len(v.AttachPath[0]) == 0 {
 		return fmt.Errorf("Device %v not mounted", volumeID)
 	}
-	if err := syscall.Unmount(v.AttachPath, 0); err != nil {
+	if len(mountpath) == 0 {
+		mountpath = v.AttachPath[0]
+	}
+	if err := syscall.Unmount(mountpath, 0); err != nil {
 		return err
 	}
-	v.AttachPath = ""
+	common.RemoveAttachPath(v, mountpath)
 	return d.UpdateVol(v)
 }
 
+// Set updates the locator and the size limit of the subvolume. Subvolumes
+// share the disk of the filesystem, so their I/O can only be unlimited.
 func (d *driver) Set(volumeID string, locator *api.VolumeLocator, spec *api.VolumeSpec) error {
-	if spec != nil {
-		return volume.ErrNotSupported
-	}
 	v, err := d.GetVol(volumeID)
 	if err != nil {
 		return err
 	}
+	if common.SetIoLimits(v, spec) && !common.IoLimits(v.Spec).Unlimited() {
+		return common.ErrSharedDevice
+	}
 	if locator != nil {
 		v.Locator = locator
 	}
+	if spec != nil && spec.Size != 0 && spec.Size != v.Spec.Size {
+		if err := qgroup.LimitReferenced(v.DevicePath, spec.Size); err != nil {
+			return err
+		}
+		v.Spec.Size = spec.Size
+	}
 	return d.UpdateVol(v)
 }
 
@@ -163,24 +301,311 @@ func (d *driver) Snapshot(volumeID string, readonly bool, locator *api.VolumeLoc
 	vols[0].Source = &api.Source{Parent: volumeID}
 	vols[0].Locator = locator
 	vols[0].Ctime = prototime.Now()
+	vols[0].Readonly = readonly
+	vols[0].DevicePath = d.subvolumePath(snapID)
+	vols[0].AttachPath = nil
 
 	if err := d.CreateVol(vols[0]); err != nil {
 		return "", err
 	}
-	chaos.Now(koStrayCreate)
+	if err := chaos.Now(koStrayCreate); err != nil {
+		return "", err
+	}
 	err = d.btrfs.Create(snapID, volumeID, "", nil)
 	if err != nil {
 		return "", err
 	}
+	if readonly {
+		if err := btrfsRun(
+			"property", "set", "-ts", vols[0].DevicePath, "ro", "true",
+		); err != nil {
+			return "", err
+		}
+	}
+	if vols[0].Spec.Size != 0 {
+		if err := qgroup.LimitReferenced(vols[0].DevicePath, vols[0].Spec.Size); err != nil {
+			return "", err
+		}
+	}
 	return vols[0].Id, nil
 }
 
-func (d *driver) Stats(volumeID string) (*api.Stats, error) {
-	return nil, nil
+// Export writes a btrfs send stream of the read-only snapshot snapID to w,
+// which is incremental if parentID is set.
+func (d *driver) Export(snapID string, parentID string, w io.Writer) error {
+	args := []string{"send"}
+	if len(parentID) != 0 {
+		parent, err := d.readonlyPath(parentID)
+		if err != nil {
+			return err
+		}
+		args = append(args, "-p", parent)
+	}
+	path, err := d.readonlyPath(snapID)
+	if err != nil {
+		return err
+	}
+	var stderr bytes.Buffer
+	cmd := exec.Command("btrfs", append(args, path)...)
+	cmd.Stdout = w
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("btrfs send of %v failed: %v: %s",
+			snapID, err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
 }
 
+// readonlyPath returns the path of the subvolume of a volume, which btrfs
+// send requires to be read-only.
+func (d *driver) readonlyPath(volumeID string) (string, error) {
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		return "", err
+	}
+	o, err := btrfsOutput("property", "get", "-ts", v.DevicePath, "ro")
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(o) != "ro=true" {
+		return "", fmt.Errorf("Volume %v is not a read-only snapshot", volumeID)
+	}
+	return v.DevicePath, nil
+}
+
+// Import receives a btrfs send stream into a new read-only volume. The
+// parent of an incremental stream must have been imported before.
+func (d *driver) Import(locator *api.VolumeLocator, r io.Reader) (string, error) {
+	volumeID := uuid.New()
+	staging := filepath.Join(d.home, "import", volumeID)
+	if err := os.MkdirAll(staging, 0700); err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(staging)
+
+	var stderr bytes.Buffer
+	cmd := exec.Command("btrfs", "receive", "-e", staging)
+	cmd.Stdin = r
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("btrfs receive failed: %v: %s",
+			err, strings.TrimSpace(stderr.String()))
+	}
+	// The subvolume is received under the name it was sent with.
+	received, err := ioutil.ReadDir(staging)
+	if err != nil {
+		return "", err
+	}
+	if len(received) != 1 {
+		return "", fmt.Errorf("btrfs receive created %v subvolumes", len(received))
+	}
+	path := d.subvolumePath(volumeID)
+	if err := os.Rename(filepath.Join(staging, received[0].Name()), path); err != nil {
+		return "", err
+	}
+
+	v := common.NewVolume(
+		volumeID,
+		api.FSType_FS_TYPE_BTRFS,
+		locator,
+		nil,
+		&api.VolumeSpec{Format: api.FSType_FS_TYPE_BTRFS},
+	)
+	v.Readonly = true
+	v.DevicePath = path
+	if err := d.CreateVol(v); err != nil {
+		d.btrfs.Remove(volumeID)
+		return "", err
+	}
+	return v.Id, nil
+}
+
+// Stats returns the space referenced by the subvolume of a volume.
+func (d *driver) Stats(volumeID string, cumulative bool) (*api.Stats, error) {
+	used, err := d.UsedSize(volumeID)
+	if err != nil {
+		return nil, err
+	}
+	return &api.Stats{BytesUsed: used}, nil
+}
+
+// UsedSize returns the space referenced by the subvolume of a volume, which
+// includes the data it shares with snapshots.
+func (d *driver) UsedSize(volumeID string) (uint64, error) {
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		return 0, err
+	}
+	q, err := qgroup.Inspect(v.DevicePath)
+	if err != nil {
+		return 0, err
+	}
+	d.checkQuota(v.Id, q.Referenced, v.Spec.Size)
+	return q.Referenced, nil
+}
+
+// Alerts returns the quota alerts of a volume that are not cleared.
 func (d *driver) Alerts(volumeID string) (*api.Alerts, error) {
-	return nil, nil
+	if _, err := d.GetVol(volumeID); err != nil {
+		return nil, err
+	}
+	d.lock.Lock()
+	alerter, err := d.alerter()
+	d.lock.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	all, err := alerter.Enumerate(&api.Alert{
+		Resource: api.ResourceType_RESOURCE_TYPE_VOLUME,
+	})
+	if err != nil {
+		return nil, err
+	}
+	alerts := &api.Alerts{}
+	for _, a := range all {
+		if a.ResourceId == volumeID && !a.Cleared {
+			alerts.Alert = append(alerts.Alert, a)
+		}
+	}
+	return alerts, nil
 }
 
-func (d *driver) Shutdown() {}
+// monitor checks the usage of the volumes with a size limit until the
+// driver is shut down.
+func (d *driver) monitor() {
+	ticker := time.NewTicker(QuotaMonitorInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-d.stop:
+			return
+		case <-ticker.C:
+			vols, err := d.Enumerate(&api.VolumeLocator{}, nil)
+			if err != nil {
+				continue
+			}
+			for _, v := range vols {
+				if v.Spec.Size == 0 {
+					continue
+				}
+				if q, err := qgroup.Inspect(v.DevicePath); err == nil {
+					d.checkQuota(v.Id, q.Referenced, v.Spec.Size)
+				}
+			}
+		}
+	}
+}
+
+// quotaAlertType returns the alert type for a volume that uses used bytes
+// of size, or 0 if no alert is due.
+func quotaAlertType(used uint64, size uint64) int64 {
+	switch {
+	case size == 0:
+		return 0
+	case used >= size:
+		return AlertTypeQuotaFull
+	case used*100 >= size*QuotaWarningPercent:
+		return AlertTypeQuotaWarning
+	}
+	return 0
+}
+
+// checkQuota raises an alert when a volume crosses a quota threshold and
+// clears the previous alert of the volume when its usage changes threshold.
+func (d *driver) checkQuota(volumeID string, used uint64, size uint64) {
+	alertType := quotaAlertType(used, size)
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	alerter, err := d.alerter()
+	if err != nil {
+		if alertType != 0 {
+			dlog.Warnf("Failed to raise alert for volume %v: %v", volumeID, err)
+		}
+		return
+	}
+	raised := d.raised[volumeID]
+	if raised != nil && raised.AlertType == alertType {
+		return
+	}
+	if raised != nil {
+		if err := alerter.Clear(raised.Resource, raised.Id, 0); err != nil {
+			dlog.Warnf("Failed to clear alert %v: %v", raised.Id, err)
+		}
+		delete(d.raised, volumeID)
+	}
+	if alertType == 0 {
+		return
+	}
+	message := fmt.Sprintf("%s volume %s is using %s of %s", Name, volumeID,
+		units.String(used), units.String(size))
+	a := &api.Alert{
+		Severity:   api.SeverityType_SEVERITY_TYPE_WARNING,
+		AlertType:  alertType,
+		Message:    message,
+		ResourceId: volumeID,
+		Resource:   api.ResourceType_RESOURCE_TYPE_VOLUME,
+	}
+	if alertType == AlertTypeQuotaFull {
+		a.Severity = api.SeverityType_SEVERITY_TYPE_ALARM
+	}
+	dlog.Warnf("%s", message)
+	if err := alerter.Raise(a); err != nil {
+		dlog.Warnf("Failed to raise alert for volume %v: %v", volumeID, err)
+		return
+	}
+	d.raised[volumeID] = a
+}
+
+// alerter returns the alert client, creating it on first use. The quota
+// alerts that are still raised from before a restart are picked up so
+// that they are cleared. Must be called with the lock held.
+func (d *driver) alerter() (alert.Alert, error) {
+	if d.alert != nil {
+		return d.alert, nil
+	}
+	kv := kvdb.Instance()
+	if kv == nil {
+		return nil, alert.ErrNotInitialized
+	}
+	var clusterID string
+	if c, err := cluster.Inst(); err == nil {
+		if info, err := c.Enumerate(); err == nil {
+			clusterID = info.Id
+		}
+	}
+	alerter, err := alert.New(alert.Name, clusterID, kv)
+	if err != nil {
+		return nil, err
+	}
+	d.raised = make(map[string]*api.Alert)
+	if alerts, err := alerter.Enumerate(&api.Alert{
+		Resource: api.ResourceType_RESOURCE_TYPE_VOLUME,
+	}); err == nil {
+		for _, a := range alerts {
+			if !a.Cleared && (a.AlertType == AlertTypeQuotaWarning ||
+				a.AlertType == AlertTypeQuotaFull) {
+				d.raised[a.ResourceId] = a
+			}
+		}
+	}
+	d.alert = alerter
+	return d.alert, nil
+}
+
+func btrfsRun(args ...string) error {
+	_, err := btrfsOutput(args...)
+	return err
+}
+
+func btrfsOutput(args ...string) (string, error) {
+	o, err := exec.Command("btrfs", args...).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("btrfs %s: %v: %s",
+			strings.Join(args, " "), err, strings.TrimSpace(string(o)))
+	}
+	return string(o), nil
+}
+
+func (d *driver) Shutdown() {
+	close(d.stop)
+}
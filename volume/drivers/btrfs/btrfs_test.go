@@ -3,12 +3,17 @@
 package btrfs
 
 import (
+	"bytes"
+	"io/ioutil"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"testing"
 
 	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/volume"
 	"github.com/libopenstorage/openstorage/volume/drivers/test"
+	"github.com/stretchr/testify/require"
 )
 
 const (
@@ -20,7 +25,7 @@ const (
 	GiB = MiB * 1024
 )
 
-func TestAll(t *testing.T) {
+func setup(t *testing.T) volume.VolumeDriver {
 	output, err := exec.Command("umount", btrfsFile).Output()
 	if err != nil {
 		t.Logf("error on umount %s (not fatal): %s %v", btrfsFile, string(output), err)
@@ -50,7 +55,106 @@ func TestAll(t *testing.T) {
 	if err != nil {
 		t.Fatalf("failed to initialize Driver: %v", err)
 	}
-	ctx := test.NewContext(volumeDriver)
+	return volumeDriver
+}
+
+func TestAll(t *testing.T) {
+	ctx := test.NewContext(setup(t))
 	ctx.Filesystem = api.FSType_FS_TYPE_BTRFS
 	test.Run(t, ctx)
 }
+
+func TestQuota(t *testing.T) {
+	d := setup(t)
+	defer d.Shutdown()
+	volumeID, err := d.Create(
+		&api.VolumeLocator{Name: "quota"},
+		nil,
+		&api.VolumeSpec{Size: 10 * MiB, Format: api.FSType_FS_TYPE_BTRFS},
+	)
+	require.NoError(t, err)
+	defer d.Delete(volumeID)
+	vols, err := d.Inspect([]string{volumeID})
+	require.NoError(t, err)
+
+	data := bytes.Repeat([]byte{1}, 8*MiB)
+	file := filepath.Join(vols[0].DevicePath, "data")
+	require.NoError(t, ioutil.WriteFile(file, data, 0644))
+	exec.Command("sync").Run()
+	require.Error(t, ioutil.WriteFile(file+"2", data, 0644),
+		"Writes beyond the size of the volume must fail")
+
+	used, err := d.UsedSize(volumeID)
+	require.NoError(t, err)
+	require.True(t, used >= 8*MiB, "Used %v", used)
+	alerts, err := d.Alerts(volumeID)
+	require.NoError(t, err)
+	require.NotEmpty(t, alerts.Alert)
+
+	require.NoError(t, d.Set(volumeID, nil, &api.VolumeSpec{Size: 100 * MiB}))
+	require.NoError(t, ioutil.WriteFile(file+"2", data, 0644))
+	_, err = d.UsedSize(volumeID)
+	require.NoError(t, err)
+	alerts, err = d.Alerts(volumeID)
+	require.NoError(t, err)
+	require.Empty(t, alerts.Alert)
+}
+
+func TestExportImport(t *testing.T) {
+	d := setup(t)
+	defer d.Shutdown()
+	volumeID, err := d.Create(
+		&api.VolumeLocator{Name: "export"},
+		nil,
+		&api.VolumeSpec{Format: api.FSType_FS_TYPE_BTRFS},
+	)
+	require.NoError(t, err)
+	defer d.Delete(volumeID)
+	vols, err := d.Inspect([]string{volumeID})
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(
+		filepath.Join(vols[0].DevicePath, "a"), []byte("a"), 0644))
+
+	writable, err := d.Snapshot(volumeID, false, &api.VolumeLocator{})
+	require.NoError(t, err)
+	defer d.Delete(writable)
+	require.Error(t, d.Export(writable, "", ioutil.Discard),
+		"Export of a writable snapshot must fail")
+
+	snap1, err := d.Snapshot(volumeID, true, &api.VolumeLocator{})
+	require.NoError(t, err)
+	defer d.Delete(snap1)
+	var full bytes.Buffer
+	require.NoError(t, d.Export(snap1, "", &full))
+
+	require.NoError(t, ioutil.WriteFile(
+		filepath.Join(vols[0].DevicePath, "b"), []byte("b"), 0644))
+	snap2, err := d.Snapshot(volumeID, true, &api.VolumeLocator{})
+	require.NoError(t, err)
+	defer d.Delete(snap2)
+	var incremental bytes.Buffer
+	require.NoError(t, d.Export(snap2, snap1, &incremental))
+
+	// Import into the same filesystem, which has the parent of the
+	// incremental stream.
+	imported1, err := d.Import(&api.VolumeLocator{Name: "imported1"}, &full)
+	require.NoError(t, err)
+	defer d.Delete(imported1)
+	imported2, err := d.Import(&api.VolumeLocator{Name: "imported2"}, &incremental)
+	require.NoError(t, err)
+	defer d.Delete(imported2)
+
+	vols, err = d.Inspect([]string{imported2})
+	require.NoError(t, err)
+	require.True(t, vols[0].Readonly)
+	b, err := ioutil.ReadFile(filepath.Join(vols[0].DevicePath, "b"))
+	require.NoError(t, err)
+	require.Equal(t, "b", string(b))
+}
+
+func TestQuotaAlertType(t *testing.T) {
+	require.Equal(t, int64(0), quotaAlertType(100, 0))
+	require.Equal(t, int64(0), quotaAlertType(89, 100))
+	require.Equal(t, AlertTypeQuotaWarning, quotaAlertType(90, 100))
+	require.Equal(t, AlertTypeQuotaFull, quotaAlertType(100, 100))
+}
@@ -2,17 +2,20 @@ package buse
 
 import (
 	"fmt"
-	"io"
 	"os"
 	"os/exec"
 	"path"
 	"strings"
+	"sync"
 	"syscall"
 
 	"go.pedge.io/dlog"
 
+	"github.com/docker/docker/pkg/mount"
 	"github.com/libopenstorage/openstorage/api"
 	"github.com/libopenstorage/openstorage/cluster"
+	"github.com/libopenstorage/openstorage/pkg/chaos"
+	"github.com/libopenstorage/openstorage/pkg/device"
 	"github.com/libopenstorage/openstorage/volume"
 	"github.com/libopenstorage/openstorage/volume/drivers/common"
 	"github.com/pborman/uuid"
@@ -28,6 +31,28 @@ const (
 	BuseDBKey = "OpenStorageBuseKey"
 	// BuseMountPath mount path for openstorage
 	BuseMountPath = "/var/lib/openstorage/buse/"
+	// NBDPrefix is the path prefix of the NBD devices
+	NBDPrefix = "/dev/nbd"
+	// nbdOwner owns the NBD devices of a host in the device allocator.
+	nbdOwner = "nbd"
+)
+
+const (
+	// Defined in <linux/falloc.h>:
+	fallocKeepSize  = 0x01
+	fallocPunchHole = 0x02
+)
+
+var (
+	koStrayCreate = chaos.Add("buse", "create", "create block file before DB")
+	koStrayAttach = chaos.Add("buse", "attach", "connect NBD device before DB")
+	koMount       = chaos.Add("buse", "mount", "mount NBD device")
+	koStrayDelete = chaos.Add("buse", "delete", "remove block file before DB")
+)
+
+var (
+	nbdAllocatorLock sync.Mutex
+	nbdAllocator     *device.Allocator
 )
 
 // Implements the open storage volume interface.
@@ -35,6 +60,9 @@ type driver struct {
 	volume.IODriver
 	volume.StoreEnumerator
 	volume.StatsDriver
+	volume.ExportDriver
+	lock        sync.Mutex
+	devices     *device.Allocator
 	buseDevices map[string]*buseDev
 }
 
@@ -53,44 +81,69 @@ func (d *buseDev) WriteAt(b []byte, off int64) (n int, err error) {
 	return d.f.WriteAt(b, off)
 }
 
-func copyFile(source string, dest string) (err error) {
-	sourcefile, err := os.Open(source)
-	if err != nil {
-		return err
-	}
+// Sync commits the block file to stable storage.
+func (d *buseDev) Sync() error {
+	return d.f.Sync()
+}
 
-	defer sourcefile.Close()
+// Discard punches a hole in the block file, so that the space is returned to
+// the host.
+func (d *buseDev) Discard(off int64, length int64) error {
+	return syscall.Fallocate(
+		int(d.f.Fd()),
+		fallocKeepSize|fallocPunchHole,
+		off,
+		length,
+	)
+}
+
+// NBDAllocator returns the allocator of the NBD devices of this host. It is
+// shared by every driver that exports its volumes over NBD, so that no two
+// volumes are ever assigned the same device.
+func NBDAllocator() (*device.Allocator, error) {
+	InitNBD()
 
-	destfile, err := os.Create(dest)
+	nbdAllocatorLock.Lock()
+	defer nbdAllocatorLock.Unlock()
+	if nbdAllocator != nil {
+		return nbdAllocator, nil
+	}
+	hostname, err := os.Hostname()
 	if err != nil {
-		return err
+		return nil, err
 	}
-
-	defer destfile.Close()
-
-	_, err = io.Copy(destfile, sourcefile)
-	if err == nil {
-		sourceinfo, err := os.Stat(source)
-		if err != nil {
-			err = os.Chmod(dest, sourceinfo.Mode())
-		}
-
+	// Devices still connected after InitNBD are owned by someone else, so
+	// the devices assigned before a restart are released.
+	devices, err := device.NewAllocator(
+		kvdb.Instance(),
+		hostname,
+		nbdOwner,
+		device.Numbers(NBDPrefix, "", 0, Count()),
+		Busy,
+	)
+	if err != nil {
+		return nil, err
 	}
-
-	return
+	nbdAllocator = devices
+	return nbdAllocator, nil
 }
 
 // Init intialized the buse driver
 func Init(params map[string]string) (volume.VolumeDriver, error) {
-	nbdInit()
+	devices, err := NBDAllocator()
+	if err != nil {
+		return nil, err
+	}
 
 	inst := &driver{
 		IODriver: volume.IONotSupported,
 		StoreEnumerator: common.NewDefaultStoreEnumerator(Name,
 			kvdb.Instance()),
-		StatsDriver: volume.StatsNotSupported,
+		StatsDriver:  volume.StatsNotSupported,
+		ExportDriver: volume.ExportNotSupported,
+		devices:      devices,
+		buseDevices:  make(map[string]*buseDev),
 	}
-	inst.buseDevices = make(map[string]*buseDev)
 	if err := os.MkdirAll(BuseMountPath, 0744); err != nil {
 		return nil, err
 	}
@@ -102,7 +155,13 @@ func Init(params map[string]string) (volume.VolumeDriver, error) {
 		for _, info := range volumeInfo {
 			if info.Status == api.VolumeStatus_VOLUME_STATUS_NONE {
 				info.Status = api.VolumeStatus_VOLUME_STATUS_UP
-				inst.UpdateVol(info)
+			}
+			if err := inst.recover(info); err != nil {
+				dlog.Warnf("Failed to recover BUSE volume %v: %v", info.Id, err)
+				info.Status = api.VolumeStatus_VOLUME_STATUS_DOWN
+			}
+			if err := inst.UpdateVol(info); err != nil {
+				dlog.Warnf("Failed to update BUSE volume %v: %v", info.Id, err)
 			}
 		}
 	} else {
@@ -121,6 +180,133 @@ func Init(params map[string]string) (volume.VolumeDriver, error) {
 	return inst, nil
 }
 
+// recover re-opens the block file of a volume after a restart. Volumes that
+// were attached are reconnected to their NBD device, which InitNBD
+// disconnected, so that the mounts still holding the device are served
+// again. The mounts are detached only if the device cannot be reconnected.
+func (d *driver) recover(v *api.Volume) error {
+	bd, err := d.open(v.Id, int64(v.Spec.Size), os.O_RDWR)
+	if err != nil {
+		return err
+	}
+	if len(v.DevicePath) == 0 {
+		v.AttachPath = nil
+		v.State = api.VolumeState_VOLUME_STATE_AVAILABLE
+		return nil
+	}
+	dev := v.DevicePath
+	if err = d.devices.Reserve(dev); err == nil {
+		if _, err = bd.nbd.Connect(dev); err != nil {
+			d.disconnect(bd, dev)
+		}
+	}
+	if err == nil {
+		dlog.Infof("BUSE reconnected volume %v to NBD device %s", v.Id, dev)
+		d.adoptMounts(v)
+		return nil
+	}
+
+	dlog.Warnf("Failed to reconnect volume %v to NBD device %s: %v",
+		v.Id, dev, err)
+	for _, p := range v.AttachPath {
+		if len(p) != 0 {
+			dlog.Infof("Detaching stale BUSE mount %v of volume %v", p, v.Id)
+			syscall.Unmount(p, syscall.MNT_DETACH)
+		}
+	}
+	v.AttachPath = nil
+	v.DevicePath = ""
+	v.State = api.VolumeState_VOLUME_STATE_AVAILABLE
+	if dev, err = d.connect(bd); err != nil {
+		return err
+	}
+	dlog.Infof("BUSE connected volume %v to NBD device %s", v.Id, dev)
+	v.DevicePath = dev
+	v.State = api.VolumeState_VOLUME_STATE_ATTACHED
+	return nil
+}
+
+// adoptMounts keeps the attach paths of a volume that are still mounted
+// from its device, and throttles them again.
+func (d *driver) adoptMounts(v *api.Volume) {
+	info, err := mount.GetMounts()
+	if err != nil {
+		dlog.Warnf("Failed to read the mounts of volume %v: %v", v.Id, err)
+		return
+	}
+	mounted := make(map[string]bool)
+	for _, m := range info {
+		if m.Source == v.DevicePath {
+			mounted[m.Mountpoint] = true
+		}
+	}
+	paths := make([]string, 0, len(v.AttachPath))
+	for _, p := range v.AttachPath {
+		if mounted[p] {
+			paths = append(paths, p)
+			common.WatchThrottle(d, v.Id, p)
+		}
+	}
+	v.AttachPath = paths
+}
+
+// open opens the block file of a volume and registers it with NBD.
+func (d *driver) open(volumeID string, size int64, flag int) (*buseDev, error) {
+	buseFile := path.Join(BuseMountPath, volumeID)
+	f, err := os.OpenFile(buseFile, flag, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if flag&os.O_CREATE != 0 {
+		if err := f.Truncate(size); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	bd := &buseDev{
+		file: buseFile,
+		f:    f,
+	}
+	if bd.nbd = Create(bd, volumeID, size); bd.nbd == nil {
+		f.Close()
+		return nil, fmt.Errorf("Cannot create NBD device for %s", volumeID)
+	}
+	d.lock.Lock()
+	d.buseDevices[volumeID] = bd
+	d.lock.Unlock()
+	return bd, nil
+}
+
+// connect connects a BUSE device to a free NBD device.
+func (d *driver) connect(bd *buseDev) (string, error) {
+	dev, err := d.devices.Assign()
+	if err != nil {
+		return "", err
+	}
+	if _, err := bd.nbd.Connect(dev); err != nil {
+		bd.nbd.Disconnect()
+		d.devices.Release(dev)
+		return "", err
+	}
+	return dev, nil
+}
+
+// disconnect disconnects a BUSE device from its NBD device.
+func (d *driver) disconnect(bd *buseDev, dev string) {
+	bd.nbd.Disconnect()
+	d.devices.Release(dev)
+}
+
+func (d *driver) buseDev(volumeID string) (*buseDev, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	bd, ok := d.buseDevices[volumeID]
+	if !ok {
+		return nil, fmt.Errorf("Cannot locate a BUSE device for %s", volumeID)
+	}
+	return bd, nil
+}
+
 //
 // These functions below implement the volume driver interface.
 //
@@ -172,42 +358,40 @@ func (d *driver) Create(
 		return "", fmt.Errorf("Missing volume format: buse")
 	}
 	// Create a file on the local buse path with this UUID.
-	buseFile := path.Join(BuseMountPath, volumeID)
-	f, err := os.Create(buseFile)
+	bd, err := d.open(
+		volumeID,
+		int64(spec.Size),
+		os.O_RDWR|os.O_CREATE|os.O_EXCL,
+	)
 	if err != nil {
 		dlog.Println(err)
 		return "", err
 	}
 
-	if err := f.Truncate(int64(spec.Size)); err != nil {
-		dlog.Println(err)
-		return "", err
-	}
-
-	bd := &buseDev{
-		file: buseFile,
-		f:    f,
-	}
-	nbd := Create(bd, volumeID, int64(spec.Size))
-	bd.nbd = nbd
-
+	// Connect only to format the volume, it is connected again on Attach.
 	dlog.Infof("Connecting to NBD...")
-	dev, err := bd.nbd.Connect()
+	dev, err := d.connect(bd)
 	if err != nil {
 		dlog.Println(err)
+		d.remove(volumeID)
 		return "", err
 	}
 
 	dlog.Infof("Formatting %s with %v", dev, spec.Format)
 	cmd := "/sbin/mkfs." + spec.Format.SimpleString()
 	o, err := exec.Command(cmd, dev).Output()
+	d.disconnect(bd, dev)
 	if err != nil {
 		dlog.Warnf("Failed to run command %v %v: %v", cmd, dev, o)
+		d.remove(volumeID)
 		return "", err
 	}
 
-	dlog.Infof("BUSE mapped NBD device %s (size=%v) to block file %s", dev,
-		spec.Size, buseFile)
+	dlog.Infof("BUSE formatted block file %s (size=%v)", bd.file, spec.Size)
+
+	if err := chaos.Now(koStrayCreate); err != nil {
+		return "", err
+	}
 
 	v := common.NewVolume(
 		volumeID,
@@ -216,12 +400,10 @@ func (d *driver) Create(
 		source,
 		spec,
 	)
-	v.DevicePath = dev
-
-	d.buseDevices[dev] = bd
 
 	err = d.CreateVol(v)
 	if err != nil {
+		d.remove(volumeID)
 		return "", err
 	}
 	return v.Id, err
@@ -234,20 +416,21 @@ func (d *driver) Delete(volumeID string) error {
 		return err
 	}
 
-	bd, ok := d.buseDevices[v.DevicePath]
-	if !ok {
-		err = fmt.Errorf("Cannot locate a BUSE device for %s", v.DevicePath)
-		dlog.Println(err)
-		return err
+	if len(v.AttachPath) > 0 {
+		return fmt.Errorf("Volume %v is mounted at %v", volumeID, v.AttachPath)
 	}
 
 	// Clean up buse block file and close the NBD connection.
-	os.Remove(bd.file)
-	bd.f.Close()
-	bd.nbd.Disconnect()
+	d.remove(volumeID)
+	if len(v.DevicePath) != 0 {
+		d.devices.Release(v.DevicePath)
+	}
+
+	if err := chaos.Now(koStrayDelete); err != nil {
+		return err
+	}
 
-	dlog.Infof("BUSE deleted volume %v at NBD device %s", volumeID,
-		v.DevicePath)
+	dlog.Infof("BUSE deleted volume %v", volumeID)
 
 	if err := d.DeleteVol(volumeID); err != nil {
 		dlog.Println(err)
@@ -257,6 +440,53 @@ func (d *driver) Delete(volumeID string) error {
 	return nil
 }
 
+// remove disconnects the BUSE device of a volume and removes its block file.
+func (d *driver) remove(volumeID string) {
+	d.lock.Lock()
+	bd, ok := d.buseDevices[volumeID]
+	delete(d.buseDevices, volumeID)
+	d.lock.Unlock()
+	Remove(volumeID)
+	if ok {
+		bd.f.Close()
+	}
+	os.Remove(path.Join(BuseMountPath, volumeID))
+}
+
+// UsedSize returns the bytes allocated to the block file of a volume, which
+// is less than its size once unused blocks are trimmed.
+func (d *driver) UsedSize(volumeID string) (uint64, error) {
+	if _, err := d.GetVol(volumeID); err != nil {
+		return 0, err
+	}
+	var st syscall.Stat_t
+	if err := syscall.Stat(path.Join(BuseMountPath, volumeID), &st); err != nil {
+		return 0, err
+	}
+	return uint64(st.Blocks) * 512, nil
+}
+
+// Fstrim trims the filesystem of a mounted volume. The NBD device turns the
+// discards into holes in the block file.
+func (d *driver) Fstrim(volumeID string) (uint64, error) {
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		return 0, err
+	}
+	if len(v.DevicePath) == 0 {
+		return 0, volume.ErrVolDetached
+	}
+	if len(v.AttachPath) == 0 {
+		return 0, fmt.Errorf("Volume %q is not mounted", volumeID)
+	}
+	trimmed, err := common.Fstrim(v.AttachPath[0])
+	if err != nil {
+		return 0, err
+	}
+	dlog.Infof("BUSE trimmed %v bytes of volume %v", trimmed, volumeID)
+	return trimmed, nil
+}
+
 func (d *driver) MountedAt(mountpath string) string {
 	return ""
 }
@@ -266,20 +496,38 @@ func (d *driver) Mount(volumeID string, mountpath string) error {
 	if err != nil {
 		return fmt.Errorf("Failed to locate volume %q", volumeID)
 	}
-	if len(v.AttachPath) > 0 && len(v.AttachPath) > 0 {
-		return fmt.Errorf("Volume %q already mounted at %q", volumeID, v.AttachPath[0])
+	if err := common.CheckAccess(
+		v,
+		mountpath,
+		api.AccessMode_ACCESS_MODE_READ_WRITE_ONCE,
+	); err != nil {
+		return err
+	}
+	if len(v.DevicePath) == 0 {
+		return fmt.Errorf("Volume %q is not attached", volumeID)
+	}
+	if err := chaos.Now(koMount); err != nil {
+		return err
 	}
-	if err := syscall.Mount(v.DevicePath, mountpath, v.Spec.Format.SimpleString(), 0, ""); err != nil {
+	flags, data := common.MountFlags(v)
+	if err := syscall.Mount(
+		v.DevicePath,
+		mountpath,
+		v.Spec.Format.SimpleString(),
+		flags,
+		data,
+	); err != nil {
 		return fmt.Errorf("Failed to mount %v at %v: %v", v.DevicePath, mountpath, err)
 	}
 
 	dlog.Infof("BUSE mounted NBD device %s at %s", v.DevicePath, mountpath)
 
-	if v.AttachPath == nil {
-		v.AttachPath = make([]string, 1)
+	common.AddAttachPath(v, mountpath)
+	if err := d.UpdateVol(v); err != nil {
+		return err
 	}
-	v.AttachPath[0] = mountpath
-	return d.UpdateVol(v)
+	common.WatchThrottle(d, volumeID, mountpath)
+	return nil
 }
 
 func (d *driver) Unmount(volumeID string, mountpath string) error {
@@ -290,10 +538,14 @@ func (d *driver) Unmount(volumeID string, mountpath string) error {
 	if len(v.AttachPath) == 0 || len(v.AttachPath[0]) == 0 {
 		return fmt.Errorf("Device %v not mounted", volumeID)
 	}
-	if err := syscall.Unmount(v.AttachPath[0], 0); err != nil {
+	if len(mountpath) == 0 {
+		mountpath = v.AttachPath[0]
+	}
+	if err := syscall.Unmount(mountpath, 0); err != nil {
 		return err
 	}
-	v.AttachPath = nil
+	common.StopThrottle(mountpath)
+	common.RemoveAttachPath(v, mountpath)
 	return d.UpdateVol(v)
 }
 
@@ -312,7 +564,7 @@ func (d *driver) Snapshot(volumeID string, readonly bool, locator *api.VolumeLoc
 	}
 
 	// BUSE does not support snapshots, so just copy the block files.
-	err = copyFile(BuseMountPath+volumeID, BuseMountPath+newVolumeID)
+	err = common.CopyFile(BuseMountPath+volumeID, BuseMountPath+newVolumeID)
 	if err != nil {
 		d.Delete(newVolumeID)
 		return "", nil
@@ -321,14 +573,19 @@ func (d *driver) Snapshot(volumeID string, readonly bool, locator *api.VolumeLoc
 	return newVolumeID, nil
 }
 
+// Set updates the locator and the I/O limits of the volume.
 func (d *driver) Set(volumeID string, locator *api.VolumeLocator, spec *api.VolumeSpec) error {
-	if spec != nil {
-		return volume.ErrNotSupported
-	}
 	v, err := d.GetVol(volumeID)
 	if err != nil {
 		return err
 	}
+	if common.SetIoLimits(v, spec) {
+		if err := common.Throttle(v); err != nil {
+			return err
+		}
+	} else if spec != nil {
+		return volume.ErrNotSupported
+	}
 	if locator != nil {
 		v.Locator = locator
 	}
@@ -336,12 +593,57 @@ func (d *driver) Set(volumeID string, locator *api.VolumeLocator, spec *api.Volu
 }
 
 func (d *driver) Attach(volumeID string, attachOptions map[string]string) (string, error) {
-	// Nothing to do on attach.
-	return path.Join(BuseMountPath, volumeID), nil
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		return "", err
+	}
+	if len(v.DevicePath) != 0 {
+		return v.DevicePath, nil
+	}
+	bd, err := d.buseDev(volumeID)
+	if err != nil {
+		return "", err
+	}
+	dev, err := d.connect(bd)
+	if err != nil {
+		return "", err
+	}
+	if err := chaos.Now(koStrayAttach); err != nil {
+		return "", err
+	}
+	v.DevicePath = dev
+	v.State = api.VolumeState_VOLUME_STATE_ATTACHED
+	if err := d.UpdateVol(v); err != nil {
+		d.disconnect(bd, dev)
+		return "", err
+	}
+	dlog.Infof("BUSE attached volume %v to NBD device %s", volumeID, dev)
+	return dev, nil
 }
 
 func (d *driver) Detach(volumeID string) error {
-	// Nothing to do on detach.
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		return err
+	}
+	if len(v.DevicePath) == 0 {
+		return nil
+	}
+	if len(v.AttachPath) > 0 {
+		return fmt.Errorf("Volume %v is mounted at %v", volumeID, v.AttachPath)
+	}
+	bd, err := d.buseDev(volumeID)
+	if err != nil {
+		return err
+	}
+	dev := v.DevicePath
+	v.DevicePath = ""
+	v.State = api.VolumeState_VOLUME_STATE_AVAILABLE
+	if err := d.UpdateVol(v); err != nil {
+		return err
+	}
+	d.disconnect(bd, dev)
+	dlog.Infof("BUSE detached volume %v from NBD device %s", volumeID, dev)
 	return nil
 }
 
@@ -6,11 +6,11 @@ package buse
 
 import (
 	"encoding/binary"
-	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"os/signal"
+	"path"
 	"runtime"
 	"sync"
 	"syscall"
@@ -39,6 +39,12 @@ const (
 	NBD_CMD_DISC  = 2
 	NBD_CMD_FLUSH = 3
 	NBD_CMD_TRIM  = 4
+	// NBD_CMD_WRITE_ZEROES is 6, 5 is reserved
+	NBD_CMD_WRITE_ZEROES = 6
+	// command flags are sent in the upper 16 bits of the type field
+	NBD_CMD_MASK_COMMAND = 0xffff
+	NBD_CMD_FLAG_FUA     = (1 << 16) // Force Unit Access
+	NBD_CMD_FLAG_NO_HOLE = (1 << 17) // Do not punch holes on WRITE_ZEROES
 	// values for flags field
 	NBD_FLAG_HAS_FLAGS  = (1 << 0) // nbd-server supports flags
 	NBD_FLAG_READ_ONLY  = (1 << 1) // device is read-only
@@ -46,6 +52,10 @@ const (
 	NBD_FLAG_SEND_FUA   = (1 << 3) // Send FUA (Force Unit Access)
 	NBD_FLAG_ROTATIONAL = (1 << 4) // Use elevator algorithm - rotational media
 	NBD_FLAG_SEND_TRIM  = (1 << 5) // Send TRIM (discard)
+	// Send WRITE_ZEROES
+	NBD_FLAG_SEND_WRITE_ZEROES = (1 << 6)
+	// errors sent in the reply error field
+	NBD_EIO = 5
 
 	// These are sent over the network in the request/reply magic fields
 	NBD_REQUEST_MAGIC = 0x25609513
@@ -68,6 +78,19 @@ type Device interface {
 	WriteAt(b []byte, off int64) (n int, err error)
 }
 
+// Syncer is implemented by devices that cache writes. NBD_CMD_FLUSH is
+// only negotiated for devices that implement it.
+type Syncer interface {
+	Sync() error
+}
+
+// Discarder is implemented by devices that can deallocate blocks, which then
+// read back as zeroes. NBD_CMD_TRIM and NBD_CMD_WRITE_ZEROES are only
+// negotiated for devices that implement it.
+type Discarder interface {
+	Discard(off int64, length int64) error
+}
+
 type request struct {
 	magic  uint32
 	typus  uint32
@@ -96,8 +119,17 @@ var (
 	nbdDevices   map[string]*NBD
 	globalMutex  *sync.Mutex
 	shuttingDown bool
+	nbdOnce      sync.Once
 )
 
+// InitNBD loads the nbd module and disconnects the NBD devices left over from
+// a previous run. It must be called before Create and only runs once, so that
+// the drivers that export their devices through NBD do not disconnect each
+// other.
+func InitNBD() {
+	nbdOnce.Do(nbdInit)
+}
+
 // Create creates a NBD type interface
 func Create(device Device, id string, size int64) *NBD {
 	if shuttingDown {
@@ -153,47 +185,49 @@ func (nbd *NBD) Size(size int64) (err error) {
 	return err
 }
 
-// Connect the network block device.
-func (nbd *NBD) Connect() (dev string, err error) {
+// Connect the network block device to dev.
+func (nbd *NBD) Connect(dev string) (string, error) {
+	if Busy(dev) {
+		return "", fmt.Errorf("NBD device %v is busy", dev)
+	}
+
 	pair, err := syscall.Socketpair(syscall.SOCK_STREAM, syscall.AF_UNIX, 0)
 	if err != nil {
 		return "", err
 	}
 
-	// Find free NBD device.
-	for i := 0; ; i++ {
-		dev = fmt.Sprintf("/dev/nbd%d", i)
-		if _, err = os.Stat(dev); os.IsNotExist(err) {
-			dev = ""
-			return "", errors.New("No more NBD devices left.")
-		}
-		if _, err = os.Stat(fmt.Sprintf("/sys/block/nbd%d/pid", i)); !os.IsNotExist(err) {
-			continue // Busy.
-		}
-
-		dlog.Infof("Attempting to open device %v", dev)
-		if nbd.deviceFile, err = os.Open(dev); err == nil {
-			// Possible candidate.
-			ioctl(nbd.deviceFile.Fd(), BLKROSET, 0)
-			if err := ioctl(nbd.deviceFile.Fd(), NBD_SET_SOCK, uintptr(pair[0])); err == nil {
-				nbd.socket = pair[1]
-				break // Success.
-			}
+	dlog.Infof("Attempting to open device %v", dev)
+	if nbd.deviceFile, err = os.Open(dev); err != nil {
+		syscall.Close(pair[0])
+		syscall.Close(pair[1])
+		return "", err
+	}
+	ioctl(nbd.deviceFile.Fd(), BLKROSET, 0)
+	if err = ioctl(nbd.deviceFile.Fd(), NBD_SET_SOCK, uintptr(pair[0])); err != nil {
+		nbd.deviceFile.Close()
+		nbd.deviceFile = nil
+		syscall.Close(pair[0])
+		syscall.Close(pair[1])
+		return "", &os.PathError{
+			Op:   dev,
+			Path: "ioctl NBD_SET_SOCK",
+			Err:  err,
 		}
 	}
+	nbd.socket = pair[1]
 
 	// Setup.
 	if err = nbd.Size(nbd.size); err != nil {
 		// Already set by nbd.Size().
-	} else if err = ioctl(nbd.deviceFile.Fd(), NBD_SET_FLAGS, 1); err != nil {
+	} else if err = ioctl(nbd.deviceFile.Fd(), NBD_SET_FLAGS, nbd.flags()); err != nil {
 		err = &os.PathError{
 			Op:   nbd.deviceFile.Name(),
 			Path: "ioctl NBD_SET_FLAGS",
 			Err:  err,
 		}
 	} else {
-		go nbd.connect()
-		go nbd.handle()
+		go nbd.connect(nbd.deviceFile, pair[0])
+		go nbd.handle(nbd.socket)
 	}
 
 	nbd.devicePath = dev
@@ -201,6 +235,18 @@ func (nbd *NBD) Connect() (dev string, err error) {
 	return dev, err
 }
 
+// flags returns the transmission flags supported by the device.
+func (nbd *NBD) flags() uintptr {
+	flags := uintptr(NBD_FLAG_HAS_FLAGS)
+	if _, ok := nbd.device.(Syncer); ok {
+		flags |= NBD_FLAG_SEND_FLUSH
+	}
+	if _, ok := nbd.device.(Discarder); ok {
+		flags |= NBD_FLAG_SEND_TRIM | NBD_FLAG_SEND_WRITE_ZEROES
+	}
+	return flags
+}
+
 // Disconnect disconnects the network block device
 func (nbd *NBD) Disconnect() {
 	nbd.mutex.Lock()
@@ -230,24 +276,53 @@ func (nbd *NBD) Disconnect() {
 	dlog.Infof("Disconnected device %v", nbd.devicePath)
 }
 
-func (nbd *NBD) connect() {
+// Remove disconnects the network block device registered as id and forgets
+// about it.
+func Remove(id string) {
+	globalMutex.Lock()
+	nbd, ok := nbdDevices[id]
+	delete(nbdDevices, id)
+	globalMutex.Unlock()
+	if ok {
+		nbd.Disconnect()
+	}
+}
+
+// Busy returns true if dev is connected to a userspace server.
+func Busy(dev string) bool {
+	_, err := os.Stat(path.Join("/sys/block", path.Base(dev), "pid"))
+	return !os.IsNotExist(err)
+}
+
+// Count returns the number of NBD devices on this host.
+func Count() int {
+	for i := 0; ; i++ {
+		if _, err := os.Stat(fmt.Sprintf("/dev/nbd%d", i)); os.IsNotExist(err) {
+			return i
+		}
+	}
+}
+
+func (nbd *NBD) connect(deviceFile *os.File, socket int) {
 	runtime.LockOSThread()
 	defer runtime.UnlockOSThread()
 
 	// NBD_CONNECT does not return until disconnect.
-	ioctl(nbd.deviceFile.Fd(), NBD_CONNECT, 0)
+	ioctl(deviceFile.Fd(), NBD_CONNECT, 0)
+	syscall.Close(socket)
 
-	dlog.Infof("Closing device file %s", nbd.devicePath)
+	dlog.Infof("Closing device file %s", deviceFile.Name())
 }
 
-// Handle block requests.
-func (nbd *NBD) handle() {
+// Handle block requests on socket until it is no longer the connected
+// socket, so that a reconnected device is never served by a stale handler.
+func (nbd *NBD) handle(socket int) {
 	buf := make([]byte, 2<<19)
 	var x request
 
 	for {
-		bytes, err := syscall.Read(nbd.socket, buf[0:28])
-		if nbd.deviceFile == nil {
+		bytes, err := syscall.Read(socket, buf[0:28])
+		if nbd.deviceFile == nil || nbd.socket != socket {
 			dlog.Infof("Disconnecting device %s", nbd.devicePath)
 			return
 		}
@@ -268,32 +343,47 @@ func (nbd *NBD) handle() {
 		case NBD_REPLY_MAGIC:
 			fallthrough
 		case NBD_REQUEST_MAGIC:
-			switch x.typus {
+			switch x.typus & NBD_CMD_MASK_COMMAND {
 			case NBD_CMD_READ:
 				nbd.device.ReadAt(buf[16:16+x.len], int64(x.from))
 				binary.BigEndian.PutUint32(buf[0:4], NBD_REPLY_MAGIC)
 				binary.BigEndian.PutUint32(buf[4:8], 0)
-				syscall.Write(nbd.socket, buf[0:16+x.len])
+				syscall.Write(socket, buf[0:16+x.len])
 			case NBD_CMD_WRITE:
-				n, _ := syscall.Read(nbd.socket, buf[28:28+x.len])
+				n, _ := syscall.Read(socket, buf[28:28+x.len])
 				for uint32(n) < x.len {
-					m, _ := syscall.Read(nbd.socket, buf[28+n:28+x.len])
+					m, _ := syscall.Read(socket, buf[28+n:28+x.len])
 					n += m
 				}
 				nbd.device.WriteAt(buf[28:28+x.len], int64(x.from))
 				binary.BigEndian.PutUint32(buf[0:4], NBD_REPLY_MAGIC)
 				binary.BigEndian.PutUint32(buf[4:8], 0)
-				syscall.Write(nbd.socket, buf[0:16])
+				syscall.Write(socket, buf[0:16])
 			case NBD_CMD_DISC:
 				dlog.Infof("Disconnecting device %s", nbd.devicePath)
 				nbd.Disconnect()
 				return
 			case NBD_CMD_FLUSH:
-				fallthrough
+				var err error
+				if s, ok := nbd.device.(Syncer); ok {
+					err = s.Sync()
+				}
+				nbd.reply(socket, buf, err)
 			case NBD_CMD_TRIM:
-				binary.BigEndian.PutUint32(buf[0:4], NBD_REPLY_MAGIC)
-				binary.BigEndian.PutUint32(buf[4:8], 1)
-				syscall.Write(nbd.socket, buf[0:16])
+				var err error
+				if d, ok := nbd.device.(Discarder); ok {
+					err = d.Discard(int64(x.from), int64(x.len))
+				}
+				nbd.reply(socket, buf, err)
+			case NBD_CMD_WRITE_ZEROES:
+				var err error
+				d, ok := nbd.device.(Discarder)
+				if ok && x.typus&NBD_CMD_FLAG_NO_HOLE == 0 {
+					err = d.Discard(int64(x.from), int64(x.len))
+				} else {
+					err = nbd.zero(int64(x.from), int64(x.len))
+				}
+				nbd.reply(socket, buf, err)
 			default:
 				dlog.Errorf("Unknown command received on device %s", nbd.devicePath)
 				nbd.Disconnect()
@@ -307,6 +397,36 @@ func (nbd *NBD) handle() {
 	}
 }
 
+// reply sends the reply to the request in buf.
+func (nbd *NBD) reply(socket int, buf []byte, err error) {
+	var nbdErr uint32
+	if err != nil {
+		dlog.Errorf("Error serving request on device %s: %v",
+			nbd.devicePath, err)
+		nbdErr = NBD_EIO
+	}
+	binary.BigEndian.PutUint32(buf[0:4], NBD_REPLY_MAGIC)
+	binary.BigEndian.PutUint32(buf[4:8], nbdErr)
+	syscall.Write(socket, buf[0:16])
+}
+
+// zero writes length zero bytes at off without deallocating blocks.
+func (nbd *NBD) zero(off int64, length int64) error {
+	zeroes := make([]byte, 64*1024)
+	for length > 0 {
+		n := int64(len(zeroes))
+		if length < n {
+			n = length
+		}
+		if _, err := nbd.device.WriteAt(zeroes[:n], off); err != nil {
+			return err
+		}
+		off += n
+		length -= n
+	}
+	return nil
+}
+
 func nbdInit() {
 	if _, err := os.Stat("/usr/sbin/modprobe"); err == nil {
 		exec.Command("/usr/sbin/modprobe", "nbd").Output()
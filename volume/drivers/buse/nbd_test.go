@@ -0,0 +1,104 @@
+package buse
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestNBD(t *testing.T) (*NBD, *buseDev, int) {
+	f, err := ioutil.TempFile("", "nbd")
+	require.NoError(t, err)
+	require.NoError(t, f.Truncate(1024*1024))
+	pair, err := syscall.Socketpair(syscall.SOCK_STREAM, syscall.AF_UNIX, 0)
+	require.NoError(t, err)
+	bd := &buseDev{file: f.Name(), f: f}
+	nbd := &NBD{
+		device:     bd,
+		deviceFile: f,
+		size:       1024 * 1024,
+		socket:     pair[1],
+		mutex:      &sync.Mutex{},
+	}
+	go nbd.handle(pair[1])
+	return nbd, bd, pair[0]
+}
+
+func sendRequest(t *testing.T, socket int, typus uint32, from uint64, data []byte, length uint32) uint32 {
+	buf := make([]byte, 28+len(data))
+	binary.BigEndian.PutUint32(buf[0:4], NBD_REQUEST_MAGIC)
+	binary.BigEndian.PutUint32(buf[4:8], typus)
+	binary.BigEndian.PutUint64(buf[8:16], 1)
+	binary.BigEndian.PutUint64(buf[16:24], from)
+	binary.BigEndian.PutUint32(buf[24:28], length)
+	copy(buf[28:], data)
+	_, err := syscall.Write(socket, buf)
+	require.NoError(t, err)
+
+	reply := make([]byte, 16)
+	n, err := syscall.Read(socket, reply)
+	require.NoError(t, err)
+	require.Equal(t, 16, n)
+	require.Equal(t, uint32(NBD_REPLY_MAGIC), binary.BigEndian.Uint32(reply[0:4]))
+	return binary.BigEndian.Uint32(reply[4:8])
+}
+
+func allocated(t *testing.T, f *os.File) int64 {
+	var st syscall.Stat_t
+	require.NoError(t, syscall.Fstat(int(f.Fd()), &st))
+	return st.Blocks * 512
+}
+
+func TestNBDFlags(t *testing.T) {
+	nbd := &NBD{device: &buseDev{}}
+	require.Equal(t, uintptr(NBD_FLAG_HAS_FLAGS|NBD_FLAG_SEND_FLUSH|
+		NBD_FLAG_SEND_TRIM|NBD_FLAG_SEND_WRITE_ZEROES), nbd.flags())
+}
+
+func TestNBDTrim(t *testing.T) {
+	nbd, bd, socket := newTestNBD(t)
+	defer os.Remove(bd.file)
+	defer syscall.Close(socket)
+
+	data := make([]byte, 64*1024)
+	for i := range data {
+		data[i] = 0xff
+	}
+	require.Zero(t, sendRequest(t, socket, NBD_CMD_WRITE, 0, data, uint32(len(data))))
+	require.Zero(t, sendRequest(t, socket, NBD_CMD_FLUSH, 0, nil, 0))
+	require.True(t, allocated(t, bd.f) >= int64(len(data)))
+
+	require.Zero(t, sendRequest(t, socket, NBD_CMD_TRIM, 0, nil, uint32(len(data))))
+	require.Zero(t, allocated(t, bd.f))
+	b := make([]byte, len(data))
+	_, err := bd.ReadAt(b, 0)
+	require.NoError(t, err)
+	require.Equal(t, make([]byte, len(b)), b)
+
+	require.Zero(t, sendRequest(t, socket, NBD_CMD_WRITE_ZEROES|NBD_CMD_FLAG_NO_HOLE,
+		0, nil, 4096))
+	require.True(t, allocated(t, bd.f) >= 4096)
+	require.Zero(t, sendRequest(t, socket, NBD_CMD_WRITE_ZEROES, 0, nil, 4096))
+	require.Zero(t, allocated(t, bd.f))
+
+	binary.BigEndian.PutUint32(data[0:4], NBD_REQUEST_MAGIC)
+	binary.BigEndian.PutUint32(data[4:8], NBD_CMD_DISC)
+	_, err = syscall.Write(socket, data[:28])
+	require.NoError(t, err)
+	for i := 0; i < 100; i++ {
+		nbd.mutex.Lock()
+		connected := nbd.IsConnected()
+		nbd.mutex.Unlock()
+		if !connected {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("NBD did not disconnect")
+}
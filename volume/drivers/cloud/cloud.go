@@ -0,0 +1,147 @@
+// Package cloud implements a volume driver on top of the block storage of a
+// cloud. Providers adapt the disks of a cloud to the driver, which attaches,
+// formats, mounts and enumerates them the same way for every cloud.
+package cloud
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"syscall"
+
+	"github.com/libopenstorage/openstorage/api"
+)
+
+const (
+	// Type of the cloud drivers
+	Type = api.DriverType_DRIVER_TYPE_BLOCK
+	// GiB is the unit of disk sizes.
+	GiB = 1024 * 1024 * 1024
+)
+
+// DiskState is the state of a disk in its cloud.
+type DiskState int
+
+const (
+	// DiskPending disks are being created or deleted.
+	DiskPending DiskState = iota
+	// DiskAvailable disks are not attached to an instance.
+	DiskAvailable
+	// DiskAttaching disks are being attached to Disk.Instance.
+	DiskAttaching
+	// DiskAttached disks are attached to Disk.Instance.
+	DiskAttached
+	// DiskDetaching disks are being detached from Disk.Instance.
+	DiskDetaching
+	// DiskDeleted disks no longer exist.
+	DiskDeleted
+	// DiskError disks failed in their cloud.
+	DiskError
+)
+
+// Disk is a block storage volume of a cloud.
+type Disk struct {
+	// Id identifies the disk in its cloud.
+	Id string
+	// Zone is the zone or location of the disk.
+	Zone string
+	// Size of the disk in GiB.
+	Size int64
+	// Type is the name of the storage class of the disk in its cloud.
+	Type string
+	// Iops provisioned for the disk, zero if its type does not provision
+	// iops.
+	Iops int64
+	// Snapshot the disk was restored from.
+	Snapshot string
+	// State of the disk.
+	State DiskState
+	// Instance the disk is attached to.
+	Instance string
+	// Labels are the tags of the disk.
+	Labels map[string]string
+}
+
+// Provider manages the disks of a cloud for the instance the driver runs
+// on. Providers wait for their cloud to complete each call.
+type Provider interface {
+	// Instance returns the id of this instance.
+	Instance() string
+	// DiskType returns the disk type and iops for cos.
+	DiskType(cos api.CosType) (string, int64)
+	// Create creates a disk from template and tags it with labels. Disks
+	// with a template.Snapshot are restored from the snapshot, and default
+	// to its size.
+	Create(template *Disk, labels map[string]string) (*Disk, error)
+	// Delete deletes the disk or snapshot id.
+	Delete(id string) error
+	// Attach attaches the disk id to this instance and returns the path its
+	// device will appear at.
+	Attach(id string) (string, error)
+	// Detach detaches the disk id from this instance.
+	Detach(id string) error
+	// Inspect returns the disks ids.
+	Inspect(ids []string) ([]*Disk, error)
+	// Enumerate returns the disks tagged with labels.
+	Enumerate(labels map[string]string) ([]*Disk, error)
+	// DeviceMappings returns the disks attached to this instance by the
+	// paths of their devices.
+	DeviceMappings() (map[string]string, error)
+	// Snapshot snapshots the disk id and returns the id of the snapshot.
+	Snapshot(id string) (string, error)
+	// IsSnapshot returns true if id identifies a snapshot.
+	IsSnapshot(id string) bool
+	// ApplyTags adds labels to the tags of the disk id.
+	ApplyTags(id string, labels map[string]string) error
+	// RemoveTags removes the tags with the keys of labels from the disk id.
+	RemoveTags(id string, labels map[string]string) error
+	// Modify changes the size, type and iops of the disk template.Id to
+	// those set in template.
+	Modify(template *Disk) (*Disk, error)
+}
+
+// Runner runs the filesystem commands of the driver, so that unit tests
+// can replace them with a fake.
+type Runner interface {
+	// Run runs the command name with args and returns its output. It
+	// returns an *ExitError if the command exits with a non zero status.
+	Run(name string, args ...string) (string, error)
+}
+
+// ExitError is the error of a command that exited with a non zero status.
+type ExitError struct {
+	// Cmd is the command line.
+	Cmd string
+	// Status is the exit status.
+	Status int
+	// Output is the output of the command.
+	Output string
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("%v failed: exit status %v: %s", e.Cmd, e.Status, e.Output)
+}
+
+// ExecRunner runs commands on the host.
+type ExecRunner struct{}
+
+// Run runs name with args on the host.
+func (e *ExecRunner) Run(name string, args ...string) (string, error) {
+	cmd := name + " " + strings.Join(args, " ")
+	o, err := exec.Command(name, args...).CombinedOutput()
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		return "", &ExitError{
+			Cmd:    cmd,
+			Status: exitErr.Sys().(syscall.WaitStatus).ExitStatus(),
+			Output: strings.TrimSpace(string(o)),
+		}
+	} else if err != nil {
+		return "", fmt.Errorf("%v failed: %v", cmd, err)
+	}
+	return string(o), nil
+}
+
+// SizeGiB translates a size in bytes to GiB, rounding up.
+func SizeGiB(size uint64) int64 {
+	return int64((size + GiB - 1) / GiB)
+}
@@ -0,0 +1,553 @@
+package cloud
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"go.pedge.io/dlog"
+	"go.pedge.io/proto/time"
+
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/pkg/chaos"
+	"github.com/libopenstorage/openstorage/pkg/mount"
+	"github.com/libopenstorage/openstorage/volume"
+	"github.com/libopenstorage/openstorage/volume/drivers/common"
+	"github.com/portworx/kvdb"
+)
+
+const (
+	// deviceTimeout is how long attached disks take to appear on the host.
+	deviceTimeout = time.Minute
+)
+
+var (
+	koStrayCreate = chaos.Add("cloud", "create", "create in cloud before DB")
+	koStrayDelete = chaos.Add("cloud", "delete", "delete in cloud before DB")
+	koStrayAttach = chaos.Add("cloud", "attach", "attach in cloud before DB")
+	koMount       = chaos.Add("cloud", "mount", "mount attached disk")
+)
+
+// Driver is a volume driver for the disks of a Provider.
+type Driver struct {
+	volume.StatsDriver
+	volume.TrimDriver
+	volume.ExportDriver
+	volume.StoreEnumerator
+	volume.IODriver
+	name          string
+	provider      Provider
+	runner        Runner
+	mounter       mount.Manager
+	deviceTimeout time.Duration
+}
+
+// NewDriver returns a driver called name for the disks of provider. A nil
+// mountImpl mounts on the host.
+func NewDriver(
+	name string,
+	provider Provider,
+	runner Runner,
+	mountImpl mount.MountImpl,
+) (*Driver, error) {
+	mounter, err := mount.New(
+		mount.DeviceMount,
+		mountImpl,
+		[]string{"/dev/"},
+		mount.NewFileJournal(filepath.Join(volume.MountJournalBase, name)),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &Driver{
+		StatsDriver:     volume.StatsNotSupported,
+		TrimDriver:      volume.TrimNotSupported,
+		ExportDriver:    volume.ExportNotSupported,
+		IODriver:        volume.IONotSupported,
+		StoreEnumerator: common.NewDefaultStoreEnumerator(name, kvdb.Instance()),
+		name:            name,
+		provider:        provider,
+		runner:          runner,
+		mounter:         mounter,
+		deviceTimeout:   deviceTimeout,
+	}, nil
+}
+
+// Name returns the name of the driver
+func (d *Driver) Name() string {
+	return d.name
+}
+
+// Type returns the type of the driver
+func (d *Driver) Type() api.DriverType {
+	return Type
+}
+
+// Status returns the current status
+func (d *Driver) Status() [][2]string {
+	return [][2]string{{"Instance", d.provider.Instance()}}
+}
+
+// Create creates a new disk. Volumes with a Source.Parent are restored
+// from the snapshot Parent and keep its filesystem, others are formatted
+// when they are first mounted.
+func (d *Driver) Create(
+	locator *api.VolumeLocator,
+	source *api.Source,
+	spec *api.VolumeSpec,
+) (string, error) {
+	// The spec records the size of the disk and the restored format.
+	specCopy := *spec
+	spec = &specCopy
+	template := &Disk{}
+	template.Type, template.Iops = d.provider.DiskType(spec.Cos)
+	format := api.FSType_FS_TYPE_NONE
+	if source != nil && len(source.Parent) != 0 {
+		if !d.provider.IsSnapshot(source.Parent) {
+			return "", fmt.Errorf("Parent %v is not a snapshot", source.Parent)
+		}
+		template.Snapshot = source.Parent
+		format = spec.Format
+		// Snapshots taken by this driver know their filesystem, others
+		// are expected to hold spec.Format.
+		if snap, err := d.GetVol(source.Parent); err == nil &&
+			snap.Format != api.FSType_FS_TYPE_NONE {
+			format = snap.Format
+		}
+		spec.Format = format
+	}
+	// Restored volumes default to the size of their snapshot.
+	if spec.Size != 0 || len(template.Snapshot) == 0 {
+		template.Size = SizeGiB(spec.Size)
+	}
+	var labels map[string]string
+	if locator != nil {
+		labels = locator.VolumeLabels
+	}
+
+	disk, err := d.provider.Create(template, labels)
+	if err != nil {
+		dlog.Warnf("Failed to create %v disk: %v", d.name, err)
+		return "", err
+	}
+	if err := chaos.Now(koStrayCreate); err != nil {
+		return "", err
+	}
+	spec.Size = uint64(disk.Size) * GiB
+	v := common.NewVolume(disk.Id, format, locator, source, spec)
+	if err := d.CreateVol(v); err != nil {
+		return "", err
+	}
+	dlog.Infof("%v created volume %v", d.name, v.Id)
+	return v.Id, nil
+}
+
+// merge updates v with the state of disk in its cloud.
+func (d *Driver) merge(v *api.Volume, disk *Disk) {
+	local := disk.Instance == d.provider.Instance()
+	v.AttachedOn = ""
+	v.State = api.VolumeState_VOLUME_STATE_DETACHED
+	v.Status = api.VolumeStatus_VOLUME_STATUS_UP
+	switch disk.State {
+	case DiskPending:
+		v.State = api.VolumeState_VOLUME_STATE_PENDING
+		v.Status = api.VolumeStatus_VOLUME_STATUS_DOWN
+	case DiskAttaching, DiskDetaching:
+		v.State = api.VolumeState_VOLUME_STATE_PENDING
+		v.AttachedOn = disk.Instance
+	case DiskAttached:
+		v.State = api.VolumeState_VOLUME_STATE_ATTACHED
+		v.AttachedOn = disk.Instance
+	case DiskDeleted:
+		v.State = api.VolumeState_VOLUME_STATE_DELETED
+		v.Status = api.VolumeStatus_VOLUME_STATUS_DOWN
+	case DiskError:
+		v.State = api.VolumeState_VOLUME_STATE_ERROR
+		v.Status = api.VolumeStatus_VOLUME_STATUS_DOWN
+	}
+	// The device of a disk is only known on the instance it is attached to.
+	if !local || disk.State != DiskAttached {
+		v.DevicePath = ""
+	}
+}
+
+// refresh merges the state of the disks of vols. Snapshots are not disks
+// and keep their recorded state.
+func (d *Driver) refresh(vols []*api.Volume) error {
+	byID := make(map[string]*api.Volume)
+	ids := make([]string, 0, len(vols))
+	for _, v := range vols {
+		if d.provider.IsSnapshot(v.Id) {
+			continue
+		}
+		ids = append(ids, v.Id)
+		byID[v.Id] = v
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	disks, err := d.provider.Inspect(ids)
+	if err != nil {
+		return err
+	}
+	for _, disk := range disks {
+		if v, ok := byID[disk.Id]; ok {
+			d.merge(v, disk)
+		}
+	}
+	return nil
+}
+
+// Inspect inspects volumes
+func (d *Driver) Inspect(volumeIDs []string) ([]*api.Volume, error) {
+	vols, err := d.StoreEnumerator.Inspect(volumeIDs)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.refresh(vols); err != nil {
+		return nil, err
+	}
+	return vols, nil
+}
+
+// Enumerate enumerates volumes
+func (d *Driver) Enumerate(
+	locator *api.VolumeLocator,
+	labels map[string]string,
+) ([]*api.Volume, error) {
+	vols, err := d.StoreEnumerator.Enumerate(locator, labels)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.refresh(vols); err != nil {
+		return nil, err
+	}
+	return vols, nil
+}
+
+// Delete deletes a detached disk or a snapshot.
+func (d *Driver) Delete(volumeID string) error {
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		return err
+	}
+	if len(v.DevicePath) != 0 {
+		return volume.ErrVolAttached
+	}
+	if err := d.provider.Delete(volumeID); err != nil {
+		return err
+	}
+	if err := chaos.Now(koStrayDelete); err != nil {
+		return err
+	}
+	return d.DeleteVol(volumeID)
+}
+
+// Snapshot snapshots the disk of a volume.
+func (d *Driver) Snapshot(
+	volumeID string,
+	readonly bool,
+	locator *api.VolumeLocator,
+) (string, error) {
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		return "", err
+	}
+	snapID, err := d.provider.Snapshot(volumeID)
+	if err != nil {
+		return "", err
+	}
+	if err := chaos.Now(koStrayCreate); err != nil {
+		return "", err
+	}
+	v.Id = snapID
+	v.Source = &api.Source{Parent: volumeID}
+	v.Locator = locator
+	v.Ctime = prototime.Now()
+	v.Readonly = readonly
+	v.State = api.VolumeState_VOLUME_STATE_DETACHED
+	v.AttachedOn = ""
+	v.DevicePath = ""
+	v.AttachPath = nil
+	if err := d.CreateVol(v); err != nil {
+		return "", err
+	}
+	return v.Id, nil
+}
+
+// Attach attaches the disk of a volume to this instance.
+func (d *Driver) Attach(volumeID string, attachOptions map[string]string) (string, error) {
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		return "", err
+	}
+	if len(v.DevicePath) != 0 {
+		return v.DevicePath, nil
+	}
+	dev, err := d.attach(volumeID)
+	if err != nil {
+		return "", err
+	}
+	if err := chaos.Now(koStrayAttach); err != nil {
+		return "", err
+	}
+	v.DevicePath = dev
+	v.AttachedOn = d.provider.Instance()
+	v.State = api.VolumeState_VOLUME_STATE_ATTACHED
+	if err := d.UpdateVol(v); err != nil {
+		d.provider.Detach(volumeID)
+		return "", err
+	}
+	return dev, nil
+}
+
+// attach attaches the disk id and returns its device once it appears.
+func (d *Driver) attach(id string) (string, error) {
+	path, err := d.provider.Attach(id)
+	if err != nil {
+		return "", err
+	}
+	deadline := time.Now().Add(d.deviceTimeout)
+	for {
+		_, err := d.runner.Run("blockdev", "--getsize64", path)
+		if err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			d.provider.Detach(id)
+			return "", fmt.Errorf("Device %v of disk %v did not appear: %v",
+				path, id, err)
+		}
+		time.Sleep(time.Second)
+	}
+	// Mounts are tracked by the device the links point to.
+	if dev, err := filepath.EvalSymlinks(path); err == nil {
+		path = dev
+	}
+	return path, nil
+}
+
+// Detach detaches the disk of a volume that is not mounted.
+func (d *Driver) Detach(volumeID string) error {
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		return err
+	}
+	if len(v.AttachPath) != 0 {
+		return fmt.Errorf("Volume %v is mounted at %v", volumeID, v.AttachPath)
+	}
+	if err := d.provider.Detach(volumeID); err != nil {
+		return err
+	}
+	v.DevicePath = ""
+	v.AttachedOn = ""
+	v.State = api.VolumeState_VOLUME_STATE_DETACHED
+	return d.UpdateVol(v)
+}
+
+// MountedAt is not supported.
+func (d *Driver) MountedAt(mountpath string) string {
+	return ""
+}
+
+// Mount mounts the attached disk of a volume, and formats new disks first.
+func (d *Driver) Mount(volumeID string, mountpath string) error {
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		return fmt.Errorf("Failed to locate volume %q", volumeID)
+	}
+	if err := common.CheckAccess(
+		v,
+		mountpath,
+		api.AccessMode_ACCESS_MODE_READ_WRITE_ONCE,
+	); err != nil {
+		return err
+	}
+	if len(v.DevicePath) == 0 {
+		return volume.ErrVolDetached
+	}
+	if v.Format == api.FSType_FS_TYPE_NONE {
+		if err := d.format(v); err != nil {
+			return err
+		}
+	}
+	if err := chaos.Now(koMount); err != nil {
+		return err
+	}
+	flags, data := common.MountFlags(v)
+	if err := d.mounter.Mount(
+		0,
+		v.DevicePath,
+		mountpath,
+		v.Format.SimpleString(),
+		flags,
+		data,
+		0,
+	); err != nil {
+		return fmt.Errorf("Failed to mount %v at %v: %v", v.DevicePath, mountpath, err)
+	}
+	common.AddAttachPath(v, mountpath)
+	return d.UpdateVol(v)
+}
+
+// format makes the filesystem in the spec of a new volume on its device.
+func (d *Driver) format(v *api.Volume) error {
+	if v.Spec.Format == api.FSType_FS_TYPE_NONE {
+		return fmt.Errorf("Volume %v has no filesystem", v.Id)
+	}
+	dlog.Infof("Formatting %s with %v", v.DevicePath, v.Spec.Format)
+	if _, err := d.runner.Run(
+		"mkfs."+v.Spec.Format.SimpleString(),
+		v.DevicePath,
+	); err != nil {
+		return err
+	}
+	v.Format = v.Spec.Format
+	return d.UpdateVol(v)
+}
+
+// Unmount unmounts a volume from mountpath, or its first mount.
+func (d *Driver) Unmount(volumeID string, mountpath string) error {
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		return err
+	}
+	if len(v.AttachPath) == 0 || len(v.AttachPath[0]) == 0 {
+		return fmt.Errorf("Device %v not mounted", volumeID)
+	}
+	if len(mountpath) == 0 {
+		mountpath = v.AttachPath[0]
+	}
+	if err := d.mounter.Unmount(v.DevicePath, mountpath, 0); err != nil {
+		return err
+	}
+	common.RemoveAttachPath(v, mountpath)
+	return d.UpdateVol(v)
+}
+
+// Shutdown shuts the driver down.
+func (d *Driver) Shutdown() {
+	dlog.Printf("%s Shutting down", d.name)
+}
+
+// Set syncs the labels of locator to the tags of the disk, and modifies
+// its size and, through the Cos, its type and iops. Disks can only grow,
+// and their filesystem is grown with them.
+func (d *Driver) Set(volumeID string, locator *api.VolumeLocator, spec *api.VolumeSpec) error {
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		return err
+	}
+	snapshot := d.provider.IsSnapshot(volumeID)
+	if snapshot && spec != nil {
+		return volume.ErrNotSupported
+	}
+	if locator != nil {
+		if !snapshot {
+			if err := d.syncTags(v, locator.VolumeLabels); err != nil {
+				return err
+			}
+		}
+		v.Locator = locator
+	}
+	if spec != nil {
+		if err := d.modify(v, spec); err != nil {
+			return err
+		}
+	}
+	return d.UpdateVol(v)
+}
+
+// syncTags replaces the tags of the disk of v that came from its labels
+// with labels.
+func (d *Driver) syncTags(v *api.Volume, labels map[string]string) error {
+	removed := make(map[string]string)
+	if v.Locator != nil {
+		for k, value := range v.Locator.VolumeLabels {
+			if _, ok := labels[k]; !ok {
+				removed[k] = value
+			}
+		}
+	}
+	if len(removed) != 0 {
+		if err := d.provider.RemoveTags(v.Id, removed); err != nil {
+			return err
+		}
+	}
+	if len(labels) != 0 {
+		return d.provider.ApplyTags(v.Id, labels)
+	}
+	return nil
+}
+
+// modify changes the size and Cos of v to those of spec, where they are
+// set.
+func (d *Driver) modify(v *api.Volume, spec *api.VolumeSpec) error {
+	template := &Disk{Id: v.Id}
+	resize := spec.Size != 0 && SizeGiB(spec.Size) != SizeGiB(v.Spec.Size)
+	if resize {
+		if spec.Size < v.Spec.Size {
+			return fmt.Errorf("Volume %v cannot shrink from %v to %v",
+				v.Id, v.Spec.Size, spec.Size)
+		}
+		if v.Format == api.FSType_FS_TYPE_XFS && len(v.AttachPath) == 0 {
+			return fmt.Errorf("Volume %v must be mounted to grow xfs", v.Id)
+		}
+		template.Size = SizeGiB(spec.Size)
+	}
+	recos := spec.Cos != api.CosType_NONE && spec.Cos != v.Spec.Cos
+	if recos {
+		template.Type, template.Iops = d.provider.DiskType(spec.Cos)
+	}
+	if !resize && !recos {
+		return nil
+	}
+	disk, err := d.provider.Modify(template)
+	if err != nil {
+		return err
+	}
+	dlog.Infof("%v modified volume %v to %v GiB %v", d.name, v.Id,
+		disk.Size, disk.Type)
+	if recos {
+		v.Spec.Cos = spec.Cos
+	}
+	if resize {
+		v.Spec.Size = uint64(disk.Size) * GiB
+		return d.growFs(v)
+	}
+	return nil
+}
+
+// growFs grows the filesystem of v to the size of its disk. Disks that are
+// not attached are attached for the duration of the resize.
+func (d *Driver) growFs(v *api.Volume) error {
+	if v.Format == api.FSType_FS_TYPE_NONE {
+		return nil
+	}
+	dev := v.DevicePath
+	if len(dev) == 0 {
+		path, err := d.attach(v.Id)
+		if err != nil {
+			return err
+		}
+		defer d.provider.Detach(v.Id)
+		dev = path
+	}
+	switch v.Format {
+	case api.FSType_FS_TYPE_EXT4:
+		if len(v.AttachPath) == 0 {
+			// e2fsck exits with 1 if it corrected errors.
+			_, err := d.runner.Run("e2fsck", "-f", "-p", dev)
+			if e, ok := err.(*ExitError); err != nil && (!ok || e.Status != 1) {
+				return err
+			}
+		}
+		_, err := d.runner.Run("resize2fs", dev)
+		return err
+	case api.FSType_FS_TYPE_XFS:
+		_, err := d.runner.Run("xfs_growfs", v.AttachPath[0])
+		return err
+	}
+	return fmt.Errorf("Cannot grow %v filesystem of volume %v",
+		v.Format.SimpleString(), v.Id)
+}
@@ -0,0 +1,327 @@
+package cloud_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/volume/drivers/aws"
+	"github.com/libopenstorage/openstorage/volume/drivers/azure"
+	"github.com/libopenstorage/openstorage/volume/drivers/cloud"
+	"github.com/libopenstorage/openstorage/volume/drivers/gce"
+	_ "github.com/libopenstorage/openstorage/volume/drivers/test"
+	"github.com/stretchr/testify/require"
+)
+
+// backend is a fake cloud with a provider for the local instance and, where
+// the fake supports it, one for another instance.
+type backend struct {
+	name   string
+	local  cloud.Provider
+	remote cloud.Provider
+	// recos is true if disks can change their type.
+	recos bool
+}
+
+func backends() []*backend {
+	compute := gce.NewFakeCompute()
+	arm := azure.NewFakeARM()
+	return []*backend{
+		{
+			name:  "aws",
+			local: aws.NewProvider(aws.NewFakeStorage("i-local", "us-east-1a"), "us-east-1a", "i-local"),
+			recos: true,
+		},
+		{
+			name:   "gce",
+			local:  gce.NewFakeProvider(compute, "local"),
+			remote: gce.NewFakeProvider(compute, "remote"),
+		},
+		{
+			name:   "azure",
+			local:  azure.NewFakeProvider(arm, "local"),
+			remote: azure.NewFakeProvider(arm, "remote"),
+			recos:  true,
+		},
+	}
+}
+
+// fakeRunner records the commands of the driver. Commands named in errs
+// fail with their error.
+type fakeRunner struct {
+	cmds []string
+	errs map[string]error
+}
+
+func (f *fakeRunner) Run(name string, args ...string) (string, error) {
+	f.cmds = append(f.cmds, name+" "+strings.Join(args, " "))
+	return "", f.errs[name]
+}
+
+// fakeMount records the mounts.
+type fakeMount struct {
+	mounts map[string]string
+}
+
+func (f *fakeMount) Mount(source, target, fstype string, flags uintptr, data string, timeout int) error {
+	f.mounts[target] = source
+	return nil
+}
+
+func (f *fakeMount) Unmount(target string, flags int, timeout int) error {
+	delete(f.mounts, target)
+	return nil
+}
+
+// forEach runs test with a driver for each backend.
+func forEach(
+	t *testing.T,
+	test func(*testing.T, *backend, *cloud.Driver, *fakeRunner, *fakeMount, string),
+) {
+	for _, b := range backends() {
+		t.Run(b.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "cloud")
+			require.NoError(t, err)
+			defer os.RemoveAll(dir)
+			runner := &fakeRunner{}
+			mounts := &fakeMount{mounts: make(map[string]string)}
+			d, err := cloud.NewDriver("cloud-"+b.name, b.local, runner, mounts)
+			require.NoError(t, err)
+			test(t, b, d, runner, mounts, dir)
+		})
+	}
+}
+
+func create(t *testing.T, d *cloud.Driver, name string, labels map[string]string) string {
+	id, err := d.Create(
+		&api.VolumeLocator{Name: name, VolumeLabels: labels},
+		nil,
+		&api.VolumeSpec{Size: cloud.GiB + 1, Format: api.FSType_FS_TYPE_EXT4},
+	)
+	require.NoError(t, err)
+	return id
+}
+
+func TestAttachMount(t *testing.T) {
+	forEach(t, func(
+		t *testing.T,
+		b *backend,
+		d *cloud.Driver,
+		runner *fakeRunner,
+		mounts *fakeMount,
+		dir string,
+	) {
+		id := create(t, d, "attach", nil)
+		require.Len(t, runner.cmds, 0, "Volumes should be formatted on mount")
+		vols, err := d.Inspect([]string{id})
+		require.NoError(t, err)
+		require.Len(t, vols, 1)
+		require.Equal(t, uint64(2*cloud.GiB), vols[0].Spec.Size)
+		require.Equal(t, api.FSType_FS_TYPE_NONE, vols[0].Format)
+		require.Equal(t, api.VolumeState_VOLUME_STATE_DETACHED, vols[0].State)
+
+		dev, err := d.Attach(id, nil)
+		require.NoError(t, err)
+		require.Equal(t, []string{"blockdev --getsize64 " + dev}, runner.cmds)
+		again, err := d.Attach(id, nil)
+		require.NoError(t, err)
+		require.Equal(t, dev, again)
+		mappings, err := b.local.DeviceMappings()
+		require.NoError(t, err)
+		require.Equal(t, map[string]string{dev: id}, mappings)
+		vols, err = d.Inspect([]string{id})
+		require.NoError(t, err)
+		require.Equal(t, api.VolumeState_VOLUME_STATE_ATTACHED, vols[0].State)
+		require.Equal(t, b.local.Instance(), vols[0].AttachedOn)
+		require.Equal(t, dev, vols[0].DevicePath)
+
+		mountPath := filepath.Join(dir, "mnt")
+		require.NoError(t, d.Mount(id, mountPath))
+		require.Equal(t, dev, mounts.mounts[mountPath])
+		require.Contains(t, runner.cmds, "mkfs.ext4 "+dev)
+		vols, err = d.Inspect([]string{id})
+		require.NoError(t, err)
+		require.Equal(t, api.FSType_FS_TYPE_EXT4, vols[0].Format)
+		require.Equal(t, []string{mountPath}, vols[0].AttachPath)
+		require.Error(t, d.Detach(id), "Detach of a mounted volume should fail")
+
+		require.NoError(t, d.Unmount(id, mountPath))
+		require.Len(t, mounts.mounts, 0)
+		require.Error(t, d.Delete(id), "Delete of an attached volume should fail")
+		require.NoError(t, d.Detach(id))
+		mappings, err = b.local.DeviceMappings()
+		require.NoError(t, err)
+		require.Len(t, mappings, 0)
+
+		require.NoError(t, d.Delete(id))
+		_, err = b.local.Inspect([]string{id})
+		require.Error(t, err, "Deleted disks should not exist")
+	})
+}
+
+func TestSnapshotRestore(t *testing.T) {
+	forEach(t, func(
+		t *testing.T,
+		b *backend,
+		d *cloud.Driver,
+		runner *fakeRunner,
+		mounts *fakeMount,
+		dir string,
+	) {
+		id := create(t, d, "parent", nil)
+		_, err := d.Attach(id, nil)
+		require.NoError(t, err)
+		mountPath := filepath.Join(dir, "parent")
+		require.NoError(t, d.Mount(id, mountPath))
+
+		snapID, err := d.Snapshot(id, true, &api.VolumeLocator{Name: "snap"})
+		require.NoError(t, err)
+		require.True(t, b.local.IsSnapshot(snapID))
+		snaps, err := d.SnapEnumerate([]string{id}, nil)
+		require.NoError(t, err)
+		require.Len(t, snaps, 1)
+		require.True(t, snaps[0].Readonly)
+		require.Len(t, snaps[0].AttachPath, 0)
+		require.Equal(t, api.FSType_FS_TYPE_EXT4, snaps[0].Format)
+
+		restored, err := d.Create(
+			&api.VolumeLocator{Name: "restored"},
+			&api.Source{Parent: snapID},
+			&api.VolumeSpec{},
+		)
+		require.NoError(t, err)
+		vols, err := d.Inspect([]string{restored})
+		require.NoError(t, err)
+		require.Equal(t, uint64(2*cloud.GiB), vols[0].Spec.Size)
+		require.Equal(t, api.FSType_FS_TYPE_EXT4, vols[0].Format)
+		runner.cmds = nil
+		_, err = d.Attach(restored, nil)
+		require.NoError(t, err)
+		require.NoError(t, d.Mount(restored, filepath.Join(dir, "restored")))
+		for _, cmd := range runner.cmds {
+			require.False(t, strings.HasPrefix(cmd, "mkfs"),
+				"Restored volumes should not be formatted")
+		}
+
+		_, err = d.Create(
+			&api.VolumeLocator{Name: "clone"},
+			&api.Source{Parent: id},
+			&api.VolumeSpec{Size: cloud.GiB},
+		)
+		require.Error(t, err, "Only snapshots can be restored")
+
+		for _, v := range []string{id, restored} {
+			require.NoError(t, d.Unmount(v, ""))
+			require.NoError(t, d.Detach(v))
+			require.NoError(t, d.Delete(v))
+		}
+		require.NoError(t, d.Delete(snapID))
+	})
+}
+
+func TestSet(t *testing.T) {
+	forEach(t, func(
+		t *testing.T,
+		b *backend,
+		d *cloud.Driver,
+		runner *fakeRunner,
+		mounts *fakeMount,
+		dir string,
+	) {
+		id := create(t, d, "set", map[string]string{"app": "db", "old": "x"})
+		dev, err := d.Attach(id, nil)
+		require.NoError(t, err)
+		mountPath := filepath.Join(dir, "mnt")
+		require.NoError(t, d.Mount(id, mountPath))
+		require.NoError(t, d.Unmount(id, mountPath))
+		require.NoError(t, d.Detach(id))
+
+		runner.cmds = nil
+		require.NoError(t, d.Set(id, nil, &api.VolumeSpec{Size: 3 * cloud.GiB}))
+		require.Len(t, runner.cmds, 3)
+		require.True(t, strings.HasPrefix(runner.cmds[0], "blockdev --getsize64 "))
+		require.Equal(t, []string{"e2fsck -f -p " + dev, "resize2fs " + dev}, runner.cmds[1:])
+		disks, err := b.local.Inspect([]string{id})
+		require.NoError(t, err)
+		require.Equal(t, int64(3), disks[0].Size)
+		require.Equal(t, cloud.DiskAvailable, disks[0].State,
+			"Disks attached to resize should be detached")
+		require.Error(t, d.Set(id, nil, &api.VolumeSpec{Size: cloud.GiB}),
+			"Volumes should not shrink")
+
+		err = d.Set(id, nil, &api.VolumeSpec{Cos: api.CosType_HIGH})
+		vols, inspectErr := d.Inspect([]string{id})
+		require.NoError(t, inspectErr)
+		if b.recos {
+			require.NoError(t, err)
+			disks, err = b.local.Inspect([]string{id})
+			require.NoError(t, err)
+			diskType, iops := b.local.DiskType(api.CosType_HIGH)
+			require.Equal(t, diskType, disks[0].Type)
+			require.Equal(t, iops, disks[0].Iops)
+			require.Equal(t, api.CosType_HIGH, vols[0].Spec.Cos)
+		} else {
+			require.Error(t, err)
+			require.NotEqual(t, api.CosType_HIGH, vols[0].Spec.Cos)
+		}
+		require.Equal(t, uint64(3*cloud.GiB), vols[0].Spec.Size)
+
+		require.NoError(t, d.Set(
+			id,
+			&api.VolumeLocator{
+				Name:         "set",
+				VolumeLabels: map[string]string{"app": "web", "tier": "1"},
+			},
+			nil,
+		))
+		disks, err = b.local.Inspect([]string{id})
+		require.NoError(t, err)
+		require.Equal(t, map[string]string{"app": "web", "tier": "1"}, disks[0].Labels)
+		disks, err = b.local.Enumerate(map[string]string{"app": "web"})
+		require.NoError(t, err)
+		require.Len(t, disks, 1)
+
+		// e2fsck exits with 1 if it corrected errors, and above if it failed.
+		runner.errs = map[string]error{"e2fsck": &cloud.ExitError{Cmd: "e2fsck", Status: 1}}
+		require.NoError(t, d.Set(id, nil, &api.VolumeSpec{Size: 4 * cloud.GiB}))
+		runner.errs["e2fsck"] = &cloud.ExitError{Cmd: "e2fsck", Status: 4}
+		require.Error(t, d.Set(id, nil, &api.VolumeSpec{Size: 5 * cloud.GiB}))
+		runner.errs = nil
+
+		require.NoError(t, d.Delete(id))
+	})
+}
+
+func TestRemoteAttach(t *testing.T) {
+	forEach(t, func(
+		t *testing.T,
+		b *backend,
+		d *cloud.Driver,
+		runner *fakeRunner,
+		mounts *fakeMount,
+		dir string,
+	) {
+		if b.remote == nil {
+			t.Skip("No remote instance in fake ", b.name)
+		}
+		id := create(t, d, "remote", nil)
+		_, err := b.remote.Attach(id)
+		require.NoError(t, err)
+		vols, err := d.Inspect([]string{id})
+		require.NoError(t, err)
+		require.Equal(t, api.VolumeState_VOLUME_STATE_ATTACHED, vols[0].State)
+		require.Equal(t, b.remote.Instance(), vols[0].AttachedOn)
+		require.Len(t, vols[0].DevicePath, 0)
+		_, err = d.Attach(id, nil)
+		require.Error(t, err, "Attach of a volume attached remotely should fail")
+		mappings, err := b.local.DeviceMappings()
+		require.NoError(t, err)
+		require.Len(t, mappings, 0)
+
+		require.NoError(t, b.remote.Detach(id))
+		require.NoError(t, d.Delete(id))
+	})
+}
@@ -0,0 +1,24 @@
+package cloud
+
+import (
+	"net/http"
+	"net/http/httptest"
+)
+
+// NewHandlerClient returns an http.Client that serves its requests with h
+// in process, so that providers can run against a fake of their cloud.
+func NewHandlerClient(h http.Handler) *http.Client {
+	return &http.Client{Transport: &handlerTransport{handler: h}}
+}
+
+type handlerTransport struct {
+	handler http.Handler
+}
+
+func (t *handlerTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	w := httptest.NewRecorder()
+	t.handler.ServeHTTP(w, r)
+	resp := w.Result()
+	resp.Request = r
+	return resp, nil
+}
@@ -0,0 +1,74 @@
+package common
+
+import (
+	"io"
+	"os"
+	"syscall"
+)
+
+// FICLONE is defined in <linux/fs.h> as _IOW(0x94, 9, int)
+const ficlone = 0x40049409
+
+// CopyFile copies source to dest. The file is cloned if the filesystem
+// supports reflinks. Otherwise blocks of zeroes are skipped so that the holes
+// of a sparse file are preserved.
+func CopyFile(source string, dest string) error {
+	sourcefile, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+
+	defer sourcefile.Close()
+
+	destfile, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+
+	defer destfile.Close()
+
+	sourceinfo, err := sourcefile.Stat()
+	if err != nil {
+		return err
+	}
+	if err := destfile.Chmod(sourceinfo.Mode()); err != nil {
+		return err
+	}
+
+	if _, _, errno := syscall.Syscall(
+		syscall.SYS_IOCTL,
+		destfile.Fd(),
+		ficlone,
+		sourcefile.Fd(),
+	); errno == 0 {
+		return nil
+	}
+
+	buf := make([]byte, 64*1024)
+	var off int64
+	for {
+		n, err := sourcefile.Read(buf)
+		if n > 0 && !isZero(buf[:n]) {
+			if _, err := destfile.WriteAt(buf[:n], off); err != nil {
+				return err
+			}
+		}
+		off += int64(n)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return destfile.Truncate(off)
+}
+
+func isZero(b []byte) bool {
+	for _, c := range b {
+		if c != 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,36 @@
+package common
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCopyFileSparse(t *testing.T) {
+	dir, err := ioutil.TempDir("", "copy")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	source := filepath.Join(dir, "source")
+	dest := filepath.Join(dir, "dest")
+
+	f, err := os.Create(source)
+	require.NoError(t, err)
+	require.NoError(t, f.Truncate(8*1024*1024))
+	_, err = f.WriteAt([]byte("copy"), 4*1024*1024)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	require.NoError(t, CopyFile(source, dest))
+	b, err := ioutil.ReadFile(dest)
+	require.NoError(t, err)
+	require.Len(t, b, 8*1024*1024)
+	require.Equal(t, "copy", string(b[4*1024*1024:4*1024*1024+4]))
+
+	var st syscall.Stat_t
+	require.NoError(t, syscall.Stat(dest, &st))
+	require.True(t, st.Blocks*512 < 1024*1024)
+}
@@ -0,0 +1,108 @@
+package common
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"syscall"
+	"unsafe"
+
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/pkg/mount"
+)
+
+// FITRIM is defined in <linux/fs.h> as _IOWR('X', 121, struct fstrim_range)
+const fitrim = 0xc0185879
+
+// MountFlags returns the mount flags and the filesystem specific data for the
+// mount options of a volume. Read only volumes and volumes with the read only
+// many access mode are always mounted read only.
+func MountFlags(v *api.Volume) (uintptr, string) {
+	flags, data := mount.ParseOptions(v.Spec.GetMountOptions())
+	if v.Readonly ||
+		v.Spec.GetAccessMode() == api.AccessMode_ACCESS_MODE_READ_ONLY_MANY {
+		flags |= syscall.MS_RDONLY
+	}
+	return flags, data
+}
+
+// BindMountFlags returns the mount flags for the mount options of a volume
+// that is bind mounted. Bind mounts do not apply filesystem specific
+// options, which return an error.
+func BindMountFlags(v *api.Volume) (uintptr, error) {
+	flags, data := MountFlags(v)
+	if len(data) != 0 {
+		return 0, fmt.Errorf("Mount options %q of volume %v are not "+
+			"supported by bind mounts", data, v.Id)
+	}
+	return flags, nil
+}
+
+// CheckAccess returns an error if the access mode of a volume does not allow
+// it to be mounted at mountpath in addition to its current mounts. The
+// defaultMode is used for volumes that do not specify an access mode.
+func CheckAccess(v *api.Volume, mountpath string, defaultMode api.AccessMode) error {
+	mode := v.Spec.GetAccessMode()
+	if mode == api.AccessMode_ACCESS_MODE_NONE {
+		mode = defaultMode
+	}
+	if mode != api.AccessMode_ACCESS_MODE_READ_WRITE_ONCE {
+		return nil
+	}
+	for _, p := range v.AttachPath {
+		if len(p) != 0 && p != mountpath {
+			return fmt.Errorf("Volume %q already mounted at %q", v.Id, p)
+		}
+	}
+	return nil
+}
+
+// AddAttachPath adds mountpath to the attach paths of a volume.
+func AddAttachPath(v *api.Volume, mountpath string) {
+	for _, p := range v.AttachPath {
+		if p == mountpath {
+			return
+		}
+	}
+	v.AttachPath = append(v.AttachPath, mountpath)
+}
+
+// RemoveAttachPath removes mountpath from the attach paths of a volume.
+func RemoveAttachPath(v *api.Volume, mountpath string) {
+	paths := make([]string, 0, len(v.AttachPath))
+	for _, p := range v.AttachPath {
+		if len(p) != 0 && p != mountpath {
+			paths = append(paths, p)
+		}
+	}
+	if len(paths) == 0 {
+		paths = nil
+	}
+	v.AttachPath = paths
+}
+
+// Fstrim discards the blocks that are not in use by the filesystem mounted at
+// mountpath and returns the number of bytes discarded.
+func Fstrim(mountpath string) (uint64, error) {
+	f, err := os.Open(mountpath)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	r := struct {
+		start  uint64
+		len    uint64
+		minlen uint64
+	}{
+		len: math.MaxUint64,
+	}
+	if _, _, errno := syscall.Syscall(
+		syscall.SYS_IOCTL,
+		f.Fd(),
+		fitrim,
+		uintptr(unsafe.Pointer(&r)),
+	); errno != 0 {
+		return 0, fmt.Errorf("Failed to trim %v: %v", mountpath, errno)
+	}
+	return r.len, nil
+}
@@ -0,0 +1,74 @@
+package common
+
+import (
+	"syscall"
+	"testing"
+
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMountFlags(t *testing.T) {
+	v := &api.Volume{
+		Spec: &api.VolumeSpec{
+			MountOptions: []string{"noexec", "nodev", "discard"},
+		},
+	}
+	flags, data := MountFlags(v)
+	require.Equal(t, uintptr(syscall.MS_NOEXEC|syscall.MS_NODEV), flags)
+	require.Equal(t, "discard", data)
+
+	v.Readonly = true
+	flags, _ = MountFlags(v)
+	require.NotZero(t, flags&syscall.MS_RDONLY)
+
+	v = &api.Volume{
+		Spec: &api.VolumeSpec{
+			AccessMode:   api.AccessMode_ACCESS_MODE_READ_ONLY_MANY,
+			MountOptions: []string{"rw"},
+		},
+	}
+	flags, _ = MountFlags(v)
+	require.NotZero(t, flags&syscall.MS_RDONLY)
+}
+
+func TestBindMountFlags(t *testing.T) {
+	v := &api.Volume{
+		Spec: &api.VolumeSpec{
+			MountOptions: []string{"noexec", "ro"},
+		},
+	}
+	flags, err := BindMountFlags(v)
+	require.NoError(t, err)
+	require.Equal(t, uintptr(syscall.MS_NOEXEC|syscall.MS_RDONLY), flags)
+
+	v.Spec.MountOptions = append(v.Spec.MountOptions, "discard")
+	_, err = BindMountFlags(v)
+	require.Error(t, err, "Filesystem options are not applied by bind mounts")
+}
+
+func TestCheckAccess(t *testing.T) {
+	v := &api.Volume{
+		Id:         "vol",
+		Spec:       &api.VolumeSpec{},
+		AttachPath: []string{"/mnt/a"},
+	}
+	rwo := api.AccessMode_ACCESS_MODE_READ_WRITE_ONCE
+	none := api.AccessMode_ACCESS_MODE_NONE
+	require.NoError(t, CheckAccess(v, "/mnt/b", none))
+	require.Error(t, CheckAccess(v, "/mnt/b", rwo))
+	require.NoError(t, CheckAccess(v, "/mnt/a", rwo))
+
+	v.Spec.AccessMode = api.AccessMode_ACCESS_MODE_READ_WRITE_MANY
+	require.NoError(t, CheckAccess(v, "/mnt/b", rwo))
+	v.Spec.AccessMode = rwo
+	require.Error(t, CheckAccess(v, "/mnt/b", none))
+
+	AddAttachPath(v, "/mnt/b")
+	AddAttachPath(v, "/mnt/b")
+	require.Equal(t, []string{"/mnt/a", "/mnt/b"}, v.AttachPath)
+	RemoveAttachPath(v, "/mnt/a")
+	require.Equal(t, []string{"/mnt/b"}, v.AttachPath)
+	RemoveAttachPath(v, "/mnt/b")
+	require.Nil(t, v.AttachPath)
+}
@@ -0,0 +1,297 @@
+package common
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.pedge.io/dlog"
+
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/pkg/seed"
+	"github.com/libopenstorage/openstorage/volume"
+)
+
+const (
+	// RuntimeSeedSource is the runtime state key for the seed URI.
+	RuntimeSeedSource = "seed_source"
+	// RuntimeSeedRevision is the runtime state key for the seed revision.
+	RuntimeSeedRevision = "seed_revision"
+	// RuntimeSeedDigest is the runtime state key for the seed digest.
+	RuntimeSeedDigest = "seed_digest"
+	// RuntimeSeedTime is the runtime state key for the time the volume
+	// was seeded.
+	RuntimeSeedTime = "seed_time"
+	// RuntimeSeedBytes is the runtime state key for the bytes seeded so far.
+	RuntimeSeedBytes = "seed_bytes"
+	// RuntimeSeedPercent is the runtime state key for the seed progress.
+	RuntimeSeedPercent = "seed_percent"
+	// RuntimeSeedError is the runtime state key for the seed failure.
+	RuntimeSeedError = "seed_error"
+	// RuntimeSeedNode is the runtime state key for the host loading the
+	// seed.
+	RuntimeSeedNode = "seed_node"
+)
+
+// seedJob is a seed being loaded into a volume.
+type seedJob struct {
+	source seed.Source
+	// done is closed when the seed completes.
+	done chan struct{}
+}
+
+var (
+	// SeedWaitTimeout is how long a mount waits for a volume to be seeded.
+	SeedWaitTimeout = 30 * time.Second
+	// SeedProgressInterval is how often the seed progress of a volume
+	// is saved.
+	SeedProgressInterval = 5 * time.Second
+
+	seedLock sync.Mutex
+	// seedJobs maps volume IDs to the seeds being loaded.
+	seedJobs = make(map[string]*seedJob)
+)
+
+// Seed starts loading the seed in the volume's source into dataDir in the
+// background. The volume must already be in the store. It is put in
+// pending state and moves to available once the seed is loaded, or to error
+// if the seed fails. Progress and the seed metadata, which is also written to
+// mdDir, are recorded in the volume's runtime state. Volume labels are passed
+// to the seed as options. Seed does nothing if the volume has no seed.
+func Seed(
+	store volume.Store,
+	v *api.Volume,
+	dataDir string,
+	mdDir string,
+) error {
+	if v.Source == nil || len(v.Source.Seed) == 0 {
+		return nil
+	}
+	var options map[string]string
+	if v.Spec != nil {
+		options = v.Spec.VolumeLabels
+	}
+	source, err := seed.New(v.Source.Seed, options)
+	if err != nil {
+		dlog.Warnf("Failed to initialize seed from %q: %v", v.Source.Seed, err)
+		return err
+	}
+	v.State = api.VolumeState_VOLUME_STATE_PENDING
+	seedState(v)[RuntimeSeedSource] = v.Source.Seed
+	seedState(v)[RuntimeSeedNode] = seedNode()
+	if err := store.UpdateVol(v); err != nil {
+		return err
+	}
+
+	job := &seedJob{source: source, done: make(chan struct{})}
+	seedLock.Lock()
+	seedJobs[v.Id] = job
+	seedLock.Unlock()
+	go loadSeed(store, v.Id, job, dataDir, mdDir)
+	return nil
+}
+
+// CancelSeed stops loading the seed of a volume before it is deleted, and
+// waits up to SeedWaitTimeout for the load to stop. Sources that cannot be
+// canceled finish their load first.
+func CancelSeed(volumeID string) {
+	seedLock.Lock()
+	job, ok := seedJobs[volumeID]
+	seedLock.Unlock()
+	if !ok {
+		return
+	}
+	if c, ok := job.source.(seed.Canceler); ok {
+		c.Cancel()
+	}
+	select {
+	case <-job.done:
+	case <-time.After(SeedWaitTimeout):
+		dlog.Warnf("Volume %v is still loading its seed from %q",
+			volumeID, job.source)
+	}
+}
+
+// RecoverSeeds fails the seeds this host was loading when the daemon
+// stopped, so that their volumes do not stay pending. Drivers call it on
+// start, the volumes can then be deleted and created again.
+func RecoverSeeds(store volume.StoreEnumerator) error {
+	vols, err := store.Enumerate(&api.VolumeLocator{}, nil)
+	if err != nil {
+		return err
+	}
+	node := seedNode()
+	for _, v := range vols {
+		if v.State != api.VolumeState_VOLUME_STATE_PENDING ||
+			v.Source == nil || len(v.Source.Seed) == 0 {
+			continue
+		}
+		if owner := seedState(v)[RuntimeSeedNode]; len(owner) != 0 && owner != node {
+			continue
+		}
+		seedLock.Lock()
+		_, running := seedJobs[v.Id]
+		seedLock.Unlock()
+		if running {
+			continue
+		}
+		dlog.Warnf("Seed of volume %v from %q was interrupted", v.Id, v.Source.Seed)
+		if err := updateSeedState(store, v.Id, func(v *api.Volume, state map[string]string) {
+			if v.State == api.VolumeState_VOLUME_STATE_PENDING {
+				v.State = api.VolumeState_VOLUME_STATE_ERROR
+				state[RuntimeSeedError] = "Seed was interrupted by a restart"
+			}
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// seedNode returns the host that loads the seeds started by this daemon.
+func seedNode() string {
+	host, err := os.Hostname()
+	if err != nil {
+		dlog.Warnf("Failed to get the hostname: %v", err)
+	}
+	return host
+}
+
+// WaitSeed waits up to timeout for the seed of a volume to complete.
+// volume.ErrVolSeeding is returned if the seed is still being loaded and
+// an error is returned if the seed failed.
+func WaitSeed(store volume.Store, volumeID string, timeout time.Duration) error {
+	seedLock.Lock()
+	job, ok := seedJobs[volumeID]
+	seedLock.Unlock()
+	if ok {
+		select {
+		case <-job.done:
+		case <-time.After(timeout):
+			return volume.ErrVolSeeding
+		}
+	}
+	v, err := store.GetVol(volumeID)
+	if err != nil {
+		return err
+	}
+	switch v.State {
+	case api.VolumeState_VOLUME_STATE_PENDING:
+		return volume.ErrVolSeeding
+	case api.VolumeState_VOLUME_STATE_ERROR:
+		return fmt.Errorf("Volume %v failed to seed from %q: %v",
+			volumeID, seedState(v)[RuntimeSeedSource],
+			seedState(v)[RuntimeSeedError])
+	}
+	return nil
+}
+
+func loadSeed(
+	store volume.Store,
+	volumeID string,
+	job *seedJob,
+	dataDir string,
+	mdDir string,
+) {
+	defer func() {
+		seedLock.Lock()
+		delete(seedJobs, volumeID)
+		seedLock.Unlock()
+		close(job.done)
+	}()
+
+	source := job.source
+	stop := make(chan struct{})
+	if p, ok := source.(seed.Progress); ok {
+		go reportSeedProgress(store, volumeID, p, stop)
+	}
+	loadErr := source.Load(dataDir)
+	if loadErr == nil {
+		loadErr = source.MetadataWrite(mdDir)
+	}
+	var metadata *seed.Metadata
+	if loadErr == nil {
+		metadata, loadErr = seed.ReadMetadata(mdDir)
+	}
+	close(stop)
+
+	if loadErr != nil {
+		dlog.Warnf("Failed to seed volume %v from %q to %q: %v",
+			volumeID, source, dataDir, loadErr)
+	}
+	if err := updateSeedState(store, volumeID, func(v *api.Volume, state map[string]string) {
+		if loadErr != nil {
+			v.State = api.VolumeState_VOLUME_STATE_ERROR
+			state[RuntimeSeedError] = loadErr.Error()
+			return
+		}
+		v.State = api.VolumeState_VOLUME_STATE_AVAILABLE
+		state[RuntimeSeedRevision] = metadata.Revision
+		state[RuntimeSeedDigest] = metadata.Digest
+		state[RuntimeSeedTime] = metadata.Time.Format(time.RFC3339)
+		state[RuntimeSeedPercent] = "100"
+	}); err != nil {
+		dlog.Warnf("Failed to update seed state of volume %v: %v",
+			volumeID, err)
+	}
+}
+
+func reportSeedProgress(
+	store volume.Store,
+	volumeID string,
+	p seed.Progress,
+	stop chan struct{},
+) {
+	ticker := time.NewTicker(SeedProgressInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		loaded, total := p.Progress()
+		if err := updateSeedState(store, volumeID, func(v *api.Volume, state map[string]string) {
+			state[RuntimeSeedBytes] = strconv.FormatUint(loaded, 10)
+			if total != 0 {
+				state[RuntimeSeedPercent] = strconv.FormatUint(loaded*100/total, 10)
+			}
+		}); err != nil {
+			dlog.Debugf("Failed to update seed progress of volume %v: %v",
+				volumeID, err)
+		}
+	}
+}
+
+func updateSeedState(
+	store volume.Store,
+	volumeID string,
+	update func(*api.Volume, map[string]string),
+) error {
+	token, err := store.Lock(volumeID)
+	if err != nil {
+		return err
+	}
+	defer store.Unlock(token)
+	v, err := store.GetVol(volumeID)
+	if err != nil {
+		return err
+	}
+	update(v, seedState(v))
+	return store.UpdateVol(v)
+}
+
+// seedState returns the runtime state map of the volume that holds the
+// seed state, adding one if there is none.
+func seedState(v *api.Volume) map[string]string {
+	for _, state := range v.RuntimeState {
+		if _, ok := state.RuntimeState[RuntimeSeedSource]; ok {
+			return state.RuntimeState
+		}
+	}
+	state := &api.RuntimeStateMap{RuntimeState: make(map[string]string)}
+	v.RuntimeState = append(v.RuntimeState, state)
+	return state.RuntimeState
+}
@@ -0,0 +1,123 @@
+package common
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/pkg/seed"
+	"github.com/libopenstorage/openstorage/volume"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSeed(t *testing.T) {
+	src, err := ioutil.TempDir("", "seed_src")
+	require.NoError(t, err)
+	defer os.RemoveAll(src)
+	dest, err := ioutil.TempDir("", "seed_dest")
+	require.NoError(t, err)
+	defer os.RemoveAll(dest)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(src, "file"), []byte("seed"), 0644))
+	seed.SetFileRoots([]string{src})
+	defer seed.SetFileRoots(nil)
+
+	v := newTestVolume("SeedVolume")
+	v.Source = &api.Source{Seed: "file://" + src}
+	require.NoError(t, testEnumerator.CreateVol(v), "CreateVol")
+	defer testEnumerator.DeleteVol(v.Id)
+
+	require.NoError(t, Seed(testEnumerator, v, dest, dest), "Seed")
+	require.NoError(t, WaitSeed(testEnumerator, v.Id, SeedWaitTimeout), "WaitSeed")
+
+	v, err = testEnumerator.GetVol(v.Id)
+	require.NoError(t, err, "GetVol")
+	assert.Equal(t, api.VolumeState_VOLUME_STATE_AVAILABLE, v.State)
+	assert.Equal(t, "file://"+src, seedState(v)[RuntimeSeedSource])
+	assert.Equal(t, "100", seedState(v)[RuntimeSeedPercent])
+	b, err := ioutil.ReadFile(filepath.Join(dest, "file"))
+	require.NoError(t, err)
+	assert.Equal(t, "seed", string(b))
+}
+
+func TestSeedError(t *testing.T) {
+	dest, err := ioutil.TempDir("", "seed_dest")
+	require.NoError(t, err)
+	defer os.RemoveAll(dest)
+
+	v := newTestVolume("SeedErrorVolume")
+	v.Source = &api.Source{Seed: "http://127.0.0.1:1/seed.tar"}
+	require.NoError(t, testEnumerator.CreateVol(v), "CreateVol")
+	defer testEnumerator.DeleteVol(v.Id)
+
+	require.NoError(t, Seed(testEnumerator, v, dest, dest), "Seed")
+	err = WaitSeed(testEnumerator, v.Id, SeedWaitTimeout)
+	assert.Error(t, err, "WaitSeed should fail")
+	assert.NotEqual(t, volume.ErrVolSeeding, err)
+
+	v, err = testEnumerator.GetVol(v.Id)
+	require.NoError(t, err, "GetVol")
+	assert.Equal(t, api.VolumeState_VOLUME_STATE_ERROR, v.State)
+	assert.NotEmpty(t, seedState(v)[RuntimeSeedError])
+}
+
+func TestCancelSeed(t *testing.T) {
+	// The server sends the archive slowly until the client goes away.
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			for {
+				if _, err := w.Write(make([]byte, 1024)); err != nil {
+					return
+				}
+				w.(http.Flusher).Flush()
+				time.Sleep(10 * time.Millisecond)
+			}
+		}))
+	defer server.Close()
+	dest, err := ioutil.TempDir("", "seed_dest")
+	require.NoError(t, err)
+	defer os.RemoveAll(dest)
+
+	v := newTestVolume("CancelSeedVolume")
+	v.Source = &api.Source{Seed: server.URL + "/seed.tar"}
+	require.NoError(t, testEnumerator.CreateVol(v), "CreateVol")
+	defer testEnumerator.DeleteVol(v.Id)
+
+	require.NoError(t, Seed(testEnumerator, v, dest, dest), "Seed")
+	CancelSeed(v.Id)
+	v, err = testEnumerator.GetVol(v.Id)
+	require.NoError(t, err, "GetVol")
+	assert.Equal(t, api.VolumeState_VOLUME_STATE_ERROR, v.State)
+}
+
+func TestRecoverSeeds(t *testing.T) {
+	v := newTestVolume("RecoverSeedsVolume")
+	v.Source = &api.Source{Seed: "http://127.0.0.1:1/seed.tar"}
+	v.State = api.VolumeState_VOLUME_STATE_PENDING
+	seedState(v)[RuntimeSeedSource] = v.Source.Seed
+	seedState(v)[RuntimeSeedNode] = seedNode()
+	require.NoError(t, testEnumerator.CreateVol(v), "CreateVol")
+	defer testEnumerator.DeleteVol(v.Id)
+	other := newTestVolume("RecoverSeedsOtherVolume")
+	other.Source = v.Source
+	other.State = api.VolumeState_VOLUME_STATE_PENDING
+	seedState(other)[RuntimeSeedSource] = other.Source.Seed
+	seedState(other)[RuntimeSeedNode] = "other"
+	require.NoError(t, testEnumerator.CreateVol(other), "CreateVol")
+	defer testEnumerator.DeleteVol(other.Id)
+
+	require.NoError(t, RecoverSeeds(testEnumerator), "RecoverSeeds")
+	v, err := testEnumerator.GetVol(v.Id)
+	require.NoError(t, err, "GetVol")
+	assert.Equal(t, api.VolumeState_VOLUME_STATE_ERROR, v.State)
+	assert.Error(t, WaitSeed(testEnumerator, v.Id, SeedWaitTimeout))
+	other, err = testEnumerator.GetVol(other.Id)
+	require.NoError(t, err, "GetVol")
+	assert.Equal(t, api.VolumeState_VOLUME_STATE_PENDING, other.State,
+		"Seeds of other hosts are not recovered")
+}
@@ -0,0 +1,208 @@
+package common
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"go.pedge.io/dlog"
+
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/pkg/cgroup"
+	"github.com/libopenstorage/openstorage/volume"
+)
+
+// cosLimit is the IOPS and bandwidth limit of a class of service.
+type cosLimit struct {
+	iops      uint64
+	bandwidth uint64
+}
+
+var (
+	// ErrSharedDevice is returned when I/O limits are set on a volume that
+	// is a directory of a filesystem. cgroups throttle whole disks, so the
+	// limits would also apply to the other volumes on the disk and to the
+	// root filesystems of the containers that mount the volume.
+	ErrSharedDevice = errors.New("Volume shares its disk with other volumes " +
+		"and cannot be throttled")
+
+	// cosLimits of the classes of service. Volumes of HIGH or no Cos are
+	// not limited.
+	cosLimits = map[api.CosType]cosLimit{
+		api.CosType_LOW:    {iops: 500, bandwidth: 50 << 20},
+		api.CosType_MEDIUM: {iops: 5000, bandwidth: 200 << 20},
+	}
+
+	// ThrottleInterval is how often the containers that mount a volume
+	// are looked up to be throttled.
+	ThrottleInterval = 5 * time.Second
+
+	throttleLock sync.Mutex
+	// throttleJobs maps the mount paths of the volumes being throttled to
+	// a channel that is closed to stop throttling.
+	throttleJobs = make(map[string]chan struct{})
+)
+
+// IoLimits returns the cgroup I/O limits of a volume spec. Sequential
+// workloads are limited by the bandwidth of their Cos, random workloads by
+// its IOPS and databases by both. MaxIops and MaxBandwidth override the
+// limits of the Cos.
+func IoLimits(spec *api.VolumeSpec) cgroup.Limits {
+	var iops, bandwidth uint64
+	if l, ok := cosLimits[spec.GetCos()]; ok {
+		switch spec.GetIoProfile() {
+		case api.IoProfile_IO_PROFILE_SEQUENTIAL:
+			bandwidth = l.bandwidth
+		case api.IoProfile_IO_PROFILE_RANDOM:
+			iops = l.iops
+		default:
+			iops, bandwidth = l.iops, l.bandwidth
+		}
+	}
+	if spec.GetMaxIops() != 0 {
+		iops = spec.GetMaxIops()
+	}
+	if spec.GetMaxBandwidth() != 0 {
+		bandwidth = spec.GetMaxBandwidth()
+	}
+	return cgroup.Limits{
+		ReadIops:  iops,
+		WriteIops: iops,
+		ReadBps:   bandwidth,
+		WriteBps:  bandwidth,
+	}
+}
+
+// SetIoLimits updates the Cos, IoProfile, MaxIops and MaxBandwidth of v from
+// spec, which are set together, and returns true if spec sets them. Setting a
+// HIGH Cos with no maxima removes the limits of a volume.
+func SetIoLimits(v *api.Volume, spec *api.VolumeSpec) bool {
+	if spec == nil || (spec.Cos == api.CosType_NONE &&
+		spec.MaxIops == 0 && spec.MaxBandwidth == 0) {
+		return false
+	}
+	v.Spec.Cos = spec.Cos
+	v.Spec.IoProfile = spec.IoProfile
+	v.Spec.MaxIops = spec.MaxIops
+	v.Spec.MaxBandwidth = spec.MaxBandwidth
+	return true
+}
+
+// Throttle applies the I/O limits of a volume to the cgroups of the
+// containers that mount it at its attach paths. Volumes that are not stored
+// on a block device can only be unlimited. Only drivers whose volumes have a
+// block device of their own, such as a loop or NBD device, throttle them.
+func Throttle(v *api.Volume) error {
+	limits := IoLimits(v.Spec)
+	for _, p := range v.AttachPath {
+		if len(p) == 0 {
+			continue
+		}
+		_, err := cgroup.ThrottleMount(p, limits)
+		if err == cgroup.ErrNotBlock && limits.Unlimited() {
+			continue
+		} else if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RejectIoLimits returns ErrSharedDevice if spec limits the I/O of a new
+// volume that shares its disk with other volumes, which cannot be throttled.
+func RejectIoLimits(spec *api.VolumeSpec) error {
+	if spec != nil && !IoLimits(spec).Unlimited() {
+		return ErrSharedDevice
+	}
+	return nil
+}
+
+// SkipThrottle warns that the I/O limits of a volume that shares its disk
+// with other volumes are not applied.
+func SkipThrottle(v *api.Volume) {
+	if !IoLimits(v.Spec).Unlimited() {
+		dlog.Warnf("Volume %v is not throttled: %v", v.Id, ErrSharedDevice)
+	}
+}
+
+// WatchThrottle throttles the containers that mount a volume mounted at
+// mountpath in the background until it is unmounted. Containers start after
+// their volumes are mounted, so they are looked up every ThrottleInterval,
+// which also applies the limits set on the volume since.
+func WatchThrottle(store volume.Store, volumeID string, mountpath string) {
+	throttleLock.Lock()
+	defer throttleLock.Unlock()
+	if _, ok := throttleJobs[mountpath]; ok {
+		return
+	}
+	stop := make(chan struct{})
+	throttleJobs[mountpath] = stop
+	go watchThrottle(store, volumeID, mountpath, stop)
+}
+
+// StopThrottle stops throttling the containers that mount the volume mounted
+// at mountpath.
+func StopThrottle(mountpath string) {
+	throttleLock.Lock()
+	defer throttleLock.Unlock()
+	if stop, ok := throttleJobs[mountpath]; ok {
+		close(stop)
+		delete(throttleJobs, mountpath)
+	}
+}
+
+// endThrottle removes the job of a watch that stops by itself.
+func endThrottle(mountpath string, stop chan struct{}) {
+	throttleLock.Lock()
+	defer throttleLock.Unlock()
+	if throttleJobs[mountpath] == stop {
+		delete(throttleJobs, mountpath)
+	}
+}
+
+func watchThrottle(
+	store volume.Store,
+	volumeID string,
+	mountpath string,
+	stop chan struct{},
+) {
+	var last cgroup.Limits
+	throttled := make(map[string]bool)
+	ticker := time.NewTicker(ThrottleInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		v, err := store.GetVol(volumeID)
+		if err != nil {
+			if err == volume.ErrEnoEnt {
+				endThrottle(mountpath, stop)
+				return
+			}
+			continue
+		}
+		limits := IoLimits(v.Spec)
+		if limits.Unlimited() && last.Unlimited() {
+			continue
+		}
+		cgroups, err := cgroup.ThrottleMount(mountpath, limits)
+		if err == cgroup.ErrNotBlock || err == cgroup.ErrNotMounted {
+			dlog.Warnf("Cannot throttle volume %v at %v: %v", volumeID, mountpath, err)
+			endThrottle(mountpath, stop)
+			return
+		} else if err != nil {
+			dlog.Warnf("Failed to throttle volume %v at %v: %v", volumeID, mountpath, err)
+			continue
+		}
+		for _, c := range cgroups {
+			if !throttled[c] || limits != last {
+				dlog.Infof("Throttled volume %v in cgroup %v to %+v", volumeID, c, limits)
+			}
+			throttled[c] = true
+		}
+		last = limits
+	}
+}
@@ -0,0 +1,57 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/pkg/cgroup"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIoLimits(t *testing.T) {
+	require.True(t, IoLimits(&api.VolumeSpec{}).Unlimited())
+	require.True(t, IoLimits(&api.VolumeSpec{Cos: api.CosType_HIGH}).Unlimited())
+
+	limits := IoLimits(&api.VolumeSpec{Cos: api.CosType_LOW})
+	require.Equal(t, cgroup.Limits{ReadBps: 50 << 20, WriteBps: 50 << 20}, limits,
+		"Sequential volumes should be limited by bandwidth")
+	limits = IoLimits(&api.VolumeSpec{
+		Cos:       api.CosType_MEDIUM,
+		IoProfile: api.IoProfile_IO_PROFILE_RANDOM,
+	})
+	require.Equal(t, cgroup.Limits{ReadIops: 5000, WriteIops: 5000}, limits,
+		"Random volumes should be limited by IOPS")
+	limits = IoLimits(&api.VolumeSpec{
+		Cos:       api.CosType_LOW,
+		IoProfile: api.IoProfile_IO_PROFILE_DB,
+		MaxIops:   100,
+	})
+	require.Equal(t, cgroup.Limits{
+		ReadIops:  100,
+		WriteIops: 100,
+		ReadBps:   50 << 20,
+		WriteBps:  50 << 20,
+	}, limits, "MaxIops should override the Cos")
+}
+
+func TestSetIoLimits(t *testing.T) {
+	v := &api.Volume{Spec: &api.VolumeSpec{Size: 1, Cos: api.CosType_LOW}}
+	require.False(t, SetIoLimits(v, nil))
+	require.False(t, SetIoLimits(v, &api.VolumeSpec{Size: 2}))
+	require.Equal(t, api.CosType_LOW, v.Spec.Cos)
+
+	require.True(t, SetIoLimits(v, &api.VolumeSpec{MaxBandwidth: 1 << 20}))
+	require.Equal(t, api.CosType_NONE, v.Spec.Cos)
+	require.Equal(t, uint64(1<<20), v.Spec.MaxBandwidth)
+	require.Equal(t, uint64(1), v.Spec.Size)
+
+	require.True(t, SetIoLimits(v, &api.VolumeSpec{Cos: api.CosType_HIGH}))
+	require.True(t, IoLimits(v.Spec).Unlimited())
+}
+
+func TestRejectIoLimits(t *testing.T) {
+	require.NoError(t, RejectIoLimits(nil))
+	require.NoError(t, RejectIoLimits(&api.VolumeSpec{Cos: api.CosType_HIGH}))
+	require.Equal(t, ErrSharedDevice, RejectIoLimits(&api.VolumeSpec{Cos: api.CosType_LOW}))
+	require.Equal(t, ErrSharedDevice, RejectIoLimits(&api.VolumeSpec{MaxIops: 100}))
+}
@@ -5,12 +5,19 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
 
 	"gopkg.in/jmcvetta/napping.v3"
 
 	"go.pedge.io/dlog"
 
 	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/pkg/chaos"
+	"github.com/libopenstorage/openstorage/pkg/mount"
 	"github.com/libopenstorage/openstorage/volume"
 	"github.com/libopenstorage/openstorage/volume/drivers/common"
 	"github.com/portworx/kvdb"
@@ -22,45 +29,27 @@ const (
 	// Type of the driver
 	Type = api.DriverType_DRIVER_TYPE_BLOCK
 
-	// LoginUri path to create a authentication token
-	loginUri = "login.json"
-	// LoginUri path to create volume
-	createVolumeUri = "block/volumes.json"
+	// taskTimeout bounds the time to wait for a CoprHD task.
+	taskTimeout = 10 * time.Minute
+	// taskPollInterval is the time between polls of a CoprHD task.
+	taskPollInterval = 2 * time.Second
+	// deviceTimeout bounds the time for the device of a lun to appear.
+	deviceTimeout = 30 * time.Second
 )
 
-// ApiError represents the default api error code
-type ApiError struct {
-	Code        string `json:"code"`
-	Retryable   string `json:"retryable"`
-	Description string `json:"description"`
-	Details     string `json:"details"`
-}
-
-// CreateVolumeArgs represents the json parameters for the create volume REST call
-type CreateVolumeArgs struct {
-	ConsistencyGroup string `json:"consistency_group"`
-	Count            int    `json:"count"`
-	Name             string `json:"name"`
-	Project          string `json:"project"`
-	Size             string `json:"size"`
-	VArray           string `json:"varray"`
-	VPool            string `json:"vpool"`
-}
-
-// CreateVolumeReply is the reply from the create volume REST call
-type CreateVolumeReply struct {
-	Task []struct {
-		Resource struct {
-			Name string `json:"name"`
-			Id   string `json:"id"`
-		} `json:"resource"`
-	} `json:"task"`
-}
+var (
+	koStrayCreate = chaos.Add("coprhd", "create", "create CoprHD volume before DB")
+	koStrayAttach = chaos.Add("coprhd", "attach", "log in to iSCSI target before DB")
+	koMount       = chaos.Add("coprhd", "mount", "mount iSCSI device")
+	koStrayDelete = chaos.Add("coprhd", "delete", "deactivate CoprHD volume before DB")
+)
 
 type driver struct {
 	volume.IODriver
 	volume.StoreEnumerator
 	volume.StatsDriver
+	volume.TrimDriver
+	volume.ExportDriver
 	consistencyGroup string
 	project          string
 	varray           string
@@ -68,10 +57,33 @@ type driver struct {
 	url              string
 	httpClient       *http.Client
 	creds            *url.Userinfo
+	// lock protects session.
+	lock    sync.Mutex
+	session *napping.Session
+	// initiatorPort is the iSCSI initiator name of this host.
+	initiatorPort    string
+	hostname         string
+	runner           Runner
+	mounter          mount.Manager
+	byPath           string
+	sysBlock         string
+	taskTimeout      time.Duration
+	taskPollInterval time.Duration
+	deviceTimeout    time.Duration
 }
 
 // Init initializes the driver
 func Init(params map[string]string) (volume.VolumeDriver, error) {
+	return newDriver(params, &execRunner{}, nil)
+}
+
+// newDriver creates a driver that runs commands with runner and mounts
+// volumes with mountImpl, or the host mount if mountImpl is nil.
+func newDriver(
+	params map[string]string,
+	runner Runner,
+	mountImpl mount.MountImpl,
+) (*driver, error) {
 	restUrl, ok := params["restUrl"]
 	if !ok {
 		return nil, fmt.Errorf("rest api 'url' configuration parameter must be set")
@@ -107,10 +119,35 @@ func Init(params map[string]string) (volume.VolumeDriver, error) {
 		return nil, fmt.Errorf("'vpool' configuration parameter must be set")
 	}
 
+	initiatorPort, ok := params["initiator"]
+	if !ok {
+		var err error
+		if initiatorPort, err = readInitiatorName(initiatorFile); err != nil {
+			return nil, fmt.Errorf("Failed to read the iSCSI initiator name: %v", err)
+		}
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+
+	mounter, err := mount.New(
+		mount.DeviceMount,
+		mountImpl,
+		[]string{"/dev/"},
+		mount.NewFileJournal(filepath.Join(volume.MountJournalBase, Name)),
+	)
+	if err != nil {
+		return nil, err
+	}
+
 	d := &driver{
 		IODriver:         volume.IONotSupported,
 		StoreEnumerator:  common.NewDefaultStoreEnumerator(Name, kvdb.Instance()),
 		StatsDriver:      volume.StatsNotSupported,
+		TrimDriver:       volume.TrimNotSupported,
+		ExportDriver:     volume.ExportNotSupported,
 		consistencyGroup: consistencyGroup,
 		project:          project,
 		varray:           varray,
@@ -122,6 +159,15 @@ func Init(params map[string]string) (volume.VolumeDriver, error) {
 				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
 			},
 		},
+		initiatorPort:    initiatorPort,
+		hostname:         hostname,
+		runner:           runner,
+		mounter:          mounter,
+		byPath:           byPathDir,
+		sysBlock:         sysBlockDir,
+		taskTimeout:      taskTimeout,
+		taskPollInterval: taskPollInterval,
+		deviceTimeout:    deviceTimeout,
 	}
 
 	return d, nil
@@ -135,121 +181,353 @@ func (d *driver) Type() api.DriverType {
 	return Type
 }
 
+// size returns the CoprHD size of bytes.
+func size(bytes uint64) string {
+	return fmt.Sprintf("%.6fGB", float64(bytes)/(1024*1024*1024))
+}
+
 func (d *driver) Create(
 	locator *api.VolumeLocator,
 	source *api.Source,
 	spec *api.VolumeSpec,
 ) (string, error) {
+	payload := CreateVolumeArgs{
+		d.consistencyGroup, // ConsistencyGroup
+		1,                  // Count
+		locator.Name,       // Name
+		d.project,          // Project
+		size(spec.Size),    // Volume Size
+		d.varray,           // Virtual Block Array
+		d.vpool,            // Virtual Block Pool
+	}
 
-	s, err := d.getAuthSession()
-
+	tasks := TaskList{}
+	if err := d.call("POST", createVolumeUri, nil, &payload, &tasks); err != nil {
+		dlog.Errorf("Failed to create volume %v: %v", locator.Name, err)
+		return "", err
+	}
+	task, err := d.waitTasks(&tasks)
 	if err != nil {
-		dlog.Errorf("Failed to create session: %s", err.Error())
+		dlog.Errorf("Failed to create volume %v: %v", locator.Name, err)
 		return "", err
 	}
 
-	e := ApiError{}
-
-	res := &CreateVolumeReply{}
-
-	sz := int64(spec.Size / (1024 * 1024 * 1000))
-
-	payload := CreateVolumeArgs{
-		d.consistencyGroup,        // ConsistencyGroup
-		1,                         // Count
-		locator.Name,              // Name
-		d.project,                 // Project
-		fmt.Sprintf("%.6fGB", sz), // Volume Size
-		d.varray,                  // Virtual Block Array
-		d.vpool,                   // Virtual Block Pool
+	if err := chaos.Now(koStrayCreate); err != nil {
+		return "", err
 	}
 
-	url := d.url + createVolumeUri
-
-	resp, err := s.Post(url, &payload, res, &e)
-
-	if resp.Status() != http.StatusAccepted {
-
-		return "", fmt.Errorf("Failed to create volume: %s", resp.Status())
+	v := common.NewVolume(
+		task.Resource.Id,
+		api.FSType_FS_TYPE_NONE,
+		locator,
+		source,
+		spec,
+	)
+	if err := d.CreateVol(v); err != nil {
+		return "", err
 	}
-
-	return res.Task[0].Resource.Id, err
+	dlog.Infof("CoprHD volume %v created as %v", locator.Name, v.Id)
+	return v.Id, nil
 }
 
 func (d *driver) Delete(volumeID string) error {
-	return nil
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		return err
+	}
+	if len(v.DevicePath) != 0 {
+		return volume.ErrVolAttached
+	}
+	tasks := TaskList{}
+	if err := d.call(
+		"POST",
+		blockUri(volumeID)+"/deactivate.json",
+		nil,
+		nil,
+		&tasks,
+	); err != nil {
+		return err
+	}
+	if _, err := d.waitTasks(&tasks); err != nil {
+		return err
+	}
+	if err := chaos.Now(koStrayDelete); err != nil {
+		return err
+	}
+	return d.DeleteVol(volumeID)
 }
 
+// Attach exports the volume to the iSCSI initiator of this host, logs in
+// to the target and returns the block device of the volume.
 func (d *driver) Attach(volumeID string, attachOptions map[string]string) (path string, err error) {
-	return "", nil
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		return "", err
+	}
+	if len(v.DevicePath) != 0 {
+		return v.DevicePath, nil
+	}
+	itl, err := d.itl(volumeID)
+	if err != nil {
+		return "", err
+	}
+	if itl == nil {
+		if err := d.export(v); err != nil {
+			return "", err
+		}
+		if itl, err = d.itl(volumeID); err != nil {
+			return "", err
+		}
+		if itl == nil {
+			return "", fmt.Errorf("Volume %v is not exported to %v",
+				volumeID, d.initiatorPort)
+		}
+	}
+	dev, err := d.login(itl)
+	if err != nil {
+		return "", err
+	}
+	if err := chaos.Now(koStrayAttach); err != nil {
+		return "", err
+	}
+	v.DevicePath = dev
+	v.AttachedOn = d.hostname
+	v.State = api.VolumeState_VOLUME_STATE_ATTACHED
+	if err := d.UpdateVol(v); err != nil {
+		return "", err
+	}
+	dlog.Infof("CoprHD volume %v attached at %v", volumeID, dev)
+	return dev, nil
+}
+
+// export creates an export group of the volume v for the initiator of this
+// host.
+func (d *driver) export(v *api.Volume) error {
+	initiator, err := d.initiator()
+	if err != nil {
+		return err
+	}
+	// The uuid of a CoprHD id urn:storageos:Volume:<uuid>:<vdc> names the
+	// export group.
+	name := v.Id
+	if parts := strings.Split(v.Id, ":"); len(parts) > 3 {
+		name = parts[3]
+	}
+	task := Task{}
+	if err := d.call(
+		"POST",
+		exportsUri,
+		nil,
+		&CreateExportArgs{
+			Name:       d.hostname + "-" + name,
+			Project:    d.project,
+			VArray:     d.varray,
+			Type:       "Initiator",
+			Initiators: []string{initiator},
+			Volumes:    []ExportVolume{{Id: v.Id}},
+		},
+		&task,
+	); err != nil {
+		return err
+	}
+	_, err = d.waitTask(task)
+	return err
 }
 
 func (d *driver) MountedAt(mountpath string) string {
 	return ""
 }
 
+// Detach removes the block device of the volume and deletes the export
+// group that exports the volume to this host.
 func (d *driver) Detach(volumeID string) error {
-	return nil
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		return err
+	}
+	if len(v.AttachPath) != 0 {
+		return fmt.Errorf("Volume %v is mounted at %v", volumeID, v.AttachPath)
+	}
+	if len(v.DevicePath) != 0 {
+		if err := d.removeDevice(v.DevicePath); err != nil {
+			return err
+		}
+	}
+	itl, err := d.itl(volumeID)
+	if err != nil {
+		return err
+	}
+	if itl != nil && len(itl.Export.Id) != 0 {
+		task := Task{}
+		if err := d.call(
+			"POST",
+			"block/exports/"+itl.Export.Id+"/deactivate.json",
+			nil,
+			nil,
+			&task,
+		); err != nil {
+			return err
+		}
+		if _, err := d.waitTask(task); err != nil {
+			return err
+		}
+	}
+	v.DevicePath = ""
+	v.AttachedOn = ""
+	v.State = api.VolumeState_VOLUME_STATE_DETACHED
+	return d.UpdateVol(v)
 }
 
 func (d *driver) Mount(volumeID string, mountpath string) error {
-	return nil
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		return fmt.Errorf("Failed to locate volume %q", volumeID)
+	}
+	if err := common.CheckAccess(
+		v,
+		mountpath,
+		api.AccessMode_ACCESS_MODE_READ_WRITE_ONCE,
+	); err != nil {
+		return err
+	}
+	if len(v.DevicePath) == 0 {
+		return volume.ErrVolDetached
+	}
+	if v.Format == api.FSType_FS_TYPE_NONE {
+		if err := d.format(v); err != nil {
+			return err
+		}
+	}
+	if err := chaos.Now(koMount); err != nil {
+		return err
+	}
+	flags, data := common.MountFlags(v)
+	if err := d.mounter.Mount(
+		0,
+		v.DevicePath,
+		mountpath,
+		v.Format.SimpleString(),
+		flags,
+		data,
+		0,
+	); err != nil {
+		return fmt.Errorf("Failed to mount %v at %v: %v", v.DevicePath, mountpath, err)
+	}
+	common.AddAttachPath(v, mountpath)
+	return d.UpdateVol(v)
 }
 
-func (d *driver) Unmount(volumeID string, mountpath string) error {
+// format makes the filesystem in the spec of a new volume on its device.
+func (d *driver) format(v *api.Volume) error {
+	if v.Spec.Format == api.FSType_FS_TYPE_NONE {
+		return fmt.Errorf("Volume %v has no filesystem", v.Id)
+	}
+	dlog.Infof("Formatting %s with %v", v.DevicePath, v.Spec.Format)
+	if _, err := d.runner.Run(
+		"mkfs."+v.Spec.Format.SimpleString(),
+		v.DevicePath,
+	); err != nil {
+		return err
+	}
+	v.Format = v.Spec.Format
+	return d.UpdateVol(v)
+}
 
-	return nil
+func (d *driver) Unmount(volumeID string, mountpath string) error {
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		return err
+	}
+	if len(v.AttachPath) == 0 || len(v.AttachPath[0]) == 0 {
+		return fmt.Errorf("Device %v not mounted", volumeID)
+	}
+	if len(mountpath) == 0 {
+		mountpath = v.AttachPath[0]
+	}
+	if err := d.mounter.Unmount(v.DevicePath, mountpath, 0); err != nil {
+		return err
+	}
+	common.RemoveAttachPath(v, mountpath)
+	return d.UpdateVol(v)
 }
 
+// Set updates the locator and expands the volume to spec.Size.
 func (d *driver) Set(
 	volumeID string,
 	locator *api.VolumeLocator,
 	spec *api.VolumeSpec) error {
-	return volume.ErrNotSupported
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		return err
+	}
+	if locator != nil {
+		v.Locator = locator
+	}
+	if spec != nil && spec.Size != 0 && spec.Size != v.Spec.Size {
+		if spec.Size < v.Spec.Size {
+			return fmt.Errorf("Volume %v cannot shrink from %v to %v",
+				volumeID, v.Spec.Size, spec.Size)
+		}
+		task := Task{}
+		if err := d.call(
+			"POST",
+			blockUri(volumeID)+"/expand.json",
+			nil,
+			&ExpandVolumeArgs{NewSize: size(spec.Size)},
+			&task,
+		); err != nil {
+			return err
+		}
+		if _, err := d.waitTask(task); err != nil {
+			return err
+		}
+		v.Spec.Size = spec.Size
+	}
+	return d.UpdateVol(v)
 }
 
 func (d *driver) Shutdown() {
 	dlog.Infof("%s Shutting down", Name)
 }
 
+// Snapshot creates a block snapshot of the volume. Snapshots are attached
+// and mounted like volumes.
 func (d *driver) Snapshot(
 	volumeID string,
 	readonly bool,
 	locator *api.VolumeLocator) (string, error) {
-	return "", nil
-}
-
-func (d *driver) Status() [][2]string {
-	return [][2]string{}
-}
-
-// getAuthSession returns an authenticated API Session
-func (d *driver) getAuthSession() (session *napping.Session, err error) {
-	e := ApiError{}
-
-	s := napping.Session{
-		Userinfo: d.creds,
-		Client:   d.httpClient,
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		return "", err
 	}
-
-	url := d.url + loginUri
-
-	resp, err := s.Get(url, nil, nil, &e)
-
+	tasks := TaskList{}
+	if err := d.call(
+		"POST",
+		blockUri(volumeID)+"/protection/snapshots.json",
+		nil,
+		&CreateSnapshotArgs{Name: locator.Name, ReadOnly: readonly},
+		&tasks,
+	); err != nil {
+		return "", err
+	}
+	task, err := d.waitTasks(&tasks)
 	if err != nil {
-		return
+		return "", err
 	}
-
-	token := resp.HttpResponse().Header.Get("X-SDS-AUTH-TOKEN")
-
-	h := http.Header{}
-
-	h.Set("X-SDS-AUTH-TOKEN", token)
-
-	session = &napping.Session{
-		Client: d.httpClient,
-		Header: &h,
+	snap := common.NewVolume(
+		task.Resource.Id,
+		v.Format,
+		locator,
+		&api.Source{Parent: volumeID},
+		v.Spec,
+	)
+	snap.Readonly = readonly
+	if err := d.CreateVol(snap); err != nil {
+		return "", err
 	}
+	return snap.Id, nil
+}
 
-	return
+func (d *driver) Status() [][2]string {
+	return [][2]string{}
 }
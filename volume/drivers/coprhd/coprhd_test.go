@@ -0,0 +1,329 @@
+package coprhd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/libopenstorage/openstorage/api"
+	_ "github.com/libopenstorage/openstorage/volume/drivers/test"
+)
+
+const (
+	testToken     = "token"
+	testInitiator = "iqn.2016-01.org.openstorage:host"
+	testTarget    = "iqn.2016-01.org.coprhd:target"
+)
+
+// fakeCoprHD serves the part of the CoprHD REST API that the driver uses.
+// Tasks are pending until they are polled once.
+type fakeCoprHD struct {
+	sync.Mutex
+	next    int
+	volumes map[string]bool
+	// exports maps export groups to the volume or snapshot they export.
+	exports map[string]string
+	tasks   map[string]*Task
+}
+
+func newFakeCoprHD() *fakeCoprHD {
+	return &fakeCoprHD{
+		volumes: make(map[string]bool),
+		exports: make(map[string]string),
+		tasks:   make(map[string]*Task),
+	}
+}
+
+func (f *fakeCoprHD) urn(kind string) string {
+	f.next++
+	return fmt.Sprintf("urn:storageos:%s:%d:vdc1", kind, f.next)
+}
+
+// task starts a task on the resource id that completes in state.
+func (f *fakeCoprHD) task(id string, state string) Task {
+	t := &Task{Id: f.urn("Task"), State: state, Resource: Resource{Id: id}}
+	if state == taskError {
+		t.Message = "failed"
+	}
+	f.tasks[t.Id] = t
+	return Task{Id: t.Id, State: taskPending, Resource: t.Resource}
+}
+
+func (f *fakeCoprHD) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+
+	if r.URL.Path == "/login.json" {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "root" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("X-SDS-AUTH-TOKEN", testToken)
+		w.Write([]byte("{}"))
+		return
+	}
+	if r.Header.Get("X-SDS-AUTH-TOKEN") != testToken {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	parts := strings.Split(strings.TrimSuffix(strings.Trim(r.URL.Path, "/"), ".json"), "/")
+	var res interface{}
+	switch {
+	case r.Method == "POST" && r.URL.Path == "/"+createVolumeUri:
+		args := CreateVolumeArgs{}
+		json.NewDecoder(r.Body).Decode(&args)
+		id := f.urn("Volume")
+		state := taskReady
+		if args.Name == "fail" {
+			state = taskError
+		} else {
+			f.volumes[id] = true
+		}
+		res = TaskList{Task: []Task{f.task(id, state)}}
+	case r.Method == "GET" && len(parts) == 3 && parts[1] == "tasks":
+		t, ok := f.tasks[parts[2]]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		res = t
+	case r.Method == "POST" && len(parts) == 4 && parts[3] == "deactivate":
+		if parts[1] == "exports" {
+			delete(f.exports, parts[2])
+			res = f.task(parts[2], taskReady)
+			break
+		}
+		if !f.volumes[parts[2]] {
+			http.NotFound(w, r)
+			return
+		}
+		delete(f.volumes, parts[2])
+		res = TaskList{Task: []Task{f.task(parts[2], taskReady)}}
+	case r.Method == "POST" && len(parts) == 5 && parts[4] == "snapshots":
+		id := f.urn("BlockSnapshot")
+		f.volumes[id] = true
+		res = TaskList{Task: []Task{f.task(id, taskReady)}}
+	case r.Method == "POST" && len(parts) == 4 && parts[3] == "expand":
+		res = f.task(parts[2], taskReady)
+	case r.Method == "GET" && r.URL.Path == "/"+initiatorSearchUri:
+		results := SearchResults{}
+		if r.URL.Query().Get("initiator_port") == testInitiator {
+			results.Resource = append(results.Resource, Resource{Id: "urn:storageos:Initiator:0:vdc1"})
+		}
+		res = results
+	case r.Method == "POST" && r.URL.Path == "/"+exportsUri:
+		args := CreateExportArgs{}
+		json.NewDecoder(r.Body).Decode(&args)
+		id := f.urn("ExportGroup")
+		f.exports[id] = args.Volumes[0].Id
+		res = f.task(id, taskReady)
+	case r.Method == "GET" && len(parts) == 4 && parts[3] == "exports":
+		itls := ItlList{}
+		for id, v := range f.exports {
+			if v != parts[2] {
+				continue
+			}
+			itl := Itl{Hlu: 1, Export: Resource{Id: id}}
+			itl.Initiator.Port = testInitiator
+			itl.Target.Port = testTarget
+			itl.Target.IpAddress = "127.0.0.1"
+			itl.Target.TcpPort = "3260"
+			itls.Itl = append(itls.Itl, itl)
+		}
+		res = itls
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	json.NewEncoder(w).Encode(res)
+}
+
+// fakeISCSI logs in by linking the lun to a device file.
+type fakeISCSI struct {
+	dir  string
+	cmds []string
+}
+
+func (f *fakeISCSI) Run(name string, args ...string) (string, error) {
+	f.cmds = append(f.cmds, name+" "+strings.Join(args, " "))
+	if name == "iscsiadm" && args[len(args)-1] == "--login" {
+		dev := filepath.Join(f.dir, "dev", "sdb")
+		if err := ioutil.WriteFile(dev, nil, 0644); err != nil {
+			return "", err
+		}
+		if err := os.MkdirAll(filepath.Join(f.dir, "sys", "sdb", "device"), 0755); err != nil {
+			return "", err
+		}
+		return "", os.Symlink(dev, filepath.Join(
+			f.dir,
+			"by-path",
+			"ip-127.0.0.1:3260-iscsi-"+testTarget+"-lun-1",
+		))
+	}
+	return "", nil
+}
+
+// fakeMount records the mounts.
+type fakeMount struct {
+	mounts map[string]string
+}
+
+func (f *fakeMount) Mount(source, target, fstype string, flags uintptr, data string, timeout int) error {
+	f.mounts[target] = source
+	return nil
+}
+
+func (f *fakeMount) Unmount(target string, flags int, timeout int) error {
+	delete(f.mounts, target)
+	return nil
+}
+
+func setup(t *testing.T) (*driver, *fakeCoprHD, *fakeISCSI, *fakeMount, func()) {
+	fake := newFakeCoprHD()
+	server := httptest.NewServer(fake)
+	dir, err := ioutil.TempDir("", "coprhd")
+	require.NoError(t, err)
+	for _, sub := range []string{"dev", "by-path", "sys"} {
+		require.NoError(t, os.Mkdir(filepath.Join(dir, sub), 0755))
+	}
+	iscsi := &fakeISCSI{dir: dir}
+	mounts := &fakeMount{mounts: make(map[string]string)}
+	d, err := newDriver(
+		map[string]string{
+			"restUrl":           server.URL + "/",
+			"user":              "root",
+			"password":          "secret",
+			"consistency_group": "cg",
+			"project":           "project",
+			"varray":            "varray",
+			"vpool":             "vpool",
+			"initiator":         testInitiator,
+		},
+		iscsi,
+		mounts,
+	)
+	require.NoError(t, err)
+	d.byPath = filepath.Join(dir, "by-path")
+	d.sysBlock = filepath.Join(dir, "sys")
+	d.taskPollInterval = time.Millisecond
+	d.deviceTimeout = 100 * time.Millisecond
+	return d, fake, iscsi, mounts, func() {
+		server.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestCreateDelete(t *testing.T) {
+	d, fake, _, _, cleanup := setup(t)
+	defer cleanup()
+
+	id, err := d.Create(
+		&api.VolumeLocator{Name: "vol"},
+		nil,
+		&api.VolumeSpec{Size: 1024 * 1024 * 1024, Format: api.FSType_FS_TYPE_EXT4},
+	)
+	require.NoError(t, err)
+	require.True(t, fake.volumes[id])
+
+	vols, err := d.Enumerate(&api.VolumeLocator{Name: "vol"}, nil)
+	require.NoError(t, err)
+	require.Len(t, vols, 1)
+	require.Equal(t, id, vols[0].Id)
+
+	require.NoError(t, d.Set(id, nil, &api.VolumeSpec{Size: 2 * 1024 * 1024 * 1024}))
+	require.Error(t, d.Set(id, nil, &api.VolumeSpec{Size: 1024}))
+
+	require.NoError(t, d.Delete(id))
+	require.False(t, fake.volumes[id])
+	_, err = d.Inspect([]string{id})
+	require.NoError(t, err)
+	vols, err = d.Enumerate(&api.VolumeLocator{Name: "vol"}, nil)
+	require.NoError(t, err)
+	require.Len(t, vols, 0)
+
+	_, err = d.Create(&api.VolumeLocator{Name: "fail"}, nil, &api.VolumeSpec{})
+	require.Error(t, err, "Failed tasks should fail the create")
+}
+
+func TestAttachMount(t *testing.T) {
+	d, fake, iscsi, mounts, cleanup := setup(t)
+	defer cleanup()
+
+	id, err := d.Create(
+		&api.VolumeLocator{Name: "attached"},
+		nil,
+		&api.VolumeSpec{Size: 1024 * 1024 * 1024, Format: api.FSType_FS_TYPE_EXT4},
+	)
+	require.NoError(t, err)
+
+	dev, err := d.Attach(id, nil)
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(iscsi.dir, "dev", "sdb"), dev)
+	require.Len(t, fake.exports, 1)
+	again, err := d.Attach(id, nil)
+	require.NoError(t, err)
+	require.Equal(t, dev, again)
+	require.Len(t, fake.exports, 1)
+
+	mountPath := filepath.Join(iscsi.dir, "mnt")
+	require.NoError(t, d.Mount(id, mountPath))
+	require.Equal(t, dev, mounts.mounts[mountPath])
+	require.Contains(t, iscsi.cmds, "mkfs.ext4 "+dev)
+	vols, err := d.Inspect([]string{id})
+	require.NoError(t, err)
+	require.Equal(t, api.FSType_FS_TYPE_EXT4, vols[0].Format)
+	require.Equal(t, []string{mountPath}, vols[0].AttachPath)
+	require.Error(t, d.Detach(id), "Detach of a mounted volume should fail")
+
+	require.NoError(t, d.Unmount(id, mountPath))
+	require.Len(t, mounts.mounts, 0)
+	require.Error(t, d.Delete(id), "Delete of an attached volume should fail")
+	require.NoError(t, d.Detach(id))
+	data, err := ioutil.ReadFile(filepath.Join(iscsi.dir, "sys", "sdb", "device", "delete"))
+	require.NoError(t, err)
+	require.Equal(t, "1", string(data))
+	require.Len(t, fake.exports, 0)
+
+	require.NoError(t, d.Delete(id))
+}
+
+func TestSnapshot(t *testing.T) {
+	d, fake, _, _, cleanup := setup(t)
+	defer cleanup()
+
+	id, err := d.Create(
+		&api.VolumeLocator{Name: "parent"},
+		nil,
+		&api.VolumeSpec{Size: 1024 * 1024 * 1024, Format: api.FSType_FS_TYPE_EXT4},
+	)
+	require.NoError(t, err)
+
+	snapID, err := d.Snapshot(id, true, &api.VolumeLocator{Name: "snap"})
+	require.NoError(t, err)
+	require.True(t, strings.Contains(snapID, ":BlockSnapshot:"))
+	snaps, err := d.SnapEnumerate([]string{id}, nil)
+	require.NoError(t, err)
+	require.Len(t, snaps, 1)
+	require.Equal(t, snapID, snaps[0].Id)
+	require.True(t, snaps[0].Readonly)
+
+	_, err = d.Attach(snapID, nil)
+	require.NoError(t, err)
+	require.Equal(t, snapID, fake.exports[fmt.Sprintf("urn:storageos:ExportGroup:%d:vdc1", fake.next-1)])
+	require.NoError(t, d.Detach(snapID))
+
+	require.NoError(t, d.Delete(snapID))
+	require.False(t, fake.volumes[snapID])
+	require.NoError(t, d.Delete(id))
+}
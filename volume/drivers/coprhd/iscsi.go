@@ -0,0 +1,122 @@
+package coprhd
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// initiatorFile holds the iSCSI initiator name of this host.
+	initiatorFile = "/etc/iscsi/initiatorname.iscsi"
+	// byPathDir holds the links to block devices by their transport path.
+	byPathDir = "/dev/disk/by-path"
+	// sysBlockDir holds the sysfs directories of block devices.
+	sysBlockDir = "/sys/block"
+)
+
+// Runner runs the iscsiadm and filesystem commands of the driver, so that
+// unit tests can replace them with a fake.
+type Runner interface {
+	// Run runs the command name with args and returns its output.
+	Run(name string, args ...string) (string, error)
+}
+
+// execRunner runs commands on the host.
+type execRunner struct{}
+
+func (e *execRunner) Run(name string, args ...string) (string, error) {
+	o, err := exec.Command(name, args...).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("%v %v failed: %v: %s",
+			name, strings.Join(args, " "), err, strings.TrimSpace(string(o)))
+	}
+	return string(o), nil
+}
+
+// readInitiatorName returns the iSCSI initiator name in file.
+func readInitiatorName(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "InitiatorName=") {
+			return strings.TrimPrefix(line, "InitiatorName="), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("No InitiatorName in %v", file)
+}
+
+// portal returns the address of the target of itl.
+func portal(itl *Itl) string {
+	port := itl.Target.TcpPort
+	if port == "" {
+		port = "3260"
+	}
+	return itl.Target.IpAddress + ":" + port
+}
+
+// login logs in to the target of itl and returns the block device of its
+// lun once it appears.
+func (d *driver) login(itl *Itl) (string, error) {
+	p := portal(itl)
+	if _, err := d.runner.Run(
+		"iscsiadm",
+		"-m", "discovery",
+		"-t", "sendtargets",
+		"-p", p,
+	); err != nil {
+		return "", err
+	}
+	if _, err := d.runner.Run(
+		"iscsiadm",
+		"-m", "node",
+		"-T", itl.Target.Port,
+		"-p", p,
+		"--login",
+	); err != nil && !strings.Contains(err.Error(), "already present") {
+		return "", err
+	}
+	link := filepath.Join(
+		d.byPath,
+		fmt.Sprintf("ip-%s-iscsi-%s-lun-%d", p, itl.Target.Port, itl.Hlu),
+	)
+	deadline := time.Now().Add(d.deviceTimeout)
+	for {
+		dev, err := filepath.EvalSymlinks(link)
+		if err == nil {
+			return dev, nil
+		}
+		if time.Now().After(deadline) {
+			return "", fmt.Errorf("Device %v did not appear: %v", link, err)
+		}
+		// Ask the kernel to scan the session for the new lun.
+		d.runner.Run("iscsiadm", "-m", "session", "--rescan")
+		time.Sleep(d.deviceTimeout / 10)
+	}
+}
+
+// removeDevice flushes and removes the block device dev. The session to
+// the target stays logged in for the other volumes on the target.
+func (d *driver) removeDevice(dev string) error {
+	if _, err := d.runner.Run("blockdev", "--flushbufs", dev); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(
+		filepath.Join(d.sysBlock, filepath.Base(dev), "device", "delete"),
+		[]byte("1"),
+		0200,
+	)
+}
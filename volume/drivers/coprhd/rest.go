@@ -0,0 +1,282 @@
+package coprhd
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"gopkg.in/jmcvetta/napping.v3"
+)
+
+const (
+	// loginUri path to create a authentication token
+	loginUri = "login.json"
+	// createVolumeUri path to create volume
+	createVolumeUri = "block/volumes.json"
+	// exportsUri path to create export groups
+	exportsUri = "block/exports.json"
+	// initiatorSearchUri path to find an initiator by its port
+	initiatorSearchUri = "compute/initiators/search.json"
+
+	// Task states
+	taskPending = "pending"
+	taskReady   = "ready"
+	taskError   = "error"
+)
+
+// ApiError represents the default api error code
+type ApiError struct {
+	Code        string `json:"code"`
+	Retryable   string `json:"retryable"`
+	Description string `json:"description"`
+	Details     string `json:"details"`
+}
+
+// CreateVolumeArgs represents the json parameters for the create volume REST call
+type CreateVolumeArgs struct {
+	ConsistencyGroup string `json:"consistency_group"`
+	Count            int    `json:"count"`
+	Name             string `json:"name"`
+	Project          string `json:"project"`
+	Size             string `json:"size"`
+	VArray           string `json:"varray"`
+	VPool            string `json:"vpool"`
+}
+
+// ExpandVolumeArgs represents the json parameters for the expand volume REST call
+type ExpandVolumeArgs struct {
+	NewSize string `json:"new_size"`
+}
+
+// CreateSnapshotArgs represents the json parameters for the create snapshot REST call
+type CreateSnapshotArgs struct {
+	Name           string `json:"name"`
+	CreateInactive bool   `json:"create_inactive"`
+	ReadOnly       bool   `json:"read_only"`
+}
+
+// CreateExportArgs represents the json parameters for the create export group REST call
+type CreateExportArgs struct {
+	Name       string         `json:"name"`
+	Project    string         `json:"project"`
+	VArray     string         `json:"varray"`
+	Type       string         `json:"type"`
+	Initiators []string       `json:"initiators"`
+	Volumes    []ExportVolume `json:"volumes"`
+}
+
+// ExportVolume is a volume or snapshot in an export group
+type ExportVolume struct {
+	Id string `json:"id"`
+}
+
+// Resource is a reference to a CoprHD resource
+type Resource struct {
+	Name string `json:"name"`
+	Id   string `json:"id"`
+}
+
+// Task is an asynchronous operation on a resource
+type Task struct {
+	Id       string   `json:"id"`
+	State    string   `json:"state"`
+	Message  string   `json:"message"`
+	Resource Resource `json:"resource"`
+}
+
+// TaskList is the reply from the REST calls that start one task per resource
+type TaskList struct {
+	Task []Task `json:"task"`
+}
+
+// SearchResults is the reply from the search REST calls
+type SearchResults struct {
+	Resource []Resource `json:"resource"`
+}
+
+// Itl is an initiator-target-lun path to an exported volume or snapshot
+type Itl struct {
+	Hlu       int `json:"hlu"`
+	Initiator struct {
+		Port string `json:"port"`
+	} `json:"initiator"`
+	Device struct {
+		Id  string `json:"id"`
+		Wwn string `json:"wwn"`
+	} `json:"device"`
+	Target struct {
+		Port      string `json:"port"`
+		IpAddress string `json:"ip_address"`
+		TcpPort   string `json:"tcp_port"`
+	} `json:"target"`
+	Export Resource `json:"export"`
+}
+
+// ItlList is the reply from the exports REST call of a volume or snapshot
+type ItlList struct {
+	Itl []Itl `json:"itl"`
+}
+
+// blockUri returns the path of the volume or snapshot id.
+func blockUri(id string) string {
+	if strings.Contains(id, ":BlockSnapshot:") {
+		return "block/snapshots/" + id
+	}
+	return "block/volumes/" + id
+}
+
+// getAuthSession returns an authenticated API Session
+func (d *driver) getAuthSession() (session *napping.Session, err error) {
+	e := ApiError{}
+
+	s := napping.Session{
+		Userinfo: d.creds,
+		Client:   d.httpClient,
+	}
+
+	url := d.url + loginUri
+
+	resp, err := s.Get(url, nil, nil, &e)
+
+	if err != nil {
+		return
+	}
+	if resp.Status() != http.StatusOK {
+		return nil, fmt.Errorf("Failed to login: %v %v", resp.Status(), e.Description)
+	}
+
+	token := resp.HttpResponse().Header.Get("X-SDS-AUTH-TOKEN")
+
+	h := http.Header{}
+
+	h.Set("X-SDS-AUTH-TOKEN", token)
+
+	session = &napping.Session{
+		Client: d.httpClient,
+		Header: &h,
+	}
+
+	return
+}
+
+// call sends a request to the CoprHD API and decodes the reply into res.
+// The session is reused until its token expires.
+func (d *driver) call(
+	method string,
+	uri string,
+	params *url.Values,
+	payload interface{},
+	res interface{},
+) error {
+	for retry := 0; ; retry++ {
+		d.lock.Lock()
+		if d.session == nil {
+			s, err := d.getAuthSession()
+			if err != nil {
+				d.lock.Unlock()
+				return err
+			}
+			d.session = s
+		}
+		s := d.session
+		d.lock.Unlock()
+
+		e := ApiError{}
+		resp, err := s.Send(&napping.Request{
+			Url:     d.url + uri,
+			Method:  method,
+			Params:  params,
+			Payload: payload,
+			Result:  res,
+			Error:   &e,
+		})
+		if err != nil {
+			return err
+		}
+		if resp.Status() == http.StatusUnauthorized && retry == 0 {
+			d.lock.Lock()
+			d.session = nil
+			d.lock.Unlock()
+			continue
+		}
+		if resp.Status() >= 300 {
+			return fmt.Errorf("%v %v failed: %v %v",
+				method, uri, resp.Status(), e.Description)
+		}
+		return nil
+	}
+}
+
+// waitTask polls task until it completes and returns the completed task.
+func (d *driver) waitTask(task Task) (*Task, error) {
+	deadline := time.Now().Add(d.taskTimeout)
+	for {
+		switch task.State {
+		case taskReady:
+			return &task, nil
+		case taskError:
+			return nil, fmt.Errorf("Task %v on %v failed: %v",
+				task.Id, task.Resource.Id, task.Message)
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("Task %v on %v did not complete in %v",
+				task.Id, task.Resource.Id, d.taskTimeout)
+		}
+		time.Sleep(d.taskPollInterval)
+		next := Task{}
+		if err := d.call(
+			"GET",
+			"vdc/tasks/"+task.Id+".json",
+			nil,
+			nil,
+			&next,
+		); err != nil {
+			return nil, err
+		}
+		task = next
+	}
+}
+
+// waitTasks waits for the first task of tasks.
+func (d *driver) waitTasks(tasks *TaskList) (*Task, error) {
+	if len(tasks.Task) == 0 {
+		return nil, fmt.Errorf("No task was started")
+	}
+	return d.waitTask(tasks.Task[0])
+}
+
+// initiator returns the id of the initiator of this host.
+func (d *driver) initiator() (string, error) {
+	res := SearchResults{}
+	if err := d.call(
+		"GET",
+		initiatorSearchUri,
+		&url.Values{"initiator_port": []string{d.initiatorPort}},
+		nil,
+		&res,
+	); err != nil {
+		return "", err
+	}
+	if len(res.Resource) == 0 {
+		return "", fmt.Errorf("Initiator %v is not registered with CoprHD",
+			d.initiatorPort)
+	}
+	return res.Resource[0].Id, nil
+}
+
+// itl returns the path from the initiator of this host to the volume or
+// snapshot id, or nil if id is not exported to this host.
+func (d *driver) itl(id string) (*Itl, error) {
+	res := ItlList{}
+	if err := d.call("GET", blockUri(id)+"/exports.json", nil, nil, &res); err != nil {
+		return nil, err
+	}
+	for i := range res.Itl {
+		if res.Itl[i].Initiator.Port == d.initiatorPort {
+			return &res.Itl[i], nil
+		}
+	}
+	return nil, nil
+}
@@ -4,12 +4,20 @@ import (
 	"github.com/libopenstorage/openstorage/api"
 	"github.com/libopenstorage/openstorage/volume"
 	"github.com/libopenstorage/openstorage/volume/drivers/aws"
+	"github.com/libopenstorage/openstorage/volume/drivers/azure"
 	"github.com/libopenstorage/openstorage/volume/drivers/btrfs"
 	"github.com/libopenstorage/openstorage/volume/drivers/buse"
 	"github.com/libopenstorage/openstorage/volume/drivers/coprhd"
+	"github.com/libopenstorage/openstorage/volume/drivers/fuse/memfs"
+	"github.com/libopenstorage/openstorage/volume/drivers/fuse/passthrough"
+	"github.com/libopenstorage/openstorage/volume/drivers/gce"
+	"github.com/libopenstorage/openstorage/volume/drivers/loop"
+	"github.com/libopenstorage/openstorage/volume/drivers/lvm"
+	"github.com/libopenstorage/openstorage/volume/drivers/mirror"
 	"github.com/libopenstorage/openstorage/volume/drivers/nfs"
 	"github.com/libopenstorage/openstorage/volume/drivers/pwx"
 	"github.com/libopenstorage/openstorage/volume/drivers/vfs"
+	"github.com/libopenstorage/openstorage/volume/drivers/zfs"
 )
 
 // Driver is the description of a supported OST driver. New Drivers are added to
@@ -24,29 +32,53 @@ var (
 	AllDrivers = []Driver{
 		// AWS driver provisions storage from EBS.
 		{DriverType: aws.Type, Name: aws.Name},
+		// Azure driver provisions storage from managed disks.
+		{DriverType: azure.Type, Name: azure.Name},
 		// BTRFS driver provisions storage from local btrfs.
 		{DriverType: btrfs.Type, Name: btrfs.Name},
 		// BUSE driver provisions storage from local volumes and implements block in user space.
 		{DriverType: buse.Type, Name: buse.Name},
 		// COPRHD driver
 		{DriverType: coprhd.Type, Name: coprhd.Name},
+		// GCE driver provisions storage from persistent disks.
+		{DriverType: gce.Type, Name: gce.Name},
+		// Loop driver provisions storage from local files attached as loop devices.
+		{DriverType: loop.Type, Name: loop.Name},
+		// LVM driver provisions storage from a thin pool of a local volume group.
+		{DriverType: lvm.Type, Name: lvm.Name},
+		// Memfs driver provisions scratch storage from memory through FUSE.
+		{DriverType: memfs.Type, Name: memfs.Name},
+		// Mirror driver provisions block storage replicated on HaLevel nodes of the cluster.
+		{DriverType: mirror.Type, Name: mirror.Name},
 		// NFS driver provisions storage from an NFS server.
 		{DriverType: nfs.Type, Name: nfs.Name},
+		// Passthrough driver provisions storage from local directories through FUSE.
+		{DriverType: passthrough.Type, Name: passthrough.Name},
 		// PWX driver provisions storage from PWX cluster.
 		{DriverType: pwx.Type, Name: pwx.Name},
 		// VFS driver provisions storage from local filesystem
 		{DriverType: vfs.Type, Name: vfs.Name},
+		// ZFS driver provisions storage from datasets and zvols of a local zfs pool.
+		{DriverType: zfs.Type, Name: zfs.Name},
 	}
 
 	volumeDriverRegistry = volume.NewVolumeDriverRegistry(
 		map[string]func(map[string]string) (volume.VolumeDriver, error){
-			aws.Name:    aws.Init,
-			btrfs.Name:  btrfs.Init,
-			buse.Name:   buse.Init,
-			coprhd.Name: coprhd.Init,
-			nfs.Name:    nfs.Init,
-			pwx.Name:    pwx.Init,
-			vfs.Name:    vfs.Init,
+			aws.Name:         aws.Init,
+			azure.Name:       azure.Init,
+			btrfs.Name:       btrfs.Init,
+			buse.Name:        buse.Init,
+			coprhd.Name:      coprhd.Init,
+			gce.Name:         gce.Init,
+			loop.Name:        loop.Init,
+			lvm.Name:         lvm.Init,
+			memfs.Name:       memfs.Init,
+			mirror.Name:      mirror.Init,
+			nfs.Name:         nfs.Init,
+			passthrough.Name: passthrough.Init,
+			pwx.Name:         pwx.Init,
+			vfs.Name:         vfs.Init,
+			zfs.Name:         zfs.Init,
 		},
 	)
 )
@@ -7,12 +7,27 @@ import (
 	"github.com/libopenstorage/openstorage/volume"
 )
 
-// Provider provides fuse.FS and fuse.MountOptions, given an *api.VolumeSpec.
+// Provider provides fuse.FS and fuse.MountOptions, given an *api.Volume.
+// The DevicePath of the volume is a directory that the provider may keep
+// the data of the volume in. A Provider that also implements
+// volume.StatsDriver serves the stats of the volumes.
 type Provider interface {
-	GetFS(volumeSpec *api.VolumeSpec) (fs.FS, error)
+	GetFS(volume *api.Volume) (fs.FS, error)
 	GetMountOptions(volumeSpec *api.VolumeSpec) ([]fuse.MountOption, error)
 }
 
+// Snapshotter is implemented by a Provider that can copy the data of a
+// volume to a snapshot. Snapshots are not supported by other Providers.
+type Snapshotter interface {
+	Snapshot(volume *api.Volume, snap *api.Volume) error
+}
+
+// Deleter is implemented by a Provider that keeps data of a volume outside
+// of its DevicePath, which it releases when the volume is deleted.
+type Deleter interface {
+	Delete(volume *api.Volume) error
+}
+
 // NewVolumeDriver creates a new volume.VolumeDriver for fuse.
 func NewVolumeDriver(name string, baseDirPath string, provider Provider) volume.VolumeDriver {
 	return newVolumeDriver(name, baseDirPath, provider)
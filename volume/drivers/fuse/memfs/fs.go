@@ -0,0 +1,397 @@
+package memfs
+
+import (
+	"os"
+	"sync"
+	"syscall"
+	"time"
+
+	"bazil.org/fuse"
+	"bazil.org/fuse/fs"
+	"bazil.org/fuse/fuseutil"
+	"golang.org/x/net/context"
+)
+
+const (
+	blockSize = 4096
+	// unlimited is the size reported for volumes without a size.
+	unlimited = 1 << 40
+)
+
+// memFS is a tree of directories and files in memory. A single lock
+// serializes all operations on the tree.
+type memFS struct {
+	sync.Mutex
+	root  *dir
+	size  uint64
+	used  uint64
+	inode uint64
+}
+
+// newFS returns an empty filesystem that holds up to size bytes of file
+// data, or any amount if size is 0.
+func newFS(size uint64) *memFS {
+	m := &memFS{size: size}
+	m.root = &dir{
+		node:     m.newNode(os.ModeDir | 0777),
+		children: make(map[string]fs.Node),
+	}
+	return m
+}
+
+// resize sets the size of the filesystem. Files that no longer fit are kept,
+// but cannot grow.
+func (m *memFS) resize(size uint64) {
+	m.Lock()
+	defer m.Unlock()
+	m.size = size
+}
+
+// clone returns a copy of the filesystem.
+func (m *memFS) clone() *memFS {
+	m.Lock()
+	defer m.Unlock()
+	c := &memFS{size: m.size, used: m.used}
+	c.root = c.cloneDir(m.root)
+	return c
+}
+
+// cloneNode returns a copy of the attributes of n in m.
+func (m *memFS) cloneNode(n node) node {
+	m.inode++
+	n.fs = m
+	n.inode = m.inode
+	return n
+}
+
+// cloneDir returns a copy of the tree under d in m.
+func (m *memFS) cloneDir(d *dir) *dir {
+	n := &dir{
+		node:     m.cloneNode(d.node),
+		children: make(map[string]fs.Node, len(d.children)),
+	}
+	for name, child := range d.children {
+		switch child := child.(type) {
+		case *dir:
+			n.children[name] = m.cloneDir(child)
+		case *file:
+			n.children[name] = &file{
+				node: m.cloneNode(child.node),
+				data: append([]byte(nil), child.data...),
+			}
+		}
+	}
+	return n
+}
+
+func (m *memFS) Root() (fs.Node, error) {
+	return m.root, nil
+}
+
+func (m *memFS) Statfs(
+	ctx context.Context,
+	req *fuse.StatfsRequest,
+	resp *fuse.StatfsResponse,
+) error {
+	m.Lock()
+	defer m.Unlock()
+	size := m.size
+	if size == 0 {
+		size = unlimited
+	}
+	resp.Bsize = blockSize
+	resp.Frsize = blockSize
+	resp.Blocks = size / blockSize
+	resp.Bfree = (size - m.used) / blockSize
+	resp.Bavail = resp.Bfree
+	resp.Namelen = 255
+	return nil
+}
+
+// grow reserves n bytes for file data. Callers hold the lock.
+func (m *memFS) grow(n uint64) error {
+	if m.size != 0 && m.used+n > m.size {
+		return fuse.Errno(syscall.ENOSPC)
+	}
+	m.used += n
+	return nil
+}
+
+// newNode returns the attributes of a new file or directory. Callers hold
+// the lock, or own the filesystem exclusively.
+func (m *memFS) newNode(mode os.FileMode) node {
+	m.inode++
+	now := time.Now()
+	return node{
+		fs:    m,
+		inode: m.inode,
+		mode:  mode,
+		mtime: now,
+		ctime: now,
+	}
+}
+
+// node holds the attributes shared by directories and files.
+type node struct {
+	fs    *memFS
+	inode uint64
+	mode  os.FileMode
+	uid   uint32
+	gid   uint32
+	atime time.Time
+	mtime time.Time
+	ctime time.Time
+}
+
+func (n *node) attr(a *fuse.Attr, size uint64) {
+	a.Inode = n.inode
+	a.Mode = n.mode
+	a.Size = size
+	a.Blocks = (size + 511) / 512
+	a.Uid = n.uid
+	a.Gid = n.gid
+	a.Atime = n.atime
+	a.Mtime = n.mtime
+	a.Ctime = n.ctime
+	a.BlockSize = blockSize
+	a.Nlink = 1
+}
+
+// setattr applies the mode, owner and time changes of req.
+func (n *node) setattr(req *fuse.SetattrRequest) {
+	if req.Valid.Mode() {
+		n.mode = n.mode&os.ModeType | req.Mode&^os.ModeType
+	}
+	if req.Valid.Uid() {
+		n.uid = req.Uid
+	}
+	if req.Valid.Gid() {
+		n.gid = req.Gid
+	}
+	if req.Valid.Atime() {
+		n.atime = req.Atime
+	}
+	if req.Valid.Mtime() {
+		n.mtime = req.Mtime
+	}
+	n.ctime = time.Now()
+}
+
+type dir struct {
+	node
+	children map[string]fs.Node
+}
+
+func (d *dir) Attr(ctx context.Context, a *fuse.Attr) error {
+	d.fs.Lock()
+	defer d.fs.Unlock()
+	d.attr(a, blockSize)
+	a.Nlink = 2
+	return nil
+}
+
+func (d *dir) Setattr(
+	ctx context.Context,
+	req *fuse.SetattrRequest,
+	resp *fuse.SetattrResponse,
+) error {
+	d.fs.Lock()
+	defer d.fs.Unlock()
+	d.setattr(req)
+	d.attr(&resp.Attr, blockSize)
+	return nil
+}
+
+func (d *dir) Lookup(ctx context.Context, name string) (fs.Node, error) {
+	d.fs.Lock()
+	defer d.fs.Unlock()
+	if n, ok := d.children[name]; ok {
+		return n, nil
+	}
+	return nil, fuse.ENOENT
+}
+
+func (d *dir) ReadDirAll(ctx context.Context) ([]fuse.Dirent, error) {
+	d.fs.Lock()
+	defer d.fs.Unlock()
+	dirents := make([]fuse.Dirent, 0, len(d.children))
+	for name, n := range d.children {
+		switch n := n.(type) {
+		case *dir:
+			dirents = append(dirents, fuse.Dirent{
+				Inode: n.inode,
+				Type:  fuse.DT_Dir,
+				Name:  name,
+			})
+		case *file:
+			dirents = append(dirents, fuse.Dirent{
+				Inode: n.inode,
+				Type:  fuse.DT_File,
+				Name:  name,
+			})
+		}
+	}
+	return dirents, nil
+}
+
+func (d *dir) Mkdir(ctx context.Context, req *fuse.MkdirRequest) (fs.Node, error) {
+	d.fs.Lock()
+	defer d.fs.Unlock()
+	if _, ok := d.children[req.Name]; ok {
+		return nil, fuse.EEXIST
+	}
+	n := &dir{
+		node:     d.fs.newNode(os.ModeDir | req.Mode&^req.Umask&os.ModePerm),
+		children: make(map[string]fs.Node),
+	}
+	n.uid, n.gid = req.Uid, req.Gid
+	d.children[req.Name] = n
+	d.mtime = n.mtime
+	return n, nil
+}
+
+func (d *dir) Create(
+	ctx context.Context,
+	req *fuse.CreateRequest,
+	resp *fuse.CreateResponse,
+) (fs.Node, fs.Handle, error) {
+	d.fs.Lock()
+	defer d.fs.Unlock()
+	if n, ok := d.children[req.Name]; ok {
+		if req.Flags&fuse.OpenExclusive != 0 {
+			return nil, nil, fuse.EEXIST
+		}
+		if f, ok := n.(*file); ok {
+			if req.Flags&fuse.OpenTruncate != 0 {
+				f.truncate(0)
+			}
+			return f, f, nil
+		}
+		return nil, nil, fuse.Errno(syscall.EISDIR)
+	}
+	f := &file{node: d.fs.newNode(req.Mode &^ req.Umask & os.ModePerm)}
+	f.uid, f.gid = req.Uid, req.Gid
+	d.children[req.Name] = f
+	d.mtime = f.mtime
+	return f, f, nil
+}
+
+func (d *dir) Remove(ctx context.Context, req *fuse.RemoveRequest) error {
+	d.fs.Lock()
+	defer d.fs.Unlock()
+	n, ok := d.children[req.Name]
+	if !ok {
+		return fuse.ENOENT
+	}
+	switch n := n.(type) {
+	case *dir:
+		if len(n.children) != 0 {
+			return fuse.Errno(syscall.ENOTEMPTY)
+		}
+	case *file:
+		n.truncate(0)
+	}
+	delete(d.children, req.Name)
+	d.mtime = time.Now()
+	return nil
+}
+
+func (d *dir) Rename(ctx context.Context, req *fuse.RenameRequest, newDir fs.Node) error {
+	d.fs.Lock()
+	defer d.fs.Unlock()
+	to, ok := newDir.(*dir)
+	if !ok {
+		return fuse.EIO
+	}
+	n, ok := d.children[req.OldName]
+	if !ok {
+		return fuse.ENOENT
+	}
+	if old, ok := to.children[req.NewName].(*file); ok {
+		old.truncate(0)
+	}
+	delete(d.children, req.OldName)
+	to.children[req.NewName] = n
+	d.mtime = time.Now()
+	to.mtime = d.mtime
+	return nil
+}
+
+// file is a regular file and is also its own handle.
+type file struct {
+	node
+	data []byte
+}
+
+func (f *file) Attr(ctx context.Context, a *fuse.Attr) error {
+	f.fs.Lock()
+	defer f.fs.Unlock()
+	f.attr(a, uint64(len(f.data)))
+	return nil
+}
+
+func (f *file) Setattr(
+	ctx context.Context,
+	req *fuse.SetattrRequest,
+	resp *fuse.SetattrResponse,
+) error {
+	f.fs.Lock()
+	defer f.fs.Unlock()
+	if req.Valid.Size() {
+		if err := f.truncate(req.Size); err != nil {
+			return err
+		}
+	}
+	f.setattr(req)
+	f.attr(&resp.Attr, uint64(len(f.data)))
+	return nil
+}
+
+// truncate resizes the file to size bytes. Callers hold the lock.
+func (f *file) truncate(size uint64) error {
+	length := uint64(len(f.data))
+	if size > length {
+		if err := f.fs.grow(size - length); err != nil {
+			return err
+		}
+		f.data = append(f.data, make([]byte, size-length)...)
+	} else {
+		f.fs.used -= length - size
+		f.data = f.data[:size]
+	}
+	f.mtime = time.Now()
+	return nil
+}
+
+func (f *file) Read(
+	ctx context.Context,
+	req *fuse.ReadRequest,
+	resp *fuse.ReadResponse,
+) error {
+	f.fs.Lock()
+	defer f.fs.Unlock()
+	fuseutil.HandleRead(req, resp, f.data)
+	return nil
+}
+
+func (f *file) Write(
+	ctx context.Context,
+	req *fuse.WriteRequest,
+	resp *fuse.WriteResponse,
+) error {
+	f.fs.Lock()
+	defer f.fs.Unlock()
+	end := uint64(req.Offset) + uint64(len(req.Data))
+	if end > uint64(len(f.data)) {
+		if err := f.truncate(end); err != nil {
+			return err
+		}
+	}
+	resp.Size = copy(f.data[req.Offset:], req.Data)
+	f.mtime = time.Now()
+	return nil
+}
+
+func (f *file) Fsync(ctx context.Context, req *fuse.FsyncRequest) error {
+	return nil
+}
@@ -0,0 +1,87 @@
+// Package memfs provides scratch volumes that are kept in memory and
+// served with FUSE. The files of a volume are kept until the volume is
+// deleted or the driver stops.
+package memfs
+
+import (
+	"os"
+	"sync"
+
+	"bazil.org/fuse"
+	"bazil.org/fuse/fs"
+
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/volume"
+	volumefuse "github.com/libopenstorage/openstorage/volume/drivers/fuse"
+)
+
+const (
+	Name = "memfs"
+	Type = api.DriverType_DRIVER_TYPE_FILE
+	// DefaultPath is where volumes are registered if no path is provided.
+	DefaultPath = "/var/lib/openstorage/memfs"
+)
+
+// Init creates the memfs driver. The path param sets the directory that
+// volumes are registered in.
+func Init(params map[string]string) (volume.VolumeDriver, error) {
+	path, ok := params["path"]
+	if !ok || path == "" {
+		path = DefaultPath
+	}
+	if err := os.MkdirAll(path, 0744); err != nil {
+		return nil, err
+	}
+	return volumefuse.NewVolumeDriver(Name, path, newProvider()), nil
+}
+
+// provider keeps an in-memory filesystem for each volume, which all the
+// mounts of the volume serve.
+type provider struct {
+	lock        sync.Mutex
+	filesystems map[string]*memFS
+}
+
+func newProvider() *provider {
+	return &provider{filesystems: make(map[string]*memFS)}
+}
+
+func (p *provider) GetFS(v *api.Volume) (fs.FS, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	m, ok := p.filesystems[v.Id]
+	if !ok {
+		m = newFS(v.Spec.Size)
+		p.filesystems[v.Id] = m
+		return m, nil
+	}
+	m.resize(v.Spec.Size)
+	return m, nil
+}
+
+// Snapshot copies the filesystem of a volume to the snapshot.
+func (p *provider) Snapshot(v *api.Volume, snap *api.Volume) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if m, ok := p.filesystems[v.Id]; ok {
+		p.filesystems[snap.Id] = m.clone()
+	} else {
+		p.filesystems[snap.Id] = newFS(snap.Spec.Size)
+	}
+	return nil
+}
+
+// Delete discards the filesystem of a volume.
+func (p *provider) Delete(v *api.Volume) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	delete(p.filesystems, v.Id)
+	return nil
+}
+
+func (p *provider) GetMountOptions(spec *api.VolumeSpec) ([]fuse.MountOption, error) {
+	return []fuse.MountOption{
+		fuse.FSName(Name),
+		fuse.Subtype(Name),
+	}, nil
+}
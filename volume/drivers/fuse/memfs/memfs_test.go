@@ -0,0 +1,103 @@
+package memfs
+
+import (
+	"os/exec"
+	"syscall"
+	"testing"
+
+	"bazil.org/fuse"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/volume/drivers/test"
+)
+
+var (
+	testPath = string("/tmp/openstorage_driver_test")
+)
+
+func TestAll(t *testing.T) {
+	if _, err := exec.LookPath("fusermount"); err != nil {
+		t.Skip("fusermount is not installed")
+	}
+	d, err := Init(map[string]string{"path": testPath})
+	require.NoError(t, err, "Failed to initialize Volume Driver")
+	ctx := test.NewContext(d)
+	ctx.Filesystem = api.FSType_FS_TYPE_FUSE
+
+	test.Run(t, ctx)
+}
+
+func TestProvider(t *testing.T) {
+	ctx := context.Background()
+	p := newProvider()
+	v := &api.Volume{Id: "vol", Spec: &api.VolumeSpec{Size: 8192}}
+	m, err := p.GetFS(v)
+	require.NoError(t, err)
+	root, err := m.Root()
+	require.NoError(t, err)
+	n, _, err := root.(*dir).Create(
+		ctx,
+		&fuse.CreateRequest{Name: "f", Mode: 0644},
+		&fuse.CreateResponse{},
+	)
+	require.NoError(t, err)
+	require.NoError(t, n.(*file).Write(
+		ctx,
+		&fuse.WriteRequest{Data: []byte("data")},
+		&fuse.WriteResponse{},
+	))
+
+	// All the mounts of a volume serve its filesystem.
+	again, err := p.GetFS(v)
+	require.NoError(t, err)
+	require.True(t, m == again)
+
+	snap := &api.Volume{Id: "snap", Spec: v.Spec}
+	require.NoError(t, p.Snapshot(v, snap))
+	require.NoError(t, n.(*file).Write(
+		ctx,
+		&fuse.WriteRequest{Data: []byte("more")},
+		&fuse.WriteResponse{},
+	))
+	s, err := p.GetFS(snap)
+	require.NoError(t, err)
+	root, err = s.Root()
+	require.NoError(t, err)
+	c, err := root.(*dir).Lookup(ctx, "f")
+	require.NoError(t, err)
+	require.Equal(t, "data", string(c.(*file).data))
+
+	require.NoError(t, p.Delete(v))
+	again, err = p.GetFS(v)
+	require.NoError(t, err)
+	require.False(t, m == again, "Deleted volumes start empty")
+}
+
+func TestSize(t *testing.T) {
+	ctx := context.Background()
+	m := newFS(8192)
+	root, err := m.Root()
+	require.NoError(t, err)
+
+	n, _, err := root.(*dir).Create(
+		ctx,
+		&fuse.CreateRequest{Name: "f", Mode: 0644},
+		&fuse.CreateResponse{},
+	)
+	require.NoError(t, err)
+	f := n.(*file)
+	resp := &fuse.WriteResponse{}
+	require.NoError(t, f.Write(ctx, &fuse.WriteRequest{Data: make([]byte, 4096)}, resp))
+	require.Equal(t, 4096, resp.Size)
+	err = f.Write(ctx, &fuse.WriteRequest{Offset: 4096, Data: make([]byte, 8192)}, resp)
+	require.Equal(t, fuse.Errno(syscall.ENOSPC), err)
+
+	statfs := &fuse.StatfsResponse{}
+	require.NoError(t, m.Statfs(ctx, &fuse.StatfsRequest{}, statfs))
+	require.Equal(t, uint64(1), statfs.Bfree)
+
+	require.NoError(t, root.(*dir).Remove(ctx, &fuse.RemoveRequest{Name: "f"}))
+	require.Equal(t, uint64(0), m.used)
+}
@@ -0,0 +1,438 @@
+package passthrough
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"bazil.org/fuse"
+	"bazil.org/fuse/fs"
+	"golang.org/x/net/context"
+)
+
+// passFS serves the directory root. Writes that would make the files under
+// root larger than size fail with ENOSPC.
+type passFS struct {
+	root  string
+	stats *stats
+	// lock serializes the changes to the size of files so that the quota
+	// is checked against an exact count of the bytes used.
+	lock sync.Mutex
+	size uint64
+	used uint64
+}
+
+func (p *passFS) Root() (fs.Node, error) {
+	return &dir{node{fs: p, path: p.root}}, nil
+}
+
+func (p *passFS) Statfs(
+	ctx context.Context,
+	req *fuse.StatfsRequest,
+	resp *fuse.StatfsResponse,
+) error {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(p.root, &st); err != nil {
+		return errno(err)
+	}
+	resp.Blocks = st.Blocks
+	resp.Bfree = st.Bfree
+	resp.Bavail = st.Bavail
+	resp.Files = st.Files
+	resp.Ffree = st.Ffree
+	resp.Bsize = uint32(st.Bsize)
+	resp.Frsize = uint32(st.Frsize)
+	resp.Namelen = uint32(st.Namelen)
+	p.lock.Lock()
+	size, used := p.size, p.used
+	p.lock.Unlock()
+	if size != 0 {
+		free := uint64(0)
+		if used < size {
+			free = (size - used) / uint64(st.Bsize)
+		}
+		resp.Blocks = size / uint64(st.Bsize)
+		if free < resp.Bfree {
+			resp.Bfree = free
+		}
+		if free < resp.Bavail {
+			resp.Bavail = free
+		}
+	}
+	return nil
+}
+
+// setSize changes the size of the volume. Files are not truncated if it
+// shrinks, only the writes that grow them fail.
+func (p *passFS) setSize(size uint64) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.size = size
+}
+
+// resize accounts for a file that changes from oldSize to newSize bytes,
+// and fails if the volume would grow beyond its size. Callers hold the
+// lock.
+func (p *passFS) resize(oldSize int64, newSize int64) error {
+	if newSize <= oldSize {
+		p.release(uint64(oldSize - newSize))
+		return nil
+	}
+	n := uint64(newSize - oldSize)
+	if p.size != 0 && p.used+n > p.size {
+		return fuse.Errno(syscall.ENOSPC)
+	}
+	p.used += n
+	return nil
+}
+
+// release accounts for n bytes that were freed. Callers hold the lock.
+func (p *passFS) release(n uint64) {
+	if n > p.used {
+		n = p.used
+	}
+	p.used -= n
+}
+
+// node is a file or directory at path under the root.
+type node struct {
+	fs   *passFS
+	path string
+}
+
+func (n *node) Attr(ctx context.Context, a *fuse.Attr) error {
+	fi, err := os.Lstat(n.path)
+	if err != nil {
+		return errno(err)
+	}
+	st := fi.Sys().(*syscall.Stat_t)
+	a.Inode = st.Ino
+	a.Size = uint64(st.Size)
+	a.Blocks = uint64(st.Blocks)
+	a.Atime = time.Unix(st.Atim.Sec, st.Atim.Nsec)
+	a.Mtime = time.Unix(st.Mtim.Sec, st.Mtim.Nsec)
+	a.Ctime = time.Unix(st.Ctim.Sec, st.Ctim.Nsec)
+	a.Mode = fi.Mode()
+	a.Nlink = uint32(st.Nlink)
+	a.Uid = st.Uid
+	a.Gid = st.Gid
+	a.Rdev = uint32(st.Rdev)
+	a.BlockSize = uint32(st.Blksize)
+	return nil
+}
+
+func (n *node) Setattr(
+	ctx context.Context,
+	req *fuse.SetattrRequest,
+	resp *fuse.SetattrResponse,
+) error {
+	if req.Valid.Size() {
+		if err := n.truncate(int64(req.Size)); err != nil {
+			return err
+		}
+	}
+	if req.Valid.Mode() {
+		if err := os.Chmod(n.path, req.Mode); err != nil {
+			return errno(err)
+		}
+	}
+	if req.Valid.Uid() || req.Valid.Gid() {
+		uid, gid := -1, -1
+		if req.Valid.Uid() {
+			uid = int(req.Uid)
+		}
+		if req.Valid.Gid() {
+			gid = int(req.Gid)
+		}
+		if err := os.Lchown(n.path, uid, gid); err != nil {
+			return errno(err)
+		}
+	}
+	if req.Valid.Atime() || req.Valid.Mtime() {
+		var a fuse.Attr
+		if err := n.Attr(ctx, &a); err != nil {
+			return err
+		}
+		if req.Valid.Atime() {
+			a.Atime = req.Atime
+		}
+		if req.Valid.Mtime() {
+			a.Mtime = req.Mtime
+		}
+		if err := os.Chtimes(n.path, a.Atime, a.Mtime); err != nil {
+			return errno(err)
+		}
+	}
+	return n.Attr(ctx, &resp.Attr)
+}
+
+// truncate resizes the file at the path of the node to size bytes.
+func (n *node) truncate(size int64) error {
+	n.fs.lock.Lock()
+	defer n.fs.lock.Unlock()
+	fi, err := os.Lstat(n.path)
+	if err != nil {
+		return errno(err)
+	}
+	if err := n.fs.resize(fi.Size(), size); err != nil {
+		return err
+	}
+	if err := os.Truncate(n.path, size); err != nil {
+		n.fs.resize(size, fi.Size())
+		return errno(err)
+	}
+	return nil
+}
+
+type dir struct {
+	node
+}
+
+func (d *dir) child(name string) node {
+	return node{fs: d.fs, path: filepath.Join(d.path, name)}
+}
+
+func (d *dir) Lookup(ctx context.Context, name string) (fs.Node, error) {
+	n := d.child(name)
+	fi, err := os.Lstat(n.path)
+	if err != nil {
+		return nil, errno(err)
+	}
+	if fi.IsDir() {
+		return &dir{n}, nil
+	}
+	return &file{n}, nil
+}
+
+func (d *dir) ReadDirAll(ctx context.Context) ([]fuse.Dirent, error) {
+	f, err := os.Open(d.path)
+	if err != nil {
+		return nil, errno(err)
+	}
+	defer f.Close()
+	fis, err := f.Readdir(-1)
+	if err != nil {
+		return nil, errno(err)
+	}
+	dirents := make([]fuse.Dirent, 0, len(fis))
+	for _, fi := range fis {
+		dirent := fuse.Dirent{
+			Inode: fi.Sys().(*syscall.Stat_t).Ino,
+			Name:  fi.Name(),
+		}
+		switch {
+		case fi.IsDir():
+			dirent.Type = fuse.DT_Dir
+		case fi.Mode()&os.ModeSymlink != 0:
+			dirent.Type = fuse.DT_Link
+		case fi.Mode().IsRegular():
+			dirent.Type = fuse.DT_File
+		}
+		dirents = append(dirents, dirent)
+	}
+	return dirents, nil
+}
+
+func (d *dir) Mkdir(ctx context.Context, req *fuse.MkdirRequest) (fs.Node, error) {
+	n := d.child(req.Name)
+	if err := os.Mkdir(n.path, req.Mode&^req.Umask); err != nil {
+		return nil, errno(err)
+	}
+	return &dir{n}, nil
+}
+
+func (d *dir) Create(
+	ctx context.Context,
+	req *fuse.CreateRequest,
+	resp *fuse.CreateResponse,
+) (fs.Node, fs.Handle, error) {
+	n := d.child(req.Name)
+	d.fs.lock.Lock()
+	defer d.fs.lock.Unlock()
+	var oldSize int64
+	if fi, err := os.Lstat(n.path); err == nil && req.Flags&fuse.OpenTruncate != 0 {
+		oldSize = fi.Size()
+	}
+	f, err := os.OpenFile(
+		n.path,
+		int(req.Flags)&^os.O_APPEND|os.O_CREATE,
+		req.Mode&^req.Umask,
+	)
+	if err != nil {
+		return nil, nil, errno(err)
+	}
+	d.fs.release(uint64(oldSize))
+	return &file{n}, &handle{fs: d.fs, f: f}, nil
+}
+
+func (d *dir) Remove(ctx context.Context, req *fuse.RemoveRequest) error {
+	n := d.child(req.Name)
+	if req.Dir {
+		return errno(syscall.Rmdir(n.path))
+	}
+	d.fs.lock.Lock()
+	defer d.fs.lock.Unlock()
+	fi, err := os.Lstat(n.path)
+	if err != nil {
+		return errno(err)
+	}
+	if err := syscall.Unlink(n.path); err != nil {
+		return errno(err)
+	}
+	if fi.Mode().IsRegular() && fi.Sys().(*syscall.Stat_t).Nlink == 1 {
+		d.fs.release(uint64(fi.Size()))
+	}
+	return nil
+}
+
+func (d *dir) Rename(ctx context.Context, req *fuse.RenameRequest, newDir fs.Node) error {
+	to, ok := newDir.(*dir)
+	if !ok {
+		return fuse.EIO
+	}
+	newPath := filepath.Join(to.path, req.NewName)
+	d.fs.lock.Lock()
+	defer d.fs.lock.Unlock()
+	fi, err := os.Lstat(newPath)
+	if err := os.Rename(filepath.Join(d.path, req.OldName), newPath); err != nil {
+		return errno(err)
+	}
+	if err == nil && fi.Mode().IsRegular() && fi.Sys().(*syscall.Stat_t).Nlink == 1 {
+		d.fs.release(uint64(fi.Size()))
+	}
+	return nil
+}
+
+func (d *dir) Symlink(ctx context.Context, req *fuse.SymlinkRequest) (fs.Node, error) {
+	n := d.child(req.NewName)
+	if err := os.Symlink(req.Target, n.path); err != nil {
+		return nil, errno(err)
+	}
+	return &file{n}, nil
+}
+
+// file is a regular file or a symlink.
+type file struct {
+	node
+}
+
+func (f *file) Open(
+	ctx context.Context,
+	req *fuse.OpenRequest,
+	resp *fuse.OpenResponse,
+) (fs.Handle, error) {
+	// The kernel sets the offset of appending writes.
+	fd, err := os.OpenFile(f.path, int(req.Flags)&^os.O_APPEND, 0)
+	if err != nil {
+		return nil, errno(err)
+	}
+	return &handle{fs: f.fs, f: fd}, nil
+}
+
+func (f *file) Readlink(ctx context.Context, req *fuse.ReadlinkRequest) (string, error) {
+	target, err := os.Readlink(f.path)
+	return target, errno(err)
+}
+
+func (f *file) Fsync(ctx context.Context, req *fuse.FsyncRequest) error {
+	fd, err := os.Open(f.path)
+	if err != nil {
+		return errno(err)
+	}
+	defer fd.Close()
+	return errno(fd.Sync())
+}
+
+// handle is an open file.
+type handle struct {
+	fs *passFS
+	f  *os.File
+}
+
+func (h *handle) Read(
+	ctx context.Context,
+	req *fuse.ReadRequest,
+	resp *fuse.ReadResponse,
+) error {
+	s := h.fs.stats
+	atomic.AddUint64(&s.ioProgress, 1)
+	defer atomic.AddUint64(&s.ioProgress, ^uint64(0))
+	start := time.Now()
+	buf := make([]byte, req.Size)
+	n, err := h.f.ReadAt(buf, req.Offset)
+	if err != nil && err != io.EOF {
+		return errno(err)
+	}
+	resp.Data = buf[:n]
+	atomic.AddUint64(&s.reads, 1)
+	atomic.AddUint64(&s.readBytes, uint64(n))
+	atomic.AddUint64(&s.readMs, uint64(time.Since(start)/time.Millisecond))
+	return nil
+}
+
+func (h *handle) Write(
+	ctx context.Context,
+	req *fuse.WriteRequest,
+	resp *fuse.WriteResponse,
+) error {
+	s := h.fs.stats
+	atomic.AddUint64(&s.ioProgress, 1)
+	defer atomic.AddUint64(&s.ioProgress, ^uint64(0))
+	start := time.Now()
+	h.fs.lock.Lock()
+	defer h.fs.lock.Unlock()
+	fi, err := h.f.Stat()
+	if err != nil {
+		return errno(err)
+	}
+	oldSize := fi.Size()
+	newSize := req.Offset + int64(len(req.Data))
+	if newSize < oldSize {
+		newSize = oldSize
+	}
+	if err := h.fs.resize(oldSize, newSize); err != nil {
+		return err
+	}
+	n, err := h.f.WriteAt(req.Data, req.Offset)
+	if err != nil {
+		// Account for the size that the file has after a short write.
+		if fi, err := h.f.Stat(); err == nil {
+			h.fs.resize(newSize, fi.Size())
+		}
+		return errno(err)
+	}
+	resp.Size = n
+	atomic.AddUint64(&s.writes, 1)
+	atomic.AddUint64(&s.writeBytes, uint64(n))
+	atomic.AddUint64(&s.writeMs, uint64(time.Since(start)/time.Millisecond))
+	return nil
+}
+
+func (h *handle) Flush(ctx context.Context, req *fuse.FlushRequest) error {
+	return nil
+}
+
+func (h *handle) Release(ctx context.Context, req *fuse.ReleaseRequest) error {
+	return errno(h.f.Close())
+}
+
+// errno returns the errno of err so that the kernel gets the error of the
+// host filesystem instead of EIO.
+func errno(err error) error {
+	switch e := err.(type) {
+	case *os.PathError:
+		err = e.Err
+	case *os.LinkError:
+		err = e.Err
+	case *os.SyscallError:
+		err = e.Err
+	}
+	if e, ok := err.(syscall.Errno); ok {
+		return fuse.Errno(e)
+	}
+	return err
+}
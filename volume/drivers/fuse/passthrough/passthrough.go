@@ -0,0 +1,177 @@
+// Package passthrough provides volumes that are directories on the host
+// served with FUSE. The size of a volume is enforced on the data written
+// through its mounts and the I/O of each volume is recorded in its stats.
+package passthrough
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+
+	"bazil.org/fuse"
+	"bazil.org/fuse/fs"
+
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/volume"
+	volumefuse "github.com/libopenstorage/openstorage/volume/drivers/fuse"
+)
+
+const (
+	Name = "passthrough"
+	Type = api.DriverType_DRIVER_TYPE_FILE
+	// DefaultPath is where volume directories are created if no path is
+	// provided.
+	DefaultPath = "/var/lib/openstorage/passthrough"
+)
+
+// Init creates the passthrough driver. The path param sets the host
+// directory that the volume directories are created in.
+func Init(params map[string]string) (volume.VolumeDriver, error) {
+	path, ok := params["path"]
+	if !ok || path == "" {
+		path = DefaultPath
+	}
+	if err := os.MkdirAll(path, 0744); err != nil {
+		return nil, err
+	}
+	return volumefuse.NewVolumeDriver(Name, path, newProvider(path)), nil
+}
+
+// provider serves the volume directories under home and keeps the stats
+// of the volumes since the driver started. All the mounts of a volume serve
+// the same filesystem, which counts the bytes they use against its size.
+type provider struct {
+	volume.StatsDriver
+	home        string
+	lock        sync.Mutex
+	stats       map[string]*stats
+	filesystems map[string]*passFS
+}
+
+func newProvider(home string) *provider {
+	return &provider{
+		StatsDriver: volume.StatsNotSupported,
+		home:        home,
+		stats:       make(map[string]*stats),
+		filesystems: make(map[string]*passFS),
+	}
+}
+
+func (p *provider) GetFS(v *api.Volume) (fs.FS, error) {
+	p.lock.Lock()
+	f, ok := p.filesystems[v.Id]
+	p.lock.Unlock()
+	if ok {
+		f.setSize(v.Spec.Size)
+		return f, nil
+	}
+	used, err := du(v.DevicePath)
+	if err != nil {
+		return nil, err
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	// Another mount may have added the filesystem while du ran.
+	if f, ok := p.filesystems[v.Id]; ok {
+		f.setSize(v.Spec.Size)
+		return f, nil
+	}
+	f = &passFS{
+		root:  v.DevicePath,
+		size:  v.Spec.Size,
+		used:  used,
+		stats: p.statsLocked(v.Id),
+	}
+	p.filesystems[v.Id] = f
+	return f, nil
+}
+
+// Delete forgets the filesystem and the stats of a volume.
+func (p *provider) Delete(v *api.Volume) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	delete(p.filesystems, v.Id)
+	delete(p.stats, v.Id)
+	return nil
+}
+
+func (p *provider) GetMountOptions(spec *api.VolumeSpec) ([]fuse.MountOption, error) {
+	return []fuse.MountOption{
+		fuse.FSName(Name),
+		fuse.Subtype(Name),
+	}, nil
+}
+
+// volumeStats returns the stats of a volume.
+func (p *provider) volumeStats(volumeID string) *stats {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.statsLocked(volumeID)
+}
+
+// statsLocked returns the stats of a volume. Callers hold the lock.
+func (p *provider) statsLocked(volumeID string) *stats {
+	s, ok := p.stats[volumeID]
+	if !ok {
+		s = &stats{}
+		p.stats[volumeID] = s
+	}
+	return s
+}
+
+// Stats returns the I/O through the mounts of a volume since the driver
+// started. Stats are always cumulative.
+func (p *provider) Stats(volumeID string, cumulative bool) (*api.Stats, error) {
+	used, err := p.UsedSize(volumeID)
+	if err != nil {
+		return nil, err
+	}
+	s := p.volumeStats(volumeID)
+	return &api.Stats{
+		Reads:      atomic.LoadUint64(&s.reads),
+		ReadMs:     atomic.LoadUint64(&s.readMs),
+		ReadBytes:  atomic.LoadUint64(&s.readBytes),
+		Writes:     atomic.LoadUint64(&s.writes),
+		WriteMs:    atomic.LoadUint64(&s.writeMs),
+		WriteBytes: atomic.LoadUint64(&s.writeBytes),
+		IoProgress: atomic.LoadUint64(&s.ioProgress),
+		IoMs:       atomic.LoadUint64(&s.readMs) + atomic.LoadUint64(&s.writeMs),
+		BytesUsed:  used,
+	}, nil
+}
+
+// UsedSize returns the size of the files in the volume directory.
+func (p *provider) UsedSize(volumeID string) (uint64, error) {
+	dir := filepath.Join(p.home, volumeID)
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return 0, volume.ErrEnoEnt
+	}
+	return du(dir)
+}
+
+// stats counts the I/O of a volume.
+type stats struct {
+	reads      uint64
+	readMs     uint64
+	readBytes  uint64
+	writes     uint64
+	writeMs    uint64
+	writeBytes uint64
+	ioProgress uint64
+}
+
+// du returns the size of the regular files under dir.
+func du(dir string) (uint64, error) {
+	var used uint64
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			used += uint64(info.Size())
+		}
+		return nil
+	})
+	return used, err
+}
@@ -0,0 +1,135 @@
+package passthrough
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"bazil.org/fuse"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/volume/drivers/test"
+)
+
+var (
+	testPath = string("/tmp/openstorage_driver_test")
+)
+
+func TestAll(t *testing.T) {
+	if _, err := exec.LookPath("fusermount"); err != nil {
+		t.Skip("fusermount is not installed")
+	}
+	d, err := Init(map[string]string{"path": testPath})
+	require.NoError(t, err, "Failed to initialize Volume Driver")
+	ctx := test.NewContext(d)
+	ctx.Filesystem = api.FSType_FS_TYPE_FUSE
+
+	test.RunShort(t, ctx)
+}
+
+func TestQuota(t *testing.T) {
+	home, err := ioutil.TempDir("", "passthrough")
+	require.NoError(t, err)
+	defer os.RemoveAll(home)
+	volPath := filepath.Join(home, "vol")
+	require.NoError(t, os.Mkdir(volPath, 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(volPath, "old"), make([]byte, 1024), 0644))
+
+	ctx := context.Background()
+	p := newProvider(home)
+	filesystem, err := p.GetFS(&api.Volume{
+		Id:         "vol",
+		DevicePath: volPath,
+		Spec:       &api.VolumeSpec{Size: 4096},
+	})
+	require.NoError(t, err)
+	root, err := filesystem.Root()
+	require.NoError(t, err)
+
+	_, h, err := root.(*dir).Create(
+		ctx,
+		&fuse.CreateRequest{Name: "new", Flags: fuse.OpenReadWrite, Mode: 0644},
+		&fuse.CreateResponse{},
+	)
+	require.NoError(t, err)
+	w := &fuse.WriteResponse{}
+	require.NoError(t, h.(*handle).Write(ctx, &fuse.WriteRequest{Data: make([]byte, 2048)}, w))
+	require.Equal(t, 2048, w.Size)
+	err = h.(*handle).Write(ctx, &fuse.WriteRequest{Offset: 2048, Data: make([]byte, 2048)}, w)
+	require.Equal(t, fuse.Errno(syscall.ENOSPC), err, "Write beyond the volume size should fail")
+
+	r := &fuse.ReadResponse{Data: make([]byte, 0, 4096)}
+	require.NoError(t, h.(*handle).Read(ctx, &fuse.ReadRequest{Size: 4096}, r))
+	require.Len(t, r.Data, 2048)
+	require.NoError(t, h.(*handle).Release(ctx, &fuse.ReleaseRequest{}))
+
+	require.NoError(t, root.(*dir).Remove(ctx, &fuse.RemoveRequest{Name: "old"}))
+	n, err := root.(*dir).Lookup(ctx, "new")
+	require.NoError(t, err)
+	require.NoError(t, n.(*file).Setattr(
+		ctx,
+		&fuse.SetattrRequest{Valid: fuse.SetattrSize, Size: 3072},
+		&fuse.SetattrResponse{},
+	), "Removed files should free space")
+
+	stats, err := p.Stats("vol", true)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), stats.Writes)
+	require.Equal(t, uint64(2048), stats.WriteBytes)
+	require.Equal(t, uint64(1), stats.Reads)
+	require.Equal(t, uint64(2048), stats.ReadBytes)
+	require.Equal(t, uint64(3072), stats.BytesUsed)
+
+	_, err = p.Stats("missing", true)
+	require.Error(t, err)
+}
+
+func TestSharedQuota(t *testing.T) {
+	home, err := ioutil.TempDir("", "passthrough")
+	require.NoError(t, err)
+	defer os.RemoveAll(home)
+	volPath := filepath.Join(home, "vol")
+	require.NoError(t, os.Mkdir(volPath, 0755))
+
+	ctx := context.Background()
+	p := newProvider(home)
+	v := &api.Volume{
+		Id:         "vol",
+		DevicePath: volPath,
+		Spec:       &api.VolumeSpec{Size: 4096},
+	}
+	// Each mount of the volume writes 3072 bytes to its own file.
+	var errs []error
+	for _, name := range []string{"a", "b"} {
+		filesystem, err := p.GetFS(v)
+		require.NoError(t, err)
+		root, err := filesystem.Root()
+		require.NoError(t, err)
+		_, h, err := root.(*dir).Create(
+			ctx,
+			&fuse.CreateRequest{Name: name, Flags: fuse.OpenReadWrite, Mode: 0644},
+			&fuse.CreateResponse{},
+		)
+		require.NoError(t, err)
+		errs = append(errs, h.(*handle).Write(
+			ctx,
+			&fuse.WriteRequest{Data: make([]byte, 3072)},
+			&fuse.WriteResponse{},
+		))
+		require.NoError(t, h.(*handle).Release(ctx, &fuse.ReleaseRequest{}))
+	}
+	require.NoError(t, errs[0])
+	require.Equal(t, fuse.Errno(syscall.ENOSPC), errs[1],
+		"Mounts of a volume should share its size")
+
+	require.NoError(t, p.Delete(v))
+	filesystem, err := p.GetFS(v)
+	require.NoError(t, err)
+	require.Equal(t, uint64(3072), filesystem.(*passFS).used,
+		"Delete should drop the filesystem of the volume")
+}
@@ -5,23 +5,32 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"syscall"
 
 	"bazil.org/fuse"
 	"bazil.org/fuse/fs"
 
 	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/pkg/chaos"
 	"github.com/libopenstorage/openstorage/volume"
 	"github.com/libopenstorage/openstorage/volume/drivers/common"
 	"github.com/pborman/uuid"
 	"github.com/portworx/kvdb"
 )
 
+var (
+	koStrayCreate = chaos.Add("fuse", "create", "create volume directory before DB")
+	koMount       = chaos.Add("fuse", "mount", "mount FUSE filesystem")
+	koStrayDelete = chaos.Add("fuse", "delete", "remove volume directory before DB")
+)
+
 type volumeDriver struct {
 	volume.IODriver
 	volume.BlockDriver
-	volume.SnapshotDriver
 	volume.StoreEnumerator
 	volume.StatsDriver
+	volume.TrimDriver
+	volume.ExportDriver
 	name        string
 	baseDirPath string
 	provider    Provider
@@ -32,15 +41,20 @@ func newVolumeDriver(
 	baseDirPath string,
 	provider Provider,
 ) *volumeDriver {
+	var statsDriver volume.StatsDriver = volume.StatsNotSupported
+	if s, ok := provider.(volume.StatsDriver); ok {
+		statsDriver = s
+	}
 	return &volumeDriver{
 		volume.IONotSupported,
 		volume.BlockNotSupported,
-		volume.SnapshotNotSupported,
 		common.NewDefaultStoreEnumerator(
 			name,
 			kvdb.Instance(),
 		),
-		volume.StatsNotSupported,
+		statsDriver,
+		volume.TrimNotSupported,
+		volume.ExportNotSupported,
 		name,
 		baseDirPath,
 		provider,
@@ -65,6 +79,9 @@ func (v *volumeDriver) Create(
 	if err := os.MkdirAll(dirPath, 0777); err != nil {
 		return "", err
 	}
+	if err := chaos.Now(koStrayCreate); err != nil {
+		return "", err
+	}
 	volume := common.NewVolume(
 		volumeID,
 		api.FSType_FS_TYPE_FUSE,
@@ -83,15 +100,66 @@ func (v *volumeDriver) Create(
 }
 
 func (v *volumeDriver) Delete(volumeID string) error {
-	if _, err := v.GetVol(volumeID); err != nil {
+	volume, err := v.GetVol(volumeID)
+	if err != nil {
 		return err
 	}
 	if err := os.RemoveAll(filepath.Join(v.baseDirPath, string(volumeID))); err != nil {
 		return err
 	}
+	if d, ok := v.provider.(Deleter); ok {
+		if err := d.Delete(volume); err != nil {
+			return err
+		}
+	}
+	if err := chaos.Now(koStrayDelete); err != nil {
+		return err
+	}
 	return v.DeleteVol(volumeID)
 }
 
+// Snapshot creates a volume with a copy of the data of a volume if the
+// provider is a Snapshotter.
+func (v *volumeDriver) Snapshot(
+	volumeID string,
+	readonly bool,
+	locator *api.VolumeLocator,
+) (string, error) {
+	s, ok := v.provider.(Snapshotter)
+	if !ok {
+		return "", volume.ErrNotSupported
+	}
+	parent, err := v.GetVol(volumeID)
+	if err != nil {
+		return "", err
+	}
+	snapID := strings.TrimSpace(string(uuid.New()))
+	dirPath := filepath.Join(v.baseDirPath, snapID)
+	if err := os.MkdirAll(dirPath, 0777); err != nil {
+		return "", err
+	}
+	snap := common.NewVolume(
+		snapID,
+		api.FSType_FS_TYPE_FUSE,
+		locator,
+		&api.Source{Parent: volumeID},
+		parent.Spec,
+	)
+	snap.DevicePath = dirPath
+	if err := s.Snapshot(parent, snap); err != nil {
+		os.RemoveAll(dirPath)
+		return "", err
+	}
+	if err := v.CreateVol(snap); err != nil {
+		if d, ok := v.provider.(Deleter); ok {
+			d.Delete(snap)
+		}
+		os.RemoveAll(dirPath)
+		return "", err
+	}
+	return snapID, nil
+}
+
 func (v *volumeDriver) MountedAt(mountpath string) string {
 	return ""
 }
@@ -101,18 +169,28 @@ func (v *volumeDriver) Mount(volumeID string, mountpath string) error {
 	if err != nil {
 		return err
 	}
-	if len(volume.AttachPath) > 0 && len(volume.AttachPath) > 0 {
-		return fmt.Errorf("Volume %q already mounted at %q", volumeID, volume.AttachPath[0])
+	if err := common.CheckAccess(
+		volume,
+		mountpath,
+		api.AccessMode_ACCESS_MODE_READ_WRITE_ONCE,
+	); err != nil {
+		return err
 	}
 	mountOptions, err := v.provider.GetMountOptions(volume.Spec)
 	if err != nil {
 		return err
 	}
-	conn, err := fuse.Mount(mountpath, mountOptions...)
+	if flags, _ := common.MountFlags(volume); flags&syscall.MS_RDONLY != 0 {
+		mountOptions = append(mountOptions, fuse.ReadOnly())
+	}
+	filesystem, err := v.provider.GetFS(volume)
 	if err != nil {
 		return err
 	}
-	filesystem, err := v.provider.GetFS(volume.Spec)
+	if err := chaos.Now(koMount); err != nil {
+		return err
+	}
+	conn, err := fuse.Mount(mountpath, mountOptions...)
 	if err != nil {
 		return err
 	}
@@ -122,13 +200,11 @@ func (v *volumeDriver) Mount(volumeID string, mountpath string) error {
 		_ = conn.Close()
 	}()
 	<-conn.Ready
-	if conn.MountError == nil {
-		if volume.AttachPath == nil {
-			volume.AttachPath = make([]string, 1)
-		}
-		volume.AttachPath[0] = mountpath
+	if conn.MountError != nil {
+		return conn.MountError
 	}
-	return conn.MountError
+	common.AddAttachPath(volume, mountpath)
+	return v.UpdateVol(volume)
 }
 
 func (v *volumeDriver) Unmount(volumeID string, mountpath string) error {
@@ -139,16 +215,30 @@ func (v *volumeDriver) Unmount(volumeID string, mountpath string) error {
 	if len(volume.AttachPath) == 0 || len(volume.AttachPath[0]) == 0 {
 		return fmt.Errorf("Device %v not mounted", volumeID)
 	}
-	if err := fuse.Unmount(volume.AttachPath[0]); err != nil {
+	if len(mountpath) == 0 {
+		mountpath = volume.AttachPath[0]
+	}
+	if err := fuse.Unmount(mountpath); err != nil {
 		return err
 	}
-	volume.AttachPath = nil
+	common.RemoveAttachPath(volume, mountpath)
 	return v.UpdateVol(volume)
 }
 
+// Set updates the locator and the size of a volume. Providers apply the
+// new size when the volume is next mounted.
 func (v *volumeDriver) Set(volumeID string, locator *api.VolumeLocator, spec *api.VolumeSpec) error {
-	return volume.ErrNotSupported
-
+	volume, err := v.GetVol(volumeID)
+	if err != nil {
+		return err
+	}
+	if locator != nil {
+		volume.Locator = locator
+	}
+	if spec != nil && spec.Size != 0 {
+		volume.Spec.Size = spec.Size
+	}
+	return v.UpdateVol(volume)
 }
 
 func (v *volumeDriver) Status() [][2]string {
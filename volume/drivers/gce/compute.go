@@ -0,0 +1,265 @@
+package gce
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// computeURL is the endpoint of the compute API.
+	computeURL = "https://compute.googleapis.com/compute/v1/"
+	// metadataURL is the endpoint of the metadata server of an instance.
+	metadataURL = "http://metadata.google.internal/computeMetadata/v1/"
+	// operationTimeout is how long operations take to complete.
+	operationTimeout = 5 * time.Minute
+	// operationPollInterval is how often operations are polled.
+	operationPollInterval = 2 * time.Second
+)
+
+// Disk statuses
+const (
+	diskCreating  = "CREATING"
+	diskRestoring = "RESTORING"
+	diskReady     = "READY"
+	diskFailed    = "FAILED"
+	diskDeleting  = "DELETING"
+)
+
+// Operation statuses
+const (
+	operationPending = "PENDING"
+	operationRunning = "RUNNING"
+	operationDone    = "DONE"
+)
+
+// errNotFound is returned for resources that do not exist.
+var errNotFound = fmt.Errorf("Resource not found")
+
+// gceDisk is a persistent disk.
+type gceDisk struct {
+	Name             string            `json:"name"`
+	SizeGb           int64             `json:"sizeGb,string,omitempty"`
+	Type             string            `json:"type,omitempty"`
+	Zone             string            `json:"zone,omitempty"`
+	Status           string            `json:"status,omitempty"`
+	SourceSnapshot   string            `json:"sourceSnapshot,omitempty"`
+	ProvisionedIops  int64             `json:"provisionedIops,string,omitempty"`
+	Users            []string          `json:"users,omitempty"`
+	Labels           map[string]string `json:"labels,omitempty"`
+	LabelFingerprint string            `json:"labelFingerprint,omitempty"`
+	SelfLink         string            `json:"selfLink,omitempty"`
+}
+
+// diskList is a page of disks.
+type diskList struct {
+	Items         []*gceDisk `json:"items"`
+	NextPageToken string     `json:"nextPageToken,omitempty"`
+}
+
+// gceSnapshot is a snapshot of a persistent disk.
+type gceSnapshot struct {
+	Name       string `json:"name"`
+	DiskSizeGb int64  `json:"diskSizeGb,string,omitempty"`
+	Status     string `json:"status,omitempty"`
+	SourceDisk string `json:"sourceDisk,omitempty"`
+	SelfLink   string `json:"selfLink,omitempty"`
+}
+
+// attachedDisk is a disk attached to an instance.
+type attachedDisk struct {
+	Source     string `json:"source"`
+	DeviceName string `json:"deviceName"`
+	Mode       string `json:"mode,omitempty"`
+	Boot       bool   `json:"boot,omitempty"`
+}
+
+// gceInstance is a virtual machine.
+type gceInstance struct {
+	Name  string          `json:"name"`
+	Disks []*attachedDisk `json:"disks"`
+}
+
+// labels is the request to set the labels of a disk.
+type labels struct {
+	Labels           map[string]string `json:"labels"`
+	LabelFingerprint string            `json:"labelFingerprint"`
+}
+
+// resize is the request to grow a disk.
+type resize struct {
+	SizeGb int64 `json:"sizeGb,string"`
+}
+
+// operation is an asynchronous change of a resource.
+type operation struct {
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	TargetLink string `json:"targetLink,omitempty"`
+	SelfLink   string `json:"selfLink"`
+	Error      *struct {
+		Errors []apiError `json:"errors"`
+	} `json:"error,omitempty"`
+}
+
+// apiError describes why a call failed.
+type apiError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// errorReply is the body of a failed call.
+type errorReply struct {
+	Error struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// tokenReply is the access token of the service account of an instance.
+type tokenReply struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+// computeClient calls the compute API of a project.
+type computeClient struct {
+	sync.Mutex
+	client       *http.Client
+	base         string
+	token        string
+	expiry       time.Time
+	pollInterval time.Duration
+}
+
+func newComputeClient(client *http.Client, project string) *computeClient {
+	return &computeClient{
+		client:       client,
+		base:         computeURL + "projects/" + project + "/",
+		pollInterval: operationPollInterval,
+	}
+}
+
+// metadata returns the value of key from the metadata server.
+func metadata(client *http.Client, key string) (string, error) {
+	req, err := http.NewRequest("GET", metadataURL+key, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("Error querying GCE metadata for key %s: %v",
+			key, resp.Status)
+	}
+	return string(body), nil
+}
+
+// accessToken returns a token of the service account of the instance,
+// refreshing it before it expires.
+func (c *computeClient) accessToken() (string, error) {
+	c.Lock()
+	defer c.Unlock()
+	if len(c.token) != 0 && time.Now().Before(c.expiry) {
+		return c.token, nil
+	}
+	body, err := metadata(c.client, "instance/service-accounts/default/token")
+	if err != nil {
+		return "", err
+	}
+	reply := tokenReply{}
+	if err := json.Unmarshal([]byte(body), &reply); err != nil {
+		return "", err
+	}
+	c.token = reply.AccessToken
+	c.expiry = time.Now().Add(time.Duration(reply.ExpiresIn)*time.Second - time.Minute)
+	return c.token, nil
+}
+
+// call sends in to the resource at path, which is relative to the project
+// unless it is a URL, and decodes the reply into out.
+func (c *computeClient) call(method string, path string, in interface{}, out interface{}) error {
+	url := path
+	if !strings.HasPrefix(path, "https://") {
+		url = c.base + path
+	}
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	token, err := c.accessToken()
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return errNotFound
+	}
+	if resp.StatusCode >= 300 {
+		reply := errorReply{}
+		json.NewDecoder(resp.Body).Decode(&reply)
+		return fmt.Errorf("%v %v failed: %v %v", method, path, resp.Status,
+			reply.Error.Message)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// do sends in to path and waits for the operation it starts.
+func (c *computeClient) do(method string, path string, in interface{}) error {
+	op := &operation{}
+	if err := c.call(method, path, in, op); err != nil {
+		return err
+	}
+	deadline := time.Now().Add(operationTimeout)
+	for op.Status != operationDone {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("Operation %v on %v did not complete in %v",
+				op.Name, op.TargetLink, operationTimeout)
+		}
+		time.Sleep(c.pollInterval)
+		next := &operation{}
+		if err := c.call("GET", op.SelfLink, nil, next); err != nil {
+			return err
+		}
+		op = next
+	}
+	if op.Error != nil && len(op.Error.Errors) != 0 {
+		return fmt.Errorf("Operation %v on %v failed: %v: %v", op.Name,
+			op.TargetLink, op.Error.Errors[0].Code, op.Error.Errors[0].Message)
+	}
+	return nil
+}
+
+// lastSegment returns the name of the resource at url.
+func lastSegment(url string) string {
+	return url[strings.LastIndex(url, "/")+1:]
+}
@@ -0,0 +1,326 @@
+package gce
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/libopenstorage/openstorage/volume/drivers/cloud"
+)
+
+const (
+	// FakeProject is the project of the fake compute API.
+	FakeProject = "fake-project"
+	// FakeZone is the zone of the fake compute API.
+	FakeZone = "fake-zone-a"
+)
+
+// fakeCompute serves the disks, snapshots and instances of a project in
+// memory. Operations are running until they are polled once.
+type fakeCompute struct {
+	sync.Mutex
+	next       int
+	disks      map[string]*gceDisk
+	snapshots  map[string]*gceSnapshot
+	instances  map[string]*gceInstance
+	operations map[string]*operation
+}
+
+// NewFakeCompute returns an in-memory compute API for FakeProject and
+// FakeZone.
+func NewFakeCompute() http.Handler {
+	return &fakeCompute{
+		disks:      make(map[string]*gceDisk),
+		snapshots:  make(map[string]*gceSnapshot),
+		instances:  make(map[string]*gceInstance),
+		operations: make(map[string]*operation),
+	}
+}
+
+// NewFakeProvider returns a provider for instance that manages the disks of
+// compute, which is a fake returned by NewFakeCompute. Providers for other
+// instances of the same fake share its disks.
+func NewFakeProvider(compute http.Handler, instance string) cloud.Provider {
+	p := newProvider(cloud.NewHandlerClient(compute), FakeProject, FakeZone, instance)
+	p.compute.pollInterval = 0
+	return p
+}
+
+// fail replies with an API error.
+func fail(w http.ResponseWriter, code int, format string, args ...interface{}) {
+	reply := errorReply{}
+	reply.Error.Code = code
+	reply.Error.Message = fmt.Sprintf(format, args...)
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(&reply)
+}
+
+// operation starts an operation on target that completes with err.
+func (f *fakeCompute) operation(target string, err *apiError) *operation {
+	f.next++
+	name := "operation-" + strconv.Itoa(f.next)
+	done := &operation{
+		Name:       name,
+		Status:     operationDone,
+		TargetLink: target,
+		SelfLink:   computeURL + "projects/" + FakeProject + "/zones/" + FakeZone + "/operations/" + name,
+	}
+	if err != nil {
+		done.Error = &struct {
+			Errors []apiError `json:"errors"`
+		}{Errors: []apiError{*err}}
+	}
+	f.operations[name] = done
+	running := *done
+	running.Status = operationRunning
+	running.Error = nil
+	return &running
+}
+
+func (f *fakeCompute) instance(name string) *gceInstance {
+	i, ok := f.instances[name]
+	if !ok {
+		i = &gceInstance{
+			Name: name,
+			Disks: []*attachedDisk{
+				{DeviceName: "persistent-disk-0", Source: name + "-boot", Boot: true},
+			},
+		}
+		f.instances[name] = i
+	}
+	return i
+}
+
+func (f *fakeCompute) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+
+	if r.URL.Path == "/computeMetadata/v1/instance/service-accounts/default/token" {
+		json.NewEncoder(w).Encode(&tokenReply{AccessToken: "token", ExpiresIn: 3600})
+		return
+	}
+	if r.Header.Get("Authorization") != "Bearer token" {
+		fail(w, http.StatusUnauthorized, "Invalid credentials")
+		return
+	}
+	prefix := "/compute/v1/projects/" + FakeProject + "/"
+	if !strings.HasPrefix(r.URL.Path, prefix) {
+		fail(w, http.StatusNotFound, "Unknown project")
+		return
+	}
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, prefix), "/")
+	var res interface{}
+	switch {
+	case len(parts) == 4 && parts[2] == "operations":
+		op, ok := f.operations[parts[3]]
+		if !ok {
+			fail(w, http.StatusNotFound, "Operation %v not found", parts[3])
+			return
+		}
+		res = op
+	case len(parts) == 3 && parts[0] == "global" && parts[1] == "snapshots":
+		snap, ok := f.snapshots[parts[2]]
+		if !ok {
+			fail(w, http.StatusNotFound, "Snapshot %v not found", parts[2])
+			return
+		}
+		if r.Method == "DELETE" {
+			delete(f.snapshots, parts[2])
+			res = f.operation(snap.SelfLink, nil)
+		} else {
+			res = snap
+		}
+	case len(parts) == 3 && parts[2] == "disks":
+		if r.Method == "POST" {
+			d := &gceDisk{}
+			if err := json.NewDecoder(r.Body).Decode(d); err != nil {
+				fail(w, http.StatusBadRequest, "%v", err)
+				return
+			}
+			if status, err := f.createDisk(d); err != nil {
+				fail(w, status, "%v", err)
+				return
+			}
+			res = f.operation(d.SelfLink, nil)
+			break
+		}
+		list := &diskList{}
+		names := make([]string, 0, len(f.disks))
+		for name := range f.disks {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			list.Items = append(list.Items, f.disks[name])
+		}
+		res = list
+	case len(parts) >= 4 && parts[2] == "disks":
+		d, ok := f.disks[parts[3]]
+		if !ok {
+			fail(w, http.StatusNotFound, "Disk %v not found", parts[3])
+			return
+		}
+		status, reply, err := f.disk(r, d, parts[4:])
+		if err != nil {
+			fail(w, status, "%v", err)
+			return
+		}
+		res = reply
+	case len(parts) >= 4 && parts[2] == "instances":
+		i := f.instance(parts[3])
+		if len(parts) == 4 {
+			res = i
+			break
+		}
+		status, reply, err := f.attach(r, i, parts[4])
+		if err != nil {
+			fail(w, status, "%v", err)
+			return
+		}
+		res = reply
+	default:
+		fail(w, http.StatusNotFound, "Unknown resource %v", r.URL.Path)
+		return
+	}
+	json.NewEncoder(w).Encode(res)
+}
+
+func (f *fakeCompute) createDisk(d *gceDisk) (int, error) {
+	if _, ok := f.disks[d.Name]; ok {
+		return http.StatusConflict, fmt.Errorf("Disk %v already exists", d.Name)
+	}
+	if len(d.SourceSnapshot) != 0 {
+		snap, ok := f.snapshots[lastSegment(d.SourceSnapshot)]
+		if !ok {
+			return http.StatusNotFound, fmt.Errorf("Snapshot %v not found", d.SourceSnapshot)
+		}
+		if d.SizeGb == 0 {
+			d.SizeGb = snap.DiskSizeGb
+		} else if d.SizeGb < snap.DiskSizeGb {
+			return http.StatusBadRequest, fmt.Errorf("Disk %v is smaller than snapshot %v",
+				d.Name, snap.Name)
+		}
+	}
+	if d.SizeGb == 0 {
+		return http.StatusBadRequest, fmt.Errorf("Disk %v has no size", d.Name)
+	}
+	d.Zone = computeURL + "projects/" + FakeProject + "/zones/" + FakeZone
+	d.Type = d.Zone + "/diskTypes/" + lastSegment(d.Type)
+	d.SelfLink = d.Zone + "/disks/" + d.Name
+	d.Status = diskReady
+	d.LabelFingerprint = strconv.Itoa(f.next)
+	f.disks[d.Name] = d
+	return 0, nil
+}
+
+// disk serves the calls on the disk d.
+func (f *fakeCompute) disk(
+	r *http.Request,
+	d *gceDisk,
+	method []string,
+) (int, interface{}, error) {
+	switch {
+	case len(method) == 0 && r.Method == "GET":
+		return 0, d, nil
+	case len(method) == 0 && r.Method == "DELETE":
+		if len(d.Users) != 0 {
+			return http.StatusBadRequest, nil,
+				fmt.Errorf("Disk %v is in use by %v", d.Name, d.Users[0])
+		}
+		delete(f.disks, d.Name)
+		return 0, f.operation(d.SelfLink, nil), nil
+	case len(method) != 1 || r.Method != "POST":
+		return http.StatusNotFound, nil, fmt.Errorf("Unknown call %v", method)
+	case method[0] == "createSnapshot":
+		snap := &gceSnapshot{}
+		if err := json.NewDecoder(r.Body).Decode(snap); err != nil {
+			return http.StatusBadRequest, nil, err
+		}
+		snap.DiskSizeGb = d.SizeGb
+		snap.SourceDisk = d.SelfLink
+		snap.Status = "READY"
+		snap.SelfLink = computeURL + "projects/" + FakeProject + "/global/snapshots/" + snap.Name
+		f.snapshots[snap.Name] = snap
+		return 0, f.operation(snap.SelfLink, nil), nil
+	case method[0] == "setLabels":
+		l := &labels{}
+		if err := json.NewDecoder(r.Body).Decode(l); err != nil {
+			return http.StatusBadRequest, nil, err
+		}
+		if l.LabelFingerprint != d.LabelFingerprint {
+			return http.StatusPreconditionFailed, nil,
+				fmt.Errorf("Labels of disk %v have changed", d.Name)
+		}
+		f.next++
+		d.Labels = l.Labels
+		d.LabelFingerprint = strconv.Itoa(f.next)
+		return 0, f.operation(d.SelfLink, nil), nil
+	case method[0] == "resize":
+		size := &resize{}
+		if err := json.NewDecoder(r.Body).Decode(size); err != nil {
+			return http.StatusBadRequest, nil, err
+		}
+		if size.SizeGb < d.SizeGb {
+			return http.StatusBadRequest, nil,
+				fmt.Errorf("Disk %v cannot shrink", d.Name)
+		}
+		d.SizeGb = size.SizeGb
+		return 0, f.operation(d.SelfLink, nil), nil
+	}
+	return http.StatusNotFound, nil, fmt.Errorf("Unknown call %v", method)
+}
+
+// attach serves the attachDisk and detachDisk calls on the instance i.
+// Failures to attach are reported by the operation, as in GCE.
+func (f *fakeCompute) attach(
+	r *http.Request,
+	i *gceInstance,
+	method string,
+) (int, interface{}, error) {
+	if r.Method != "POST" {
+		return http.StatusNotFound, nil, fmt.Errorf("Unknown call %v", method)
+	}
+	instanceLink := computeURL + "projects/" + FakeProject + "/zones/" + FakeZone +
+		"/instances/" + i.Name
+	switch method {
+	case "attachDisk":
+		a := &attachedDisk{}
+		if err := json.NewDecoder(r.Body).Decode(a); err != nil {
+			return http.StatusBadRequest, nil, err
+		}
+		d, ok := f.disks[lastSegment(a.Source)]
+		if !ok {
+			return http.StatusNotFound, nil, fmt.Errorf("Disk %v not found", a.Source)
+		}
+		if len(d.Users) != 0 {
+			return 0, f.operation(instanceLink, &apiError{
+				Code:    "RESOURCE_IN_USE_BY_ANOTHER_RESOURCE",
+				Message: fmt.Sprintf("Disk %v is in use by %v", d.Name, d.Users[0]),
+			}), nil
+		}
+		d.Users = []string{instanceLink}
+		i.Disks = append(i.Disks, a)
+		return 0, f.operation(instanceLink, nil), nil
+	case "detachDisk":
+		device := r.URL.Query().Get("deviceName")
+		for n, a := range i.Disks {
+			if a.DeviceName != device {
+				continue
+			}
+			i.Disks = append(i.Disks[:n], i.Disks[n+1:]...)
+			if d, ok := f.disks[lastSegment(a.Source)]; ok {
+				d.Users = nil
+			}
+			return 0, f.operation(instanceLink, nil), nil
+		}
+		return 0, f.operation(instanceLink, &apiError{
+			Code:    "INVALID_USAGE",
+			Message: fmt.Sprintf("No attached disk %v", device),
+		}), nil
+	}
+	return http.StatusNotFound, nil, fmt.Errorf("Unknown call %v", method)
+}
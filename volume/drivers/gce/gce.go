@@ -0,0 +1,322 @@
+package gce
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pborman/uuid"
+	"go.pedge.io/dlog"
+
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/volume"
+	"github.com/libopenstorage/openstorage/volume/drivers/cloud"
+)
+
+const (
+	// Name of the driver
+	Name = "gce"
+	// Type of the driver
+	Type = cloud.Type
+	// diskPrefix names the disks of the driver.
+	diskPrefix = "osd-"
+	// snapshotPrefix names the snapshots of the driver.
+	snapshotPrefix = "osd-snap-"
+	// devicePrefix is the path of the devices of attached disks, which are
+	// named after their disk.
+	devicePrefix = "/dev/disk/by-id/google-"
+)
+
+// provider manages the persistent disks of a GCE instance.
+type provider struct {
+	compute  *computeClient
+	project  string
+	zone     string
+	instance string
+}
+
+// Init initializes the driver for the instance it runs on. The project,
+// zone and instance params override those of the metadata server.
+func Init(params map[string]string) (volume.VolumeDriver, error) {
+	client := &http.Client{Timeout: time.Minute}
+	md := make(map[string]string)
+	for param, key := range map[string]string{
+		"project":  "project/project-id",
+		"zone":     "instance/zone",
+		"instance": "instance/name",
+	} {
+		if value, ok := params[param]; ok {
+			md[param] = value
+			continue
+		}
+		value, err := metadata(client, key)
+		if err != nil {
+			return nil, err
+		}
+		md[param] = lastSegment(value)
+	}
+	dlog.Infof("GCE instance %v zone %v project %v",
+		md["instance"], md["zone"], md["project"])
+	d, err := cloud.NewDriver(
+		Name,
+		newProvider(client, md["project"], md["zone"], md["instance"]),
+		&cloud.ExecRunner{},
+		nil,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func newProvider(client *http.Client, project, zone, instance string) *provider {
+	return &provider{
+		compute:  newComputeClient(client, project),
+		project:  project,
+		zone:     zone,
+		instance: instance,
+	}
+}
+
+func (p *provider) diskPath(id string) string {
+	return "zones/" + p.zone + "/disks/" + id
+}
+
+func (p *provider) instancePath() string {
+	return "zones/" + p.zone + "/instances/" + p.instance
+}
+
+func (p *provider) Instance() string {
+	return p.instance
+}
+
+func (p *provider) DiskType(cos api.CosType) (string, int64) {
+	switch cos {
+	case api.CosType_LOW:
+		return "pd-standard", 0
+	case api.CosType_HIGH:
+		return "pd-ssd", 0
+	}
+	return "pd-balanced", 0
+}
+
+// disk translates a persistent disk to a disk.
+func (p *provider) disk(d *gceDisk) *cloud.Disk {
+	disk := &cloud.Disk{
+		Id:     d.Name,
+		Zone:   lastSegment(d.Zone),
+		Size:   d.SizeGb,
+		Type:   lastSegment(d.Type),
+		Iops:   d.ProvisionedIops,
+		Labels: d.Labels,
+	}
+	if len(d.SourceSnapshot) != 0 {
+		disk.Snapshot = lastSegment(d.SourceSnapshot)
+	}
+	switch d.Status {
+	case diskCreating, diskRestoring, diskDeleting:
+		disk.State = cloud.DiskPending
+	case diskReady:
+		disk.State = cloud.DiskAvailable
+		if len(d.Users) != 0 {
+			disk.State = cloud.DiskAttached
+			disk.Instance = lastSegment(d.Users[0])
+		}
+	default:
+		disk.State = cloud.DiskError
+	}
+	return disk
+}
+
+func (p *provider) get(id string) (*gceDisk, error) {
+	d := &gceDisk{}
+	if err := p.compute.call("GET", p.diskPath(id), nil, d); err != nil {
+		if err == errNotFound {
+			return nil, fmt.Errorf("Disk %v does not exist", id)
+		}
+		return nil, err
+	}
+	return d, nil
+}
+
+func (p *provider) Create(template *cloud.Disk, labels map[string]string) (*cloud.Disk, error) {
+	d := &gceDisk{
+		Name:            diskPrefix + uuid.New(),
+		SizeGb:          template.Size,
+		Type:            "zones/" + p.zone + "/diskTypes/" + template.Type,
+		ProvisionedIops: template.Iops,
+		Labels:          labels,
+	}
+	if len(template.Snapshot) != 0 {
+		d.SourceSnapshot = "global/snapshots/" + template.Snapshot
+	}
+	if err := p.compute.do("POST", "zones/"+p.zone+"/disks", d); err != nil {
+		return nil, err
+	}
+	created, err := p.get(d.Name)
+	if err != nil {
+		return nil, err
+	}
+	return p.disk(created), nil
+}
+
+func (p *provider) Delete(id string) error {
+	if p.IsSnapshot(id) {
+		return p.compute.do("DELETE", "global/snapshots/"+id, nil)
+	}
+	return p.compute.do("DELETE", p.diskPath(id), nil)
+}
+
+func (p *provider) Attach(id string) (string, error) {
+	if err := p.compute.do(
+		"POST",
+		p.instancePath()+"/attachDisk",
+		&attachedDisk{
+			Source:     p.compute.base + p.diskPath(id),
+			DeviceName: id,
+			Mode:       "READ_WRITE",
+		},
+	); err != nil {
+		return "", err
+	}
+	return devicePrefix + id, nil
+}
+
+func (p *provider) Detach(id string) error {
+	return p.compute.do("POST", p.instancePath()+"/detachDisk?deviceName="+id, nil)
+}
+
+func (p *provider) Inspect(ids []string) ([]*cloud.Disk, error) {
+	disks := make([]*cloud.Disk, 0, len(ids))
+	for _, id := range ids {
+		d, err := p.get(id)
+		if err != nil {
+			return nil, err
+		}
+		disks = append(disks, p.disk(d))
+	}
+	return disks, nil
+}
+
+func (p *provider) Enumerate(labels map[string]string) ([]*cloud.Disk, error) {
+	var disks []*cloud.Disk
+	for token := ""; ; {
+		path := "zones/" + p.zone + "/disks"
+		if len(token) != 0 {
+			path += "?pageToken=" + token
+		}
+		list := &diskList{}
+		if err := p.compute.call("GET", path, nil, list); err != nil {
+			return nil, err
+		}
+		for _, d := range list.Items {
+			if hasLabels(d.Labels, labels) {
+				disks = append(disks, p.disk(d))
+			}
+		}
+		if token = list.NextPageToken; len(token) == 0 {
+			return disks, nil
+		}
+	}
+}
+
+// hasLabels returns true if set has all the labels of subset.
+func hasLabels(set map[string]string, subset map[string]string) bool {
+	for k, v := range subset {
+		if set[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *provider) DeviceMappings() (map[string]string, error) {
+	instance := &gceInstance{}
+	if err := p.compute.call("GET", p.instancePath(), nil, instance); err != nil {
+		return nil, err
+	}
+	m := make(map[string]string)
+	for _, d := range instance.Disks {
+		if d.Boot {
+			continue
+		}
+		m[devicePrefix+d.DeviceName] = lastSegment(d.Source)
+	}
+	return m, nil
+}
+
+func (p *provider) Snapshot(id string) (string, error) {
+	name := snapshotPrefix + uuid.New()
+	if err := p.compute.do(
+		"POST",
+		p.diskPath(id)+"/createSnapshot",
+		&gceSnapshot{Name: name},
+	); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+func (p *provider) IsSnapshot(id string) bool {
+	return strings.HasPrefix(id, snapshotPrefix)
+}
+
+// setLabels changes the labels of the disk id with update.
+func (p *provider) setLabels(id string, update func(map[string]string)) error {
+	d, err := p.get(id)
+	if err != nil {
+		return err
+	}
+	l := make(map[string]string)
+	for k, v := range d.Labels {
+		l[k] = v
+	}
+	update(l)
+	return p.compute.do(
+		"POST",
+		p.diskPath(id)+"/setLabels",
+		&labels{Labels: l, LabelFingerprint: d.LabelFingerprint},
+	)
+}
+
+func (p *provider) ApplyTags(id string, tags map[string]string) error {
+	return p.setLabels(id, func(l map[string]string) {
+		for k, v := range tags {
+			l[k] = v
+		}
+	})
+}
+
+func (p *provider) RemoveTags(id string, tags map[string]string) error {
+	return p.setLabels(id, func(l map[string]string) {
+		for k := range tags {
+			delete(l, k)
+		}
+	})
+}
+
+// Modify grows disks. Persistent disks cannot change their type.
+func (p *provider) Modify(template *cloud.Disk) (*cloud.Disk, error) {
+	d, err := p.get(template.Id)
+	if err != nil {
+		return nil, err
+	}
+	if len(template.Type) != 0 && template.Type != lastSegment(d.Type) {
+		return nil, fmt.Errorf("Disk %v cannot change type from %v to %v",
+			d.Name, lastSegment(d.Type), template.Type)
+	}
+	if template.Size != 0 && template.Size != d.SizeGb {
+		if err := p.compute.do(
+			"POST",
+			p.diskPath(d.Name)+"/resize",
+			&resize{SizeGb: template.Size},
+		); err != nil {
+			return nil, err
+		}
+		if d, err = p.get(d.Name); err != nil {
+			return nil, err
+		}
+	}
+	return p.disk(d), nil
+}
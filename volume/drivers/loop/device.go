@@ -0,0 +1,219 @@
+package loop
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	// Defined in <linux/loop.h>:
+	loopSetFd       = 0x4C00
+	loopClrFd       = 0x4C01
+	loopSetStatus64 = 0x4C04
+	loopGetStatus64 = 0x4C05
+	loopSetCapacity = 0x4C07
+	loopConfigure   = 0x4C0A
+	loopCtlGetFree  = 0x4C82
+	loFlagsReadOnly = 1
+	loNameSize      = 64
+	loKeySize       = 32
+	// Defined in <linux/major.h>:
+	loopMajor = 7
+	// Defined in <linux/fs.h>:
+	blkGetSize64 = 0x80081272
+)
+
+var (
+	// ErrNoLoopDevice is returned if no free loop device could be claimed.
+	ErrNoLoopDevice = errors.New("No free loop device")
+)
+
+// loopInfo64 is struct loop_info64 from <linux/loop.h>.
+type loopInfo64 struct {
+	device         uint64
+	inode          uint64
+	rdevice        uint64
+	offset         uint64
+	sizeLimit      uint64
+	number         uint32
+	encryptType    uint32
+	encryptKeySize uint32
+	flags          uint32
+	fileName       [loNameSize]byte
+	cryptName      [loNameSize]byte
+	encryptKey     [loKeySize]byte
+	init           [2]uint64
+}
+
+// loopConfig is struct loop_config from <linux/loop.h>.
+type loopConfig struct {
+	fd        uint32
+	blockSize uint32
+	info      loopInfo64
+	reserved  [8]uint64
+}
+
+func ioctl(fd, request, arg uintptr) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, request, arg)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// openLoop opens a loop device and creates its device node if the host
+// does not, e.g. in a container.
+func openLoop(n int) (*os.File, error) {
+	dev := fmt.Sprintf("/dev/loop%d", n)
+	f, err := os.OpenFile(dev, os.O_RDWR, 0)
+	if !os.IsNotExist(err) {
+		return f, err
+	}
+	if err := syscall.Mknod(
+		dev,
+		syscall.S_IFBLK|0660,
+		loopMajor<<8|n&0xff|(n&^0xff)<<12,
+	); err != nil && !os.IsExist(err) {
+		return nil, err
+	}
+	return os.OpenFile(dev, os.O_RDWR, 0)
+}
+
+// attachLoop attaches file to a free loop device and returns the path of
+// the loop device.
+func attachLoop(file string, readonly bool) (string, error) {
+	mode := os.O_RDWR
+	if readonly {
+		mode = os.O_RDONLY
+	}
+	f, err := os.OpenFile(file, mode, 0)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	ctl, err := os.OpenFile("/dev/loop-control", os.O_RDWR, 0)
+	if err != nil {
+		return "", err
+	}
+	defer ctl.Close()
+
+	// Another process may claim the free device first, so retry a few times.
+	for i := 0; i < 8; i++ {
+		n, _, errno := syscall.Syscall(
+			syscall.SYS_IOCTL,
+			ctl.Fd(),
+			loopCtlGetFree,
+			0,
+		)
+		if errno != 0 {
+			return "", errno
+		}
+		l, err := openLoop(int(n))
+		if err != nil {
+			return "", err
+		}
+		err = configureLoop(l, f, readonly)
+		l.Close()
+		if err == syscall.EBUSY {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		return l.Name(), nil
+	}
+	return "", ErrNoLoopDevice
+}
+
+// configureLoop binds the loop device l to f.
+func configureLoop(l *os.File, f *os.File, readonly bool) error {
+	config := loopConfig{fd: uint32(f.Fd())}
+	copy(config.info.fileName[:loNameSize-1], f.Name())
+	if readonly {
+		config.info.flags = loFlagsReadOnly
+	}
+	err := ioctl(l.Fd(), loopConfigure, uintptr(unsafe.Pointer(&config)))
+	if err != syscall.EINVAL && err != syscall.ENOTTY {
+		return err
+	}
+	// Kernels older than 5.8 do not support LOOP_CONFIGURE.
+	if err := ioctl(l.Fd(), loopSetFd, f.Fd()); err != nil {
+		return err
+	}
+	if err := ioctl(
+		l.Fd(),
+		loopSetStatus64,
+		uintptr(unsafe.Pointer(&config.info)),
+	); err != nil {
+		ioctl(l.Fd(), loopClrFd, 0)
+		return err
+	}
+	return nil
+}
+
+// detachLoop unbinds the loop device dev from its file.
+func detachLoop(dev string) error {
+	l, err := os.OpenFile(dev, os.O_RDONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+	if err := ioctl(l.Fd(), loopClrFd, 0); err != nil && err != syscall.ENXIO {
+		return fmt.Errorf("Failed to detach %v: %v", dev, err)
+	}
+	return nil
+}
+
+// isLoopOf returns true if the loop device dev is bound to file.
+func isLoopOf(dev string, file string) bool {
+	l, err := os.OpenFile(dev, os.O_RDONLY, 0)
+	if err != nil {
+		return false
+	}
+	defer l.Close()
+	var info loopInfo64
+	if err := ioctl(
+		l.Fd(),
+		loopGetStatus64,
+		uintptr(unsafe.Pointer(&info)),
+	); err != nil {
+		return false
+	}
+	var st syscall.Stat_t
+	if err := syscall.Stat(file, &st); err != nil {
+		return false
+	}
+	return info.inode == st.Ino && info.device == st.Dev
+}
+
+// setCapacity makes the loop device dev pick up the new size of its file.
+func setCapacity(dev string) error {
+	l, err := os.OpenFile(dev, os.O_RDONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+	return ioctl(l.Fd(), loopSetCapacity, 0)
+}
+
+// deviceSize returns the size of the block device dev in bytes.
+func deviceSize(dev string) (uint64, error) {
+	l, err := os.OpenFile(dev, os.O_RDONLY, 0)
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	var size uint64
+	if err := ioctl(
+		l.Fd(),
+		blkGetSize64,
+		uintptr(unsafe.Pointer(&size)),
+	); err != nil {
+		return 0, err
+	}
+	return size, nil
+}
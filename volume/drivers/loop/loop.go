@@ -0,0 +1,410 @@
+package loop
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"syscall"
+
+	"go.pedge.io/dlog"
+
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/pkg/chaos"
+	"github.com/libopenstorage/openstorage/volume"
+	"github.com/libopenstorage/openstorage/volume/drivers/common"
+	"github.com/pborman/uuid"
+	"github.com/portworx/kvdb"
+)
+
+const (
+	// Name of the driver
+	Name = "loop"
+	// Type of the driver
+	Type = api.DriverType_DRIVER_TYPE_BLOCK
+	// LoopPath where the block files of the volumes are created
+	LoopPath = "/var/lib/openstorage/loop/"
+	// RootParam is the Init param for the directory of the block files
+	RootParam = "path"
+)
+
+var (
+	koStrayCreate = chaos.Add("loop", "create", "create block file before DB")
+	koStrayAttach = chaos.Add("loop", "attach", "attach loop device before DB")
+	koMount       = chaos.Add("loop", "mount", "mount loop device")
+	koStrayDelete = chaos.Add("loop", "delete", "remove block file before DB")
+)
+
+// Implements the open storage volume interface with a sparse file per
+// volume that is attached through a kernel loop device.
+type driver struct {
+	volume.IODriver
+	volume.StoreEnumerator
+	volume.StatsDriver
+	volume.ExportDriver
+	root string
+}
+
+// Init initializes the loop driver. Loop devices outlive the daemon, so
+// volumes that are still attached are left as they are.
+func Init(params map[string]string) (volume.VolumeDriver, error) {
+	root, ok := params[RootParam]
+	if !ok {
+		root = LoopPath
+	}
+	if err := os.MkdirAll(root, 0744); err != nil {
+		return nil, err
+	}
+	inst := &driver{
+		IODriver:        volume.IONotSupported,
+		StoreEnumerator: common.NewDefaultStoreEnumerator(Name, kvdb.Instance()),
+		StatsDriver:     volume.StatsNotSupported,
+		ExportDriver:    volume.ExportNotSupported,
+		root:            root,
+	}
+
+	volumeInfo, err := inst.StoreEnumerator.Enumerate(
+		&api.VolumeLocator{},
+		nil,
+	)
+	if err != nil {
+		dlog.Println("Could not enumerate Volumes, ", err)
+		return inst, nil
+	}
+	for _, v := range volumeInfo {
+		if len(v.DevicePath) == 0 || isLoopOf(v.DevicePath, inst.file(v.Id)) {
+			continue
+		}
+		dlog.Warnf("Loop device %v of volume %v is gone", v.DevicePath, v.Id)
+		v.DevicePath = ""
+		v.AttachPath = nil
+		v.State = api.VolumeState_VOLUME_STATE_AVAILABLE
+		if err := inst.UpdateVol(v); err != nil {
+			dlog.Warnf("Failed to update volume %v: %v", v.Id, err)
+		}
+	}
+
+	dlog.Println("Loop driver initialized with block files at: ", root)
+	return inst, nil
+}
+
+func (d *driver) file(volumeID string) string {
+	return path.Join(d.root, volumeID)
+}
+
+//
+// These functions below implement the volume driver interface.
+//
+
+func (d *driver) String() string {
+	return Name
+}
+
+func (d *driver) Name() string {
+	return Name
+}
+
+func (d *driver) Type() api.DriverType {
+	return Type
+}
+
+// Status diagnostic information
+func (d *driver) Status() [][2]string {
+	return [][2]string{}
+}
+
+// Create a sparse block file for the volume. The file is formatted through
+// a loop device, or cloned from the block file of source.Parent.
+func (d *driver) Create(
+	locator *api.VolumeLocator,
+	source *api.Source,
+	spec *api.VolumeSpec,
+) (string, error) {
+	if spec.Size == 0 {
+		return "", fmt.Errorf("Volume size cannot be zero: loop")
+	}
+	volumeID := uuid.New()
+	file := d.file(volumeID)
+
+	var err error
+	if source != nil && len(source.Parent) != 0 {
+		err = d.clone(source.Parent, file)
+	} else {
+		err = d.format(file, spec)
+	}
+	if err != nil {
+		os.Remove(file)
+		return "", err
+	}
+
+	if err := chaos.Now(koStrayCreate); err != nil {
+		return "", err
+	}
+
+	v := common.NewVolume(
+		volumeID,
+		spec.Format,
+		locator,
+		source,
+		spec,
+	)
+	if err := d.CreateVol(v); err != nil {
+		os.Remove(file)
+		return "", err
+	}
+	return v.Id, nil
+}
+
+// format creates the block file and makes the filesystem in spec.Format.
+func (d *driver) format(file string, spec *api.VolumeSpec) error {
+	f, err := os.OpenFile(file, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	err = f.Truncate(int64(spec.Size))
+	f.Close()
+	if err != nil {
+		return err
+	}
+	if spec.Format == api.FSType_FS_TYPE_NONE {
+		return nil
+	}
+
+	dev, err := attachLoop(file, false)
+	if err != nil {
+		return err
+	}
+	defer detachLoop(dev)
+	dlog.Infof("Formatting %s with %v", dev, spec.Format)
+	cmd := "/sbin/mkfs." + spec.Format.SimpleString()
+	if o, err := exec.Command(cmd, dev).CombinedOutput(); err != nil {
+		return fmt.Errorf("Failed to run command %v %v: %v: %s", cmd, dev, err, o)
+	}
+	return nil
+}
+
+// clone copies the block file of the volume parentID to file. Writes that
+// are still cached by an attached loop device are flushed first.
+func (d *driver) clone(parentID string, file string) error {
+	parent, err := d.GetVol(parentID)
+	if err != nil {
+		return err
+	}
+	if len(parent.DevicePath) != 0 {
+		if f, err := os.Open(parent.DevicePath); err == nil {
+			f.Sync()
+			f.Close()
+		}
+	}
+	return common.CopyFile(d.file(parentID), file)
+}
+
+func (d *driver) Delete(volumeID string) error {
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		dlog.Println(err)
+		return err
+	}
+	if len(v.AttachPath) > 0 {
+		return fmt.Errorf("Volume %v is mounted at %v", volumeID, v.AttachPath)
+	}
+	if len(v.DevicePath) != 0 {
+		if err := detachLoop(v.DevicePath); err != nil {
+			return err
+		}
+	}
+	if err := os.Remove(d.file(volumeID)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := chaos.Now(koStrayDelete); err != nil {
+		return err
+	}
+	return d.DeleteVol(volumeID)
+}
+
+func (d *driver) MountedAt(mountpath string) string {
+	return ""
+}
+
+func (d *driver) Mount(volumeID string, mountpath string) error {
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		return fmt.Errorf("Failed to locate volume %q", volumeID)
+	}
+	if err := common.CheckAccess(
+		v,
+		mountpath,
+		api.AccessMode_ACCESS_MODE_READ_WRITE_ONCE,
+	); err != nil {
+		return err
+	}
+	if len(v.DevicePath) == 0 {
+		return volume.ErrVolDetached
+	}
+	if err := chaos.Now(koMount); err != nil {
+		return err
+	}
+	flags, data := common.MountFlags(v)
+	if err := syscall.Mount(
+		v.DevicePath,
+		mountpath,
+		v.Spec.Format.SimpleString(),
+		flags,
+		data,
+	); err != nil {
+		return fmt.Errorf("Failed to mount %v at %v: %v", v.DevicePath, mountpath, err)
+	}
+	common.AddAttachPath(v, mountpath)
+	return d.UpdateVol(v)
+}
+
+func (d *driver) Unmount(volumeID string, mountpath string) error {
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		return err
+	}
+	if len(v.AttachPath) == 0 || len(v.AttachPath[0]) == 0 {
+		return fmt.Errorf("Device %v not mounted", volumeID)
+	}
+	if len(mountpath) == 0 {
+		mountpath = v.AttachPath[0]
+	}
+	if err := syscall.Unmount(mountpath, 0); err != nil {
+		return err
+	}
+	common.RemoveAttachPath(v, mountpath)
+	return d.UpdateVol(v)
+}
+
+func (d *driver) Snapshot(volumeID string, readonly bool, locator *api.VolumeLocator) (string, error) {
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		return "", err
+	}
+	snapID, err := d.Create(locator, &api.Source{Parent: volumeID}, v.Spec)
+	if err != nil {
+		return "", err
+	}
+	if !readonly {
+		return snapID, nil
+	}
+	snap, err := d.GetVol(snapID)
+	if err != nil {
+		return "", err
+	}
+	snap.Readonly = true
+	return snapID, d.UpdateVol(snap)
+}
+
+// Set updates the locator and grows the volume to spec.Size. The
+// filesystem on the volume is not resized.
+func (d *driver) Set(volumeID string, locator *api.VolumeLocator, spec *api.VolumeSpec) error {
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		return err
+	}
+	if locator != nil {
+		v.Locator = locator
+	}
+	if spec != nil && spec.Size != 0 && spec.Size != v.Spec.Size {
+		if err := d.resize(v, spec.Size); err != nil {
+			return err
+		}
+	}
+	return d.UpdateVol(v)
+}
+
+func (d *driver) resize(v *api.Volume, size uint64) error {
+	if size < v.Spec.Size {
+		return fmt.Errorf("Volume %v cannot shrink from %v to %v",
+			v.Id, v.Spec.Size, size)
+	}
+	if err := os.Truncate(d.file(v.Id), int64(size)); err != nil {
+		return err
+	}
+	if len(v.DevicePath) != 0 {
+		if err := setCapacity(v.DevicePath); err != nil {
+			return fmt.Errorf("Failed to resize %v: %v", v.DevicePath, err)
+		}
+	}
+	dlog.Infof("Loop volume %v resized from %v to %v", v.Id, v.Spec.Size, size)
+	v.Spec.Size = size
+	return nil
+}
+
+func (d *driver) Attach(volumeID string, attachOptions map[string]string) (string, error) {
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		return "", err
+	}
+	if len(v.DevicePath) != 0 {
+		return v.DevicePath, nil
+	}
+	dev, err := attachLoop(d.file(volumeID), v.Readonly)
+	if err != nil {
+		return "", err
+	}
+	if err := chaos.Now(koStrayAttach); err != nil {
+		return "", err
+	}
+	v.DevicePath = dev
+	v.State = api.VolumeState_VOLUME_STATE_ATTACHED
+	if err := d.UpdateVol(v); err != nil {
+		detachLoop(dev)
+		return "", err
+	}
+	dlog.Infof("Loop volume %v attached at %s", volumeID, dev)
+	return dev, nil
+}
+
+func (d *driver) Detach(volumeID string) error {
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		return err
+	}
+	if len(v.DevicePath) == 0 {
+		return nil
+	}
+	if len(v.AttachPath) > 0 {
+		return fmt.Errorf("Volume %v is mounted at %v", volumeID, v.AttachPath)
+	}
+	if err := detachLoop(v.DevicePath); err != nil {
+		return err
+	}
+	dlog.Infof("Loop volume %v detached from %s", volumeID, v.DevicePath)
+	v.DevicePath = ""
+	v.State = api.VolumeState_VOLUME_STATE_AVAILABLE
+	return d.UpdateVol(v)
+}
+
+// UsedSize returns the bytes allocated to the block file of a volume.
+func (d *driver) UsedSize(volumeID string) (uint64, error) {
+	if _, err := d.GetVol(volumeID); err != nil {
+		return 0, err
+	}
+	var st syscall.Stat_t
+	if err := syscall.Stat(d.file(volumeID), &st); err != nil {
+		return 0, err
+	}
+	return uint64(st.Blocks) * 512, nil
+}
+
+// Fstrim trims the filesystem of a mounted volume. The loop device turns the
+// discards into holes in the block file.
+func (d *driver) Fstrim(volumeID string) (uint64, error) {
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		return 0, err
+	}
+	if len(v.DevicePath) == 0 {
+		return 0, volume.ErrVolDetached
+	}
+	if len(v.AttachPath) == 0 {
+		return 0, fmt.Errorf("Volume %q is not mounted", volumeID)
+	}
+	return common.Fstrim(v.AttachPath[0])
+}
+
+func (d *driver) Shutdown() {
+	dlog.Printf("%s Shutting down", Name)
+}
@@ -0,0 +1,57 @@
+package loop
+
+import (
+	"os"
+	"testing"
+
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/volume/drivers/test"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testPath = string("/tmp/openstorage_loop_test")
+)
+
+func TestAll(t *testing.T) {
+	err := os.MkdirAll(testPath, 0744)
+	if err != nil {
+		t.Fatalf("Failed to create test path: %v", err)
+	}
+
+	d, err := Init(map[string]string{RootParam: testPath})
+	if err != nil {
+		t.Fatalf("Failed to initialize Volume Driver: %v", err)
+	}
+	ctx := test.NewContext(d)
+	ctx.Filesystem = api.FSType_FS_TYPE_EXT4
+
+	test.Run(t, ctx)
+}
+
+func TestResize(t *testing.T) {
+	d, err := Init(map[string]string{RootParam: testPath})
+	require.NoError(t, err)
+	volumeID, err := d.Create(
+		&api.VolumeLocator{Name: "resize"},
+		nil,
+		&api.VolumeSpec{Size: 16 * 1024 * 1024},
+	)
+	require.NoError(t, err)
+	defer d.Delete(volumeID)
+
+	dev, err := d.Attach(volumeID, nil)
+	require.NoError(t, err)
+	defer d.Detach(volumeID)
+	require.True(t, isLoopOf(dev, d.(*driver).file(volumeID)))
+
+	require.NoError(t, d.Set(volumeID, nil, &api.VolumeSpec{Size: 32 * 1024 * 1024}))
+	size, err := deviceSize(dev)
+	require.NoError(t, err)
+	require.Equal(t, uint64(32*1024*1024), size)
+	require.Error(t, d.Set(volumeID, nil, &api.VolumeSpec{Size: 1024 * 1024}))
+
+	used, err := d.UsedSize(volumeID)
+	require.NoError(t, err)
+	require.True(t, used < 32*1024*1024)
+}
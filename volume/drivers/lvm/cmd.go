@@ -0,0 +1,108 @@
+package lvm
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/libopenstorage/openstorage/api"
+)
+
+// run runs an lvm or device mapper command and returns its output.
+func run(name string, args ...string) (string, error) {
+	o, err := exec.Command(name, args...).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("%v %v failed: %v: %s",
+			name, strings.Join(args, " "), err, strings.TrimSpace(string(o)))
+	}
+	return string(o), nil
+}
+
+// lvName returns the vg/lv name of a logical volume.
+func lvName(vg string, lv string) string {
+	return vg + "/" + lv
+}
+
+// dmName returns the device mapper name of a logical volume, which has the
+// dashes in the vg and lv names doubled.
+func dmName(vg string, lv string) string {
+	return strings.Replace(vg, "-", "--", -1) + "-" +
+		strings.Replace(lv, "-", "--", -1)
+}
+
+// lvSize returns the size of a logical volume and the bytes allocated to it
+// from its thin pool.
+func lvSize(vg string, lv string) (uint64, uint64, error) {
+	o, err := run(
+		"lvs",
+		"--noheadings",
+		"--nosuffix",
+		"--units", "b",
+		"-o", "lv_size,data_percent",
+		lvName(vg, lv),
+	)
+	if err != nil {
+		return 0, 0, err
+	}
+	fields := strings.Fields(o)
+	if len(fields) != 2 {
+		return 0, 0, fmt.Errorf("Unexpected lvs output for %v: %q",
+			lvName(vg, lv), o)
+	}
+	size, err := strconv.ParseUint(fields[0], 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	percent, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	return size, uint64(float64(size) * percent / 100), nil
+}
+
+// pvNames returns the physical volumes of a volume group.
+func pvNames(vg string) ([]string, error) {
+	o, err := run(
+		"pvs",
+		"--noheadings",
+		"-o", "pv_name",
+		"-S", "vg_name="+vg,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(o), nil
+}
+
+// parseDmStats sums the counters of all areas printed by dmsetup stats
+// print. Each line has the area followed by the /proc/diskstats counters.
+func parseDmStats(o string) (*api.Stats, error) {
+	stats := &api.Stats{}
+	for _, line := range strings.Split(o, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) < 12 || !strings.Contains(fields[0], "+") {
+			return nil, fmt.Errorf("Unexpected dmsetup stats output: %q", line)
+		}
+		counters := make([]uint64, 11)
+		for i := range counters {
+			c, err := strconv.ParseUint(fields[i+1], 10, 64)
+			if err != nil {
+				return nil, err
+			}
+			counters[i] = c
+		}
+		stats.Reads += counters[0]
+		stats.ReadBytes += counters[2] * 512
+		stats.ReadMs += counters[3]
+		stats.Writes += counters[4]
+		stats.WriteBytes += counters[6] * 512
+		stats.WriteMs += counters[7]
+		stats.IoProgress += counters[8]
+		stats.IoMs += counters[9]
+	}
+	return stats, nil
+}
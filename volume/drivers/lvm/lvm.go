@@ -0,0 +1,623 @@
+package lvm
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"go.pedge.io/dlog"
+
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/cluster"
+	"github.com/libopenstorage/openstorage/pkg/chaos"
+	"github.com/libopenstorage/openstorage/pkg/disk"
+	"github.com/libopenstorage/openstorage/volume"
+	"github.com/libopenstorage/openstorage/volume/drivers/common"
+	"github.com/pborman/uuid"
+	"github.com/portworx/kvdb"
+)
+
+const (
+	// Name of the driver
+	Name = "lvm"
+	// Type of the driver
+	Type = api.DriverType_DRIVER_TYPE_BLOCK
+	// VgParam is the Init param for the volume group
+	VgParam = "vg"
+	// PoolParam is the Init param for the thin pool in the volume group
+	PoolParam = "pool"
+	// DefaultPool is the thin pool used if none is configured
+	DefaultPool = "thinpool"
+	// poolInterval is how often the size of the thin pool is refreshed.
+	poolInterval = time.Minute
+)
+
+var (
+	koStrayCreate = chaos.Add("lvm", "create", "create logical volume before DB")
+	koStrayAttach = chaos.Add("lvm", "attach", "activate logical volume before DB")
+	koMount       = chaos.Add("lvm", "mount", "mount logical volume")
+	koStrayDelete = chaos.Add("lvm", "delete", "remove logical volume before DB")
+)
+
+// Implements the open storage volume interface with thin logical volumes
+// in a thin pool of an LVM volume group.
+type driver struct {
+	volume.IODriver
+	volume.StoreEnumerator
+	volume.StatsDriver
+	volume.ExportDriver
+	cluster.NullClusterListener
+	vg    string
+	pool  string
+	lock  sync.Mutex
+	stats map[string]*statsSample
+	// pools is the thin pool reported as a storage pool, refreshed in the
+	// background so that the heartbeat does not run the LVM commands.
+	pools []api.StoragePool
+	stop  chan struct{}
+}
+
+// statsSample is the last cumulative stats of a volume, from which the
+// stats of the following interval are computed.
+type statsSample struct {
+	stats *api.Stats
+	at    time.Time
+}
+
+// Init initializes the lvm driver for the thin pool params[PoolParam] in
+// the volume group params[VgParam].
+func Init(params map[string]string) (volume.VolumeDriver, error) {
+	vg, ok := params[VgParam]
+	if !ok {
+		return nil, fmt.Errorf("'vg' configuration parameter must be set")
+	}
+	pool, ok := params[PoolParam]
+	if !ok {
+		pool = DefaultPool
+	}
+	if _, _, err := lvSize(vg, pool); err != nil {
+		return nil, fmt.Errorf("Failed to locate thin pool %v: %v",
+			lvName(vg, pool), err)
+	}
+
+	inst := &driver{
+		IODriver:        volume.IONotSupported,
+		StoreEnumerator: common.NewDefaultStoreEnumerator(Name, kvdb.Instance()),
+		StatsDriver:     volume.StatsNotSupported,
+		ExportDriver:    volume.ExportNotSupported,
+		vg:              vg,
+		pool:            pool,
+		stats:           make(map[string]*statsSample),
+		stop:            make(chan struct{}),
+	}
+	inst.refreshPools()
+	go inst.watchPools()
+
+	volumeInfo, err := inst.StoreEnumerator.Enumerate(
+		&api.VolumeLocator{},
+		nil,
+	)
+	if err == nil {
+		for _, v := range volumeInfo {
+			if len(v.DevicePath) == 0 {
+				continue
+			}
+			if _, err := os.Stat(v.DevicePath); err == nil {
+				continue
+			}
+			dlog.Warnf("Device %v of volume %v is not active", v.DevicePath, v.Id)
+			v.DevicePath = ""
+			v.AttachPath = nil
+			v.State = api.VolumeState_VOLUME_STATE_AVAILABLE
+			if err := inst.UpdateVol(v); err != nil {
+				dlog.Warnf("Failed to update volume %v: %v", v.Id, err)
+			}
+		}
+	} else {
+		dlog.Println("Could not enumerate Volumes, ", err)
+	}
+
+	c, err := cluster.Inst()
+	if err != nil {
+		dlog.Println("LVM initializing in single node mode")
+	} else {
+		dlog.Println("LVM initializing in clustered mode")
+		c.AddEventListener(inst)
+	}
+
+	dlog.Infof("LVM initialized with thin pool %v", lvName(vg, pool))
+	return inst, nil
+}
+
+func (d *driver) devicePath(volumeID string) string {
+	return path.Join("/dev", d.vg, volumeID)
+}
+
+//
+// These functions below implement the volume driver interface.
+//
+
+func (d *driver) String() string {
+	return Name
+}
+
+func (d *driver) Name() string {
+	return Name
+}
+
+func (d *driver) Type() api.DriverType {
+	return Type
+}
+
+// Status diagnostic information
+func (d *driver) Status() [][2]string {
+	return [][2]string{
+		{"Volume Group", d.vg},
+		{"Thin Pool", d.pool},
+	}
+}
+
+func (d *driver) Create(
+	locator *api.VolumeLocator,
+	source *api.Source,
+	spec *api.VolumeSpec,
+) (string, error) {
+	return d.create(locator, source, spec, false)
+}
+
+// create creates a thin logical volume and formats it, or creates a thin
+// snapshot of source.Parent.
+func (d *driver) create(
+	locator *api.VolumeLocator,
+	source *api.Source,
+	spec *api.VolumeSpec,
+	readonly bool,
+) (string, error) {
+	if spec.Size == 0 {
+		return "", fmt.Errorf("Volume size cannot be zero: lvm")
+	}
+	volumeID := uuid.New()
+
+	if source != nil && len(source.Parent) != 0 {
+		permission := "rw"
+		if readonly {
+			permission = "r"
+		}
+		if _, err := run(
+			"lvcreate",
+			"-s",
+			"-p", permission,
+			"-n", volumeID,
+			lvName(d.vg, source.Parent),
+		); err != nil {
+			return "", err
+		}
+	} else {
+		if _, err := run(
+			"lvcreate",
+			"-V", fmt.Sprintf("%db", spec.Size),
+			"-T", lvName(d.vg, d.pool),
+			"-n", volumeID,
+		); err != nil {
+			return "", err
+		}
+		err := d.format(volumeID, spec.Format)
+		if _, e := run("lvchange", "-an", lvName(d.vg, volumeID)); e != nil {
+			dlog.Warnf("Failed to deactivate %v: %v", volumeID, e)
+		}
+		if err != nil {
+			run("lvremove", "-f", lvName(d.vg, volumeID))
+			return "", err
+		}
+	}
+
+	if err := chaos.Now(koStrayCreate); err != nil {
+		return "", err
+	}
+
+	v := common.NewVolume(
+		volumeID,
+		spec.Format,
+		locator,
+		source,
+		spec,
+	)
+	v.Readonly = readonly
+	if err := d.CreateVol(v); err != nil {
+		run("lvremove", "-f", lvName(d.vg, volumeID))
+		return "", err
+	}
+	return v.Id, nil
+}
+
+// format makes a filesystem on a new logical volume, which is active after
+// lvcreate.
+func (d *driver) format(volumeID string, format api.FSType) error {
+	if format == api.FSType_FS_TYPE_NONE {
+		return nil
+	}
+	dev := d.devicePath(volumeID)
+	dlog.Infof("Formatting %s with %v", dev, format)
+	_, err := run("/sbin/mkfs."+format.SimpleString(), dev)
+	return err
+}
+
+func (d *driver) Delete(volumeID string) error {
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		dlog.Println(err)
+		return err
+	}
+	if len(v.AttachPath) > 0 {
+		return fmt.Errorf("Volume %v is mounted at %v", volumeID, v.AttachPath)
+	}
+	if len(v.DevicePath) != 0 {
+		d.deleteStats(volumeID)
+	}
+	if _, err := run("lvremove", "-f", lvName(d.vg, volumeID)); err != nil {
+		return err
+	}
+	if err := chaos.Now(koStrayDelete); err != nil {
+		return err
+	}
+	return d.DeleteVol(volumeID)
+}
+
+func (d *driver) MountedAt(mountpath string) string {
+	return ""
+}
+
+func (d *driver) Mount(volumeID string, mountpath string) error {
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		return fmt.Errorf("Failed to locate volume %q", volumeID)
+	}
+	if err := common.CheckAccess(
+		v,
+		mountpath,
+		api.AccessMode_ACCESS_MODE_READ_WRITE_ONCE,
+	); err != nil {
+		return err
+	}
+	if len(v.DevicePath) == 0 {
+		return volume.ErrVolDetached
+	}
+	if err := chaos.Now(koMount); err != nil {
+		return err
+	}
+	flags, data := common.MountFlags(v)
+	if err := syscall.Mount(
+		v.DevicePath,
+		mountpath,
+		v.Spec.Format.SimpleString(),
+		flags,
+		data,
+	); err != nil {
+		return fmt.Errorf("Failed to mount %v at %v: %v", v.DevicePath, mountpath, err)
+	}
+	common.AddAttachPath(v, mountpath)
+	return d.UpdateVol(v)
+}
+
+func (d *driver) Unmount(volumeID string, mountpath string) error {
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		return err
+	}
+	if len(v.AttachPath) == 0 || len(v.AttachPath[0]) == 0 {
+		return fmt.Errorf("Device %v not mounted", volumeID)
+	}
+	if len(mountpath) == 0 {
+		mountpath = v.AttachPath[0]
+	}
+	if err := syscall.Unmount(mountpath, 0); err != nil {
+		return err
+	}
+	common.RemoveAttachPath(v, mountpath)
+	return d.UpdateVol(v)
+}
+
+// Snapshot creates a thin snapshot, which shares its blocks with the
+// volume until either is written.
+func (d *driver) Snapshot(volumeID string, readonly bool, locator *api.VolumeLocator) (string, error) {
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		return "", err
+	}
+	return d.create(locator, &api.Source{Parent: volumeID}, v.Spec, readonly)
+}
+
+// Set updates the locator and grows the volume and its filesystem to
+// spec.Size.
+func (d *driver) Set(volumeID string, locator *api.VolumeLocator, spec *api.VolumeSpec) error {
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		return err
+	}
+	if locator != nil {
+		v.Locator = locator
+	}
+	if spec != nil && spec.Size != 0 && spec.Size != v.Spec.Size {
+		if err := d.resize(v, spec.Size); err != nil {
+			return err
+		}
+	}
+	return d.UpdateVol(v)
+}
+
+func (d *driver) resize(v *api.Volume, size uint64) error {
+	if size < v.Spec.Size {
+		return fmt.Errorf("Volume %v cannot shrink from %v to %v",
+			v.Id, v.Spec.Size, size)
+	}
+	if v.Spec.Format == api.FSType_FS_TYPE_XFS && len(v.AttachPath) == 0 {
+		return fmt.Errorf("Volume %v must be mounted to grow xfs", v.Id)
+	}
+	if _, err := run(
+		"lvextend",
+		"-L", fmt.Sprintf("%db", size),
+		lvName(d.vg, v.Id),
+	); err != nil {
+		return err
+	}
+	dlog.Infof("LVM volume %v resized from %v to %v", v.Id, v.Spec.Size, size)
+	v.Spec.Size = size
+	return d.growFs(v)
+}
+
+// growFs grows the filesystem of a volume to the size of its logical
+// volume. Ext4 filesystems that are not mounted are checked first, xfs can
+// only be grown while mounted.
+func (d *driver) growFs(v *api.Volume) error {
+	switch v.Spec.Format {
+	case api.FSType_FS_TYPE_EXT4:
+		dev := d.devicePath(v.Id)
+		if len(v.DevicePath) == 0 {
+			if _, err := run("lvchange", "-ay", "-K", lvName(d.vg, v.Id)); err != nil {
+				return err
+			}
+			defer run("lvchange", "-an", lvName(d.vg, v.Id))
+		}
+		if len(v.AttachPath) == 0 {
+			// e2fsck exits with 1 if it corrected errors.
+			err := exec.Command("e2fsck", "-f", "-p", dev).Run()
+			if e, ok := err.(*exec.ExitError); ok &&
+				e.Sys().(syscall.WaitStatus).ExitStatus() > 1 {
+				return fmt.Errorf("Failed to check %v: %v", dev, err)
+			}
+		}
+		_, err := run("resize2fs", dev)
+		return err
+	case api.FSType_FS_TYPE_XFS:
+		_, err := run("xfs_growfs", v.AttachPath[0])
+		return err
+	}
+	return nil
+}
+
+func (d *driver) Attach(volumeID string, attachOptions map[string]string) (string, error) {
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		return "", err
+	}
+	if len(v.DevicePath) != 0 {
+		return v.DevicePath, nil
+	}
+	// Thin snapshots are created with the activation skip flag.
+	if _, err := run("lvchange", "-ay", "-K", lvName(d.vg, volumeID)); err != nil {
+		return "", err
+	}
+	if _, err := run("dmsetup", "stats", "create", dmName(d.vg, volumeID)); err != nil {
+		dlog.Warnf("Failed to create stats for volume %v: %v", volumeID, err)
+	}
+	if err := chaos.Now(koStrayAttach); err != nil {
+		return "", err
+	}
+	v.DevicePath = d.devicePath(volumeID)
+	v.State = api.VolumeState_VOLUME_STATE_ATTACHED
+	if err := d.UpdateVol(v); err != nil {
+		run("lvchange", "-an", lvName(d.vg, volumeID))
+		return "", err
+	}
+	return v.DevicePath, nil
+}
+
+func (d *driver) Detach(volumeID string) error {
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		return err
+	}
+	if len(v.DevicePath) == 0 {
+		return nil
+	}
+	if len(v.AttachPath) > 0 {
+		return fmt.Errorf("Volume %v is mounted at %v", volumeID, v.AttachPath)
+	}
+	d.deleteStats(volumeID)
+	if _, err := run("lvchange", "-an", lvName(d.vg, volumeID)); err != nil {
+		return err
+	}
+	v.DevicePath = ""
+	v.State = api.VolumeState_VOLUME_STATE_AVAILABLE
+	return d.UpdateVol(v)
+}
+
+func (d *driver) deleteStats(volumeID string) {
+	d.lock.Lock()
+	delete(d.stats, volumeID)
+	d.lock.Unlock()
+	run(
+		"dmsetup",
+		"stats",
+		"delete",
+		"--allprograms",
+		"--allregions",
+		dmName(d.vg, volumeID),
+	)
+}
+
+// Stats returns the I/O statistics that device mapper collects for an
+// attached volume.
+func (d *driver) Stats(volumeID string, cumulative bool) (*api.Stats, error) {
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		return nil, err
+	}
+	if len(v.DevicePath) == 0 {
+		return nil, volume.ErrVolDetached
+	}
+	o, err := run(
+		"dmsetup",
+		"stats",
+		"print",
+		"--allprograms",
+		"--allregions",
+		dmName(d.vg, volumeID),
+	)
+	if err != nil {
+		return nil, err
+	}
+	stats, err := parseDmStats(o)
+	if err != nil {
+		return nil, err
+	}
+	if _, stats.BytesUsed, err = lvSize(d.vg, volumeID); err != nil {
+		return nil, err
+	}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	now := time.Now()
+	last, ok := d.stats[volumeID]
+	d.stats[volumeID] = &statsSample{stats: stats, at: now}
+	if cumulative || !ok {
+		return stats, nil
+	}
+	return &api.Stats{
+		Reads:      stats.Reads - last.stats.Reads,
+		ReadMs:     stats.ReadMs - last.stats.ReadMs,
+		ReadBytes:  stats.ReadBytes - last.stats.ReadBytes,
+		Writes:     stats.Writes - last.stats.Writes,
+		WriteMs:    stats.WriteMs - last.stats.WriteMs,
+		WriteBytes: stats.WriteBytes - last.stats.WriteBytes,
+		IoProgress: stats.IoProgress,
+		IoMs:       stats.IoMs - last.stats.IoMs,
+		BytesUsed:  stats.BytesUsed,
+		IntervalMs: uint64(now.Sub(last.at) / time.Millisecond),
+	}, nil
+}
+
+// UsedSize returns the bytes allocated to a volume from the thin pool.
+func (d *driver) UsedSize(volumeID string) (uint64, error) {
+	if _, err := d.GetVol(volumeID); err != nil {
+		return 0, err
+	}
+	_, used, err := lvSize(d.vg, volumeID)
+	return used, err
+}
+
+// Fstrim trims the filesystem of a mounted volume, which returns the
+// discarded blocks to the thin pool.
+func (d *driver) Fstrim(volumeID string) (uint64, error) {
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		return 0, err
+	}
+	if len(v.DevicePath) == 0 {
+		return 0, volume.ErrVolDetached
+	}
+	if len(v.AttachPath) == 0 {
+		return 0, fmt.Errorf("Volume %q is not mounted", volumeID)
+	}
+	return common.Fstrim(v.AttachPath[0])
+}
+
+func (d *driver) Shutdown() {
+	dlog.Printf("%s Shutting down", Name)
+	close(d.stop)
+}
+
+// ListenerPools reports the thin pool as a storage pool of this node.
+func (d *driver) ListenerPools() []api.StoragePool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return append([]api.StoragePool(nil), d.pools...)
+}
+
+// watchPools refreshes the storage pool at every poolInterval.
+func (d *driver) watchPools() {
+	ticker := time.NewTicker(poolInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-d.stop:
+			return
+		case <-ticker.C:
+			d.refreshPools()
+		}
+	}
+}
+
+// refreshPools reads the size and the medium of the thin pool. The pool
+// has the class of service of its medium.
+func (d *driver) refreshPools() {
+	size, used, err := lvSize(d.vg, d.pool)
+	if err != nil {
+		dlog.Warnf("Failed to get the size of thin pool %v: %v",
+			lvName(d.vg, d.pool), err)
+		return
+	}
+	medium := d.medium()
+	pools := []api.StoragePool{
+		{
+			Cos:       disk.Cos(medium),
+			Medium:    medium,
+			TotalSize: size,
+			Used:      used,
+		},
+	}
+	d.lock.Lock()
+	d.pools = pools
+	d.lock.Unlock()
+}
+
+// medium returns the storage medium of the physical volumes of the volume
+// group. A volume group with any rotational disk is magnetic.
+func (d *driver) medium() api.StorageMedium {
+	pvs, err := pvNames(d.vg)
+	if err != nil || len(pvs) == 0 {
+		return api.StorageMedium_STORAGE_MEDIUM_MAGNETIC
+	}
+	nvme := true
+	for _, pv := range pvs {
+		name := filepath.Base(pv)
+		if rotational(name) {
+			return api.StorageMedium_STORAGE_MEDIUM_MAGNETIC
+		}
+		nvme = nvme && strings.HasPrefix(name, "nvme")
+	}
+	if nvme {
+		return api.StorageMedium_STORAGE_MEDIUM_NVME
+	}
+	return api.StorageMedium_STORAGE_MEDIUM_SSD
+}
+
+// rotational returns true unless the kernel reports the block device name,
+// or the disk of the partition name, as non rotational.
+func rotational(name string) bool {
+	sys := filepath.Join("/sys/class/block", name)
+	b, err := ioutil.ReadFile(filepath.Join(sys, "queue", "rotational"))
+	if os.IsNotExist(err) {
+		if sys, err = filepath.EvalSymlinks(sys); err == nil {
+			b, err = ioutil.ReadFile(filepath.Join(sys, "..", "queue", "rotational"))
+		}
+	}
+	return err != nil || strings.TrimSpace(string(b)) != "0"
+}
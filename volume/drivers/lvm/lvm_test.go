@@ -0,0 +1,125 @@
+package lvm
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/volume/drivers/test"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testVg   = "osd_test_vg"
+	testPool = "osd_test_pool"
+)
+
+// setupVg creates a volume group with a thin pool on a loop device backed by
+// a sparse file and returns a function that removes it.
+func setupVg(t *testing.T) func() {
+	if os.Geteuid() != 0 {
+		t.Skip("LVM tests must run as root")
+	}
+	for _, cmd := range []string{"losetup", "pvcreate", "vgcreate", "lvcreate"} {
+		if _, err := exec.LookPath(cmd); err != nil {
+			t.Skipf("LVM tests need %v", cmd)
+		}
+	}
+	f, err := ioutil.TempFile("", "lvm")
+	require.NoError(t, err)
+	require.NoError(t, f.Truncate(4*1024*1024*1024))
+	require.NoError(t, f.Close())
+	o, err := run("losetup", "-f", "--show", f.Name())
+	require.NoError(t, err)
+	dev := strings.TrimSpace(o)
+
+	cleanup := func() {
+		run("vgremove", "-f", testVg)
+		run("pvremove", "-f", dev)
+		run("losetup", "-d", dev)
+		os.Remove(f.Name())
+	}
+	for _, args := range [][]string{
+		{"pvcreate", "-f", dev},
+		{"vgcreate", testVg, dev},
+		{"lvcreate", "-T", "-L", "3g", lvName(testVg, testPool)},
+	} {
+		if _, err := run(args[0], args[1:]...); err != nil {
+			cleanup()
+			t.Fatal(err)
+		}
+	}
+	return cleanup
+}
+
+func TestAll(t *testing.T) {
+	defer setupVg(t)()
+
+	d, err := Init(map[string]string{VgParam: testVg, PoolParam: testPool})
+	if err != nil {
+		t.Fatalf("Failed to initialize Volume Driver: %v", err)
+	}
+	ctx := test.NewContext(d)
+	ctx.Filesystem = api.FSType_FS_TYPE_EXT4
+
+	test.Run(t, ctx)
+
+	pools := d.(*driver).ListenerPools()
+	require.Len(t, pools, 1)
+	require.NotZero(t, pools[0].TotalSize)
+	require.NotEqual(t, api.CosType_NONE, pools[0].Cos)
+}
+
+func TestResize(t *testing.T) {
+	defer setupVg(t)()
+
+	d, err := Init(map[string]string{VgParam: testVg, PoolParam: testPool})
+	require.NoError(t, err)
+	volumeID, err := d.Create(
+		&api.VolumeLocator{Name: "resize"},
+		nil,
+		&api.VolumeSpec{Size: 64 * 1024 * 1024, Format: api.FSType_FS_TYPE_EXT4},
+	)
+	require.NoError(t, err)
+	defer d.Delete(volumeID)
+
+	require.NoError(t, d.Set(volumeID, nil, &api.VolumeSpec{Size: 128 * 1024 * 1024}))
+	size, _, err := lvSize(testVg, volumeID)
+	require.NoError(t, err)
+	require.Equal(t, uint64(128*1024*1024), size)
+	require.Error(t, d.Set(volumeID, nil, &api.VolumeSpec{Size: 1024 * 1024}))
+
+	_, err = d.Attach(volumeID, nil)
+	require.NoError(t, err)
+	defer d.Detach(volumeID)
+	stats, err := d.Stats(volumeID, true)
+	require.NoError(t, err)
+	require.NotZero(t, stats.BytesUsed)
+}
+
+func TestDmName(t *testing.T) {
+	require.Equal(t, "vg--a-lv--b--c", dmName("vg-a", "lv-b-c"))
+}
+
+func TestParseDmStats(t *testing.T) {
+	area := "%d+1024 10 0 80 3 20 0 160 5 1 7 9 0 0\n"
+	stats, err := parseDmStats(fmt.Sprintf(area, 0) + fmt.Sprintf(area, 1024))
+	require.NoError(t, err)
+	require.Equal(t, &api.Stats{
+		Reads:      20,
+		ReadBytes:  2 * 80 * 512,
+		ReadMs:     6,
+		Writes:     40,
+		WriteBytes: 2 * 160 * 512,
+		WriteMs:    10,
+		IoProgress: 2,
+		IoMs:       14,
+	}, stats)
+
+	_, err = parseDmStats("bogus")
+	require.Error(t, err)
+}
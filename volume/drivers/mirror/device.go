@@ -0,0 +1,208 @@
+package mirror
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"go.pedge.io/dlog"
+
+	"github.com/libopenstorage/openstorage/volume/drivers/buse"
+)
+
+// regionSize is the unit of the comparison and copy of a resync.
+const regionSize = 1 << 20
+
+// mirrorDev is the device of a volume on this node. It implements the buse
+// Device, Syncer and Discarder interfaces on the local replica and mirrors
+// the writes to the peers of the other replicas before they complete.
+type mirrorDev struct {
+	volumeID string
+	size     int64
+	f        *os.File
+	nbd      *buse.NBD
+	// fail is called with the node of a peer that failed a write, which
+	// no longer receives the writes. The write fails if fail does.
+	fail func(node string) error
+	// lock is held for reading by the writes and for writing by the copy
+	// of a region to a resyncing peer, which then misses no write.
+	lock      sync.RWMutex
+	peersLock sync.Mutex
+	peers     map[string]*peer
+	// epoch of the membership the volume was attached in, which the
+	// writes are sent in.
+	epoch uint64
+}
+
+func (m *mirrorDev) ReadAt(b []byte, off int64) (n int, err error) {
+	return m.f.ReadAt(b, off)
+}
+
+func (m *mirrorDev) WriteAt(b []byte, off int64) (n int, err error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	if err := m.mirror(
+		func() error {
+			_, err := m.f.WriteAt(b, off)
+			return err
+		},
+		func(p *peer) error {
+			return p.write(m.volumeID, m.currentEpoch(), b, off)
+		},
+	); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// Sync commits the replicas to stable storage.
+func (m *mirrorDev) Sync() error {
+	return m.mirror(
+		m.f.Sync,
+		func(p *peer) error {
+			return p.sync(m.volumeID, m.currentEpoch())
+		},
+	)
+}
+
+// Discard punches a hole in the replicas.
+func (m *mirrorDev) Discard(off int64, length int64) error {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	return m.mirror(
+		func() error {
+			return punchHole(m.f, off, length)
+		},
+		func(p *peer) error {
+			return p.discard(m.volumeID, m.currentEpoch(), off, length)
+		},
+	)
+}
+
+// mirror runs an operation on the local replica and on the peers in
+// parallel. Peers that fail are dropped and reported to fail, the operation
+// only fails if the local replica does or if fail does.
+func (m *mirrorDev) mirror(local func() error, remote func(p *peer) error) error {
+	peers := m.targets()
+	errs := make([]error, len(peers))
+	var wg sync.WaitGroup
+	for i, p := range peers {
+		wg.Add(1)
+		go func(i int, p *peer) {
+			defer wg.Done()
+			errs[i] = remote(p)
+		}(i, p)
+	}
+	err := local()
+	wg.Wait()
+	if err != nil {
+		return err
+	}
+	for i, p := range peers {
+		if errs[i] == nil {
+			continue
+		}
+		dlog.Warnf("Failed to mirror volume %v to node %v: %v",
+			m.volumeID, p.node, errs[i])
+		m.drop(p.node)
+		if err := m.fail(p.node); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// currentEpoch returns the epoch the writes are sent in.
+func (m *mirrorDev) currentEpoch() uint64 {
+	m.peersLock.Lock()
+	defer m.peersLock.Unlock()
+	return m.epoch
+}
+
+// setEpoch sends the writes in epoch from now on.
+func (m *mirrorDev) setEpoch(epoch uint64) {
+	m.peersLock.Lock()
+	defer m.peersLock.Unlock()
+	m.epoch = epoch
+}
+
+// targets returns the peers that receive the writes.
+func (m *mirrorDev) targets() []*peer {
+	m.peersLock.Lock()
+	defer m.peersLock.Unlock()
+	peers := make([]*peer, 0, len(m.peers))
+	for _, p := range m.peers {
+		peers = append(peers, p)
+	}
+	return peers
+}
+
+// add makes a peer receive the writes.
+func (m *mirrorDev) add(p *peer) {
+	m.peersLock.Lock()
+	defer m.peersLock.Unlock()
+	m.peers[p.node] = p
+}
+
+// drop stops sending the writes to the peer of node.
+func (m *mirrorDev) drop(node string) {
+	m.peersLock.Lock()
+	defer m.peersLock.Unlock()
+	delete(m.peers, node)
+}
+
+// has returns true if the peer of node receives the writes.
+func (m *mirrorDev) has(node string) bool {
+	m.peersLock.Lock()
+	defer m.peersLock.Unlock()
+	_, ok := m.peers[node]
+	return ok
+}
+
+// resync copies the regions of the local replica that differ on a peer in
+// epoch. The peer receives the writes from the start so that it is in sync
+// at the end. It returns the number of bytes copied.
+func (m *mirrorDev) resync(p *peer, epoch uint64) (uint64, error) {
+	m.add(p)
+	var copied uint64
+	for off := int64(0); off < m.size; off += regionSize {
+		n, err := m.resyncRegion(p, epoch, off)
+		if err != nil {
+			m.drop(p.node)
+			return copied, err
+		}
+		copied += n
+	}
+	if !m.has(p.node) {
+		return copied, fmt.Errorf("Node %v failed a write during the resync", p.node)
+	}
+	return copied, nil
+}
+
+// resyncRegion copies a region to a peer if its checksums differ. Writes
+// wait for the copy.
+func (m *mirrorDev) resyncRegion(p *peer, epoch uint64, off int64) (uint64, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	length := int64(regionSize)
+	if rest := m.size - off; rest < length {
+		length = rest
+	}
+	b := make([]byte, length)
+	if _, err := m.f.ReadAt(b, off); err != nil && err != io.EOF {
+		return 0, err
+	}
+	sum, err := p.sum(m.volumeID, epoch, off, len(b))
+	if err != nil {
+		return 0, err
+	}
+	if bytes.Equal(sum, checksum(b)) {
+		return 0, nil
+	}
+	if err := p.write(m.volumeID, epoch, b, off); err != nil {
+		return 0, err
+	}
+	return uint64(len(b)), nil
+}
@@ -0,0 +1,156 @@
+package mirror
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/portworx/kvdb"
+)
+
+const (
+	// nodesKey is the kvdb prefix of the replication servers of the nodes.
+	nodesKey = "mirror/nodes/"
+	// replicasKey is the kvdb prefix of the replicas of the volumes.
+	replicasKey = "mirror/replicas/"
+	// locksKey is the kvdb prefix of the locks of the replicas.
+	locksKey = "mirror/locks/"
+	// secretKey is the kvdb key of the secret of the cluster, unless it is
+	// configured.
+	secretKey = "mirror/secret"
+)
+
+// nodeInfo is the replication server of a node.
+type nodeInfo struct {
+	Addr string `json:"addr"`
+}
+
+// secretInfo is the secret the replication servers authenticate the nodes
+// with.
+type secretInfo struct {
+	Secret string `json:"secret"`
+}
+
+// Replica of a volume on a node.
+type Replica struct {
+	Node string `json:"node"`
+	// Stale replicas missed writes. They are resynced from a replica in
+	// sync and receive no reads until then.
+	Stale bool `json:"stale,omitempty"`
+}
+
+// Membership of the replicas of a volume.
+type Membership struct {
+	VolumeID string    `json:"volume_id"`
+	Replicas []Replica `json:"replicas"`
+	// Primary is the node the volume is attached on, which serves its I/O
+	// and mirrors the writes to the other replicas.
+	Primary string `json:"primary,omitempty"`
+	// Epoch is incremented every time the primary changes. The requests to
+	// the replicas carry the epoch of their sender, and the replicas reject
+	// the requests of an older epoch than the newest they have seen.
+	Epoch uint64 `json:"epoch,omitempty"`
+}
+
+// setPrimary makes node the primary, or none if node is empty, and starts a
+// new epoch if it changes.
+func (m *Membership) setPrimary(node string) {
+	if m.Primary != node {
+		m.Primary = node
+		m.Epoch++
+	}
+}
+
+// replica returns the replica on node, nil if there is none.
+func (m *Membership) replica(node string) *Replica {
+	for i := range m.Replicas {
+		if m.Replicas[i].Node == node {
+			return &m.Replicas[i]
+		}
+	}
+	return nil
+}
+
+// nodes returns the nodes of the replicas.
+func (m *Membership) nodes() []string {
+	nodes := make([]string, 0, len(m.Replicas))
+	for _, r := range m.Replicas {
+		nodes = append(nodes, r.Node)
+	}
+	return nodes
+}
+
+// inSync returns the nodes of the replicas in sync, except node.
+func (m *Membership) inSync(except string) []string {
+	var nodes []string
+	for _, r := range m.Replicas {
+		if !r.Stale && r.Node != except {
+			nodes = append(nodes, r.Node)
+		}
+	}
+	return nodes
+}
+
+// source returns the node that resyncs the stale replicas: the primary if
+// the volume is attached, or else the first replica in sync.
+func (m *Membership) source() string {
+	if len(m.Primary) != 0 {
+		return m.Primary
+	}
+	if nodes := m.inSync(""); len(nodes) != 0 {
+		return nodes[0]
+	}
+	return ""
+}
+
+// membership returns the membership of the replicas of a volume.
+func (d *driver) membership(volumeID string) (*Membership, error) {
+	var m Membership
+	if _, err := d.kv.GetVal(replicasKey+volumeID, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// memberships returns the memberships of the replicas of all volumes.
+func (d *driver) memberships() ([]*Membership, error) {
+	kvps, err := d.kv.Enumerate(replicasKey)
+	if err != nil {
+		return nil, err
+	}
+	sets := make([]*Membership, 0, len(kvps))
+	for _, kvp := range kvps {
+		var m Membership
+		if err := json.Unmarshal(kvp.Value, &m); err != nil {
+			return nil, fmt.Errorf("Failed to decode replicas %v: %v", kvp.Key, err)
+		}
+		sets = append(sets, &m)
+	}
+	return sets, nil
+}
+
+// updateMembership applies update to the membership of the replicas of a
+// volume under its lock, so that the nodes of the cluster see and make the
+// changes in order. Nothing is changed if update returns an error.
+func (d *driver) updateMembership(
+	volumeID string,
+	update func(m *Membership) error,
+) (*Membership, error) {
+	lock, err := d.kv.Lock(locksKey + volumeID)
+	if err != nil {
+		return nil, err
+	}
+	defer d.kv.Unlock(lock)
+	m, err := d.membership(volumeID)
+	if err == kvdb.ErrNotFound {
+		return nil, fmt.Errorf("Volume %v has no replicas", volumeID)
+	} else if err != nil {
+		return nil, err
+	}
+	if err := update(m); err != nil {
+		return nil, err
+	}
+	if _, err := d.kv.Put(replicasKey+volumeID, m, 0); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
@@ -0,0 +1,1203 @@
+package mirror
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"sync"
+	"syscall"
+	"time"
+
+	"go.pedge.io/dlog"
+
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/cluster"
+	"github.com/libopenstorage/openstorage/pkg/chaos"
+	"github.com/libopenstorage/openstorage/pkg/device"
+	"github.com/libopenstorage/openstorage/pkg/disk"
+	"github.com/libopenstorage/openstorage/pkg/placement"
+	"github.com/libopenstorage/openstorage/pkg/units"
+	"github.com/libopenstorage/openstorage/volume"
+	"github.com/libopenstorage/openstorage/volume/drivers/buse"
+	"github.com/libopenstorage/openstorage/volume/drivers/common"
+	"github.com/pborman/uuid"
+	"github.com/portworx/kvdb"
+)
+
+const (
+	// Name of the driver
+	Name = "mirror"
+	// Type of the driver
+	Type = api.DriverType_DRIVER_TYPE_CLUSTERED
+	// RootParam is the Init param for the directory of the replicas
+	RootParam = "root"
+	// PortParam is the Init param for the port of the replication server
+	PortParam = "port"
+	// SecretParam is the Init param for the secret the replication servers
+	// authenticate the nodes with. It must be the same on all nodes.
+	SecretParam = "secret"
+	// DefaultRoot is the directory of the replicas if none is configured
+	DefaultRoot = "/var/lib/openstorage/mirror"
+	// DefaultPort is the port of the replication server if none is configured
+	DefaultPort = "9010"
+)
+
+var (
+	koStrayCreate = chaos.Add("mirror", "create", "create replicas before DB")
+	koStrayAttach = chaos.Add("mirror", "attach", "connect NBD device before DB")
+	koMount       = chaos.Add("mirror", "mount", "mount NBD device")
+	koStrayDelete = chaos.Add("mirror", "delete", "remove replicas before DB")
+
+	// resyncInterval is how often the stale replicas are resynced, in case
+	// their node failed a write without going down.
+	resyncInterval = 30 * time.Second
+
+	errNotJoined = errors.New("Node has not joined the cluster")
+)
+
+// Implements the open storage volume interface with volumes whose data is
+// replicated on HaLevel nodes. A volume is attached through NBD on a node
+// with a replica, which mirrors the writes to the other replicas.
+type driver struct {
+	volume.IODriver
+	volume.StoreEnumerator
+	volume.StatsDriver
+	volume.ExportDriver
+	volume.SnapshotDriver
+	cluster.NullClusterListener
+	kv       kvdb.Kvdb
+	port     string
+	secret   []byte
+	replicas *store
+	// medium of the disk of the replicas.
+	medium  api.StorageMedium
+	devices *device.Allocator
+	// place picks the nodes of the replicas of a volume, which already has
+	// replicas on the placed nodes.
+	place   func(spec *api.VolumeSpec, placed []string) (*placement.Placement, error)
+	lock    sync.Mutex
+	self    string
+	server  *server
+	stop    chan struct{}
+	peers   map[string]*peer
+	mirrors map[string]*mirrorDev
+	resyncs map[string]bool
+}
+
+// Init initializes the mirror driver, which stores replicas in
+// params[RootParam] and serves them to the other nodes on params[PortParam]
+// of the data interface. The nodes authenticate with params[SecretParam], or
+// with a random secret shared in kvdb if it is not set. It requires cluster
+// mode.
+func Init(params map[string]string) (volume.VolumeDriver, error) {
+	c, err := cluster.Inst()
+	if err != nil {
+		return nil, fmt.Errorf("Mirror driver requires cluster mode: %v", err)
+	}
+
+	d, err := newDriver(params, kvdb.Instance())
+	if err != nil {
+		return nil, err
+	}
+	if d.devices, err = buse.NBDAllocator(); err != nil {
+		return nil, err
+	}
+	d.place = func(spec *api.VolumeSpec, placed []string) (*placement.Placement, error) {
+		return c.Place(spec, placed, "")
+	}
+	c.AddEventListener(d)
+
+	dlog.Infof("Mirror initialized with replicas in %v", d.replicas.root)
+	return d, nil
+}
+
+func newDriver(params map[string]string, kv kvdb.Kvdb) (*driver, error) {
+	root, ok := params[RootParam]
+	if !ok {
+		root = DefaultRoot
+	}
+	port, ok := params[PortParam]
+	if !ok {
+		port = DefaultPort
+	}
+	if err := os.MkdirAll(root, 0744); err != nil {
+		return nil, err
+	}
+	secret, err := clusterSecret(params, kv)
+	if err != nil {
+		return nil, err
+	}
+	return &driver{
+		IODriver:        volume.IONotSupported,
+		StoreEnumerator: common.NewDefaultStoreEnumerator(Name, kv),
+		StatsDriver:     volume.StatsNotSupported,
+		ExportDriver:    volume.ExportNotSupported,
+		SnapshotDriver:  volume.SnapshotNotSupported,
+		kv:              kv,
+		port:            port,
+		secret:          secret,
+		replicas:        newStore(root),
+		medium:          disk.MediumOf(root),
+		peers:           make(map[string]*peer),
+		mirrors:         make(map[string]*mirrorDev),
+		resyncs:         make(map[string]bool),
+	}, nil
+}
+
+// clusterSecret returns params[SecretParam], or else the secret in kvdb,
+// which the first node to start generates.
+func clusterSecret(params map[string]string, kv kvdb.Kvdb) ([]byte, error) {
+	if secret, ok := params[SecretParam]; ok {
+		if len(secret) == 0 {
+			return nil, fmt.Errorf("Param %v is empty", SecretParam)
+		}
+		return []byte(secret), nil
+	}
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	info := secretInfo{Secret: hex.EncodeToString(b)}
+	if _, err := kv.Create(secretKey, &info, 0); err == kvdb.ErrExist {
+		if _, err := kv.GetVal(secretKey, &info); err != nil {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, err
+	}
+	return []byte(info.Secret), nil
+}
+
+// start serves the replicas of this node on its data interface and publishes
+// the address of the server for the other nodes.
+func (d *driver) start(self *api.Node) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if d.server != nil {
+		return nil
+	}
+	s, err := listen(net.JoinHostPort(self.DataIp, d.port), d.replicas, d.secret)
+	if err != nil {
+		return err
+	}
+	if _, err := d.kv.Put(nodesKey+self.Id, &nodeInfo{Addr: s.addr()}, 0); err != nil {
+		s.close()
+		return err
+	}
+	d.self = self.Id
+	d.server = s
+	d.stop = make(chan struct{})
+	go d.watch(d.stop)
+	dlog.Infof("Mirror serving replicas of node %v on %v", self.Id, s.addr())
+	return nil
+}
+
+// halt stops the replication server.
+func (d *driver) halt() {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if d.server == nil {
+		return
+	}
+	close(d.stop)
+	d.server.close()
+	d.server = nil
+	for node, p := range d.peers {
+		p.close()
+		delete(d.peers, node)
+	}
+}
+
+// watch resyncs the stale replicas periodically until stop is closed.
+func (d *driver) watch(stop chan struct{}) {
+	ticker := time.NewTicker(resyncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			d.resyncStale("")
+		}
+	}
+}
+
+func (d *driver) selfID() string {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return d.self
+}
+
+// recover detaches the volumes that were attached on this node before a
+// restart. Their NBD devices were disconnected by InitNBD, so any mounts of
+// the devices are stale, and the volumes may be attached on another node
+// since.
+func (d *driver) recover() {
+	self := d.selfID()
+	vols, err := d.StoreEnumerator.Enumerate(&api.VolumeLocator{}, nil)
+	if err != nil {
+		dlog.Warnf("Failed to enumerate mirror volumes: %v", err)
+		return
+	}
+	for _, v := range vols {
+		if v.AttachedOn != self || len(v.DevicePath) == 0 {
+			continue
+		}
+		for _, p := range v.AttachPath {
+			if len(p) != 0 {
+				dlog.Infof("Detaching stale mirror mount %v of volume %v", p, v.Id)
+				syscall.Unmount(p, syscall.MNT_DETACH)
+			}
+		}
+		if d.devices != nil {
+			d.devices.Release(v.DevicePath)
+		}
+		v.AttachPath = nil
+		v.DevicePath = ""
+		v.AttachedOn = ""
+		v.State = api.VolumeState_VOLUME_STATE_AVAILABLE
+		if err := d.UpdateVol(v); err != nil {
+			dlog.Warnf("Failed to update mirror volume %v: %v", v.Id, err)
+			continue
+		}
+		d.release(v.Id)
+	}
+}
+
+// removeOrphans removes the replicas of this node that are no longer members
+// of their volume, which was deleted or moved while the node was down.
+func (d *driver) removeOrphans() {
+	ids, err := d.replicas.list()
+	if err != nil {
+		dlog.Warnf("Failed to list mirror replicas: %v", err)
+		return
+	}
+	self := d.selfID()
+	for _, id := range ids {
+		m, err := d.membership(id)
+		if err == nil && m.replica(self) != nil {
+			continue
+		} else if err != nil && err != kvdb.ErrNotFound {
+			continue
+		}
+		dlog.Infof("Removing orphan replica of volume %v", id)
+		d.replicas.remove(id)
+	}
+}
+
+// peer returns the connection to the replication server of a node. Its
+// address is looked up every time, in case the node restarted.
+func (d *driver) peer(node string) (*peer, error) {
+	var info nodeInfo
+	if _, err := d.kv.GetVal(nodesKey+node, &info); err != nil {
+		return nil, fmt.Errorf("Cannot locate the replication server of node %v: %v",
+			node, err)
+	}
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	p, ok := d.peers[node]
+	if ok && p.addr == info.Addr {
+		return p, nil
+	}
+	if ok {
+		p.close()
+	}
+	p = newPeer(node, info.Addr, d.secret)
+	d.peers[node] = p
+	return p, nil
+}
+
+// createReplica creates the replica of a volume on node in epoch.
+func (d *driver) createReplica(node string, volumeID string, epoch uint64, size uint64) error {
+	if node == d.selfID() {
+		return d.replicas.create(volumeID, int64(size))
+	}
+	p, err := d.peer(node)
+	if err != nil {
+		return err
+	}
+	return p.create(volumeID, epoch, int64(size))
+}
+
+// removeReplica removes the replica of a volume on node in epoch.
+func (d *driver) removeReplica(node string, volumeID string, epoch uint64) error {
+	if node == d.selfID() {
+		d.closeDev(volumeID)
+		return d.replicas.remove(volumeID)
+	}
+	d.dropPeer(volumeID, node)
+	p, err := d.peer(node)
+	if err != nil {
+		return err
+	}
+	return p.remove(volumeID, epoch)
+}
+
+// removeReplicas removes the replicas of a volume on nodes in epoch. Replicas
+// that cannot be removed are left as orphans for their node to remove.
+func (d *driver) removeReplicas(volumeID string, epoch uint64, nodes []string) {
+	for _, node := range nodes {
+		if err := d.removeReplica(node, volumeID, epoch); err != nil {
+			dlog.Warnf("Failed to remove the replica of volume %v on node %v: %v",
+				volumeID, node, err)
+		}
+	}
+}
+
+// mirrorDev returns the device of a volume, which is opened on the local
+// replica the first time.
+func (d *driver) mirrorDev(volumeID string, size uint64) (*mirrorDev, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if m, ok := d.mirrors[volumeID]; ok {
+		return m, nil
+	}
+	f, err := d.replicas.open(volumeID)
+	if err != nil {
+		return nil, err
+	}
+	m := &mirrorDev{
+		volumeID: volumeID,
+		size:     int64(size),
+		f:        f,
+		peers:    make(map[string]*peer),
+	}
+	m.fail = func(node string) error {
+		return d.markStale(volumeID, node, m.currentEpoch())
+	}
+	d.mirrors[volumeID] = m
+	return m, nil
+}
+
+// closeDev forgets the device of a volume.
+func (d *driver) closeDev(volumeID string) {
+	d.lock.Lock()
+	m, ok := d.mirrors[volumeID]
+	delete(d.mirrors, volumeID)
+	d.lock.Unlock()
+	if ok && m.nbd != nil {
+		buse.Remove(volumeID)
+	}
+}
+
+// dropPeer stops mirroring the writes to a volume to node.
+func (d *driver) dropPeer(volumeID string, node string) {
+	d.lock.Lock()
+	m, ok := d.mirrors[volumeID]
+	d.lock.Unlock()
+	if ok {
+		m.drop(node)
+	}
+}
+
+// attachDev makes this node the primary of a volume and returns its device,
+// which mirrors the writes to the replicas in sync. The replicas reject the
+// writes of the previous primaries from then on.
+func (d *driver) attachDev(v *api.Volume) (*mirrorDev, error) {
+	self := d.selfID()
+	if len(self) == 0 {
+		return nil, errNotJoined
+	}
+	ms, err := d.updateMembership(v.Id, func(m *Membership) error {
+		r := m.replica(self)
+		if r == nil {
+			return fmt.Errorf("Volume %v has no replica on node %v, its replicas are on %v",
+				v.Id, self, m.nodes())
+		}
+		if r.Stale {
+			return fmt.Errorf("Replica of volume %v on node %v is stale", v.Id, self)
+		}
+		if len(m.Primary) != 0 && m.Primary != self {
+			return fmt.Errorf("Volume %v is attached on node %v", v.Id, m.Primary)
+		}
+		m.setPrimary(self)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	m, err := d.mirrorDev(v.Id, v.Spec.Size)
+	if err != nil {
+		d.release(v.Id)
+		return nil, err
+	}
+	m.setEpoch(ms.Epoch)
+	d.replicas.fence(v.Id, ms.Epoch)
+	// The device may be open from a resync, with peers that went stale
+	// since, which is harmless. Peers in sync must not be missing.
+	for _, node := range ms.inSync(self) {
+		p, err := d.peer(node)
+		if err == nil {
+			m.add(p)
+		} else if err := d.markStale(v.Id, node, ms.Epoch); err != nil {
+			d.release(v.Id)
+			return nil, err
+		}
+	}
+	// The sync carries the new epoch to the peers, which fences them off
+	// the previous primary right away.
+	if err := m.Sync(); err != nil {
+		d.release(v.Id)
+		return nil, err
+	}
+	return m, nil
+}
+
+// release stops this node from being the primary of a volume.
+func (d *driver) release(volumeID string) {
+	self := d.selfID()
+	if _, err := d.updateMembership(volumeID, func(m *Membership) error {
+		if m.Primary == self {
+			m.setPrimary("")
+		}
+		return nil
+	}); err != nil {
+		dlog.Warnf("Failed to release volume %v: %v", volumeID, err)
+	}
+}
+
+// markStale marks the replica of a volume on node stale after it failed a
+// write in epoch. It fails if this node is no longer the primary of epoch,
+// and so do the writes: the peers of a newer primary reject them too.
+func (d *driver) markStale(volumeID string, node string, epoch uint64) error {
+	self := d.selfID()
+	_, err := d.updateMembership(volumeID, func(m *Membership) error {
+		if m.Primary != self || m.Epoch != epoch {
+			return fmt.Errorf("Volume %v is no longer attached on node %v", volumeID, self)
+		}
+		if r := m.replica(node); r != nil {
+			r.Stale = true
+		}
+		return nil
+	})
+	if err == nil {
+		dlog.Warnf("Replica of volume %v on node %v is stale", volumeID, node)
+	}
+	return err
+}
+
+// connect connects the device of a volume to a free NBD device.
+func (d *driver) connect(m *mirrorDev) (string, error) {
+	if m.nbd == nil {
+		if m.nbd = buse.Create(m, m.volumeID, m.size); m.nbd == nil {
+			return "", fmt.Errorf("Cannot create NBD device for %s", m.volumeID)
+		}
+	}
+	dev, err := d.devices.Assign()
+	if err != nil {
+		return "", err
+	}
+	if _, err := m.nbd.Connect(dev); err != nil {
+		m.nbd.Disconnect()
+		d.devices.Release(dev)
+		return "", err
+	}
+	return dev, nil
+}
+
+// disconnect disconnects the device of a volume from its NBD device.
+func (d *driver) disconnect(m *mirrorDev, dev string) {
+	m.nbd.Disconnect()
+	d.devices.Release(dev)
+}
+
+// nodeDown marks the replicas on a node that is down stale, unless they are
+// the last in sync, and lets the volumes attached on it be attached on
+// another node.
+func (d *driver) nodeDown(node string) error {
+	if node == d.selfID() {
+		return nil
+	}
+	sets, err := d.memberships()
+	if err != nil {
+		return err
+	}
+	for _, ms := range sets {
+		if ms.replica(node) == nil {
+			continue
+		}
+		d.dropPeer(ms.VolumeID, node)
+		if _, err := d.updateMembership(ms.VolumeID, func(m *Membership) error {
+			if r := m.replica(node); r != nil && len(m.inSync(node)) != 0 {
+				r.Stale = true
+			}
+			if m.Primary == node {
+				m.setPrimary("")
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+		dlog.Infof("Node %v of a replica of volume %v is down", node, ms.VolumeID)
+	}
+	return nil
+}
+
+// resyncStale resyncs the stale replicas on node, or on all nodes if node is
+// empty, of the volumes this node is the source of.
+func (d *driver) resyncStale(node string) {
+	self := d.selfID()
+	sets, err := d.memberships()
+	if err != nil {
+		dlog.Warnf("Failed to enumerate mirror replicas: %v", err)
+		return
+	}
+	for _, ms := range sets {
+		if ms.source() != self {
+			continue
+		}
+		for _, r := range ms.Replicas {
+			if r.Stale && (len(node) == 0 || r.Node == node) {
+				go func(volumeID, node string) {
+					if err := d.resync(volumeID, node); err != nil {
+						dlog.Warnf("%v", err)
+					}
+				}(ms.VolumeID, r.Node)
+			}
+		}
+	}
+}
+
+// resync copies the local replica of a volume to its stale replica on node,
+// which is then in sync unless another node became the source meanwhile.
+func (d *driver) resync(volumeID string, node string) error {
+	key := volumeID + "/" + node
+	d.lock.Lock()
+	if d.resyncs[key] {
+		d.lock.Unlock()
+		return nil
+	}
+	d.resyncs[key] = true
+	d.lock.Unlock()
+	defer func() {
+		d.lock.Lock()
+		delete(d.resyncs, key)
+		d.lock.Unlock()
+	}()
+
+	self := d.selfID()
+	ms, err := d.membership(volumeID)
+	if err != nil {
+		return err
+	}
+	if source := ms.source(); source != self {
+		return fmt.Errorf("Node %v is the source of volume %v", source, volumeID)
+	}
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		return err
+	}
+	p, err := d.peer(node)
+	if err != nil {
+		return err
+	}
+	if err := p.create(volumeID, ms.Epoch, int64(v.Spec.Size)); err != nil {
+		return fmt.Errorf("Failed to create the replica of volume %v on node %v: %v",
+			volumeID, node, err)
+	}
+	m, err := d.mirrorDev(volumeID, v.Spec.Size)
+	if err != nil {
+		return err
+	}
+	copied, err := m.resync(p, ms.Epoch)
+	if err != nil {
+		return fmt.Errorf("Failed to resync volume %v to node %v: %v", volumeID, node, err)
+	}
+	if _, err := d.updateMembership(volumeID, func(ms *Membership) error {
+		if source := ms.source(); source != self {
+			return fmt.Errorf("Node %v became the source of volume %v during its resync to node %v",
+				source, volumeID, node)
+		}
+		r := ms.replica(node)
+		if r == nil {
+			return fmt.Errorf("Node %v no longer has a replica of volume %v", node, volumeID)
+		}
+		r.Stale = false
+		return nil
+	}); err != nil {
+		m.drop(node)
+		return err
+	}
+	dlog.Infof("Resynced volume %v to node %v, %v copied",
+		volumeID, node, units.String(copied))
+	return nil
+}
+
+// setHaLevel adds replicas to a volume on the nodes picked by placement,
+// which are resynced in the background, or removes the stale replicas first
+// and the replica of the primary last.
+func (d *driver) setHaLevel(v *api.Volume, level int64) error {
+	if level < 1 {
+		return fmt.Errorf("HaLevel %v must be 1 or more", level)
+	}
+	ms, err := d.membership(v.Id)
+	if err != nil {
+		return err
+	}
+	epoch := ms.Epoch
+	current := ms.nodes()
+	if int(level) > len(current) {
+		// The current replicas are kept wherever they are, even on nodes
+		// that are down or full, and replace the pins of the spec.
+		spec := *v.Spec
+		spec.HaLevel = level
+		spec.ReplicaSet = nil
+		p, err := d.place(&spec, current)
+		if err != nil {
+			return err
+		}
+		var added []string
+		for _, node := range p.Nodes {
+			if ms.replica(node) != nil {
+				continue
+			}
+			if err := d.createReplica(node, v.Id, epoch, v.Spec.Size); err != nil {
+				d.removeReplicas(v.Id, epoch, added)
+				return fmt.Errorf("Failed to create the replica of volume %v on node %v: %v",
+					v.Id, node, err)
+			}
+			added = append(added, node)
+		}
+		if ms, err = d.updateMembership(v.Id, func(m *Membership) error {
+			for _, node := range added {
+				if m.replica(node) == nil {
+					m.Replicas = append(m.Replicas, Replica{Node: node, Stale: true})
+				}
+			}
+			return nil
+		}); err != nil {
+			d.removeReplicas(v.Id, epoch, added)
+			return err
+		}
+		go d.resyncStale("")
+	} else if int(level) < len(current) {
+		var removed []string
+		if ms, err = d.updateMembership(v.Id, func(m *Membership) error {
+			rank := func(r Replica) int {
+				switch {
+				case r.Stale:
+					return 0
+				case r.Node == m.Primary:
+					return 2
+				}
+				return 1
+			}
+			var replicas []Replica
+			for i := 0; i <= 2; i++ {
+				for _, r := range m.Replicas {
+					if rank(r) == i {
+						replicas = append(replicas, r)
+					}
+				}
+			}
+			n := len(replicas) - int(level)
+			removed = nil
+			for _, r := range replicas[:n] {
+				removed = append(removed, r.Node)
+			}
+			m.Replicas = replicas[n:]
+			return nil
+		}); err != nil {
+			return err
+		}
+		d.removeReplicas(v.Id, ms.Epoch, removed)
+	}
+	v.Spec.HaLevel = level
+	v.ReplicaSets = []*api.ReplicaSet{{Nodes: ms.nodes()}}
+	return nil
+}
+
+// withReplicas sets the nodes of the replicas of volumes. Volumes with stale
+// replicas are degraded.
+func (d *driver) withReplicas(vols []*api.Volume) {
+	for _, v := range vols {
+		ms, err := d.membership(v.Id)
+		if err != nil {
+			continue
+		}
+		v.ReplicaSets = []*api.ReplicaSet{{Nodes: ms.nodes()}}
+		if len(ms.inSync("")) < len(ms.Replicas) &&
+			v.Status == api.VolumeStatus_VOLUME_STATUS_UP {
+			v.Status = api.VolumeStatus_VOLUME_STATUS_DEGRADED
+		}
+	}
+}
+
+//
+// These functions below implement the volume driver interface.
+//
+
+func (d *driver) String() string {
+	return Name
+}
+
+func (d *driver) Name() string {
+	return Name
+}
+
+func (d *driver) Type() api.DriverType {
+	return Type
+}
+
+// Status diagnostic information
+func (d *driver) Status() [][2]string {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	status := [][2]string{{"Replicas", d.replicas.root}}
+	if d.server != nil {
+		status = append(status, [2]string{"Replication", d.server.addr()})
+	}
+	return status
+}
+
+// Inspect returns volumes with the nodes of their replicas.
+func (d *driver) Inspect(volumeIDs []string) ([]*api.Volume, error) {
+	vols, err := d.StoreEnumerator.Inspect(volumeIDs)
+	if err != nil {
+		return nil, err
+	}
+	d.withReplicas(vols)
+	return vols, nil
+}
+
+// Enumerate returns volumes with the nodes of their replicas.
+func (d *driver) Enumerate(
+	locator *api.VolumeLocator,
+	labels map[string]string,
+) ([]*api.Volume, error) {
+	vols, err := d.StoreEnumerator.Enumerate(locator, labels)
+	if err != nil {
+		return nil, err
+	}
+	d.withReplicas(vols)
+	return vols, nil
+}
+
+// Create creates the replicas of a volume on HaLevel nodes picked by
+// placement and formats it through this node, if it has a format.
+func (d *driver) Create(
+	locator *api.VolumeLocator,
+	source *api.Source,
+	spec *api.VolumeSpec,
+) (string, error) {
+	if len(d.selfID()) == 0 {
+		return "", errNotJoined
+	}
+	if spec.Size == 0 {
+		return "", fmt.Errorf("Volume size cannot be zero: mirror")
+	}
+	if spec.HaLevel < 1 {
+		spec.HaLevel = 1
+	}
+	p, err := d.place(spec, nil)
+	if err != nil {
+		return "", err
+	}
+	volumeID := uuid.New()
+	ms := &Membership{VolumeID: volumeID}
+	for _, node := range p.Nodes {
+		ms.Replicas = append(ms.Replicas, Replica{Node: node})
+	}
+	// The membership goes first so that the replicas are not orphans.
+	if _, err := d.kv.Put(replicasKey+volumeID, ms, 0); err != nil {
+		return "", err
+	}
+	remove := func() {
+		d.removeReplicas(volumeID, ms.Epoch, p.Nodes)
+		d.kv.Delete(replicasKey + volumeID)
+	}
+	for _, node := range p.Nodes {
+		if err := d.createReplica(node, volumeID, ms.Epoch, spec.Size); err != nil {
+			remove()
+			return "", fmt.Errorf("Failed to create the replica of volume %v on node %v: %v",
+				volumeID, node, err)
+		}
+	}
+
+	v := common.NewVolume(
+		volumeID,
+		spec.Format,
+		locator,
+		source,
+		spec,
+	)
+	v.ReplicaSets = []*api.ReplicaSet{{Nodes: p.Nodes}}
+	if spec.Format != api.FSType_FS_TYPE_NONE {
+		if err := d.format(v); err != nil {
+			remove()
+			return "", err
+		}
+	}
+
+	if err := chaos.Now(koStrayCreate); err != nil {
+		return "", err
+	}
+
+	if err := d.CreateVol(v); err != nil {
+		remove()
+		return "", err
+	}
+	dlog.Infof("Mirror created volume %v with replicas on %v", volumeID, p.Nodes)
+	return v.Id, nil
+}
+
+// format formats a volume through an NBD device of this node, so that the
+// filesystem is mirrored to all replicas.
+func (d *driver) format(v *api.Volume) error {
+	m, err := d.attachDev(v)
+	if err != nil {
+		return err
+	}
+	defer d.release(v.Id)
+	dev, err := d.connect(m)
+	if err != nil {
+		return err
+	}
+	dlog.Infof("Formatting %s with %v", dev, v.Spec.Format)
+	cmd := "/sbin/mkfs." + v.Spec.Format.SimpleString()
+	o, err := exec.Command(cmd, dev).CombinedOutput()
+	d.disconnect(m, dev)
+	if err != nil {
+		return fmt.Errorf("Failed to run command %v %v: %v: %s", cmd, dev, err, o)
+	}
+	return nil
+}
+
+func (d *driver) Delete(volumeID string) error {
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		return err
+	}
+	if len(v.AttachPath) > 0 {
+		return fmt.Errorf("Volume %v is mounted at %v", volumeID, v.AttachPath)
+	}
+	if len(v.DevicePath) != 0 {
+		return fmt.Errorf("Volume %v is attached on node %v", volumeID, v.AttachedOn)
+	}
+	ms, err := d.membership(volumeID)
+	if err == nil {
+		d.removeReplicas(volumeID, ms.Epoch, ms.nodes())
+	} else if err != kvdb.ErrNotFound {
+		return err
+	}
+
+	if err := chaos.Now(koStrayDelete); err != nil {
+		return err
+	}
+
+	if _, err := d.kv.Delete(replicasKey + volumeID); err != nil && err != kvdb.ErrNotFound {
+		return err
+	}
+	if err := d.DeleteVol(volumeID); err != nil {
+		return err
+	}
+	dlog.Infof("Mirror deleted volume %v", volumeID)
+	return nil
+}
+
+// Fstrim trims the filesystem of a mounted volume. The NBD device turns the
+// discards into holes in the replicas.
+func (d *driver) Fstrim(volumeID string) (uint64, error) {
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		return 0, err
+	}
+	if len(v.DevicePath) == 0 {
+		return 0, volume.ErrVolDetached
+	}
+	if len(v.AttachPath) == 0 {
+		return 0, fmt.Errorf("Volume %q is not mounted", volumeID)
+	}
+	return common.Fstrim(v.AttachPath[0])
+}
+
+func (d *driver) MountedAt(mountpath string) string {
+	return ""
+}
+
+func (d *driver) Mount(volumeID string, mountpath string) error {
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		return fmt.Errorf("Failed to locate volume %q", volumeID)
+	}
+	if err := common.CheckAccess(
+		v,
+		mountpath,
+		api.AccessMode_ACCESS_MODE_READ_WRITE_ONCE,
+	); err != nil {
+		return err
+	}
+	if len(v.DevicePath) == 0 || v.AttachedOn != d.selfID() {
+		return fmt.Errorf("Volume %q is not attached on this node", volumeID)
+	}
+	if err := chaos.Now(koMount); err != nil {
+		return err
+	}
+	flags, data := common.MountFlags(v)
+	if err := syscall.Mount(
+		v.DevicePath,
+		mountpath,
+		v.Spec.Format.SimpleString(),
+		flags,
+		data,
+	); err != nil {
+		return fmt.Errorf("Failed to mount %v at %v: %v", v.DevicePath, mountpath, err)
+	}
+
+	dlog.Infof("Mirror mounted NBD device %s at %s", v.DevicePath, mountpath)
+
+	common.AddAttachPath(v, mountpath)
+	if err := d.UpdateVol(v); err != nil {
+		return err
+	}
+	common.WatchThrottle(d, volumeID, mountpath)
+	return nil
+}
+
+func (d *driver) Unmount(volumeID string, mountpath string) error {
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		return err
+	}
+	if len(v.AttachPath) == 0 || len(v.AttachPath[0]) == 0 {
+		return fmt.Errorf("Device %v not mounted", volumeID)
+	}
+	if len(mountpath) == 0 {
+		mountpath = v.AttachPath[0]
+	}
+	if err := syscall.Unmount(mountpath, 0); err != nil {
+		return err
+	}
+	common.StopThrottle(mountpath)
+	common.RemoveAttachPath(v, mountpath)
+	return d.UpdateVol(v)
+}
+
+// Set updates the locator, the HaLevel and the I/O limits of the volume.
+func (d *driver) Set(volumeID string, locator *api.VolumeLocator, spec *api.VolumeSpec) error {
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		return err
+	}
+	haLevel := spec != nil && spec.HaLevel != 0 && spec.HaLevel != v.Spec.HaLevel
+	if haLevel {
+		if err := d.setHaLevel(v, spec.HaLevel); err != nil {
+			return err
+		}
+	}
+	if common.SetIoLimits(v, spec) {
+		if err := common.Throttle(v); err != nil {
+			return err
+		}
+	} else if spec != nil && !haLevel {
+		return volume.ErrNotSupported
+	}
+	if locator != nil {
+		v.Locator = locator
+	}
+	return d.UpdateVol(v)
+}
+
+// Attach connects a volume to an NBD device of this node, which must have a
+// replica in sync.
+func (d *driver) Attach(volumeID string, attachOptions map[string]string) (string, error) {
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		return "", err
+	}
+	self := d.selfID()
+	if len(v.DevicePath) != 0 && v.AttachedOn == self {
+		return v.DevicePath, nil
+	}
+	m, err := d.attachDev(v)
+	if err != nil {
+		return "", err
+	}
+	dev, err := d.connect(m)
+	if err != nil {
+		d.release(volumeID)
+		return "", err
+	}
+	if err := chaos.Now(koStrayAttach); err != nil {
+		return "", err
+	}
+	v.DevicePath = dev
+	v.AttachedOn = self
+	v.State = api.VolumeState_VOLUME_STATE_ATTACHED
+	if err := d.UpdateVol(v); err != nil {
+		d.disconnect(m, dev)
+		d.release(volumeID)
+		return "", err
+	}
+	go d.resyncStale("")
+	dlog.Infof("Mirror attached volume %v to NBD device %s", volumeID, dev)
+	return dev, nil
+}
+
+func (d *driver) Detach(volumeID string) error {
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		return err
+	}
+	if len(v.DevicePath) == 0 {
+		return nil
+	}
+	if v.AttachedOn != d.selfID() {
+		return volume.ErrVolAttachedOnRemoteNode
+	}
+	if len(v.AttachPath) > 0 {
+		return fmt.Errorf("Volume %v is mounted at %v", volumeID, v.AttachPath)
+	}
+	m, err := d.mirrorDev(volumeID, v.Spec.Size)
+	if err != nil {
+		return err
+	}
+	dev := v.DevicePath
+	v.DevicePath = ""
+	v.AttachedOn = ""
+	v.State = api.VolumeState_VOLUME_STATE_AVAILABLE
+	if err := d.UpdateVol(v); err != nil {
+		return err
+	}
+	if m.nbd != nil {
+		d.disconnect(m, dev)
+	}
+	d.release(volumeID)
+	dlog.Infof("Mirror detached volume %v from NBD device %s", volumeID, dev)
+	return nil
+}
+
+func (d *driver) Shutdown() {
+	dlog.Printf("%s Shutting down", Name)
+	d.halt()
+	d.replicas.close()
+}
+
+// ListenerPools reports the filesystem of the replicas as a storage pool of
+// this node, with the Cos of its disk, so that placement accounts for its
+// free space.
+func (d *driver) ListenerPools() []api.StoragePool {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(d.replicas.root, &st); err != nil {
+		dlog.Warnf("Failed to get the size of %v: %v", d.replicas.root, err)
+		return nil
+	}
+	return []api.StoragePool{
+		{
+			Cos:       disk.Cos(d.medium),
+			Medium:    d.medium,
+			TotalSize: st.Blocks * uint64(st.Bsize),
+			Used:      (st.Blocks - st.Bfree) * uint64(st.Bsize),
+		},
+	}
+}
+
+// ClusterInit starts the replication server on the first node of a cluster.
+func (d *driver) ClusterInit(self *api.Node) error {
+	return d.start(self)
+}
+
+// Join starts the replication server, detaches the volumes that were
+// attached before a restart and removes the replicas deleted meanwhile.
+func (d *driver) Join(
+	self *api.Node,
+	initState *cluster.ClusterInitState,
+	handleNotifications cluster.ClusterNotify,
+) error {
+	if err := d.start(self); err != nil {
+		return err
+	}
+	d.recover()
+	d.removeOrphans()
+	return nil
+}
+
+// Add resyncs the stale replicas of a node that is up.
+func (d *driver) Add(node *api.Node) error {
+	go d.resyncStale(node.Id)
+	return nil
+}
+
+// Update marks the replicas of a node that is down stale, or resyncs them
+// if it is back up.
+func (d *driver) Update(node *api.Node) error {
+	switch node.Status {
+	case api.Status_STATUS_OK:
+		go d.resyncStale(node.Id)
+	case api.Status_STATUS_OFFLINE:
+		return d.nodeDown(node.Id)
+	}
+	return nil
+}
+
+// MarkNodeDown marks the replicas of a node stale.
+func (d *driver) MarkNodeDown(node *api.Node) error {
+	return d.nodeDown(node.Id)
+}
+
+// CanNodeRemove refuses to remove the node of the last replica in sync of a
+// volume.
+func (d *driver) CanNodeRemove(node *api.Node) error {
+	sets, err := d.memberships()
+	if err != nil {
+		return err
+	}
+	for _, ms := range sets {
+		if nodes := ms.inSync(""); len(nodes) == 1 && nodes[0] == node.Id {
+			dlog.Warnf("Node %v has the last replica in sync of volume %v",
+				node.Id, ms.VolumeID)
+			return cluster.ErrRemoveCausesDataLoss
+		}
+	}
+	return nil
+}
+
+// Remove drops the replicas of a node from their volumes. If a replica was
+// the last in sync, which requires a forced removal, the stale replicas are
+// all that is left and are marked in sync.
+func (d *driver) Remove(node *api.Node, forceRemove bool) error {
+	sets, err := d.memberships()
+	if err != nil {
+		return err
+	}
+	for _, ms := range sets {
+		if ms.replica(node.Id) == nil {
+			continue
+		}
+		d.dropPeer(ms.VolumeID, node.Id)
+		if _, err := d.updateMembership(ms.VolumeID, func(m *Membership) error {
+			replicas := m.Replicas[:0]
+			for _, r := range m.Replicas {
+				if r.Node != node.Id {
+					replicas = append(replicas, r)
+				}
+			}
+			m.Replicas = replicas
+			if len(m.inSync("")) == 0 {
+				dlog.Warnf("Volume %v lost its last replica in sync on node %v",
+					m.VolumeID, node.Id)
+				for i := range m.Replicas {
+					m.Replicas[i].Stale = false
+				}
+			}
+			if m.Primary == node.Id {
+				m.setPrimary("")
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+		dlog.Infof("Removed the replica of volume %v on node %v", ms.VolumeID, node.Id)
+	}
+	return nil
+}
+
+// Halt stops the replication server.
+func (d *driver) Halt(self *api.Node, db *cluster.ClusterInfo) error {
+	d.halt()
+	return nil
+}
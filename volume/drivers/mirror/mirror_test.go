@@ -0,0 +1,340 @@
+package mirror
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/cluster"
+	"github.com/libopenstorage/openstorage/pkg/placement"
+	"github.com/pborman/uuid"
+	"github.com/portworx/kvdb"
+	"github.com/portworx/kvdb/mem"
+	"github.com/stretchr/testify/require"
+	"go.pedge.io/dlog"
+)
+
+// testNodes returns n online nodes on localhost.
+func testNodes(n int) []api.Node {
+	nodes := make([]api.Node, n)
+	for i := range nodes {
+		nodes[i] = api.Node{
+			Id:     fmt.Sprintf("node%d", i),
+			DataIp: "127.0.0.1",
+			Status: api.Status_STATUS_OK,
+			Pools:  []api.StoragePool{{TotalSize: 1 << 30}},
+		}
+	}
+	return nodes
+}
+
+// testPlace places the replicas of volumes on nodes.
+func testPlace(nodes []api.Node, self string) func(*api.VolumeSpec, []string) (*placement.Placement, error) {
+	return func(spec *api.VolumeSpec, placed []string) (*placement.Placement, error) {
+		return placement.Place(&placement.Request{
+			Spec:   spec,
+			Nodes:  nodes,
+			Self:   self,
+			Placed: placed,
+		})
+	}
+}
+
+// newTestCluster starts the driver on n nodes on localhost that share a
+// kvdb, and returns a function that stops them.
+func newTestCluster(t *testing.T, n int) ([]*driver, func()) {
+	kv, err := kvdb.New(mem.Name, "mirror_test", []string{}, nil, dlog.Panicf)
+	require.NoError(t, err)
+	nodes := testNodes(n)
+	drivers := make([]*driver, n)
+	cleanup := func() {
+		for _, d := range drivers {
+			if d != nil {
+				d.Shutdown()
+				os.RemoveAll(d.replicas.root)
+			}
+		}
+	}
+	for i := range nodes {
+		root, err := ioutil.TempDir("", "mirror")
+		require.NoError(t, err)
+		d, err := newDriver(map[string]string{RootParam: root, PortParam: "0"}, kv)
+		require.NoError(t, err)
+		d.place = testPlace(nodes, nodes[i].Id)
+		drivers[i] = d
+		if err := d.Join(&nodes[i], nil, nil); err != nil {
+			cleanup()
+			t.Fatal(err)
+		}
+	}
+	return drivers, cleanup
+}
+
+func readReplica(t *testing.T, d *driver, volumeID string) []byte {
+	b, err := ioutil.ReadFile(path.Join(d.replicas.root, volumeID))
+	require.NoError(t, err)
+	return b
+}
+
+// waitInSync waits for the stale replicas of a volume to be resynced.
+func waitInSync(t *testing.T, d *driver, volumeID string) {
+	for i := 0; i < 100; i++ {
+		ms, err := d.membership(volumeID)
+		require.NoError(t, err)
+		if len(ms.inSync("")) == len(ms.Replicas) {
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	t.Fatalf("Replicas of volume %v are still stale", volumeID)
+}
+
+func TestMirror(t *testing.T) {
+	drivers, cleanup := newTestCluster(t, 3)
+	defer cleanup()
+	d := drivers[0]
+
+	volumeID, err := d.Create(
+		&api.VolumeLocator{Name: "mirror"},
+		nil,
+		&api.VolumeSpec{Size: 4 << 20, HaLevel: 3},
+	)
+	require.NoError(t, err)
+	vols, err := drivers[1].Inspect([]string{volumeID})
+	require.NoError(t, err)
+	require.Len(t, vols, 1)
+	require.Equal(t, []string{"node0", "node1", "node2"}, vols[0].ReplicaSets[0].Nodes)
+
+	v, err := d.GetVol(volumeID)
+	require.NoError(t, err)
+	m, err := d.attachDev(v)
+	require.NoError(t, err)
+	_, err = drivers[1].attachDev(v)
+	require.Error(t, err, "A volume is attached on one node at a time")
+
+	// Writes are on all replicas when they complete.
+	a := bytes.Repeat([]byte("a"), 8192)
+	_, err = m.WriteAt(a, 4096)
+	require.NoError(t, err)
+	require.NoError(t, m.Sync())
+	for _, o := range drivers {
+		require.Equal(t, a, readReplica(t, o, volumeID)[4096:4096+8192])
+	}
+
+	// A node that is down misses the writes until it is back up.
+	require.NoError(t, d.Update(&api.Node{Id: "node2", Status: api.Status_STATUS_OFFLINE}))
+	vols, err = d.Inspect([]string{volumeID})
+	require.NoError(t, err)
+	require.Equal(t, api.VolumeStatus_VOLUME_STATUS_DEGRADED, vols[0].Status)
+	b := bytes.Repeat([]byte("b"), 8192)
+	_, err = m.WriteAt(b, 2<<20)
+	require.NoError(t, err)
+	require.NotEqual(t, readReplica(t, d, volumeID), readReplica(t, drivers[2], volumeID))
+	require.NoError(t, d.Add(&api.Node{Id: "node2", Status: api.Status_STATUS_OK}))
+	waitInSync(t, d, volumeID)
+	require.Equal(t, readReplica(t, d, volumeID), readReplica(t, drivers[2], volumeID))
+
+	// A node that fails a write is stale until it restarts.
+	drivers[1].halt()
+	c := bytes.Repeat([]byte("c"), 4096)
+	_, err = m.WriteAt(c, 0)
+	require.NoError(t, err)
+	ms, err := d.membership(volumeID)
+	require.NoError(t, err)
+	require.True(t, ms.replica("node1").Stale)
+	require.Equal(t, c, readReplica(t, drivers[2], volumeID)[:4096])
+	require.NoError(t, drivers[1].Join(
+		&api.Node{Id: "node1", DataIp: "127.0.0.1"}, nil, nil))
+	require.NoError(t, d.Add(&api.Node{Id: "node1", Status: api.Status_STATUS_OK}))
+	waitInSync(t, d, volumeID)
+	require.Equal(t, readReplica(t, d, volumeID), readReplica(t, drivers[1], volumeID))
+
+	// Writes go to the resynced node again.
+	_, err = m.WriteAt(b, 0)
+	require.NoError(t, err)
+	for _, o := range drivers {
+		require.Equal(t, b[:4096], readReplica(t, o, volumeID)[:4096])
+	}
+
+	d.release(volumeID)
+	require.NoError(t, d.Delete(volumeID))
+	for _, o := range drivers {
+		_, err := os.Stat(path.Join(o.replicas.root, volumeID))
+		require.True(t, os.IsNotExist(err))
+	}
+}
+
+func TestHaLevel(t *testing.T) {
+	drivers, cleanup := newTestCluster(t, 3)
+	defer cleanup()
+	d := drivers[0]
+
+	volumeID, err := d.Create(
+		&api.VolumeLocator{Name: "ha"},
+		nil,
+		&api.VolumeSpec{Size: 3<<20 + 4096},
+	)
+	require.NoError(t, err)
+	v, err := d.GetVol(volumeID)
+	require.NoError(t, err)
+	m, err := d.attachDev(v)
+	require.NoError(t, err)
+	_, err = m.WriteAt(bytes.Repeat([]byte("a"), 4096), 3<<20)
+	require.NoError(t, err)
+	d.release(volumeID)
+
+	// New replicas are resynced.
+	require.NoError(t, d.Set(volumeID, nil, &api.VolumeSpec{HaLevel: 3}))
+	waitInSync(t, d, volumeID)
+	for _, o := range drivers[1:] {
+		require.Equal(t, readReplica(t, d, volumeID), readReplica(t, o, volumeID))
+	}
+	v, err = d.GetVol(volumeID)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), v.Spec.HaLevel)
+
+	// Stale replicas are removed first.
+	require.NoError(t, d.MarkNodeDown(&api.Node{Id: "node1"}))
+	require.NoError(t, d.Set(volumeID, nil, &api.VolumeSpec{HaLevel: 2}))
+	vols, err := d.Inspect([]string{volumeID})
+	require.NoError(t, err)
+	require.Equal(t, []string{"node0", "node2"}, vols[0].ReplicaSets[0].Nodes)
+	require.Equal(t, api.VolumeStatus_VOLUME_STATUS_UP, vols[0].Status)
+	_, err = os.Stat(path.Join(drivers[1].replicas.root, volumeID))
+	require.True(t, os.IsNotExist(err))
+
+	// A replica on a node that is down is kept when replicas are added.
+	nodes := testNodes(3)
+	nodes[2].Status = api.Status_STATUS_OFFLINE
+	d.place = testPlace(nodes, "node0")
+	require.NoError(t, d.Set(volumeID, nil, &api.VolumeSpec{HaLevel: 3}))
+	waitInSync(t, d, volumeID)
+	vols, err = d.Inspect([]string{volumeID})
+	require.NoError(t, err)
+	require.Equal(t, []string{"node0", "node2", "node1"}, vols[0].ReplicaSets[0].Nodes)
+	require.Equal(t, readReplica(t, d, volumeID), readReplica(t, drivers[1], volumeID))
+}
+
+func TestRemoveNode(t *testing.T) {
+	drivers, cleanup := newTestCluster(t, 2)
+	defer cleanup()
+
+	volumeID, err := drivers[0].Create(
+		&api.VolumeLocator{Name: "remove"},
+		nil,
+		&api.VolumeSpec{Size: 1 << 20, HaLevel: 2},
+	)
+	require.NoError(t, err)
+
+	// The last replica in sync is never stale.
+	require.NoError(t, drivers[0].MarkNodeDown(&api.Node{Id: "node1"}))
+	require.NoError(t, drivers[1].MarkNodeDown(&api.Node{Id: "node0"}))
+	ms, err := drivers[0].membership(volumeID)
+	require.NoError(t, err)
+	require.Equal(t, []string{"node0"}, ms.inSync(""))
+
+	require.Equal(t, cluster.ErrRemoveCausesDataLoss,
+		drivers[1].CanNodeRemove(&api.Node{Id: "node0"}))
+	require.NoError(t, drivers[1].CanNodeRemove(&api.Node{Id: "node1"}))
+	require.NoError(t, drivers[0].Remove(&api.Node{Id: "node1"}, false))
+	ms, err = drivers[0].membership(volumeID)
+	require.NoError(t, err)
+	require.Equal(t, []Replica{{Node: "node0"}}, ms.Replicas)
+
+	v, err := drivers[1].GetVol(volumeID)
+	require.NoError(t, err)
+	_, err = drivers[1].attachDev(v)
+	require.Error(t, err, "Volumes are attached on nodes with a replica")
+}
+
+func TestFencing(t *testing.T) {
+	drivers, cleanup := newTestCluster(t, 2)
+	defer cleanup()
+
+	volumeID, err := drivers[0].Create(
+		&api.VolumeLocator{Name: "fencing"},
+		nil,
+		&api.VolumeSpec{Size: 1 << 20, HaLevel: 2},
+	)
+	require.NoError(t, err)
+	v, err := drivers[0].GetVol(volumeID)
+	require.NoError(t, err)
+	old, err := drivers[0].attachDev(v)
+	require.NoError(t, err)
+
+	// node1 takes over from node0, which it cannot reach but is still up.
+	require.NoError(t, drivers[1].MarkNodeDown(&api.Node{Id: "node0"}))
+	_, err = drivers[1].attachDev(v)
+	require.NoError(t, err)
+
+	// The writes of the previous primary are rejected.
+	_, err = old.WriteAt(bytes.Repeat([]byte("a"), 4096), 0)
+	require.Error(t, err)
+	require.Equal(t, make([]byte, 4096), readReplica(t, drivers[1], volumeID)[:4096])
+	ms, err := drivers[1].membership(volumeID)
+	require.NoError(t, err)
+	require.Equal(t, "node1", ms.Primary)
+	require.False(t, ms.replica("node1").Stale)
+}
+
+func TestFencingRestart(t *testing.T) {
+	root, err := ioutil.TempDir("", "mirror")
+	require.NoError(t, err)
+	defer os.RemoveAll(root)
+	volumeID := uuid.New()
+	s := newStore(root)
+	_, err = s.do(&request{op: opCreate, volumeID: volumeID, epoch: 2, offset: 4096})
+	require.NoError(t, err)
+	s.close()
+
+	// The newest epoch is remembered after a restart, also for removals.
+	s = newStore(root)
+	defer s.close()
+	_, err = s.do(&request{op: opSync, volumeID: volumeID, epoch: 1})
+	require.Error(t, err)
+	_, err = s.do(&request{op: opDelete, volumeID: volumeID, epoch: 1})
+	require.Error(t, err)
+	list, err := s.list()
+	require.NoError(t, err)
+	require.Equal(t, []string{volumeID}, list)
+
+	_, err = s.do(&request{op: opDelete, volumeID: volumeID, epoch: 2})
+	require.NoError(t, err)
+	list, err = s.list()
+	require.NoError(t, err)
+	require.Empty(t, list)
+	_, err = os.Stat(s.epochPath(volumeID))
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestServer(t *testing.T) {
+	drivers, cleanup := newTestCluster(t, 1)
+	defer cleanup()
+	d := drivers[0]
+	addr := d.server.addr()
+
+	// Only the nodes that know the secret of the cluster are served.
+	p := newPeer("other", addr, []byte("wrong"))
+	defer p.close()
+	require.Error(t, p.create(uuid.New(), 0, 4096))
+	list, err := d.replicas.list()
+	require.NoError(t, err)
+	require.Empty(t, list)
+
+	// Volume IDs are plain uuids.
+	p = newPeer("node0", addr, d.secret)
+	defer p.close()
+	for _, id := range []string{"", ".", "..", "../escaped", "a/b"} {
+		require.Error(t, p.create(id, 0, 4096), id)
+	}
+	_, err = os.Stat(path.Join(d.replicas.root, "..", "escaped"))
+	require.True(t, os.IsNotExist(err))
+	volumeID := uuid.New()
+	require.NoError(t, p.create(volumeID, 0, 4096))
+	require.Len(t, readReplica(t, d, volumeID), 4096)
+}
@@ -0,0 +1,154 @@
+package mirror
+
+import (
+	"bufio"
+	"net"
+	"sync"
+	"time"
+)
+
+var (
+	// dialTimeout is how long connecting to the server of a peer may take.
+	dialTimeout = 5 * time.Second
+	// ioTimeout is how long a request to a peer may take.
+	ioTimeout = 30 * time.Second
+)
+
+// peer is the connection to the replication server of another node. Its
+// requests are sent one at a time and it reconnects after an error.
+type peer struct {
+	node   string
+	addr   string
+	secret []byte
+	lock   sync.Mutex
+	conn   net.Conn
+	rd     *bufio.Reader
+	w      *bufio.Writer
+}
+
+func newPeer(node string, addr string, secret []byte) *peer {
+	return &peer{
+		node:   node,
+		addr:   addr,
+		secret: secret,
+	}
+}
+
+// do sends a request and returns the data of its reply.
+func (p *peer) do(r *request) ([]byte, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.conn == nil {
+		conn, err := net.DialTimeout("tcp", p.addr, dialTimeout)
+		if err != nil {
+			return nil, err
+		}
+		p.conn = conn
+		p.rd = bufio.NewReader(conn)
+		p.w = bufio.NewWriter(conn)
+		p.conn.SetDeadline(time.Now().Add(ioTimeout))
+		if err := handshake(p.rd, p.w, p.secret); err != nil {
+			p.reset()
+			return nil, err
+		}
+	}
+	p.conn.SetDeadline(time.Now().Add(ioTimeout))
+	if err := writeRequest(p.w, r); err != nil {
+		p.reset()
+		return nil, err
+	}
+	data, err := readReply(p.rd)
+	if _, ok := err.(remoteError); err != nil && !ok {
+		p.reset()
+	}
+	return data, err
+}
+
+func (p *peer) reset() {
+	if p.conn != nil {
+		p.conn.Close()
+		p.conn = nil
+	}
+}
+
+func (p *peer) close() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.reset()
+}
+
+func (p *peer) create(volumeID string, epoch uint64, size int64) error {
+	_, err := p.do(&request{
+		op:       opCreate,
+		volumeID: volumeID,
+		epoch:    epoch,
+		offset:   uint64(size),
+	})
+	return err
+}
+
+func (p *peer) remove(volumeID string, epoch uint64) error {
+	_, err := p.do(&request{op: opDelete, volumeID: volumeID, epoch: epoch})
+	return err
+}
+
+// write writes b at off, in requests of maxLength bytes at most.
+func (p *peer) write(volumeID string, epoch uint64, b []byte, off int64) error {
+	for len(b) > 0 {
+		n := len(b)
+		if n > maxLength {
+			n = maxLength
+		}
+		if _, err := p.do(&request{
+			op:       opWrite,
+			volumeID: volumeID,
+			epoch:    epoch,
+			offset:   uint64(off),
+			length:   uint32(n),
+			data:     b[:n],
+		}); err != nil {
+			return err
+		}
+		b = b[n:]
+		off += int64(n)
+	}
+	return nil
+}
+
+func (p *peer) discard(volumeID string, epoch uint64, off int64, length int64) error {
+	for length > 0 {
+		n := length
+		if n > maxLength {
+			n = maxLength
+		}
+		if _, err := p.do(&request{
+			op:       opDiscard,
+			volumeID: volumeID,
+			epoch:    epoch,
+			offset:   uint64(off),
+			length:   uint32(n),
+		}); err != nil {
+			return err
+		}
+		off += n
+		length -= n
+	}
+	return nil
+}
+
+func (p *peer) sync(volumeID string, epoch uint64) error {
+	_, err := p.do(&request{op: opSync, volumeID: volumeID, epoch: epoch})
+	return err
+}
+
+// sum returns the checksum of length bytes at off, which is at most
+// maxLength.
+func (p *peer) sum(volumeID string, epoch uint64, off int64, length int) ([]byte, error) {
+	return p.do(&request{
+		op:       opSum,
+		volumeID: volumeID,
+		epoch:    epoch,
+		offset:   uint64(off),
+		length:   uint32(length),
+	})
+}
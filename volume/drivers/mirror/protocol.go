@@ -0,0 +1,236 @@
+package mirror
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// The replication protocol is an exchange of requests and replies on a TCP
+// connection from the node that a volume is attached on to the nodes of its
+// other replicas. The requests of a connection are served in order.
+//
+//	request: magic op offset epoch length idLength id [data of a write]
+//	reply:   magic errLength dataLength [err] [data]
+//
+// Integers are big endian. The offset and the epoch are 64 bits, the other
+// fields 32 bits.
+//
+// A connection starts with a handshake in which each node proves that it
+// knows the secret of the cluster with the HMAC-SHA256 of a nonce of the
+// other:
+//
+//	server: nonce
+//	client: hmac("client" nonce) clientNonce
+//	server: reply with hmac("server" clientNonce) as data
+
+const (
+	requestMagic = 0x4d495251
+	replyMagic   = 0x4d495250
+	// maxLength is the largest range of a request and the largest reply.
+	maxLength = 32 << 20
+	// maxID is the longest volume ID of a request.
+	maxID = 256
+	// nonceLength is the length of the nonces of a handshake.
+	nonceLength = 32
+)
+
+var errUnauthenticated = errors.New("Replication peer does not know the secret of the cluster")
+
+const (
+	// opCreate creates a replica of offset bytes, or resizes it.
+	opCreate uint32 = iota + 1
+	// opDelete removes a replica.
+	opDelete
+	// opWrite writes the data at offset.
+	opWrite
+	// opDiscard deallocates length bytes at offset.
+	opDiscard
+	// opSync commits a replica to stable storage.
+	opSync
+	// opSum returns the checksum of length bytes at offset.
+	opSum
+)
+
+// request to the replication server of a node.
+type request struct {
+	op       uint32
+	volumeID string
+	// epoch of the membership of the volume the sender sends the request
+	// in, which fences off the previous primaries.
+	epoch  uint64
+	offset uint64
+	length uint32
+	data   []byte
+}
+
+// remoteError is an error returned by the replication server of a node, as
+// opposed to an error of the connection to it.
+type remoteError string
+
+func (e remoteError) Error() string {
+	return string(e)
+}
+
+func writeRequest(w *bufio.Writer, r *request) error {
+	var hdr [32]byte
+	binary.BigEndian.PutUint32(hdr[0:], requestMagic)
+	binary.BigEndian.PutUint32(hdr[4:], r.op)
+	binary.BigEndian.PutUint64(hdr[8:], r.offset)
+	binary.BigEndian.PutUint64(hdr[16:], r.epoch)
+	binary.BigEndian.PutUint32(hdr[24:], r.length)
+	binary.BigEndian.PutUint32(hdr[28:], uint32(len(r.volumeID)))
+	w.Write(hdr[:])
+	w.WriteString(r.volumeID)
+	if r.op == opWrite {
+		w.Write(r.data)
+	}
+	return w.Flush()
+}
+
+func readRequest(rd *bufio.Reader) (*request, error) {
+	var hdr [32]byte
+	if _, err := io.ReadFull(rd, hdr[:]); err != nil {
+		return nil, err
+	}
+	if magic := binary.BigEndian.Uint32(hdr[0:]); magic != requestMagic {
+		return nil, fmt.Errorf("Invalid request magic %#x", magic)
+	}
+	r := &request{
+		op:     binary.BigEndian.Uint32(hdr[4:]),
+		offset: binary.BigEndian.Uint64(hdr[8:]),
+		epoch:  binary.BigEndian.Uint64(hdr[16:]),
+		length: binary.BigEndian.Uint32(hdr[24:]),
+	}
+	idLength := binary.BigEndian.Uint32(hdr[28:])
+	if idLength > maxID || r.length > maxLength {
+		return nil, fmt.Errorf("Request of %v bytes for a volume ID of %v bytes is too large",
+			r.length, idLength)
+	}
+	id := make([]byte, idLength)
+	if _, err := io.ReadFull(rd, id); err != nil {
+		return nil, err
+	}
+	r.volumeID = string(id)
+	if r.op == opWrite {
+		r.data = make([]byte, r.length)
+		if _, err := io.ReadFull(rd, r.data); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+func writeReply(w *bufio.Writer, data []byte, err error) error {
+	var msg string
+	if err != nil {
+		msg = err.Error()
+		data = nil
+	}
+	var hdr [12]byte
+	binary.BigEndian.PutUint32(hdr[0:], replyMagic)
+	binary.BigEndian.PutUint32(hdr[4:], uint32(len(msg)))
+	binary.BigEndian.PutUint32(hdr[8:], uint32(len(data)))
+	w.Write(hdr[:])
+	w.WriteString(msg)
+	w.Write(data)
+	return w.Flush()
+}
+
+// readReply returns the data of a reply, or its error as a remoteError.
+func readReply(rd *bufio.Reader) ([]byte, error) {
+	var hdr [12]byte
+	if _, err := io.ReadFull(rd, hdr[:]); err != nil {
+		return nil, err
+	}
+	if magic := binary.BigEndian.Uint32(hdr[0:]); magic != replyMagic {
+		return nil, fmt.Errorf("Invalid reply magic %#x", magic)
+	}
+	msgLength := binary.BigEndian.Uint32(hdr[4:])
+	dataLength := binary.BigEndian.Uint32(hdr[8:])
+	if msgLength > maxLength || dataLength > maxLength {
+		return nil, fmt.Errorf("Reply of %v bytes is too large", msgLength+dataLength)
+	}
+	msg := make([]byte, msgLength)
+	if _, err := io.ReadFull(rd, msg); err != nil {
+		return nil, err
+	}
+	data := make([]byte, dataLength)
+	if _, err := io.ReadFull(rd, data); err != nil {
+		return nil, err
+	}
+	if msgLength != 0 {
+		return nil, remoteError(msg)
+	}
+	return data, nil
+}
+
+// proof returns the proof that the node of role knows secret, for a nonce of
+// the other node.
+func proof(secret []byte, role string, nonce []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(role))
+	mac.Write(nonce)
+	return mac.Sum(nil)
+}
+
+func newNonce() ([]byte, error) {
+	nonce := make([]byte, nonceLength)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return nonce, nil
+}
+
+// accept runs the handshake of the server of a connection. It fails if the
+// client does not know secret.
+func accept(rd *bufio.Reader, w *bufio.Writer, secret []byte) error {
+	nonce, err := newNonce()
+	if err != nil {
+		return err
+	}
+	w.Write(nonce)
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	msg := make([]byte, sha256.Size+nonceLength)
+	if _, err := io.ReadFull(rd, msg); err != nil {
+		return err
+	}
+	if !hmac.Equal(msg[:sha256.Size], proof(secret, "client", nonce)) {
+		writeReply(w, nil, errUnauthenticated)
+		return errUnauthenticated
+	}
+	return writeReply(w, proof(secret, "server", msg[sha256.Size:]), nil)
+}
+
+// handshake runs the handshake of the client of a connection. It fails if
+// the server does not know secret.
+func handshake(rd *bufio.Reader, w *bufio.Writer, secret []byte) error {
+	nonce := make([]byte, nonceLength)
+	if _, err := io.ReadFull(rd, nonce); err != nil {
+		return err
+	}
+	clientNonce, err := newNonce()
+	if err != nil {
+		return err
+	}
+	w.Write(proof(secret, "client", nonce))
+	w.Write(clientNonce)
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	data, err := readReply(rd)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal(data, proof(secret, "server", clientNonce)) {
+		return errUnauthenticated
+	}
+	return nil
+}
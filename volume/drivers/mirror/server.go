@@ -0,0 +1,344 @@
+package mirror
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/pborman/uuid"
+	"go.pedge.io/dlog"
+)
+
+const (
+	// Defined in <linux/falloc.h>:
+	fallocKeepSize  = 0x01
+	fallocPunchHole = 0x02
+)
+
+// epochsDir is the directory of the store with the newest epoch seen of each
+// replica, so that fencing survives a restart of the node.
+const epochsDir = "epochs"
+
+// store of the replica files of this node, which are named after their
+// volume.
+type store struct {
+	root  string
+	lock  sync.Mutex
+	files map[string]*os.File
+	// epochs caches the epoch files of the volumes.
+	epochs map[string]uint64
+}
+
+func newStore(root string) *store {
+	return &store{
+		root:   root,
+		files:  make(map[string]*os.File),
+		epochs: make(map[string]uint64),
+	}
+}
+
+// fence fails if epoch is older than the newest epoch seen of a volume, and
+// makes epoch the newest otherwise. A newer epoch is saved before the request
+// is served.
+func (s *store) fence(volumeID string, epoch uint64) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	newest, err := s.newestEpoch(volumeID)
+	if err != nil {
+		return err
+	}
+	if epoch < newest {
+		return fmt.Errorf("Request of epoch %v to volume %v is older than epoch %v",
+			epoch, volumeID, newest)
+	}
+	if epoch == newest {
+		return nil
+	}
+	if err := s.saveEpoch(volumeID, epoch); err != nil {
+		return err
+	}
+	s.epochs[volumeID] = epoch
+	return nil
+}
+
+func (s *store) epochPath(volumeID string) string {
+	return path.Join(s.root, epochsDir, volumeID)
+}
+
+// newestEpoch returns the newest epoch seen of a volume, or 0 if none was.
+// Called with the lock held.
+func (s *store) newestEpoch(volumeID string) (uint64, error) {
+	if epoch, ok := s.epochs[volumeID]; ok {
+		return epoch, nil
+	}
+	b, err := ioutil.ReadFile(s.epochPath(volumeID))
+	if os.IsNotExist(err) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+	epoch, err := strconv.ParseUint(strings.TrimSpace(string(b)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid epoch file of volume %v: %v", volumeID, err)
+	}
+	s.epochs[volumeID] = epoch
+	return epoch, nil
+}
+
+// saveEpoch writes the epoch file of a volume to a temporary file and renames
+// it over the epoch file, so that a crash never leaves a partial epoch.
+func (s *store) saveEpoch(volumeID string, epoch uint64) error {
+	dir := path.Join(s.root, epochsDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(dir, volumeID)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(strconv.FormatUint(epoch, 10)); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), s.epochPath(volumeID))
+}
+
+// create creates the replica of a volume, or resizes it if it exists.
+func (s *store) create(volumeID string, size int64) error {
+	f, err := os.OpenFile(path.Join(s.root, volumeID), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return f.Truncate(size)
+}
+
+// open returns the open file of the replica of a volume.
+func (s *store) open(volumeID string) (*os.File, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if f, ok := s.files[volumeID]; ok {
+		return f, nil
+	}
+	f, err := os.OpenFile(path.Join(s.root, volumeID), os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	s.files[volumeID] = f
+	return f, nil
+}
+
+// remove closes and removes the replica of a volume and its epoch file.
+func (s *store) remove(volumeID string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if f, ok := s.files[volumeID]; ok {
+		f.Close()
+		delete(s.files, volumeID)
+	}
+	if err := os.Remove(path.Join(s.root, volumeID)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	delete(s.epochs, volumeID)
+	if err := os.Remove(s.epochPath(volumeID)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// list returns the volume IDs of the replicas. The epochs directory is not a
+// replica.
+func (s *store) list() ([]string, error) {
+	entries, err := ioutil.ReadDir(s.root)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, e := range entries {
+		if e.Mode().IsRegular() {
+			ids = append(ids, e.Name())
+		}
+	}
+	return ids, nil
+}
+
+func (s *store) close() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for id, f := range s.files {
+		f.Close()
+		delete(s.files, id)
+	}
+}
+
+// checkID returns an error if a volume ID from another node is not a plain
+// uuid, which could name a file out of the root of the store.
+func checkID(volumeID string) error {
+	if uuid.Parse(volumeID) == nil || filepath.Base(volumeID) != volumeID {
+		return fmt.Errorf("Invalid volume ID %q", volumeID)
+	}
+	return nil
+}
+
+// do serves a request to a replica and returns the data of the reply.
+func (s *store) do(r *request) ([]byte, error) {
+	if err := checkID(r.volumeID); err != nil {
+		return nil, err
+	}
+	if err := s.fence(r.volumeID, r.epoch); err != nil {
+		return nil, err
+	}
+	switch r.op {
+	case opCreate:
+		return nil, s.create(r.volumeID, int64(r.offset))
+	case opDelete:
+		return nil, s.remove(r.volumeID)
+	}
+	f, err := s.open(r.volumeID)
+	if err != nil {
+		return nil, err
+	}
+	switch r.op {
+	case opWrite:
+		_, err := f.WriteAt(r.data, int64(r.offset))
+		return nil, err
+	case opDiscard:
+		return nil, punchHole(f, int64(r.offset), int64(r.length))
+	case opSync:
+		return nil, f.Sync()
+	case opSum:
+		b := make([]byte, r.length)
+		if _, err := f.ReadAt(b, int64(r.offset)); err != nil && err != io.EOF {
+			return nil, err
+		}
+		return checksum(b), nil
+	}
+	return nil, fmt.Errorf("Unknown operation %v", r.op)
+}
+
+// punchHole deallocates a range of a replica, which then reads back as
+// zeroes.
+func punchHole(f *os.File, off int64, length int64) error {
+	return syscall.Fallocate(
+		int(f.Fd()),
+		fallocKeepSize|fallocPunchHole,
+		off,
+		length,
+	)
+}
+
+func checksum(b []byte) []byte {
+	sum := sha1.Sum(b)
+	return sum[:]
+}
+
+// server serves the replicas of this node to the nodes their volumes are
+// attached on. It only serves the nodes that know the secret of the cluster.
+type server struct {
+	replicas *store
+	secret   []byte
+	listener net.Listener
+	lock     sync.Mutex
+	conns    map[net.Conn]bool
+	closed   bool
+}
+
+// listen starts a server on addr.
+func listen(addr string, replicas *store, secret []byte) (*server, error) {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	s := &server{
+		replicas: replicas,
+		secret:   secret,
+		listener: l,
+		conns:    make(map[net.Conn]bool),
+	}
+	go s.serve()
+	return s, nil
+}
+
+// addr returns the address the server listens on.
+func (s *server) addr() string {
+	return s.listener.Addr().String()
+}
+
+func (s *server) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.lock.Lock()
+		if s.closed {
+			s.lock.Unlock()
+			conn.Close()
+			return
+		}
+		s.conns[conn] = true
+		s.lock.Unlock()
+		go s.handle(conn)
+	}
+}
+
+func (s *server) handle(conn net.Conn) {
+	defer func() {
+		s.lock.Lock()
+		delete(s.conns, conn)
+		s.lock.Unlock()
+		conn.Close()
+	}()
+	rd := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+	conn.SetDeadline(time.Now().Add(ioTimeout))
+	if err := accept(rd, w, s.secret); err != nil {
+		dlog.Warnf("Rejecting replication connection from %v: %v", conn.RemoteAddr(), err)
+		return
+	}
+	conn.SetDeadline(time.Time{})
+	for {
+		r, err := readRequest(rd)
+		if err != nil {
+			if err != io.EOF {
+				dlog.Warnf("Closing replication connection from %v: %v",
+					conn.RemoteAddr(), err)
+			}
+			return
+		}
+		data, err := s.replicas.do(r)
+		if err := writeReply(w, data, err); err != nil {
+			return
+		}
+	}
+}
+
+// close stops the server and closes its connections.
+func (s *server) close() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.closed = true
+	s.listener.Close()
+	for conn := range s.conns {
+		conn.Close()
+	}
+}
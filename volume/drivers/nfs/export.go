@@ -0,0 +1,252 @@
+package nfs
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"go.pedge.io/dlog"
+
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/pkg/mount"
+)
+
+const (
+	// ExportLabel is the volume label that places a volume on the export
+	// with that path or server:path.
+	ExportLabel = "export"
+	// RuntimeExport is the runtime state of a volume with the export that
+	// it was placed on.
+	RuntimeExport = "nfs_export"
+	// healthTimeout bounds the time that a hung export can block Status.
+	healthTimeout = 5 * time.Second
+)
+
+// export is an NFS export, or a local directory if there is no server,
+// that is mounted at mountPath and holds volumes.
+type export struct {
+	server    string
+	path      string
+	mountPath string
+	mounter   mount.Manager
+}
+
+func (e *export) String() string {
+	if e.server == "" {
+		return e.path
+	}
+	return e.server + ":" + e.path
+}
+
+// parseExports returns the exports in the comma separated server and path
+// params. A single server serves all the paths and a local directory is
+// bind mounted if no server is given.
+func parseExports(params map[string]string) ([]*export, error) {
+	p, ok := params["path"]
+	if !ok || p == "" {
+		return nil, errors.New("No NFS path provided")
+	}
+	paths := strings.Split(p, ",")
+	var servers []string
+	if s, ok := params["server"]; ok && s != "" {
+		servers = strings.Split(s, ",")
+	}
+	if len(servers) > 1 && len(servers) != len(paths) {
+		return nil, fmt.Errorf("%d NFS servers provided for %d paths",
+			len(servers), len(paths))
+	}
+	exports := make([]*export, len(paths))
+	for i, p := range paths {
+		e := &export{
+			path:      strings.TrimSpace(p),
+			mountPath: filepath.Join(nfsMountPath, strconv.Itoa(i)),
+		}
+		if len(servers) == 1 {
+			e.server = strings.TrimSpace(servers[0])
+		} else if len(servers) > 1 {
+			e.server = strings.TrimSpace(servers[i])
+		}
+		exports[i] = e
+	}
+	return exports, nil
+}
+
+// mount creates the mount manager of the export and mounts the export at
+// its mount path unless it is already mounted there. The mount manager
+// journals to journal.
+func (e *export) mount(journal string) error {
+	// Create a mount manager for this NFS server. Blank sever is OK.
+	mounter, err := mount.New(
+		mount.NFSMount,
+		nil,
+		[]string{e.server},
+		mount.NewFileJournal(journal),
+	)
+	if err != nil {
+		dlog.Warnf("Failed to create mount manager for server: %v (%v)", e.server, err)
+		return err
+	}
+	e.mounter = mounter
+	if err := os.MkdirAll(e.mountPath, 0744); err != nil {
+		return err
+	}
+	src := e.path
+	if e.server != "" {
+		src = ":" + e.path
+	}
+	// If src is already mounted at dest, leave it be.
+	if mountExists, _ := mounter.Exists(src, e.mountPath); mountExists {
+		return nil
+	}
+	syscall.Unmount(e.mountPath, 0)
+	if e.server != "" {
+		err = syscall.Mount(
+			src,
+			e.mountPath,
+			"nfs",
+			0,
+			"nolock,addr="+e.server,
+		)
+	} else {
+		err = syscall.Mount(src, e.mountPath, "", syscall.MS_BIND, "")
+	}
+	if err != nil {
+		dlog.Printf("Unable to mount %v at %s (%+v)", e, e.mountPath, err)
+		return err
+	}
+	return nil
+}
+
+// usage returns the bytes available to volumes on the export and its size.
+func (e *export) usage() (uint64, uint64, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(e.mountPath, &st); err != nil {
+		return 0, 0, err
+	}
+	return st.Bavail * uint64(st.Bsize), st.Blocks * uint64(st.Bsize), nil
+}
+
+// health returns the usage of the export, or an error if the export is not
+// mounted or does not answer within healthTimeout.
+func (e *export) health() (uint64, uint64, error) {
+	if src, err := mountSource(e.mountPath); err != nil {
+		return 0, 0, err
+	} else if src == "" {
+		return 0, 0, fmt.Errorf("%v is not mounted at %v", e, e.mountPath)
+	}
+	type result struct {
+		free  uint64
+		total uint64
+		err   error
+	}
+	c := make(chan result, 1)
+	go func() {
+		free, total, err := e.usage()
+		c <- result{free, total, err}
+	}()
+	select {
+	case r := <-c:
+		return r.free, r.total, r.err
+	case <-time.After(healthTimeout):
+		return 0, 0, fmt.Errorf("%v did not respond within %v", e, healthTimeout)
+	}
+}
+
+// place returns the export for a new volume. A volume is placed on the
+// export named by its ExportLabel, next to its parent if it has one, or
+// else on the healthy export with the most free space.
+func (d *driver) place(locator *api.VolumeLocator, source *api.Source) (*export, error) {
+	if name, ok := locator.VolumeLabels[ExportLabel]; ok {
+		for _, e := range d.exports {
+			if name == e.String() || name == e.path {
+				return e, nil
+			}
+		}
+		return nil, fmt.Errorf("Export %v is not configured", name)
+	}
+	if source != nil && source.Parent != "" {
+		parent, err := d.GetVol(source.Parent)
+		if err != nil {
+			return nil, err
+		}
+		return d.exportOf(parent)
+	}
+	var (
+		best     *export
+		bestFree uint64
+	)
+	for _, e := range d.exports {
+		free, _, err := e.health()
+		if err != nil {
+			dlog.Warnf("Not placing volumes on %v: %v", e, err)
+			continue
+		}
+		if best == nil || free > bestFree {
+			best, bestFree = e, free
+		}
+	}
+	if best == nil {
+		return nil, errors.New("No NFS export is available")
+	}
+	return best, nil
+}
+
+// exportOf returns the export that holds the volume v.
+func (d *driver) exportOf(v *api.Volume) (*export, error) {
+	name := ""
+	for _, state := range v.RuntimeState {
+		if value, ok := state.RuntimeState[RuntimeExport]; ok {
+			name = value
+		}
+	}
+	// Volumes that were created before exports were recorded are on the
+	// only export.
+	if name == "" && len(d.exports) == 1 {
+		return d.exports[0], nil
+	}
+	for _, e := range d.exports {
+		if name == e.String() {
+			return e, nil
+		}
+	}
+	return nil, fmt.Errorf("Export %v of volume %v is not configured",
+		name, v.Id)
+}
+
+// mountSource returns the source mounted at path, or "" if nothing is
+// mounted there.
+func mountSource(path string) (string, error) {
+	f, err := os.Open("/proc/mounts")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	source := ""
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) > 1 && fields[1] == path {
+			source = fields[0]
+		}
+	}
+	return source, scanner.Err()
+}
+
+// nfsState returns the runtime state of the volume v that is kept by
+// this driver.
+func nfsState(v *api.Volume) map[string]string {
+	for _, state := range v.RuntimeState {
+		if _, ok := state.RuntimeState[RuntimeExport]; ok {
+			return state.RuntimeState
+		}
+	}
+	state := &api.RuntimeStateMap{RuntimeState: make(map[string]string)}
+	v.RuntimeState = append(v.RuntimeState, state)
+	return state.RuntimeState
+}
@@ -3,19 +3,21 @@ package nfs
 import (
 	"errors"
 	"fmt"
-	"io"
+	"io/ioutil"
 	"os"
 	"path"
+	"path/filepath"
 	"syscall"
 
 	"go.pedge.io/dlog"
 
 	"github.com/libopenstorage/openstorage/api"
 	"github.com/libopenstorage/openstorage/config"
-	"github.com/libopenstorage/openstorage/pkg/mount"
-	"github.com/libopenstorage/openstorage/pkg/seed"
+	"github.com/libopenstorage/openstorage/pkg/chaos"
+	"github.com/libopenstorage/openstorage/pkg/units"
 	"github.com/libopenstorage/openstorage/volume"
 	"github.com/libopenstorage/openstorage/volume/drivers/common"
+	"github.com/pborman/uuid"
 	"github.com/portworx/kvdb"
 )
 
@@ -27,67 +29,63 @@ const (
 	nfsBlockFile = ".blockdevice"
 )
 
+var (
+	koStrayCreate = chaos.Add("nfs", "create", "create volume directory before DB")
+	koAttach      = chaos.Add("nfs", "attach", "attach volume")
+	koMount       = chaos.Add("nfs", "mount", "bind mount volume directory")
+	koStrayDelete = chaos.Add("nfs", "delete", "remove volume directory before DB")
+)
+
 // Implements the open storage volume interface.
 type driver struct {
 	volume.IODriver
 	volume.StoreEnumerator
 	volume.StatsDriver
-	nfsServer string
-	nfsPath   string
-	mounter   mount.Manager
+	volume.TrimDriver
+	volume.ExportDriver
+	exports []*export
+	quota   string
 }
 
+// Init mounts the exports in the comma separated server and path params.
+// The quota param selects how the size of volumes is limited, see
+// QuotaImage and QuotaProject.
 func Init(params map[string]string) (volume.VolumeDriver, error) {
-	path, ok := params["path"]
-	if !ok {
-		return nil, errors.New("No NFS path provided")
-	}
-	server, ok := params["server"]
-	if !ok {
-		dlog.Printf("No NFS server provided, will attempt to bind mount %s", path)
-	} else {
-		dlog.Printf("NFS driver initializing with %s:%s ", server, path)
-	}
-	// Create a mount manager for this NFS server. Blank sever is OK.
-	mounter, err := mount.New(mount.NFSMount, nil, []string{server})
+	exports, err := parseExports(params)
 	if err != nil {
-		dlog.Warnf("Failed to create mount manager for server: %v (%v)", server, err)
 		return nil, err
 	}
+	for _, e := range exports {
+		if e.server == "" {
+			dlog.Printf("No NFS server provided, will attempt to bind mount %s", e.path)
+		} else {
+			dlog.Printf("NFS driver initializing with %v ", e)
+		}
+	}
+	quota := params["quota"]
+	switch quota {
+	case "":
+		quota = QuotaNone
+	case QuotaNone, QuotaImage, QuotaProject:
+	default:
+		return nil, fmt.Errorf("Unknown NFS quota %q", quota)
+	}
 	inst := &driver{
 		IODriver:        volume.IONotSupported,
 		StoreEnumerator: common.NewDefaultStoreEnumerator(Name, kvdb.Instance()),
 		StatsDriver:     volume.StatsNotSupported,
-		nfsServer:       server,
-		nfsPath:         path,
-		mounter:         mounter,
-	}
-	if err := os.MkdirAll(nfsMountPath, 0744); err != nil {
-		return nil, err
-	}
-	src := inst.nfsPath
-	if server != "" {
-		src = ":" + inst.nfsPath
-	}
-	// If src is already mounted at dest, leave it be.
-	mountExists, err := mounter.Exists(src, nfsMountPath)
-	if !mountExists {
-		// Mount the nfs server locally on a unique path.
-		syscall.Unmount(nfsMountPath, 0)
-		if server != "" {
-			err = syscall.Mount(
-				src,
-				nfsMountPath,
-				"nfs",
-				0,
-				"nolock,addr="+inst.nfsServer,
-			)
-		} else {
-			err = syscall.Mount(src, nfsMountPath, "", syscall.MS_BIND, "")
+		TrimDriver:      volume.TrimNotSupported,
+		ExportDriver:    volume.ExportNotSupported,
+		exports:         exports,
+		quota:           quota,
+	}
+	// Mount each export locally on a unique path.
+	for i, e := range exports {
+		journal := filepath.Join(volume.MountJournalBase, Name)
+		if i > 0 {
+			journal = fmt.Sprintf("%s.%d", journal, i)
 		}
-		if err != nil {
-			dlog.Printf("Unable to mount %s:%s at %s (%+v)",
-				inst.nfsServer, inst.nfsPath, nfsMountPath, err)
+		if err := e.mount(journal); err != nil {
 			return nil, err
 		}
 	}
@@ -101,6 +99,10 @@ func Init(params map[string]string) (volume.VolumeDriver, error) {
 		}
 	}
 
+	if err := common.RecoverSeeds(inst); err != nil {
+		dlog.Warnf("Failed to recover the seeds of NFS volumes: %v", err)
+	}
+
 	dlog.Println("NFS initialized and driver mounted at: ", nfsMountPath)
 	return inst, nil
 }
@@ -113,9 +115,20 @@ func (d *driver) Type() api.DriverType {
 	return Type
 }
 
-// Status diagnostic information
+// Status reports the health and free space of each export.
 func (d *driver) Status() [][2]string {
-	return [][2]string{}
+	status := make([][2]string, 0, len(d.exports))
+	for _, e := range d.exports {
+		state := ""
+		if free, total, err := e.health(); err != nil {
+			state = fmt.Sprintf("Offline: %v", err)
+		} else {
+			state = fmt.Sprintf("Online, %v free of %v",
+				units.String(free), units.String(total))
+		}
+		status = append(status, [2]string{e.String(), state})
+	}
+	return status
 }
 
 //
@@ -127,43 +140,26 @@ func (d *driver) Create(
 	source *api.Source,
 	spec *api.VolumeSpec) (string, error) {
 
-	volumeID := locator.Name
-	if _, err := d.GetVol(volumeID); err == nil {
-		return "", errors.New("Volume with that name already exists")
-	}
-
-	// Create a directory on the NFS server with this UUID.
-	volPath := path.Join(nfsMountPath, volumeID)
-	err := os.MkdirAll(volPath, 0744)
-	if err != nil {
-		dlog.Println(err)
+	if err := d.checkName(locator); err != nil {
 		return "", err
 	}
-	if source != nil {
-		if len(source.Seed) != 0 {
-			seed, err := seed.New(source.Seed, spec.VolumeLabels)
-			if err != nil {
-				dlog.Warnf("Failed to initailize seed from %q : %v",
-					source.Seed, err)
-				return "", err
-			}
-			err = seed.Load(path.Join(volPath, config.DataDir))
-			if err != nil {
-				dlog.Warnf("Failed to  seed from %q to %q: %v",
-					source.Seed, nfsMountPath, err)
-				return "", err
-			}
+	// Only volumes stored in images have a block device of their own that
+	// can be throttled.
+	if d.quota != QuotaImage || spec.GetSize() == 0 {
+		if err := common.RejectIoLimits(spec); err != nil {
+			return "", err
 		}
 	}
-
-	f, err := os.Create(path.Join(nfsMountPath, volumeID+nfsBlockFile))
+	volumeID := locator.Name
+	e, err := d.place(locator, source)
 	if err != nil {
-		dlog.Println(err)
 		return "", err
 	}
-	defer f.Close()
 
-	if err := f.Truncate(int64(spec.Size)); err != nil {
+	// Create a directory on the NFS server with this UUID.
+	volPath := path.Join(e.mountPath, volumeID)
+	err = os.MkdirAll(volPath, 0744)
+	if err != nil {
 		dlog.Println(err)
 		return "", err
 	}
@@ -175,26 +171,92 @@ func (d *driver) Create(
 		source,
 		spec,
 	)
-	v.DevicePath = path.Join(nfsMountPath, volumeID+nfsBlockFile)
+	nfsState(v)[RuntimeExport] = e.String()
+	if err := d.limit(v, e, volPath); err != nil {
+		dlog.Println(err)
+		os.RemoveAll(volPath)
+		return "", err
+	}
+
+	if err := chaos.Now(koStrayCreate); err != nil {
+		return "", err
+	}
 
 	if err := d.CreateVol(v); err != nil {
+		d.remove(v, e, volPath)
+		return "", err
+	}
+	if err := common.Seed(d, v, path.Join(volPath, config.DataDir), volPath); err != nil {
+		d.remove(v, e, volPath)
+		d.DeleteVol(v.Id)
 		return "", err
 	}
 	return v.Id, err
 }
 
+// limit limits a new volume to its size with the quota of the driver. The
+// image of a volume stays mounted so that it can be seeded.
+func (d *driver) limit(v *api.Volume, e *export, volPath string) error {
+	if v.Spec.Size == 0 || d.quota == QuotaNone {
+		return nil
+	}
+	switch d.quota {
+	case QuotaImage:
+		file := imageFile(e, v.Id)
+		if err := createImage(file, v.Spec.Size); err != nil {
+			return err
+		}
+		if err := mountImage(file, volPath); err != nil {
+			os.Remove(file)
+			return err
+		}
+		v.DevicePath = file
+	case QuotaProject:
+		if err := setProjectQuota(e, volPath, v.Id, v.Spec.Size); err != nil {
+			return err
+		}
+	}
+	nfsState(v)[RuntimeQuota] = d.quota
+	return nil
+}
+
+// remove removes the files of the volume v from the export e.
+func (d *driver) remove(v *api.Volume, e *export, volPath string) error {
+	switch quotaOf(v) {
+	case QuotaImage:
+		if err := unmountImage(volPath); err != nil {
+			return err
+		}
+		os.Remove(v.DevicePath)
+	case QuotaProject:
+		if err := setProjectQuota(e, volPath, v.Id, 0); err != nil {
+			dlog.Warnf("Failed to remove the quota of volume %v: %v", v.Id, err)
+		}
+	}
+	return os.RemoveAll(volPath)
+}
+
 func (d *driver) Delete(volumeID string) error {
 	v, err := d.GetVol(volumeID)
 	if err != nil {
 		dlog.Println(err)
 		return err
 	}
-
-	// Delete the simulated block volume
-	os.Remove(v.DevicePath)
+	e, err := d.exportOf(v)
+	if err != nil {
+		return err
+	}
+	common.CancelSeed(volumeID)
 
 	// Delete the directory on the nfs server.
-	os.RemoveAll(path.Join(nfsMountPath, volumeID))
+	if err := d.remove(v, e, path.Join(e.mountPath, volumeID)); err != nil {
+		dlog.Println(err)
+		return err
+	}
+
+	if err := chaos.Now(koStrayDelete); err != nil {
+		return err
+	}
 
 	err = d.DeleteVol(volumeID)
 	if err != nil {
@@ -210,34 +272,67 @@ func (d *driver) MountedAt(mountpath string) string {
 }
 
 func (d *driver) Mount(volumeID string, mountpath string) error {
+	if err := common.WaitSeed(d, volumeID, common.SeedWaitTimeout); err != nil {
+		return err
+	}
 	v, err := d.GetVol(volumeID)
 	if err != nil {
 		dlog.Println(err)
 		return err
 	}
+	e, err := d.exportOf(v)
+	if err != nil {
+		return err
+	}
 
-	srcPath := path.Join(":", d.nfsPath, volumeID)
-	mountExists, err := d.mounter.Exists(srcPath, mountpath)
+	if err := common.CheckAccess(
+		v,
+		mountpath,
+		api.AccessMode_ACCESS_MODE_READ_WRITE_MANY,
+	); err != nil {
+		return err
+	}
+	flags, err := common.BindMountFlags(v)
+	if err != nil {
+		return err
+	}
+	volPath := path.Join(e.mountPath, volumeID)
+	if quotaOf(v) == QuotaImage {
+		if err := mountImage(v.DevicePath, volPath); err != nil {
+			return err
+		}
+	}
+	if err := chaos.Now(koMount); err != nil {
+		return err
+	}
+	srcPath := path.Join(":", e.path, volumeID)
+	mountExists, err := e.mounter.Exists(srcPath, mountpath)
 	if !mountExists {
-		d.mounter.Unmount(path.Join(nfsMountPath, volumeID), mountpath, 0)
-		if err := d.mounter.Mount(
-			0, path.Join(nfsMountPath, volumeID),
+		e.mounter.Unmount(volPath, mountpath, 0)
+		if err := e.mounter.Mount(
+			0, volPath,
 			mountpath,
-			string(v.Spec.Format),
-			syscall.MS_BIND,
+			v.Spec.Format.SimpleString(),
+			syscall.MS_BIND|flags,
 			"",
 			0,
 		); err != nil {
 			dlog.Printf("Cannot mount %s at %s because %+v",
-				path.Join(nfsMountPath, volumeID), mountpath, err)
+				volPath, mountpath, err)
 			return err
 		}
 	}
-	if v.AttachPath == nil {
-		v.AttachPath = make([]string, 0)
+	common.AddAttachPath(v, mountpath)
+	if err := d.UpdateVol(v); err != nil {
+		return err
+	}
+	// Only volumes stored in images have a block device of their own.
+	if quotaOf(v) == QuotaImage {
+		common.WatchThrottle(d, volumeID, mountpath)
+	} else {
+		common.SkipThrottle(v)
 	}
-	v.AttachPath = append(v.AttachPath, mountpath)
-	return d.UpdateVol(v)
+	return nil
 }
 
 func (d *driver) Unmount(volumeID string, mountpath string) error {
@@ -248,36 +343,96 @@ func (d *driver) Unmount(volumeID string, mountpath string) error {
 	if len(v.AttachPath) == 0 {
 		return fmt.Errorf("Device %v not mounted", volumeID)
 	}
-	err = d.mounter.Unmount(path.Join(nfsMountPath, volumeID), mountpath, 0)
+	e, err := d.exportOf(v)
 	if err != nil {
 		return err
 	}
-	v.AttachPath = d.mounter.Mounts(path.Join(nfsMountPath, volumeID))
+	volPath := path.Join(e.mountPath, volumeID)
+	err = e.mounter.Unmount(volPath, mountpath, 0)
+	if err != nil {
+		return err
+	}
+	v.AttachPath = e.mounter.Mounts(volPath)
+	if exists, _ := e.mounter.Exists(volPath, mountpath); !exists {
+		common.StopThrottle(mountpath)
+	}
+	if len(v.AttachPath) == 0 && quotaOf(v) == QuotaImage {
+		if err := unmountImage(volPath); err != nil {
+			return err
+		}
+	}
 	return d.UpdateVol(v)
 }
 
+// Snapshot copies the volume to a new volume on the same export. Files are
+// reflinked where the export supports it and copied otherwise.
 func (d *driver) Snapshot(volumeID string, readonly bool, locator *api.VolumeLocator) (string, error) {
-	volIDs := []string{volumeID}
-	vols, err := d.Inspect(volIDs)
+	v, err := d.GetVol(volumeID)
 	if err != nil {
-		return "", nil
+		return "", err
 	}
-	source := &api.Source{Parent: volumeID}
-	newVolumeID, err := d.Create(locator, source, vols[0].Spec)
+	e, err := d.exportOf(v)
 	if err != nil {
-		return "", nil
+		return "", err
 	}
+	if err := d.checkName(locator); err != nil {
+		return "", err
+	}
+	newVolumeID := uuid.New()
 
-	// NFS does not support snapshots, so just copy the files.
-	if err := copyDir(nfsMountPath+volumeID, nfsMountPath+newVolumeID); err != nil {
-		d.Delete(newVolumeID)
-		return "", nil
+	snap := common.NewVolume(
+		newVolumeID,
+		api.FSType_FS_TYPE_NFS,
+		locator,
+		&api.Source{Parent: volumeID},
+		v.Spec,
+	)
+	nfsState(snap)[RuntimeExport] = e.String()
+	volPath := path.Join(e.mountPath, volumeID)
+	snapPath := path.Join(e.mountPath, newVolumeID)
+	// The snapshot is removed on failure, so it must not exist yet.
+	if _, err := os.Lstat(snapPath); !os.IsNotExist(err) {
+		return "", fmt.Errorf("Path %v of snapshot already exists", snapPath)
+	}
+	switch quota := quotaOf(v); quota {
+	case QuotaImage:
+		snap.DevicePath = imageFile(e, newVolumeID)
+		nfsState(snap)[RuntimeQuota] = quota
+		if err := os.Mkdir(snapPath, 0744); err != nil {
+			return "", err
+		}
+		// Flush writes to the mounted image before it is copied.
+		syscall.Sync()
+		err = common.CopyFile(v.DevicePath, snap.DevicePath)
+	case QuotaProject:
+		nfsState(snap)[RuntimeQuota] = quota
+		if err = cloneDir(volPath, snapPath); err == nil {
+			err = setProjectQuota(e, snapPath, newVolumeID, v.Spec.Size)
+		}
+	default:
+		err = cloneDir(volPath, snapPath)
+	}
+	if err != nil {
+		dlog.Printf("Failed to snapshot %v: %v", volumeID, err)
+		d.remove(snap, e, snapPath)
+		return "", err
+	}
+	if err := d.CreateVol(snap); err != nil {
+		d.remove(snap, e, snapPath)
+		return "", err
 	}
 	return newVolumeID, nil
 }
 
 func (d *driver) Attach(volumeID string, attachOptions map[string]string) (string, error) {
-	return path.Join(nfsMountPath, volumeID+nfsBlockFile), nil
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		return "", err
+	}
+	if err := chaos.Now(koAttach); err != nil {
+		return "", err
+	}
+	return v.DevicePath, nil
 }
 
 func (d *driver) Detach(volumeID string) error {
@@ -285,52 +440,112 @@ func (d *driver) Detach(volumeID string) error {
 }
 
 func (d *driver) Set(volumeID string, locator *api.VolumeLocator, spec *api.VolumeSpec) error {
-	if spec != nil {
-		return volume.ErrNotSupported
-	}
 	v, err := d.GetVol(volumeID)
 	if err != nil {
 		return err
 	}
+	// Only volumes stored in images have a block device of their own that
+	// can be throttled.
+	limits := common.SetIoLimits(v, spec)
+	if limits && quotaOf(v) != QuotaImage && !common.IoLimits(v.Spec).Unlimited() {
+		return common.ErrSharedDevice
+	}
 	if locator != nil {
 		v.Locator = locator
 	}
+	if spec != nil && spec.Size != 0 && spec.Size != v.Spec.Size {
+		if err := d.resize(v, spec.Size); err != nil {
+			return err
+		}
+	}
+	if limits && quotaOf(v) == QuotaImage {
+		if err := common.Throttle(v); err != nil {
+			return err
+		}
+	}
 	return d.UpdateVol(v)
 }
 
-func (d *driver) Shutdown() {
-	dlog.Printf("%s Shutting down", Name)
-	syscall.Unmount(nfsMountPath, 0)
-}
-
-func copyFile(source string, dest string) (err error) {
-	sourcefile, err := os.Open(source)
+// resize changes the size limit of a volume. Images can only grow, and the
+// filesystem in an image is grown with it.
+func (d *driver) resize(v *api.Volume, size uint64) error {
+	e, err := d.exportOf(v)
 	if err != nil {
 		return err
 	}
+	volPath := path.Join(e.mountPath, v.Id)
+	switch quotaOf(v) {
+	case QuotaImage:
+		if size < v.Spec.Size {
+			return fmt.Errorf("Volume %v cannot shrink from %v to %v",
+				v.Id, v.Spec.Size, size)
+		}
+		if err := resizeImage(v.DevicePath, volPath, size); err != nil {
+			return err
+		}
+	case QuotaProject:
+		if err := setProjectQuota(e, volPath, v.Id, size); err != nil {
+			return err
+		}
+	}
+	dlog.Infof("NFS volume %v resized from %v to %v", v.Id, v.Spec.Size, size)
+	v.Spec.Size = size
+	return nil
+}
 
-	defer sourcefile.Close()
+func (d *driver) Shutdown() {
+	dlog.Printf("%s Shutting down", Name)
+	// Release the images that are only mounted for seeding.
+	if vols, err := d.Enumerate(&api.VolumeLocator{}, nil); err == nil {
+		for _, v := range vols {
+			if quotaOf(v) != QuotaImage || len(v.AttachPath) != 0 {
+				continue
+			}
+			if e, err := d.exportOf(v); err == nil {
+				unmountImage(path.Join(e.mountPath, v.Id))
+			}
+		}
+	}
+	for _, e := range d.exports {
+		syscall.Unmount(e.mountPath, 0)
+	}
+}
 
-	destfile, err := os.Create(dest)
+// checkName returns an error if the name of a new volume is in use or cannot
+// be the name of a directory in an export.
+func (d *driver) checkName(locator *api.VolumeLocator) error {
+	if locator == nil {
+		return errors.New("Volume locator is required")
+	}
+	name := locator.Name
+	if name == "" || name == "." || name == ".." || filepath.Base(name) != name {
+		return fmt.Errorf("Invalid volume name %q", name)
+	}
+	if _, err := d.GetVol(name); err == nil {
+		return errors.New("Volume with that name already exists")
+	}
+	vols, err := d.Enumerate(&api.VolumeLocator{Name: name}, nil)
 	if err != nil {
 		return err
 	}
-
-	defer destfile.Close()
-
-	_, err = io.Copy(destfile, sourcefile)
-	if err == nil {
-		sourceinfo, err := os.Stat(source)
-		if err != nil {
-			err = os.Chmod(dest, sourceinfo.Mode())
-		}
-
+	if len(vols) > 0 {
+		return errors.New("Volume with that name already exists")
 	}
+	return nil
+}
 
-	return
+// cloneDir copies the directory source to dest. cp reflinks the files on
+// filesystems that share extents, which makes the copy nearly free, and
+// copyDir copies the data on any other filesystem.
+func cloneDir(source string, dest string) error {
+	if _, err := run("cp", "-a", "--reflink=always", source, dest); err == nil {
+		return nil
+	}
+	os.RemoveAll(dest)
+	return copyDir(source, dest)
 }
 
-func copyDir(source string, dest string) (err error) {
+func copyDir(source string, dest string) error {
 	// get properties of source dir
 	sourceinfo, err := os.Stat(source)
 	if err != nil {
@@ -338,36 +553,29 @@ func copyDir(source string, dest string) (err error) {
 	}
 
 	// create dest dir
+	if err := os.MkdirAll(dest, sourceinfo.Mode()); err != nil {
+		return err
+	}
 
-	err = os.MkdirAll(dest, sourceinfo.Mode())
+	objects, err := ioutil.ReadDir(source)
 	if err != nil {
 		return err
 	}
 
-	directory, _ := os.Open(source)
-
-	objects, err := directory.Readdir(-1)
-
 	for _, obj := range objects {
-
-		sourcefilepointer := source + "/" + obj.Name()
-
-		destinationfilepointer := dest + "/" + obj.Name()
+		sourcefilepointer := filepath.Join(source, obj.Name())
+		destinationfilepointer := filepath.Join(dest, obj.Name())
 
 		if obj.IsDir() {
 			// create sub-directories - recursively
 			err = copyDir(sourcefilepointer, destinationfilepointer)
-			if err != nil {
-				fmt.Println(err)
-			}
 		} else {
-			// perform copy
-			err = copyFile(sourcefilepointer, destinationfilepointer)
-			if err != nil {
-				fmt.Println(err)
-			}
+			// perform copy, cloning the file if possible
+			err = common.CopyFile(sourcefilepointer, destinationfilepointer)
+		}
+		if err != nil {
+			return err
 		}
-
 	}
-	return
+	return nil
 }
@@ -1,10 +1,16 @@
 package nfs
 
 import (
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"syscall"
 	"testing"
 
+	"github.com/stretchr/testify/require"
+
 	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/volume/drivers/common"
 	"github.com/libopenstorage/openstorage/volume/drivers/test"
 )
 
@@ -27,3 +33,148 @@ func TestAll(t *testing.T) {
 
 	test.RunShort(t, ctx)
 }
+
+func TestPlacement(t *testing.T) {
+	small, large := tmpfs(t, "small", "16m"), tmpfs(t, "large", "32m")
+	defer syscall.Unmount(small, 0)
+	defer syscall.Unmount(large, 0)
+
+	d, err := Init(map[string]string{"path": small + "," + large})
+	require.NoError(t, err)
+	defer d.Shutdown()
+
+	status := d.Status()
+	require.Len(t, status, 2)
+	require.Equal(t, small, status[0][0])
+	require.Contains(t, status[0][1], "Online")
+
+	id, err := d.Create(
+		&api.VolumeLocator{Name: "placed"},
+		nil,
+		&api.VolumeSpec{},
+	)
+	require.NoError(t, err)
+	defer d.Delete(id)
+	_, err = os.Stat(filepath.Join(nfsMountPath, "1", id))
+	require.NoError(t, err, "Volume should be placed on the export with the most free space")
+
+	id, err = d.Create(
+		&api.VolumeLocator{
+			Name:         "labeled",
+			VolumeLabels: map[string]string{ExportLabel: small},
+		},
+		nil,
+		&api.VolumeSpec{},
+	)
+	require.NoError(t, err)
+	defer d.Delete(id)
+	_, err = os.Stat(filepath.Join(nfsMountPath, "0", id))
+	require.NoError(t, err, "Volume should be placed on the labeled export")
+
+	_, err = d.Create(
+		&api.VolumeLocator{
+			Name:         "unknown",
+			VolumeLabels: map[string]string{ExportLabel: "/unknown"},
+		},
+		nil,
+		&api.VolumeSpec{},
+	)
+	require.Error(t, err)
+}
+
+func TestImageQuota(t *testing.T) {
+	require.NoError(t, os.MkdirAll(testPath, 0744))
+	d, err := Init(map[string]string{"path": testPath, "quota": QuotaImage})
+	require.NoError(t, err)
+	defer d.Shutdown()
+
+	id, err := d.Create(
+		&api.VolumeLocator{Name: "quota"},
+		nil,
+		&api.VolumeSpec{Size: 16 * 1024 * 1024},
+	)
+	require.NoError(t, err)
+	vols, err := d.Inspect([]string{id})
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(nfsMountPath, "0", id+nfsBlockFile), vols[0].DevicePath)
+
+	mountPath := filepath.Join(testPath, "quota-mnt")
+	require.NoError(t, os.MkdirAll(mountPath, 0755))
+	defer os.RemoveAll(mountPath)
+	require.NoError(t, d.Mount(id, mountPath))
+	err = ioutil.WriteFile(filepath.Join(mountPath, "big"), make([]byte, 32*1024*1024), 0644)
+	require.Error(t, err, "Writes beyond the size of the volume should fail")
+	require.NoError(t, ioutil.WriteFile(filepath.Join(mountPath, "small"), []byte("data"), 0644))
+	require.NoError(t, d.Unmount(id, mountPath))
+
+	require.NoError(t, d.Set(id, nil, &api.VolumeSpec{Size: 32 * 1024 * 1024}))
+	require.Error(t, d.Set(id, nil, &api.VolumeSpec{Size: 8 * 1024 * 1024}))
+
+	snapID, err := d.Snapshot(id, false, &api.VolumeLocator{Name: "quota-snap"})
+	require.NoError(t, err)
+	require.NoError(t, d.Mount(snapID, mountPath))
+	data, err := ioutil.ReadFile(filepath.Join(mountPath, "small"))
+	require.NoError(t, err)
+	require.Equal(t, "data", string(data))
+	require.NoError(t, d.Unmount(snapID, mountPath))
+
+	require.NoError(t, d.Delete(snapID))
+	require.NoError(t, d.Delete(id))
+	_, err = os.Stat(vols[0].DevicePath)
+	require.True(t, os.IsNotExist(err), "Image should be removed")
+}
+
+func TestSnapshot(t *testing.T) {
+	require.NoError(t, os.MkdirAll(testPath, 0744))
+	d, err := Init(map[string]string{"path": testPath})
+	require.NoError(t, err)
+	defer d.Shutdown()
+
+	id, err := d.Create(&api.VolumeLocator{Name: "copied"}, nil, &api.VolumeSpec{})
+	require.NoError(t, err)
+	defer d.Delete(id)
+	volPath := filepath.Join(nfsMountPath, "0", id)
+	require.NoError(t, os.MkdirAll(filepath.Join(volPath, "dir"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(volPath, "dir", "file"), []byte("data"), 0644))
+
+	snapID, err := d.Snapshot(id, false, &api.VolumeLocator{Name: "copied-snap"})
+	require.NoError(t, err)
+	defer d.Delete(snapID)
+	data, err := ioutil.ReadFile(filepath.Join(nfsMountPath, "0", snapID, "dir", "file"))
+	require.NoError(t, err)
+	require.Equal(t, "data", string(data))
+
+	for _, locator := range []*api.VolumeLocator{
+		nil, {}, {Name: ".."}, {Name: "a/b"}, {Name: "copied-snap"},
+	} {
+		_, err := d.Snapshot(id, false, locator)
+		require.Error(t, err, "%v", locator)
+	}
+	_, err = os.Stat(volPath)
+	require.NoError(t, err)
+}
+
+func TestIoLimits(t *testing.T) {
+	require.NoError(t, os.MkdirAll(testPath, 0744))
+	d, err := Init(map[string]string{"path": testPath})
+	require.NoError(t, err)
+	defer d.Shutdown()
+
+	_, err = d.Create(&api.VolumeLocator{Name: "limited"}, nil,
+		&api.VolumeSpec{Cos: api.CosType_LOW})
+	require.Equal(t, common.ErrSharedDevice, err)
+	id, err := d.Create(&api.VolumeLocator{Name: "limited"}, nil, &api.VolumeSpec{})
+	require.NoError(t, err)
+	defer d.Delete(id)
+	require.Equal(t, common.ErrSharedDevice, d.Set(id, nil, &api.VolumeSpec{MaxIops: 100}),
+		"Directories of an export should not be throttled")
+	require.NoError(t, d.Set(id, nil, &api.VolumeSpec{Cos: api.CosType_HIGH}))
+}
+
+// tmpfs mounts a tmpfs of size under the test path to serve as an export.
+func tmpfs(t *testing.T, name string, size string) string {
+	dir := filepath.Join(testPath, name)
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	require.NoError(t, syscall.Mount("tmpfs", dir, "tmpfs", 0, "size="+size))
+	return dir
+}
@@ -0,0 +1,149 @@
+package nfs
+
+import (
+	"fmt"
+	"hash/fnv"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/libopenstorage/openstorage/api"
+)
+
+const (
+	// QuotaNone does not limit the size of volumes.
+	QuotaNone = "none"
+	// QuotaImage limits a volume to its size by keeping its files in an
+	// ext4 image on the export that is loop mounted on the volume directory.
+	QuotaImage = "image"
+	// QuotaProject limits a volume to its size with an XFS project quota on
+	// the volume directory. The export must be an XFS filesystem that is
+	// mounted with prjquota on this node.
+	QuotaProject = "project"
+	// RuntimeQuota is the runtime state of a volume with the quota that
+	// limits its size.
+	RuntimeQuota = "nfs_quota"
+)
+
+// quotaOf returns the quota that limits the size of the volume v.
+func quotaOf(v *api.Volume) string {
+	for _, state := range v.RuntimeState {
+		if value, ok := state.RuntimeState[RuntimeQuota]; ok {
+			return value
+		}
+	}
+	return QuotaNone
+}
+
+// imageFile returns the path of the image of a volume on the export e.
+func imageFile(e *export, volumeID string) string {
+	return filepath.Join(e.mountPath, volumeID+nfsBlockFile)
+}
+
+// createImage creates a sparse image of size bytes and makes an ext4
+// filesystem on it.
+func createImage(file string, size uint64) error {
+	f, err := os.OpenFile(file, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	err = f.Truncate(int64(size))
+	f.Close()
+	if err != nil {
+		os.Remove(file)
+		return err
+	}
+	if _, err := run("mkfs.ext4", "-F", "-q", file); err != nil {
+		os.Remove(file)
+		return err
+	}
+	return nil
+}
+
+// mountImage loop mounts the image file on dir unless it is mounted there.
+func mountImage(file string, dir string) error {
+	if src, err := mountSource(dir); err != nil || src != "" {
+		return err
+	}
+	_, err := run("mount", "-o", "loop", file, dir)
+	return err
+}
+
+// unmountImage unmounts the image on dir. The loop device that mount set
+// up is released with it.
+func unmountImage(dir string) error {
+	if src, err := mountSource(dir); err != nil || src == "" {
+		return err
+	}
+	return syscall.Unmount(dir, 0)
+}
+
+// resizeImage grows the image file to size bytes along with the filesystem
+// on it, online if the image is mounted on dir.
+func resizeImage(file string, dir string, size uint64) error {
+	if err := os.Truncate(file, int64(size)); err != nil {
+		return err
+	}
+	dev, err := mountSource(dir)
+	if err != nil {
+		return err
+	}
+	if dev != "" {
+		if _, err := run("losetup", "-c", dev); err != nil {
+			return err
+		}
+		_, err := run("resize2fs", dev)
+		return err
+	}
+	// e2fsck exits with 1 if it corrected errors.
+	err = exec.Command("e2fsck", "-f", "-p", file).Run()
+	if e, ok := err.(*exec.ExitError); ok &&
+		e.Sys().(syscall.WaitStatus).ExitStatus() > 1 {
+		return fmt.Errorf("Failed to check %v: %v", file, err)
+	}
+	_, err = run("resize2fs", file)
+	return err
+}
+
+// projectID returns the XFS project of a volume.
+func projectID(volumeID string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(volumeID))
+	if id := h.Sum32(); id != 0 {
+		return id
+	}
+	return 1
+}
+
+// setProjectQuota limits the volume directory dir on the export e to size
+// bytes. A size of 0 removes the limit.
+func setProjectQuota(e *export, dir string, volumeID string, size uint64) error {
+	id := projectID(volumeID)
+	if _, err := run(
+		"xfs_quota",
+		"-x",
+		"-c", fmt.Sprintf("project -s -p %s %d", dir, id),
+		e.mountPath,
+	); err != nil {
+		return err
+	}
+	_, err := run(
+		"xfs_quota",
+		"-x",
+		"-c", fmt.Sprintf("limit -p bhard=%d %d", size, id),
+		e.mountPath,
+	)
+	return err
+}
+
+// run runs the command name with args and returns its output.
+func run(name string, args ...string) (string, error) {
+	o, err := exec.Command(name, args...).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("%v %v failed: %v: %s",
+			name, strings.Join(args, " "), err, strings.TrimSpace(string(o)))
+	}
+	return string(o), nil
+}
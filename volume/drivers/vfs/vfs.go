@@ -10,6 +10,8 @@ import (
 	"go.pedge.io/dlog"
 
 	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/pkg/chaos"
+	"github.com/libopenstorage/openstorage/pkg/mount"
 	"github.com/libopenstorage/openstorage/volume"
 	"github.com/libopenstorage/openstorage/volume/drivers/common"
 	"github.com/pborman/uuid"
@@ -21,6 +23,15 @@ const (
 	Name = "vfs"
 	// Type of the driver
 	Type = api.DriverType_DRIVER_TYPE_FILE
+	// Propagation is the param for the mount propagation of volume mounts,
+	// e.g. rshared when osd runs in a container.
+	Propagation = "propagation"
+)
+
+var (
+	koStrayCreate = chaos.Add("vfs", "create", "create volume directory before DB")
+	koMount       = chaos.Add("vfs", "mount", "bind mount volume directory")
+	koStrayDelete = chaos.Add("vfs", "delete", "remove volume directory before DB")
 )
 
 type driver struct {
@@ -29,17 +40,42 @@ type driver struct {
 	volume.SnapshotDriver
 	volume.StoreEnumerator
 	volume.StatsDriver
+	volume.TrimDriver
+	volume.ExportDriver
+	mounter     mount.Manager
+	propagation uintptr
 }
 
 // Init Driver intialization.
 func Init(params map[string]string) (volume.VolumeDriver, error) {
-	return &driver{
+	propagation, err := mount.ParsePropagation(params[Propagation])
+	if err != nil {
+		return nil, err
+	}
+	mounter, err := mount.New(
+		mount.BindMount,
+		nil,
+		[]string{volume.VolumeBase},
+		mount.NewFileJournal(filepath.Join(volume.MountJournalBase, Name)),
+	)
+	if err != nil {
+		return nil, err
+	}
+	d := &driver{
 		volume.IONotSupported,
 		volume.BlockNotSupported,
 		volume.SnapshotNotSupported,
 		common.NewDefaultStoreEnumerator(Name, kvdb.Instance()),
 		volume.StatsNotSupported,
-	}, nil
+		volume.TrimNotSupported,
+		volume.ExportNotSupported,
+		mounter,
+		propagation,
+	}
+	if err := common.RecoverSeeds(d); err != nil {
+		dlog.Warnf("Failed to recover the seeds of vfs volumes: %v", err)
+	}
+	return d, nil
 }
 
 func (d *driver) Name() string {
@@ -51,11 +87,17 @@ func (d *driver) Type() api.DriverType {
 }
 
 func (d *driver) Create(locator *api.VolumeLocator, source *api.Source, spec *api.VolumeSpec) (string, error) {
+	if err := common.RejectIoLimits(spec); err != nil {
+		return "", err
+	}
 	volumeID := strings.TrimSuffix(uuid.New(), "\n")
 	// Create a directory on the Local machine with this UUID.
 	if err := os.MkdirAll(filepath.Join(volume.VolumeBase, string(volumeID)), 0744); err != nil {
 		return "", err
 	}
+	if err := chaos.Now(koStrayCreate); err != nil {
+		return "", err
+	}
 	v := common.NewVolume(
 		volumeID,
 		api.FSType_FS_TYPE_VFS,
@@ -67,14 +109,23 @@ func (d *driver) Create(locator *api.VolumeLocator, source *api.Source, spec *ap
 	if err := d.CreateVol(v); err != nil {
 		return "", err
 	}
-	return v.Id, d.UpdateVol(v)
+	if err := common.Seed(d, v, v.DevicePath, v.DevicePath); err != nil {
+		d.DeleteVol(v.Id)
+		os.RemoveAll(v.DevicePath)
+		return "", err
+	}
+	return v.Id, nil
 }
 
 func (d *driver) Delete(volumeID string) error {
 	if _, err := d.GetVol(volumeID); err != nil {
 		return err
 	}
+	common.CancelSeed(volumeID)
 	os.RemoveAll(filepath.Join(volume.VolumeBase, string(volumeID)))
+	if err := chaos.Now(koStrayDelete); err != nil {
+		return err
+	}
 	if err := d.DeleteVol(volumeID); err != nil {
 		return err
 	}
@@ -89,20 +140,36 @@ func (d *driver) MountedAt(mountpath string) string {
 // Mount volume at specified path
 // Errors ErrEnoEnt, ErrVolDetached may be returned.
 func (d *driver) Mount(volumeID string, mountpath string) error {
+	if err := common.WaitSeed(d, volumeID, common.SeedWaitTimeout); err != nil {
+		return err
+	}
 	v, err := d.GetVol(volumeID)
 	if err != nil {
 		dlog.Println(err)
 		return err
 	}
-	if len(v.AttachPath) > 0 && len(v.AttachPath) > 0 {
-		return fmt.Errorf("Volume %q already mounted at %q", volumeID, v.AttachPath[0])
+	if err := common.CheckAccess(
+		v,
+		mountpath,
+		api.AccessMode_ACCESS_MODE_READ_WRITE_ONCE,
+	); err != nil {
+		return err
 	}
-	syscall.Unmount(mountpath, 0)
-	if err := syscall.Mount(
+	flags, err := common.BindMountFlags(v)
+	if err != nil {
+		return err
+	}
+	if err := chaos.Now(koMount); err != nil {
+		return err
+	}
+	if err := d.mounter.Mount(
+		0,
 		filepath.Join(volume.VolumeBase, string(volumeID)),
 		mountpath,
-		string(v.Spec.Format),
-		syscall.MS_BIND, "",
+		"",
+		syscall.MS_BIND|d.propagation|flags,
+		"",
+		0,
 	); err != nil {
 		dlog.Printf("Cannot mount %s at %s because %+v",
 			filepath.Join(volume.VolumeBase, string(volumeID)),
@@ -111,10 +178,8 @@ func (d *driver) Mount(volumeID string, mountpath string) error {
 		)
 		return err
 	}
-	if v.AttachPath == nil {
-		v.AttachPath = make([]string, 1)
-	}
-	v.AttachPath[0] = mountpath
+	common.AddAttachPath(v, mountpath)
+	common.SkipThrottle(v)
 	return d.UpdateVol(v)
 }
 
@@ -128,21 +193,34 @@ func (d *driver) Unmount(volumeID string, mountpath string) error {
 	if len(v.AttachPath) == 0 || len(v.AttachPath[0]) == 0 {
 		return fmt.Errorf("Device %v not mounted", volumeID)
 	}
-	if err := syscall.Unmount(v.AttachPath[0], 0); err != nil {
+	if len(mountpath) == 0 {
+		mountpath = v.AttachPath[0]
+	}
+	source := filepath.Join(volume.VolumeBase, string(volumeID))
+	if err := d.mounter.Unmount(source, mountpath, 0); err != nil {
 		return err
 	}
-	v.AttachPath = nil
+	if exists, _ := d.mounter.Exists(source, mountpath); exists {
+		return nil
+	}
+	common.RemoveAttachPath(v, mountpath)
 	return d.UpdateVol(v)
 }
 
+// Set updates the locator of the volume. Volumes are directories of the
+// host filesystem, so their I/O can only be unlimited.
 func (d *driver) Set(volumeID string, locator *api.VolumeLocator, spec *api.VolumeSpec) error {
-	if spec != nil {
-		return volume.ErrNotSupported
-	}
 	v, err := d.GetVol(volumeID)
 	if err != nil {
 		return err
 	}
+	if common.SetIoLimits(v, spec) {
+		if !common.IoLimits(v.Spec).Unlimited() {
+			return common.ErrSharedDevice
+		}
+	} else if spec != nil {
+		return volume.ErrNotSupported
+	}
 	if locator != nil {
 		v.Locator = locator
 	}
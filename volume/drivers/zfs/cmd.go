@@ -0,0 +1,105 @@
+package zfs
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// Runner runs the zfs and filesystem commands of the driver, so that unit
+// tests can replace the zfs CLI with a fake.
+type Runner interface {
+	// Run runs the command name with args and returns its output.
+	Run(name string, args ...string) (string, error)
+}
+
+// execRunner runs commands on the host.
+type execRunner struct{}
+
+func (e *execRunner) Run(name string, args ...string) (string, error) {
+	o, err := exec.Command(name, args...).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("%v %v failed: %v: %s",
+			name, strings.Join(args, " "), err, strings.TrimSpace(string(o)))
+	}
+	return string(o), nil
+}
+
+// zfs runs a zfs subcommand.
+func (d *driver) zfs(args ...string) (string, error) {
+	return d.runner.Run("zfs", args...)
+}
+
+// get returns the value of the property prop of the dataset name.
+func (d *driver) get(name string, prop string) (string, error) {
+	o, err := d.zfs("get", "-Hp", "-o", "value", prop, name)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(o), nil
+}
+
+// getUint returns the value of the numeric property prop of the dataset
+// name.
+func (d *driver) getUint(name string, prop string) (uint64, error) {
+	v, err := d.get(name, prop)
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.ParseUint(v, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("Unexpected %v of %v: %q", prop, name, v)
+	}
+	return n, nil
+}
+
+// set sets the property prop of the dataset name to value.
+func (d *driver) set(name string, prop string, value string) error {
+	_, err := d.zfs("set", prop+"="+value, name)
+	return err
+}
+
+// snapshots returns the snapshots of the dataset name, oldest first, with
+// the clones that were created from each of them.
+func (d *driver) snapshots(name string) ([]snapshot, error) {
+	o, err := d.zfs(
+		"list",
+		"-H",
+		"-t", "snapshot",
+		"-o", "name,clones",
+		"-s", "createtxg",
+		"-d", "1",
+		name,
+	)
+	if err != nil {
+		return nil, err
+	}
+	var snaps []snapshot
+	for _, line := range strings.Split(o, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		s := snapshot{name: fields[0]}
+		if len(fields) > 1 && fields[1] != "-" {
+			s.clones = strings.Split(fields[1], ",")
+		}
+		snaps = append(snaps, s)
+	}
+	return snaps, nil
+}
+
+// snapshot is a zfs snapshot and the datasets cloned from it.
+type snapshot struct {
+	name   string
+	clones []string
+}
+
+// onOff returns the zfs value of a boolean property.
+func onOff(b bool) string {
+	if b {
+		return "on"
+	}
+	return "off"
+}
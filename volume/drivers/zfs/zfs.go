@@ -0,0 +1,530 @@
+package zfs
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"syscall"
+
+	"go.pedge.io/dlog"
+
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/pkg/chaos"
+	"github.com/libopenstorage/openstorage/volume"
+	"github.com/libopenstorage/openstorage/volume/drivers/common"
+	"github.com/pborman/uuid"
+	"github.com/portworx/kvdb"
+)
+
+const (
+	// Name of the driver
+	Name = "zfs"
+	// Type of the driver
+	Type = api.DriverType_DRIVER_TYPE_FILE
+	// PoolParam is the Init param for the pool, or the dataset in a pool,
+	// under which the volumes are created
+	PoolParam = "pool"
+	// ZvolPath is the directory of the device nodes of zvols
+	ZvolPath = "/dev/zvol/"
+)
+
+var (
+	koStrayCreate = chaos.Add("zfs", "create", "create dataset before DB")
+	koAttach      = chaos.Add("zfs", "attach", "attach zvol")
+	koMount       = chaos.Add("zfs", "mount", "mount dataset")
+	koStrayDelete = chaos.Add("zfs", "delete", "destroy dataset before DB")
+)
+
+// Implements the open storage volume interface with a zfs dataset per
+// volume. Volumes that are formatted with a filesystem other than zfs are
+// backed by a zvol instead.
+type driver struct {
+	volume.IODriver
+	volume.StoreEnumerator
+	volume.StatsDriver
+	volume.ExportDriver
+	pool   string
+	runner Runner
+}
+
+// Init initializes the zfs driver for the pool params[PoolParam].
+func Init(params map[string]string) (volume.VolumeDriver, error) {
+	return newDriver(params, &execRunner{})
+}
+
+func newDriver(params map[string]string, runner Runner) (*driver, error) {
+	pool, ok := params[PoolParam]
+	if !ok {
+		return nil, fmt.Errorf("'pool' configuration parameter must be set")
+	}
+	inst := &driver{
+		IODriver:        volume.IONotSupported,
+		StoreEnumerator: common.NewDefaultStoreEnumerator(Name, kvdb.Instance()),
+		StatsDriver:     volume.StatsNotSupported,
+		ExportDriver:    volume.ExportNotSupported,
+		pool:            strings.TrimSuffix(pool, "/"),
+		runner:          runner,
+	}
+	if _, err := inst.zfs("list", "-H", "-o", "name", inst.pool); err != nil {
+		return nil, fmt.Errorf("Failed to locate pool %v: %v", pool, err)
+	}
+	dlog.Infof("ZFS initialized with pool %v", inst.pool)
+	return inst, nil
+}
+
+// dataset returns the name of the dataset or zvol of a volume.
+func (d *driver) dataset(volumeID string) string {
+	return d.pool + "/" + volumeID
+}
+
+// zvolPath returns the device node of the zvol of a volume.
+func (d *driver) zvolPath(volumeID string) string {
+	return path.Join(ZvolPath, d.dataset(volumeID))
+}
+
+// isZvol returns true if volumes in format are backed by a zvol.
+func isZvol(format api.FSType) bool {
+	return format != api.FSType_FS_TYPE_ZFS
+}
+
+//
+// These functions below implement the volume driver interface.
+//
+
+func (d *driver) String() string {
+	return Name
+}
+
+func (d *driver) Name() string {
+	return Name
+}
+
+func (d *driver) Type() api.DriverType {
+	return Type
+}
+
+// Status diagnostic information
+func (d *driver) Status() [][2]string {
+	return [][2]string{
+		{"Pool", d.pool},
+	}
+}
+
+func (d *driver) Create(
+	locator *api.VolumeLocator,
+	source *api.Source,
+	spec *api.VolumeSpec,
+) (string, error) {
+	return d.create(locator, source, spec, false)
+}
+
+// create creates a dataset or a sparse zvol, or clones a snapshot of
+// source.Parent. The snapshot is named after the new volume.
+func (d *driver) create(
+	locator *api.VolumeLocator,
+	source *api.Source,
+	spec *api.VolumeSpec,
+	readonly bool,
+) (string, error) {
+	if spec.Size == 0 {
+		return "", fmt.Errorf("Volume size cannot be zero: zfs")
+	}
+	volumeID := uuid.New()
+	name := d.dataset(volumeID)
+	props := properties(spec, readonly)
+
+	if source != nil && len(source.Parent) != 0 {
+		parent, err := d.GetVol(source.Parent)
+		if err != nil {
+			return "", err
+		}
+		if isZvol(parent.Spec.Format) != isZvol(spec.Format) {
+			return "", fmt.Errorf("Volume %v cannot be cloned as %v",
+				parent.Id, spec.Format)
+		}
+		snap := d.dataset(source.Parent) + "@" + volumeID
+		if _, err := d.zfs("snapshot", snap); err != nil {
+			return "", err
+		}
+		if _, err := d.zfs(append(append([]string{"clone"}, props...), snap, name)...); err != nil {
+			d.zfs("destroy", snap)
+			return "", err
+		}
+	} else if isZvol(spec.Format) {
+		args := []string{"create", "-s", "-V", fmt.Sprintf("%d", spec.Size)}
+		if _, err := d.zfs(append(append(args, props...), name)...); err != nil {
+			return "", err
+		}
+		if err := d.format(volumeID, spec.Format); err != nil {
+			d.zfs("destroy", name)
+			return "", err
+		}
+	} else {
+		if _, err := d.zfs(append(append([]string{"create"}, props...), name)...); err != nil {
+			return "", err
+		}
+	}
+
+	if err := chaos.Now(koStrayCreate); err != nil {
+		return "", err
+	}
+
+	v := common.NewVolume(
+		volumeID,
+		spec.Format,
+		locator,
+		source,
+		spec,
+	)
+	v.Readonly = readonly
+	if err := d.CreateVol(v); err != nil {
+		d.destroy(volumeID)
+		return "", err
+	}
+	return v.Id, nil
+}
+
+// properties returns the zfs create options for a volume in spec. The size
+// of a dataset is enforced by its refquota. Clones do not inherit the
+// properties of their origin, so they are given the same options.
+func properties(spec *api.VolumeSpec, readonly bool) []string {
+	var props []string
+	if !isZvol(spec.Format) {
+		props = append(props,
+			"-o", "mountpoint=legacy",
+			"-o", fmt.Sprintf("refquota=%d", spec.Size),
+		)
+	}
+	if spec.Compressed {
+		props = append(props, "-o", "compression=on")
+	}
+	if spec.Dedupe {
+		props = append(props, "-o", "dedup=on")
+	}
+	if readonly {
+		props = append(props, "-o", "readonly=on")
+	}
+	return props
+}
+
+// format makes a filesystem on a new zvol.
+func (d *driver) format(volumeID string, format api.FSType) error {
+	if format == api.FSType_FS_TYPE_NONE {
+		return nil
+	}
+	dev := d.device(volumeID)
+	dlog.Infof("Formatting %s with %v", dev, format)
+	_, err := d.runner.Run("/sbin/mkfs."+format.SimpleString(), dev)
+	return err
+}
+
+// device returns the device node of the zvol of a volume, which udev creates
+// asynchronously after the zvol is created.
+func (d *driver) device(volumeID string) string {
+	if _, err := d.runner.Run("udevadm", "settle"); err != nil {
+		dlog.Warnf("Failed to wait for the zvol of %v: %v", volumeID, err)
+	}
+	return d.zvolPath(volumeID)
+}
+
+// Delete destroys the dataset of a volume with its snapshots. Snapshots
+// that volumes were cloned from are handed over to one of those clones
+// first, so that the clones outlive the volume.
+func (d *driver) Delete(volumeID string) error {
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		dlog.Println(err)
+		return err
+	}
+	if len(v.AttachPath) > 0 {
+		return fmt.Errorf("Volume %v is mounted at %v", volumeID, v.AttachPath)
+	}
+	if err := d.destroy(volumeID); err != nil {
+		return err
+	}
+	if err := chaos.Now(koStrayDelete); err != nil {
+		return err
+	}
+	return d.DeleteVol(volumeID)
+}
+
+// destroy destroys the dataset of a volume and the snapshot it was cloned
+// from.
+func (d *driver) destroy(volumeID string) error {
+	name := d.dataset(volumeID)
+	snaps, err := d.snapshots(name)
+	if err != nil {
+		return err
+	}
+	// Promoting a clone moves every snapshot up to its origin to the clone.
+	for i := len(snaps) - 1; i >= 0; i-- {
+		if len(snaps[i].clones) == 0 {
+			continue
+		}
+		if _, err := d.zfs("promote", snaps[i].clones[0]); err != nil {
+			return err
+		}
+		break
+	}
+	origin, err := d.get(name, "origin")
+	if err != nil {
+		return err
+	}
+	if _, err := d.zfs("destroy", "-r", name); err != nil {
+		return err
+	}
+	if origin != "-" && len(origin) != 0 {
+		if _, err := d.zfs("destroy", origin); err != nil {
+			dlog.Warnf("Failed to destroy origin %v of volume %v: %v",
+				origin, volumeID, err)
+		}
+	}
+	return nil
+}
+
+func (d *driver) MountedAt(mountpath string) string {
+	return ""
+}
+
+func (d *driver) Mount(volumeID string, mountpath string) error {
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		return fmt.Errorf("Failed to locate volume %q", volumeID)
+	}
+	if err := common.CheckAccess(
+		v,
+		mountpath,
+		api.AccessMode_ACCESS_MODE_READ_WRITE_ONCE,
+	); err != nil {
+		return err
+	}
+	source := d.dataset(volumeID)
+	if isZvol(v.Spec.Format) {
+		if len(v.DevicePath) == 0 {
+			return volume.ErrVolDetached
+		}
+		source = v.DevicePath
+	}
+	if err := chaos.Now(koMount); err != nil {
+		return err
+	}
+	flags, data := common.MountFlags(v)
+	if err := syscall.Mount(
+		source,
+		mountpath,
+		v.Spec.Format.SimpleString(),
+		flags,
+		data,
+	); err != nil {
+		return fmt.Errorf("Failed to mount %v at %v: %v", source, mountpath, err)
+	}
+	common.AddAttachPath(v, mountpath)
+	return d.UpdateVol(v)
+}
+
+func (d *driver) Unmount(volumeID string, mountpath string) error {
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		return err
+	}
+	if len(v.AttachPath) == 0 || len(v.AttachPath[0]) == 0 {
+		return fmt.Errorf("Device %v not mounted", volumeID)
+	}
+	if len(mountpath) == 0 {
+		mountpath = v.AttachPath[0]
+	}
+	if err := syscall.Unmount(mountpath, 0); err != nil {
+		return err
+	}
+	common.RemoveAttachPath(v, mountpath)
+	return d.UpdateVol(v)
+}
+
+// Snapshot creates a zfs snapshot of a volume and clones it into a new
+// volume, which shares its blocks with the volume until either is written.
+func (d *driver) Snapshot(volumeID string, readonly bool, locator *api.VolumeLocator) (string, error) {
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		return "", err
+	}
+	return d.create(locator, &api.Source{Parent: volumeID}, v.Spec, readonly)
+}
+
+// SnapEnumerate returns the snapshot volumes whose zfs snapshot still
+// exists in the pool.
+func (d *driver) SnapEnumerate(
+	volumeIDs []string,
+	snapLabels map[string]string,
+) ([]*api.Volume, error) {
+	vols, err := d.StoreEnumerator.SnapEnumerate(volumeIDs, snapLabels)
+	if err != nil {
+		return nil, err
+	}
+	o, err := d.zfs("list", "-H", "-t", "snapshot", "-o", "name", "-r", d.pool)
+	if err != nil {
+		return nil, err
+	}
+	snaps := make(map[string]bool)
+	for _, name := range strings.Fields(o) {
+		if i := strings.LastIndex(name, "@"); i != -1 {
+			snaps[name[i+1:]] = true
+		}
+	}
+	snapVols := make([]*api.Volume, 0, len(vols))
+	for _, v := range vols {
+		if snaps[v.Id] {
+			snapVols = append(snapVols, v)
+		}
+	}
+	return snapVols, nil
+}
+
+// Set updates the locator, resizes the volume to spec.Size and turns
+// compression on or off as in spec.Compressed.
+func (d *driver) Set(volumeID string, locator *api.VolumeLocator, spec *api.VolumeSpec) error {
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		return err
+	}
+	if locator != nil {
+		v.Locator = locator
+	}
+	if spec != nil {
+		if spec.Size != 0 && spec.Size != v.Spec.Size {
+			if err := d.resize(v, spec.Size); err != nil {
+				return err
+			}
+		}
+		if spec.Compressed != v.Spec.Compressed {
+			if err := d.set(
+				d.dataset(volumeID),
+				"compression",
+				onOff(spec.Compressed),
+			); err != nil {
+				return err
+			}
+			v.Spec.Compressed = spec.Compressed
+		}
+	}
+	return d.UpdateVol(v)
+}
+
+// resize sets the refquota of a dataset, which zfs refuses to lower below
+// the space the dataset references. Zvols can only grow, and the
+// filesystem on a zvol is grown with it while it is mounted.
+func (d *driver) resize(v *api.Volume, size uint64) error {
+	name := d.dataset(v.Id)
+	if !isZvol(v.Spec.Format) {
+		if err := d.set(name, "refquota", fmt.Sprintf("%d", size)); err != nil {
+			return err
+		}
+	} else {
+		if size < v.Spec.Size {
+			return fmt.Errorf("Volume %v cannot shrink from %v to %v",
+				v.Id, v.Spec.Size, size)
+		}
+		if err := d.set(name, "volsize", fmt.Sprintf("%d", size)); err != nil {
+			return err
+		}
+		if err := d.growFs(v); err != nil {
+			return err
+		}
+	}
+	dlog.Infof("ZFS volume %v resized from %v to %v", v.Id, v.Spec.Size, size)
+	v.Spec.Size = size
+	return nil
+}
+
+// growFs grows the filesystem of a mounted zvol to the size of the zvol.
+func (d *driver) growFs(v *api.Volume) error {
+	if len(v.AttachPath) == 0 {
+		return nil
+	}
+	var err error
+	switch v.Spec.Format {
+	case api.FSType_FS_TYPE_EXT4:
+		_, err = d.runner.Run("resize2fs", v.DevicePath)
+	case api.FSType_FS_TYPE_XFS:
+		_, err = d.runner.Run("xfs_growfs", v.AttachPath[0])
+	}
+	return err
+}
+
+// Attach returns the device node of a zvol. Datasets are mounted directly
+// and are not attached.
+func (d *driver) Attach(volumeID string, attachOptions map[string]string) (string, error) {
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		return "", err
+	}
+	if !isZvol(v.Spec.Format) || len(v.DevicePath) != 0 {
+		return v.DevicePath, nil
+	}
+	if err := chaos.Now(koAttach); err != nil {
+		return "", err
+	}
+	v.DevicePath = d.device(volumeID)
+	v.State = api.VolumeState_VOLUME_STATE_ATTACHED
+	if err := d.UpdateVol(v); err != nil {
+		return "", err
+	}
+	return v.DevicePath, nil
+}
+
+func (d *driver) Detach(volumeID string) error {
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		return err
+	}
+	if len(v.DevicePath) == 0 {
+		return nil
+	}
+	if len(v.AttachPath) > 0 {
+		return fmt.Errorf("Volume %v is mounted at %v", volumeID, v.AttachPath)
+	}
+	v.DevicePath = ""
+	v.State = api.VolumeState_VOLUME_STATE_AVAILABLE
+	return d.UpdateVol(v)
+}
+
+// Stats returns the space used by a volume. Zfs does not account I/O per
+// dataset in its properties.
+func (d *driver) Stats(volumeID string, cumulative bool) (*api.Stats, error) {
+	used, err := d.UsedSize(volumeID)
+	if err != nil {
+		return nil, err
+	}
+	return &api.Stats{BytesUsed: used}, nil
+}
+
+// UsedSize returns the space used by the dataset of a volume and its
+// snapshots.
+func (d *driver) UsedSize(volumeID string) (uint64, error) {
+	if _, err := d.GetVol(volumeID); err != nil {
+		return 0, err
+	}
+	return d.getUint(d.dataset(volumeID), "used")
+}
+
+// Fstrim trims the filesystem of a mounted zvol, which frees the discarded
+// blocks in the pool. Datasets free their blocks on delete.
+func (d *driver) Fstrim(volumeID string) (uint64, error) {
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		return 0, err
+	}
+	if !isZvol(v.Spec.Format) {
+		return 0, volume.ErrNotSupported
+	}
+	if len(v.DevicePath) == 0 {
+		return 0, volume.ErrVolDetached
+	}
+	if len(v.AttachPath) == 0 {
+		return 0, fmt.Errorf("Volume %q is not mounted", volumeID)
+	}
+	return common.Fstrim(v.AttachPath[0])
+}
+
+func (d *driver) Shutdown() {
+	dlog.Printf("%s Shutting down", Name)
+}
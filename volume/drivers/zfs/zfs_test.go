@@ -0,0 +1,381 @@
+package zfs
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/volume"
+	_ "github.com/libopenstorage/openstorage/volume/drivers/test"
+	"github.com/stretchr/testify/require"
+)
+
+const testPool = "tank/osd"
+
+// fakeZfs emulates the zfs subcommands that the driver runs on a pool in
+// memory.
+type fakeZfs struct {
+	datasets  map[string]*fakeDataset
+	snapshots map[string]*fakeSnapshot
+	txg       int
+	commands  []string
+}
+
+type fakeDataset struct {
+	props  map[string]string
+	origin string
+}
+
+type fakeSnapshot struct {
+	clones []string
+	txg    int
+}
+
+func newFakeZfs() *fakeZfs {
+	return &fakeZfs{
+		datasets: map[string]*fakeDataset{
+			testPool: {props: map[string]string{}},
+		},
+		snapshots: make(map[string]*fakeSnapshot),
+	}
+}
+
+func (f *fakeZfs) Run(name string, args ...string) (string, error) {
+	f.commands = append(f.commands, name+" "+strings.Join(args, " "))
+	if name != "zfs" {
+		return "", nil
+	}
+	switch args[0] {
+	case "list":
+		return f.list(args[1:])
+	case "create":
+		return "", f.create(args[1:])
+	case "snapshot":
+		return "", f.snapshot(args[1])
+	case "clone":
+		return "", f.clone(args[1:])
+	case "promote":
+		return "", f.promote(args[1])
+	case "get":
+		return f.get(args[len(args)-2], args[len(args)-1])
+	case "set":
+		return "", f.set(args[1], args[2])
+	case "destroy":
+		return "", f.destroy(args[len(args)-1])
+	}
+	return "", fmt.Errorf("unknown command %v", args)
+}
+
+// options returns the -o properties of args and the remaining arguments.
+func options(args []string) (map[string]string, []string) {
+	props := make(map[string]string)
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		if args[i] == "-o" {
+			kv := strings.SplitN(args[i+1], "=", 2)
+			props[kv[0]] = kv[1]
+			i++
+		} else {
+			rest = append(rest, args[i])
+		}
+	}
+	return props, rest
+}
+
+func (f *fakeZfs) list(args []string) (string, error) {
+	name := args[len(args)-1]
+	if _, ok := f.datasets[name]; !ok {
+		return "", fmt.Errorf("dataset %v does not exist", name)
+	}
+	if args[1] != "-t" {
+		return name + "\n", nil
+	}
+	var snaps []string
+	for s := range f.snapshots {
+		if strings.HasPrefix(s, name+"@") ||
+			(args[len(args)-2] == "-r" && strings.HasPrefix(s, name+"/")) {
+			snaps = append(snaps, s)
+		}
+	}
+	sort.Slice(snaps, func(i, j int) bool {
+		return f.snapshots[snaps[i]].txg < f.snapshots[snaps[j]].txg
+	})
+	o := ""
+	for _, s := range snaps {
+		if args[4] == "name" {
+			o += s + "\n"
+			continue
+		}
+		clones := strings.Join(f.snapshots[s].clones, ",")
+		if len(clones) == 0 {
+			clones = "-"
+		}
+		o += s + "\t" + clones + "\n"
+	}
+	return o, nil
+}
+
+func (f *fakeZfs) create(args []string) error {
+	props, rest := options(args)
+	name := rest[len(rest)-1]
+	if _, ok := f.datasets[name]; ok {
+		return fmt.Errorf("dataset %v exists", name)
+	}
+	if rest[0] == "-s" {
+		props["volsize"] = rest[2]
+	}
+	props["used"] = "65536"
+	f.datasets[name] = &fakeDataset{props: props}
+	return nil
+}
+
+func (f *fakeZfs) snapshot(snap string) error {
+	if _, ok := f.datasets[strings.Split(snap, "@")[0]]; !ok {
+		return fmt.Errorf("dataset of %v does not exist", snap)
+	}
+	f.txg++
+	f.snapshots[snap] = &fakeSnapshot{txg: f.txg}
+	return nil
+}
+
+func (f *fakeZfs) clone(args []string) error {
+	props, rest := options(args)
+	snap, ok := f.snapshots[rest[0]]
+	if !ok {
+		return fmt.Errorf("snapshot %v does not exist", rest[0])
+	}
+	props["used"] = "4096"
+	f.datasets[rest[1]] = &fakeDataset{props: props, origin: rest[0]}
+	snap.clones = append(snap.clones, rest[1])
+	return nil
+}
+
+// promote moves the snapshots of the origin of a clone up to the origin
+// itself to the clone, and makes the origin dataset a clone of it.
+func (f *fakeZfs) promote(name string) error {
+	clone := f.datasets[name]
+	parts := strings.Split(clone.origin, "@")
+	parent := f.datasets[parts[0]]
+	txg := f.snapshots[clone.origin].txg
+	for s, snap := range f.snapshots {
+		if !strings.HasPrefix(s, parts[0]+"@") || snap.txg > txg {
+			continue
+		}
+		moved := name + "@" + strings.Split(s, "@")[1]
+		delete(f.snapshots, s)
+		f.snapshots[moved] = snap
+		for _, c := range snap.clones {
+			f.datasets[c].origin = moved
+		}
+	}
+	origin := name + "@" + parts[1]
+	clone.origin, parent.origin = parent.origin, origin
+	f.snapshots[origin].clones = []string{parts[0]}
+	return nil
+}
+
+func (f *fakeZfs) get(prop string, name string) (string, error) {
+	ds, ok := f.datasets[name]
+	if !ok {
+		return "", fmt.Errorf("dataset %v does not exist", name)
+	}
+	if prop == "origin" {
+		if len(ds.origin) == 0 {
+			return "-\n", nil
+		}
+		return ds.origin + "\n", nil
+	}
+	return ds.props[prop] + "\n", nil
+}
+
+func (f *fakeZfs) set(kv string, name string) error {
+	ds, ok := f.datasets[name]
+	if !ok {
+		return fmt.Errorf("dataset %v does not exist", name)
+	}
+	p := strings.SplitN(kv, "=", 2)
+	ds.props[p[0]] = p[1]
+	return nil
+}
+
+func (f *fakeZfs) destroy(name string) error {
+	if snap, ok := f.snapshots[name]; ok {
+		if len(snap.clones) != 0 {
+			return fmt.Errorf("snapshot %v has dependent clones", name)
+		}
+		delete(f.snapshots, name)
+		return nil
+	}
+	ds, ok := f.datasets[name]
+	if !ok {
+		return fmt.Errorf("dataset %v does not exist", name)
+	}
+	for s, snap := range f.snapshots {
+		if strings.HasPrefix(s, name+"@") && len(snap.clones) != 0 {
+			return fmt.Errorf("snapshot %v has dependent clones", s)
+		}
+	}
+	for s := range f.snapshots {
+		if strings.HasPrefix(s, name+"@") {
+			delete(f.snapshots, s)
+		}
+	}
+	if len(ds.origin) != 0 {
+		snap := f.snapshots[ds.origin]
+		for i, c := range snap.clones {
+			if c == name {
+				snap.clones = append(snap.clones[:i], snap.clones[i+1:]...)
+				break
+			}
+		}
+	}
+	delete(f.datasets, name)
+	return nil
+}
+
+func TestInit(t *testing.T) {
+	_, err := newDriver(map[string]string{}, newFakeZfs())
+	require.Error(t, err)
+	_, err = newDriver(map[string]string{PoolParam: "bogus"}, newFakeZfs())
+	require.Error(t, err)
+}
+
+func TestCreateSet(t *testing.T) {
+	f := newFakeZfs()
+	d, err := newDriver(map[string]string{PoolParam: testPool}, f)
+	require.NoError(t, err)
+
+	volumeID, err := d.Create(
+		&api.VolumeLocator{Name: "dataset"},
+		nil,
+		&api.VolumeSpec{
+			Size:       1024 * 1024,
+			Format:     api.FSType_FS_TYPE_ZFS,
+			Compressed: true,
+		},
+	)
+	require.NoError(t, err)
+	ds := f.datasets[d.dataset(volumeID)]
+	require.Equal(t, "legacy", ds.props["mountpoint"])
+	require.Equal(t, "1048576", ds.props["refquota"])
+	require.Equal(t, "on", ds.props["compression"])
+
+	require.NoError(t, d.Set(volumeID, nil, &api.VolumeSpec{Size: 512 * 1024}))
+	require.Equal(t, "524288", ds.props["refquota"])
+	require.Equal(t, "off", ds.props["compression"])
+	vols, err := d.Inspect([]string{volumeID})
+	require.NoError(t, err)
+	require.Equal(t, uint64(512*1024), vols[0].Spec.Size)
+	require.False(t, vols[0].Spec.Compressed)
+
+	path, err := d.Attach(volumeID, nil)
+	require.NoError(t, err)
+	require.Empty(t, path)
+
+	stats, err := d.Stats(volumeID, true)
+	require.NoError(t, err)
+	require.Equal(t, uint64(65536), stats.BytesUsed)
+	used, err := d.UsedSize(volumeID)
+	require.NoError(t, err)
+	require.Equal(t, uint64(65536), used)
+
+	require.NoError(t, d.Delete(volumeID))
+	require.NotContains(t, f.datasets, d.dataset(volumeID))
+}
+
+func TestZvol(t *testing.T) {
+	f := newFakeZfs()
+	d, err := newDriver(map[string]string{PoolParam: testPool}, f)
+	require.NoError(t, err)
+
+	volumeID, err := d.Create(
+		&api.VolumeLocator{Name: "zvol"},
+		nil,
+		&api.VolumeSpec{Size: 1024 * 1024, Format: api.FSType_FS_TYPE_EXT4},
+	)
+	require.NoError(t, err)
+	ds := f.datasets[d.dataset(volumeID)]
+	require.Equal(t, "1048576", ds.props["volsize"])
+	require.NotContains(t, ds.props, "refquota")
+	require.Contains(t, f.commands, "/sbin/mkfs.ext4 "+d.zvolPath(volumeID))
+
+	path, err := d.Attach(volumeID, nil)
+	require.NoError(t, err)
+	require.Equal(t, "/dev/zvol/"+testPool+"/"+volumeID, path)
+
+	require.NoError(t, d.Set(volumeID, nil, &api.VolumeSpec{Size: 2 * 1024 * 1024}))
+	require.Equal(t, "2097152", ds.props["volsize"])
+	require.Error(t, d.Set(volumeID, nil, &api.VolumeSpec{Size: 1024}))
+
+	require.NoError(t, d.Detach(volumeID))
+	require.NoError(t, d.Delete(volumeID))
+}
+
+func TestSnapshot(t *testing.T) {
+	f := newFakeZfs()
+	d, err := newDriver(map[string]string{PoolParam: testPool}, f)
+	require.NoError(t, err)
+
+	volumeID, err := d.Create(
+		&api.VolumeLocator{Name: "origin"},
+		nil,
+		&api.VolumeSpec{Size: 1024 * 1024, Format: api.FSType_FS_TYPE_ZFS},
+	)
+	require.NoError(t, err)
+	snap1, err := d.Snapshot(volumeID, true, &api.VolumeLocator{Name: "snap1"})
+	require.NoError(t, err)
+	snap2, err := d.Snapshot(volumeID, false, &api.VolumeLocator{Name: "snap2"})
+	require.NoError(t, err)
+
+	clone := f.datasets[d.dataset(snap1)]
+	require.Equal(t, d.dataset(volumeID)+"@"+snap1, clone.origin)
+	require.Equal(t, "on", clone.props["readonly"])
+	require.Equal(t, "legacy", clone.props["mountpoint"])
+	require.Equal(t, "1048576", clone.props["refquota"])
+
+	snaps, err := d.SnapEnumerate([]string{volumeID}, nil)
+	require.NoError(t, err)
+	require.Len(t, snaps, 2)
+
+	// Snapshots whose zfs snapshot is gone are not reported.
+	delete(f.snapshots, d.dataset(volumeID)+"@"+snap1)
+	snaps, err = d.SnapEnumerate([]string{volumeID}, nil)
+	require.NoError(t, err)
+	require.Len(t, snaps, 1)
+	require.Equal(t, snap2, snaps[0].Id)
+	f.snapshots[d.dataset(volumeID)+"@"+snap1] = &fakeSnapshot{
+		clones: []string{d.dataset(snap1)},
+	}
+
+	// The clones outlive the volume they were cloned from.
+	require.NoError(t, d.Delete(volumeID))
+	require.NotContains(t, f.datasets, d.dataset(volumeID))
+	require.Contains(t, f.datasets, d.dataset(snap1))
+	require.Contains(t, f.datasets, d.dataset(snap2))
+	require.NoError(t, d.Delete(snap2))
+	require.NoError(t, d.Delete(snap1))
+	require.Len(t, f.datasets, 1)
+	require.Empty(t, f.snapshots)
+
+	_, err = d.Inspect([]string{volumeID})
+	require.NoError(t, err)
+	_, err = d.Snapshot(volumeID, false, nil)
+	require.Error(t, err)
+	require.Error(t, d.Delete(volumeID))
+}
+
+func TestFstrim(t *testing.T) {
+	d, err := newDriver(map[string]string{PoolParam: testPool}, newFakeZfs())
+	require.NoError(t, err)
+	volumeID, err := d.Create(
+		&api.VolumeLocator{Name: "trim"},
+		nil,
+		&api.VolumeSpec{Size: 1024 * 1024, Format: api.FSType_FS_TYPE_ZFS},
+	)
+	require.NoError(t, err)
+	_, err = d.Fstrim(volumeID)
+	require.Equal(t, volume.ErrNotSupported, err)
+	require.NoError(t, d.Delete(volumeID))
+}
@@ -2,6 +2,7 @@ package volume
 
 import (
 	"errors"
+	"io"
 
 	"github.com/libopenstorage/openstorage/api"
 )
@@ -34,6 +35,8 @@ var (
 	ErrVolHasSnaps = errors.New("Volume has snapshots associated")
 	// ErrNotSupported returned when the operation is not supported
 	ErrNotSupported = errors.New("Operation not supported")
+	// ErrVolSeeding returned when the volume is still being seeded
+	ErrVolSeeding = errors.New("Volume is being seeded")
 )
 
 // Constants used by the VolumeDriver
@@ -46,6 +49,8 @@ const (
 	DriverAPIBase = "/var/lib/osd/driver/"
 	// MountBase for osd mountpoints
 	MountBase = "/var/lib/osd/mounts/"
+	// MountJournalBase where the mount tables of volume drivers are saved
+	MountJournalBase = "/var/lib/osd/mounttable/"
 	// VolumeBase for osd volumes
 	VolumeBase = "/var/lib/osd/"
 )
@@ -127,11 +132,32 @@ type StatsDriver interface {
 	GetActiveRequests() (*api.ActiveRequests, error)
 }
 
+// TrimDriver interface provides discard of unused blocks
+type TrimDriver interface {
+	// Fstrim discards the blocks that are not in use by the filesystem of a
+	// mounted volume and returns the number of bytes discarded.
+	// Errors ErrEnoEnt, ErrVolDetached may be returned.
+	Fstrim(volumeID string) (uint64, error)
+}
+
+// ExportDriver interface provides moving volumes between hosts as streams
+type ExportDriver interface {
+	// Export writes the data of the read-only snapshot snapID to w. If
+	// parentID is set, only the changes since the snapshot parentID are
+	// written, which must have been imported on the receiving host.
+	// Errors ErrEnoEnt may be returned.
+	Export(snapID string, parentID string, w io.Writer) error
+	// Import creates a read-only volume from a stream written by Export.
+	Import(locator *api.VolumeLocator, r io.Reader) (string, error)
+}
+
 // ProtoDriver must be implemented by all volume drivers.  It specifies the
 // most basic functionality, such as creating and deleting volumes.
 type ProtoDriver interface {
 	SnapshotDriver
 	StatsDriver
+	TrimDriver
+	ExportDriver
 	// Name returns the name of the driver.
 	Name() string
 	// Type of this driver
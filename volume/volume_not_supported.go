@@ -1,6 +1,8 @@
 package volume
 
 import (
+	"io"
+
 	"github.com/libopenstorage/openstorage/api"
 )
 
@@ -17,6 +19,12 @@ var (
 	// StatsNotSupported is a null stats driver implementation. This can be used
 	// by drivers that do not want to implement the stats interface.
 	StatsNotSupported = &statsNotSupported{}
+	// TrimNotSupported is a null trim driver implementation. This can be used
+	// by drivers that cannot discard unused blocks.
+	TrimNotSupported = &trimNotSupported{}
+	// ExportNotSupported is a null export driver implementation. This can be
+	// used by drivers that cannot move volumes between hosts.
+	ExportNotSupported = &exportNotSupported{}
 )
 
 type blockNotSupported struct{}
@@ -73,3 +81,22 @@ func (s *statsNotSupported) Alerts(volumeID string) (*api.Alerts, error) {
 func (s *statsNotSupported) GetActiveRequests() (*api.ActiveRequests, error) {
 	return nil, nil
 }
+
+type trimNotSupported struct{}
+
+// Fstrim discards unused blocks
+func (t *trimNotSupported) Fstrim(volumeID string) (uint64, error) {
+	return 0, ErrNotSupported
+}
+
+type exportNotSupported struct{}
+
+// Export writes the data of a snapshot
+func (e *exportNotSupported) Export(snapID string, parentID string, w io.Writer) error {
+	return ErrNotSupported
+}
+
+// Import creates a volume from exported data
+func (e *exportNotSupported) Import(locator *api.VolumeLocator, r io.Reader) (string, error) {
+	return "", ErrNotSupported
+}